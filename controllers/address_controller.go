@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressController struct {
+	addressService *services.AddressService
+}
+
+func NewAddressController(addressService *services.AddressService) *AddressController {
+	return &AddressController{
+		addressService: addressService,
+	}
+}
+
+// CustomerAddressRequest is an address book entry. Postal codes are checked
+// against the format of the country, e.g. 3, 3+2 or 3+3 digits in Taiwan.
+type CustomerAddressRequest struct {
+	AddressRequest
+	Label             string `json:"label" binding:"max=50" example:"Home"`
+	IsDefaultShipping bool   `json:"is_default_shipping" example:"true"`
+	IsDefaultBilling  bool   `json:"is_default_billing" example:"false"`
+}
+
+func (r CustomerAddressRequest) toDetails() services.AddressDetails {
+	return services.AddressDetails{
+		Label:             r.Label,
+		Address:           r.toAddress(),
+		IsDefaultShipping: r.IsDefaultShipping,
+		IsDefaultBilling:  r.IsDefaultBilling,
+	}
+}
+
+func addressErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAddressNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidAddress):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTooManyAddresses):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List addresses
+// @Description List the current user's address book
+// @Tags addresses
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.CustomerAddress "Addresses"
+// @Router /auth/profile/addresses [get]
+func (c *AddressController) List(ctx *gin.Context) {
+	currentUser := ctx.MustGet("user").(models.User)
+	addresses, err := c.addressService.List(currentUser.ID)
+	if err != nil {
+		ctx.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, addresses)
+}
+
+// @Summary Get address
+// @Description Get an address of the current user's address book
+// @Tags addresses
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} models.CustomerAddress "Address"
+// @Failure 404 {object} map[string]string "Address not found"
+// @Router /auth/profile/addresses/{id} [get]
+func (c *AddressController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	address, err := c.addressService.Get(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, address)
+}
+
+// @Summary Create address
+// @Description Add an address to the current user's address book. The first address becomes the default for shipping and billing.
+// @Tags addresses
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CustomerAddressRequest true "Address"
+// @Success 201 {object} models.CustomerAddress "Created address"
+// @Failure 400 {object} map[string]string "Invalid address"
+// @Failure 422 {object} map[string]string "Address book is full"
+// @Router /auth/profile/addresses [post]
+func (c *AddressController) Create(ctx *gin.Context) {
+	var req CustomerAddressRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	address, err := c.addressService.Create(currentUser.ID, req.toDetails())
+	if err != nil {
+		ctx.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, address)
+}
+
+// @Summary Update address
+// @Description Replace an address of the current user's address book. Orders already placed keep the address they were placed with.
+// @Tags addresses
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Param request body CustomerAddressRequest true "Address"
+// @Success 200 {object} models.CustomerAddress "Updated address"
+// @Failure 400 {object} map[string]string "Invalid address"
+// @Failure 404 {object} map[string]string "Address not found"
+// @Router /auth/profile/addresses/{id} [put]
+func (c *AddressController) Update(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req CustomerAddressRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	address, err := c.addressService.Update(currentUser.ID, id, req.toDetails())
+	if err != nil {
+		ctx.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, address)
+}
+
+// @Summary Delete address
+// @Description Remove an address from the current user's address book
+// @Tags addresses
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} map[string]string "Address deleted"
+// @Failure 404 {object} map[string]string "Address not found"
+// @Router /auth/profile/addresses/{id} [delete]
+func (c *AddressController) Delete(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.addressService.Delete(currentUser.ID, id); err != nil {
+		ctx.JSON(addressErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Address deleted"})
+}
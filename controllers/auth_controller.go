@@ -30,6 +30,14 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=6" example:"password123"`
 }
 
+type UpdateCurrencyRequest struct {
+	Currency string `json:"currency" example:"USD"`
+}
+
+type CustomerGroupRequest struct {
+	Group string `json:"group" binding:"max=32" example:"vip"`
+}
+
 type UpdateProfileRequest struct {
 	Name  string `json:"name" binding:"required" example:"John Doe"`
 	Email string `json:"email" binding:"required,email" example:"user@example.com"`
@@ -60,10 +68,11 @@ func (c *AuthController) Register(ctx *gin.Context) {
 }
 
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return JWT token. A guest cart sent in X-Cart-Token is merged into the user's cart.
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param request body LoginRequest true "Login credentials"
 // @Success 200 {object} map[string]interface{} "Login successful with token and user info"
 // @Failure 400 {object} map[string]string "Invalid input"
@@ -76,7 +85,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
-	user, token, err := c.authService.Login(req.Email, req.Password)
+	user, token, err := c.authService.Login(req.Email, req.Password, ctx.GetHeader(cartTokenHeader))
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -163,3 +172,69 @@ func (c *AuthController) UpdateProfile(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, updatedUser)
 }
+
+// @Summary Set preferred currency
+// @Description Set the currency used for prices when no Accept-Currency header is sent. An empty value clears it.
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateCurrencyRequest true "Preferred currency"
+// @Success 200 {object} models.User "Updated user profile"
+// @Failure 400 {object} map[string]string "Unsupported currency"
+// @Failure 401 {object} map[string]string "User not authenticated"
+// @Router /auth/profile/currency [put]
+func (c *AuthController) UpdateCurrency(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req UpdateCurrencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := user.(models.User)
+	updatedUser, err := c.authService.SetPreferredCurrency(currentUser.ID, req.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedUser)
+}
+
+// @Summary Set customer group
+// @Description Put a user in a customer group that promotions can target. An empty group clears it (staff only).
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body CustomerGroupRequest true "Customer group"
+// @Success 200 {object} models.User "Updated user"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/customer-group [put]
+func (c *AuthController) SetCustomerGroup(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req CustomerGroupRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.authService.SetCustomerGroup(id, req.Group)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
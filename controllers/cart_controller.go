@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cartTokenHeader carries the token of a guest cart
+const cartTokenHeader = "X-Cart-Token"
+
+type CartController struct {
+	cartService *services.CartService
+}
+
+func NewCartController(cartService *services.CartService) *CartController {
+	return &CartController{
+		cartService: cartService,
+	}
+}
+
+type AddCartItemRequest struct {
+	ProductID uint `json:"product_id" binding:"required" example:"1"`
+	Quantity  int  `json:"quantity" binding:"required,min=1" example:"2"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"min=0" example:"3"`
+}
+
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required,max=64" example:"WELCOME100"`
+}
+
+func cartErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCartNotFound), errors.Is(err, services.ErrCartItemNotFound),
+		errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrCouponNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidQuantity), errors.Is(err, services.ErrQuantityTooLarge):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPriceNotFound), errors.Is(err, services.ErrCouponExpired),
+		errors.Is(err, services.ErrCouponUsedUp), errors.Is(err, services.ErrCouponLimitPerUser):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// cartOwner identifies the cart of a request: the signed-in user's, or
+// the guest cart named by the cart token header
+func cartOwner(ctx *gin.Context) (uint, string) {
+	if user, exists := ctx.Get("user"); exists {
+		return user.(models.User).ID, ""
+	}
+	return 0, ctx.GetHeader(cartTokenHeader)
+}
+
+// respondCart writes a cart, echoing the guest token so new guests learn it
+func respondCart(ctx *gin.Context, cart *models.Cart) {
+	if cart.Token != nil {
+		ctx.Header(cartTokenHeader, *cart.Token)
+	}
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.JSON(http.StatusOK, cart)
+}
+
+// @Summary Get cart
+// @Description Get the cart of the signed-in user, or the guest cart of X-Cart-Token, priced in the currency selected by Accept-Currency
+// @Tags cart
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Success 200 {object} models.Cart "Cart"
+// @Router /cart [get]
+func (c *CartController) Get(ctx *gin.Context) {
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.Get(userID, token, priceList)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	respondCart(ctx, cart)
+}
+
+// @Summary Add cart item
+// @Description Add a product to the cart. Guests without a cart get a new one whose token is returned in X-Cart-Token and in the body.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param request body AddCartItemRequest true "Product and quantity"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Failure 409 {object} map[string]string "Insufficient stock"
+// @Failure 422 {object} map[string]string "Product has no price in this currency"
+// @Router /cart/items [post]
+func (c *CartController) AddItem(ctx *gin.Context) {
+	var req AddCartItemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.AddItem(userID, token, req.ProductID, req.Quantity, priceList)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCart(ctx, cart)
+}
+
+// @Summary Update cart item
+// @Description Set the quantity of a cart line; zero removes it
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param productId path int true "Product ID"
+// @Param request body UpdateCartItemRequest true "Quantity"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 404 {object} map[string]string "Cart or item not found"
+// @Failure 409 {object} map[string]string "Insufficient stock"
+// @Router /cart/items/{productId} [put]
+func (c *CartController) UpdateItem(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	var req UpdateCartItemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.UpdateItem(userID, token, productID, req.Quantity, priceList)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCart(ctx, cart)
+}
+
+// @Summary Remove cart item
+// @Description Remove a product from the cart
+// @Tags cart
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param productId path int true "Product ID"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 404 {object} map[string]string "Cart or item not found"
+// @Router /cart/items/{productId} [delete]
+func (c *CartController) RemoveItem(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.RemoveItem(userID, token, productID, priceList)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCart(ctx, cart)
+}
+
+// @Summary Apply coupon
+// @Description Enter a coupon code on the cart, replacing any earlier one. The cart lists the promotions applied and, when the coupon gives no discount, why.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param request body ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 404 {object} map[string]string "Coupon not found"
+// @Failure 422 {object} map[string]string "Coupon expired or used up"
+// @Router /cart/coupon [put]
+func (c *CartController) ApplyCoupon(ctx *gin.Context) {
+	var req ApplyCouponRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.ApplyCoupon(userID, token, req.Code, priceList)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCart(ctx, cart)
+}
+
+// @Summary Remove coupon
+// @Description Remove the coupon code from the cart
+// @Tags cart
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Success 200 {object} models.Cart "Updated cart"
+// @Failure 404 {object} map[string]string "Cart not found"
+// @Router /cart/coupon [delete]
+func (c *CartController) RemoveCoupon(ctx *gin.Context) {
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.RemoveCoupon(userID, token, priceList)
+	if err != nil {
+		ctx.JSON(cartErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respondCart(ctx, cart)
+}
+
+// @Summary Clear cart
+// @Description Remove every line and the coupon from the cart
+// @Tags cart
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Success 204 "Cleared"
+// @Router /cart [delete]
+func (c *CartController) Clear(ctx *gin.Context) {
+	userID, token := cartOwner(ctx)
+	if err := c.cartService.Clear(userID, token); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartRecoveryController struct {
+	cartRecoveryService *services.CartRecoveryService
+}
+
+func NewCartRecoveryController(cartRecoveryService *services.CartRecoveryService) *CartRecoveryController {
+	return &CartRecoveryController{
+		cartRecoveryService: cartRecoveryService,
+	}
+}
+
+// CartReminderSettings is whether a customer gets abandoned cart reminders
+type CartReminderSettings struct {
+	Enabled bool `json:"enabled" example:"false"`
+}
+
+func cartRecoveryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidCartRecovery), errors.Is(err, services.ErrInvalidUnsubscribeToken):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary Unsubscribe from cart reminders
+// @Description Stop abandoned cart reminders with the link at the bottom of each reminder. No sign-in is needed.
+// @Tags cart-reminders
+// @Produce json
+// @Param token query string true "Unsubscribe token from the reminder"
+// @Success 200 {object} map[string]string "Unsubscribed"
+// @Failure 400 {object} map[string]string "Invalid token"
+// @Router /cart-reminders/unsubscribe [get]
+// @Router /cart-reminders/unsubscribe [post]
+func (c *CartRecoveryController) Unsubscribe(ctx *gin.Context) {
+	if err := c.cartRecoveryService.Unsubscribe(ctx.Query("token")); err != nil {
+		ctx.JSON(cartRecoveryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "unsubscribed"})
+}
+
+// @Summary Get cart reminder settings
+// @Description Whether the current user gets reminders about carts left with items in them
+// @Tags cart-reminders
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} CartReminderSettings "Settings"
+// @Router /cart-reminders [get]
+func (c *CartRecoveryController) Settings(ctx *gin.Context) {
+	currentUser := ctx.MustGet("user").(models.User)
+	enabled, err := c.cartRecoveryService.RemindersEnabled(currentUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart reminder settings"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, CartReminderSettings{Enabled: enabled})
+}
+
+// @Summary Update cart reminder settings
+// @Description Turn the current user's abandoned cart reminders on or off. Turning them off stops reminders already under way.
+// @Tags cart-reminders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CartReminderSettings true "Settings"
+// @Success 200 {object} CartReminderSettings "Settings"
+// @Router /cart-reminders [put]
+func (c *CartRecoveryController) UpdateSettings(ctx *gin.Context) {
+	var req CartReminderSettings
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.cartRecoveryService.SetReminders(currentUser.ID, req.Enabled); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart reminder settings"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, req)
+}
+
+// @Summary List cart recoveries
+// @Description List abandoned carts and their reminders, newest first, optionally filtered by status
+// @Tags cart-reminders
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Status" Enums(active, returned, finished, converted, unsubscribed)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.CartRecovery "Cart recoveries"
+// @Failure 400 {object} map[string]string "Unknown status"
+// @Router /admin/cart-recoveries [get]
+func (c *CartRecoveryController) List(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	recoveries, err := c.cartRecoveryService.List(ctx.Query("status"), page, pageSize)
+	if err != nil {
+		ctx.JSON(cartRecoveryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, recoveries)
+}
+
+// @Summary Cart recovery stats
+// @Description How many abandoned carts were found in the last days, how many the reminders won back and the revenue of those orders
+// @Tags cart-reminders
+// @Security BearerAuth
+// @Produce json
+// @Param days query int false "Days to look back" default(30)
+// @Success 200 {object} models.CartRecoveryStats "Stats"
+// @Failure 400 {object} map[string]string "Invalid number of days"
+// @Router /admin/cart-recoveries/stats [get]
+func (c *CartRecoveryController) Stats(ctx *gin.Context) {
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of days"})
+		return
+	}
+
+	stats, err := c.cartRecoveryService.Stats(days)
+	if err != nil {
+		ctx.JSON(cartRecoveryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
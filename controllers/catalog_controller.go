@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportSize = 50 << 20
+
+type CatalogController struct {
+	catalogService *services.CatalogService
+}
+
+func NewCatalogController(catalogService *services.CatalogService) *CatalogController {
+	return &CatalogController{
+		catalogService: catalogService,
+	}
+}
+
+// @Summary Start catalog import
+// @Description Upload a CSV or JSON file to upsert products by SKU. The file is processed asynchronously (staff only).
+// @Tags catalog
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV (sku,name,description,category) or JSON array file"
+// @Param format formData string false "csv or json, detected from the file extension when omitted"
+// @Success 202 {object} models.ImportJob "Queued import job"
+// @Failure 400 {object} map[string]string "Invalid input or unsupported format"
+// @Failure 413 {object} map[string]string "File too large"
+// @Router /catalog/imports [post]
+func (c *CatalogController) StartImport(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize+multipartOverhead)
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer f.Close()
+
+	currentUser := ctx.MustGet("user").(models.User)
+	job, err := c.catalogService.StartImport(ctx.Request.Context(), currentUser.ID, fh.Filename, ctx.PostForm("format"), f, fh.Size)
+	if errors.Is(err, services.ErrUnsupportedFormat) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// @Summary Get import job
+// @Description Get the status and counters of a catalog import (staff only)
+// @Tags catalog
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Import job ID"
+// @Success 200 {object} models.ImportJob "Import job"
+// @Failure 404 {object} map[string]string "Import job not found"
+// @Router /catalog/imports/{id} [get]
+func (c *CatalogController) GetImport(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	job, err := c.catalogService.GetJob(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// @Summary Download import error report
+// @Description Download the rejected rows of an import as CSV (staff only)
+// @Tags catalog
+// @Security BearerAuth
+// @Produce text/csv
+// @Param id path int true "Import job ID"
+// @Success 200 {file} binary "CSV error report"
+// @Failure 404 {object} map[string]string "Import job not found"
+// @Router /catalog/imports/{id}/errors [get]
+func (c *CatalogController) DownloadErrors(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	if _, err := c.catalogService.GetJob(id); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%d-errors.csv"`, id))
+	if err := c.catalogService.WriteErrorReport(id, ctx.Writer); err != nil {
+		ctx.Error(err)
+	}
+}
+
+// @Summary Export catalog
+// @Description Stream the whole catalog as CSV or JSON (staff only)
+// @Tags catalog
+// @Security BearerAuth
+// @Produce text/csv,json
+// @Param format query string false "csv or json" default(csv)
+// @Success 200 {file} binary "Catalog export"
+// @Failure 400 {object} map[string]string "Unsupported format"
+// @Router /catalog/export [get]
+func (c *CatalogController) Export(ctx *gin.Context) {
+	format, err := services.DetectFormat(ctx.DefaultQuery("format", services.FormatCSV), "")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == services.FormatJSON {
+		contentType = "application/json"
+	}
+	filename := fmt.Sprintf("catalog-%s.%s", time.Now().Format("20060102"), format)
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+	// 串流輸出，途中發生錯誤時已無法更改狀態碼，只能記錄
+	if err := c.catalogService.Export(format, ctx.Writer); err != nil {
+		ctx.Error(err)
+	}
+}
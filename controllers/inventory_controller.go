@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryController struct {
+	inventoryService *services.InventoryService
+}
+
+func NewInventoryController(inventoryService *services.InventoryService) *InventoryController {
+	return &InventoryController{
+		inventoryService: inventoryService,
+	}
+}
+
+type CreateWarehouseRequest struct {
+	Code string `json:"code" binding:"required,max=32" example:"TPE-1"`
+	Name string `json:"name" binding:"required" example:"Taipei warehouse"`
+}
+
+type RecordMovementRequest struct {
+	ProductID   uint   `json:"product_id" binding:"required" example:"1"`
+	WarehouseID uint   `json:"warehouse_id" binding:"required" example:"1"`
+	Type        string `json:"type" binding:"required,oneof=receive adjust return write_off" example:"receive"`
+	// Quantity is signed for adjustments, negative for write-offs and
+	// positive otherwise
+	Quantity  int    `json:"quantity" binding:"required" example:"50"`
+	Reference string `json:"reference" example:"PO-2024-001"`
+	Note      string `json:"note" example:"Weekly delivery"`
+}
+
+func inventoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrWarehouseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidMovement), errors.Is(err, services.ErrInvalidQuantity):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List warehouses
+// @Description List all warehouses (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Warehouse "Warehouses"
+// @Router /inventory/warehouses [get]
+func (c *InventoryController) ListWarehouses(ctx *gin.Context) {
+	warehouses, err := c.inventoryService.ListWarehouses()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list warehouses"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, warehouses)
+}
+
+// @Summary Create warehouse
+// @Description Create a warehouse (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateWarehouseRequest true "Warehouse"
+// @Success 201 {object} models.Warehouse "Created warehouse"
+// @Failure 400 {object} map[string]string "Invalid input or duplicate code"
+// @Router /inventory/warehouses [post]
+func (c *InventoryController) CreateWarehouse(ctx *gin.Context) {
+	var req CreateWarehouseRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warehouse, err := c.inventoryService.CreateWarehouse(req.Code, req.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, warehouse)
+}
+
+// @Summary Get stock levels
+// @Description Get on-hand and reserved stock of a product per warehouse (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {array} models.StockLevel "Stock levels"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /inventory/products/{id} [get]
+func (c *InventoryController) StockLevels(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	levels, err := c.inventoryService.StockLevels(id)
+	if err != nil {
+		ctx.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, levels)
+}
+
+// @Summary List stock movements
+// @Description List the stock ledger of a product, newest first (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.StockMovement "Stock movements"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /inventory/products/{id}/movements [get]
+func (c *InventoryController) Movements(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	movements, err := c.inventoryService.Movements(id, page, pageSize)
+	if err != nil {
+		ctx.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, movements)
+}
+
+// @Summary Record stock movement
+// @Description Receive, return, write off or adjust stock in a warehouse (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body RecordMovementRequest true "Stock movement"
+// @Success 201 {object} models.StockMovement "Recorded movement"
+// @Failure 400 {object} map[string]string "Invalid movement"
+// @Failure 404 {object} map[string]string "Product or warehouse not found"
+// @Failure 409 {object} map[string]string "Adjustment would drop stock below reserved"
+// @Router /inventory/movements [post]
+func (c *InventoryController) RecordMovement(ctx *gin.Context) {
+	var req RecordMovementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	movement, err := c.inventoryService.RecordMovement(currentUser.ID, req.ProductID, req.WarehouseID, req.Type, req.Quantity, req.Reference, req.Note)
+	if err != nil {
+		ctx.JSON(inventoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, movement)
+}
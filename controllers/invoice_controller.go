@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceController struct {
+	invoiceService *services.InvoiceService
+}
+
+func NewInvoiceController(invoiceService *services.InvoiceService) *InvoiceController {
+	return &InvoiceController{
+		invoiceService: invoiceService,
+	}
+}
+
+func invoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrInvoiceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrOrderNotPaid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrEInvoiceFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List order invoices
+// @Description List the invoice and credit notes of an order, oldest first. Customers see their own orders; staff see all.
+// @Tags invoices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} models.Invoice "Invoices and credit notes"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /orders/{id}/invoices [get]
+func (c *InvoiceController) List(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	invoices, err := c.invoiceService.ForOrder(&currentUser, id)
+	if err != nil {
+		ctx.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invoices)
+}
+
+// @Summary Issue order invoice
+// @Description Issue the invoice of a paid order that has none yet, e.g. when the e-invoice provider was unavailable at payment (staff only). Returns the existing invoice if there is one.
+// @Tags invoices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Invoice "Invoice"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 422 {object} map[string]string "Order is not paid"
+// @Failure 502 {object} map[string]string "E-invoice provider error"
+// @Router /admin/orders/{id}/invoices [post]
+func (c *InvoiceController) Issue(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	invoice, err := c.invoiceService.IssueForOrder(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invoice)
+}
+
+// @Summary Get invoice
+// @Description Get an invoice or credit note. Customers see their own; staff see all.
+// @Tags invoices
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.Invoice "Invoice or credit note"
+// @Failure 404 {object} map[string]string "Invoice not found"
+// @Router /invoices/{id} [get]
+func (c *InvoiceController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	invoice, err := c.invoiceService.Get(&currentUser, id)
+	if err != nil {
+		ctx.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invoice)
+}
+
+// @Summary Download invoice
+// @Description Download an invoice or credit note as PDF. Customers download their own; staff download all.
+// @Tags invoices
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path int true "Invoice ID"
+// @Success 200 {file} binary "PDF document"
+// @Failure 404 {object} map[string]string "Invoice not found"
+// @Router /invoices/{id}/pdf [get]
+func (c *InvoiceController) Download(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	invoice, doc, err := c.invoiceService.PDF(&currentUser, id)
+	if err != nil {
+		ctx.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+	ctx.Data(http.StatusOK, "application/pdf", doc)
+}
@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/services"
+	"e-commerce/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxFilesPerUpload = 10
+	// multipartOverhead leaves room for boundaries and headers on top of the file size limit
+	multipartOverhead = 1 << 20
+)
+
+type MediaController struct {
+	mediaService   *services.MediaService
+	productService *services.ProductService
+}
+
+func NewMediaController(mediaService *services.MediaService, productService *services.ProductService) *MediaController {
+	return &MediaController{
+		mediaService:   mediaService,
+		productService: productService,
+	}
+}
+
+type ReorderImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1" example:"3,1,2"`
+}
+
+func mediaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFileTooLarge), errors.Is(err, services.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrDuplicateImage):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// @Summary Upload product images
+// @Description Upload one or more images for a product (staff only). Thumbnails and WebP variants are generated automatically.
+// @Tags media
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param file formData file true "Image file (repeat the field for multiple images)"
+// @Success 201 {array} models.ProductImage "Uploaded images"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "Unsupported image type"
+// @Router /products/{id}/images [post]
+func (c *MediaController) Upload(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	limit := c.mediaService.MaxUploadSize()*maxFilesPerUpload + multipartOverhead
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrFileTooLarge.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if len(files) > maxFilesPerUpload {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Too many files in one request"})
+		return
+	}
+
+	uploaded := make([]models.ProductImage, 0, len(files))
+	for _, fh := range files {
+		if fh.Size > c.mediaService.MaxUploadSize() {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrFileTooLarge.Error(), "file": fh.Filename})
+			return
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload", "file": fh.Filename})
+			return
+		}
+		image, err := c.mediaService.Upload(ctx.Request.Context(), productID, f)
+		f.Close()
+		if err != nil {
+			ctx.JSON(mediaErrorStatus(err), gin.H{"error": err.Error(), "file": fh.Filename})
+			return
+		}
+		uploaded = append(uploaded, *image)
+	}
+
+	ctx.JSON(http.StatusCreated, uploaded)
+}
+
+// @Summary List product images
+// @Description List the images of a product in display order. Like the product itself, images of unpublished products are only listed for staff or with a preview token.
+// @Tags media
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param preview_token query string false "Preview token of an unpublished product"
+// @Success 200 {array} models.ProductImage "Images"
+// @Failure 403 {object} map[string]string "Invalid or expired preview token"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id}/images [get]
+func (c *MediaController) List(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	if _, err := findProduct(ctx, c.productService, productID); err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := c.mediaService.List(ctx.Request.Context(), productID)
+	if err != nil {
+		ctx.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+// @Summary Reorder product images
+// @Description Set the display order of all images of a product (staff only)
+// @Tags media
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body ReorderImagesRequest true "Image IDs in the desired order"
+// @Success 200 {array} models.ProductImage "Images in their new order"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id}/images/order [put]
+func (c *MediaController) Reorder(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := c.mediaService.Reorder(ctx.Request.Context(), productID, req.ImageIDs)
+	if err != nil {
+		ctx.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+// @Summary Delete product image
+// @Description Delete an image and its variants (staff only)
+// @Tags media
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param imageId path int true "Image ID"
+// @Success 200 {object} map[string]string "Image deleted"
+// @Failure 404 {object} map[string]string "Image not found"
+// @Router /products/{id}/images/{imageId} [delete]
+func (c *MediaController) Delete(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	imageID, ok := parseIDParam(ctx, "imageId")
+	if !ok {
+		return
+	}
+
+	if err := c.mediaService.Delete(ctx.Request.Context(), productID, imageID); err != nil {
+		ctx.JSON(mediaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
+}
+
+// @Summary Serve media file
+// @Description Serve a product or review image, or a return photo with a signed URL. Signed URLs are verified when a signing key is configured.
+// @Tags media
+// @Produce octet-stream
+// @Param key path string true "Object key"
+// @Param expires query int false "Expiry timestamp of a signed URL"
+// @Param signature query string false "Signature of a signed URL"
+// @Success 200 {file} binary "Media content"
+// @Failure 403 {object} map[string]string "Invalid or expired signature"
+// @Failure 404 {object} map[string]string "Not found"
+// @Router /media/{key} [get]
+func (c *MediaController) Serve(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	if err := c.mediaService.VerifyURL(key, ctx.Request.URL.Query()); errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	rc, err := c.mediaService.Open(ctx.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer rc.Close()
+
+	// 物件以內容雜湊命名，內容不會變動，因此可長期快取
+	cacheControl := "public, max-age=31536000, immutable"
+	if ctx.Query("signature") != "" {
+		cacheControl = "private, max-age=3600"
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ctx.DataFromReader(http.StatusOK, -1, contentType, rc, map[string]string{
+		"Cache-Control":          cacheControl,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
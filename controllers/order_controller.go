@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderController struct {
+	checkoutService     *services.CheckoutService
+	orderService        *services.OrderService
+	cancellationService *services.CancellationService
+}
+
+func NewOrderController(checkoutService *services.CheckoutService, orderService *services.OrderService, cancellationService *services.CancellationService) *OrderController {
+	return &OrderController{
+		checkoutService:     checkoutService,
+		orderService:        orderService,
+		cancellationService: cancellationService,
+	}
+}
+
+type AddressRequest struct {
+	Name       string `json:"name" binding:"required,max=100" example:"王小明"`
+	Phone      string `json:"phone" binding:"max=32" example:"0912345678"`
+	Line1      string `json:"line1" binding:"required,max=255" example:"信義路五段7號"`
+	Line2      string `json:"line2" binding:"max=255" example:"89樓"`
+	City       string `json:"city" binding:"required,max=100" example:"台北市"`
+	PostalCode string `json:"postal_code" binding:"required,max=16" example:"110"`
+	Country    string `json:"country" binding:"required,len=2" example:"TW"`
+}
+
+func (r AddressRequest) toAddress() models.Address {
+	return models.Address{
+		Name:       strings.TrimSpace(r.Name),
+		Phone:      strings.TrimSpace(r.Phone),
+		Line1:      strings.TrimSpace(r.Line1),
+		Line2:      strings.TrimSpace(r.Line2),
+		City:       strings.TrimSpace(r.City),
+		PostalCode: strings.TrimSpace(r.PostalCode),
+		Country:    strings.ToUpper(r.Country),
+	}
+}
+
+// EInvoiceRequest is how an order in Taiwan takes its e-invoice: member
+// carrier (the default), mobile barcode carrier, donation by love code or a
+// company invoice with its unified business number
+type EInvoiceRequest struct {
+	Type     string `json:"type" binding:"omitempty,oneof=member mobile donation company" example:"mobile"`
+	Carrier  string `json:"carrier" binding:"max=64" example:"/ABC+123"`
+	LoveCode string `json:"love_code" binding:"max=7" example:"168001"`
+	TaxID    string `json:"tax_id" binding:"max=8" example:"22099131"`
+	Title    string `json:"title" binding:"max=100" example:"台灣積體電路製造股份有限公司"`
+}
+
+// CheckoutRequest takes addresses inline or by ID from the address book.
+// Without either, the default addresses are used.
+type CheckoutRequest struct {
+	ShippingAddress   *AddressRequest `json:"shipping_address"`
+	ShippingAddressID uint            `json:"shipping_address_id" example:"1"`
+	BillingAddress    *AddressRequest `json:"billing_address"`
+	BillingAddressID  uint            `json:"billing_address_id" example:"2"`
+	// ShippingMethodID is one of the methods of GET /shipping/quote
+	ShippingMethodID uint             `json:"shipping_method_id" example:"1"`
+	EInvoice         *EInvoiceRequest `json:"einvoice"`
+	Note             string           `json:"note" binding:"max=500" example:"Please ring the bell"`
+}
+
+type CancelOrderRequest struct {
+	Reason string `json:"reason" binding:"max=500" example:"Ordered the wrong size"`
+}
+
+type OrderStatusRequest struct {
+	Status         string `json:"status" binding:"required" example:"shipped"`
+	Reason         string `json:"reason" binding:"max=500" example:"Customer asked to cancel"`
+	Carrier        string `json:"carrier" binding:"max=64" example:"black-cat"`
+	TrackingNumber string `json:"tracking_number" binding:"max=64" example:"9056-1234-5678"`
+}
+
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrAddressNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrIdempotencyKeyRequired), errors.Is(err, services.ErrInvalidOrderStatus),
+		errors.Is(err, services.ErrShippingMethodRequired), errors.Is(err, services.ErrAddressRequired),
+		errors.Is(err, services.ErrInvalidAddress), errors.Is(err, services.ErrInvalidEInvoice):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTransitionForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrIdempotencyKeyReused), errors.Is(err, services.ErrCartEmpty),
+		errors.Is(err, services.ErrPaymentOverdue), errors.Is(err, services.ErrTrackingNumberRequired),
+		errors.Is(err, services.ErrReasonRequired), errors.Is(err, services.ErrNotFullyRefunded),
+		errors.Is(err, services.ErrShippingUnavailable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrCartInvalid), errors.Is(err, services.ErrInsufficientStock),
+		errors.Is(err, services.ErrIllegalTransition), errors.Is(err, services.ErrOrderStatusChanged),
+		errors.Is(err, services.ErrCouponUsedUp), errors.Is(err, services.ErrCouponLimitPerUser),
+		errors.Is(err, services.ErrCancellationClosed), errors.Is(err, services.ErrReservationNotFound),
+		errors.Is(err, services.ErrReturnExceedsSold):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPaymentFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary Checkout
+// @Description Turn the current user's cart into an order, priced in the currency selected by Accept-Currency, and hold its stock until payment is due. Retrying with the same Idempotency-Key returns the original order.
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string true "Unique key per checkout attempt"
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param request body CheckoutRequest true "Addresses or address book IDs, shipping method, e-invoice and note"
+// @Success 201 {object} models.Order "Order placed"
+// @Success 200 {object} models.Order "Order placed earlier with the same key"
+// @Failure 400 {object} map[string]string "Missing Idempotency-Key, address, shipping method, invalid e-invoice details or invalid input"
+// @Failure 404 {object} map[string]string "Address not in the address book"
+// @Failure 409 {object} map[string]string "Cart has items or a coupon that cannot be checked out"
+// @Failure 422 {object} map[string]string "Empty cart, shipping method not available or key reused with different details"
+// @Router /checkout [post]
+func (c *OrderController) Checkout(ctx *gin.Context) {
+	var req CheckoutRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	details := services.CheckoutDetails{
+		ShippingAddressID: req.ShippingAddressID,
+		BillingAddressID:  req.BillingAddressID,
+		ShippingMethodID:  req.ShippingMethodID,
+		Note:              req.Note,
+	}
+	if req.ShippingAddress != nil {
+		details.ShippingAddress = req.ShippingAddress.toAddress()
+	}
+	if req.EInvoice != nil {
+		details.EInvoice = models.EInvoiceBuyer{
+			Type:     req.EInvoice.Type,
+			Carrier:  req.EInvoice.Carrier,
+			LoveCode: req.EInvoice.LoveCode,
+			TaxID:    req.EInvoice.TaxID,
+			Title:    req.EInvoice.Title,
+		}
+	}
+	if req.BillingAddress != nil {
+		billing := req.BillingAddress.toAddress()
+		details.BillingAddress = &billing
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	order, created, err := c.checkoutService.Checkout(currentUser, ctx.GetHeader("Idempotency-Key"), details, priceList)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if !created {
+		ctx.Header("Idempotent-Replayed", "true")
+		ctx.JSON(http.StatusOK, order)
+		return
+	}
+	ctx.JSON(http.StatusCreated, order)
+}
+
+// @Summary List orders
+// @Description List the current user's orders, newest first
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Order "Orders"
+// @Router /orders [get]
+func (c *OrderController) List(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	currentUser := ctx.MustGet("user").(models.User)
+	orders, err := c.orderService.List(currentUser.ID, page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list orders"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, orders)
+}
+
+// @Summary Get order
+// @Description Get one of the current user's orders
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order "Order"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /orders/{id} [get]
+func (c *OrderController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	order, err := c.orderService.Get(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+// @Summary Cancel order
+// @Description Cancel one of the current user's orders before fulfillment starts, or within the cancellation window after checkout (ORDER_CANCELLATION_WINDOW, 1h by default). Reserved or sold stock is put back, coupons can be used again and a captured payment is refunded in full.
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body CancelOrderRequest false "Why the order is cancelled"
+// @Success 200 {object} models.Order "Cancelled order"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 409 {object} map[string]string "Order can no longer be cancelled"
+// @Failure 502 {object} map[string]string "Order cancelled but the refund failed at the payment provider"
+// @Router /orders/{id}/cancel [post]
+func (c *OrderController) Cancel(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req CancelOrderRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	// 顧客只能取消自己的訂單，即使是員工帳號也走顧客的權限
+	currentUser.Role = models.RoleCustomer
+	order, err := c.cancellationService.Cancel(ctx.Request.Context(), &currentUser, id, req.Reason)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+// @Summary Order status history
+// @Description List the status changes of an order, oldest first. Customers see their own orders; staff see all.
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} models.OrderStatusChange "Status changes"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /orders/{id}/history [get]
+func (c *OrderController) History(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	changes, err := c.orderService.History(&currentUser, id)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
+
+// @Summary List all orders
+// @Description List every order, newest first, optionally filtered by status (staff only)
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Order status" Enums(pending_payment, paid, fulfilling, shipped, delivered, cancelled, refunded)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Order "Orders"
+// @Failure 400 {object} map[string]string "Invalid status"
+// @Router /admin/orders [get]
+func (c *OrderController) ListAll(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	orders, err := c.orderService.ListAll(ctx.Query("status"), page, pageSize)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, orders)
+}
+
+// @Summary Get any order
+// @Description Get an order of any customer (staff only)
+// @Tags orders
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order "Order"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /admin/orders/{id} [get]
+func (c *OrderController) GetAny(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	order, err := c.orderService.GetAny(id)
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+// @Summary Change order status
+// @Description Move an order to another status (staff only). Shipping requires a tracking number. Cancelling a paid order requires a reason, works after the customer's cancellation window has closed and refunds the payment. Orders become refunded only once their payment has been refunded in full.
+// @Tags orders
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body OrderStatusRequest true "New status"
+// @Success 200 {object} models.Order "Updated order"
+// @Failure 400 {object} map[string]string "Invalid status"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 409 {object} map[string]string "Transition not allowed from the current status"
+// @Failure 422 {object} map[string]string "Transition requirements not met"
+// @Failure 502 {object} map[string]string "Order cancelled but the refund failed at the payment provider"
+// @Router /admin/orders/{id}/status [put]
+func (c *OrderController) ChangeStatus(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req OrderStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if req.Status == models.OrderStatusCancelled {
+		order, err := c.cancellationService.Cancel(ctx.Request.Context(), &currentUser, id, req.Reason)
+		if err != nil {
+			ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, order)
+		return
+	}
+
+	order, err := c.orderService.ChangeStatus(&currentUser, id, services.StatusChange{
+		To:             req.Status,
+		Reason:         req.Reason,
+		Carrier:        req.Carrier,
+		TrackingNumber: req.TrackingNumber,
+	})
+	if err != nil {
+		ctx.JSON(orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentController struct {
+	paymentService *services.PaymentService
+}
+
+func NewPaymentController(paymentService *services.PaymentService) *PaymentController {
+	return &PaymentController{
+		paymentService: paymentService,
+	}
+}
+
+type PayRequest struct {
+	CardNumber string `json:"card_number" binding:"required,max=32" example:"4242424242424242"`
+}
+
+type SimulateChallengeRequest struct {
+	Approve bool `json:"approve" example:"true"`
+}
+
+func paymentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrPaymentNotFound),
+		errors.Is(err, services.ErrChallengeNotFaked), errors.Is(err, services.ErrPaymentMethodNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidCardNumber), errors.Is(err, services.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, services.ErrOrderNotPayable), errors.Is(err, services.ErrNoPendingChallenge),
+		errors.Is(err, services.ErrOrderStatusChanged):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPaymentOverdue):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrPaymentFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary Pay for order
+// @Description Charge the order total to a card. With the fake provider the card number picks the outcome: 4242424242424242 succeeds, 4000000000000002 and 4000000000009995 are declined, 4000000000003220 requires 3-D Secure and 4000000000000119 fails with a processing error.
+// @Tags payments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body PayRequest true "Card"
+// @Success 201 {object} models.Payment "Payment captured, or waiting for 3-D Secure when status is requires_action"
+// @Failure 402 {object} map[string]string "Card declined"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Failure 409 {object} map[string]string "Order is not awaiting payment"
+// @Failure 502 {object} map[string]string "Payment provider error"
+// @Router /orders/{id}/payments [post]
+func (c *PaymentController) Pay(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req PayRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	payment, err := c.paymentService.Pay(ctx.Request.Context(), currentUser.ID, id, req.CardNumber)
+	if err != nil {
+		ctx.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, payment)
+}
+
+// @Summary List order payments
+// @Description List the payment attempts of an order, oldest first. Customers see their own orders; staff see all.
+// @Tags payments
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} models.Payment "Payments"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /orders/{id}/payments [get]
+func (c *PaymentController) List(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	payments, err := c.paymentService.ForOrder(&currentUser, id)
+	if err != nil {
+		ctx.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, payments)
+}
+
+// @Summary Payment webhook
+// @Description Receive a signed notification from the payment provider. Events are applied at most once.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]string "Event accepted"
+// @Failure 400 {object} map[string]string "Invalid or expired signature"
+// @Router /payments/webhook [post]
+func (c *PaymentController) Webhook(ctx *gin.Context) {
+	payload, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+
+	if err := c.paymentService.HandleWebhook(ctx.Request.Context(), payload, ctx.Request.Header); err != nil {
+		ctx.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// @Summary Simulate 3-D Secure
+// @Description Complete or fail a pending 3-D Secure challenge. Only available with the fake payment provider.
+// @Tags payments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Payment ID"
+// @Param request body SimulateChallengeRequest true "Whether the customer passes the challenge"
+// @Success 200 {object} models.Payment "Updated payment"
+// @Failure 404 {object} map[string]string "Payment not found or provider is not fake"
+// @Failure 409 {object} map[string]string "Payment is not waiting for 3-D Secure"
+// @Router /payments/{id}/simulate-3ds [post]
+func (c *PaymentController) SimulateChallenge(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req SimulateChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	payment, err := c.paymentService.SimulateChallenge(ctx.Request.Context(), currentUser.ID, id, req.Approve)
+	if err != nil {
+		ctx.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, payment)
+}
+
+// @Summary Save payment method
+// @Description Save a card at the payment provider so it can be charged later, e.g. for subscription renewals. Only the last four digits are kept.
+// @Tags payments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PayRequest true "Card"
+// @Success 201 {object} models.PaymentMethod "Saved payment method"
+// @Failure 400 {object} map[string]string "Invalid card number"
+// @Failure 502 {object} map[string]string "Payment provider error"
+// @Router /payment-methods [post]
+func (c *PaymentController) SaveMethod(ctx *gin.Context) {
+	var req PayRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	method, err := c.paymentService.SaveMethod(ctx.Request.Context(), currentUser.ID, req.CardNumber)
+	if err != nil {
+		ctx.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, method)
+}
+
+// @Summary List payment methods
+// @Description List the current user's saved payment methods
+// @Tags payments
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.PaymentMethod "Saved payment methods"
+// @Router /payment-methods [get]
+func (c *PaymentController) ListMethods(ctx *gin.Context) {
+	currentUser := ctx.MustGet("user").(models.User)
+	methods, err := c.paymentService.Methods(currentUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list payment methods"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, methods)
+}
+
+// @Summary Delete payment method
+// @Description Forget a saved payment method. Subscriptions charged to it stop renewing until they are given another one.
+// @Tags payments
+// @Security BearerAuth
+// @Param id path int true "Payment method ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "Payment method not found"
+// @Router /payment-methods/{id} [delete]
+func (c *PaymentController) DeleteMethod(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.paymentService.DeleteMethod(currentUser.ID, id); err != nil {
+		ctx.JSON(paymentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
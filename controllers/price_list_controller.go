@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PriceListController struct {
+	pricingService *services.PricingService
+}
+
+func NewPriceListController(pricingService *services.PricingService) *PriceListController {
+	return &PriceListController{
+		pricingService: pricingService,
+	}
+}
+
+type CreatePriceListRequest struct {
+	Code      string `json:"code" binding:"required,max=32" example:"US-USD"`
+	Name      string `json:"name" binding:"required" example:"United States"`
+	Currency  string `json:"currency" binding:"required,len=3" example:"USD"`
+	Market    string `json:"market" binding:"omitempty,len=2" example:"US"`
+	IsDefault bool   `json:"is_default" example:"false"`
+	Active    bool   `json:"active" example:"true"`
+}
+
+type UpdatePriceListRequest struct {
+	Name      string `json:"name" binding:"required" example:"United States"`
+	Market    string `json:"market" binding:"omitempty,len=2" example:"US"`
+	IsDefault bool   `json:"is_default" example:"false"`
+	Active    bool   `json:"active" example:"true"`
+}
+
+type SetPriceRequest struct {
+	// Amount is a decimal string in the price list currency
+	Amount string `json:"amount" binding:"required" example:"1200"`
+}
+
+func pricingErrorStatus(err error) int {
+	if errors.Is(err, services.ErrPriceListNotFound) || errors.Is(err, services.ErrProductNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// @Summary List price lists
+// @Description List all price lists (staff only)
+// @Tags pricing
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.PriceList "Price lists"
+// @Router /price-lists [get]
+func (c *PriceListController) List(ctx *gin.Context) {
+	priceLists, err := c.pricingService.ListPriceLists()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list price lists"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, priceLists)
+}
+
+// @Summary Create price list
+// @Description Create a price list for a currency and market (staff only)
+// @Tags pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreatePriceListRequest true "Price list"
+// @Success 201 {object} models.PriceList "Created price list"
+// @Failure 400 {object} map[string]string "Invalid input or unsupported currency"
+// @Router /price-lists [post]
+func (c *PriceListController) Create(ctx *gin.Context) {
+	var req CreatePriceListRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	priceList, err := c.pricingService.CreatePriceList(req.Code, req.Name, req.Currency, req.Market, req.IsDefault, req.Active)
+	if err != nil {
+		ctx.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, priceList)
+}
+
+// @Summary Update price list
+// @Description Update a price list; its currency cannot change (staff only)
+// @Tags pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Price list ID"
+// @Param request body UpdatePriceListRequest true "Price list"
+// @Success 200 {object} models.PriceList "Updated price list"
+// @Failure 404 {object} map[string]string "Price list not found"
+// @Router /price-lists/{id} [put]
+func (c *PriceListController) Update(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdatePriceListRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	priceList, err := c.pricingService.UpdatePriceList(id, req.Name, req.Market, req.IsDefault, req.Active)
+	if err != nil {
+		ctx.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, priceList)
+}
+
+// @Summary Set product price
+// @Description Set the price of a product in a price list (staff only)
+// @Tags pricing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Price list ID"
+// @Param productId path int true "Product ID"
+// @Param request body SetPriceRequest true "Price"
+// @Success 200 {object} models.ProductPrice "Saved price"
+// @Failure 400 {object} map[string]string "Invalid amount"
+// @Failure 404 {object} map[string]string "Price list or product not found"
+// @Router /price-lists/{id}/prices/{productId} [put]
+func (c *PriceListController) SetPrice(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	var req SetPriceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	price, err := c.pricingService.SetPrice(id, productID, req.Amount)
+	if err != nil {
+		ctx.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, price)
+}
+
+// @Summary Delete product price
+// @Description Remove a product from a price list (staff only)
+// @Tags pricing
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Price list ID"
+// @Param productId path int true "Product ID"
+// @Success 200 {object} map[string]string "Price deleted"
+// @Failure 404 {object} map[string]string "Price list not found"
+// @Router /price-lists/{id}/prices/{productId} [delete]
+func (c *PriceListController) DeletePrice(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	if err := c.pricingService.DeletePrice(id, productID); err != nil {
+		ctx.JSON(pricingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Price deleted"})
+}
@@ -0,0 +1,463 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductController struct {
+	productService *services.ProductService
+	mediaService   *services.MediaService
+	pricingService *services.PricingService
+}
+
+func NewProductController(productService *services.ProductService, mediaService *services.MediaService, pricingService *services.PricingService) *ProductController {
+	return &ProductController{
+		productService: productService,
+		mediaService:   mediaService,
+		pricingService: pricingService,
+	}
+}
+
+// attachPrices fills in prices from the price list chosen by PricingMiddleware
+func (c *ProductController) attachPrices(ctx *gin.Context, products []models.Product) error {
+	priceList, exists := ctx.Get("price_list")
+	if !exists {
+		return nil
+	}
+	return c.pricingService.AttachPrices(priceList.(*models.PriceList), products)
+}
+
+type CreateProductRequest struct {
+	SKU         string `json:"sku" binding:"required,max=64" example:"COFFEE-001"`
+	Name        string `json:"name" binding:"required" example:"Ethiopia Yirgacheffe 250g"`
+	Description string `json:"description" example:"Light roast with floral notes"`
+	Category    string `json:"category" binding:"max=64" example:"coffee"`
+	TaxClass    string `json:"tax_class" binding:"omitempty,oneof=standard reduced zero exempt" example:"standard"`
+	WeightGrams int    `json:"weight_grams" binding:"min=0" example:"250"`
+}
+
+type UpdateProductRequest struct {
+	Name        string `json:"name" binding:"required" example:"Ethiopia Yirgacheffe 250g"`
+	Description string `json:"description" example:"Light roast with floral notes"`
+	Category    string `json:"category" binding:"max=64" example:"coffee"`
+	TaxClass    string `json:"tax_class" binding:"omitempty,oneof=standard reduced zero exempt" example:"standard"`
+	WeightGrams int    `json:"weight_grams" binding:"min=0" example:"250"`
+}
+
+type ChangeProductStatusRequest struct {
+	Status      string     `json:"status" binding:"required,oneof=draft in_review scheduled published archived" example:"scheduled"`
+	PublishAt   *time.Time `json:"publish_at" example:"2024-01-01T00:00:00Z"`
+	UnpublishAt *time.Time `json:"unpublish_at" example:"2024-02-01T00:00:00Z"`
+}
+
+type PreviewTokenResponse struct {
+	Token     string    `json:"token" example:"1704067200.5f2b..."`
+	ExpiresAt time.Time `json:"expires_at" example:"2024-01-01T00:00:00Z"`
+	URL       string    `json:"url" example:"/api/v1/products/1?preview_token=1704067200.5f2b..."`
+}
+
+// isStaff reports whether the request was made by a signed-in staff member
+func isStaff(ctx *gin.Context) bool {
+	user, exists := ctx.Get("user")
+	if !exists {
+		return false
+	}
+	currentUser := user.(models.User)
+	return currentUser.IsStaff()
+}
+
+// findProduct loads a product for the request: staff see any product, a
+// preview token shows an unpublished one and everyone else only sees
+// visible products
+func findProduct(ctx *gin.Context, productService *services.ProductService, id uint) (*models.Product, error) {
+	switch token := ctx.Query("preview_token"); {
+	case isStaff(ctx):
+		return productService.GetByID(id)
+	case token != "":
+		// 預覽內容不可被共用快取
+		ctx.Header("Cache-Control", "private, no-store")
+		return productService.GetPreview(id, token)
+	default:
+		return productService.GetVisible(id)
+	}
+}
+
+func productErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrRevisionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidTransition):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidPreviewToken):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// parseIDParam reads a numeric path parameter
+func parseIDParam(ctx *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// @Summary List products
+// @Description List published products with pagination, priced in the currency selected by Accept-Currency. Staff see products in every status.
+// @Tags products
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param status query string false "Filter by status (staff only)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Product "Products"
+// @Router /products [get]
+func (c *ProductController) List(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	var products []models.Product
+	var err error
+	if isStaff(ctx) {
+		products, err = c.productService.List(ctx.Query("status"), page, pageSize)
+	} else {
+		products, err = c.productService.ListVisible(page, pageSize)
+	}
+	if err == nil {
+		err = c.attachPrices(ctx, products)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list products"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
+
+// @Summary Get product
+// @Description Get a product with its images, priced in the currency selected by Accept-Currency. Unpublished products are only shown to staff or with a preview token.
+// @Tags products
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param id path int true "Product ID"
+// @Param preview_token query string false "Preview token for an unpublished product"
+// @Success 200 {object} models.Product "Product"
+// @Failure 403 {object} map[string]string "Invalid preview token"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id} [get]
+func (c *ProductController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	product, err := findProduct(ctx, c.productService, id)
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := c.mediaService.AttachURLs(ctx.Request.Context(), product.Images); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve image URLs"})
+		return
+	}
+	products := []models.Product{*product}
+	if err := c.attachPrices(ctx, products); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve price"})
+		return
+	}
+	product = &products[0]
+
+	ctx.JSON(http.StatusOK, product)
+}
+
+// @Summary Create product
+// @Description Create a catalog product (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateProductRequest true "Product details"
+// @Success 201 {object} models.Product "Created product"
+// @Failure 400 {object} map[string]string "Invalid input or SKU already exists"
+// @Failure 403 {object} map[string]string "Insufficient permissions"
+// @Router /products [post]
+func (c *ProductController) Create(ctx *gin.Context) {
+	var req CreateProductRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	product, err := c.productService.Create(currentUser, req.SKU, services.ProductDetails{
+		Name:        req.Name,
+		Description: req.Description,
+		Category:    req.Category,
+		TaxClass:    req.TaxClass,
+		WeightGrams: req.WeightGrams,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, product)
+}
+
+// @Summary Update product
+// @Description Update a catalog product (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body UpdateProductRequest true "Product details"
+// @Success 200 {object} models.Product "Updated product"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id} [put]
+func (c *ProductController) Update(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateProductRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	product, err := c.productService.Update(currentUser, id, services.ProductDetails{
+		Name:        req.Name,
+		Description: req.Description,
+		Category:    req.Category,
+		TaxClass:    req.TaxClass,
+		WeightGrams: req.WeightGrams,
+	})
+	if errors.Is(err, services.ErrProductNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
+
+// @Summary Delete product
+// @Description Delete a catalog product (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]string "Product deleted"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id} [delete]
+func (c *ProductController) Delete(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := c.productService.Delete(id); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
+}
+
+// @Summary Change product status
+// @Description Move a product through the publishing workflow, optionally scheduling publish and unpublish times (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body ChangeProductStatusRequest true "New status"
+// @Success 200 {object} models.Product "Updated product"
+// @Failure 400 {object} map[string]string "Invalid schedule"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Failure 409 {object} map[string]string "Transition not allowed"
+// @Router /products/{id}/status [put]
+func (c *ProductController) ChangeStatus(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ChangeProductStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	product, err := c.productService.ChangeStatus(currentUser, id, req.Status, req.PublishAt, req.UnpublishAt)
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
+
+// @Summary Create preview link
+// @Description Create a signed link to view an unpublished product without signing in (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 201 {object} PreviewTokenResponse "Preview token"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id}/preview-token [post]
+func (c *ProductController) CreatePreviewToken(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	token, expiresAt, err := c.productService.PreviewToken(id)
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, PreviewTokenResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		URL:       "/api/v1/products/" + strconv.FormatUint(uint64(id), 10) + "?preview_token=" + token,
+	})
+}
+
+// @Summary List product revisions
+// @Description List the revision history of a product, newest first (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.ProductRevision "Revisions"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id}/revisions [get]
+func (c *ProductController) ListRevisions(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	revisions, err := c.productService.Revisions(id, page, pageSize)
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+// @Summary Get product revision
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param version path int true "Revision version"
+// @Success 200 {object} models.ProductRevision "Revision"
+// @Failure 404 {object} map[string]string "Revision not found"
+// @Router /products/{id}/revisions/{version} [get]
+func (c *ProductController) GetRevision(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	version, ok := parseIDParam(ctx, "version")
+	if !ok {
+		return
+	}
+
+	revision, err := c.productService.Revision(id, int(version))
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revision)
+}
+
+// @Summary Compare product revisions
+// @Description List the fields that changed between two revisions (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param from query int true "Older version"
+// @Param to query int true "Newer version"
+// @Success 200 {array} services.FieldChange "Changed fields"
+// @Failure 404 {object} map[string]string "Revision not found"
+// @Router /products/{id}/revisions/diff [get]
+func (c *ProductController) DiffRevisions(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	from, errFrom := strconv.Atoi(ctx.Query("from"))
+	to, errTo := strconv.Atoi(ctx.Query("to"))
+	if errFrom != nil || errTo != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be revision versions"})
+		return
+	}
+
+	changes, err := c.productService.DiffRevisions(id, from, to)
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
+
+// @Summary Roll back product
+// @Description Restore the name, description and category of an earlier revision; the publishing status is unchanged (staff only)
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param version path int true "Revision version to restore"
+// @Success 200 {object} models.Product "Restored product"
+// @Failure 404 {object} map[string]string "Product or revision not found"
+// @Router /products/{id}/revisions/{version}/rollback [post]
+func (c *ProductController) Rollback(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	version, ok := parseIDParam(ctx, "version")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	product, err := c.productService.Rollback(currentUser, id, int(version))
+	if err != nil {
+		ctx.JSON(productErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, product)
+}
@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"e-commerce/money"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionController struct {
+	promotionService *services.PromotionService
+}
+
+func NewPromotionController(promotionService *services.PromotionService) *PromotionController {
+	return &PromotionController{
+		promotionService: promotionService,
+	}
+}
+
+type PromotionTierRequest struct {
+	Threshold string `json:"threshold" binding:"required" example:"2000"`
+	Discount  string `json:"discount" binding:"required" example:"200"`
+}
+
+// PromotionRequest describes a promotion. Amounts are decimal strings in
+// the promotion currency.
+type PromotionRequest struct {
+	Name           string                 `json:"name" binding:"required,max=100" example:"Spend NT$2000 save NT$200"`
+	Description    string                 `json:"description" example:"Automatically applied at checkout"`
+	Type           string                 `json:"type" binding:"required,oneof=percent_off fixed_amount free_shipping buy_x_get_y tiered" example:"tiered"`
+	Active         bool                   `json:"active" example:"true"`
+	Priority       int                    `json:"priority" example:"10"`
+	Exclusive      bool                   `json:"exclusive" example:"false"`
+	RequiresCoupon bool                   `json:"requires_coupon" example:"false"`
+	StartsAt       *time.Time             `json:"starts_at" example:"2024-01-01T00:00:00Z"`
+	EndsAt         *time.Time             `json:"ends_at" example:"2024-02-01T00:00:00Z"`
+	Currency       string                 `json:"currency" binding:"omitempty,len=3" example:"TWD"`
+	Percent        int                    `json:"percent" example:"10"`
+	Amount         string                 `json:"amount" example:"100"`
+	BuyQuantity    int                    `json:"buy_quantity" example:"2"`
+	GetQuantity    int                    `json:"get_quantity" example:"1"`
+	Tiers          []PromotionTierRequest `json:"tiers" binding:"dive"`
+	Categories     []string               `json:"categories" example:"coffee"`
+	SKUs           []string               `json:"skus" example:"COFFEE-001"`
+	CustomerGroups []string               `json:"customer_groups" example:"vip"`
+	MinSubtotal    string                 `json:"min_subtotal" example:"1000"`
+}
+
+func (r PromotionRequest) toDetails() services.PromotionDetails {
+	tiers := make([]services.PromotionTierDetails, len(r.Tiers))
+	for i, t := range r.Tiers {
+		tiers[i] = services.PromotionTierDetails{Threshold: t.Threshold, Discount: t.Discount}
+	}
+	return services.PromotionDetails{
+		Name:           r.Name,
+		Description:    r.Description,
+		Type:           r.Type,
+		Active:         r.Active,
+		Priority:       r.Priority,
+		Exclusive:      r.Exclusive,
+		RequiresCoupon: r.RequiresCoupon,
+		StartsAt:       r.StartsAt,
+		EndsAt:         r.EndsAt,
+		Currency:       r.Currency,
+		Percent:        r.Percent,
+		Amount:         r.Amount,
+		BuyQuantity:    r.BuyQuantity,
+		GetQuantity:    r.GetQuantity,
+		Tiers:          tiers,
+		Categories:     r.Categories,
+		SKUs:           r.SKUs,
+		CustomerGroups: r.CustomerGroups,
+		MinSubtotal:    r.MinSubtotal,
+	}
+}
+
+type CouponRequest struct {
+	Code         string `json:"code" binding:"required,max=64" example:"WELCOME100"`
+	UsageLimit   int    `json:"usage_limit" binding:"min=0" example:"1000"`
+	PerUserLimit int    `json:"per_user_limit" binding:"min=0" example:"1"`
+}
+
+func promotionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPromotionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidPromotion), errors.Is(err, money.ErrUnknownCurrency):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCouponCodeTaken):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List promotions
+// @Description List promotions by descending priority (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Promotion "Promotions"
+// @Router /admin/promotions [get]
+func (c *PromotionController) List(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	promotions, err := c.promotionService.List(page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list promotions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promotions)
+}
+
+// @Summary Get promotion
+// @Description Get a promotion (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Success 200 {object} models.Promotion "Promotion"
+// @Failure 404 {object} map[string]string "Promotion not found"
+// @Router /admin/promotions/{id} [get]
+func (c *PromotionController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	promotion, err := c.promotionService.Get(id)
+	if err != nil {
+		ctx.JSON(promotionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promotion)
+}
+
+// @Summary Create promotion
+// @Description Create a percent-off, fixed-amount, free-shipping, buy X get Y or tiered promotion with optional conditions (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body PromotionRequest true "Promotion"
+// @Success 201 {object} models.Promotion "Created promotion"
+// @Failure 400 {object} map[string]string "Invalid promotion"
+// @Router /admin/promotions [post]
+func (c *PromotionController) Create(ctx *gin.Context) {
+	var req PromotionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := c.promotionService.Create(req.toDetails())
+	if err != nil {
+		ctx.JSON(promotionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, promotion)
+}
+
+// @Summary Update promotion
+// @Description Replace a promotion (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Param request body PromotionRequest true "Promotion"
+// @Success 200 {object} models.Promotion "Updated promotion"
+// @Failure 400 {object} map[string]string "Invalid promotion"
+// @Failure 404 {object} map[string]string "Promotion not found"
+// @Router /admin/promotions/{id} [put]
+func (c *PromotionController) Update(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req PromotionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promotion, err := c.promotionService.Update(id, req.toDetails())
+	if err != nil {
+		ctx.JSON(promotionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, promotion)
+}
+
+// @Summary Delete promotion
+// @Description Delete a promotion and its coupons; placed orders keep their discounts (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Success 200 {object} map[string]string "Promotion deleted"
+// @Failure 404 {object} map[string]string "Promotion not found"
+// @Router /admin/promotions/{id} [delete]
+func (c *PromotionController) Delete(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := c.promotionService.Delete(id); err != nil {
+		ctx.JSON(promotionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Promotion deleted"})
+}
+
+// @Summary List coupons
+// @Description List the coupon codes of a promotion with their usage (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Success 200 {array} models.Coupon "Coupons"
+// @Failure 404 {object} map[string]string "Promotion not found"
+// @Router /admin/promotions/{id}/coupons [get]
+func (c *PromotionController) ListCoupons(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	coupons, err := c.promotionService.Coupons(id)
+	if err != nil {
+		ctx.JSON(promotionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, coupons)
+}
+
+// @Summary Create coupon
+// @Description Add a coupon code with optional global and per-customer usage limits to a promotion that requires a coupon (staff only)
+// @Tags promotions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Promotion ID"
+// @Param request body CouponRequest true "Coupon"
+// @Success 201 {object} models.Coupon "Created coupon"
+// @Failure 400 {object} map[string]string "Invalid coupon"
+// @Failure 404 {object} map[string]string "Promotion not found"
+// @Failure 409 {object} map[string]string "Code already exists"
+// @Router /admin/promotions/{id}/coupons [post]
+func (c *PromotionController) CreateCoupon(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req CouponRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon, err := c.promotionService.CreateCoupon(id, services.CouponDetails{
+		Code:         req.Code,
+		UsageLimit:   req.UsageLimit,
+		PerUserLimit: req.PerUserLimit,
+	})
+	if err != nil {
+		ctx.JSON(promotionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, coupon)
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecommendationController struct {
+	recommendationService *services.RecommendationService
+	pricingService        *services.PricingService
+}
+
+func NewRecommendationController(recommendationService *services.RecommendationService, pricingService *services.PricingService) *RecommendationController {
+	return &RecommendationController{
+		recommendationService: recommendationService,
+		pricingService:        pricingService,
+	}
+}
+
+// @Summary Frequently bought together
+// @Description List products often bought with a product, topped up with best sellers of the same category, priced in the currency selected by Accept-Currency
+// @Tags products
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param id path int true "Product ID"
+// @Param limit query int false "Maximum number of products" default(10)
+// @Success 200 {array} models.Product "Recommended products"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id}/recommendations [get]
+func (c *RecommendationController) List(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+
+	products, err := c.recommendationService.For(id, limit)
+	if errors.Is(err, services.ErrProductNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err == nil {
+		if priceList, exists := ctx.Get("price_list"); exists {
+			err = c.pricingService.AttachPrices(priceList.(*models.PriceList), products)
+		}
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list recommendations"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefundController struct {
+	refundService *services.RefundService
+}
+
+func NewRefundController(refundService *services.RefundService) *RefundController {
+	return &RefundController{
+		refundService: refundService,
+	}
+}
+
+type RefundLineRequest struct {
+	OrderItemID uint `json:"order_item_id" binding:"required" example:"1"`
+	Quantity    int  `json:"quantity" binding:"required,min=1" example:"1"`
+}
+
+type RefundRequest struct {
+	Lines   []RefundLineRequest `json:"lines" binding:"dive"`
+	Amount  string              `json:"amount" example:"450.00"`
+	Restock bool                `json:"restock" example:"true"`
+	Reason  string              `json:"reason" binding:"max=500" example:"Damaged in transit"`
+}
+
+func refundErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrOrderItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRefundAmount), errors.Is(err, services.ErrInvalidQuantity),
+		errors.Is(err, services.ErrRestockWithoutLines):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNothingToRefund), errors.Is(err, services.ErrRefundExceedsCaptured),
+		errors.Is(err, services.ErrRefundExceedsQuantity), errors.Is(err, services.ErrAlreadyRestocked):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrPaymentFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary Refund order
+// @Description Refund a whole order, some of its lines or an arbitrary amount through the payment provider (staff only). Without lines or an amount the rest of the order is refunded. Refunds never exceed the captured amount.
+// @Tags refunds
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body RefundRequest true "What to refund"
+// @Success 201 {object} models.Refund "Refund"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Order or order line not found"
+// @Failure 422 {object} map[string]string "Nothing left to refund"
+// @Failure 502 {object} map[string]string "Payment provider error"
+// @Router /admin/orders/{id}/refunds [post]
+func (c *RefundController) Create(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req RefundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]services.RefundLine, len(req.Lines))
+	for i, line := range req.Lines {
+		lines[i] = services.RefundLine{OrderItemID: line.OrderItemID, Quantity: line.Quantity}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	refund, err := c.refundService.Refund(ctx.Request.Context(), &currentUser, id, services.RefundRequest{
+		Lines:   lines,
+		Amount:  req.Amount,
+		Restock: req.Restock,
+		Reason:  req.Reason,
+	})
+	if err != nil {
+		ctx.JSON(refundErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, refund)
+}
+
+// @Summary List order refunds
+// @Description List the refunds of an order, oldest first. Customers see their own orders; staff see all.
+// @Tags refunds
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} models.Refund "Refunds"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /orders/{id}/refunds [get]
+func (c *RefundController) List(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	refunds, err := c.refundService.ForOrder(&currentUser, id)
+	if err != nil {
+		ctx.JSON(refundErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, refunds)
+}
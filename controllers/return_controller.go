@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReturnController struct {
+	returnService *services.ReturnService
+}
+
+func NewReturnController(returnService *services.ReturnService) *ReturnController {
+	return &ReturnController{
+		returnService: returnService,
+	}
+}
+
+type ReturnLineRequest struct {
+	OrderItemID uint   `json:"order_item_id" binding:"required" example:"1"`
+	Quantity    int    `json:"quantity" binding:"required,min=1" example:"1"`
+	Reason      string `json:"reason" binding:"required,oneof=damaged defective wrong_item not_as_described changed_mind other" example:"damaged"`
+	Comment     string `json:"comment" binding:"max=500" example:"Coffee spilled in the box"`
+}
+
+type ReturnRequest struct {
+	Lines []ReturnLineRequest `json:"lines" binding:"required,min=1,dive"`
+	Note  string              `json:"note" binding:"max=500" example:"The bag arrived torn"`
+}
+
+type ReviewReturnRequest struct {
+	Note string `json:"note" binding:"max=500" example:"Outside the return policy"`
+}
+
+type InspectionLineRequest struct {
+	ReturnItemID uint   `json:"return_item_id" binding:"required" example:"1"`
+	Outcome      string `json:"outcome" binding:"required,oneof=restock write_off" example:"restock"`
+}
+
+type InspectReturnRequest struct {
+	Lines  []InspectionLineRequest `json:"lines" binding:"dive"`
+	Amount string                  `json:"amount" example:"400.00"`
+	Note   string                  `json:"note" binding:"max=500" example:"One bag torn, written off"`
+}
+
+func returnErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrReturnNotFound),
+		errors.Is(err, services.ErrOrderItemNotFound), errors.Is(err, services.ErrReturnItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidReturn), errors.Is(err, services.ErrInvalidQuantity),
+		errors.Is(err, services.ErrInspectionIncomplete), errors.Is(err, services.ErrInvalidRefundAmount):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrReturnChanged):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotReturnable), errors.Is(err, services.ErrReturnExceedsQuantity),
+		errors.Is(err, services.ErrReturnStatus), errors.Is(err, services.ErrTooManyReturnPhotos),
+		errors.Is(err, services.ErrNothingToRefund), errors.Is(err, services.ErrRefundExceedsCaptured),
+		errors.Is(err, services.ErrRefundExceedsQuantity), errors.Is(err, services.ErrReturnExceedsSold),
+		errors.Is(err, services.ErrReservationNotFound):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrFileTooLarge), errors.Is(err, services.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrLabelFailed), errors.Is(err, services.ErrPaymentFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary Request return
+// @Description Ask to send back lines of your own delivered order, giving a reason for each line
+// @Tags returns
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body ReturnRequest true "Lines to return"
+// @Success 201 {object} models.Return "Return"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Order or order line not found"
+// @Failure 422 {object} map[string]string "Order not delivered or lines already returned"
+// @Router /orders/{id}/returns [post]
+func (c *ReturnController) Request(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]services.ReturnLine, len(req.Lines))
+	for i, line := range req.Lines {
+		lines[i] = services.ReturnLine{
+			OrderItemID: line.OrderItemID,
+			Quantity:    line.Quantity,
+			Reason:      line.Reason,
+			Comment:     line.Comment,
+		}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	ret, err := c.returnService.Request(ctx.Request.Context(), &currentUser, id, services.ReturnDetails{Lines: lines, Note: req.Note})
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, ret)
+}
+
+// @Summary List order returns
+// @Description List the returns of an order, oldest first. Customers see their own orders; staff see all.
+// @Tags returns
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {array} models.Return "Returns"
+// @Failure 404 {object} map[string]string "Order not found"
+// @Router /orders/{id}/returns [get]
+func (c *ReturnController) ListForOrder(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	returns, err := c.returnService.ForOrder(ctx.Request.Context(), &currentUser, id)
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, returns)
+}
+
+// @Summary Get return
+// @Description Get a return with its lines, photos and label. Customers see their own; staff see all.
+// @Tags returns
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Return ID"
+// @Success 200 {object} models.Return "Return"
+// @Failure 404 {object} map[string]string "Return not found"
+// @Router /returns/{id} [get]
+func (c *ReturnController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	ret, err := c.returnService.Get(ctx.Request.Context(), &currentUser, id)
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ret)
+}
+
+// @Summary Add return photo
+// @Description Attach a photo to a line of your own return while it waits for approval (at most 5 per line)
+// @Tags returns
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Return ID"
+// @Param itemId path int true "Return item ID"
+// @Param file formData file true "Photo"
+// @Success 201 {object} models.ReturnPhoto "Uploaded photo"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 404 {object} map[string]string "Return or line not found"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "Unsupported image type"
+// @Failure 422 {object} map[string]string "Return already reviewed or too many photos"
+// @Router /returns/{id}/items/{itemId}/photos [post]
+func (c *ReturnController) AddPhoto(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseIDParam(ctx, "itemId")
+	if !ok {
+		return
+	}
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer f.Close()
+
+	currentUser := ctx.MustGet("user").(models.User)
+	photo, err := c.returnService.AddPhoto(ctx.Request.Context(), currentUser.ID, id, itemID, f)
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, photo)
+}
+
+// @Summary List returns
+// @Description List returns, oldest first, optionally filtered by status (staff only)
+// @Tags returns
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Return status" Enums(requested, approved, rejected, received, completed)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Return "Returns"
+// @Failure 400 {object} map[string]string "Invalid status"
+// @Router /admin/returns [get]
+func (c *ReturnController) List(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	returns, err := c.returnService.List(ctx.Query("status"), page, pageSize)
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, returns)
+}
+
+// @Summary Approve return
+// @Description Approve a requested return and create a prepaid return label through the carrier (staff only)
+// @Tags returns
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Param request body ReviewReturnRequest false "Note to the customer"
+// @Success 200 {object} models.Return "Approved return"
+// @Failure 404 {object} map[string]string "Return not found"
+// @Failure 409 {object} map[string]string "Return changed meanwhile"
+// @Failure 422 {object} map[string]string "Return already reviewed"
+// @Failure 502 {object} map[string]string "Carrier error"
+// @Router /admin/returns/{id}/approve [post]
+func (c *ReturnController) Approve(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ReviewReturnRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	ret, err := c.returnService.Approve(ctx.Request.Context(), &currentUser, id, req.Note)
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ret)
+}
+
+// @Summary Reject return
+// @Description Reject a requested return with a reason shown to the customer (staff only)
+// @Tags returns
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Param request body ReviewReturnRequest true "Reason"
+// @Success 200 {object} models.Return "Rejected return"
+// @Failure 400 {object} map[string]string "Missing reason"
+// @Failure 404 {object} map[string]string "Return not found"
+// @Failure 409 {object} map[string]string "Return changed meanwhile"
+// @Failure 422 {object} map[string]string "Return already reviewed"
+// @Router /admin/returns/{id}/reject [post]
+func (c *ReturnController) Reject(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ReviewReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	ret, err := c.returnService.Reject(ctx.Request.Context(), &currentUser, id, req.Note)
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ret)
+}
+
+// @Summary Inspect return
+// @Description Record the outcome of every line of an approved return, restocking or writing off the units, and refund the lines through the payment provider (staff only). Inspecting a received return whose refund failed retries the refund.
+// @Tags returns
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Return ID"
+// @Param request body InspectReturnRequest true "Outcomes and optional refund amount"
+// @Success 200 {object} models.Return "Completed return"
+// @Failure 400 {object} map[string]string "Invalid input or missing outcomes"
+// @Failure 404 {object} map[string]string "Return not found"
+// @Failure 409 {object} map[string]string "Return changed meanwhile"
+// @Failure 422 {object} map[string]string "Return not approved or nothing left to refund"
+// @Failure 502 {object} map[string]string "Payment provider error"
+// @Router /admin/returns/{id}/inspect [post]
+func (c *ReturnController) Inspect(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req InspectReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]services.InspectionLine, len(req.Lines))
+	for i, line := range req.Lines {
+		lines[i] = services.InspectionLine{ReturnItemID: line.ReturnItemID, Outcome: line.Outcome}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	ret, err := c.returnService.Inspect(ctx.Request.Context(), &currentUser, id, services.Inspection{
+		Lines:  lines,
+		Amount: req.Amount,
+		Note:   req.Note,
+	})
+	if err != nil {
+		ctx.JSON(returnErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ret)
+}
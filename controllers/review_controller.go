@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewController struct {
+	reviewService *services.ReviewService
+}
+
+func NewReviewController(reviewService *services.ReviewService) *ReviewController {
+	return &ReviewController{
+		reviewService: reviewService,
+	}
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5" example:"5"`
+	Title  string `json:"title" binding:"max=120" example:"Great coffee"`
+	Body   string `json:"body" binding:"max=5000" example:"Fruity and fresh, will buy again."`
+}
+
+type ModerateReviewRequest struct {
+	Note string `json:"note" binding:"max=500" example:"Contains personal information"`
+}
+
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrReviewNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNotVerifiedBuyer), errors.Is(err, services.ErrReviewForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrReviewExists), errors.Is(err, services.ErrAlreadyVoted):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrFileTooLarge), errors.Is(err, services.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// @Summary List product reviews
+// @Description List approved reviews of a product
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param sort query string false "newest, helpful or rating" default(newest)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Review "Reviews"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /products/{id}/reviews [get]
+func (c *ReviewController) ListForProduct(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	reviews, err := c.reviewService.ListForProduct(ctx.Request.Context(), productID, ctx.Query("sort"), page, pageSize)
+	if err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Create review
+// @Description Review a purchased product. New reviews wait for moderation.
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body ReviewRequest true "Review"
+// @Success 201 {object} models.Review "Created review"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Not a verified buyer"
+// @Failure 409 {object} map[string]string "Already reviewed"
+// @Router /products/{id}/reviews [post]
+func (c *ReviewController) Create(ctx *gin.Context) {
+	productID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	review, err := c.reviewService.Create(currentUser, productID, req.Rating, req.Title, req.Body)
+	if err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, review)
+}
+
+// @Summary Update review
+// @Description Edit your own review. Edited reviews go back to moderation.
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body ReviewRequest true "Review"
+// @Success 200 {object} models.Review "Updated review"
+// @Failure 403 {object} map[string]string "Not your review"
+// @Failure 404 {object} map[string]string "Review not found"
+// @Router /reviews/{id} [put]
+func (c *ReviewController) Update(ctx *gin.Context) {
+	reviewID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ReviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	review, err := c.reviewService.Update(currentUser.ID, reviewID, req.Rating, req.Title, req.Body)
+	if err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, review)
+}
+
+// @Summary Delete review
+// @Description Delete your own review, or any review as staff
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} map[string]string "Review deleted"
+// @Failure 403 {object} map[string]string "Not your review"
+// @Failure 404 {object} map[string]string "Review not found"
+// @Router /reviews/{id} [delete]
+func (c *ReviewController) Delete(ctx *gin.Context) {
+	reviewID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.reviewService.Delete(currentUser, reviewID); err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
+
+// @Summary Add review photo
+// @Description Attach a photo to your own review (at most 5)
+// @Tags reviews
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param file formData file true "Photo"
+// @Success 201 {object} models.ReviewPhoto "Uploaded photo"
+// @Failure 400 {object} map[string]string "Invalid input or too many photos"
+// @Failure 403 {object} map[string]string "Not your review"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "Unsupported image type"
+// @Router /reviews/{id}/photos [post]
+func (c *ReviewController) AddPhoto(ctx *gin.Context) {
+	reviewID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer f.Close()
+
+	currentUser := ctx.MustGet("user").(models.User)
+	photo, err := c.reviewService.AddPhoto(ctx.Request.Context(), currentUser.ID, reviewID, f)
+	if err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, photo)
+}
+
+// @Summary Vote review helpful
+// @Description Mark another customer's review as helpful, once per user
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.Review "Review with updated vote count"
+// @Failure 404 {object} map[string]string "Review not found"
+// @Failure 409 {object} map[string]string "Already voted"
+// @Router /reviews/{id}/helpful [post]
+func (c *ReviewController) Vote(ctx *gin.Context) {
+	reviewID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	review, err := c.reviewService.Vote(currentUser.ID, reviewID)
+	if err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, review)
+}
+
+// @Summary Review moderation queue
+// @Description List reviews by moderation status, oldest first (staff only)
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "pending, approved or rejected" default(pending)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Review "Reviews"
+// @Router /admin/reviews [get]
+func (c *ReviewController) ModerationQueue(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	reviews, err := c.reviewService.ModerationQueue(ctx.Request.Context(), ctx.Query("status"), page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reviews"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Approve review
+// @Description Publish a review and include it in the product rating (staff only)
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body ModerateReviewRequest false "Moderation note"
+// @Success 200 {object} models.Review "Approved review"
+// @Failure 404 {object} map[string]string "Review not found"
+// @Router /admin/reviews/{id}/approve [put]
+func (c *ReviewController) Approve(ctx *gin.Context) {
+	c.moderate(ctx, true)
+}
+
+// @Summary Reject review
+// @Description Hide a review from the product page (staff only)
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body ModerateReviewRequest false "Moderation note"
+// @Success 200 {object} models.Review "Rejected review"
+// @Failure 404 {object} map[string]string "Review not found"
+// @Router /admin/reviews/{id}/reject [put]
+func (c *ReviewController) Reject(ctx *gin.Context) {
+	c.moderate(ctx, false)
+}
+
+func (c *ReviewController) moderate(ctx *gin.Context, approve bool) {
+	reviewID, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ModerateReviewRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	review, err := c.reviewService.Moderate(currentUser.ID, reviewID, approve, req.Note)
+	if err != nil {
+		ctx.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, review)
+}
@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShippingController struct {
+	shippingService *services.ShippingService
+	cartService     *services.CartService
+}
+
+func NewShippingController(shippingService *services.ShippingService, cartService *services.CartService) *ShippingController {
+	return &ShippingController{
+		shippingService: shippingService,
+		cartService:     cartService,
+	}
+}
+
+type ShippingZoneRequest struct {
+	Name        string   `json:"name" binding:"required,max=100" example:"Taiwan main island"`
+	Countries   []string `json:"countries" binding:"required,min=1" example:"TW"`
+	Cities      []string `json:"cities" example:"台北市"`
+	PostalCodes []string `json:"postal_codes" example:"1"`
+}
+
+// ShippingTierRequest is a step of a rate table. Min is whole grams for
+// weight-based rates and a decimal amount for price-based rates.
+type ShippingTierRequest struct {
+	Min    string `json:"min" binding:"required" example:"1000"`
+	Amount string `json:"amount" binding:"required" example:"150"`
+}
+
+// ShippingMethodRequest describes a shipping method. Amounts are decimal
+// strings in the method currency.
+type ShippingMethodRequest struct {
+	Name           string                `json:"name" binding:"required,max=100" example:"Home delivery"`
+	Active         bool                  `json:"active" example:"true"`
+	Currency       string                `json:"currency" binding:"omitempty,len=3" example:"TWD"`
+	RateType       string                `json:"rate_type" binding:"required,oneof=flat weight price free carrier" example:"flat"`
+	Amount         string                `json:"amount" example:"100"`
+	Tiers          []ShippingTierRequest `json:"tiers" binding:"dive"`
+	FreeOver       string                `json:"free_over" example:"1500"`
+	Carrier        string                `json:"carrier" example:"fake"`
+	CarrierService string                `json:"carrier_service" example:"express"`
+	EstimatedDays  int                   `json:"estimated_days" binding:"min=0" example:"2"`
+}
+
+func (r ShippingMethodRequest) toDetails() services.ShippingMethodDetails {
+	tiers := make([]services.ShippingTierDetails, len(r.Tiers))
+	for i, t := range r.Tiers {
+		tiers[i] = services.ShippingTierDetails{Min: t.Min, Amount: t.Amount}
+	}
+	return services.ShippingMethodDetails{
+		Name:           r.Name,
+		Active:         r.Active,
+		Currency:       r.Currency,
+		RateType:       r.RateType,
+		Amount:         r.Amount,
+		Tiers:          tiers,
+		FreeOver:       r.FreeOver,
+		Carrier:        r.Carrier,
+		CarrierService: r.CarrierService,
+		EstimatedDays:  r.EstimatedDays,
+	}
+}
+
+func shippingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrShippingZoneNotFound), errors.Is(err, services.ErrShippingMethodNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidShippingZone), errors.Is(err, services.ErrInvalidShippingMethod),
+		errors.Is(err, money.ErrUnknownCurrency):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCartEmpty):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary Quote shipping
+// @Description List the shipping methods available for the current cart to an address, cheapest first, priced in the currency selected by Accept-Currency
+// @Tags shipping
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param country query string true "Country code" example(TW)
+// @Param city query string false "City" example(台北市)
+// @Param postal_code query string false "Postal code" example(110)
+// @Success 200 {array} models.ShippingQuote "Available methods"
+// @Failure 400 {object} map[string]string "Missing country"
+// @Failure 422 {object} map[string]string "Cart is empty"
+// @Router /shipping/quote [get]
+func (c *ShippingController) Quote(ctx *gin.Context) {
+	address := models.Address{
+		Country:    strings.ToUpper(strings.TrimSpace(ctx.Query("country"))),
+		City:       strings.TrimSpace(ctx.Query("city")),
+		PostalCode: strings.TrimSpace(ctx.Query("postal_code")),
+	}
+	if len(address.Country) != 2 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "country must be a two-letter country code"})
+		return
+	}
+
+	userID, token := cartOwner(ctx)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	cart, err := c.cartService.Get(userID, token, priceList)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	quotes, err := c.shippingService.Quote(ctx.Request.Context(), cart, address)
+	if err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.JSON(http.StatusOK, quotes)
+}
+
+// @Summary List shipping zones
+// @Description List shipping zones with their methods (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.ShippingZone "Zones"
+// @Router /admin/shipping/zones [get]
+func (c *ShippingController) ListZones(ctx *gin.Context) {
+	zones, err := c.shippingService.Zones()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list shipping zones"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, zones)
+}
+
+// @Summary Get shipping zone
+// @Description Get a shipping zone with its methods (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 200 {object} models.ShippingZone "Zone"
+// @Failure 404 {object} map[string]string "Zone not found"
+// @Router /admin/shipping/zones/{id} [get]
+func (c *ShippingController) GetZone(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	zone, err := c.shippingService.Zone(id)
+	if err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, zone)
+}
+
+// @Summary Create shipping zone
+// @Description Create a zone of countries, optionally narrowed to cities or postal code prefixes (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ShippingZoneRequest true "Zone"
+// @Success 201 {object} models.ShippingZone "Created zone"
+// @Failure 400 {object} map[string]string "Invalid zone"
+// @Router /admin/shipping/zones [post]
+func (c *ShippingController) CreateZone(ctx *gin.Context) {
+	var req ShippingZoneRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := c.shippingService.CreateZone(services.ShippingZoneDetails{
+		Name:        req.Name,
+		Countries:   req.Countries,
+		Cities:      req.Cities,
+		PostalCodes: req.PostalCodes,
+	})
+	if err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, zone)
+}
+
+// @Summary Update shipping zone
+// @Description Replace the name and area of a zone (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Param request body ShippingZoneRequest true "Zone"
+// @Success 200 {object} models.ShippingZone "Updated zone"
+// @Failure 400 {object} map[string]string "Invalid zone"
+// @Failure 404 {object} map[string]string "Zone not found"
+// @Router /admin/shipping/zones/{id} [put]
+func (c *ShippingController) UpdateZone(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ShippingZoneRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	zone, err := c.shippingService.UpdateZone(id, services.ShippingZoneDetails{
+		Name:        req.Name,
+		Countries:   req.Countries,
+		Cities:      req.Cities,
+		PostalCodes: req.PostalCodes,
+	})
+	if err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, zone)
+}
+
+// @Summary Delete shipping zone
+// @Description Delete a zone and its methods (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Success 200 {object} map[string]string "Zone deleted"
+// @Failure 404 {object} map[string]string "Zone not found"
+// @Router /admin/shipping/zones/{id} [delete]
+func (c *ShippingController) DeleteZone(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := c.shippingService.DeleteZone(id); err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Shipping zone deleted"})
+}
+
+// @Summary Create shipping method
+// @Description Add a flat, weight-based, price-based, free or live carrier rate to a zone, optionally free over an order value (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Zone ID"
+// @Param request body ShippingMethodRequest true "Method"
+// @Success 201 {object} models.ShippingMethod "Created method"
+// @Failure 400 {object} map[string]string "Invalid method"
+// @Failure 404 {object} map[string]string "Zone not found"
+// @Router /admin/shipping/zones/{id}/methods [post]
+func (c *ShippingController) CreateMethod(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ShippingMethodRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, err := c.shippingService.CreateMethod(id, req.toDetails())
+	if err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, method)
+}
+
+// @Summary Update shipping method
+// @Description Replace a shipping method (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Method ID"
+// @Param request body ShippingMethodRequest true "Method"
+// @Success 200 {object} models.ShippingMethod "Updated method"
+// @Failure 400 {object} map[string]string "Invalid method"
+// @Failure 404 {object} map[string]string "Method not found"
+// @Router /admin/shipping/methods/{id} [put]
+func (c *ShippingController) UpdateMethod(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ShippingMethodRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method, err := c.shippingService.UpdateMethod(id, req.toDetails())
+	if err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, method)
+}
+
+// @Summary Delete shipping method
+// @Description Delete a shipping method (staff only)
+// @Tags shipping
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Method ID"
+// @Success 200 {object} map[string]string "Method deleted"
+// @Failure 404 {object} map[string]string "Method not found"
+// @Router /admin/shipping/methods/{id} [delete]
+func (c *ShippingController) DeleteMethod(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := c.shippingService.DeleteMethod(id); err != nil {
+		ctx.JSON(shippingErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Shipping method deleted"})
+}
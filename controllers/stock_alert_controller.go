@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StockAlertController struct {
+	stockAlertService *services.StockAlertService
+}
+
+func NewStockAlertController(stockAlertService *services.StockAlertService) *StockAlertController {
+	return &StockAlertController{
+		stockAlertService: stockAlertService,
+	}
+}
+
+type SetThresholdRequest struct {
+	Threshold int `json:"threshold" binding:"min=0" example:"10"`
+}
+
+func stockAlertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrProductInStock):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidThreshold):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List stock alerts
+// @Description List low-stock alerts, newest first (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "open (default) or all"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.StockAlert "Stock alerts"
+// @Router /inventory/alerts [get]
+func (c *StockAlertController) ListAlerts(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	alerts, err := c.stockAlertService.Alerts(ctx.Query("status") != "all", page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alerts"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, alerts)
+}
+
+// @Summary Set reorder threshold
+// @Description Alert staff when the available stock of a product drops below the threshold (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param request body SetThresholdRequest true "Threshold"
+// @Success 200 {object} models.ReorderThreshold "Saved threshold"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /inventory/products/{id}/threshold [put]
+func (c *StockAlertController) SetThreshold(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req SetThresholdRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	threshold, err := c.stockAlertService.SetThreshold(id, req.Threshold)
+	if err != nil {
+		ctx.JSON(stockAlertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, threshold)
+}
+
+// @Summary Remove reorder threshold
+// @Description Stop low-stock alerts for a product (staff only)
+// @Tags inventory
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]string "Threshold removed"
+// @Router /inventory/products/{id}/threshold [delete]
+func (c *StockAlertController) DeleteThreshold(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := c.stockAlertService.DeleteThreshold(id); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove threshold"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Threshold removed"})
+}
+
+// @Summary Subscribe to back-in-stock email
+// @Description Get an email when an out-of-stock product is available again
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 201 {object} models.BackInStockSubscription "Subscription"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Failure 409 {object} map[string]string "Product is in stock"
+// @Router /products/{id}/stock-subscription [post]
+func (c *StockAlertController) Subscribe(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	subscription, err := c.stockAlertService.Subscribe(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(stockAlertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, subscription)
+}
+
+// @Summary Unsubscribe from back-in-stock email
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} map[string]string "Unsubscribed"
+// @Router /products/{id}/stock-subscription [delete]
+func (c *StockAlertController) Unsubscribe(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.stockAlertService.Unsubscribe(currentUser.ID, id); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Unsubscribed"})
+}
+
+// @Summary List my back-in-stock subscriptions
+// @Tags products
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.BackInStockSubscription "Subscriptions"
+// @Router /stock-subscriptions [get]
+func (c *StockAlertController) ListSubscriptions(ctx *gin.Context) {
+	currentUser := ctx.MustGet("user").(models.User)
+	subscriptions, err := c.stockAlertService.Subscriptions(currentUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscriptions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscriptions)
+}
@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionController struct {
+	subscriptionService *services.SubscriptionService
+}
+
+func NewSubscriptionController(subscriptionService *services.SubscriptionService) *SubscriptionController {
+	return &SubscriptionController{
+		subscriptionService: subscriptionService,
+	}
+}
+
+type SubscriptionPlanRequest struct {
+	Name            string `json:"name" binding:"required,max=100" example:"Coffee of the month"`
+	ProductID       uint   `json:"product_id" binding:"required" example:"1"`
+	Quantity        int    `json:"quantity" binding:"required,min=1" example:"1"`
+	Interval        string `json:"interval" binding:"required,oneof=week month" example:"week"`
+	IntervalCount   int    `json:"interval_count" binding:"required,min=1" example:"2"`
+	DiscountPercent int    `json:"discount_percent" binding:"min=0,max=100" example:"10"`
+	Active          bool   `json:"active" example:"true"`
+}
+
+func (r SubscriptionPlanRequest) toDetails() services.PlanDetails {
+	return services.PlanDetails{
+		Name:            r.Name,
+		ProductID:       r.ProductID,
+		Quantity:        r.Quantity,
+		Interval:        r.Interval,
+		IntervalCount:   r.IntervalCount,
+		DiscountPercent: r.DiscountPercent,
+		Active:          r.Active,
+	}
+}
+
+// SubscribeRequest takes the shipping address inline or by ID from the
+// address book, as at checkout. Quantity and frequency default to the plan's.
+type SubscribeRequest struct {
+	PlanID            uint            `json:"plan_id" binding:"required" example:"1"`
+	Quantity          int             `json:"quantity" binding:"min=0" example:"1"`
+	Interval          string          `json:"interval" binding:"omitempty,oneof=week month" example:"week"`
+	IntervalCount     int             `json:"interval_count" binding:"min=0" example:"2"`
+	PaymentMethodID   uint            `json:"payment_method_id" binding:"required" example:"1"`
+	ShippingAddress   *AddressRequest `json:"shipping_address"`
+	ShippingAddressID uint            `json:"shipping_address_id" example:"1"`
+	// ShippingMethodID is one of the methods of GET /shipping/quote
+	ShippingMethodID uint `json:"shipping_method_id" example:"1"`
+}
+
+// ChangeSubscriptionRequest replaces the quantity, frequency and payment
+// method of a subscription
+type ChangeSubscriptionRequest struct {
+	Quantity        int    `json:"quantity" binding:"required,min=1" example:"2"`
+	Interval        string `json:"interval" binding:"required,oneof=week month" example:"month"`
+	IntervalCount   int    `json:"interval_count" binding:"required,min=1" example:"1"`
+	PaymentMethodID uint   `json:"payment_method_id" binding:"required" example:"2"`
+}
+
+type CancelSubscriptionRequest struct {
+	Reason string `json:"reason" binding:"max=500" example:"Too much coffee"`
+}
+
+func subscriptionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPlanNotFound), errors.Is(err, services.ErrSubscriptionNotFound),
+		errors.Is(err, services.ErrPaymentMethodNotFound), errors.Is(err, services.ErrAddressNotFound),
+		errors.Is(err, services.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidSubscription), errors.Is(err, services.ErrInvalidAddress),
+		errors.Is(err, services.ErrAddressRequired), errors.Is(err, services.ErrShippingMethodRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, services.ErrShippingUnavailable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrSubscriptionStatus), errors.Is(err, services.ErrSubscriptionChanged),
+		errors.Is(err, services.ErrCartInvalid), errors.Is(err, services.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPaymentFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List subscription plans
+// @Description List the plans customers can subscribe to
+// @Tags subscriptions
+// @Produce json
+// @Success 200 {array} models.SubscriptionPlan "Plans"
+// @Router /subscription-plans [get]
+func (c *SubscriptionController) Plans(ctx *gin.Context) {
+	plans, err := c.subscriptionService.Plans(false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscription plans"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plans)
+}
+
+// @Summary List all subscription plans
+// @Description List every subscription plan, including inactive ones
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.SubscriptionPlan "Plans"
+// @Router /admin/subscription-plans [get]
+func (c *SubscriptionController) AllPlans(ctx *gin.Context) {
+	plans, err := c.subscriptionService.Plans(true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscription plans"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plans)
+}
+
+// @Summary Create subscription plan
+// @Description Offer a product on a schedule, optionally at a discount
+// @Tags subscriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SubscriptionPlanRequest true "Plan"
+// @Success 201 {object} models.SubscriptionPlan "Created plan"
+// @Failure 400 {object} map[string]string "Invalid plan"
+// @Failure 404 {object} map[string]string "Product not found"
+// @Router /admin/subscription-plans [post]
+func (c *SubscriptionController) CreatePlan(ctx *gin.Context) {
+	var req SubscriptionPlanRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := c.subscriptionService.CreatePlan(req.toDetails())
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, plan)
+}
+
+// @Summary Update subscription plan
+// @Description Update a plan. Existing subscriptions keep the terms they signed up with.
+// @Tags subscriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Plan ID"
+// @Param request body SubscriptionPlanRequest true "Plan"
+// @Success 200 {object} models.SubscriptionPlan "Updated plan"
+// @Failure 400 {object} map[string]string "Invalid plan"
+// @Failure 404 {object} map[string]string "Plan or product not found"
+// @Router /admin/subscription-plans/{id} [put]
+func (c *SubscriptionController) UpdatePlan(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req SubscriptionPlanRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := c.subscriptionService.UpdatePlan(id, req.toDetails())
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plan)
+}
+
+// @Summary Subscribe
+// @Description Subscribe to a plan. The first order is placed and charged to the saved payment method right away, priced in the currency selected by Accept-Currency; later ones are placed on schedule.
+// @Tags subscriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SubscribeRequest true "Subscription"
+// @Success 201 {object} models.Subscription "Subscription"
+// @Failure 400 {object} map[string]string "Invalid subscription or address"
+// @Failure 402 {object} map[string]string "Card declined"
+// @Failure 404 {object} map[string]string "Plan, payment method or address not found"
+// @Failure 409 {object} map[string]string "Product is out of stock"
+// @Failure 502 {object} map[string]string "Payment provider error"
+// @Router /subscriptions [post]
+func (c *SubscriptionController) Subscribe(ctx *gin.Context) {
+	var req SubscribeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	details := services.SubscriptionDetails{
+		PlanID:            req.PlanID,
+		Quantity:          req.Quantity,
+		Interval:          req.Interval,
+		IntervalCount:     req.IntervalCount,
+		PaymentMethodID:   req.PaymentMethodID,
+		ShippingAddressID: req.ShippingAddressID,
+		ShippingMethodID:  req.ShippingMethodID,
+	}
+	if req.ShippingAddress != nil {
+		details.ShippingAddress = req.ShippingAddress.toAddress()
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	sub, err := c.subscriptionService.Subscribe(ctx.Request.Context(), currentUser, details, priceList)
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, sub)
+}
+
+// @Summary List subscriptions
+// @Description List the current user's subscriptions, newest first
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Subscription "Subscriptions"
+// @Router /subscriptions [get]
+func (c *SubscriptionController) List(ctx *gin.Context) {
+	currentUser := ctx.MustGet("user").(models.User)
+	subs, err := c.subscriptionService.List(currentUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscriptions"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subs)
+}
+
+// @Summary Get subscription
+// @Description Get one of the current user's subscriptions
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.Subscription "Subscription"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Router /subscriptions/{id} [get]
+func (c *SubscriptionController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	sub, err := c.subscriptionService.Get(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sub)
+}
+
+// @Summary Change subscription
+// @Description Change the quantity, frequency or payment method of a subscription. A new payment method retries a past due renewal right away.
+// @Tags subscriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param request body ChangeSubscriptionRequest true "Changes"
+// @Success 200 {object} models.Subscription "Updated subscription"
+// @Failure 400 {object} map[string]string "Invalid quantity or frequency"
+// @Failure 404 {object} map[string]string "Subscription or payment method not found"
+// @Failure 409 {object} map[string]string "Subscription is cancelled"
+// @Router /subscriptions/{id} [put]
+func (c *SubscriptionController) Change(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req ChangeSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	sub, err := c.subscriptionService.Change(currentUser.ID, id, services.SubscriptionChange{
+		Quantity:        req.Quantity,
+		Interval:        req.Interval,
+		IntervalCount:   req.IntervalCount,
+		PaymentMethodID: req.PaymentMethodID,
+	})
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sub)
+}
+
+// @Summary Skip next delivery
+// @Description Skip the next renewal of an active subscription
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.Subscription "Updated subscription"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is not active"
+// @Router /subscriptions/{id}/skip [post]
+func (c *SubscriptionController) Skip(ctx *gin.Context) {
+	c.act(ctx, c.subscriptionService.Skip)
+}
+
+// @Summary Pause subscription
+// @Description Stop renewing an active subscription until it is resumed
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.Subscription "Updated subscription"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is not active"
+// @Router /subscriptions/{id}/pause [post]
+func (c *SubscriptionController) Pause(ctx *gin.Context) {
+	c.act(ctx, c.subscriptionService.Pause)
+}
+
+// @Summary Resume subscription
+// @Description Resume a paused subscription. The next renewal is the first one on schedule from now.
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} models.Subscription "Updated subscription"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is not paused"
+// @Router /subscriptions/{id}/resume [post]
+func (c *SubscriptionController) Resume(ctx *gin.Context) {
+	c.act(ctx, c.subscriptionService.Resume)
+}
+
+// act runs a self-service action without a body on one of the current
+// user's subscriptions
+func (c *SubscriptionController) act(ctx *gin.Context, action func(userID, id uint) (*models.Subscription, error)) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	sub, err := action(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sub)
+}
+
+// @Summary Cancel subscription
+// @Description Cancel a subscription. Orders already placed are not affected.
+// @Tags subscriptions
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param request body CancelSubscriptionRequest false "Reason"
+// @Success 200 {object} models.Subscription "Cancelled subscription"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription is already cancelled"
+// @Router /subscriptions/{id}/cancel [post]
+func (c *SubscriptionController) Cancel(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req CancelSubscriptionRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	sub, err := c.subscriptionService.Cancel(currentUser.ID, id, req.Reason)
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sub)
+}
+
+// @Summary List all subscriptions
+// @Description List every customer's subscriptions, newest first, optionally filtered by status
+// @Tags subscriptions
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Status" Enums(active, paused, past_due, renewing, cancelled)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {array} models.Subscription "Subscriptions"
+// @Failure 400 {object} map[string]string "Unknown status"
+// @Router /admin/subscriptions [get]
+func (c *SubscriptionController) ListAll(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+
+	subs, err := c.subscriptionService.ListAll(ctx.Query("status"), page, pageSize)
+	if err != nil {
+		ctx.JSON(subscriptionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subs)
+}
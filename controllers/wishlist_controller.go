@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WishlistController struct {
+	wishlistService *services.WishlistService
+}
+
+func NewWishlistController(wishlistService *services.WishlistService) *WishlistController {
+	return &WishlistController{
+		wishlistService: wishlistService,
+	}
+}
+
+type WishlistRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"Birthday"`
+}
+
+type AddWishlistItemRequest struct {
+	ProductID uint `json:"product_id" binding:"required" example:"1"`
+}
+
+type MoveWishlistItemRequest struct {
+	WishlistID uint `json:"wishlist_id" binding:"required" example:"2"`
+}
+
+type MoveToCartRequest struct {
+	Quantity int `json:"quantity" binding:"omitempty,min=1" example:"1"`
+}
+
+func wishlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrWishlistNotFound), errors.Is(err, services.ErrWishlistItemNotFound),
+		errors.Is(err, services.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrWishlistNameTaken), errors.Is(err, services.ErrWishlistLimit),
+		errors.Is(err, services.ErrInsufficientStock):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidQuantity), errors.Is(err, services.ErrQuantityTooLarge):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPriceNotFound):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// @Summary List wishlists
+// @Description List the current user's wishlists with their items and price drops
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Wishlist "Wishlists"
+// @Router /wishlists [get]
+func (c *WishlistController) List(ctx *gin.Context) {
+	currentUser := ctx.MustGet("user").(models.User)
+	wishlists, err := c.wishlistService.List(currentUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list wishlists"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wishlists)
+}
+
+// @Summary Create wishlist
+// @Description Create a named wishlist for the current user
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body WishlistRequest true "Wishlist name"
+// @Success 201 {object} models.Wishlist "Created wishlist"
+// @Failure 409 {object} map[string]string "Name taken or too many wishlists"
+// @Router /wishlists [post]
+func (c *WishlistController) Create(ctx *gin.Context) {
+	var req WishlistRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	wishlist, err := c.wishlistService.Create(currentUser.ID, req.Name)
+	if err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, wishlist)
+}
+
+// @Summary Get wishlist
+// @Description Get one of the current user's wishlists
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} models.Wishlist "Wishlist"
+// @Failure 404 {object} map[string]string "Wishlist not found"
+// @Router /wishlists/{id} [get]
+func (c *WishlistController) Get(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	wishlist, err := c.wishlistService.Get(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wishlist)
+}
+
+// @Summary Rename wishlist
+// @Description Rename one of the current user's wishlists
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Param request body WishlistRequest true "Wishlist name"
+// @Success 200 {object} models.Wishlist "Renamed wishlist"
+// @Failure 404 {object} map[string]string "Wishlist not found"
+// @Failure 409 {object} map[string]string "Name taken"
+// @Router /wishlists/{id} [put]
+func (c *WishlistController) Rename(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req WishlistRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	wishlist, err := c.wishlistService.Rename(currentUser.ID, id, req.Name)
+	if err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wishlist)
+}
+
+// @Summary Delete wishlist
+// @Description Delete one of the current user's wishlists and its items
+// @Tags wishlists
+// @Security BearerAuth
+// @Param id path int true "Wishlist ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "Wishlist not found"
+// @Router /wishlists/{id} [delete]
+func (c *WishlistController) Delete(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.wishlistService.Delete(currentUser.ID, id); err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Add wishlist item
+// @Description Save a product on a wishlist, remembering its price in the currency selected by Accept-Currency
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param id path int true "Wishlist ID"
+// @Param request body AddWishlistItemRequest true "Product"
+// @Success 201 {object} models.WishlistItem "Saved item"
+// @Failure 404 {object} map[string]string "Wishlist or product not found"
+// @Router /wishlists/{id}/items [post]
+func (c *WishlistController) AddItem(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var req AddWishlistItemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var priceList *models.PriceList
+	if pl, exists := ctx.Get("price_list"); exists {
+		priceList = pl.(*models.PriceList)
+	}
+	currentUser := ctx.MustGet("user").(models.User)
+	item, err := c.wishlistService.AddItem(currentUser.ID, id, req.ProductID, priceList)
+	if err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, item)
+}
+
+// @Summary Remove wishlist item
+// @Description Remove a product from a wishlist
+// @Tags wishlists
+// @Security BearerAuth
+// @Param id path int true "Wishlist ID"
+// @Param productId path int true "Product ID"
+// @Success 204 "Removed"
+// @Failure 404 {object} map[string]string "Wishlist or item not found"
+// @Router /wishlists/{id}/items/{productId} [delete]
+func (c *WishlistController) RemoveItem(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.wishlistService.RemoveItem(currentUser.ID, id, productID); err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Move wishlist item
+// @Description Move a product to another of the current user's wishlists
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Param id path int true "Wishlist ID"
+// @Param productId path int true "Product ID"
+// @Param request body MoveWishlistItemRequest true "Target wishlist"
+// @Success 204 "Moved"
+// @Failure 404 {object} map[string]string "Wishlist or item not found"
+// @Router /wishlists/{id}/items/{productId}/move [post]
+func (c *WishlistController) MoveItem(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	var req MoveWishlistItemRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.wishlistService.MoveItem(currentUser.ID, id, productID, req.WishlistID); err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Move wishlist item to cart
+// @Description Add a wishlist item to the cart and remove it from the wishlist
+// @Tags wishlists
+// @Security BearerAuth
+// @Accept json
+// @Param Accept-Currency header string false "Preferred currencies, e.g. USD, JPY;q=0.5"
+// @Param id path int true "Wishlist ID"
+// @Param productId path int true "Product ID"
+// @Param request body MoveToCartRequest false "Quantity, 1 by default"
+// @Success 204 "Moved to cart"
+// @Failure 404 {object} map[string]string "Wishlist or item not found"
+// @Failure 409 {object} map[string]string "Insufficient stock"
+// @Failure 422 {object} map[string]string "Product has no price in this currency"
+// @Router /wishlists/{id}/items/{productId}/move-to-cart [post]
+func (c *WishlistController) MoveToCart(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+	productID, ok := parseIDParam(ctx, "productId")
+	if !ok {
+		return
+	}
+
+	req := MoveToCartRequest{Quantity: 1}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Quantity == 0 {
+			req.Quantity = 1
+		}
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	priceList := ctx.MustGet("price_list").(*models.PriceList)
+	if err := c.wishlistService.MoveToCart(currentUser.ID, id, productID, req.Quantity, priceList); err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Share wishlist
+// @Description Create a read-only share token for a wishlist, or return the existing one
+// @Tags wishlists
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Wishlist ID"
+// @Success 200 {object} models.Wishlist "Wishlist with share token"
+// @Failure 404 {object} map[string]string "Wishlist not found"
+// @Router /wishlists/{id}/share [post]
+func (c *WishlistController) Share(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	wishlist, err := c.wishlistService.Share(currentUser.ID, id)
+	if err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wishlist)
+}
+
+// @Summary Stop sharing wishlist
+// @Description Revoke the share token of a wishlist so existing links stop working
+// @Tags wishlists
+// @Security BearerAuth
+// @Param id path int true "Wishlist ID"
+// @Success 204 "Sharing stopped"
+// @Failure 404 {object} map[string]string "Wishlist not found"
+// @Router /wishlists/{id}/share [delete]
+func (c *WishlistController) Unshare(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	currentUser := ctx.MustGet("user").(models.User)
+	if err := c.wishlistService.Unshare(currentUser.ID, id); err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Get shared wishlist
+// @Description Read a wishlist through its share link
+// @Tags wishlists
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.Wishlist "Wishlist"
+// @Failure 404 {object} map[string]string "Wishlist not found"
+// @Router /shared-wishlists/{token} [get]
+func (c *WishlistController) Shared(ctx *gin.Context) {
+	wishlist, err := c.wishlistService.Shared(ctx.Param("token"))
+	if err != nil {
+		ctx.JSON(wishlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	// 分享連結只供檢視，不回傳 token 本身
+	wishlist.ShareToken = nil
+
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.JSON(http.StatusOK, wishlist)
+}
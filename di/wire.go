@@ -6,9 +6,16 @@ package di
 import (
 	"e-commerce/configs"
 	"e-commerce/controllers"
+	"e-commerce/einvoice"
+	"e-commerce/mailer"
 	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/pdf"
 	"e-commerce/repository"
 	"e-commerce/services"
+	"e-commerce/shipping"
+	"e-commerce/storage"
+	"e-commerce/tax"
 
 	"github.com/google/wire"
 	"gorm.io/gorm"
@@ -16,9 +23,44 @@ import (
 
 // Container 定義應用程式的依賴注入容器
 type Container struct {
-	DB             *gorm.DB
-	AuthController *controllers.AuthController
-	AuthMiddleware *middlewares.AuthMiddleware
+	DB *gorm.DB
+
+	// Controllers
+	AuthController           *controllers.AuthController
+	ProductController        *controllers.ProductController
+	MediaController          *controllers.MediaController
+	CatalogController        *controllers.CatalogController
+	ReviewController         *controllers.ReviewController
+	PriceListController      *controllers.PriceListController
+	InventoryController      *controllers.InventoryController
+	StockAlertController     *controllers.StockAlertController
+	RecommendationController *controllers.RecommendationController
+	WishlistController       *controllers.WishlistController
+	CartController           *controllers.CartController
+	OrderController          *controllers.OrderController
+	PaymentController        *controllers.PaymentController
+	RefundController         *controllers.RefundController
+	PromotionController      *controllers.PromotionController
+	ShippingController       *controllers.ShippingController
+	AddressController        *controllers.AddressController
+	InvoiceController        *controllers.InvoiceController
+	ReturnController         *controllers.ReturnController
+	SubscriptionController   *controllers.SubscriptionController
+	CartRecoveryController   *controllers.CartRecoveryController
+
+	// Middlewares
+	AuthMiddleware    *middlewares.AuthMiddleware
+	PricingMiddleware *middlewares.PricingMiddleware
+
+	// Background workers
+	ProductService        *services.ProductService
+	CatalogService        *services.CatalogService
+	InventoryService      *services.InventoryService
+	RecommendationService *services.RecommendationService
+	OrderService          *services.OrderService
+	SubscriptionService   *services.SubscriptionService
+	CartRecoveryService   *services.CartRecoveryService
+	StockAlertService     *services.StockAlertService
 }
 
 // provideGormDB 提供原始的 gorm.DB 實例
@@ -31,6 +73,41 @@ func provideConfigsDatabase(db *gorm.DB) *configs.Database {
 	return &configs.Database{DB: db}
 }
 
+// provideMailer 依據環境變數提供寄信實作
+func provideMailer() (mailer.Mailer, error) {
+	return mailer.NewFromEnv()
+}
+
+// providePaymentProvider 依據環境變數提供金流實作
+func providePaymentProvider() (payments.PaymentProvider, error) {
+	return payments.NewFromEnv()
+}
+
+// provideTaxProvider 依據環境變數提供稅務計算實作
+func provideTaxProvider() (tax.Provider, error) {
+	return tax.NewFromEnv()
+}
+
+// provideCarriers 依據環境變數提供即時運費的物流商
+func provideCarriers() ([]shipping.Carrier, error) {
+	return shipping.NewFromEnv()
+}
+
+// provideTemplates 載入發票等文件的 PDF 範本
+func provideTemplates() (*pdf.Templates, error) {
+	return pdf.NewTemplatesFromEnv()
+}
+
+// provideEInvoiceIssuer 依據環境變數提供電子發票加值中心實作
+func provideEInvoiceIssuer() (einvoice.Issuer, error) {
+	return einvoice.NewFromEnv()
+}
+
+// provideStorage 依據環境變數提供媒體儲存實作
+func provideStorage() (storage.Storage, error) {
+	return storage.NewFromEnv()
+}
+
 // Initialize 初始化應用程式依賴
 func Initialize(envFile string) (*Container, error) {
 	wire.Build(
@@ -41,18 +118,99 @@ func Initialize(envFile string) (*Container, error) {
 		// Repository
 		repository.NewGormUserRepository,
 		wire.Bind(new(repository.UserRepository), new(*repository.GormUserRepository)),
+		repository.NewGormProductRepository,
+		repository.NewGormProductRevisionRepository,
+		repository.NewGormProductImageRepository,
+		repository.NewGormImportJobRepository,
+		repository.NewGormReviewRepository,
+		repository.NewGormPriceListRepository,
+		repository.NewGormInventoryRepository,
+		repository.NewGormStockAlertRepository,
+		repository.NewGormRecommendationRepository,
+		repository.NewGormWishlistRepository,
+		repository.NewGormCartRepository,
+		repository.NewGormOrderRepository,
+		repository.NewGormPaymentRepository,
+		repository.NewGormRefundRepository,
+		repository.NewGormPromotionRepository,
+		repository.NewGormShippingRepository,
+		repository.NewGormAddressRepository,
+		repository.NewGormInvoiceRepository,
+		repository.NewGormReturnRepository,
+		repository.NewGormSubscriptionRepository,
+		repository.NewGormCartRecoveryRepository,
+
+		// Storage
+		provideStorage,
+		provideMailer,
+		providePaymentProvider,
+		provideTaxProvider,
+		provideCarriers,
+		provideTemplates,
+		provideEInvoiceIssuer,
 
 		// Service
 		services.NewAuthService,
+		wire.Bind(new(services.GuestCartMerger), new(*services.CartService)),
+		services.NewProductService,
+		services.NewPricingService,
+		services.NewMediaService,
+		services.NewCatalogService,
+		services.NewReviewService,
+		services.NewStockAlertService,
+		wire.Bind(new(services.StockObserver), new(*services.StockAlertService)),
+		services.NewInventoryService,
+		services.NewRecommendationService,
+		services.NewPromotionService,
+		wire.Bind(new(services.CartDiscounter), new(*services.PromotionService)),
+		services.NewCartService,
+		wire.Bind(new(services.CartAdder), new(*services.CartService)),
+		services.NewShippingService,
+		services.NewAddressService,
+		services.NewTaxService,
+		services.NewCartRecoveryService,
+		wire.Bind(new(services.ConversionTracker), new(*services.CartRecoveryService)),
+		services.NewCheckoutService,
+		services.NewOrderService,
+		wire.Bind(new(services.PurchaseVerifier), new(*services.OrderService)),
+		services.NewInvoiceService,
+		wire.Bind(new(services.Invoicer), new(*services.InvoiceService)),
+		services.NewPaymentService,
+		services.NewRefundService,
+		services.NewCancellationService,
+		services.NewReturnService,
+		services.NewSubscriptionService,
+		services.NewWishlistService,
 
 		// Controller
 		controllers.NewAuthController,
+		controllers.NewProductController,
+		controllers.NewMediaController,
+		controllers.NewCatalogController,
+		controllers.NewReviewController,
+		controllers.NewPriceListController,
+		controllers.NewInventoryController,
+		controllers.NewStockAlertController,
+		controllers.NewRecommendationController,
+		controllers.NewWishlistController,
+		controllers.NewCartController,
+		controllers.NewOrderController,
+		controllers.NewPaymentController,
+		controllers.NewRefundController,
+		controllers.NewPromotionController,
+		controllers.NewShippingController,
+		controllers.NewAddressController,
+		controllers.NewInvoiceController,
+		controllers.NewReturnController,
+		controllers.NewSubscriptionController,
+		controllers.NewCartRecoveryController,
 
 		// Middleware
 		middlewares.NewAuthMiddleware,
+		middlewares.NewPricingMiddleware,
 
 		// Container
-		wire.Struct(new(Container), "DB", "AuthController", "AuthMiddleware"),
+		wire.Struct(new(Container), "*"),
 	)
 	return nil, nil
 }
@@ -8,17 +8,59 @@ package di
 import (
 	"e-commerce/configs"
 	"e-commerce/controllers"
+	"e-commerce/einvoice"
+	"e-commerce/mailer"
 	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/pdf"
 	"e-commerce/repository"
 	"e-commerce/services"
+	"e-commerce/shipping"
+	"e-commerce/storage"
+	"e-commerce/tax"
 	"gorm.io/gorm"
 )
 
 // Container 定義應用程式的依賴注入容器
 type Container struct {
-	DB             *gorm.DB
-	AuthController *controllers.AuthController
-	AuthMiddleware *middlewares.AuthMiddleware
+	DB *gorm.DB
+
+	// Controllers
+	AuthController           *controllers.AuthController
+	ProductController        *controllers.ProductController
+	MediaController          *controllers.MediaController
+	CatalogController        *controllers.CatalogController
+	ReviewController         *controllers.ReviewController
+	PriceListController      *controllers.PriceListController
+	InventoryController      *controllers.InventoryController
+	StockAlertController     *controllers.StockAlertController
+	RecommendationController *controllers.RecommendationController
+	WishlistController       *controllers.WishlistController
+	CartController           *controllers.CartController
+	OrderController          *controllers.OrderController
+	PaymentController        *controllers.PaymentController
+	RefundController         *controllers.RefundController
+	PromotionController      *controllers.PromotionController
+	ShippingController       *controllers.ShippingController
+	AddressController        *controllers.AddressController
+	InvoiceController        *controllers.InvoiceController
+	ReturnController         *controllers.ReturnController
+	SubscriptionController   *controllers.SubscriptionController
+	CartRecoveryController   *controllers.CartRecoveryController
+
+	// Middlewares
+	AuthMiddleware    *middlewares.AuthMiddleware
+	PricingMiddleware *middlewares.PricingMiddleware
+
+	// Background workers
+	ProductService        *services.ProductService
+	CatalogService        *services.CatalogService
+	InventoryService      *services.InventoryService
+	RecommendationService *services.RecommendationService
+	OrderService          *services.OrderService
+	SubscriptionService   *services.SubscriptionService
+	CartRecoveryService   *services.CartRecoveryService
+	StockAlertService     *services.StockAlertService
 }
 
 // provideDB 提供数据库实例
@@ -26,17 +68,177 @@ func provideDB(envFile string) *configs.Database {
 	return configs.ConnectDB(envFile)
 }
 
+// provideMailer 依據環境變數提供寄信實作
+func provideMailer() (mailer.Mailer, error) {
+	return mailer.NewFromEnv()
+}
+
+// providePaymentProvider 依據環境變數提供金流實作
+func providePaymentProvider() (payments.PaymentProvider, error) {
+	return payments.NewFromEnv()
+}
+
+// provideTaxProvider 依據環境變數提供稅務計算實作
+func provideTaxProvider() (tax.Provider, error) {
+	return tax.NewFromEnv()
+}
+
+// provideCarriers 依據環境變數提供即時運費的物流商
+func provideCarriers() ([]shipping.Carrier, error) {
+	return shipping.NewFromEnv()
+}
+
+// provideTemplates 載入發票等文件的 PDF 範本
+func provideTemplates() (*pdf.Templates, error) {
+	return pdf.NewTemplatesFromEnv()
+}
+
+// provideEInvoiceIssuer 依據環境變數提供電子發票加值中心實作
+func provideEInvoiceIssuer() (einvoice.Issuer, error) {
+	return einvoice.NewFromEnv()
+}
+
+// provideStorage 依據環境變數提供媒體儲存實作
+func provideStorage() (storage.Storage, error) {
+	return storage.NewFromEnv()
+}
+
 // Initialize 初始化應用程式依賴
 func Initialize(envFile string) (*Container, error) {
 	database := provideDB(envFile)
 	userRepository := repository.NewGormUserRepository(database.DB)
-	authService := services.NewAuthService(userRepository)
+	cartRepository := repository.NewGormCartRepository(database.DB)
+	productRepository := repository.NewGormProductRepository(database.DB)
+	productRevisionRepository := repository.NewGormProductRevisionRepository(database.DB)
+	productImageRepository := repository.NewGormProductImageRepository(database.DB)
+	importJobRepository := repository.NewGormImportJobRepository(database.DB)
+	reviewRepository := repository.NewGormReviewRepository(database.DB)
+	priceListRepository := repository.NewGormPriceListRepository(database.DB)
+	inventoryRepository := repository.NewGormInventoryRepository(database.DB)
+	stockAlertRepository := repository.NewGormStockAlertRepository(database.DB)
+	recommendationRepository := repository.NewGormRecommendationRepository(database.DB)
+	wishlistRepository := repository.NewGormWishlistRepository(database.DB)
+	orderRepository := repository.NewGormOrderRepository(database.DB)
+	paymentRepository := repository.NewGormPaymentRepository(database.DB)
+	refundRepository := repository.NewGormRefundRepository(database.DB)
+	promotionRepository := repository.NewGormPromotionRepository(database.DB)
+	shippingRepository := repository.NewGormShippingRepository(database.DB)
+	addressRepository := repository.NewGormAddressRepository(database.DB)
+	invoiceRepository := repository.NewGormInvoiceRepository(database.DB)
+	returnRepository := repository.NewGormReturnRepository(database.DB)
+	subscriptionRepository := repository.NewGormSubscriptionRepository(database.DB)
+	cartRecoveryRepository := repository.NewGormCartRecoveryRepository(database.DB)
+	storageStorage, err := provideStorage()
+	if err != nil {
+		return nil, err
+	}
+	mailerMailer, err := provideMailer()
+	if err != nil {
+		return nil, err
+	}
+	paymentProvider, err := providePaymentProvider()
+	if err != nil {
+		return nil, err
+	}
+	taxProvider, err := provideTaxProvider()
+	if err != nil {
+		return nil, err
+	}
+	v, err := provideCarriers()
+	if err != nil {
+		return nil, err
+	}
+	templates, err := provideTemplates()
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := provideEInvoiceIssuer()
+	if err != nil {
+		return nil, err
+	}
+	stockAlertService := services.NewStockAlertService(stockAlertRepository, inventoryRepository, productRepository, userRepository, mailerMailer)
+	inventoryService := services.NewInventoryService(inventoryRepository, productRepository, stockAlertService)
+	pricingService := services.NewPricingService(priceListRepository, productRepository)
+	promotionService := services.NewPromotionService(promotionRepository, userRepository)
+	cartService := services.NewCartService(cartRepository, productRepository, inventoryService, pricingService, promotionService)
+	authService := services.NewAuthService(userRepository, cartService)
 	authController := controllers.NewAuthController(authService)
 	authMiddleware := middlewares.NewAuthMiddleware(database, authService)
+	productService := services.NewProductService(productRepository, productRevisionRepository)
+	mediaService := services.NewMediaService(productImageRepository, productRepository, storageStorage)
+	productController := controllers.NewProductController(productService, mediaService, pricingService)
+	mediaController := controllers.NewMediaController(mediaService, productService)
+	catalogService := services.NewCatalogService(productRepository, productRevisionRepository, importJobRepository, storageStorage)
+	catalogController := controllers.NewCatalogController(catalogService)
+	orderService := services.NewOrderService(orderRepository, inventoryService, mailerMailer)
+	reviewService := services.NewReviewService(reviewRepository, productRepository, orderService, storageStorage)
+	reviewController := controllers.NewReviewController(reviewService)
+	priceListController := controllers.NewPriceListController(pricingService)
+	pricingMiddleware := middlewares.NewPricingMiddleware(pricingService)
+	inventoryController := controllers.NewInventoryController(inventoryService)
+	stockAlertController := controllers.NewStockAlertController(stockAlertService)
+	recommendationService := services.NewRecommendationService(recommendationRepository, productRepository)
+	recommendationController := controllers.NewRecommendationController(recommendationService, pricingService)
+	wishlistService := services.NewWishlistService(wishlistRepository, productRepository, priceListRepository, cartService)
+	wishlistController := controllers.NewWishlistController(wishlistService)
+	cartController := controllers.NewCartController(cartService)
+	addressService := services.NewAddressService(addressRepository)
+	shippingService := services.NewShippingService(shippingRepository, v)
+	taxService := services.NewTaxService(taxProvider)
+	cartRecoveryService := services.NewCartRecoveryService(cartRecoveryRepository, cartRepository, productRepository, userRepository, promotionService, mailerMailer)
+	checkoutService := services.NewCheckoutService(orderRepository, cartService, addressService, shippingService, taxService, stockAlertService, cartRecoveryService)
+	invoiceService := services.NewInvoiceService(invoiceRepository, orderService, templates, issuer)
+	paymentService := services.NewPaymentService(paymentRepository, orderService, paymentProvider, invoiceService)
+	refundService := services.NewRefundService(refundRepository, paymentRepository, orderService, inventoryService, paymentProvider, mailerMailer, invoiceService)
+	cancellationService := services.NewCancellationService(orderService, paymentService, refundService)
+	orderController := controllers.NewOrderController(checkoutService, orderService, cancellationService)
+	paymentController := controllers.NewPaymentController(paymentService)
+	refundController := controllers.NewRefundController(refundService)
+	promotionController := controllers.NewPromotionController(promotionService)
+	shippingController := controllers.NewShippingController(shippingService, cartService)
+	addressController := controllers.NewAddressController(addressService)
+	invoiceController := controllers.NewInvoiceController(invoiceService)
+	returnService := services.NewReturnService(returnRepository, productRepository, orderService, inventoryService, refundService, shippingService, storageStorage, mailerMailer)
+	returnController := controllers.NewReturnController(returnService)
+	subscriptionService := services.NewSubscriptionService(subscriptionRepository, productRepository, userRepository, pricingService, checkoutService, orderService, paymentService, mailerMailer)
+	subscriptionController := controllers.NewSubscriptionController(subscriptionService)
+	cartRecoveryController := controllers.NewCartRecoveryController(cartRecoveryService)
 	container := &Container{
-		DB:             database.DB,
-		AuthController: authController,
-		AuthMiddleware: authMiddleware,
+		DB: database.DB,
+
+		AuthController:           authController,
+		ProductController:        productController,
+		MediaController:          mediaController,
+		CatalogController:        catalogController,
+		ReviewController:         reviewController,
+		PriceListController:      priceListController,
+		InventoryController:      inventoryController,
+		StockAlertController:     stockAlertController,
+		RecommendationController: recommendationController,
+		WishlistController:       wishlistController,
+		CartController:           cartController,
+		OrderController:          orderController,
+		PaymentController:        paymentController,
+		RefundController:         refundController,
+		PromotionController:      promotionController,
+		ShippingController:       shippingController,
+		AddressController:        addressController,
+		InvoiceController:        invoiceController,
+		ReturnController:         returnController,
+		SubscriptionController:   subscriptionController,
+		CartRecoveryController:   cartRecoveryController,
+
+		AuthMiddleware:    authMiddleware,
+		PricingMiddleware: pricingMiddleware,
+
+		ProductService:        productService,
+		CatalogService:        catalogService,
+		InventoryService:      inventoryService,
+		RecommendationService: recommendationService,
+		OrderService:          orderService,
+		SubscriptionService:   subscriptionService,
+		CartRecoveryService:   cartRecoveryService,
+		StockAlertService:     stockAlertService,
 	}
 	return container, nil
 }
\ No newline at end of file
@@ -0,0 +1,149 @@
+// Package einvoice issues Taiwan government uniform invoices (電子發票)
+// through a licensed value-added service provider.
+package einvoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"e-commerce/money"
+)
+
+// Ways a buyer receives an e-invoice
+const (
+	// BuyerMember stores the invoice in the shop's member carrier
+	BuyerMember = "member"
+	// BuyerMobile stores the invoice in the buyer's mobile barcode carrier
+	BuyerMobile = "mobile"
+	// BuyerDonation donates the invoice to a charity by its love code
+	BuyerDonation = "donation"
+	// BuyerCompany issues a B2B invoice to a unified business number
+	BuyerCompany = "company"
+)
+
+var (
+	ErrInvoiceNotFound = errors.New("e-invoice not found")
+	ErrAlreadyVoided   = errors.New("e-invoice is already voided")
+	ErrExceedsInvoice  = errors.New("allowance exceeds what is left on the e-invoice")
+)
+
+var (
+	// 手機條碼：斜線加 7 碼數字、大寫英文或 + - .
+	mobileBarcodePattern = regexp.MustCompile(`^/[0-9A-Z.+-]{7}$`)
+	loveCodePattern      = regexp.MustCompile(`^[0-9]{3,7}$`)
+	taxIDPattern         = regexp.MustCompile(`^[0-9]{8}$`)
+)
+
+// ValidMobileBarcode reports whether code is a mobile barcode carrier, e.g.
+// "/ABC+123"
+func ValidMobileBarcode(code string) bool {
+	return mobileBarcodePattern.MatchString(code)
+}
+
+// ValidLoveCode reports whether code is a donation love code of 3 to 7
+// digits
+func ValidLoveCode(code string) bool {
+	return loveCodePattern.MatchString(code)
+}
+
+// ValidTaxID reports whether id is a unified business number (統一編號)
+// with a valid check digit. The weighted digit sum must be divisible by 5;
+// when the seventh digit is 7 its product 28 may count as 1 or 0.
+func ValidTaxID(id string) bool {
+	if !taxIDPattern.MatchString(id) {
+		return false
+	}
+	weights := [8]int{1, 2, 1, 2, 1, 2, 4, 1}
+	sum := 0
+	for i, w := range weights {
+		p := int(id[i]-'0') * w
+		sum += p/10 + p%10
+	}
+	return sum%5 == 0 || (id[6] == '7' && (sum+1)%5 == 0)
+}
+
+// Buyer is how the buyer takes the invoice. Carrier is the mobile barcode
+// or member ID, LoveCode the charity, and TaxID and Name the company.
+type Buyer struct {
+	Type     string
+	Carrier  string
+	LoveCode string
+	TaxID    string
+	Name     string
+	Email    string
+}
+
+// Item is a line of an invoice or allowance. Amounts include tax.
+type Item struct {
+	Description string
+	Quantity    int
+	UnitPrice   money.Money
+	Amount      money.Money
+}
+
+// IssueRequest asks for an invoice of a sale. Total includes Tax.
+type IssueRequest struct {
+	// Reference is our own identifier, e.g. the order number. Issuing again
+	// with the same reference returns the invoice already issued for it.
+	Reference string
+	Buyer     Buyer
+	Items     []Item
+	Tax       money.Money
+	Total     money.Money
+}
+
+// Invoice is an issued e-invoice
+type Invoice struct {
+	Number     string
+	RandomCode string
+	IssuedAt   time.Time
+}
+
+// AllowanceRequest asks for an allowance (折讓) against an invoice, which
+// gives back part of a sale. Total includes Tax.
+type AllowanceRequest struct {
+	// Reference is our own identifier, e.g. the credit note number. Asking
+	// again with the same reference returns the allowance already issued.
+	Reference     string
+	InvoiceNumber string
+	Items         []Item
+	Tax           money.Money
+	Total         money.Money
+}
+
+// Allowance is an issued allowance
+type Allowance struct {
+	Number   string
+	IssuedAt time.Time
+}
+
+// Issuer is an e-invoice service provider. Implementations must be safe for
+// concurrent use, and idempotent on the references of requests so that a
+// request whose response was lost can be sent again.
+type Issuer interface {
+	Name() string
+	Issue(ctx context.Context, req IssueRequest) (*Invoice, error)
+	// Void cancels an invoice as a whole, e.g. when the sale is refunded in
+	// full. Voiding an invoice again fails with ErrAlreadyVoided.
+	Void(ctx context.Context, number, reason string) error
+	Allowance(ctx context.Context, req AllowanceRequest) (*Allowance, error)
+}
+
+// NewFromEnv builds the issuer selected by EINVOICE_PROVIDER. Without one
+// it returns a nil issuer and no e-invoices are issued; the fake issuer must
+// be asked for by name, so a missing setting never hands out fake invoice
+// numbers.
+func NewFromEnv() (Issuer, error) {
+	switch provider := os.Getenv("EINVOICE_PROVIDER"); provider {
+	case "":
+		return nil, nil
+	case "fake":
+		return NewFakeIssuer(), nil
+	default:
+		return nil, fmt.Errorf("unknown e-invoice provider %q", provider)
+	}
+}
@@ -0,0 +1,163 @@
+package einvoice
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"e-commerce/money"
+)
+
+var errFakeUnavailable = errors.New("fake e-invoice provider: service unavailable")
+
+type fakeInvoice struct {
+	issued  Invoice
+	total   money.Money
+	allowed money.Money
+	voided  bool
+}
+
+// FakeIssuer is an in-memory e-invoice provider for development and tests.
+// It numbers invoices from track AA and never talks to the network.
+type FakeIssuer struct {
+	mu         sync.Mutex
+	invoices   map[string]*fakeInvoice
+	references map[string]string
+	allowances map[string]*Allowance
+	next       int
+	down       bool
+	now        func() time.Time
+}
+
+func NewFakeIssuer() *FakeIssuer {
+	return &FakeIssuer{
+		invoices:   make(map[string]*fakeInvoice),
+		references: make(map[string]string),
+		allowances: make(map[string]*Allowance),
+		next:       10000000,
+		now:        time.Now,
+	}
+}
+
+func (f *FakeIssuer) Name() string {
+	return "fake"
+}
+
+// SetDown makes the issuer fail every request, to test retries
+func (f *FakeIssuer) SetDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+// Voided reports whether an invoice was voided
+func (f *FakeIssuer) Voided(number string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	inv, ok := f.invoices[number]
+	return ok && inv.voided
+}
+
+func (f *FakeIssuer) Issue(ctx context.Context, req IssueRequest) (*Invoice, error) {
+	if err := validateBuyer(req.Buyer); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return nil, errFakeUnavailable
+	}
+	if number, ok := f.references[req.Reference]; ok && req.Reference != "" {
+		issued := f.invoices[number].issued
+		return &issued, nil
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return nil, err
+	}
+	number := fmt.Sprintf("AA%08d", f.next)
+	f.next++
+	inv := &fakeInvoice{
+		issued:  Invoice{Number: number, RandomCode: fmt.Sprintf("%04d", n.Int64()), IssuedAt: f.now()},
+		total:   req.Total,
+		allowed: money.Zero(req.Total.Currency),
+	}
+	f.invoices[number] = inv
+	f.references[req.Reference] = number
+	issued := inv.issued
+	return &issued, nil
+}
+
+// validateBuyer rejects what the tax authority's platform would
+func validateBuyer(b Buyer) error {
+	switch b.Type {
+	case BuyerMember:
+		if b.Carrier == "" {
+			return errors.New("member carrier ID is required")
+		}
+	case BuyerMobile:
+		if !ValidMobileBarcode(b.Carrier) {
+			return fmt.Errorf("invalid mobile barcode %q", b.Carrier)
+		}
+	case BuyerDonation:
+		if !ValidLoveCode(b.LoveCode) {
+			return fmt.Errorf("invalid love code %q", b.LoveCode)
+		}
+	case BuyerCompany:
+		if !ValidTaxID(b.TaxID) {
+			return fmt.Errorf("invalid unified business number %q", b.TaxID)
+		}
+	default:
+		return fmt.Errorf("unknown buyer type %q", b.Type)
+	}
+	return nil
+}
+
+func (f *FakeIssuer) Void(ctx context.Context, number, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return errFakeUnavailable
+	}
+	inv, ok := f.invoices[number]
+	if !ok {
+		return ErrInvoiceNotFound
+	}
+	if inv.voided {
+		return ErrAlreadyVoided
+	}
+	inv.voided = true
+	return nil
+}
+
+func (f *FakeIssuer) Allowance(ctx context.Context, req AllowanceRequest) (*Allowance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return nil, errFakeUnavailable
+	}
+	if allowance, ok := f.allowances[req.Reference]; ok && req.Reference != "" {
+		issued := *allowance
+		return &issued, nil
+	}
+	inv, ok := f.invoices[req.InvoiceNumber]
+	if !ok {
+		return nil, ErrInvoiceNotFound
+	}
+	if inv.voided {
+		return nil, ErrAlreadyVoided
+	}
+	if inv.allowed.Add(req.Total).Cmp(inv.total) > 0 {
+		return nil, ErrExceedsInvoice
+	}
+	inv.allowed = inv.allowed.Add(req.Total)
+	now := f.now()
+	allowance := &Allowance{Number: fmt.Sprintf("AL%s%06d", now.Format("20060102"), len(f.allowances)+1), IssuedAt: now}
+	f.allowances[req.Reference] = allowance
+	issued := *allowance
+	return &issued, nil
+}
@@ -0,0 +1,99 @@
+package einvoice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"e-commerce/money"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid func(string) bool
+		code  string
+		want  bool
+	}{
+		{"mobile barcode", ValidMobileBarcode, "/ABC+123", true},
+		{"mobile barcode with dot and dash", ValidMobileBarcode, "/A.-1234", true},
+		{"mobile barcode without slash", ValidMobileBarcode, "ABC+1234", false},
+		{"mobile barcode lower case", ValidMobileBarcode, "/abc1234", false},
+		{"mobile barcode too short", ValidMobileBarcode, "/ABC123", false},
+		{"love code", ValidLoveCode, "168001", true},
+		{"love code too short", ValidLoveCode, "12", false},
+		{"love code letters", ValidLoveCode, "12A4", false},
+		{"tax ID", ValidTaxID, "22099131", true},
+		{"tax ID", ValidTaxID, "04595257", true},
+		{"tax ID bad check digit", ValidTaxID, "12345678", false},
+		{"tax ID seventh digit 7 counted as 1", ValidTaxID, "10000073", true},
+		{"tax ID seventh digit 7 counted as 0", ValidTaxID, "10000074", true},
+		{"tax ID seventh digit 7 invalid", ValidTaxID, "10000075", false},
+		{"tax ID too short", ValidTaxID, "2209913", false},
+	}
+	for _, tt := range tests {
+		if got := tt.valid(tt.code); got != tt.want {
+			t.Errorf("%s %q valid = %v, want %v", tt.name, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestFakeIssuer(t *testing.T) {
+	ctx := context.Background()
+	issuer := NewFakeIssuer()
+	twd := func(n int64) money.Money { return money.New(n*100, "TWD") }
+
+	if _, err := issuer.Issue(ctx, IssueRequest{Buyer: Buyer{Type: BuyerMobile, Carrier: "/abc"}, Total: twd(100)}); err == nil {
+		t.Error("Issue() with a bad barcode succeeded")
+	}
+	inv, err := issuer.Issue(ctx, IssueRequest{Reference: "ORD-1", Buyer: Buyer{Type: BuyerDonation, LoveCode: "168001"}, Tax: twd(5), Total: twd(105)})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if len(inv.Number) != 10 || len(inv.RandomCode) != 4 {
+		t.Errorf("Issue() = %+v, want a track number and a random code", inv)
+	}
+	// 回應遺失後重送同一個參考編號，拿回同一張發票
+	again, err := issuer.Issue(ctx, IssueRequest{Reference: "ORD-1", Buyer: Buyer{Type: BuyerDonation, LoveCode: "168001"}, Tax: twd(5), Total: twd(105)})
+	if err != nil || *again != *inv {
+		t.Errorf("Issue() again = %+v, %v, want %+v", again, err, inv)
+	}
+
+	allowance, err := issuer.Allowance(ctx, AllowanceRequest{Reference: "CN-1", InvoiceNumber: inv.Number, Total: twd(60)})
+	if err != nil {
+		t.Fatalf("Allowance() error = %v", err)
+	}
+	if again, err := issuer.Allowance(ctx, AllowanceRequest{Reference: "CN-1", InvoiceNumber: inv.Number, Total: twd(60)}); err != nil || *again != *allowance {
+		t.Errorf("Allowance() again = %+v, %v, want %+v", again, err, allowance)
+	}
+	if _, err := issuer.Allowance(ctx, AllowanceRequest{Reference: "CN-2", InvoiceNumber: inv.Number, Total: twd(50)}); !errors.Is(err, ErrExceedsInvoice) {
+		t.Errorf("Allowance() over the invoice error = %v, want %v", err, ErrExceedsInvoice)
+	}
+
+	issuer.SetDown(true)
+	if err := issuer.Void(ctx, inv.Number, "refunded"); err == nil {
+		t.Error("Void() with the issuer down succeeded")
+	}
+	issuer.SetDown(false)
+	if err := issuer.Void(ctx, inv.Number, "refunded"); err != nil || !issuer.Voided(inv.Number) {
+		t.Fatalf("Void() error = %v", err)
+	}
+	if err := issuer.Void(ctx, inv.Number, "refunded"); !errors.Is(err, ErrAlreadyVoided) {
+		t.Errorf("Void() twice error = %v, want %v", err, ErrAlreadyVoided)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("EINVOICE_PROVIDER", "")
+	if issuer, err := NewFromEnv(); err != nil || issuer != nil {
+		t.Errorf("NewFromEnv() unset = %v, %v, want no issuer", issuer, err)
+	}
+	t.Setenv("EINVOICE_PROVIDER", "fake")
+	if issuer, err := NewFromEnv(); err != nil || issuer == nil || issuer.Name() != "fake" {
+		t.Errorf("NewFromEnv() = %v, %v, want the fake issuer", issuer, err)
+	}
+	t.Setenv("EINVOICE_PROVIDER", "ecpay")
+	if _, err := NewFromEnv(); err == nil {
+		t.Error("NewFromEnv() with an unknown provider succeeded")
+	}
+}
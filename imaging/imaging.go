@@ -0,0 +1,213 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	// 註冊 GIF 解碼器
+	_ "image/gif"
+)
+
+// Variant describes a derived rendition of an uploaded image
+type Variant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+	Format    string // "jpeg", "png" or "webp"
+}
+
+// DefaultVariants are generated for every product image
+var DefaultVariants = []Variant{
+	{Name: "thumb", MaxWidth: 200, MaxHeight: 200, Format: "jpeg"},
+	{Name: "medium", MaxWidth: 800, MaxHeight: 800, Format: "jpeg"},
+	{Name: "thumb_webp", MaxWidth: 200, MaxHeight: 200, Format: "webp"},
+	{Name: "medium_webp", MaxWidth: 800, MaxHeight: 800, Format: "webp"},
+}
+
+// MaxPixels bounds the images that are decoded: a few kilobytes of
+// compressed data can otherwise expand to gigabytes of pixels. 50 megapixels
+// is about 200 MB once decoded to RGBA.
+const MaxPixels = 50_000_000
+
+var (
+	// ErrEncoderUnavailable is returned when no encoder exists for a format
+	ErrEncoderUnavailable = errors.New("image encoder unavailable")
+	// ErrTooManyPixels is returned for images larger than MaxPixels
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+var contentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+}
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ContentType returns the MIME type for a variant format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Extension returns the file extension for a MIME type
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// CheckPixels reads only the header of an image and fails with
+// ErrTooManyPixels when it is larger than MaxPixels
+func CheckPixels(data []byte) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return ErrTooManyPixels
+	}
+	return nil
+}
+
+// Decode reads an image in any of the registered formats, refusing images
+// larger than MaxPixels before allocating them
+func Decode(data []byte) (image.Image, string, error) {
+	if err := CheckPixels(data); err != nil {
+		return nil, "", err
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+// Fit scales img down so it fits within maxW x maxH, keeping the aspect
+// ratio. Images that already fit are returned unchanged.
+func Fit(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxW && h <= maxH {
+		return img
+	}
+
+	scale := float64(maxW) / float64(w)
+	if s := float64(maxH) / float64(h); s < scale {
+		scale = s
+	}
+	nw, nh := int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+	return resize(img, nw, nh)
+}
+
+// resize uses area averaging, which gives good results when shrinking
+func resize(img image.Image, nw, nh int) image.Image {
+	src := image.NewRGBA(img.Bounds())
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0 := y * sh / nh
+		y1 := (y + 1) * sh / nh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < nw; x++ {
+			x0 := x * sw / nw
+			x1 := (x + 1) * sw / nw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sb.Min.X+sx, sb.Min.Y+sy)
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// Encode writes img in the requested format. WebP output relies on the
+// cwebp binary being installed since the standard library has no encoder.
+func Encode(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	case "webp":
+		return encodeWebP(img)
+	default:
+		return nil, ErrEncoderUnavailable
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten draws img onto a white background since JPEG has no alpha channel
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+func encodeWebP(img image.Image) ([]byte, error) {
+	bin, err := exec.LookPath("cwebp")
+	if err != nil {
+		return nil, ErrEncoderUnavailable
+	}
+
+	dir, err := os.MkdirTemp("", "webp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.webp")
+	f, err := os.Create(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := exec.Command(bin, "-quiet", "-q", strconv.Itoa(80), in, "-o", out).Run(); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(out)
+}
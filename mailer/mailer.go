@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewFromEnv builds the mailer selected by MAIL_DRIVER
+func NewFromEnv() (Mailer, error) {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "", "log":
+		return NewLogMailer(), nil
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
+
+// Recipients splits a comma separated list of addresses
+func Recipients(list string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func validate(msg Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("message has no recipients")
+	}
+	for _, addr := range msg.To {
+		// 防止標頭注入
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("invalid recipient %q", addr)
+		}
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("invalid subject")
+	}
+	return nil
+}
+
+// LogMailer writes messages to the log instead of sending them, which is
+// the default for development
+type LogMailer struct{}
+
+func NewLogMailer() Mailer {
+	return LogMailer{}
+}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	log.Printf("mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryMailer keeps sent messages in memory for tests
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages sent so far
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecipients(t *testing.T) {
+	got := Recipients(" ops@example.com, ,buyer@example.com ")
+	want := []string{"ops@example.com", "buyer@example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Recipients() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg := Message{To: []string{"a@example.com", "b@example.com"}, Subject: "補貨通知", Body: "line 1\nline 2"}
+	got := string(buildMessage("shop@example.com", msg, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	for _, want := range []string{
+		"From: shop@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n",
+		"\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHeaderInjectionIsRejected(t *testing.T) {
+	m := NewMemoryMailer()
+	tests := []Message{
+		{Subject: "no recipients"},
+		{To: []string{"a@example.com\r\nBcc: evil@example.com"}, Subject: "hi"},
+		{To: []string{"a@example.com"}, Subject: "hi\r\nBcc: evil@example.com"},
+	}
+	for _, msg := range tests {
+		if err := m.Send(context.Background(), msg); err == nil {
+			t.Errorf("Send(%q) succeeded, want error", msg.To)
+		}
+	}
+	if len(m.Sent()) != 0 {
+		t.Errorf("Sent() = %d messages, want 0", len(m.Sent()))
+	}
+}
@@ -21,6 +21,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -54,10 +55,39 @@ func main() {
 	// Run database migrations with the injected DB instance
 	migrations.Migrate(container.DB)
 
+	// Start background workers
+	go container.ProductService.Run(context.Background())
+	go container.CatalogService.Run(context.Background())
+	go container.InventoryService.Run(context.Background())
+	go container.RecommendationService.Run(context.Background())
+	go container.OrderService.Run(context.Background())
+	go container.SubscriptionService.Run(context.Background())
+	go container.CartRecoveryService.Run(context.Background())
+	go container.StockAlertService.Run(context.Background())
+
 	r := gin.Default()
 
 	// Setup routes using the container and middleware
 	routes.SetupAuthRoutes(r, container.AuthController, container.AuthMiddleware)
+	routes.SetupProductRoutes(r, container.ProductController, container.MediaController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupCatalogRoutes(r, container.CatalogController, container.AuthMiddleware)
+	routes.SetupReviewRoutes(r, container.ReviewController, container.AuthMiddleware)
+	routes.SetupPricingRoutes(r, container.PriceListController, container.AuthMiddleware)
+	routes.SetupInventoryRoutes(r, container.InventoryController, container.AuthMiddleware)
+	routes.SetupStockAlertRoutes(r, container.StockAlertController, container.AuthMiddleware)
+	routes.SetupRecommendationRoutes(r, container.RecommendationController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupWishlistRoutes(r, container.WishlistController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupCartRoutes(r, container.CartController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupOrderRoutes(r, container.OrderController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupPaymentRoutes(r, container.PaymentController, container.AuthMiddleware)
+	routes.SetupRefundRoutes(r, container.RefundController, container.AuthMiddleware)
+	routes.SetupPromotionRoutes(r, container.PromotionController, container.AuthMiddleware)
+	routes.SetupShippingRoutes(r, container.ShippingController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupAddressRoutes(r, container.AddressController, container.AuthMiddleware)
+	routes.SetupInvoiceRoutes(r, container.InvoiceController, container.AuthMiddleware)
+	routes.SetupReturnRoutes(r, container.ReturnController, container.AuthMiddleware)
+	routes.SetupSubscriptionRoutes(r, container.SubscriptionController, container.AuthMiddleware, container.PricingMiddleware)
+	routes.SetupCartRecoveryRoutes(r, container.CartRecoveryController, container.AuthMiddleware)
 
 	// Swagger documentation route
 	docs.SwaggerInfo.BasePath = "/api/v1"
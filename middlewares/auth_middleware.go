@@ -33,33 +33,77 @@ func (am *AuthMiddleware) Handle() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		user, errMsg := am.authenticate(authHeader)
+		if errMsg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errMsg})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
+		c.Set("user", user)
+		c.Next()
+	}
+}
+
+// Optional sets the user when a valid token is sent but lets anonymous
+// requests through, for public routes that personalise their response
+func (am *AuthMiddleware) Optional() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			if user, errMsg := am.authenticate(authHeader); errMsg == "" {
+				c.Set("user", user)
+			}
 		}
+		c.Next()
+	}
+}
+
+// authenticate resolves the user of a bearer token, returning an error
+// message suitable for the client when it fails
+func (am *AuthMiddleware) authenticate(authHeader string) (models.User, string) {
+	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-		var user models.User
-		if err := am.db.DB.First(&user, claims["sub"]).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+
+	if err != nil || !token.Valid {
+		return models.User{}, "Invalid token"
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return models.User{}, "Invalid token claims"
+	}
+
+	var user models.User
+	if err := am.db.DB.First(&user, claims["sub"]).Error; err != nil {
+		return models.User{}, "User not found"
+	}
+
+	return user, ""
+}
+
+// RequireRole must run after Handle and rejects users whose role is not
+// one of roles
+func (am *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user", user)
-		c.Next()
+		currentUser := user.(models.User)
+		for _, role := range roles {
+			if currentUser.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
 	}
 }
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"e-commerce/models"
+	"e-commerce/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PricingMiddleware struct {
+	pricingService *services.PricingService
+}
+
+func NewPricingMiddleware(pricingService *services.PricingService) *PricingMiddleware {
+	return &PricingMiddleware{
+		pricingService: pricingService,
+	}
+}
+
+// Handle selects the price list from the Accept-Currency header or the
+// signed-in user's preference and stores it as "price_list"
+func (pm *PricingMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user *models.User
+		if u, exists := c.Get("user"); exists {
+			currentUser := u.(models.User)
+			user = &currentUser
+		}
+
+		priceList, err := pm.pricingService.SelectPriceList(c.GetHeader("Accept-Currency"), user)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No price list configured"})
+			c.Abort()
+			return
+		}
+
+		// 回應內容依幣別而異，需告知快取
+		c.Header("Vary", "Accept-Currency, Authorization")
+		c.Header("Content-Currency", priceList.Currency)
+		c.Set("price_list", priceList)
+		c.Next()
+	}
+}
@@ -8,9 +8,91 @@ import (
 )
 
 func Migrate(db *gorm.DB) {
-	err := db.AutoMigrate(&models.User{})
+	// 發佈流程上線前的商品都已公開，新增欄位後維持公開
+	hadProductStatus := db.Migrator().HasColumn(&models.Product{}, "status")
+	hadProducts := db.Migrator().HasTable(&models.Product{})
+
+	err := db.AutoMigrate(
+		&models.User{},
+		&models.Product{},
+		&models.ProductImage{},
+		&models.ProductImageVariant{},
+		&models.ProductRevision{},
+		&models.ImportJob{},
+		&models.ImportRowError{},
+		&models.Review{},
+		&models.ReviewPhoto{},
+		&models.ReviewVote{},
+		&models.PriceList{},
+		&models.ProductPrice{},
+		&models.Warehouse{},
+		&models.StockLevel{},
+		&models.StockMovement{},
+		&models.StockReservation{},
+		&models.ReorderThreshold{},
+		&models.StockAlert{},
+		&models.BackInStockSubscription{},
+		&models.ProductRecommendation{},
+		&models.Wishlist{},
+		&models.WishlistItem{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.OrderTax{},
+		&models.OrderStatusChange{},
+		&models.Payment{},
+		&models.PaymentEvent{},
+		&models.PaymentMethod{},
+		&models.SubscriptionPlan{},
+		&models.Subscription{},
+		&models.CartRecovery{},
+		&models.CartReminderOptOut{},
+		&models.Refund{},
+		&models.RefundItem{},
+		&models.Promotion{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.AppliedPromotion{},
+		&models.ShippingZone{},
+		&models.ShippingMethod{},
+		&models.CustomerAddress{},
+		&models.InvoiceSequence{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+		&models.InvoiceTax{},
+		&models.Return{},
+		&models.ReturnItem{},
+		&models.ReturnPhoto{},
+	)
 	if err != nil {
 		log.Fatal("Failed to migrate database: ", err)
 	}
+
+	if hadProducts && !hadProductStatus {
+		if err := db.Model(&models.Product{}).Where("1 = 1").Updates(map[string]interface{}{
+			"status":       models.ProductStatusPublished,
+			"published_at": gorm.Expr("created_at"),
+		}).Error; err != nil {
+			log.Fatal("Failed to publish existing products: ", err)
+		}
+	}
+
+	if err := seedPriceLists(db); err != nil {
+		log.Fatal("Failed to seed price lists: ", err)
+	}
 	log.Println("Database Migration Completed!")
 }
+
+// seedPriceLists creates the built-in price lists on a fresh database
+func seedPriceLists(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.PriceList{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+	return db.Create([]models.PriceList{
+		{Code: "TW-TWD", Name: "Taiwan", Currency: "TWD", Market: "TW", IsDefault: true, Active: true},
+		{Code: "US-USD", Name: "United States", Currency: "USD", Market: "US", Active: true},
+		{Code: "JP-JPY", Name: "Japan", Currency: "JPY", Market: "JP", Active: true},
+	}).Error
+}
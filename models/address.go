@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Address is a postal address. It is stored inline wherever it is used so
+// that orders keep the address as it was at checkout.
+type Address struct {
+	Name       string `json:"name" gorm:"size:100" example:"王小明"`
+	Phone      string `json:"phone" gorm:"size:32" example:"0912345678"`
+	Line1      string `json:"line1" gorm:"size:255" example:"信義路五段7號"`
+	Line2      string `json:"line2,omitempty" gorm:"size:255" example:"89樓"`
+	City       string `json:"city" gorm:"size:100" example:"台北市"`
+	PostalCode string `json:"postal_code" gorm:"size:16" example:"110"`
+	Country    string `json:"country" gorm:"size:2" example:"TW"`
+}
+
+// CustomerAddress is an address in a user's address book. Checkout copies
+// the Address onto the order, so later edits never change placed orders.
+// At most one address of a user is the default for shipping and one for
+// billing.
+type CustomerAddress struct {
+	ID                uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt         time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt         time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	UserID            uint      `json:"-" gorm:"index"`
+	Label             string    `json:"label,omitempty" gorm:"size:50" example:"Home"`
+	Address           `gorm:"embedded"`
+	IsDefaultShipping bool `json:"is_default_shipping" example:"true"`
+	IsDefaultBilling  bool `json:"is_default_billing" example:"true"`
+}
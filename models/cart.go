@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+// Problems that keep a cart line from being checked out
+const (
+	CartIssueUnavailable       = "unavailable"
+	CartIssueInsufficientStock = "insufficient_stock"
+	CartIssueNoPrice           = "no_price"
+)
+
+// Cart belongs to a signed-in user or, for guests, is identified by an
+// unguessable token that the client keeps and sends back
+type Cart struct {
+	ID        uint       `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"index" example:"2024-01-01T00:00:00Z"`
+	UserID    *uint      `json:"-" gorm:"uniqueIndex"`
+	Token     *string    `json:"token,omitempty" gorm:"uniqueIndex;size:64" example:"5b1d0e..."`
+	Items     []CartItem `json:"items" gorm:"foreignKey:CartID"`
+	// CouponCode is the code entered by the customer, checked again on
+	// every pricing
+	CouponCode string `json:"coupon_code,omitempty" gorm:"size:64" example:"WELCOME100"`
+
+	Subtotal      *money.Money       `json:"subtotal,omitempty" gorm:"-"`
+	DiscountTotal *money.Money       `json:"discount_total,omitempty" gorm:"-"`
+	Total         *money.Money       `json:"total,omitempty" gorm:"-"`
+	Promotions    []AppliedPromotion `json:"promotions,omitempty" gorm:"-"`
+	FreeShipping  bool               `json:"free_shipping,omitempty" gorm:"-"`
+	// CouponIssue says why the entered coupon gives no discount
+	CouponIssue string `json:"coupon_issue,omitempty" gorm:"-" example:"coupon does not apply to this cart"`
+}
+
+// CartItem is a quantity of a product in a cart. Prices are not stored;
+// they come from the price list of each request.
+type CartItem struct {
+	ID        uint      `json:"-" gorm:"primarykey"`
+	CreatedAt time.Time `json:"added_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	CartID    uint      `json:"-" gorm:"uniqueIndex:idx_cart_product"`
+	ProductID uint      `json:"product_id" gorm:"uniqueIndex:idx_cart_product;index" example:"1"`
+	Quantity  int       `json:"quantity" example:"2"`
+
+	Product   *Product     `json:"product,omitempty" gorm:"-"`
+	UnitPrice *money.Money `json:"unit_price,omitempty" gorm:"-"`
+	LineTotal *money.Money `json:"line_total,omitempty" gorm:"-"`
+	// Discounts explain the promotions that applied to the line
+	Discounts []LineDiscount `json:"discounts,omitempty" gorm:"-"`
+	// Issue is set when the line cannot be checked out as it is
+	Issue string `json:"issue,omitempty" gorm:"-" example:"insufficient_stock"`
+}
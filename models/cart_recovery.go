@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	// CartRecoveryStatusActive is an abandoned cart still being reminded of
+	CartRecoveryStatusActive = "active"
+	// CartRecoveryStatusReturned is a cart the customer changed after a
+	// reminder; no more reminders are sent for it
+	CartRecoveryStatusReturned = "returned"
+	// CartRecoveryStatusFinished is a cart that was sent every reminder
+	CartRecoveryStatusFinished     = "finished"
+	CartRecoveryStatusConverted    = "converted"
+	CartRecoveryStatusUnsubscribed = "unsubscribed"
+)
+
+// CartRecovery is one attempt to win back a signed-in customer's cart that
+// sat idle with items in it. A cart changed and abandoned again gets a new
+// recovery. Orders placed from the cart soon after a reminder count as
+// conversions.
+type CartRecovery struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	CartID    uint      `json:"cart_id" gorm:"uniqueIndex:idx_cart_recovery" example:"1"`
+	UserID    uint      `json:"user_id" gorm:"index" example:"7"`
+	// CartUpdatedAt is when the cart was last changed before it was
+	// abandoned
+	CartUpdatedAt  time.Time  `json:"cart_updated_at" gorm:"uniqueIndex:idx_cart_recovery" example:"2024-01-01T00:00:00Z"`
+	Status         string     `json:"status" gorm:"index;size:16" example:"active"`
+	RemindersSent  int        `json:"reminders_sent" example:"1"`
+	NextReminderAt *time.Time `json:"next_reminder_at,omitempty" gorm:"index" example:"2024-01-02T01:00:00Z"`
+	LastRemindedAt *time.Time `json:"last_reminded_at,omitempty" example:"2024-01-01T01:00:00Z"`
+	// CouponCode is the one-time coupon sent with the last reminder
+	CouponCode  string      `json:"coupon_code,omitempty" gorm:"size:64" example:"COMEBACK-3F9A0C1B"`
+	OrderID     *uint       `json:"order_id,omitempty" example:"1"`
+	OrderTotal  money.Money `json:"order_total" gorm:"embedded;embeddedPrefix:order_total_"`
+	ConvertedAt *time.Time  `json:"converted_at,omitempty" example:"2024-01-02T09:30:00Z"`
+}
+
+// CartReminderOptOut is a customer who does not want abandoned cart
+// reminders
+type CartReminderOptOut struct {
+	UserID    uint      `json:"user_id" gorm:"primarykey;autoIncrement:false" example:"7"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// CartRecoveryStats summarizes the recoveries started since a point in
+// time. Revenue is the total of converted orders per currency.
+type CartRecoveryStats struct {
+	Since          time.Time     `json:"since" example:"2024-01-01T00:00:00Z"`
+	Abandoned      int64         `json:"abandoned" example:"120"`
+	Converted      int64         `json:"converted" example:"14"`
+	Unsubscribed   int64         `json:"unsubscribed" example:"3"`
+	ConversionRate float64       `json:"conversion_rate" example:"0.1167"`
+	Revenue        []money.Money `json:"revenue"`
+}
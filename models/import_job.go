@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob tracks an asynchronous catalog import
+type ImportJob struct {
+	ID           uint       `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt    time.Time  `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	UserID       uint       `json:"user_id" gorm:"index" example:"1"`
+	Format       string     `json:"format" example:"csv"`
+	Filename     string     `json:"filename" example:"catalog.csv"`
+	StorageKey   string     `json:"-"`
+	Status       string     `json:"status" gorm:"index" example:"completed"`
+	TotalRows    int        `json:"total_rows" example:"1000"`
+	ImportedRows int        `json:"imported_rows" example:"998"`
+	FailedRows   int        `json:"failed_rows" example:"2"`
+	Error        string     `json:"error,omitempty" example:""`
+	StartedAt    *time.Time `json:"started_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
+
+// ImportRowError records why a single row of an import was rejected
+type ImportRowError struct {
+	ID      uint   `json:"-" gorm:"primarykey"`
+	JobID   uint   `json:"-" gorm:"index"`
+	Row     int    `json:"row" example:"12"`
+	SKU     string `json:"sku" example:"COFFEE-001"`
+	Field   string `json:"field" example:"name"`
+	Message string `json:"message" example:"name is required"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	MovementReceive = "receive"
+	MovementSell    = "sell"
+	MovementAdjust  = "adjust"
+	MovementReturn  = "return"
+	// MovementWriteOff takes returned units that cannot be sold again back
+	// out of stock
+	MovementWriteOff = "write_off"
+)
+
+const (
+	ReservationActive    = "active"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Warehouse is a location holding stock
+type Warehouse struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	Code      string    `json:"code" gorm:"uniqueIndex;size:32" example:"TPE-1"`
+	Name      string    `json:"name" example:"Taipei warehouse"`
+	Active    bool      `json:"active" example:"true"`
+}
+
+// StockLevel is the quantity of a SKU held in a warehouse. Reserved units
+// are still on hand but promised to checkouts in progress.
+type StockLevel struct {
+	ID          uint      `json:"id" gorm:"primarykey" example:"1"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	ProductID   uint      `json:"product_id" gorm:"uniqueIndex:idx_stock_product_warehouse" example:"1"`
+	WarehouseID uint      `json:"warehouse_id" gorm:"uniqueIndex:idx_stock_product_warehouse" example:"1"`
+	OnHand      int       `json:"on_hand" example:"100"`
+	Reserved    int       `json:"reserved" example:"5"`
+}
+
+// Available is the quantity that can still be reserved
+func (s StockLevel) Available() int {
+	return s.OnHand - s.Reserved
+}
+
+// StockMovement is an append-only ledger entry for a change of on-hand
+// stock. Quantity is signed; BalanceAfter is the on-hand quantity after it.
+type StockMovement struct {
+	ID           uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index" example:"2024-01-01T00:00:00Z"`
+	ProductID    uint      `json:"product_id" gorm:"index" example:"1"`
+	WarehouseID  uint      `json:"warehouse_id" example:"1"`
+	Type         string    `json:"type" example:"receive"`
+	Quantity     int       `json:"quantity" example:"50"`
+	BalanceAfter int       `json:"balance_after" example:"150"`
+	Reference    string    `json:"reference,omitempty" gorm:"index" example:"PO-2024-001"`
+	Note         string    `json:"note,omitempty" example:"Weekly delivery"`
+	UserID       *uint     `json:"user_id,omitempty" example:"1"`
+}
+
+// StockReservation holds stock for a checkout until it is committed,
+// released or expires
+type StockReservation struct {
+	ID          uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Reference   string    `json:"reference" gorm:"index" example:"checkout-7f3a"`
+	ProductID   uint      `json:"product_id" example:"1"`
+	WarehouseID uint      `json:"warehouse_id" example:"1"`
+	Quantity    int       `json:"quantity" example:"2"`
+	Status      string    `json:"status" gorm:"index:idx_reservation_status_expiry" example:"active"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index:idx_reservation_status_expiry" example:"2024-01-01T00:15:00Z"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// InvoiceSequence hands out the numbers of one kind of document of a store
+// in a year. A number is taken in the transaction that stores its document,
+// so numbers have no gaps.
+type InvoiceSequence struct {
+	ID    uint   `gorm:"primarykey"`
+	Store string `gorm:"uniqueIndex:idx_invoice_sequence;size:16"`
+	Kind  string `gorm:"uniqueIndex:idx_invoice_sequence;size:16"`
+	Year  int    `gorm:"uniqueIndex:idx_invoice_sequence"`
+	Last  int64
+}
+
+// Invoice is an invoice issued when an order is paid, or a credit note
+// issued when it is refunded. It copies what it bills from the order and is
+// never changed afterwards. For orders with a Taiwan e-invoice, an invoice
+// holds the government invoice number and random code, and a credit note the
+// allowance number, or the invoice number when the refund voided it. These
+// are filled in once the provider has answered; until then the document is
+// stored as pending, so a retry finishes it instead of issuing it twice.
+type Invoice struct {
+	ID                 uint          `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt          time.Time     `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	Kind               string        `json:"kind" gorm:"size:16" example:"invoice"`
+	Number             string        `json:"number" gorm:"uniqueIndex;size:32" example:"INV-WEB-2024-000001"`
+	Store              string        `json:"store" gorm:"size:16" example:"WEB"`
+	Year               int           `json:"year" example:"2024"`
+	Sequence           int64         `json:"-"`
+	Source             string        `json:"-" gorm:"uniqueIndex;size:32"`
+	OrderID            uint          `json:"order_id" gorm:"index" example:"1"`
+	OrderNumber        string        `json:"order_number" gorm:"size:32" example:"ORD-20240101-3F9A0C1B"`
+	RefundID           *uint         `json:"refund_id,omitempty" example:"1"`
+	InvoiceNumber      string        `json:"invoice_number,omitempty" gorm:"size:32" example:"INV-WEB-2024-000001"`
+	UserID             uint          `json:"-" gorm:"index"`
+	IssuedAt           time.Time     `json:"issued_at" example:"2024-01-01T00:00:00Z"`
+	Email              string        `json:"email" example:"user@example.com"`
+	BillingAddress     Address       `json:"billing_address" gorm:"embedded;embeddedPrefix:billing_"`
+	Currency           string        `json:"currency" gorm:"size:3" example:"TWD"`
+	TaxInclusive       bool          `json:"tax_inclusive" example:"true"`
+	Subtotal           money.Money   `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	DiscountTotal      money.Money   `json:"discount_total" gorm:"embedded;embeddedPrefix:discount_total_"`
+	ShippingTotal      money.Money   `json:"shipping_total" gorm:"embedded;embeddedPrefix:shipping_total_"`
+	TaxTotal           money.Money   `json:"tax_total" gorm:"embedded;embeddedPrefix:tax_total_"`
+	Total              money.Money   `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	Reason             string        `json:"reason,omitempty" gorm:"size:500" example:"Damaged in transit"`
+	EInvoiceNumber     string        `json:"einvoice_number,omitempty" gorm:"size:20" example:"AA10000000"`
+	EInvoiceRandomCode string        `json:"einvoice_random_code,omitempty" gorm:"size:4" example:"3861"`
+	EInvoiceVoided     bool          `json:"einvoice_voided,omitempty" example:"false"`
+	EInvoicePending    bool          `json:"einvoice_pending,omitempty" gorm:"index" example:"false"`
+	Lines              []InvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID"`
+	Taxes              []InvoiceTax  `json:"taxes,omitempty" gorm:"foreignKey:InvoiceID"`
+}
+
+// EInvoiceBuyer is how the buyer of an order in Taiwan takes its
+// government uniform invoice: in the shop's member carrier, in a mobile
+// barcode carrier, donated by love code, or as a company invoice with a
+// unified business number.
+type EInvoiceBuyer struct {
+	Type     string `json:"type,omitempty" gorm:"size:16" example:"mobile"`
+	Carrier  string `json:"carrier,omitempty" gorm:"size:64" example:"/ABC+123"`
+	LoveCode string `json:"love_code,omitempty" gorm:"size:7" example:"168001"`
+	TaxID    string `json:"tax_id,omitempty" gorm:"size:8" example:"22099131"`
+	Title    string `json:"title,omitempty" gorm:"size:100" example:"台灣積體電路製造股份有限公司"`
+}
+
+// InvoicePrefixes start the numbers of each kind of document
+var InvoicePrefixes = map[string]string{
+	InvoiceKindInvoice:    "INV",
+	InvoiceKindCreditNote: "CN",
+}
+
+// FormatNumber writes the number of the document with the given place in
+// its sequence, e.g. INV-WEB-2024-000001
+func (i *Invoice) FormatNumber(sequence int64) string {
+	return fmt.Sprintf("%s-%s-%d-%06d", InvoicePrefixes[i.Kind], i.Store, i.Year, sequence)
+}
+
+// IsCreditNote reports whether the document credits a refund
+func (i *Invoice) IsCreditNote() bool {
+	return i.Kind == InvoiceKindCreditNote
+}
+
+type InvoiceLine struct {
+	ID          uint        `json:"-" gorm:"primarykey"`
+	InvoiceID   uint        `json:"-" gorm:"index"`
+	SKU         string      `json:"sku,omitempty" gorm:"size:64" example:"COFFEE-001"`
+	Description string      `json:"description" example:"Ethiopia Yirgacheffe 250g"`
+	Quantity    int         `json:"quantity" example:"2"`
+	UnitPrice   money.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	Total       money.Money `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+}
+
+type InvoiceTax struct {
+	ID        uint        `json:"-" gorm:"primarykey"`
+	InvoiceID uint        `json:"-" gorm:"index"`
+	TaxClass  string      `json:"tax_class" gorm:"size:16" example:"standard"`
+	Rate      int64       `json:"rate" example:"500"`
+	Taxable   money.Money `json:"taxable" gorm:"embedded;embeddedPrefix:taxable_"`
+	Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+}
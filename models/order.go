@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	OrderStatusPendingPayment = "pending_payment"
+	OrderStatusPaid           = "paid"
+	OrderStatusFulfilling     = "fulfilling"
+	OrderStatusShipped        = "shipped"
+	OrderStatusDelivered      = "delivered"
+	OrderStatusCancelled      = "cancelled"
+	OrderStatusRefunded       = "refunded"
+)
+
+// Order is the immutable record of a checkout. Products, prices and
+// addresses are copied in so later catalog changes do not alter it. Stock
+// held for an unpaid order is released at PaymentDueAt. With TaxInclusive
+// the prices already contain TaxTotal and it is not added to Total.
+type Order struct {
+	ID              uint               `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt       time.Time          `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt       time.Time          `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Number          string             `json:"number" gorm:"uniqueIndex;size:32" example:"ORD-20240101-3F9A0C1B"`
+	UserID          uint               `json:"user_id" gorm:"uniqueIndex:idx_order_idempotency;index" example:"1"`
+	IdempotencyKey  string             `json:"-" gorm:"uniqueIndex:idx_order_idempotency;size:255"`
+	RequestHash     string             `json:"-" gorm:"size:64"`
+	Status          string             `json:"status" gorm:"index;size:32" example:"pending_payment"`
+	Email           string             `json:"email" example:"user@example.com"`
+	PriceListID     uint               `json:"price_list_id" example:"1"`
+	Currency        string             `json:"currency" gorm:"size:3" example:"TWD"`
+	Subtotal        money.Money        `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	DiscountTotal   money.Money        `json:"discount_total" gorm:"embedded;embeddedPrefix:discount_total_"`
+	ShippingTotal   money.Money        `json:"shipping_total" gorm:"embedded;embeddedPrefix:shipping_total_"`
+	TaxTotal        money.Money        `json:"tax_total" gorm:"embedded;embeddedPrefix:tax_total_"`
+	TaxInclusive    bool               `json:"tax_inclusive" example:"true"`
+	Total           money.Money        `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	RefundedTotal   money.Money        `json:"refunded_total" gorm:"embedded;embeddedPrefix:refunded_total_"`
+	ShippingAddress Address            `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_"`
+	BillingAddress  Address            `json:"billing_address" gorm:"embedded;embeddedPrefix:billing_"`
+	Note            string             `json:"note,omitempty" gorm:"size:500" example:"Please ring the bell"`
+	EInvoice        EInvoiceBuyer      `json:"einvoice" gorm:"embedded;embeddedPrefix:einvoice_"`
+	ShippingMethod  string             `json:"shipping_method,omitempty" gorm:"size:100" example:"Home delivery"`
+	Carrier         string             `json:"carrier,omitempty" gorm:"size:64" example:"black-cat"`
+	TrackingNumber  string             `json:"tracking_number,omitempty" gorm:"size:64" example:"9056-1234-5678"`
+	PaymentDueAt    time.Time          `json:"payment_due_at" example:"2024-01-01T00:30:00Z"`
+	Items           []OrderItem        `json:"items" gorm:"foreignKey:OrderID"`
+	Promotions      []AppliedPromotion `json:"promotions,omitempty" gorm:"foreignKey:OrderID"`
+	Taxes           []OrderTax         `json:"taxes,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+// OrderItem is a product line of an order as it was sold. DiscountTotal is
+// the share of promotions taken off LineTotal and TaxTotal the tax on what
+// is left. RefundedQuantity and RestockedQuantity count units refunded and
+// put back into stock.
+type OrderItem struct {
+	ID                uint        `json:"id" gorm:"primarykey" example:"1"`
+	OrderID           uint        `json:"-" gorm:"index"`
+	ProductID         uint        `json:"product_id" gorm:"index" example:"1"`
+	SKU               string      `json:"sku" gorm:"size:64" example:"COFFEE-001"`
+	Name              string      `json:"name" example:"Ethiopia Yirgacheffe 250g"`
+	Quantity          int         `json:"quantity" example:"2"`
+	UnitPrice         money.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	LineTotal         money.Money `json:"line_total" gorm:"embedded;embeddedPrefix:line_total_"`
+	DiscountTotal     money.Money `json:"discount_total" gorm:"embedded;embeddedPrefix:discount_total_"`
+	TaxClass          string      `json:"tax_class" gorm:"size:16" example:"standard"`
+	TaxTotal          money.Money `json:"tax_total" gorm:"embedded;embeddedPrefix:tax_total_"`
+	RefundedQuantity  int         `json:"refunded_quantity" example:"0"`
+	RestockedQuantity int         `json:"restocked_quantity" example:"0"`
+}
+
+// OrderTax is the tax charged on an order at one rate. Rate is in basis
+// points and Taxable excludes the tax.
+type OrderTax struct {
+	ID           uint        `json:"-" gorm:"primarykey"`
+	OrderID      uint        `json:"-" gorm:"index"`
+	Jurisdiction string      `json:"jurisdiction" gorm:"size:8" example:"TW"`
+	TaxClass     string      `json:"tax_class" gorm:"size:16" example:"standard"`
+	Rate         int64       `json:"rate" example:"500"`
+	Taxable      money.Money `json:"taxable" gorm:"embedded;embeddedPrefix:taxable_"`
+	Amount       money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+}
+
+// OrderStatusChange records a status transition of an order. A nil ActorID
+// means the change was made by the system.
+type OrderStatusChange struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	OrderID   uint      `json:"order_id" gorm:"index" example:"1"`
+	From      string    `json:"from" gorm:"size:32" example:"pending_payment"`
+	To        string    `json:"to" gorm:"size:32" example:"paid"`
+	ActorID   *uint     `json:"actor_id,omitempty" example:"1"`
+	ActorName string    `json:"actor_name" example:"system"`
+	Reason    string    `json:"reason,omitempty" gorm:"size:500" example:"Payment received"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusRequiresAction    = "requires_action"
+	PaymentStatusAuthorized        = "authorized"
+	PaymentStatusCaptured          = "captured"
+	PaymentStatusDeclined          = "declined"
+	PaymentStatusVoided            = "voided"
+	PaymentStatusFailed            = "failed"
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+)
+
+// Payment is one attempt to pay for an order through a payment provider.
+// Card numbers are never stored, only their last four digits.
+type Payment struct {
+	ID             uint        `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt      time.Time   `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt      time.Time   `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	OrderID        uint        `json:"order_id" gorm:"index" example:"1"`
+	Provider       string      `json:"provider" gorm:"size:32" example:"fake"`
+	ProviderRef    string      `json:"provider_ref" gorm:"index;size:64" example:"ch_3f9a0c1b"`
+	Status         string      `json:"status" gorm:"index;size:32" example:"captured"`
+	Amount         money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	RefundedAmount money.Money `json:"refunded_amount" gorm:"embedded;embeddedPrefix:refunded_amount_"`
+	CardLast4      string      `json:"card_last4,omitempty" gorm:"size:4" example:"4242"`
+	ActionURL      string      `json:"action_url,omitempty" example:"https://fake-3ds.invalid/challenge/ch_3f9a0c1b"`
+	FailureReason  string      `json:"failure_reason,omitempty" example:"card_declined"`
+}
+
+// PaymentEvent is a processed provider webhook. The unique event ID makes
+// redelivered or replayed webhooks no-ops.
+type PaymentEvent struct {
+	ID          uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	Provider    string    `json:"provider" gorm:"uniqueIndex:idx_payment_event;size:32" example:"fake"`
+	EventID     string    `json:"event_id" gorm:"uniqueIndex:idx_payment_event;size:64" example:"evt_3f9a0c1b"`
+	Type        string    `json:"type" gorm:"size:64" example:"payment.authorized"`
+	ProviderRef string    `json:"provider_ref" gorm:"size:64" example:"ch_3f9a0c1b"`
+}
+
+// PaymentMethod is a card a customer saved at the payment provider so it can
+// be charged without them, e.g. for subscription renewals. Only the
+// provider's token and the last four digits are stored.
+type PaymentMethod struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UserID    uint      `json:"-" gorm:"index"`
+	Provider  string    `json:"provider" gorm:"size:32" example:"fake"`
+	Token     string    `json:"-" gorm:"size:128"`
+	CardLast4 string    `json:"card_last4" gorm:"size:4" example:"4242"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+// PriceList holds product prices for one currency and market. The default
+// list is used when a request does not ask for a specific currency.
+type PriceList struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Code      string    `json:"code" gorm:"uniqueIndex;size:32" example:"TW-TWD"`
+	Name      string    `json:"name" example:"Taiwan"`
+	Currency  string    `json:"currency" gorm:"size:3;index" example:"TWD"`
+	Market    string    `json:"market" gorm:"size:2" example:"TW"`
+	IsDefault bool      `json:"is_default" example:"true"`
+	Active    bool      `json:"active" example:"true"`
+}
+
+// ProductPrice is the price of a product in a price list
+type ProductPrice struct {
+	ID          uint        `json:"id" gorm:"primarykey" example:"1"`
+	UpdatedAt   time.Time   `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	PriceListID uint        `json:"price_list_id" gorm:"uniqueIndex:idx_price_list_product" example:"1"`
+	ProductID   uint        `json:"product_id" gorm:"uniqueIndex:idx_price_list_product;index" example:"1"`
+	Price       money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+}
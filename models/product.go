@@ -0,0 +1,103 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	ProductStatusDraft     = "draft"
+	ProductStatusInReview  = "in_review"
+	ProductStatusScheduled = "scheduled"
+	ProductStatusPublished = "published"
+	ProductStatusArchived  = "archived"
+)
+
+// Product represents an item in the catalog
+type Product struct {
+	ID          uint           `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt   time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	SKU         string         `json:"sku" gorm:"uniqueIndex;size:64" example:"COFFEE-001"`
+	Name        string         `json:"name" example:"Ethiopia Yirgacheffe 250g"`
+	Description string         `json:"description" example:"Light roast with floral notes"`
+	Category    string         `json:"category" gorm:"size:64;index" example:"coffee"`
+	TaxClass    string         `json:"tax_class" gorm:"size:16;default:standard" example:"standard"`
+	WeightGrams int            `json:"weight_grams" example:"250"`
+	Status      string         `json:"status" gorm:"size:16;index;default:draft" example:"published"`
+	PublishAt   *time.Time     `json:"publish_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	UnpublishAt *time.Time     `json:"unpublish_at,omitempty" example:"2024-02-01T00:00:00Z"`
+	PublishedAt *time.Time     `json:"published_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	Rating      RatingSummary  `json:"rating" gorm:"embedded;embeddedPrefix:rating_"`
+	Price       *money.Money   `json:"price,omitempty" gorm:"-"`
+	Images      []ProductImage `json:"images,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+// IsVisible reports whether customers can see the product at now. A
+// scheduled product goes live at PublishAt even if the publishing worker
+// has not caught up yet.
+func (p Product) IsVisible(now time.Time) bool {
+	live := p.Status == ProductStatusPublished ||
+		(p.Status == ProductStatusScheduled && p.PublishAt != nil && !p.PublishAt.After(now))
+	return live && (p.UnpublishAt == nil || p.UnpublishAt.After(now))
+}
+
+// RatingSummary is denormalized from approved reviews and recalculated on
+// every review write
+type RatingSummary struct {
+	Average float64 `json:"average" example:"4.5"`
+	Count   int     `json:"count" example:"10"`
+	Star1   int     `json:"star_1" example:"0"`
+	Star2   int     `json:"star_2" example:"0"`
+	Star3   int     `json:"star_3" example:"1"`
+	Star4   int     `json:"star_4" example:"3"`
+	Star5   int     `json:"star_5" example:"6"`
+}
+
+// NewRatingSummary builds a summary from the number of reviews per star,
+// where counts[0] holds one-star reviews
+func NewRatingSummary(counts [5]int) RatingSummary {
+	summary := RatingSummary{
+		Star1: counts[0], Star2: counts[1], Star3: counts[2], Star4: counts[3], Star5: counts[4],
+	}
+	total := 0
+	for i, n := range counts {
+		summary.Count += n
+		total += (i + 1) * n
+	}
+	if summary.Count > 0 {
+		// 四捨五入到小數點後兩位
+		summary.Average = math.Round(float64(total)/float64(summary.Count)*100) / 100
+	}
+	return summary
+}
+
+// ProductImage is an uploaded image attached to a product. Position
+// determines display order, starting at zero for the primary image.
+type ProductImage struct {
+	ID          uint                  `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt   time.Time             `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	ProductID   uint                  `json:"product_id" gorm:"index" example:"1"`
+	Position    int                   `json:"position" example:"0"`
+	StorageKey  string                `json:"-"`
+	ContentType string                `json:"content_type" example:"image/jpeg"`
+	Size        int64                 `json:"size" example:"204800"`
+	Width       int                   `json:"width" example:"1200"`
+	Height      int                   `json:"height" example:"1200"`
+	URL         string                `json:"url" gorm:"-" example:"/api/v1/media/products/1/ab12cd34.jpg"`
+	Variants    []ProductImageVariant `json:"variants,omitempty" gorm:"foreignKey:ImageID;constraint:OnDelete:CASCADE"`
+}
+
+// ProductImageVariant is a resized or re-encoded copy of a ProductImage
+type ProductImageVariant struct {
+	ID          uint   `json:"-" gorm:"primarykey"`
+	ImageID     uint   `json:"-" gorm:"index"`
+	Name        string `json:"name" example:"thumb"`
+	StorageKey  string `json:"-"`
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	Width       int    `json:"width" example:"200"`
+	Height      int    `json:"height" example:"200"`
+	URL         string `json:"url" gorm:"-" example:"/api/v1/media/products/1/ab12cd34_thumb.jpg"`
+}
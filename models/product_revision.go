@@ -0,0 +1,74 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	RevisionActionCreate   = "create"
+	RevisionActionUpdate   = "update"
+	RevisionActionStatus   = "status"
+	RevisionActionImport   = "import"
+	RevisionActionRollback = "rollback"
+)
+
+// ProductSnapshot is the editable state of a product at one revision
+type ProductSnapshot struct {
+	SKU         string     `json:"sku"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	TaxClass    string     `json:"tax_class,omitempty"`
+	WeightGrams int        `json:"weight_grams,omitempty"`
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+}
+
+func NewProductSnapshot(p Product) ProductSnapshot {
+	return ProductSnapshot{
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Description: p.Description,
+		Category:    p.Category,
+		TaxClass:    p.TaxClass,
+		WeightGrams: p.WeightGrams,
+		Status:      p.Status,
+		PublishAt:   p.PublishAt,
+		UnpublishAt: p.UnpublishAt,
+	}
+}
+
+// Value stores the snapshot as JSON
+func (s ProductSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// Scan reads a snapshot stored as JSON
+func (s *ProductSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return errors.New("unsupported product snapshot value")
+}
+
+// ProductRevision is an immutable, numbered snapshot of a product taken
+// after each edit
+type ProductRevision struct {
+	ID         uint            `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt  time.Time       `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	ProductID  uint            `json:"product_id" gorm:"uniqueIndex:idx_product_revision" example:"1"`
+	Version    int             `json:"version" gorm:"uniqueIndex:idx_product_revision" example:"3"`
+	Action     string          `json:"action" example:"update"`
+	AuthorID   *uint           `json:"author_id,omitempty" example:"1"`
+	AuthorName string          `json:"author_name,omitempty" example:"Amy Chen"`
+	Note       string          `json:"note,omitempty" example:"Rolled back to version 1"`
+	Snapshot   ProductSnapshot `json:"snapshot" gorm:"type:jsonb"`
+}
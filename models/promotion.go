@@ -0,0 +1,148 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	PromotionPercentOff   = "percent_off"
+	PromotionFixedAmount  = "fixed_amount"
+	PromotionFreeShipping = "free_shipping"
+	PromotionBuyXGetY     = "buy_x_get_y"
+	PromotionTiered       = "tiered"
+)
+
+// PromotionConditions limit which carts and lines a promotion applies to.
+// Empty lists match everything; a line qualifies when it is in one of the
+// categories or has one of the SKUs. MinSubtotal is compared with the
+// qualifying lines before any discount.
+type PromotionConditions struct {
+	Categories     []string     `json:"categories,omitempty" example:"coffee"`
+	SKUs           []string     `json:"skus,omitempty" example:"COFFEE-001"`
+	CustomerGroups []string     `json:"customer_groups,omitempty" example:"vip"`
+	MinSubtotal    *money.Money `json:"min_subtotal,omitempty"`
+}
+
+// Value stores the conditions as JSON
+func (c PromotionConditions) Value() (driver.Value, error) {
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+// Scan reads conditions stored as JSON
+func (c *PromotionConditions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return errors.New("unsupported promotion conditions value")
+}
+
+// PromotionTier takes Discount off once the qualifying lines reach Threshold
+type PromotionTier struct {
+	Threshold money.Money `json:"threshold"`
+	Discount  money.Money `json:"discount"`
+}
+
+// PromotionTiers are the steps of a tiered promotion, lowest threshold first
+type PromotionTiers []PromotionTier
+
+// Value stores the tiers as JSON
+func (t PromotionTiers) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+// Scan reads tiers stored as JSON
+func (t *PromotionTiers) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	}
+	return errors.New("unsupported promotion tiers value")
+}
+
+// Promotion is a discount rule. Rules are tried from the highest Priority
+// down; an Exclusive rule only applies to a cart no other rule has
+// discounted yet and stops the rules after it. Rules that RequireCoupon
+// apply only when one of their coupon codes is entered.
+type Promotion struct {
+	ID             uint       `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt      time.Time  `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Name           string     `json:"name" gorm:"size:100" example:"Spend NT$2000 save NT$200"`
+	Description    string     `json:"description,omitempty" example:"Automatically applied at checkout"`
+	Type           string     `json:"type" gorm:"size:32" example:"tiered"`
+	Active         bool       `json:"active" gorm:"index" example:"true"`
+	Priority       int        `json:"priority" example:"10"`
+	Exclusive      bool       `json:"exclusive" example:"false"`
+	RequiresCoupon bool       `json:"requires_coupon" example:"false"`
+	StartsAt       *time.Time `json:"starts_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	EndsAt         *time.Time `json:"ends_at,omitempty" example:"2024-02-01T00:00:00Z"`
+	// Currency is required by rules with amounts; percentage and buy X get
+	// Y rules without one apply in every currency
+	Currency    string              `json:"currency,omitempty" gorm:"size:3" example:"TWD"`
+	Percent     int                 `json:"percent,omitempty" example:"10"`
+	Amount      money.Money         `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	BuyQuantity int                 `json:"buy_quantity,omitempty" example:"2"`
+	GetQuantity int                 `json:"get_quantity,omitempty" example:"1"`
+	Tiers       PromotionTiers      `json:"tiers,omitempty" gorm:"type:jsonb"`
+	Conditions  PromotionConditions `json:"conditions" gorm:"type:jsonb"`
+}
+
+// IsRunning reports whether the promotion is active and within its date
+// window at now
+func (p Promotion) IsRunning(now time.Time) bool {
+	return p.Active && (p.StartsAt == nil || !p.StartsAt.After(now)) && (p.EndsAt == nil || p.EndsAt.After(now))
+}
+
+// Coupon is a code that unlocks a promotion. Zero limits mean unlimited;
+// UsedCount only changes inside the transaction that places an order.
+type Coupon struct {
+	ID           uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt    time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	PromotionID  uint      `json:"promotion_id" gorm:"index" example:"1"`
+	Code         string    `json:"code" gorm:"uniqueIndex;size:64" example:"WELCOME100"`
+	UsageLimit   int       `json:"usage_limit" example:"1000"`
+	PerUserLimit int       `json:"per_user_limit" example:"1"`
+	UsedCount    int       `json:"used_count" example:"12"`
+}
+
+// CouponRedemption records a coupon used by an order. It is deleted, and
+// the use given back, when the order is cancelled.
+type CouponRedemption struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	CouponID  uint      `json:"coupon_id" gorm:"uniqueIndex:idx_coupon_redemption;index:idx_coupon_user" example:"1"`
+	UserID    uint      `json:"user_id" gorm:"index:idx_coupon_user" example:"1"`
+	OrderID   uint      `json:"order_id" gorm:"uniqueIndex:idx_coupon_redemption;index" example:"1"`
+}
+
+// AppliedPromotion is a promotion that discounted a cart. Checkout copies
+// them onto the order.
+type AppliedPromotion struct {
+	ID           uint        `json:"-" gorm:"primarykey"`
+	OrderID      uint        `json:"-" gorm:"index"`
+	PromotionID  uint        `json:"promotion_id" example:"1"`
+	Name         string      `json:"name" gorm:"size:100" example:"Spend NT$2000 save NT$200"`
+	CouponID     *uint       `json:"-"`
+	CouponCode   string      `json:"coupon_code,omitempty" gorm:"size:64" example:"WELCOME100"`
+	Discount     money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	FreeShipping bool        `json:"free_shipping,omitempty" example:"false"`
+}
+
+// LineDiscount explains how much a promotion took off a cart line
+type LineDiscount struct {
+	PromotionID uint        `json:"promotion_id" example:"1"`
+	Name        string      `json:"name" example:"Buy 2 get 1 free"`
+	Amount      money.Money `json:"amount"`
+}
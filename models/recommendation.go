@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// ProductRecommendation links a product to one that is often bought with
+// it. Support is the number of orders containing both products and
+// Confidence is Support divided by the orders containing ProductID. Rows
+// are rebuilt by the recommendation batch job.
+type ProductRecommendation struct {
+	ID               uint      `json:"-" gorm:"primarykey"`
+	ProductID        uint      `json:"product_id" gorm:"uniqueIndex:idx_product_recommendation" example:"1"`
+	RelatedProductID uint      `json:"related_product_id" gorm:"uniqueIndex:idx_product_recommendation" example:"2"`
+	Rank             int       `json:"rank" example:"1"`
+	Support          int       `json:"support" example:"12"`
+	Confidence       float64   `json:"confidence" example:"0.4"`
+	ComputedAt       time.Time `json:"computed_at" example:"2024-01-01T00:00:00Z"`
+}
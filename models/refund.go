@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+// Refund returns money of a captured payment, optionally for specific order
+// lines. A pending refund already counts against the refundable amount so
+// two concurrent refunds cannot exceed what was captured.
+type Refund struct {
+	ID            uint         `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt     time.Time    `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt     time.Time    `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	OrderID       uint         `json:"order_id" gorm:"index" example:"1"`
+	PaymentID     uint         `json:"payment_id" gorm:"index" example:"1"`
+	ProviderRef   string       `json:"provider_ref,omitempty" gorm:"size:64" example:"re_3f9a0c1b"`
+	Status        string       `json:"status" gorm:"size:32" example:"succeeded"`
+	Amount        money.Money  `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Reason        string       `json:"reason,omitempty" gorm:"size:500" example:"Damaged in transit"`
+	Restock       bool         `json:"restock" example:"true"`
+	ActorID       *uint        `json:"actor_id,omitempty" example:"1"`
+	FailureReason string       `json:"failure_reason,omitempty" example:"charge is not in a state that allows this operation"`
+	Items         []RefundItem `json:"items,omitempty" gorm:"foreignKey:RefundID"`
+}
+
+// RefundItem is a quantity of an order line covered by a refund
+type RefundItem struct {
+	ID          uint `json:"id" gorm:"primarykey" example:"1"`
+	RefundID    uint `json:"-" gorm:"index"`
+	OrderItemID uint `json:"order_item_id" example:"1"`
+	ProductID   uint `json:"product_id" example:"1"`
+	Quantity    int  `json:"quantity" example:"1"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	// ReturnStatusReceived is an inspected return whose refund has not gone
+	// through yet
+	ReturnStatusReceived  = "received"
+	ReturnStatusCompleted = "completed"
+)
+
+// Reasons a customer can give for returning a line
+const (
+	ReturnReasonDamaged        = "damaged"
+	ReturnReasonDefective      = "defective"
+	ReturnReasonWrongItem      = "wrong_item"
+	ReturnReasonNotAsDescribed = "not_as_described"
+	ReturnReasonChangedMind    = "changed_mind"
+	ReturnReasonOther          = "other"
+)
+
+// Outcomes of inspecting a returned line
+const (
+	ReturnOutcomeRestock  = "restock"
+	ReturnOutcomeWriteOff = "write_off"
+)
+
+// Return is a customer's request to send back lines of a delivered order
+// (RMA). Staff approve it with a prepaid return label or reject it, then
+// inspect what arrives; completing the inspection refunds the lines.
+type Return struct {
+	ID             uint         `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt      time.Time    `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt      time.Time    `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Number         string       `json:"number" gorm:"uniqueIndex;size:32" example:"RMA-20240101-3F9A0C1B"`
+	OrderID        uint         `json:"order_id" gorm:"index" example:"1"`
+	OrderNumber    string       `json:"order_number" gorm:"size:32" example:"ORD-20240101-3F9A0C1B"`
+	UserID         uint         `json:"user_id" gorm:"index" example:"1"`
+	Status         string       `json:"status" gorm:"index;size:32" example:"requested"`
+	Note           string       `json:"note,omitempty" gorm:"size:500" example:"The bag arrived torn"`
+	ResolutionNote string       `json:"resolution_note,omitempty" gorm:"size:500" example:"Outside the return policy"`
+	Carrier        string       `json:"carrier,omitempty" gorm:"size:64" example:"fake"`
+	TrackingNumber string       `json:"tracking_number,omitempty" gorm:"size:64" example:"FK0000000001"`
+	LabelURL       string       `json:"label_url,omitempty" example:"https://labels.example.com/FK0000000001.pdf"`
+	RefundID       *uint        `json:"refund_id,omitempty" example:"1"`
+	ReviewedBy     *uint        `json:"-"`
+	ReviewedAt     *time.Time   `json:"reviewed_at,omitempty" example:"2024-01-02T00:00:00Z"`
+	InspectedBy    *uint        `json:"-"`
+	InspectedAt    *time.Time   `json:"inspected_at,omitempty" example:"2024-01-05T00:00:00Z"`
+	Items          []ReturnItem `json:"items" gorm:"foreignKey:ReturnID"`
+}
+
+// ReturnItem is a quantity of an order line being returned. Outcome is set
+// when staff inspect it.
+type ReturnItem struct {
+	ID          uint          `json:"id" gorm:"primarykey" example:"1"`
+	ReturnID    uint          `json:"-" gorm:"index"`
+	OrderItemID uint          `json:"order_item_id" example:"1"`
+	ProductID   uint          `json:"product_id" example:"1"`
+	SKU         string        `json:"sku" gorm:"size:64" example:"COFFEE-001"`
+	Name        string        `json:"name" example:"Ethiopia Yirgacheffe 250g"`
+	Quantity    int           `json:"quantity" example:"1"`
+	Reason      string        `json:"reason" gorm:"size:32" example:"damaged"`
+	Comment     string        `json:"comment,omitempty" gorm:"size:500" example:"Coffee spilled in the box"`
+	Outcome     string        `json:"outcome,omitempty" gorm:"size:16" example:"restock"`
+	Photos      []ReturnPhoto `json:"photos,omitempty" gorm:"foreignKey:ReturnItemID;constraint:OnDelete:CASCADE"`
+}
+
+// ReturnPhoto is an image showing the state of a returned line
+type ReturnPhoto struct {
+	ID           uint   `json:"id" gorm:"primarykey" example:"1"`
+	ReturnItemID uint   `json:"-" gorm:"index"`
+	StorageKey   string `json:"-"`
+	ContentType  string `json:"content_type" example:"image/jpeg"`
+	URL          string `json:"url" gorm:"-" example:"/api/v1/media/returns/1/ab12cd34.jpg"`
+}
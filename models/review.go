@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// Review is a verified buyer's rating of a product. Only approved reviews
+// are public and count towards the product rating.
+type Review struct {
+	ID             uint          `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt      time.Time     `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt      time.Time     `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	ProductID      uint          `json:"product_id" gorm:"uniqueIndex:idx_review_product_user;index" example:"1"`
+	UserID         uint          `json:"user_id" gorm:"uniqueIndex:idx_review_product_user" example:"1"`
+	AuthorName     string        `json:"author_name" example:"John D."`
+	Rating         int           `json:"rating" example:"5"`
+	Title          string        `json:"title" example:"Great coffee"`
+	Body           string        `json:"body" example:"Fruity and fresh, will buy again."`
+	Status         string        `json:"status" gorm:"index" example:"approved"`
+	ModerationNote string        `json:"moderation_note,omitempty" example:""`
+	ModeratedBy    *uint         `json:"-"`
+	ModeratedAt    *time.Time    `json:"moderated_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	HelpfulCount   int           `json:"helpful_count" example:"3"`
+	Photos         []ReviewPhoto `json:"photos,omitempty" gorm:"foreignKey:ReviewID;constraint:OnDelete:CASCADE"`
+}
+
+// ReviewPhoto is an image attached to a review
+type ReviewPhoto struct {
+	ID          uint   `json:"id" gorm:"primarykey" example:"1"`
+	ReviewID    uint   `json:"-" gorm:"index"`
+	StorageKey  string `json:"-"`
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	URL         string `json:"url" gorm:"-" example:"/api/v1/media/reviews/1/ab12cd34.jpg"`
+}
+
+// ReviewVote records that a user found a review helpful
+type ReviewVote struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	ReviewID  uint `gorm:"uniqueIndex:idx_review_vote"`
+	UserID    uint `gorm:"uniqueIndex:idx_review_vote"`
+}
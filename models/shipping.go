@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"e-commerce/money"
+)
+
+const (
+	ShippingRateFlat   = "flat"
+	ShippingRateWeight = "weight"
+	ShippingRatePrice  = "price"
+	ShippingRateFree   = "free"
+	// ShippingRateCarrier asks the carrier of the method for a live rate
+	ShippingRateCarrier = "carrier"
+)
+
+// ShippingArea lists the places a zone covers. Cities and postal code
+// prefixes narrow a zone down within its countries; empty lists match
+// the whole country.
+type ShippingArea struct {
+	Countries   []string `json:"countries" example:"TW"`
+	Cities      []string `json:"cities,omitempty" example:"台北市"`
+	PostalCodes []string `json:"postal_codes,omitempty" example:"1"`
+}
+
+// Value stores the area as JSON
+func (a ShippingArea) Value() (driver.Value, error) {
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// Scan reads an area stored as JSON
+func (a *ShippingArea) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	}
+	return errors.New("unsupported shipping area value")
+}
+
+// ShippingRateTier charges Amount from Min upwards. Min is in grams for
+// weight-based rates and in minor units of the method currency for
+// price-based rates.
+type ShippingRateTier struct {
+	Min    int64       `json:"min" example:"1000"`
+	Amount money.Money `json:"amount"`
+}
+
+// ShippingRateTiers are the steps of a rate table, lowest Min first
+type ShippingRateTiers []ShippingRateTier
+
+// Value stores the tiers as JSON
+func (t ShippingRateTiers) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+// Scan reads tiers stored as JSON
+func (t *ShippingRateTiers) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	}
+	return errors.New("unsupported shipping rate tiers value")
+}
+
+// ShippingZone is a set of destinations sharing shipping methods. When
+// several zones cover an address the most specific one is used.
+type ShippingZone struct {
+	ID        uint             `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time        `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time        `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Name      string           `json:"name" gorm:"size:100" example:"Taiwan main island"`
+	Area      ShippingArea     `json:"area" gorm:"type:jsonb"`
+	Methods   []ShippingMethod `json:"methods,omitempty" gorm:"foreignKey:ZoneID"`
+}
+
+// ShippingMethod is a way of delivering to a zone and how it is charged.
+// Orders whose value reaches FreeOver ship for free with any rate type.
+type ShippingMethod struct {
+	ID        uint              `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt time.Time         `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time         `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	ZoneID    uint              `json:"zone_id" gorm:"index" example:"1"`
+	Name      string            `json:"name" gorm:"size:100" example:"Home delivery"`
+	Active    bool              `json:"active" example:"true"`
+	Currency  string            `json:"currency" gorm:"size:3" example:"TWD"`
+	RateType  string            `json:"rate_type" gorm:"size:16" example:"flat"`
+	Amount    money.Money       `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Tiers     ShippingRateTiers `json:"tiers,omitempty" gorm:"type:jsonb"`
+	FreeOver  *money.Money      `json:"free_over,omitempty" gorm:"embedded;embeddedPrefix:free_over_"`
+	// Carrier and CarrierService name the live rate of carrier methods
+	Carrier        string `json:"carrier,omitempty" gorm:"size:32" example:"fake"`
+	CarrierService string `json:"carrier_service,omitempty" gorm:"size:32" example:"express"`
+	EstimatedDays  int    `json:"estimated_days,omitempty" example:"2"`
+}
+
+// ShippingQuote is the price of a shipping method for a cart and address
+type ShippingQuote struct {
+	MethodID      uint        `json:"method_id" example:"1"`
+	Name          string      `json:"name" example:"Home delivery"`
+	Carrier       string      `json:"carrier,omitempty" example:"fake"`
+	Amount        money.Money `json:"amount"`
+	EstimatedDays int         `json:"estimated_days,omitempty" example:"2"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// ReorderThreshold is the available quantity of a product below which
+// staff are alerted to reorder
+type ReorderThreshold struct {
+	ProductID uint      `json:"product_id" gorm:"primarykey;autoIncrement:false" example:"1"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Threshold int       `json:"threshold" example:"10"`
+}
+
+// StockAlert records that a product fell below its reorder threshold. A
+// product has at most one open alert; it is resolved once stock recovers.
+type StockAlert struct {
+	ID         uint       `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	ProductID  uint       `json:"product_id" gorm:"uniqueIndex:idx_open_stock_alert,where:resolved_at IS NULL" example:"1"`
+	Threshold  int        `json:"threshold" example:"10"`
+	Available  int        `json:"available" example:"3"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty" example:"2024-01-02T00:00:00Z"`
+}
+
+// BackInStockSubscription asks for an email when a product can be bought
+// again. NotifiedAt is set once the email has been sent.
+type BackInStockSubscription struct {
+	ID         uint       `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	ProductID  uint       `json:"product_id" gorm:"uniqueIndex:idx_stock_subscription" example:"1"`
+	UserID     uint       `json:"user_id" gorm:"uniqueIndex:idx_stock_subscription" example:"1"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty" example:"2024-01-02T00:00:00Z"`
+}
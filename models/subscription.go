@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// Intervals between subscription deliveries
+const (
+	SubscriptionIntervalWeek  = "week"
+	SubscriptionIntervalMonth = "month"
+)
+
+const (
+	SubscriptionStatusActive = "active"
+	SubscriptionStatusPaused = "paused"
+	// SubscriptionStatusPastDue is a subscription whose renewal failed and
+	// is being retried
+	SubscriptionStatusPastDue = "past_due"
+	// SubscriptionStatusRenewing is a subscription whose renewal is being
+	// placed and charged. Customers cannot change it until that is done.
+	SubscriptionStatusRenewing  = "renewing"
+	SubscriptionStatusCancelled = "cancelled"
+)
+
+// SubscriptionPlan is a product customers can have delivered on a schedule,
+// e.g. a bag of coffee beans every two weeks at 10% off
+type SubscriptionPlan struct {
+	ID              uint      `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt       time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt       time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Name            string    `json:"name" gorm:"size:100" example:"Coffee of the month"`
+	ProductID       uint      `json:"product_id" gorm:"index" example:"1"`
+	Quantity        int       `json:"quantity" example:"1"`
+	Interval        string    `json:"interval" gorm:"size:8" example:"week"`
+	IntervalCount   int       `json:"interval_count" example:"2"`
+	DiscountPercent int       `json:"discount_percent" example:"10"`
+	Active          bool      `json:"active" gorm:"index" example:"true"`
+}
+
+// Subscription is a customer's standing order for a plan. Each renewal
+// places an order and charges it to a saved payment method; failed renewals
+// are retried (dunning) before the subscription is cancelled.
+type Subscription struct {
+	ID               uint       `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt        time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt        time.Time  `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	UserID           uint       `json:"-" gorm:"index"`
+	PlanID           uint       `json:"plan_id" gorm:"index" example:"1"`
+	PlanName         string     `json:"plan_name" gorm:"size:100" example:"Coffee of the month"`
+	ProductID        uint       `json:"product_id" example:"1"`
+	Quantity         int        `json:"quantity" example:"1"`
+	Interval         string     `json:"interval" gorm:"size:8" example:"week"`
+	IntervalCount    int        `json:"interval_count" example:"2"`
+	DiscountPercent  int        `json:"discount_percent" example:"10"`
+	Status           string     `json:"status" gorm:"index;size:16" example:"active"`
+	PriceListID      uint       `json:"-"`
+	PaymentMethodID  uint       `json:"payment_method_id" example:"1"`
+	ShippingAddress  Address    `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_"`
+	ShippingMethodID uint       `json:"shipping_method_id,omitempty" example:"1"`
+	NextRenewalAt    time.Time  `json:"next_renewal_at" gorm:"index" example:"2024-01-15T00:00:00Z"`
+	LastRenewedAt    *time.Time `json:"last_renewed_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	LastOrderID      *uint      `json:"last_order_id,omitempty" example:"1"`
+	// Renewals counts the orders placed and paid for the subscription
+	Renewals       int        `json:"renewals" example:"1"`
+	FailedAttempts int        `json:"failed_attempts,omitempty" example:"0"`
+	NextRetryAt    *time.Time `json:"next_retry_at,omitempty" gorm:"index" example:"2024-01-16T00:00:00Z"`
+	LastFailure    string     `json:"last_failure,omitempty" gorm:"size:500" example:"payment was declined: card_declined"`
+	CancelReason   string     `json:"cancel_reason,omitempty" gorm:"size:500" example:"Too much coffee"`
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty" example:"2024-02-01T00:00:00Z"`
+	// Version is bumped by every update, so concurrent changes cannot
+	// overwrite each other
+	Version int `json:"-"`
+}
@@ -15,6 +15,23 @@ type User struct {
 	Name      string    `json:"name" example:"John Doe"`
 	Email     string    `json:"email" gorm:"unique" example:"user@example.com"`
 	Password  string    `json:"password,omitempty" example:"password123"`
+	Role      string    `json:"role" gorm:"default:customer" example:"customer"`
+	// PreferredCurrency selects the price list when the request does not
+	// send an Accept-Currency header
+	PreferredCurrency string `json:"preferred_currency,omitempty" gorm:"size:3" example:"TWD"`
+	// CustomerGroup is set by staff and targets promotions, e.g. "vip"
+	CustomerGroup string `json:"customer_group,omitempty" gorm:"size:32" example:"vip"`
+}
+
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// IsStaff reports whether the user may manage the catalog and orders
+func (u *User) IsStaff() bool {
+	return u.Role == RoleStaff || u.Role == RoleAdmin
 }
 
 func (u *User) HashPassword() error {
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"e-commerce/money"
+)
+
+// Wishlist is a named list of products a user saved for later. A share
+// token, once generated, gives read-only access to anyone holding it.
+type Wishlist struct {
+	ID         uint           `json:"id" gorm:"primarykey" example:"1"`
+	CreatedAt  time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt  time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	UserID     uint           `json:"-" gorm:"uniqueIndex:idx_wishlist_user_name"`
+	Name       string         `json:"name" gorm:"uniqueIndex:idx_wishlist_user_name;size:100" example:"Birthday"`
+	ShareToken *string        `json:"share_token,omitempty" gorm:"uniqueIndex;size:64" example:"3f9a0c..."`
+	Items      []WishlistItem `json:"items" gorm:"foreignKey:WishlistID"`
+}
+
+// WishlistItem is a product on a wishlist. AddedPrice is the price shown
+// when the item was added, from the price list the user was browsing, so
+// later price drops can be pointed out.
+type WishlistItem struct {
+	ID          uint        `json:"-" gorm:"primarykey"`
+	CreatedAt   time.Time   `json:"added_at" example:"2024-01-01T00:00:00Z"`
+	WishlistID  uint        `json:"-" gorm:"uniqueIndex:idx_wishlist_product"`
+	ProductID   uint        `json:"product_id" gorm:"uniqueIndex:idx_wishlist_product;index" example:"1"`
+	PriceListID uint        `json:"-"`
+	AddedPrice  money.Money `json:"added_price" gorm:"embedded;embeddedPrefix:added_price_"`
+
+	Product      *Product     `json:"product,omitempty" gorm:"-"`
+	CurrentPrice *money.Money `json:"current_price,omitempty" gorm:"-"`
+	// PriceDrop is how much cheaper the product is than when it was added
+	PriceDrop *money.Money `json:"price_drop,omitempty" gorm:"-"`
+}
@@ -0,0 +1,281 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Currency describes how amounts in a currency are stored and rounded.
+// Amounts are always kept in ISO 4217 minor units; Increment is the
+// smallest amount used in practice (TWD is priced in whole dollars).
+type Currency struct {
+	Code      string
+	Symbol    string
+	Exponent  int
+	Increment int64
+}
+
+var currencies = map[string]Currency{
+	"TWD": {Code: "TWD", Symbol: "NT$", Exponent: 2, Increment: 100},
+	"USD": {Code: "USD", Symbol: "US$", Exponent: 2, Increment: 1},
+	"JPY": {Code: "JPY", Symbol: "¥", Exponent: 0, Increment: 1},
+}
+
+// Lookup returns the currency registered for an ISO 4217 code
+func Lookup(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, ErrUnknownCurrency
+	}
+	return c, nil
+}
+
+// IsSupported reports whether code is a known currency
+func IsSupported(code string) bool {
+	_, err := Lookup(code)
+	return err == nil
+}
+
+// Money is an exact amount in minor units of a currency
+type Money struct {
+	Amount   int64  `json:"amount" example:"120000"`
+	Currency string `json:"currency" gorm:"size:3" example:"TWD"`
+}
+
+// New creates an amount from minor units
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Zero returns a zero amount in currency
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Parse reads a decimal string such as "12.50" without going through
+// floating point. More decimals than the currency allows is an error.
+func Parse(s, currency string) (Money, error) {
+	c, err := Lookup(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return Money{}, ErrInvalidAmount
+	}
+	if len(frac) > c.Exponent {
+		return Money{}, fmt.Errorf("%w: %s allows at most %d decimals", ErrInvalidAmount, c.Code, c.Exponent)
+	}
+	frac += strings.Repeat("0", c.Exponent-len(frac))
+
+	digits := whole + frac
+	if strings.TrimLeft(digits, "0123456789") != "" {
+		return Money{}, ErrInvalidAmount
+	}
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+func (m Money) currency() Currency {
+	c, err := Lookup(m.Currency)
+	if err != nil {
+		// 未知幣別以兩位小數處理，避免格式化時 panic
+		return Currency{Code: m.Currency, Exponent: 2, Increment: 1}
+	}
+	return c
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) mustMatch(o Money) {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: %v: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency))
+	}
+}
+
+// Add returns m + o. Mixing currencies is a programming error and panics.
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}
+}
+
+// Sub returns m - o. Mixing currencies is a programming error and panics.
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}
+}
+
+// Cmp compares two amounts of the same currency, returning -1, 0 or 1
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Mul multiplies by a whole quantity
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// MulFrac multiplies by num/den, rounding half away from zero to the
+// nearest minor unit
+func (m Money) MulFrac(num, den int64) Money {
+	return Money{Amount: divRound(m.Amount*num, den), Currency: m.Currency}
+}
+
+// Round rounds half away from zero to the currency's practical increment
+func (m Money) Round() Money {
+	inc := m.currency().Increment
+	if inc <= 1 {
+		return m
+	}
+	return Money{Amount: divRound(m.Amount, inc) * inc, Currency: m.Currency}
+}
+
+// IsRounded reports whether m is already a multiple of the currency increment
+func (m Money) IsRounded() bool {
+	return m.Round() == m
+}
+
+// Allocate splits m across the given weights without losing minor units;
+// the remainder goes to the earliest shares
+func (m Money) Allocate(weights ...int64) []Money {
+	var total int64
+	for _, w := range weights {
+		total += w
+	}
+	shares := make([]Money, len(weights))
+	if total == 0 {
+		for i := range shares {
+			shares[i] = Zero(m.Currency)
+		}
+		return shares
+	}
+
+	remainder := m.Amount
+	for i, w := range weights {
+		shares[i] = Money{Amount: m.Amount * w / total, Currency: m.Currency}
+		remainder -= shares[i].Amount
+	}
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(shares) {
+		if weights[i] == 0 {
+			continue
+		}
+		shares[i].Amount += step
+		remainder -= step
+	}
+	return shares
+}
+
+// Decimal formats the amount as a plain decimal string, e.g. "1200.00"
+func (m Money) Decimal() string {
+	c := m.currency()
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if c.Exponent == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	s := fmt.Sprintf("%0*d", c.Exponent+1, amount)
+	return sign + s[:len(s)-c.Exponent] + "." + s[len(s)-c.Exponent:]
+}
+
+// String formats the amount for display, e.g. "NT$1,200" or "US$12.50".
+// Currencies rounded to whole units are shown without decimals.
+func (m Money) String() string {
+	c := m.currency()
+	decimal := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(decimal, "-") {
+		sign = "-"
+		decimal = decimal[1:]
+	}
+
+	whole, frac, _ := strings.Cut(decimal, ".")
+	if c.Increment > 1 && strings.Trim(frac, "0") == "" {
+		frac = ""
+	}
+
+	var grouped strings.Builder
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(d)
+	}
+
+	symbol := c.Symbol
+	if symbol == "" {
+		symbol = c.Code + " "
+	}
+	if frac != "" {
+		return sign + symbol + grouped.String() + "." + frac
+	}
+	return sign + symbol + grouped.String()
+}
+
+// MarshalJSON adds a formatted value next to the raw minor units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount    int64  `json:"amount"`
+		Currency  string `json:"currency"`
+		Formatted string `json:"formatted"`
+	}{m.Amount, m.Currency, m.String()})
+}
+
+func divRound(n, d int64) int64 {
+	if d < 0 {
+		n, d = -n, -d
+	}
+	q, r := n/d, n%d
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= d {
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
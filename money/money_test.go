@@ -0,0 +1,104 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"1200", "TWD", 120000, false},
+		{"1,200.5", "TWD", 120050, false},
+		{"12.50", "USD", 1250, false},
+		{"-3.1", "USD", -310, false},
+		{"1500", "JPY", 1500, false},
+		{"1500.5", "JPY", 0, true},
+		{"12.345", "USD", 0, true},
+		{"abc", "USD", 0, true},
+		{"", "USD", 0, true},
+		{"10", "EUR", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+" "+tt.currency, func(t *testing.T) {
+			got, err := Parse(tt.input, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Amount != tt.want {
+				t.Errorf("Parse() = %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundingAndFormatting(t *testing.T) {
+	tests := []struct {
+		name    string
+		money   Money
+		rounded int64
+		display string
+	}{
+		{"TWD rounds to whole dollars", New(120050, "TWD"), 120100, "NT$1,201"},
+		{"TWD rounds down", New(120049, "TWD"), 120000, "NT$1,200"},
+		{"TWD negative", New(-150, "TWD"), -200, "-NT$2"},
+		{"USD keeps cents", New(123456, "USD"), 123456, "US$1,234.56"},
+		{"JPY has no decimals", New(1500, "JPY"), 1500, "¥1,500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rounded := tt.money.Round()
+			if rounded.Amount != tt.rounded {
+				t.Errorf("Round() = %d, want %d", rounded.Amount, tt.rounded)
+			}
+			if got := rounded.String(); got != tt.display {
+				t.Errorf("String() = %q, want %q", got, tt.display)
+			}
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	price := New(33300, "TWD")
+	if got := price.Mul(3).Amount; got != 99900 {
+		t.Errorf("Mul() = %d, want 99900", got)
+	}
+	// 5% of NT$333 is NT$16.65
+	if got := price.MulFrac(5, 100).Amount; got != 1665 {
+		t.Errorf("MulFrac() = %d, want 1665", got)
+	}
+
+	shares := New(1000, "USD").Allocate(1, 1, 1)
+	if shares[0].Amount != 334 || shares[1].Amount != 333 || shares[2].Amount != 333 {
+		t.Errorf("Allocate() = %v", shares)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Add() with different currencies should panic")
+		}
+	}()
+	New(1, "USD").Add(New(1, "JPY"))
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(New(120000, "TWD"))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"amount":120000,"currency":"TWD","formatted":"NT$1,200"}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var m Money
+	if err := json.Unmarshal(data, &m); err != nil || m != New(120000, "TWD") {
+		t.Errorf("Unmarshal() = %+v, %v", m, err)
+	}
+}
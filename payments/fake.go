@@ -0,0 +1,223 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"e-commerce/money"
+)
+
+// Test cards understood by FakeProvider
+const (
+	TestCardSuccess           = "4242424242424242"
+	TestCardDeclined          = "4000000000000002"
+	TestCardInsufficientFunds = "4000000000009995"
+	TestCard3DS               = "4000000000003220"
+	TestCardProcessingError   = "4000000000000119"
+)
+
+// FakeSignatureHeader carries the signature of fake provider webhooks
+const FakeSignatureHeader = "Fake-Signature"
+
+var errFakeUnavailable = errors.New("fake provider: processing error")
+
+type fakeCharge struct {
+	reference string
+	amount    money.Money
+	status    string
+	captured  money.Money
+	refunded  money.Money
+}
+
+// FakeProvider is an in-memory gateway for development and tests. It never
+// talks to the network; the card number alone decides the outcome.
+type FakeProvider struct {
+	mu      sync.Mutex
+	secret  []byte
+	charges map[string]*fakeCharge
+	// methods maps saved method tokens to their card numbers
+	methods map[string]string
+	now     func() time.Time
+}
+
+// NewFakeProvider creates a fake gateway that signs webhooks with secret.
+// Without a secret a random one is used, so only webhooks made by this
+// instance verify.
+func NewFakeProvider(secret string) *FakeProvider {
+	if secret == "" {
+		secret = randomID("whsec_")
+	}
+	return &FakeProvider{
+		secret:  []byte(secret),
+		charges: make(map[string]*fakeCharge),
+		methods: make(map[string]string),
+		now:     time.Now,
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	card := strings.ReplaceAll(req.Method, " ", "")
+	if saved, ok := p.methods[card]; ok {
+		card = saved
+	}
+	if card == TestCardProcessingError {
+		return nil, errFakeUnavailable
+	}
+
+	ref := randomID("ch_")
+	charge := &fakeCharge{reference: req.Reference, amount: req.Amount, captured: money.Zero(req.Amount.Currency), refunded: money.Zero(req.Amount.Currency)}
+	p.charges[ref] = charge
+
+	result := &Result{ProviderRef: ref}
+	switch card {
+	case TestCardSuccess:
+		charge.status = StatusAuthorized
+	case TestCard3DS:
+		if req.OffSession {
+			charge.status = StatusDeclined
+			result.FailureReason = "authentication_required"
+			break
+		}
+		charge.status = StatusRequiresAction
+		result.ActionURL = "https://fake-3ds.invalid/challenge/" + ref
+	case TestCardInsufficientFunds:
+		charge.status = StatusDeclined
+		result.FailureReason = "insufficient_funds"
+	case TestCardDeclined:
+		charge.status = StatusDeclined
+		result.FailureReason = "card_declined"
+	default:
+		charge.status = StatusDeclined
+		result.FailureReason = "unknown_test_card"
+	}
+	result.Status = charge.status
+	return result, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, providerRef string, amount money.Money) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[providerRef]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	if charge.status == StatusCaptured && charge.captured == amount {
+		// 重送的 capture 視為成功
+		return &Result{ProviderRef: providerRef, Status: StatusCaptured}, nil
+	}
+	if charge.status != StatusAuthorized || amount.Currency != charge.amount.Currency || amount.Cmp(charge.amount) > 0 {
+		return nil, ErrInvalidState
+	}
+	charge.status = StatusCaptured
+	charge.captured = amount
+	return &Result{ProviderRef: providerRef, Status: StatusCaptured}, nil
+}
+
+func (p *FakeProvider) Void(ctx context.Context, providerRef string) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[providerRef]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	if charge.status != StatusAuthorized && charge.status != StatusRequiresAction && charge.status != StatusVoided {
+		return nil, ErrInvalidState
+	}
+	charge.status = StatusVoided
+	return &Result{ProviderRef: providerRef, Status: StatusVoided}, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, providerRef string, amount money.Money) (*Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	charge, ok := p.charges[providerRef]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	if charge.status != StatusCaptured && charge.status != StatusRefunded {
+		return nil, ErrInvalidState
+	}
+	if amount.Currency != charge.captured.Currency || amount.Amount <= 0 || charge.refunded.Add(amount).Cmp(charge.captured) > 0 {
+		return nil, ErrInvalidState
+	}
+	charge.refunded = charge.refunded.Add(amount)
+	if charge.refunded == charge.captured {
+		charge.status = StatusRefunded
+	}
+	return &Result{ProviderRef: randomID("re_"), Status: StatusRefunded}, nil
+}
+
+// SaveMethod stores any card number; whether charging it succeeds is decided
+// by the test card when it is charged
+func (p *FakeProvider) SaveMethod(ctx context.Context, card string) (*SavedMethod, error) {
+	card = strings.ReplaceAll(card, " ", "")
+	if len(card) < 12 || strings.Trim(card, "0123456789") != "" {
+		return nil, errors.New("fake provider: invalid card number")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	token := randomID("pm_")
+	p.methods[token] = card
+	return &SavedMethod{Token: token, CardLast4: card[len(card)-4:]}, nil
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	if err := VerifySignature(p.secret, payload, header.Get(FakeSignatureHeader), p.now()); err != nil {
+		return nil, err
+	}
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" || event.ProviderRef == "" {
+		return nil, errors.New("malformed webhook payload")
+	}
+	return &event, nil
+}
+
+// Complete3DS simulates the customer finishing (or failing) a 3-D Secure
+// challenge. It returns the signed webhook the gateway would send, ready to
+// be posted to the webhook endpoint.
+func (p *FakeProvider) Complete3DS(providerRef string, approve bool) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	charge, ok := p.charges[providerRef]
+	if !ok {
+		p.mu.Unlock()
+		return nil, nil, ErrChargeNotFound
+	}
+	if charge.status != StatusRequiresAction {
+		p.mu.Unlock()
+		return nil, nil, ErrInvalidState
+	}
+	event := Event{ID: randomID("evt_"), ProviderRef: providerRef, Amount: charge.amount, CreatedAt: p.now()}
+	if approve {
+		charge.status = StatusAuthorized
+		event.Type = EventAuthorized
+	} else {
+		charge.status = StatusDeclined
+		event.Type = EventFailed
+		event.Reason = "authentication_failed"
+	}
+	p.mu.Unlock()
+
+	return p.SignEvent(event)
+}
+
+// SignEvent encodes and signs an event as the fake gateway would
+func (p *FakeProvider) SignEvent(event Event) ([]byte, http.Header, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, Sign(p.secret, payload, p.now()))
+	return payload, header, nil
+}
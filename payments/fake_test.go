@@ -0,0 +1,132 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"e-commerce/money"
+)
+
+func TestFakeCardMatrix(t *testing.T) {
+	p := NewFakeProvider("secret")
+	tests := []struct {
+		card       string
+		wantStatus string
+		wantReason string
+	}{
+		{TestCardSuccess, StatusAuthorized, ""},
+		{"4242 4242 4242 4242", StatusAuthorized, ""},
+		{TestCardDeclined, StatusDeclined, "card_declined"},
+		{TestCardInsufficientFunds, StatusDeclined, "insufficient_funds"},
+		{TestCard3DS, StatusRequiresAction, ""},
+		{"5555555555554444", StatusDeclined, "unknown_test_card"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.card, func(t *testing.T) {
+			result, err := p.Authorize(context.Background(), AuthorizeRequest{Reference: "ORD-1", Amount: money.New(1000, "TWD"), Method: tt.card})
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if result.Status != tt.wantStatus || result.FailureReason != tt.wantReason {
+				t.Errorf("Authorize() = %s (%s), want %s (%s)", result.Status, result.FailureReason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+
+	if _, err := p.Authorize(context.Background(), AuthorizeRequest{Amount: money.New(1000, "TWD"), Method: TestCardProcessingError}); err == nil {
+		t.Error("Authorize() with the processing error card succeeded")
+	}
+}
+
+func TestFakeCaptureVoidRefund(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("secret")
+	amount := money.New(1000, "TWD")
+	result, _ := p.Authorize(ctx, AuthorizeRequest{Amount: amount, Method: TestCardSuccess})
+
+	if _, err := p.Refund(ctx, result.ProviderRef, amount); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Refund() before capture error = %v, want %v", err, ErrInvalidState)
+	}
+	if _, err := p.Capture(ctx, result.ProviderRef, amount); err != nil {
+		t.Fatalf("Capture() error = %v", err)
+	}
+	if _, err := p.Void(ctx, result.ProviderRef); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Void() after capture error = %v, want %v", err, ErrInvalidState)
+	}
+	if _, err := p.Refund(ctx, result.ProviderRef, money.New(400, "TWD")); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if _, err := p.Refund(ctx, result.ProviderRef, money.New(700, "TWD")); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Refund() beyond the captured amount error = %v, want %v", err, ErrInvalidState)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	p := NewFakeProvider("secret")
+	result, _ := p.Authorize(context.Background(), AuthorizeRequest{Amount: money.New(1000, "TWD"), Method: TestCard3DS})
+	payload, header, err := p.Complete3DS(result.ProviderRef, true)
+	if err != nil {
+		t.Fatalf("Complete3DS() error = %v", err)
+	}
+
+	event, err := p.VerifyWebhook(payload, header)
+	if err != nil || event.Type != EventAuthorized || event.ProviderRef != result.ProviderRef {
+		t.Fatalf("VerifyWebhook() = %+v, %v, want an authorized event", event, err)
+	}
+
+	tampered := append([]byte(nil), payload...)
+	tampered[len(tampered)-2] ^= 1
+	if _, err := p.VerifyWebhook(tampered, header); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered payload error = %v, want %v", err, ErrInvalidSignature)
+	}
+	if _, err := NewFakeProvider("other").VerifyWebhook(payload, header); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("wrong secret error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// 超過容許時間的簽章視為重放
+	p.now = func() time.Time { return time.Now().Add(signatureTolerance + time.Minute) }
+	if _, err := p.VerifyWebhook(payload, header); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("stale signature error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestFakeSavedMethod(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("secret")
+	amount := money.New(1000, "TWD")
+
+	saved, err := p.SaveMethod(ctx, "4242 4242 4242 4242")
+	if err != nil || saved.CardLast4 != "4242" || saved.Token == "" {
+		t.Fatalf("SaveMethod() = %+v, %v, want a token for a card ending in 4242", saved, err)
+	}
+	if result, err := p.Authorize(ctx, AuthorizeRequest{Amount: amount, Method: saved.Token, OffSession: true}); err != nil || result.Status != StatusAuthorized {
+		t.Errorf("Authorize() with a saved card = %+v, %v, want authorized", result, err)
+	}
+
+	// 無人在場時需要 3DS 的卡會被拒
+	saved, _ = p.SaveMethod(ctx, TestCard3DS)
+	result, err := p.Authorize(ctx, AuthorizeRequest{Amount: amount, Method: saved.Token, OffSession: true})
+	if err != nil || result.Status != StatusDeclined || result.FailureReason != "authentication_required" {
+		t.Errorf("off-session Authorize() with a 3-D Secure card = %+v, %v, want declined", result, err)
+	}
+	if _, err := p.SaveMethod(ctx, "not a card"); err == nil {
+		t.Error("SaveMethod() with an invalid card succeeded")
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "")
+	if _, err := NewFromEnv(); err == nil {
+		t.Error("NewFromEnv() without PAYMENT_PROVIDER succeeded")
+	}
+	t.Setenv("PAYMENT_PROVIDER", "fake")
+	if provider, err := NewFromEnv(); err != nil || provider == nil {
+		t.Errorf("NewFromEnv() = %v, %v, want the fake provider", provider, err)
+	}
+	t.Setenv("PAYMENT_PROVIDER", "stripe")
+	if _, err := NewFromEnv(); err == nil {
+		t.Error("NewFromEnv() with an unknown provider succeeded")
+	}
+}
@@ -0,0 +1,163 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-commerce/money"
+)
+
+// Statuses a provider reports for a charge
+const (
+	StatusAuthorized     = "authorized"
+	StatusRequiresAction = "requires_action"
+	StatusDeclined       = "declined"
+	StatusCaptured       = "captured"
+	StatusVoided         = "voided"
+	StatusRefunded       = "refunded"
+)
+
+// Webhook event types
+const (
+	EventAuthorized = "payment.authorized"
+	EventFailed     = "payment.failed"
+	EventCaptured   = "payment.captured"
+	EventRefunded   = "payment.refunded"
+)
+
+// signatureTolerance is how old a signed webhook may be before it is
+// rejected as a replay
+const signatureTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrChargeNotFound   = errors.New("charge not found")
+	ErrInvalidState     = errors.New("charge is not in a state that allows this operation")
+)
+
+// AuthorizeRequest asks a provider to hold an amount on a payment method
+type AuthorizeRequest struct {
+	// Reference is our own identifier, sent along for reconciliation
+	Reference string
+	Amount    money.Money
+	// Method is a provider-specific token for the card or wallet
+	Method string
+	// OffSession is set when the customer is not there to complete 3-D
+	// Secure, e.g. for subscription renewals. Charges that need it are
+	// declined instead.
+	OffSession bool
+}
+
+// SavedMethod is a card stored at the provider for later charges
+type SavedMethod struct {
+	// Token is passed as AuthorizeRequest.Method to charge the card
+	Token     string
+	CardLast4 string
+}
+
+// Result is the outcome of a provider call
+type Result struct {
+	ProviderRef string
+	Status      string
+	// ActionURL is where the customer completes 3-D Secure when Status is
+	// StatusRequiresAction
+	ActionURL     string
+	FailureReason string
+}
+
+// Event is a verified webhook notification
+type Event struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	ProviderRef string      `json:"provider_ref"`
+	Amount      money.Money `json:"amount"`
+	Reason      string      `json:"reason,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// PaymentProvider is a payment gateway. Implementations must be safe for
+// concurrent use.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Result, error)
+	Capture(ctx context.Context, providerRef string, amount money.Money) (*Result, error)
+	Void(ctx context.Context, providerRef string) (*Result, error)
+	Refund(ctx context.Context, providerRef string, amount money.Money) (*Result, error)
+	// SaveMethod stores a card so it can be charged again without the
+	// customer entering it
+	SaveMethod(ctx context.Context, card string) (*SavedMethod, error)
+	// VerifyWebhook checks the signature of a webhook and parses its event
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// NewFromEnv builds the provider selected by PAYMENT_PROVIDER. The fake
+// provider accepts any test card, so it has to be chosen explicitly.
+func NewFromEnv() (PaymentProvider, error) {
+	switch provider := os.Getenv("PAYMENT_PROVIDER"); provider {
+	case "":
+		return nil, errors.New("PAYMENT_PROVIDER is not set")
+	case "fake":
+		return NewFakeProvider(os.Getenv("PAYMENT_WEBHOOK_SECRET")), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", provider)
+	}
+}
+
+// Sign returns a signature header value for payload, in the form
+// "t=<unix time>,v1=<hex hmac>"
+func Sign(secret []byte, payload []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + computeSignature(secret, ts, payload)
+}
+
+func computeSignature(secret []byte, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a header made by Sign. Signatures older than the
+// tolerance are rejected so a captured webhook cannot be replayed later.
+func VerifySignature(secret []byte, payload []byte, header string, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(sig), []byte(computeSignature(secret, ts, payload))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func randomID(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,181 @@
+// Package pdf writes plain-text documents such as invoices as PDF. Text is
+// laid out on a grid of cells: ASCII takes one cell and is set in Courier,
+// everything else takes two and is set in MSung-Light, a CJK font PDF
+// readers supply themselves, so no fonts need to be embedded.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	pageWidth  = 595.28 // A4
+	pageHeight = 841.89
+	margin     = 48
+	fontSize   = 10
+	cellWidth  = 6 // Courier 的字寬為 0.6 em
+	leading    = 14
+
+	// Columns is the number of cells on a line
+	Columns = 83
+	// Lines is the number of lines on a page
+	Lines = 53
+)
+
+// Width returns the number of cells s takes
+func Width(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	return 2
+}
+
+// Render lays out text on A4 pages. Lines longer than Columns are cut, a
+// form feed starts a new page and pages break by themselves after Lines.
+func Render(text string) []byte {
+	var pages [][]string
+	for _, page := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\f") {
+		lines := strings.Split(strings.TrimSuffix(page, "\n"), "\n")
+		for len(lines) > Lines {
+			pages = append(pages, lines[:Lines])
+			lines = lines[Lines:]
+		}
+		pages = append(pages, lines)
+	}
+
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// 1 目錄、2 頁面樹、3–6 字型，之後每頁一個頁面與一個內容串流
+	pageRefs := make([]string, len(pages))
+	for i := range pages {
+		pageRefs[i] = fmt.Sprintf("%d 0 R", 7+2*i)
+	}
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(pages)))
+	w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	w.object("<< /Type /Font /Subtype /Type0 /BaseFont /MSung-Light /Encoding /UniCNS-UCS2-H /DescendantFonts [5 0 R] >>")
+	w.object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /MSung-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (CNS1) /Supplement 0 >> /FontDescriptor 6 0 R /DW 1000 >>")
+	w.object("<< /Type /FontDescriptor /FontName /MSung-Light /Flags 6 /FontBBox [-160 -249 1015 1071] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	for i, lines := range pages {
+		content := pageContent(lines)
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 8+2*i))
+		w.object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+	return w.finish()
+}
+
+// pageContent draws each line as runs of ASCII and wide characters placed
+// at their cells
+func pageContent(lines []string) string {
+	var b strings.Builder
+	b.WriteString("BT\n")
+	for i, line := range lines {
+		y := pageHeight - margin - fontSize - float64(i*leading)
+		cell := 0
+		for _, run := range splitRuns(cut(line, Columns)) {
+			x := margin + float64(cell*cellWidth)
+			if runeWidth([]rune(run)[0]) == 1 {
+				fmt.Fprintf(&b, "/F1 %d Tf 0 Tc 1 0 0 1 %.2f %.2f Tm (%s) Tj\n", fontSize, x, y, escape(run))
+			} else {
+				// 全形字寬 1 em，補上字距讓每字佔兩格
+				fmt.Fprintf(&b, "/F2 %d Tf %d Tc 1 0 0 1 %.2f %.2f Tm <%s> Tj\n", fontSize, 2*cellWidth-fontSize, x, y, ucs2(run))
+			}
+			cell += Width(run)
+		}
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+// cut shortens s to at most cells cells
+func cut(s string, cells int) string {
+	n := 0
+	for i, r := range s {
+		if n += runeWidth(r); n > cells {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// splitRuns splits a line where it changes between ASCII and wide characters
+func splitRuns(line string) []string {
+	var runs []string
+	start := 0
+	for i, r := range line {
+		if i > start {
+			prev, _ := utf8.DecodeLastRuneInString(line[:i])
+			if runeWidth(prev) != runeWidth(r) {
+				runs = append(runs, line[start:i])
+				start = i
+			}
+		}
+	}
+	if start < len(line) {
+		runs = append(runs, line[start:])
+	}
+	return runs
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r == 0x7f:
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ucs2 encodes s as hex UCS-2. Characters outside the Basic Multilingual
+// Plane cannot be shown with the font and become a full-width question mark.
+func ucs2(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xffff {
+			r = '？'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *writer) object(body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", len(w.offsets), body)
+}
+
+func (w *writer) finish() []byte {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.buf.Bytes()
+}
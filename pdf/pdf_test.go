@@ -0,0 +1,64 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	text := "INVOICE 發票\n" + strings.Repeat("line\n", Lines) + "(total)"
+	doc := Render(text)
+
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatal("Render() is not a complete PDF file")
+	}
+	if !bytes.Contains(doc, []byte("/Count 2")) {
+		t.Error("Render() did not break onto a second page")
+	}
+	// 「發票」以 UCS-2 編碼
+	if !bytes.Contains(doc, []byte("<767C7968>")) {
+		t.Error("Render() did not encode the CJK text")
+	}
+	if !bytes.Contains(doc, []byte(`(\(total\))`)) {
+		t.Error("Render() did not escape parentheses")
+	}
+
+	// 交叉參照表的位移須指向各物件
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(doc)
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := strings.Split(string(doc[xref:]), "\n")[3:]
+	for i, entry := range entries {
+		if strings.HasPrefix(entry, "trailer") {
+			break
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, doc[offset:offset+len(want)], want)
+		}
+	}
+}
+
+func TestTemplatePadding(t *testing.T) {
+	tests := []struct {
+		fn    string
+		cells int
+		in    string
+		want  string
+	}{
+		{"pad", 6, "abc", "abc   "},
+		{"lpad", 6, "abc", "   abc"},
+		{"pad", 6, "咖啡豆", "咖啡豆"},
+		{"pad", 5, "咖啡豆", "咖啡 "},
+		{"lpad", 3, "abcdef", "abc"},
+	}
+	for _, tt := range tests {
+		got := funcs[tt.fn].(func(int, any) string)(tt.cells, tt.in)
+		if got != tt.want || Width(got) != tt.cells {
+			t.Errorf("%s(%d, %q) = %q, want %q", tt.fn, tt.cells, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package pdf
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Templates renders documents from text/template files into PDF. Each file
+// is named after the document it renders, e.g. invoice.tmpl.
+type Templates struct {
+	tmpl *template.Template
+}
+
+var funcs = template.FuncMap{
+	// pad 靠左、lpad 靠右對齊到指定格數，過長則截斷
+	"pad": func(cells int, v any) string {
+		s := cut(fmt.Sprint(v), cells)
+		return s + strings.Repeat(" ", cells-Width(s))
+	},
+	"lpad": func(cells int, v any) string {
+		s := cut(fmt.Sprint(v), cells)
+		return strings.Repeat(" ", cells-Width(s)) + s
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"rule": func() string {
+		return strings.Repeat("-", Columns)
+	},
+	"percent": func(basisPoints int64) string {
+		return fmt.Sprintf("%d.%02d%%", basisPoints/100, basisPoints%100)
+	},
+}
+
+// NewTemplates loads the built-in templates. Files in dir, when given,
+// replace the built-in templates of the same name.
+func NewTemplates(dir string) (*Templates, error) {
+	tmpl, err := template.New("").Funcs(funcs).ParseFS(defaultTemplates, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			if tmpl, err = tmpl.ParseFiles(files...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Templates{tmpl: tmpl}, nil
+}
+
+// NewTemplatesFromEnv loads the templates, replacing built-in ones with those
+// in DOCUMENT_TEMPLATE_DIR
+func NewTemplatesFromEnv() (*Templates, error) {
+	return NewTemplates(os.Getenv("DOCUMENT_TEMPLATE_DIR"))
+}
+
+// Render executes the template name, e.g. "invoice.tmpl", with data and
+// lays the result out as PDF
+func (t *Templates) Render(name string, data any) ([]byte, error) {
+	var text bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&text, name, data); err != nil {
+		return nil, err
+	}
+	return Render(text.String()), nil
+}
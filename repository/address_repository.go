@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+type AddressRepository interface {
+	// Create and Update take the default flags away from the user's other
+	// addresses when address has them
+	Create(address *models.CustomerAddress) error
+	Update(address *models.CustomerAddress) error
+	Delete(id uint) error
+	FindByID(id uint) (*models.CustomerAddress, error)
+	FindByUserID(userID uint) ([]models.CustomerAddress, error)
+}
+
+type GormAddressRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAddressRepository(db *gorm.DB) AddressRepository {
+	return &GormAddressRepository{db: db}
+}
+
+// clearDefaults removes the defaults address takes over from the other
+// addresses of its user
+func clearDefaults(tx *gorm.DB, address *models.CustomerAddress) error {
+	others := tx.Model(&models.CustomerAddress{}).Where("user_id = ? AND id <> ?", address.UserID, address.ID)
+	if address.IsDefaultShipping {
+		if err := others.Session(&gorm.Session{}).Update("is_default_shipping", false).Error; err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultBilling {
+		if err := others.Session(&gorm.Session{}).Update("is_default_billing", false).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GormAddressRepository) Create(address *models.CustomerAddress) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(address).Error; err != nil {
+			return err
+		}
+		return clearDefaults(tx, address)
+	})
+}
+
+func (r *GormAddressRepository) Update(address *models.CustomerAddress) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(address).Error; err != nil {
+			return err
+		}
+		return clearDefaults(tx, address)
+	})
+}
+
+func (r *GormAddressRepository) Delete(id uint) error {
+	return r.db.Delete(&models.CustomerAddress{}, id).Error
+}
+
+func (r *GormAddressRepository) FindByID(id uint) (*models.CustomerAddress, error) {
+	var address models.CustomerAddress
+	if err := r.db.First(&address, id).Error; err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (r *GormAddressRepository) FindByUserID(userID uint) ([]models.CustomerAddress, error) {
+	var addresses []models.CustomerAddress
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&addresses).Error
+	return addresses, err
+}
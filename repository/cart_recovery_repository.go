@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCartRecoveryChanged = errors.New("cart recovery was changed by someone else")
+
+type CartRecoveryRepository interface {
+	// FindAbandoned returns carts of users who have not opted out, with
+	// items, last changed after idleAfter and no later than idleBefore, that
+	// have no recovery yet for that change, least recently changed first
+	FindAbandoned(idleAfter, idleBefore time.Time, limit int) ([]models.Cart, error)
+	Create(recovery *models.CartRecovery) error
+	// FindDue returns active recoveries with a reminder due at now
+	FindDue(now time.Time, limit int) ([]models.CartRecovery, error)
+	// FindOpen returns the latest recovery of a cart that has not converted
+	// or been unsubscribed
+	FindOpen(cartID uint) (*models.CartRecovery, error)
+	// FindByStatus returns recoveries in a status, or all of them for an
+	// empty status, newest first
+	FindByStatus(status string, offset, limit int) ([]models.CartRecovery, error)
+	// Update saves a recovery if it is still in status from, and fails with
+	// ErrCartRecoveryChanged otherwise
+	Update(recovery *models.CartRecovery, from string) error
+	// CountByStatus counts the recoveries started since a time by status
+	CountByStatus(since time.Time) (map[string]int64, error)
+	// Revenue totals the orders of recoveries started since a time that
+	// converted, per currency
+	Revenue(since time.Time) ([]money.Money, error)
+
+	// OptOut records that a user wants no reminders and stops their active
+	// recoveries
+	OptOut(userID uint) error
+	OptIn(userID uint) error
+	IsOptedOut(userID uint) (bool, error)
+}
+
+type GormCartRecoveryRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCartRecoveryRepository(db *gorm.DB) CartRecoveryRepository {
+	return &GormCartRecoveryRepository{db: db}
+}
+
+func (r *GormCartRecoveryRepository) FindAbandoned(idleAfter, idleBefore time.Time, limit int) ([]models.Cart, error) {
+	var carts []models.Cart
+	err := preloadCartItems(r.db).
+		Where("carts.user_id IS NOT NULL AND carts.updated_at > ? AND carts.updated_at <= ?", idleAfter, idleBefore).
+		Where("EXISTS (SELECT 1 FROM cart_items WHERE cart_items.cart_id = carts.id)").
+		Where("NOT EXISTS (SELECT 1 FROM cart_recoveries WHERE cart_recoveries.cart_id = carts.id AND cart_recoveries.cart_updated_at = carts.updated_at)").
+		Where("NOT EXISTS (SELECT 1 FROM cart_reminder_opt_outs WHERE cart_reminder_opt_outs.user_id = carts.user_id)").
+		Order("carts.updated_at").Limit(limit).Find(&carts).Error
+	return carts, err
+}
+
+func (r *GormCartRecoveryRepository) Create(recovery *models.CartRecovery) error {
+	return r.db.Create(recovery).Error
+}
+
+func (r *GormCartRecoveryRepository) FindDue(now time.Time, limit int) ([]models.CartRecovery, error) {
+	var recoveries []models.CartRecovery
+	err := r.db.Where("status = ? AND next_reminder_at <= ?", models.CartRecoveryStatusActive, now).
+		Order("id").Limit(limit).Find(&recoveries).Error
+	return recoveries, err
+}
+
+func (r *GormCartRecoveryRepository) FindOpen(cartID uint) (*models.CartRecovery, error) {
+	var recovery models.CartRecovery
+	err := r.db.Where("cart_id = ? AND status IN ?", cartID, []string{
+		models.CartRecoveryStatusActive, models.CartRecoveryStatusReturned, models.CartRecoveryStatusFinished,
+	}).Order("id DESC").First(&recovery).Error
+	if err != nil {
+		return nil, err
+	}
+	return &recovery, nil
+}
+
+func (r *GormCartRecoveryRepository) FindByStatus(status string, offset, limit int) ([]models.CartRecovery, error) {
+	var recoveries []models.CartRecovery
+	query := r.db.Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Offset(offset).Limit(limit).Find(&recoveries).Error
+	return recoveries, err
+}
+
+func (r *GormCartRecoveryRepository) Update(recovery *models.CartRecovery, from string) error {
+	// 以狀態為條件更新，避免提醒排程與結帳同時修改
+	result := r.db.Model(recovery).Where("status = ?", from).Select("*").Omit("id", "created_at").Updates(recovery)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCartRecoveryChanged
+	}
+	return nil
+}
+
+func (r *GormCartRecoveryRepository) CountByStatus(since time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := r.db.Model(&models.CartRecovery{}).
+		Select("status, COUNT(*) AS count").
+		Where("created_at >= ?", since).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *GormCartRecoveryRepository) Revenue(since time.Time) ([]money.Money, error) {
+	var revenue []money.Money
+	err := r.db.Model(&models.CartRecovery{}).
+		Select("order_total_currency AS currency, SUM(order_total_amount) AS amount").
+		Where("created_at >= ? AND status = ?", since, models.CartRecoveryStatusConverted).
+		Group("order_total_currency").
+		Order("order_total_currency").
+		Scan(&revenue).Error
+	return revenue, err
+}
+
+func (r *GormCartRecoveryRepository) OptOut(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.CartReminderOptOut{UserID: userID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.CartRecovery{}).
+			Where("user_id = ? AND status = ?", userID, models.CartRecoveryStatusActive).
+			Updates(map[string]interface{}{
+				"status":           models.CartRecoveryStatusUnsubscribed,
+				"next_reminder_at": nil,
+				"updated_at":       time.Now(),
+			}).Error
+	})
+}
+
+func (r *GormCartRecoveryRepository) OptIn(userID uint) error {
+	return r.db.Delete(&models.CartReminderOptOut{}, "user_id = ?", userID).Error
+}
+
+func (r *GormCartRecoveryRepository) IsOptedOut(userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CartReminderOptOut{}).Where("user_id = ?", userID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartRepository interface {
+	Create(cart *models.Cart) error
+	// FindByUserID and FindByToken return a cart with its items, oldest first
+	FindByUserID(userID uint) (*models.Cart, error)
+	FindByToken(token string) (*models.Cart, error)
+	// SetItem sets the quantity of a product in a cart, adding the line if needed
+	SetItem(cartID, productID uint, quantity int) error
+	RemoveItem(cartID, productID uint) error
+	// SetCoupon stores the coupon code of a cart; an empty code removes it
+	SetCoupon(cartID uint, code string) error
+	// Clear removes every line and the coupon of a cart
+	Clear(cartID uint) error
+	// Merge sets the given quantities on the target cart and deletes the
+	// source cart in one transaction
+	Merge(sourceID, targetID uint, quantities map[uint]int) error
+}
+
+type GormCartRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCartRepository(db *gorm.DB) CartRepository {
+	return &GormCartRepository{db: db}
+}
+
+func preloadCartItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	})
+}
+
+func (r *GormCartRepository) Create(cart *models.Cart) error {
+	return r.db.Create(cart).Error
+}
+
+func (r *GormCartRepository) FindByUserID(userID uint) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.db.Scopes(preloadCartItems).Where("user_id = ?", userID).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (r *GormCartRepository) FindByToken(token string) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.db.Scopes(preloadCartItems).Where("token = ?", token).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func setCartItem(tx *gorm.DB, cartID, productID uint, quantity int) error {
+	item := models.CartItem{CartID: cartID, ProductID: productID, Quantity: quantity}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cart_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "updated_at"}),
+	}).Create(&item).Error; err != nil {
+		return err
+	}
+	// 購物車的更新時間用來判斷是否被放棄
+	return tx.Model(&models.Cart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+}
+
+func (r *GormCartRepository) SetItem(cartID, productID uint, quantity int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return setCartItem(tx, cartID, productID, quantity)
+	})
+}
+
+func (r *GormCartRepository) RemoveItem(cartID, productID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id = ? AND product_id = ?", cartID, productID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Cart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+	})
+}
+
+func (r *GormCartRepository) SetCoupon(cartID uint, code string) error {
+	return r.db.Model(&models.Cart{}).Where("id = ?", cartID).
+		Updates(map[string]interface{}{"coupon_code": code, "updated_at": time.Now()}).Error
+}
+
+func (r *GormCartRepository) Clear(cartID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return clearCart(tx, cartID)
+	})
+}
+
+func clearCart(tx *gorm.DB, cartID uint) error {
+	if cartID == 0 {
+		// 訂閱續訂等不是從購物車來的訂單
+		return nil
+	}
+	if err := tx.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Cart{}).Where("id = ?", cartID).
+		Updates(map[string]interface{}{"coupon_code": "", "updated_at": time.Now()}).Error
+}
+
+func (r *GormCartRepository) Merge(sourceID, targetID uint, quantities map[uint]int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 鎖住訪客購物車，避免同時登入時重複合併
+		var source models.Cart
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&source, sourceID).Error; err != nil {
+			return err
+		}
+		for productID, quantity := range quantities {
+			if err := setCartItem(tx, targetID, productID, quantity); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("cart_id = ?", sourceID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&source).Error
+	})
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+type ImportJobRepository interface {
+	Create(job *models.ImportJob) error
+	FindByID(id uint) (*models.ImportJob, error)
+	FindByStatus(status string) ([]models.ImportJob, error)
+	Update(job *models.ImportJob) error
+	AddRowErrors(rowErrors []models.ImportRowError) error
+	FindRowErrors(jobID uint) ([]models.ImportRowError, error)
+	DeleteRowErrors(jobID uint) error
+}
+
+type GormImportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewGormImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &GormImportJobRepository{db: db}
+}
+
+func (r *GormImportJobRepository) Create(job *models.ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *GormImportJobRepository) FindByID(id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *GormImportJobRepository) FindByStatus(status string) ([]models.ImportJob, error) {
+	var jobs []models.ImportJob
+	err := r.db.Where("status = ?", status).Order("id").Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *GormImportJobRepository) Update(job *models.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *GormImportJobRepository) AddRowErrors(rowErrors []models.ImportRowError) error {
+	if len(rowErrors) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(rowErrors, 500).Error
+}
+
+func (r *GormImportJobRepository) FindRowErrors(jobID uint) ([]models.ImportRowError, error) {
+	var rowErrors []models.ImportRowError
+	err := r.db.Where("job_id = ?", jobID).Order("row, id").Find(&rowErrors).Error
+	return rowErrors, err
+}
+
+func (r *GormImportJobRepository) DeleteRowErrors(jobID uint) error {
+	return r.db.Where("job_id = ?", jobID).Delete(&models.ImportRowError{}).Error
+}
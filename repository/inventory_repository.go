@@ -0,0 +1,471 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReturnExceedsSold   = errors.New("return exceeds the quantity sold")
+)
+
+// InsufficientStockError reports which product could not be fulfilled
+type InsufficientStockError struct {
+	ProductID uint
+	Requested int
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock for product %d: requested %d, available %d", e.ProductID, e.Requested, e.Available)
+}
+
+func (e *InsufficientStockError) Unwrap() error {
+	return ErrInsufficientStock
+}
+
+// ReservationItem is a quantity of a product to hold
+type ReservationItem struct {
+	ProductID uint
+	Quantity  int
+}
+
+// StockReturn is a quantity of a product sold under Reference coming back
+type StockReturn struct {
+	Reference string
+	ProductID uint
+	Quantity  int
+	// WriteOff takes the units out of stock again as soon as they are back,
+	// for returns that cannot be sold again
+	WriteOff bool
+	UserID   *uint
+	Note     string
+}
+
+type InventoryRepository interface {
+	CreateWarehouse(warehouse *models.Warehouse) error
+	FindWarehouses() ([]models.Warehouse, error)
+	FindWarehouseByID(id uint) (*models.Warehouse, error)
+	FindStockLevels(productID uint) ([]models.StockLevel, error)
+	FindMovements(productID uint, offset, limit int) ([]models.StockMovement, error)
+	// ApplyMovement appends a ledger entry and updates on-hand stock in one
+	// transaction. On-hand stock may never drop below the reserved quantity.
+	ApplyMovement(movement *models.StockMovement) error
+	// Reserve holds stock for every item or for none of them, spreading an
+	// item over several warehouses when needed
+	Reserve(reference string, items []ReservationItem, expiresAt time.Time) ([]models.StockReservation, error)
+	FindReservations(reference string) ([]models.StockReservation, error)
+	// CommitReservations turns the active reservations of reference into
+	// sales and reports how many reservations it committed
+	CommitReservations(reference string) (int, error)
+	// ReturnSold books returned units back into the warehouses they were
+	// sold from in one transaction. It fails with ErrReturnExceedsSold when
+	// more would come back than was sold and not yet returned.
+	ReturnSold(ret StockReturn) error
+	// ReleaseReservations returns active reserved stock, marking it with status
+	ReleaseReservations(reference, status string) error
+	FindExpiredReservationReferences(now time.Time) ([]string, error)
+}
+
+type GormInventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewGormInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &GormInventoryRepository{db: db}
+}
+
+func (r *GormInventoryRepository) CreateWarehouse(warehouse *models.Warehouse) error {
+	return r.db.Create(warehouse).Error
+}
+
+func (r *GormInventoryRepository) FindWarehouses() ([]models.Warehouse, error) {
+	var warehouses []models.Warehouse
+	err := r.db.Order("id").Find(&warehouses).Error
+	return warehouses, err
+}
+
+func (r *GormInventoryRepository) FindWarehouseByID(id uint) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := r.db.First(&warehouse, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+func (r *GormInventoryRepository) FindStockLevels(productID uint) ([]models.StockLevel, error) {
+	var levels []models.StockLevel
+	err := r.db.Where("product_id = ?", productID).Order("warehouse_id").Find(&levels).Error
+	return levels, err
+}
+
+func (r *GormInventoryRepository) FindMovements(productID uint, offset, limit int) ([]models.StockMovement, error) {
+	var movements []models.StockMovement
+	err := r.db.Where("product_id = ?", productID).
+		Order("id DESC").Offset(offset).Limit(limit).
+		Find(&movements).Error
+	return movements, err
+}
+
+// lockStockLevel returns the stock row of a product in a warehouse, locked
+// for the rest of the transaction and created when missing
+func lockStockLevel(tx *gorm.DB, productID, warehouseID uint) (*models.StockLevel, error) {
+	level := models.StockLevel{ProductID: productID, WarehouseID: warehouseID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&level).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND warehouse_id = ?", productID, warehouseID).
+		First(&level).Error; err != nil {
+		return nil, err
+	}
+	return &level, nil
+}
+
+func (r *GormInventoryRepository) ApplyMovement(movement *models.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return applyMovement(tx, movement)
+	})
+}
+
+func applyMovement(tx *gorm.DB, movement *models.StockMovement) error {
+	level, err := lockStockLevel(tx, movement.ProductID, movement.WarehouseID)
+	if err != nil {
+		return err
+	}
+
+	onHand := level.OnHand + movement.Quantity
+	if onHand < level.Reserved {
+		return &InsufficientStockError{ProductID: movement.ProductID, Requested: -movement.Quantity, Available: level.Available()}
+	}
+	if err := tx.Model(level).Update("on_hand", onHand).Error; err != nil {
+		return err
+	}
+
+	movement.BalanceAfter = onHand
+	return tx.Create(movement).Error
+}
+
+func (r *GormInventoryRepository) Reserve(reference string, items []ReservationItem, expiresAt time.Time) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reservations, err = reserve(tx, reference, items, expiresAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// reserve holds stock for items within tx, so callers can reserve as part
+// of a larger transaction
+func reserve(tx *gorm.DB, reference string, items []ReservationItem, expiresAt time.Time) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	// 依商品編號排序後再鎖定，避免多筆結帳互相死結
+	for _, item := range sortedItems(items) {
+		var levels []models.StockLevel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Joins("JOIN warehouses ON warehouses.id = stock_levels.warehouse_id AND warehouses.active = ?", true).
+			Where("stock_levels.product_id = ?", item.ProductID).
+			Order("stock_levels.id").
+			Find(&levels).Error; err != nil {
+			return nil, err
+		}
+
+		allocations, err := allocate(item, levels)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range allocations {
+			if err := tx.Model(&models.StockLevel{}).
+				Where("id = ?", a.levelID).
+				Update("reserved", gorm.Expr("reserved + ?", a.quantity)).Error; err != nil {
+				return nil, err
+			}
+			reservations = append(reservations, models.StockReservation{
+				Reference:   reference,
+				ProductID:   item.ProductID,
+				WarehouseID: a.warehouseID,
+				Quantity:    a.quantity,
+				Status:      models.ReservationActive,
+				ExpiresAt:   expiresAt,
+			})
+		}
+	}
+	if len(reservations) == 0 {
+		return reservations, nil
+	}
+	if err := tx.Create(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *GormInventoryRepository) FindReservations(reference string) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := r.db.Where("reference = ?", reference).Order("id").Find(&reservations).Error
+	return reservations, err
+}
+
+func (r *GormInventoryRepository) CommitReservations(reference string) (int, error) {
+	var committed int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		committed, err = commitReservations(tx, reference)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return committed, nil
+}
+
+// commitReservations commits within tx, so callers can commit stock as part
+// of a larger transaction
+func commitReservations(tx *gorm.DB, reference string) (int, error) {
+	reservations, err := lockActiveReservations(tx, reference)
+	if err != nil {
+		return 0, err
+	}
+	for _, res := range reservations {
+		level, err := lockStockLevel(tx, res.ProductID, res.WarehouseID)
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.Model(level).Update("reserved", level.Reserved-res.Quantity).Error; err != nil {
+			return 0, err
+		}
+		if err := applyMovement(tx, &models.StockMovement{
+			ProductID:   res.ProductID,
+			WarehouseID: res.WarehouseID,
+			Type:        models.MovementSell,
+			Quantity:    -res.Quantity,
+			Reference:   reference,
+		}); err != nil {
+			return 0, err
+		}
+	}
+	return len(reservations), markReservations(tx, reservations, models.ReservationCommitted)
+}
+
+func (r *GormInventoryRepository) ReturnSold(ret StockReturn) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return returnSold(tx, ret)
+	})
+}
+
+// returnSold books a return within tx, so callers can return stock as part
+// of a larger transaction
+func returnSold(tx *gorm.DB, ret StockReturn) error {
+	var sold []models.StockReservation
+	// 鎖定已售出的預留，同一商品的退貨依序計算可退數量
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ? AND product_id = ? AND status = ?", ret.Reference, ret.ProductID, models.ReservationCommitted).
+		Order("id").
+		Find(&sold).Error; err != nil {
+		return err
+	}
+	var returned []models.StockMovement
+	if err := tx.Where("reference = ? AND product_id = ? AND type = ?", ret.Reference, ret.ProductID, models.MovementReturn).
+		Find(&returned).Error; err != nil {
+		return err
+	}
+
+	allocations, err := allocateReturn(ret, sold, returned)
+	if err != nil {
+		return err
+	}
+	for _, a := range allocations {
+		for _, movement := range returnMovements(ret, a) {
+			if err := applyMovement(tx, movement); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *GormInventoryRepository) ReleaseReservations(reference, status string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return releaseReservations(tx, reference, status)
+	})
+}
+
+func releaseReservations(tx *gorm.DB, reference, status string) error {
+	reservations, err := lockActiveReservations(tx, reference)
+	if err != nil {
+		return err
+	}
+	for _, res := range reservations {
+		if err := tx.Model(&models.StockLevel{}).
+			Where("product_id = ? AND warehouse_id = ?", res.ProductID, res.WarehouseID).
+			Update("reserved", gorm.Expr("reserved - ?", res.Quantity)).Error; err != nil {
+			return err
+		}
+	}
+	return markReservations(tx, reservations, status)
+}
+
+func (r *GormInventoryRepository) FindExpiredReservationReferences(now time.Time) ([]string, error) {
+	var references []string
+	err := r.db.Model(&models.StockReservation{}).
+		Where("status = ? AND expires_at < ?", models.ReservationActive, now).
+		Distinct().Pluck("reference", &references).Error
+	return references, err
+}
+
+func lockActiveReservations(tx *gorm.DB, reference string) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference = ? AND status = ?", reference, models.ReservationActive).
+		Order("product_id, id").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+func markReservations(tx *gorm.DB, reservations []models.StockReservation, status string) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(reservations))
+	for i, res := range reservations {
+		ids[i] = res.ID
+	}
+	return tx.Model(&models.StockReservation{}).Where("id IN ?", ids).Update("status", status).Error
+}
+
+type allocation struct {
+	levelID     uint
+	warehouseID uint
+	quantity    int
+}
+
+// allocate takes stock from the warehouses with the most available first
+func allocate(item ReservationItem, levels []models.StockLevel) ([]allocation, error) {
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].Available() > levels[j].Available() })
+
+	available := 0
+	for _, level := range levels {
+		available += level.Available()
+	}
+	if available < item.Quantity {
+		return nil, &InsufficientStockError{ProductID: item.ProductID, Requested: item.Quantity, Available: available}
+	}
+
+	var allocations []allocation
+	remaining := item.Quantity
+	for _, level := range levels {
+		if remaining == 0 {
+			break
+		}
+		take := level.Available()
+		if take > remaining {
+			take = remaining
+		}
+		if take <= 0 {
+			continue
+		}
+		allocations = append(allocations, allocation{levelID: level.ID, warehouseID: level.WarehouseID, quantity: take})
+		remaining -= take
+	}
+	return allocations, nil
+}
+
+// allocateReturn spreads a return over the warehouses the units were sold
+// from, skipping what has already come back to each of them
+func allocateReturn(ret StockReturn, sold []models.StockReservation, returned []models.StockMovement) ([]allocation, error) {
+	if len(sold) == 0 {
+		return nil, ErrReservationNotFound
+	}
+	var warehouses []uint
+	left := make(map[uint]int)
+	for _, res := range sold {
+		if _, seen := left[res.WarehouseID]; !seen {
+			warehouses = append(warehouses, res.WarehouseID)
+		}
+		left[res.WarehouseID] += res.Quantity
+	}
+	for _, movement := range returned {
+		if _, seen := left[movement.WarehouseID]; seen {
+			left[movement.WarehouseID] -= movement.Quantity
+		}
+	}
+
+	returnable := 0
+	for _, warehouseID := range warehouses {
+		if left[warehouseID] > 0 {
+			returnable += left[warehouseID]
+		}
+	}
+	if ret.Quantity > returnable {
+		return nil, fmt.Errorf("%w: %d of product %d left to return", ErrReturnExceedsSold, returnable, ret.ProductID)
+	}
+
+	var allocations []allocation
+	remaining := ret.Quantity
+	for _, warehouseID := range warehouses {
+		take := left[warehouseID]
+		if take > remaining {
+			take = remaining
+		}
+		if take <= 0 {
+			continue
+		}
+		allocations = append(allocations, allocation{warehouseID: warehouseID, quantity: take})
+		if remaining -= take; remaining == 0 {
+			break
+		}
+	}
+	return allocations, nil
+}
+
+// returnMovements are the ledger entries booking a returned allocation
+func returnMovements(ret StockReturn, a allocation) []*models.StockMovement {
+	movements := []*models.StockMovement{{
+		ProductID:   ret.ProductID,
+		WarehouseID: a.warehouseID,
+		Type:        models.MovementReturn,
+		Quantity:    a.quantity,
+		Reference:   ret.Reference,
+		Note:        ret.Note,
+		UserID:      ret.UserID,
+	}}
+	if ret.WriteOff {
+		movements = append(movements, &models.StockMovement{
+			ProductID:   ret.ProductID,
+			WarehouseID: a.warehouseID,
+			Type:        models.MovementWriteOff,
+			Quantity:    -a.quantity,
+			Reference:   ret.Reference,
+			Note:        ret.Note,
+			UserID:      ret.UserID,
+		})
+	}
+	return movements
+}
+
+// sortedItems merges duplicate products and orders items by product ID
+func sortedItems(items []ReservationItem) []ReservationItem {
+	merged := make(map[uint]int)
+	for _, item := range items {
+		merged[item.ProductID] += item.Quantity
+	}
+	sorted := make([]ReservationItem, 0, len(merged))
+	for productID, quantity := range merged {
+		sorted = append(sorted, ReservationItem{ProductID: productID, Quantity: quantity})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+	return sorted
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository interface {
+	// Issue numbers the invoice with the next number of its store, kind and
+	// year and stores it. The number is only taken if the invoice is stored.
+	Issue(invoice *models.Invoice) error
+	FindByID(id uint) (*models.Invoice, error)
+	// FindBySource returns the document issued for an order or a refund
+	FindBySource(source string) (*models.Invoice, error)
+	// FindByOrderID returns the documents of an order, oldest first
+	FindByOrderID(orderID uint) ([]models.Invoice, error)
+	// CompleteEInvoice records the e-invoice issued for a pending document
+	CompleteEInvoice(invoice *models.Invoice) error
+}
+
+type GormInvoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewGormInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &GormInvoiceRepository{db: db}
+}
+
+func (r *GormInvoiceRepository) Issue(invoice *models.Invoice) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		seq := models.InvoiceSequence{Store: invoice.Store, Kind: invoice.Kind, Year: invoice.Year}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+			return err
+		}
+		// 鎖住序號列直到交易結束，號碼才不會重複或跳號
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("store = ? AND kind = ? AND year = ?", invoice.Store, invoice.Kind, invoice.Year).
+			First(&seq).Error; err != nil {
+			return err
+		}
+		seq.Last++
+		if err := tx.Model(&seq).Update("last", seq.Last).Error; err != nil {
+			return err
+		}
+		invoice.Sequence = seq.Last
+		invoice.Number = invoice.FormatNumber(seq.Last)
+		return tx.Create(invoice).Error
+	})
+}
+
+func (r *GormInvoiceRepository) FindByID(id uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Preload("Lines").Preload("Taxes").First(&invoice, id).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *GormInvoiceRepository) FindBySource(source string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Preload("Lines").Preload("Taxes").Where("source = ?", source).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *GormInvoiceRepository) FindByOrderID(orderID uint) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *GormInvoiceRepository) CompleteEInvoice(invoice *models.Invoice) error {
+	invoice.EInvoicePending = false
+	return r.db.Model(invoice).
+		Select("EInvoiceNumber", "EInvoiceRandomCode", "EInvoiceVoided", "EInvoicePending").
+		Updates(invoice).Error
+}
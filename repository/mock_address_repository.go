@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+type MockAddressRepository struct {
+	mu        sync.Mutex
+	addresses []models.CustomerAddress
+	nextID    uint
+}
+
+func NewMockAddressRepository() AddressRepository {
+	return &MockAddressRepository{nextID: 1}
+}
+
+func (m *MockAddressRepository) clearDefaults(address *models.CustomerAddress) {
+	for i := range m.addresses {
+		other := &m.addresses[i]
+		if other.UserID != address.UserID || other.ID == address.ID {
+			continue
+		}
+		if address.IsDefaultShipping {
+			other.IsDefaultShipping = false
+		}
+		if address.IsDefaultBilling {
+			other.IsDefaultBilling = false
+		}
+	}
+}
+
+func (m *MockAddressRepository) Create(address *models.CustomerAddress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	address.ID = m.nextID
+	m.nextID++
+	address.CreatedAt = time.Now()
+	address.UpdatedAt = address.CreatedAt
+	m.addresses = append(m.addresses, *address)
+	m.clearDefaults(address)
+	return nil
+}
+
+func (m *MockAddressRepository) Update(address *models.CustomerAddress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.addresses {
+		if m.addresses[i].ID == address.ID {
+			address.UpdatedAt = time.Now()
+			m.addresses[i] = *address
+			m.clearDefaults(address)
+			return nil
+		}
+	}
+	return errors.New("address not found")
+}
+
+func (m *MockAddressRepository) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.addresses {
+		if m.addresses[i].ID == id {
+			m.addresses = append(m.addresses[:i], m.addresses[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockAddressRepository) FindByID(id uint) (*models.CustomerAddress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, address := range m.addresses {
+		if address.ID == id {
+			result := address
+			return &result, nil
+		}
+	}
+	return nil, errors.New("address not found")
+}
+
+func (m *MockAddressRepository) FindByUserID(userID uint) ([]models.CustomerAddress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var addresses []models.CustomerAddress
+	for _, address := range m.addresses {
+		if address.UserID == userID {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
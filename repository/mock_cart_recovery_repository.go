@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"e-commerce/money"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockCartRecoveryRepository struct {
+	mu         sync.Mutex
+	carts      *MockCartRepository
+	recoveries []models.CartRecovery
+	optOuts    map[uint]bool
+}
+
+func NewMockCartRecoveryRepository(cartRepo CartRepository) CartRecoveryRepository {
+	return &MockCartRecoveryRepository{
+		carts:   cartRepo.(*MockCartRepository),
+		optOuts: make(map[uint]bool),
+	}
+}
+
+func (m *MockCartRecoveryRepository) FindAbandoned(idleAfter, idleBefore time.Time, limit int) ([]models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.carts.mu.Lock()
+	defer m.carts.mu.Unlock()
+
+	var carts []models.Cart
+	for _, cart := range m.carts.carts {
+		if cart.UserID == nil || m.optOuts[*cart.UserID] ||
+			!cart.UpdatedAt.After(idleAfter) || cart.UpdatedAt.After(idleBefore) {
+			continue
+		}
+		if m.recovered(cart.ID, cart.UpdatedAt) {
+			continue
+		}
+		if withItems := m.carts.withItems(cart); len(withItems.Items) > 0 {
+			carts = append(carts, *withItems)
+		}
+	}
+	sort.Slice(carts, func(i, j int) bool { return carts[i].UpdatedAt.Before(carts[j].UpdatedAt) })
+	if len(carts) > limit {
+		carts = carts[:limit]
+	}
+	return carts, nil
+}
+
+// recovered reports whether a cart already has a recovery for the change
+// made at updatedAt
+func (m *MockCartRecoveryRepository) recovered(cartID uint, updatedAt time.Time) bool {
+	for _, recovery := range m.recoveries {
+		if recovery.CartID == cartID && recovery.CartUpdatedAt.Equal(updatedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockCartRecoveryRepository) Create(recovery *models.CartRecovery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.recovered(recovery.CartID, recovery.CartUpdatedAt) {
+		return errors.New("cart already has a recovery")
+	}
+	recovery.ID = uint(len(m.recoveries) + 1)
+	recovery.CreatedAt = time.Now()
+	recovery.UpdatedAt = recovery.CreatedAt
+	m.recoveries = append(m.recoveries, *recovery)
+	return nil
+}
+
+func (m *MockCartRecoveryRepository) FindDue(now time.Time, limit int) ([]models.CartRecovery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var recoveries []models.CartRecovery
+	for _, recovery := range m.recoveries {
+		if recovery.Status == models.CartRecoveryStatusActive && recovery.NextReminderAt != nil &&
+			!recovery.NextReminderAt.After(now) && len(recoveries) < limit {
+			recoveries = append(recoveries, recovery)
+		}
+	}
+	return recoveries, nil
+}
+
+func (m *MockCartRecoveryRepository) FindOpen(cartID uint) (*models.CartRecovery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.recoveries) - 1; i >= 0; i-- {
+		recovery := m.recoveries[i]
+		switch recovery.Status {
+		case models.CartRecoveryStatusActive, models.CartRecoveryStatusReturned, models.CartRecoveryStatusFinished:
+			if recovery.CartID == cartID {
+				return &recovery, nil
+			}
+		}
+	}
+	return nil, errors.New("cart recovery not found")
+}
+
+func (m *MockCartRecoveryRepository) FindByStatus(status string, offset, limit int) ([]models.CartRecovery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var recoveries []models.CartRecovery
+	for i := len(m.recoveries) - 1; i >= 0; i-- {
+		if status == "" || m.recoveries[i].Status == status {
+			recoveries = append(recoveries, m.recoveries[i])
+		}
+	}
+	if offset >= len(recoveries) {
+		return nil, nil
+	}
+	recoveries = recoveries[offset:]
+	if len(recoveries) > limit {
+		recoveries = recoveries[:limit]
+	}
+	return recoveries, nil
+}
+
+func (m *MockCartRecoveryRepository) Update(recovery *models.CartRecovery, from string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if recovery.ID == 0 || int(recovery.ID) > len(m.recoveries) {
+		return errors.New("cart recovery not found")
+	}
+	if m.recoveries[recovery.ID-1].Status != from {
+		return ErrCartRecoveryChanged
+	}
+	recovery.UpdatedAt = time.Now()
+	m.recoveries[recovery.ID-1] = *recovery
+	return nil
+}
+
+func (m *MockCartRecoveryRepository) CountByStatus(since time.Time) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[string]int64)
+	for _, recovery := range m.recoveries {
+		if !recovery.CreatedAt.Before(since) {
+			counts[recovery.Status]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *MockCartRecoveryRepository) Revenue(since time.Time) ([]money.Money, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	totals := make(map[string]int64)
+	for _, recovery := range m.recoveries {
+		if recovery.Status == models.CartRecoveryStatusConverted && !recovery.CreatedAt.Before(since) {
+			totals[recovery.OrderTotal.Currency] += recovery.OrderTotal.Amount
+		}
+	}
+	var revenue []money.Money
+	for currency, amount := range totals {
+		revenue = append(revenue, money.Money{Amount: amount, Currency: currency})
+	}
+	sort.Slice(revenue, func(i, j int) bool { return revenue[i].Currency < revenue[j].Currency })
+	return revenue, nil
+}
+
+func (m *MockCartRecoveryRepository) OptOut(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.optOuts[userID] = true
+	now := time.Now()
+	for i := range m.recoveries {
+		if m.recoveries[i].UserID == userID && m.recoveries[i].Status == models.CartRecoveryStatusActive {
+			m.recoveries[i].Status = models.CartRecoveryStatusUnsubscribed
+			m.recoveries[i].NextReminderAt = nil
+			m.recoveries[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (m *MockCartRecoveryRepository) OptIn(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.optOuts, userID)
+	return nil
+}
+
+func (m *MockCartRecoveryRepository) IsOptedOut(userID uint) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.optOuts[userID], nil
+}
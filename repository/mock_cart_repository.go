@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+type MockCartRepository struct {
+	mu     sync.Mutex
+	carts  map[uint]*models.Cart
+	items  []*models.CartItem
+	nextID uint
+}
+
+func NewMockCartRepository() CartRepository {
+	return &MockCartRepository{
+		carts:  make(map[uint]*models.Cart),
+		nextID: 1,
+	}
+}
+
+// withItems returns a copy of a cart with its items, oldest first
+func (m *MockCartRepository) withItems(cart *models.Cart) *models.Cart {
+	result := *cart
+	result.Items = nil
+	for _, item := range m.items {
+		if item.CartID == cart.ID {
+			result.Items = append(result.Items, *item)
+		}
+	}
+	return &result
+}
+
+func (m *MockCartRepository) Create(cart *models.Cart) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.carts {
+		if cart.UserID != nil && existing.UserID != nil && *existing.UserID == *cart.UserID {
+			return errors.New("user already has a cart")
+		}
+	}
+	cart.ID = m.nextID
+	m.nextID++
+	cart.CreatedAt = time.Now()
+	cart.UpdatedAt = cart.CreatedAt
+	stored := *cart
+	stored.Items = nil
+	m.carts[cart.ID] = &stored
+	return nil
+}
+
+func (m *MockCartRepository) FindByUserID(userID uint) (*models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cart := range m.carts {
+		if cart.UserID != nil && *cart.UserID == userID {
+			return m.withItems(cart), nil
+		}
+	}
+	return nil, errors.New("cart not found")
+}
+
+func (m *MockCartRepository) FindByToken(token string) (*models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cart := range m.carts {
+		if cart.Token != nil && *cart.Token == token {
+			return m.withItems(cart), nil
+		}
+	}
+	return nil, errors.New("cart not found")
+}
+
+func (m *MockCartRepository) setItem(cartID, productID uint, quantity int) {
+	now := time.Now()
+	m.carts[cartID].UpdatedAt = now
+	for _, item := range m.items {
+		if item.CartID == cartID && item.ProductID == productID {
+			item.Quantity = quantity
+			item.UpdatedAt = now
+			return
+		}
+	}
+	m.items = append(m.items, &models.CartItem{
+		ID:        uint(len(m.items) + 1),
+		CreatedAt: now,
+		UpdatedAt: now,
+		CartID:    cartID,
+		ProductID: productID,
+		Quantity:  quantity,
+	})
+}
+
+func (m *MockCartRepository) SetItem(cartID, productID uint, quantity int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.carts[cartID]; !exists {
+		return errors.New("cart not found")
+	}
+	m.setItem(cartID, productID, quantity)
+	return nil
+}
+
+// removeItems deletes the items of a cart matching productID, or all of
+// them when productID is zero
+func (m *MockCartRepository) removeItems(cartID, productID uint) {
+	var items []*models.CartItem
+	for _, item := range m.items {
+		if item.CartID != cartID || (productID != 0 && item.ProductID != productID) {
+			items = append(items, item)
+		}
+	}
+	m.items = items
+}
+
+func (m *MockCartRepository) RemoveItem(cartID, productID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeItems(cartID, productID)
+	if cart, exists := m.carts[cartID]; exists {
+		cart.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *MockCartRepository) SetCoupon(cartID uint, code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cart, exists := m.carts[cartID]
+	if !exists {
+		return errors.New("cart not found")
+	}
+	cart.CouponCode = code
+	cart.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MockCartRepository) Clear(cartID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeItems(cartID, 0)
+	if cart, exists := m.carts[cartID]; exists {
+		cart.CouponCode = ""
+		cart.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *MockCartRepository) Merge(sourceID, targetID uint, quantities map[uint]int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.carts[sourceID]; !exists {
+		return errors.New("cart not found")
+	}
+	if _, exists := m.carts[targetID]; !exists {
+		return errors.New("cart not found")
+	}
+	for productID, quantity := range quantities {
+		m.setItem(targetID, productID, quantity)
+	}
+	m.removeItems(sourceID, 0)
+	delete(m.carts, sourceID)
+	return nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+)
+
+type MockImportJobRepository struct {
+	jobs      map[uint]*models.ImportJob
+	rowErrors []models.ImportRowError
+	nextID    uint
+}
+
+func NewMockImportJobRepository() ImportJobRepository {
+	return &MockImportJobRepository{
+		jobs:   make(map[uint]*models.ImportJob),
+		nextID: 1,
+	}
+}
+
+func (m *MockImportJobRepository) Create(job *models.ImportJob) error {
+	job.ID = m.nextID
+	m.nextID++
+	stored := *job
+	m.jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *MockImportJobRepository) FindByID(id uint) (*models.ImportJob, error) {
+	if job, exists := m.jobs[id]; exists {
+		found := *job
+		return &found, nil
+	}
+	return nil, errors.New("import job not found")
+}
+
+func (m *MockImportJobRepository) FindByStatus(status string) ([]models.ImportJob, error) {
+	var jobs []models.ImportJob
+	for id := uint(1); id < m.nextID; id++ {
+		if job, exists := m.jobs[id]; exists && job.Status == status {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *MockImportJobRepository) Update(job *models.ImportJob) error {
+	if _, exists := m.jobs[job.ID]; !exists {
+		return errors.New("import job not found")
+	}
+	stored := *job
+	m.jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *MockImportJobRepository) AddRowErrors(rowErrors []models.ImportRowError) error {
+	m.rowErrors = append(m.rowErrors, rowErrors...)
+	return nil
+}
+
+func (m *MockImportJobRepository) FindRowErrors(jobID uint) ([]models.ImportRowError, error) {
+	var found []models.ImportRowError
+	for _, rowError := range m.rowErrors {
+		if rowError.JobID == jobID {
+			found = append(found, rowError)
+		}
+	}
+	return found, nil
+}
+
+func (m *MockImportJobRepository) DeleteRowErrors(jobID uint) error {
+	kept := m.rowErrors[:0]
+	for _, rowError := range m.rowErrors {
+		if rowError.JobID != jobID {
+			kept = append(kept, rowError)
+		}
+	}
+	m.rowErrors = kept
+	return nil
+}
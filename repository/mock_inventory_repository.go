@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockInventoryRepository guards its state with a mutex so tests can
+// exercise concurrent reservations
+type MockInventoryRepository struct {
+	mu           sync.Mutex
+	warehouses   map[uint]*models.Warehouse
+	levels       []*models.StockLevel
+	movements    []models.StockMovement
+	reservations []*models.StockReservation
+}
+
+func NewMockInventoryRepository() InventoryRepository {
+	return &MockInventoryRepository{
+		warehouses: make(map[uint]*models.Warehouse),
+	}
+}
+
+func (m *MockInventoryRepository) CreateWarehouse(warehouse *models.Warehouse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.warehouses {
+		if existing.Code == warehouse.Code {
+			return errors.New("warehouse code already exists")
+		}
+	}
+	warehouse.ID = uint(len(m.warehouses) + 1)
+	m.warehouses[warehouse.ID] = warehouse
+	return nil
+}
+
+func (m *MockInventoryRepository) FindWarehouses() ([]models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var warehouses []models.Warehouse
+	for _, warehouse := range m.warehouses {
+		warehouses = append(warehouses, *warehouse)
+	}
+	sort.Slice(warehouses, func(i, j int) bool { return warehouses[i].ID < warehouses[j].ID })
+	return warehouses, nil
+}
+
+func (m *MockInventoryRepository) FindWarehouseByID(id uint) (*models.Warehouse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if warehouse, exists := m.warehouses[id]; exists {
+		return warehouse, nil
+	}
+	return nil, errors.New("warehouse not found")
+}
+
+func (m *MockInventoryRepository) level(productID, warehouseID uint) *models.StockLevel {
+	for _, level := range m.levels {
+		if level.ProductID == productID && level.WarehouseID == warehouseID {
+			return level
+		}
+	}
+	level := &models.StockLevel{ID: uint(len(m.levels) + 1), ProductID: productID, WarehouseID: warehouseID}
+	m.levels = append(m.levels, level)
+	return level
+}
+
+func (m *MockInventoryRepository) FindStockLevels(productID uint) ([]models.StockLevel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var levels []models.StockLevel
+	for _, level := range m.levels {
+		if level.ProductID == productID {
+			levels = append(levels, *level)
+		}
+	}
+	return levels, nil
+}
+
+func (m *MockInventoryRepository) FindMovements(productID uint, offset, limit int) ([]models.StockMovement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var movements []models.StockMovement
+	for i := len(m.movements) - 1; i >= 0; i-- {
+		if m.movements[i].ProductID == productID {
+			movements = append(movements, m.movements[i])
+		}
+	}
+	if offset >= len(movements) {
+		return nil, nil
+	}
+	movements = movements[offset:]
+	if limit > 0 && len(movements) > limit {
+		movements = movements[:limit]
+	}
+	return movements, nil
+}
+
+func (m *MockInventoryRepository) ApplyMovement(movement *models.StockMovement) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.applyMovement(movement)
+}
+
+func (m *MockInventoryRepository) applyMovement(movement *models.StockMovement) error {
+	level := m.level(movement.ProductID, movement.WarehouseID)
+	onHand := level.OnHand + movement.Quantity
+	if onHand < level.Reserved {
+		return &InsufficientStockError{ProductID: movement.ProductID, Requested: -movement.Quantity, Available: level.Available()}
+	}
+	level.OnHand = onHand
+	movement.ID = uint(len(m.movements) + 1)
+	movement.BalanceAfter = onHand
+	movement.CreatedAt = time.Now()
+	m.movements = append(m.movements, *movement)
+	return nil
+}
+
+func (m *MockInventoryRepository) Reserve(reference string, items []ReservationItem, expiresAt time.Time) ([]models.StockReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type planned struct {
+		item        ReservationItem
+		allocations []allocation
+	}
+	var plans []planned
+	for _, item := range sortedItems(items) {
+		var levels []models.StockLevel
+		for _, level := range m.levels {
+			warehouse, exists := m.warehouses[level.WarehouseID]
+			if level.ProductID == item.ProductID && exists && warehouse.Active {
+				levels = append(levels, *level)
+			}
+		}
+		allocations, err := allocate(item, levels)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, planned{item, allocations})
+	}
+
+	var reservations []models.StockReservation
+	for _, plan := range plans {
+		for _, a := range plan.allocations {
+			m.level(plan.item.ProductID, a.warehouseID).Reserved += a.quantity
+			res := &models.StockReservation{
+				ID:          uint(len(m.reservations) + 1),
+				Reference:   reference,
+				ProductID:   plan.item.ProductID,
+				WarehouseID: a.warehouseID,
+				Quantity:    a.quantity,
+				Status:      models.ReservationActive,
+				ExpiresAt:   expiresAt,
+			}
+			m.reservations = append(m.reservations, res)
+			reservations = append(reservations, *res)
+		}
+	}
+	return reservations, nil
+}
+
+func (m *MockInventoryRepository) FindReservations(reference string) ([]models.StockReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var reservations []models.StockReservation
+	for _, res := range m.reservations {
+		if res.Reference == reference {
+			reservations = append(reservations, *res)
+		}
+	}
+	return reservations, nil
+}
+
+func (m *MockInventoryRepository) CommitReservations(reference string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	committed := 0
+	for _, res := range m.reservations {
+		if res.Reference != reference || res.Status != models.ReservationActive {
+			continue
+		}
+		m.level(res.ProductID, res.WarehouseID).Reserved -= res.Quantity
+		if err := m.applyMovement(&models.StockMovement{
+			ProductID:   res.ProductID,
+			WarehouseID: res.WarehouseID,
+			Type:        models.MovementSell,
+			Quantity:    -res.Quantity,
+			Reference:   reference,
+		}); err != nil {
+			return 0, err
+		}
+		res.Status = models.ReservationCommitted
+		committed++
+	}
+	return committed, nil
+}
+
+func (m *MockInventoryRepository) ReturnSold(ret StockReturn) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.returnSold(ret)
+}
+
+// returnAll books several returns, all or none of them, as the transaction
+// of the Gorm repository would
+func (m *MockInventoryRepository) returnAll(rets []StockReturn) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	movements := len(m.movements)
+	onHand := make(map[*models.StockLevel]int, len(m.levels))
+	for _, level := range m.levels {
+		onHand[level] = level.OnHand
+	}
+	for _, ret := range rets {
+		if err := m.returnSold(ret); err != nil {
+			m.movements = m.movements[:movements]
+			for level, n := range onHand {
+				level.OnHand = n
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockInventoryRepository) returnSold(ret StockReturn) error {
+	var sold []models.StockReservation
+	for _, res := range m.reservations {
+		if res.Reference == ret.Reference && res.ProductID == ret.ProductID && res.Status == models.ReservationCommitted {
+			sold = append(sold, *res)
+		}
+	}
+	var returned []models.StockMovement
+	for _, movement := range m.movements {
+		if movement.Reference == ret.Reference && movement.ProductID == ret.ProductID && movement.Type == models.MovementReturn {
+			returned = append(returned, movement)
+		}
+	}
+
+	allocations, err := allocateReturn(ret, sold, returned)
+	if err != nil {
+		return err
+	}
+	// 退回的數量一定能再報廢，不會只寫入一半
+	for _, a := range allocations {
+		for _, movement := range returnMovements(ret, a) {
+			if err := m.applyMovement(movement); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MockInventoryRepository) ReleaseReservations(reference, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, res := range m.reservations {
+		if res.Reference == reference && res.Status == models.ReservationActive {
+			m.level(res.ProductID, res.WarehouseID).Reserved -= res.Quantity
+			res.Status = status
+		}
+	}
+	return nil
+}
+
+func (m *MockInventoryRepository) FindExpiredReservationReferences(now time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool)
+	var references []string
+	for _, res := range m.reservations {
+		if res.Status == models.ReservationActive && res.ExpiresAt.Before(now) && !seen[res.Reference] {
+			seen[res.Reference] = true
+			references = append(references, res.Reference)
+		}
+	}
+	return references, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type MockInvoiceRepository struct {
+	mu        sync.Mutex
+	invoices  []models.Invoice
+	sequences map[string]int64
+}
+
+func NewMockInvoiceRepository() InvoiceRepository {
+	return &MockInvoiceRepository{sequences: make(map[string]int64)}
+}
+
+func (m *MockInvoiceRepository) Issue(invoice *models.Invoice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.invoices {
+		if existing.Source == invoice.Source {
+			return errors.New("duplicate invoice source")
+		}
+	}
+	key := fmt.Sprintf("%s/%s/%d", invoice.Store, invoice.Kind, invoice.Year)
+	m.sequences[key]++
+	invoice.ID = uint(len(m.invoices) + 1)
+	invoice.CreatedAt = time.Now()
+	invoice.Sequence = m.sequences[key]
+	invoice.Number = invoice.FormatNumber(invoice.Sequence)
+	for i := range invoice.Lines {
+		invoice.Lines[i].InvoiceID = invoice.ID
+	}
+	for i := range invoice.Taxes {
+		invoice.Taxes[i].InvoiceID = invoice.ID
+	}
+	m.invoices = append(m.invoices, copyInvoice(*invoice))
+	return nil
+}
+
+func copyInvoice(invoice models.Invoice) models.Invoice {
+	invoice.Lines = append([]models.InvoiceLine(nil), invoice.Lines...)
+	invoice.Taxes = append([]models.InvoiceTax(nil), invoice.Taxes...)
+	return invoice
+}
+
+func (m *MockInvoiceRepository) FindByID(id uint) (*models.Invoice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, invoice := range m.invoices {
+		if invoice.ID == id {
+			found := copyInvoice(invoice)
+			return &found, nil
+		}
+	}
+	return nil, errors.New("invoice not found")
+}
+
+func (m *MockInvoiceRepository) FindBySource(source string) (*models.Invoice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, invoice := range m.invoices {
+		if invoice.Source == source {
+			found := copyInvoice(invoice)
+			return &found, nil
+		}
+	}
+	return nil, errors.New("invoice not found")
+}
+
+func (m *MockInvoiceRepository) FindByOrderID(orderID uint) ([]models.Invoice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var invoices []models.Invoice
+	for _, invoice := range m.invoices {
+		if invoice.OrderID == orderID {
+			invoices = append(invoices, copyInvoice(invoice))
+		}
+	}
+	return invoices, nil
+}
+
+func (m *MockInvoiceRepository) CompleteEInvoice(invoice *models.Invoice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.invoices {
+		if m.invoices[i].ID == invoice.ID {
+			invoice.EInvoicePending = false
+			m.invoices[i].EInvoiceNumber = invoice.EInvoiceNumber
+			m.invoices[i].EInvoiceRandomCode = invoice.EInvoiceRandomCode
+			m.invoices[i].EInvoiceVoided = invoice.EInvoiceVoided
+			m.invoices[i].EInvoicePending = false
+			return nil
+		}
+	}
+	return errors.New("invoice not found")
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MockOrderRepository reserves stock, redeems coupons and empties carts
+// through the mock inventory, cart and promotion repositories it is given
+type MockOrderRepository struct {
+	mu            sync.Mutex
+	orders        []*models.Order
+	changes       []models.OrderStatusChange
+	inventoryRepo InventoryRepository
+	cartRepo      CartRepository
+	promotions    *MockPromotionRepository
+}
+
+func NewMockOrderRepository(inventoryRepo InventoryRepository, cartRepo CartRepository, promotionRepo PromotionRepository) OrderRepository {
+	return &MockOrderRepository{
+		inventoryRepo: inventoryRepo,
+		cartRepo:      cartRepo,
+		promotions:    promotionRepo.(*MockPromotionRepository),
+	}
+}
+
+func (m *MockOrderRepository) Place(order *models.Order, items []ReservationItem, cartID uint) ([]models.StockReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.orders {
+		if existing.UserID == order.UserID && existing.IdempotencyKey == order.IdempotencyKey {
+			return nil, errors.New("duplicate key value violates unique constraint")
+		}
+	}
+
+	// 模擬交易：優惠券超過上限或庫存不足時不留下訂單
+	order.ID = uint(len(m.orders) + 1)
+	if err := m.promotions.redeem(order); err != nil {
+		order.ID = 0
+		return nil, err
+	}
+	reservations, err := m.inventoryRepo.Reserve(order.Number, items, order.PaymentDueAt)
+	if err != nil {
+		m.promotions.release(order.ID)
+		order.ID = 0
+		return nil, err
+	}
+	if err := m.cartRepo.Clear(cartID); err != nil {
+		return nil, err
+	}
+
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	for i := range order.Items {
+		order.Items[i].ID = uint(i + 1)
+		order.Items[i].OrderID = order.ID
+	}
+	for i := range order.Promotions {
+		order.Promotions[i].ID = uint(i + 1)
+		order.Promotions[i].OrderID = order.ID
+	}
+	for i := range order.Taxes {
+		order.Taxes[i].ID = uint(i + 1)
+		order.Taxes[i].OrderID = order.ID
+	}
+	m.orders = append(m.orders, m.copy(order))
+	return reservations, nil
+}
+
+func (m *MockOrderRepository) copy(order *models.Order) *models.Order {
+	result := *order
+	result.Items = append([]models.OrderItem(nil), order.Items...)
+	result.Promotions = append([]models.AppliedPromotion(nil), order.Promotions...)
+	result.Taxes = append([]models.OrderTax(nil), order.Taxes...)
+	return &result
+}
+
+func (m *MockOrderRepository) FindByID(id uint) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, order := range m.orders {
+		if order.ID == id {
+			return m.copy(order), nil
+		}
+	}
+	return nil, errors.New("order not found")
+}
+
+func (m *MockOrderRepository) FindByIdempotencyKey(userID uint, key string) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, order := range m.orders {
+		if order.UserID == userID && order.IdempotencyKey == key {
+			return m.copy(order), nil
+		}
+	}
+	return nil, errors.New("order not found")
+}
+
+func (m *MockOrderRepository) FindByUserID(userID uint, offset, limit int) ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var orders []models.Order
+	for i := len(m.orders) - 1; i >= 0; i-- {
+		if m.orders[i].UserID == userID {
+			orders = append(orders, *m.copy(m.orders[i]))
+		}
+	}
+	if offset >= len(orders) {
+		return nil, nil
+	}
+	orders = orders[offset:]
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (m *MockOrderRepository) FindAll(status string, offset, limit int) ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var orders []models.Order
+	for i := len(m.orders) - 1; i >= 0; i-- {
+		if status == "" || m.orders[i].Status == status {
+			orders = append(orders, *m.copy(m.orders[i]))
+		}
+	}
+	if offset >= len(orders) {
+		return nil, nil
+	}
+	orders = orders[offset:]
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (m *MockOrderRepository) FindOverdue(now time.Time, limit int) ([]models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var orders []models.Order
+	for _, order := range m.orders {
+		if order.Status == models.OrderStatusPendingPayment && order.PaymentDueAt.Before(now) {
+			orders = append(orders, *m.copy(order))
+		}
+		if limit > 0 && len(orders) == limit {
+			break
+		}
+	}
+	return orders, nil
+}
+
+func (m *MockOrderRepository) Transition(order *models.Order, from string, change *models.OrderStatusChange, stock OrderStock) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, stored := range m.orders {
+		if stored.ID != order.ID {
+			continue
+		}
+		if stored.Status != from {
+			return ErrOrderStatusChanged
+		}
+		// 模擬交易：庫存處理失敗時不改變訂單狀態
+		if err := m.applyStock(stored, change, stock); err != nil {
+			return err
+		}
+		stored.Status = change.To
+		stored.Carrier = order.Carrier
+		stored.TrackingNumber = order.TrackingNumber
+		stored.UpdatedAt = time.Now()
+
+		change.ID = uint(len(m.changes) + 1)
+		change.CreatedAt = stored.UpdatedAt
+		m.changes = append(m.changes, *change)
+		return nil
+	}
+	return errors.New("order not found")
+}
+
+func (m *MockOrderRepository) applyStock(order *models.Order, change *models.OrderStatusChange, stock OrderStock) error {
+	switch stock {
+	case StockCommit:
+		committed, err := m.inventoryRepo.CommitReservations(order.Number)
+		if err != nil {
+			return err
+		}
+		if committed == 0 {
+			return ErrReservationNotFound
+		}
+	case StockRelease:
+		return m.inventoryRepo.ReleaseReservations(order.Number, models.ReservationReleased)
+	case StockRestock:
+		for _, item := range order.Items {
+			if n := item.Quantity - item.RestockedQuantity; n > 0 {
+				if err := m.inventoryRepo.ReturnSold(StockReturn{
+					Reference: order.Number,
+					ProductID: item.ProductID,
+					Quantity:  n,
+					UserID:    change.ActorID,
+					Note:      "order " + change.To,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range order.Items {
+			order.Items[i].RestockedQuantity = order.Items[i].Quantity
+		}
+	}
+	return nil
+}
+
+func (m *MockOrderRepository) ReleaseCoupons(orderID uint) error {
+	m.promotions.release(orderID)
+	return nil
+}
+
+func (m *MockOrderRepository) FindStatusHistory(orderID uint) ([]models.OrderStatusChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var changes []models.OrderStatusChange
+	for _, change := range m.changes {
+		if change.OrderID == orderID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (m *MockOrderRepository) HasPurchased(userID, productID uint, statuses []string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, order := range m.orders {
+		if order.UserID != userID || !containsStatus(statuses, order.Status) {
+			continue
+		}
+		for _, item := range order.Items {
+			if item.ProductID == productID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+type MockPaymentRepository struct {
+	mu       sync.Mutex
+	payments []*models.Payment
+	events   []models.PaymentEvent
+	methods  []models.PaymentMethod
+	nextID   uint
+}
+
+func NewMockPaymentRepository() PaymentRepository {
+	return &MockPaymentRepository{}
+}
+
+func (m *MockPaymentRepository) Create(payment *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	payment.ID = uint(len(m.payments) + 1)
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = payment.CreatedAt
+	stored := *payment
+	m.payments = append(m.payments, &stored)
+	return nil
+}
+
+func (m *MockPaymentRepository) Update(payment *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, stored := range m.payments {
+		if stored.ID == payment.ID {
+			payment.UpdatedAt = time.Now()
+			*stored = *payment
+			return nil
+		}
+	}
+	return errors.New("payment not found")
+}
+
+func (m *MockPaymentRepository) FindByID(id uint) (*models.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, payment := range m.payments {
+		if payment.ID == id {
+			result := *payment
+			return &result, nil
+		}
+	}
+	return nil, errors.New("payment not found")
+}
+
+func (m *MockPaymentRepository) FindByProviderRef(provider, providerRef string) (*models.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, payment := range m.payments {
+		if payment.Provider == provider && payment.ProviderRef == providerRef {
+			result := *payment
+			return &result, nil
+		}
+	}
+	return nil, errors.New("payment not found")
+}
+
+func (m *MockPaymentRepository) FindByOrderID(orderID uint) ([]models.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var payments []models.Payment
+	for _, payment := range m.payments {
+		if payment.OrderID == orderID {
+			payments = append(payments, *payment)
+		}
+	}
+	return payments, nil
+}
+
+func (m *MockPaymentRepository) RecordEvent(event *models.PaymentEvent) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.events {
+		if existing.Provider == event.Provider && existing.EventID == event.EventID {
+			return false, nil
+		}
+	}
+	event.ID = uint(len(m.events) + 1)
+	event.CreatedAt = time.Now()
+	m.events = append(m.events, *event)
+	return true, nil
+}
+
+func (m *MockPaymentRepository) DeleteEvent(provider, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, event := range m.events {
+		if event.Provider == provider && event.EventID == eventID {
+			m.events = append(m.events[:i], m.events[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockPaymentRepository) CreateMethod(method *models.PaymentMethod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	method.ID = m.nextID
+	method.CreatedAt = time.Now()
+	m.methods = append(m.methods, *method)
+	return nil
+}
+
+func (m *MockPaymentRepository) FindMethod(id uint) (*models.PaymentMethod, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, method := range m.methods {
+		if method.ID == id {
+			return &method, nil
+		}
+	}
+	return nil, errors.New("payment method not found")
+}
+
+func (m *MockPaymentRepository) FindMethodsByUserID(userID uint) ([]models.PaymentMethod, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var methods []models.PaymentMethod
+	for _, method := range m.methods {
+		if method.UserID == userID {
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+func (m *MockPaymentRepository) DeleteMethod(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, method := range m.methods {
+		if method.ID == id {
+			m.methods = append(m.methods[:i], m.methods[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
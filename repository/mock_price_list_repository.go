@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+)
+
+type MockPriceListRepository struct {
+	priceLists map[uint]*models.PriceList
+	prices     map[[2]uint]*models.ProductPrice
+	nextID     uint
+}
+
+func NewMockPriceListRepository() PriceListRepository {
+	return &MockPriceListRepository{
+		priceLists: make(map[uint]*models.PriceList),
+		prices:     make(map[[2]uint]*models.ProductPrice),
+		nextID:     1,
+	}
+}
+
+func (m *MockPriceListRepository) clearDefault(priceList *models.PriceList) {
+	if !priceList.IsDefault {
+		return
+	}
+	for _, other := range m.priceLists {
+		if other.ID != priceList.ID {
+			other.IsDefault = false
+		}
+	}
+}
+
+func (m *MockPriceListRepository) Create(priceList *models.PriceList) error {
+	for _, existing := range m.priceLists {
+		if existing.Code == priceList.Code {
+			return errors.New("price list code already exists")
+		}
+	}
+	m.clearDefault(priceList)
+	priceList.ID = m.nextID
+	m.nextID++
+	m.priceLists[priceList.ID] = priceList
+	return nil
+}
+
+func (m *MockPriceListRepository) Update(priceList *models.PriceList) error {
+	if _, exists := m.priceLists[priceList.ID]; !exists {
+		return errors.New("price list not found")
+	}
+	m.clearDefault(priceList)
+	m.priceLists[priceList.ID] = priceList
+	return nil
+}
+
+func (m *MockPriceListRepository) FindByID(id uint) (*models.PriceList, error) {
+	if priceList, exists := m.priceLists[id]; exists {
+		return priceList, nil
+	}
+	return nil, errors.New("price list not found")
+}
+
+func (m *MockPriceListRepository) FindAll() ([]models.PriceList, error) {
+	var priceLists []models.PriceList
+	for _, priceList := range m.priceLists {
+		priceLists = append(priceLists, *priceList)
+	}
+	sort.Slice(priceLists, func(i, j int) bool { return priceLists[i].ID < priceLists[j].ID })
+	return priceLists, nil
+}
+
+func (m *MockPriceListRepository) FindActiveByCurrency(currency string) (*models.PriceList, error) {
+	priceLists, _ := m.FindAll()
+	sort.SliceStable(priceLists, func(i, j int) bool { return priceLists[i].IsDefault && !priceLists[j].IsDefault })
+	for _, priceList := range priceLists {
+		if priceList.Currency == currency && priceList.Active {
+			return m.priceLists[priceList.ID], nil
+		}
+	}
+	return nil, errors.New("price list not found")
+}
+
+func (m *MockPriceListRepository) FindDefault() (*models.PriceList, error) {
+	for _, priceList := range m.priceLists {
+		if priceList.IsDefault && priceList.Active {
+			return priceList, nil
+		}
+	}
+	return nil, errors.New("price list not found")
+}
+
+func (m *MockPriceListRepository) SetPrice(price *models.ProductPrice) error {
+	key := [2]uint{price.PriceListID, price.ProductID}
+	if existing, exists := m.prices[key]; exists {
+		price.ID = existing.ID
+	} else {
+		price.ID = uint(len(m.prices) + 1)
+	}
+	m.prices[key] = price
+	return nil
+}
+
+func (m *MockPriceListRepository) DeletePrice(priceListID, productID uint) error {
+	delete(m.prices, [2]uint{priceListID, productID})
+	return nil
+}
+
+func (m *MockPriceListRepository) FindPrice(priceListID, productID uint) (*models.ProductPrice, error) {
+	if price, exists := m.prices[[2]uint{priceListID, productID}]; exists {
+		return price, nil
+	}
+	return nil, errors.New("price not found")
+}
+
+func (m *MockPriceListRepository) FindPrices(priceListID uint, productIDs []uint) ([]models.ProductPrice, error) {
+	var prices []models.ProductPrice
+	for _, productID := range productIDs {
+		if price, exists := m.prices[[2]uint{priceListID, productID}]; exists {
+			prices = append(prices, *price)
+		}
+	}
+	return prices, nil
+}
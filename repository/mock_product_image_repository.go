@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+)
+
+type MockProductImageRepository struct {
+	images map[uint]*models.ProductImage
+	nextID uint
+}
+
+func NewMockProductImageRepository() ProductImageRepository {
+	return &MockProductImageRepository{
+		images: make(map[uint]*models.ProductImage),
+		nextID: 1,
+	}
+}
+
+func (m *MockProductImageRepository) Create(image *models.ProductImage) error {
+	image.ID = m.nextID
+	m.nextID++
+	m.images[image.ID] = image
+	return nil
+}
+
+func (m *MockProductImageRepository) FindByID(id uint) (*models.ProductImage, error) {
+	if image, exists := m.images[id]; exists {
+		return image, nil
+	}
+	return nil, errors.New("image not found")
+}
+
+func (m *MockProductImageRepository) FindByProductID(productID uint) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	for _, image := range m.images {
+		if image.ProductID == productID {
+			images = append(images, *image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Position < images[j].Position })
+	return images, nil
+}
+
+func (m *MockProductImageRepository) Delete(id uint) error {
+	image, exists := m.images[id]
+	if !exists {
+		return errors.New("image not found")
+	}
+	delete(m.images, id)
+	for _, other := range m.images {
+		if other.ProductID == image.ProductID && other.Position > image.Position {
+			other.Position--
+		}
+	}
+	return nil
+}
+
+func (m *MockProductImageRepository) Reorder(productID uint, imageIDs []uint) error {
+	current, _ := m.FindByProductID(productID)
+	seen := make(map[uint]bool)
+	for _, id := range imageIDs {
+		image, exists := m.images[id]
+		if !exists || image.ProductID != productID || seen[id] {
+			return errors.New("image list does not match product images")
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(current) {
+		return errors.New("image list does not match product images")
+	}
+	for i, id := range imageIDs {
+		m.images[id].Position = i
+	}
+	return nil
+}
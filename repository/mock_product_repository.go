@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+	"time"
+)
+
+type MockProductRepository struct {
+	products map[uint]*models.Product
+	nextID   uint
+}
+
+func NewMockProductRepository() ProductRepository {
+	return &MockProductRepository{
+		products: make(map[uint]*models.Product),
+		nextID:   1,
+	}
+}
+
+func (m *MockProductRepository) Create(product *models.Product) error {
+	for _, existing := range m.products {
+		if existing.SKU == product.SKU {
+			return errors.New("sku already exists")
+		}
+	}
+	if product.ID == 0 {
+		product.ID = m.nextID
+	}
+	if product.Status == "" {
+		product.Status = models.ProductStatusDraft
+	}
+	if product.ID >= m.nextID {
+		m.nextID = product.ID + 1
+	}
+	m.products[product.ID] = product
+	return nil
+}
+
+func (m *MockProductRepository) FindByID(id uint) (*models.Product, error) {
+	if product, exists := m.products[id]; exists {
+		return product, nil
+	}
+	return nil, errors.New("product not found")
+}
+
+func (m *MockProductRepository) FindBySKU(sku string) (*models.Product, error) {
+	for _, product := range m.products {
+		if product.SKU == sku {
+			return product, nil
+		}
+	}
+	return nil, errors.New("product not found")
+}
+
+func (m *MockProductRepository) find(match func(p *models.Product) bool, offset, limit int) []models.Product {
+	ids := make([]uint, 0, len(m.products))
+	for id, product := range m.products {
+		if match(product) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var products []models.Product
+	for i, id := range ids {
+		if i < offset || (limit > 0 && len(products) >= limit) {
+			continue
+		}
+		products = append(products, *m.products[id])
+	}
+	return products
+}
+
+func (m *MockProductRepository) FindByIDs(ids []uint) ([]models.Product, error) {
+	var products []models.Product
+	for _, id := range ids {
+		if product, exists := m.products[id]; exists {
+			products = append(products, *product)
+		}
+	}
+	return products, nil
+}
+
+func (m *MockProductRepository) FindAll(offset, limit int) ([]models.Product, error) {
+	return m.find(func(p *models.Product) bool { return true }, offset, limit), nil
+}
+
+func (m *MockProductRepository) FindVisible(now time.Time, offset, limit int) ([]models.Product, error) {
+	return m.find(func(p *models.Product) bool { return p.IsVisible(now) }, offset, limit), nil
+}
+
+func (m *MockProductRepository) FindByStatus(status string, offset, limit int) ([]models.Product, error) {
+	return m.find(func(p *models.Product) bool { return p.Status == status }, offset, limit), nil
+}
+
+func (m *MockProductRepository) Update(product *models.Product) error {
+	if _, exists := m.products[product.ID]; !exists {
+		return errors.New("product not found")
+	}
+	m.products[product.ID] = product
+	return nil
+}
+
+func (m *MockProductRepository) Delete(id uint) error {
+	if _, exists := m.products[id]; !exists {
+		return errors.New("product not found")
+	}
+	delete(m.products, id)
+	return nil
+}
+
+func (m *MockProductRepository) FindInBatches(batchSize int, fn func(products []models.Product) error) error {
+	for offset := 0; ; offset += batchSize {
+		products, _ := m.FindAll(offset, batchSize)
+		if len(products) == 0 {
+			return nil
+		}
+		if err := fn(products); err != nil {
+			return err
+		}
+	}
+}
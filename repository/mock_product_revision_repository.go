@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"time"
+)
+
+type MockProductRevisionRepository struct {
+	productRepo ProductRepository
+	revisions   []models.ProductRevision
+}
+
+func NewMockProductRevisionRepository(productRepo ProductRepository) ProductRevisionRepository {
+	return &MockProductRevisionRepository{
+		productRepo: productRepo,
+	}
+}
+
+func (m *MockProductRevisionRepository) Create(product *models.Product, revision *models.ProductRevision) error {
+	if err := m.productRepo.Create(product); err != nil {
+		return err
+	}
+	revision.ProductID = product.ID
+	m.record(revision, *product)
+	return nil
+}
+
+func (m *MockProductRevisionRepository) UpsertBySKU(products []*models.Product, revision models.ProductRevision) error {
+	for _, product := range products {
+		if existing, _ := m.productRepo.FindBySKU(product.SKU); existing != nil {
+			existing.Name = product.Name
+			existing.Description = product.Description
+			existing.Category = product.Category
+			product.ID = existing.ID
+		} else if err := m.productRepo.Create(product); err != nil {
+			return err
+		}
+		stored, err := m.productRepo.FindByID(product.ID)
+		if err != nil {
+			return err
+		}
+		next := revision
+		next.ProductID = product.ID
+		m.record(&next, *stored)
+	}
+	return nil
+}
+
+func (m *MockProductRevisionRepository) Save(product *models.Product, revision *models.ProductRevision) error {
+	if err := m.productRepo.Update(product); err != nil {
+		return err
+	}
+	revision.ProductID = product.ID
+	m.record(revision, *product)
+	return nil
+}
+
+func (m *MockProductRevisionRepository) PublishScheduled(now time.Time, revision models.ProductRevision) (int64, error) {
+	return m.applySchedule(revision, func(product *models.Product) bool {
+		if product.Status != models.ProductStatusScheduled || product.PublishAt == nil || product.PublishAt.After(now) {
+			return false
+		}
+		product.Status = models.ProductStatusPublished
+		product.PublishedAt = product.PublishAt
+		return true
+	})
+}
+
+func (m *MockProductRevisionRepository) ArchiveExpired(now time.Time, revision models.ProductRevision) (int64, error) {
+	return m.applySchedule(revision, func(product *models.Product) bool {
+		live := product.Status == models.ProductStatusPublished || product.Status == models.ProductStatusScheduled
+		if !live || product.UnpublishAt == nil || product.UnpublishAt.After(now) {
+			return false
+		}
+		product.Status = models.ProductStatusArchived
+		return true
+	})
+}
+
+// applySchedule records a revision of every product apply changed
+func (m *MockProductRevisionRepository) applySchedule(revision models.ProductRevision, apply func(product *models.Product) bool) (int64, error) {
+	var changed int64
+	for _, product := range m.productRepo.(*MockProductRepository).products {
+		if apply(product) {
+			next := revision
+			next.ProductID = product.ID
+			m.record(&next, *product)
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (m *MockProductRevisionRepository) record(revision *models.ProductRevision, product models.Product) {
+	revision.Version = 1
+	for _, existing := range m.revisions {
+		if existing.ProductID == revision.ProductID && existing.Version >= revision.Version {
+			revision.Version = existing.Version + 1
+		}
+	}
+	revision.ID = uint(len(m.revisions) + 1)
+	revision.CreatedAt = time.Now()
+	revision.Snapshot = models.NewProductSnapshot(product)
+	m.revisions = append(m.revisions, *revision)
+}
+
+func (m *MockProductRevisionRepository) FindByProductID(productID uint, offset, limit int) ([]models.ProductRevision, error) {
+	var revisions []models.ProductRevision
+	for i := len(m.revisions) - 1; i >= 0; i-- {
+		if m.revisions[i].ProductID == productID {
+			revisions = append(revisions, m.revisions[i])
+		}
+	}
+	if offset >= len(revisions) {
+		return nil, nil
+	}
+	revisions = revisions[offset:]
+	if limit > 0 && len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+	return revisions, nil
+}
+
+func (m *MockProductRevisionRepository) FindByVersion(productID uint, version int) (*models.ProductRevision, error) {
+	for i := range m.revisions {
+		if m.revisions[i].ProductID == productID && m.revisions[i].Version == version {
+			revision := m.revisions[i]
+			return &revision, nil
+		}
+	}
+	return nil, errors.New("revision not found")
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MockPromotionRepository struct {
+	mu          sync.Mutex
+	promotions  []*models.Promotion
+	coupons     []*models.Coupon
+	redemptions []models.CouponRedemption
+	nextID      uint
+}
+
+func NewMockPromotionRepository() PromotionRepository {
+	return &MockPromotionRepository{nextID: 1}
+}
+
+func (m *MockPromotionRepository) Create(promotion *models.Promotion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	promotion.ID = m.nextID
+	m.nextID++
+	promotion.CreatedAt = time.Now()
+	promotion.UpdatedAt = promotion.CreatedAt
+	stored := *promotion
+	m.promotions = append(m.promotions, &stored)
+	return nil
+}
+
+func (m *MockPromotionRepository) Update(promotion *models.Promotion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.promotions {
+		if existing.ID == promotion.ID {
+			promotion.UpdatedAt = time.Now()
+			stored := *promotion
+			m.promotions[i] = &stored
+			return nil
+		}
+	}
+	return errors.New("promotion not found")
+}
+
+func (m *MockPromotionRepository) Delete(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.promotions {
+		if existing.ID == id {
+			m.promotions = append(m.promotions[:i], m.promotions[i+1:]...)
+			break
+		}
+	}
+	var coupons []*models.Coupon
+	for _, coupon := range m.coupons {
+		if coupon.PromotionID != id {
+			coupons = append(coupons, coupon)
+		}
+	}
+	m.coupons = coupons
+	return nil
+}
+
+func (m *MockPromotionRepository) FindByID(id uint) (*models.Promotion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, promotion := range m.promotions {
+		if promotion.ID == id {
+			result := *promotion
+			return &result, nil
+		}
+	}
+	return nil, errors.New("promotion not found")
+}
+
+// sorted returns copies of the promotions by descending priority
+func (m *MockPromotionRepository) sorted() []models.Promotion {
+	promotions := make([]models.Promotion, len(m.promotions))
+	for i, promotion := range m.promotions {
+		promotions[i] = *promotion
+	}
+	sort.SliceStable(promotions, func(i, j int) bool {
+		return promotions[i].Priority > promotions[j].Priority
+	})
+	return promotions
+}
+
+func (m *MockPromotionRepository) FindAll(offset, limit int) ([]models.Promotion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	promotions := m.sorted()
+	if offset >= len(promotions) {
+		return nil, nil
+	}
+	promotions = promotions[offset:]
+	if limit > 0 && len(promotions) > limit {
+		promotions = promotions[:limit]
+	}
+	return promotions, nil
+}
+
+func (m *MockPromotionRepository) FindRunning(now time.Time) ([]models.Promotion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var running []models.Promotion
+	for _, promotion := range m.sorted() {
+		if promotion.IsRunning(now) {
+			running = append(running, promotion)
+		}
+	}
+	return running, nil
+}
+
+func (m *MockPromotionRepository) CreateCoupon(coupon *models.Coupon) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.coupons {
+		if existing.Code == coupon.Code {
+			return errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	coupon.ID = m.nextID
+	m.nextID++
+	coupon.CreatedAt = time.Now()
+	stored := *coupon
+	m.coupons = append(m.coupons, &stored)
+	return nil
+}
+
+func (m *MockPromotionRepository) FindCouponByCode(code string) (*models.Coupon, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, coupon := range m.coupons {
+		if coupon.Code == code {
+			result := *coupon
+			return &result, nil
+		}
+	}
+	return nil, errors.New("coupon not found")
+}
+
+func (m *MockPromotionRepository) FindCoupons(promotionID uint) ([]models.Coupon, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var coupons []models.Coupon
+	for _, coupon := range m.coupons {
+		if coupon.PromotionID == promotionID {
+			coupons = append(coupons, *coupon)
+		}
+	}
+	return coupons, nil
+}
+
+func (m *MockPromotionRepository) CountRedemptions(couponID, userID uint) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.countRedemptions(couponID, userID), nil
+}
+
+func (m *MockPromotionRepository) countRedemptions(couponID, userID uint) int {
+	count := 0
+	for _, redemption := range m.redemptions {
+		if redemption.CouponID == couponID && redemption.UserID == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// redeem checks and counts the coupons of an order all or nothing, like
+// the transaction of GormOrderRepository.Place
+func (m *MockPromotionRepository) redeem(order *models.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var used []*models.Coupon
+	for _, applied := range order.Promotions {
+		if applied.CouponID == nil {
+			continue
+		}
+		var coupon *models.Coupon
+		for _, c := range m.coupons {
+			if c.ID == *applied.CouponID {
+				coupon = c
+			}
+		}
+		if coupon == nil {
+			return errors.New("coupon not found")
+		}
+		if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+			return ErrCouponUsedUp
+		}
+		if coupon.PerUserLimit > 0 && m.countRedemptions(coupon.ID, order.UserID) >= coupon.PerUserLimit {
+			return ErrCouponLimitPerUser
+		}
+		used = append(used, coupon)
+	}
+	for _, coupon := range used {
+		coupon.UsedCount++
+		m.redemptions = append(m.redemptions, models.CouponRedemption{
+			ID:        uint(len(m.redemptions) + 1),
+			CreatedAt: time.Now(),
+			CouponID:  coupon.ID,
+			UserID:    order.UserID,
+			OrderID:   order.ID,
+		})
+	}
+	return nil
+}
+
+func (m *MockPromotionRepository) release(orderID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []models.CouponRedemption
+	for _, redemption := range m.redemptions {
+		if redemption.OrderID != orderID {
+			kept = append(kept, redemption)
+			continue
+		}
+		for _, coupon := range m.coupons {
+			if coupon.ID == redemption.CouponID && coupon.UsedCount > 0 {
+				coupon.UsedCount--
+			}
+		}
+	}
+	m.redemptions = kept
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockRecommendationRepository reads sales from the ledger of a
+// MockInventoryRepository
+type MockRecommendationRepository struct {
+	mu            sync.Mutex
+	recs          []models.ProductRecommendation
+	inventoryRepo *MockInventoryRepository
+	productRepo   ProductRepository
+}
+
+func NewMockRecommendationRepository(inventoryRepo InventoryRepository, productRepo ProductRepository) RecommendationRepository {
+	return &MockRecommendationRepository{
+		inventoryRepo: inventoryRepo.(*MockInventoryRepository),
+		productRepo:   productRepo,
+	}
+}
+
+// sales returns the sell movements recorded since the given time
+func (m *MockRecommendationRepository) sales(since time.Time) []models.StockMovement {
+	m.inventoryRepo.mu.Lock()
+	defer m.inventoryRepo.mu.Unlock()
+	var movements []models.StockMovement
+	for _, movement := range m.inventoryRepo.movements {
+		if movement.Type == models.MovementSell && !movement.CreatedAt.Before(since) {
+			movements = append(movements, movement)
+		}
+	}
+	return movements
+}
+
+func (m *MockRecommendationRepository) FindSaleBaskets(since time.Time, fn func(productIDs []uint) error) error {
+	baskets := make(map[string][]uint)
+	var references []string
+	for _, movement := range m.sales(since) {
+		if movement.Reference == "" {
+			continue
+		}
+		basket, exists := baskets[movement.Reference]
+		if !exists {
+			references = append(references, movement.Reference)
+		}
+		if !containsID(basket, movement.ProductID) {
+			baskets[movement.Reference] = append(basket, movement.ProductID)
+		}
+	}
+	sort.Strings(references)
+	for _, reference := range references {
+		if err := fn(baskets[reference]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockRecommendationRepository) ReplaceAll(recs []models.ProductRecommendation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recs = make([]models.ProductRecommendation, len(recs))
+	for i, rec := range recs {
+		rec.ID = uint(i + 1)
+		m.recs[i] = rec
+	}
+	return nil
+}
+
+func (m *MockRecommendationRepository) FindByProductID(productID uint, limit int) ([]models.ProductRecommendation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var recs []models.ProductRecommendation
+	for _, rec := range m.recs {
+		if rec.ProductID == productID {
+			recs = append(recs, rec)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Rank < recs[j].Rank })
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	return recs, nil
+}
+
+func (m *MockRecommendationRepository) FindBestSellers(category string, since, now time.Time, exclude []uint, limit int) ([]models.Product, error) {
+	sold := make(map[uint]int)
+	for _, movement := range m.sales(since) {
+		sold[movement.ProductID] -= movement.Quantity
+	}
+
+	var products []models.Product
+	for productID := range sold {
+		if containsID(exclude, productID) {
+			continue
+		}
+		product, err := m.productRepo.FindByID(productID)
+		if err != nil || !product.IsVisible(now) {
+			continue
+		}
+		if category != "" && product.Category != category {
+			continue
+		}
+		products = append(products, *product)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		if sold[products[i].ID] != sold[products[j].ID] {
+			return sold[products[i].ID] > sold[products[j].ID]
+		}
+		return products[i].ID < products[j].ID
+	})
+	if limit > 0 && len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MockRefundRepository updates the payments and orders held by the mock
+// payment and order repositories it is given
+type MockRefundRepository struct {
+	mu       sync.Mutex
+	refunds  []*models.Refund
+	payments *MockPaymentRepository
+	orders   *MockOrderRepository
+}
+
+func NewMockRefundRepository(paymentRepo PaymentRepository, orderRepo OrderRepository) RefundRepository {
+	return &MockRefundRepository{
+		payments: paymentRepo.(*MockPaymentRepository),
+		orders:   orderRepo.(*MockOrderRepository),
+	}
+}
+
+func (m *MockRefundRepository) lockAll() func() {
+	m.mu.Lock()
+	m.payments.mu.Lock()
+	m.orders.mu.Lock()
+	return func() {
+		m.orders.mu.Unlock()
+		m.payments.mu.Unlock()
+		m.mu.Unlock()
+	}
+}
+
+func (m *MockRefundRepository) payment(id uint) *models.Payment {
+	for _, payment := range m.payments.payments {
+		if payment.ID == id {
+			return payment
+		}
+	}
+	return nil
+}
+
+func (m *MockRefundRepository) order(id uint) *models.Order {
+	for _, order := range m.orders.orders {
+		if order.ID == id {
+			return order
+		}
+	}
+	return nil
+}
+
+func orderItem(order *models.Order, id uint) *models.OrderItem {
+	for i := range order.Items {
+		if order.Items[i].ID == id {
+			return &order.Items[i]
+		}
+	}
+	return nil
+}
+
+func (m *MockRefundRepository) Create(refund *models.Refund) error {
+	unlock := m.lockAll()
+	defer unlock()
+
+	payment, order := m.payment(refund.PaymentID), m.order(refund.OrderID)
+	if payment == nil || order == nil || payment.RefundedAmount.Add(refund.Amount).Cmp(payment.Amount) > 0 {
+		return ErrRefundExceedsCaptured
+	}
+	for _, item := range refund.Items {
+		line := orderItem(order, item.OrderItemID)
+		if line == nil || line.RefundedQuantity+item.Quantity > line.Quantity {
+			return ErrRefundExceedsQuantity
+		}
+	}
+
+	// 模擬交易：全部檢查通過後才更新
+	payment.RefundedAmount = payment.RefundedAmount.Add(refund.Amount)
+	for _, item := range refund.Items {
+		orderItem(order, item.OrderItemID).RefundedQuantity += item.Quantity
+	}
+	order.RefundedTotal = order.RefundedTotal.Add(refund.Amount)
+
+	refund.ID = uint(len(m.refunds) + 1)
+	refund.CreatedAt = time.Now()
+	refund.UpdatedAt = refund.CreatedAt
+	for i := range refund.Items {
+		refund.Items[i].ID = uint(i + 1)
+		refund.Items[i].RefundID = refund.ID
+	}
+	m.store(refund)
+	return nil
+}
+
+func (m *MockRefundRepository) store(refund *models.Refund) {
+	stored := *refund
+	stored.Items = append([]models.RefundItem(nil), refund.Items...)
+	for i, existing := range m.refunds {
+		if existing.ID == refund.ID {
+			m.refunds[i] = &stored
+			return
+		}
+	}
+	m.refunds = append(m.refunds, &stored)
+}
+
+func (m *MockRefundRepository) Complete(refund *models.Refund) error {
+	unlock := m.lockAll()
+	defer unlock()
+
+	if order := m.order(refund.OrderID); order != nil && refund.Restock {
+		restocked := make(map[uint]int, len(refund.Items))
+		rets := make([]StockReturn, len(refund.Items))
+		for i, item := range refund.Items {
+			line := orderItem(order, item.OrderItemID)
+			restocked[item.OrderItemID] += item.Quantity
+			if line == nil || line.RestockedQuantity+restocked[item.OrderItemID] > line.Quantity {
+				return ErrAlreadyRestocked
+			}
+			rets[i] = StockReturn{
+				Reference: order.Number,
+				ProductID: item.ProductID,
+				Quantity:  item.Quantity,
+				UserID:    refund.ActorID,
+				Note:      fmt.Sprintf("refund %d", refund.ID),
+			}
+		}
+		// 模擬交易：庫存全部入帳後才記為已補貨
+		if err := m.orders.inventoryRepo.(*MockInventoryRepository).returnAll(rets); err != nil {
+			return err
+		}
+		for _, item := range refund.Items {
+			orderItem(order, item.OrderItemID).RestockedQuantity += item.Quantity
+		}
+	}
+	refund.Status = models.RefundStatusSucceeded
+	refund.UpdatedAt = time.Now()
+	m.store(refund)
+	if payment := m.payment(refund.PaymentID); payment != nil {
+		payment.Status = models.PaymentStatusPartiallyRefunded
+		if payment.RefundedAmount.Cmp(payment.Amount) >= 0 {
+			payment.Status = models.PaymentStatusRefunded
+		}
+	}
+	return nil
+}
+
+func (m *MockRefundRepository) Fail(refund *models.Refund) error {
+	unlock := m.lockAll()
+	defer unlock()
+
+	refund.Status = models.RefundStatusFailed
+	refund.UpdatedAt = time.Now()
+	m.store(refund)
+	if payment := m.payment(refund.PaymentID); payment != nil {
+		payment.RefundedAmount = payment.RefundedAmount.Sub(refund.Amount)
+	}
+	if order := m.order(refund.OrderID); order != nil {
+		order.RefundedTotal = order.RefundedTotal.Sub(refund.Amount)
+		for _, item := range refund.Items {
+			orderItem(order, item.OrderItemID).RefundedQuantity -= item.Quantity
+		}
+	}
+	return nil
+}
+
+func (m *MockRefundRepository) FindByOrderID(orderID uint) ([]models.Refund, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var refunds []models.Refund
+	for _, refund := range m.refunds {
+		if refund.OrderID == orderID {
+			result := *refund
+			result.Items = append([]models.RefundItem(nil), refund.Items...)
+			refunds = append(refunds, result)
+		}
+	}
+	return refunds, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MockReturnRepository checks returns against the lines of the mock order
+// repository and books their stock through the mock inventory repository
+// it is given
+type MockReturnRepository struct {
+	mu        sync.Mutex
+	returns   []models.Return
+	photos    uint
+	orders    *MockOrderRepository
+	inventory *MockInventoryRepository
+}
+
+func NewMockReturnRepository(orderRepo OrderRepository, inventoryRepo InventoryRepository) ReturnRepository {
+	return &MockReturnRepository{
+		orders:    orderRepo.(*MockOrderRepository),
+		inventory: inventoryRepo.(*MockInventoryRepository),
+	}
+}
+
+func copyReturn(ret models.Return) models.Return {
+	ret.Items = append([]models.ReturnItem(nil), ret.Items...)
+	for i := range ret.Items {
+		ret.Items[i].Photos = append([]models.ReturnPhoto(nil), ret.Items[i].Photos...)
+	}
+	return ret
+}
+
+func (m *MockReturnRepository) Create(ret *models.Return) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, err := m.orders.FindByID(ret.OrderID)
+	if err != nil {
+		return err
+	}
+	var open []models.ReturnItem
+	for _, existing := range m.returns {
+		if existing.OrderID == ret.OrderID && slices.Contains(openReturnStatuses, existing.Status) {
+			open = append(open, existing.Items...)
+		}
+	}
+	if err := checkReturnable(ret, order.Items, open); err != nil {
+		return err
+	}
+	ret.ID = uint(len(m.returns) + 1)
+	ret.CreatedAt = time.Now()
+	ret.UpdatedAt = ret.CreatedAt
+	itemID := uint(1)
+	for _, existing := range m.returns {
+		itemID += uint(len(existing.Items))
+	}
+	for i := range ret.Items {
+		ret.Items[i].ID = itemID
+		ret.Items[i].ReturnID = ret.ID
+		itemID++
+	}
+	m.returns = append(m.returns, copyReturn(*ret))
+	return nil
+}
+
+func (m *MockReturnRepository) FindByID(id uint) (*models.Return, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == 0 || int(id) > len(m.returns) {
+		return nil, errors.New("return not found")
+	}
+	found := copyReturn(m.returns[id-1])
+	return &found, nil
+}
+
+func (m *MockReturnRepository) FindByOrderID(orderID uint) ([]models.Return, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var returns []models.Return
+	for _, ret := range m.returns {
+		if ret.OrderID == orderID {
+			returns = append(returns, copyReturn(ret))
+		}
+	}
+	return returns, nil
+}
+
+func (m *MockReturnRepository) FindByStatus(status string, offset, limit int) ([]models.Return, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var returns []models.Return
+	for _, ret := range m.returns {
+		if status == "" || ret.Status == status {
+			returns = append(returns, copyReturn(ret))
+		}
+	}
+	if offset >= len(returns) {
+		return []models.Return{}, nil
+	}
+	returns = returns[offset:]
+	if len(returns) > limit {
+		returns = returns[:limit]
+	}
+	return returns, nil
+}
+
+func (m *MockReturnRepository) Update(ret *models.Return, from string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.update(ret, from)
+}
+
+func (m *MockReturnRepository) Receive(ret *models.Return, stock []StockReturn) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ret.ID == 0 || int(ret.ID) > len(m.returns) {
+		return errors.New("return not found")
+	}
+	if m.returns[ret.ID-1].Status != models.ReturnStatusApproved {
+		return ErrReturnChanged
+	}
+	// 模擬交易：庫存無法入帳時退貨維持已核准
+	if err := m.inventory.returnAll(stock); err != nil {
+		return err
+	}
+	return m.update(ret, models.ReturnStatusApproved)
+}
+
+func (m *MockReturnRepository) update(ret *models.Return, from string) error {
+	if ret.ID == 0 || int(ret.ID) > len(m.returns) {
+		return errors.New("return not found")
+	}
+	stored := &m.returns[ret.ID-1]
+	if stored.Status != from {
+		return ErrReturnChanged
+	}
+	ret.UpdatedAt = time.Now()
+	items := stored.Items
+	*stored = copyReturn(*ret)
+	// 照片只經由 AddPhoto 新增
+	for i := range stored.Items {
+		for _, item := range items {
+			if item.ID == stored.Items[i].ID {
+				stored.Items[i].Photos = item.Photos
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MockReturnRepository) AddPhoto(photo *models.ReturnPhoto) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for r := range m.returns {
+		for i := range m.returns[r].Items {
+			item := &m.returns[r].Items[i]
+			if item.ID == photo.ReturnItemID {
+				m.photos++
+				photo.ID = m.photos
+				item.Photos = append(item.Photos, *photo)
+				return nil
+			}
+		}
+	}
+	return errors.New("return item not found")
+}
+
+func (m *MockReturnRepository) CountPhotos(returnItemID uint) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ret := range m.returns {
+		for _, item := range ret.Items {
+			if item.ID == returnItemID {
+				return len(item.Photos), nil
+			}
+		}
+	}
+	return 0, nil
+}
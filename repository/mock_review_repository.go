@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+)
+
+type MockReviewRepository struct {
+	reviews     map[uint]*models.Review
+	votes       map[[2]uint]bool
+	productRepo ProductRepository
+	nextID      uint
+}
+
+// NewMockReviewRepository keeps product ratings of productRepo up to date
+// like the database implementation does
+func NewMockReviewRepository(productRepo ProductRepository) ReviewRepository {
+	return &MockReviewRepository{
+		reviews:     make(map[uint]*models.Review),
+		votes:       make(map[[2]uint]bool),
+		productRepo: productRepo,
+		nextID:      1,
+	}
+}
+
+func (m *MockReviewRepository) recalculate(productID uint) {
+	product, err := m.productRepo.FindByID(productID)
+	if err != nil {
+		return
+	}
+	var counts [5]int
+	for _, review := range m.reviews {
+		if review.ProductID == productID && review.Status == models.ReviewStatusApproved {
+			counts[review.Rating-1]++
+		}
+	}
+	product.Rating = models.NewRatingSummary(counts)
+}
+
+func (m *MockReviewRepository) Create(review *models.Review) error {
+	if _, err := m.FindByProductAndUser(review.ProductID, review.UserID); err == nil {
+		return errors.New("duplicate review")
+	}
+	review.ID = m.nextID
+	m.nextID++
+	m.reviews[review.ID] = review
+	m.recalculate(review.ProductID)
+	return nil
+}
+
+func (m *MockReviewRepository) FindByID(id uint) (*models.Review, error) {
+	if review, exists := m.reviews[id]; exists {
+		return review, nil
+	}
+	return nil, errors.New("review not found")
+}
+
+func (m *MockReviewRepository) FindByProductAndUser(productID, userID uint) (*models.Review, error) {
+	for _, review := range m.reviews {
+		if review.ProductID == productID && review.UserID == userID {
+			return review, nil
+		}
+	}
+	return nil, errors.New("review not found")
+}
+
+func (m *MockReviewRepository) list(match func(*models.Review) bool, less func(a, b *models.Review) bool, offset, limit int) []models.Review {
+	var found []*models.Review
+	for _, review := range m.reviews {
+		if match(review) {
+			found = append(found, review)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return less(found[i], found[j]) })
+
+	var reviews []models.Review
+	for i, review := range found {
+		if i >= offset && (limit <= 0 || len(reviews) < limit) {
+			reviews = append(reviews, *review)
+		}
+	}
+	return reviews
+}
+
+func (m *MockReviewRepository) FindByProductID(productID uint, sortBy string, offset, limit int) ([]models.Review, error) {
+	less := func(a, b *models.Review) bool { return a.ID > b.ID }
+	switch sortBy {
+	case "helpful":
+		less = func(a, b *models.Review) bool {
+			if a.HelpfulCount != b.HelpfulCount {
+				return a.HelpfulCount > b.HelpfulCount
+			}
+			return a.ID > b.ID
+		}
+	case "rating":
+		less = func(a, b *models.Review) bool {
+			if a.Rating != b.Rating {
+				return a.Rating > b.Rating
+			}
+			return a.ID > b.ID
+		}
+	}
+	return m.list(func(r *models.Review) bool {
+		return r.ProductID == productID && r.Status == models.ReviewStatusApproved
+	}, less, offset, limit), nil
+}
+
+func (m *MockReviewRepository) FindByStatus(status string, offset, limit int) ([]models.Review, error) {
+	return m.list(func(r *models.Review) bool { return r.Status == status },
+		func(a, b *models.Review) bool { return a.ID < b.ID }, offset, limit), nil
+}
+
+func (m *MockReviewRepository) Update(review *models.Review) error {
+	if _, exists := m.reviews[review.ID]; !exists {
+		return errors.New("review not found")
+	}
+	m.reviews[review.ID] = review
+	m.recalculate(review.ProductID)
+	return nil
+}
+
+func (m *MockReviewRepository) Delete(id uint) error {
+	review, exists := m.reviews[id]
+	if !exists {
+		return errors.New("review not found")
+	}
+	delete(m.reviews, id)
+	m.recalculate(review.ProductID)
+	return nil
+}
+
+func (m *MockReviewRepository) AddPhoto(photo *models.ReviewPhoto) error {
+	review, exists := m.reviews[photo.ReviewID]
+	if !exists {
+		return errors.New("review not found")
+	}
+	photo.ID = uint(len(review.Photos) + 1)
+	review.Photos = append(review.Photos, *photo)
+	return nil
+}
+
+func (m *MockReviewRepository) CountPhotos(reviewID uint) (int, error) {
+	if review, exists := m.reviews[reviewID]; exists {
+		return len(review.Photos), nil
+	}
+	return 0, nil
+}
+
+func (m *MockReviewRepository) AddVote(reviewID, userID uint) error {
+	key := [2]uint{reviewID, userID}
+	if m.votes[key] {
+		return ErrDuplicateVote
+	}
+	review, exists := m.reviews[reviewID]
+	if !exists {
+		return errors.New("review not found")
+	}
+	m.votes[key] = true
+	review.HelpfulCount++
+	return nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+type MockShippingRepository struct {
+	mu      sync.Mutex
+	zones   []models.ShippingZone
+	methods []models.ShippingMethod
+	nextID  uint
+}
+
+func NewMockShippingRepository() ShippingRepository {
+	return &MockShippingRepository{nextID: 1}
+}
+
+func (m *MockShippingRepository) CreateZone(zone *models.ShippingZone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zone.ID = m.nextID
+	m.nextID++
+	zone.CreatedAt = time.Now()
+	zone.UpdatedAt = zone.CreatedAt
+	stored := *zone
+	stored.Methods = nil
+	m.zones = append(m.zones, stored)
+	return nil
+}
+
+func (m *MockShippingRepository) UpdateZone(zone *models.ShippingZone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.zones {
+		if m.zones[i].ID == zone.ID {
+			zone.UpdatedAt = time.Now()
+			stored := *zone
+			stored.Methods = nil
+			m.zones[i] = stored
+			return nil
+		}
+	}
+	return errors.New("shipping zone not found")
+}
+
+func (m *MockShippingRepository) DeleteZone(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.zones {
+		if m.zones[i].ID == id {
+			m.zones = append(m.zones[:i], m.zones[i+1:]...)
+			break
+		}
+	}
+	var methods []models.ShippingMethod
+	for _, method := range m.methods {
+		if method.ZoneID != id {
+			methods = append(methods, method)
+		}
+	}
+	m.methods = methods
+	return nil
+}
+
+// withMethods returns a copy of zone with its methods attached
+func (m *MockShippingRepository) withMethods(zone models.ShippingZone) models.ShippingZone {
+	zone.Methods = nil
+	for _, method := range m.methods {
+		if method.ZoneID == zone.ID {
+			zone.Methods = append(zone.Methods, method)
+		}
+	}
+	return zone
+}
+
+func (m *MockShippingRepository) FindZoneByID(id uint) (*models.ShippingZone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, zone := range m.zones {
+		if zone.ID == id {
+			result := m.withMethods(zone)
+			return &result, nil
+		}
+	}
+	return nil, errors.New("shipping zone not found")
+}
+
+func (m *MockShippingRepository) FindZones() ([]models.ShippingZone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	zones := make([]models.ShippingZone, len(m.zones))
+	for i, zone := range m.zones {
+		zones[i] = m.withMethods(zone)
+	}
+	return zones, nil
+}
+
+func (m *MockShippingRepository) CreateMethod(method *models.ShippingMethod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	method.ID = m.nextID
+	m.nextID++
+	method.CreatedAt = time.Now()
+	method.UpdatedAt = method.CreatedAt
+	m.methods = append(m.methods, *method)
+	return nil
+}
+
+func (m *MockShippingRepository) UpdateMethod(method *models.ShippingMethod) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.methods {
+		if m.methods[i].ID == method.ID {
+			method.UpdatedAt = time.Now()
+			m.methods[i] = *method
+			return nil
+		}
+	}
+	return errors.New("shipping method not found")
+}
+
+func (m *MockShippingRepository) DeleteMethod(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.methods {
+		if m.methods[i].ID == id {
+			m.methods = append(m.methods[:i], m.methods[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockShippingRepository) FindMethodByID(id uint) (*models.ShippingMethod, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, method := range m.methods {
+		if method.ID == id {
+			result := method
+			return &result, nil
+		}
+	}
+	return nil, errors.New("shipping method not found")
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+type MockStockAlertRepository struct {
+	mu            sync.Mutex
+	thresholds    map[uint]*models.ReorderThreshold
+	alerts        []*models.StockAlert
+	subscriptions []*models.BackInStockSubscription
+}
+
+func NewMockStockAlertRepository() StockAlertRepository {
+	return &MockStockAlertRepository{
+		thresholds: make(map[uint]*models.ReorderThreshold),
+	}
+}
+
+func (m *MockStockAlertRepository) SetThreshold(threshold *models.ReorderThreshold) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	threshold.UpdatedAt = time.Now()
+	m.thresholds[threshold.ProductID] = threshold
+	return nil
+}
+
+func (m *MockStockAlertRepository) DeleteThreshold(productID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.thresholds, productID)
+	return nil
+}
+
+func (m *MockStockAlertRepository) FindThreshold(productID uint) (*models.ReorderThreshold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if threshold, exists := m.thresholds[productID]; exists {
+		return threshold, nil
+	}
+	return nil, errors.New("threshold not found")
+}
+
+func (m *MockStockAlertRepository) OpenAlert(alert *models.StockAlert) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.alerts {
+		if existing.ProductID == alert.ProductID && existing.ResolvedAt == nil {
+			return false, nil
+		}
+	}
+	alert.ID = uint(len(m.alerts) + 1)
+	alert.CreatedAt = time.Now()
+	m.alerts = append(m.alerts, alert)
+	return true, nil
+}
+
+func (m *MockStockAlertRepository) ResolveAlerts(productID uint, resolvedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, alert := range m.alerts {
+		if alert.ProductID == productID && alert.ResolvedAt == nil {
+			alert.ResolvedAt = &resolvedAt
+		}
+	}
+	return nil
+}
+
+func (m *MockStockAlertRepository) FindAlerts(openOnly bool, offset, limit int) ([]models.StockAlert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var alerts []models.StockAlert
+	for i := len(m.alerts) - 1; i >= 0; i-- {
+		if !openOnly || m.alerts[i].ResolvedAt == nil {
+			alerts = append(alerts, *m.alerts[i])
+		}
+	}
+	if offset >= len(alerts) {
+		return nil, nil
+	}
+	alerts = alerts[offset:]
+	if limit > 0 && len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+func (m *MockStockAlertRepository) Subscribe(subscription *models.BackInStockSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.subscriptions {
+		if existing.ProductID == subscription.ProductID && existing.UserID == subscription.UserID {
+			existing.NotifiedAt = nil
+			*subscription = *existing
+			return nil
+		}
+	}
+	subscription.ID = uint(len(m.subscriptions) + 1)
+	subscription.CreatedAt = time.Now()
+	m.subscriptions = append(m.subscriptions, subscription)
+	return nil
+}
+
+func (m *MockStockAlertRepository) Unsubscribe(productID, userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.subscriptions {
+		if existing.ProductID == productID && existing.UserID == userID {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockStockAlertRepository) FindSubscriptionsByUser(userID uint) ([]models.BackInStockSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subscriptions []models.BackInStockSubscription
+	for _, subscription := range m.subscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *MockStockAlertRepository) ClaimSubscriptions(productID uint, notifiedAt time.Time) ([]models.BackInStockSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subscriptions []models.BackInStockSubscription
+	for _, subscription := range m.subscriptions {
+		if subscription.ProductID == productID && subscription.NotifiedAt == nil {
+			subscription.NotifiedAt = &notifiedAt
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return subscriptions, nil
+}
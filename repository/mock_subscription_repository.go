@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sync"
+	"time"
+)
+
+type MockSubscriptionRepository struct {
+	mu            sync.Mutex
+	plans         []models.SubscriptionPlan
+	subscriptions []models.Subscription
+}
+
+func NewMockSubscriptionRepository() SubscriptionRepository {
+	return &MockSubscriptionRepository{}
+}
+
+func (m *MockSubscriptionRepository) CreatePlan(plan *models.SubscriptionPlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	plan.ID = uint(len(m.plans) + 1)
+	plan.CreatedAt = time.Now()
+	plan.UpdatedAt = plan.CreatedAt
+	m.plans = append(m.plans, *plan)
+	return nil
+}
+
+func (m *MockSubscriptionRepository) UpdatePlan(plan *models.SubscriptionPlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if plan.ID == 0 || int(plan.ID) > len(m.plans) {
+		return errors.New("plan not found")
+	}
+	plan.UpdatedAt = time.Now()
+	m.plans[plan.ID-1] = *plan
+	return nil
+}
+
+func (m *MockSubscriptionRepository) FindPlan(id uint) (*models.SubscriptionPlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == 0 || int(id) > len(m.plans) {
+		return nil, errors.New("plan not found")
+	}
+	plan := m.plans[id-1]
+	return &plan, nil
+}
+
+func (m *MockSubscriptionRepository) FindPlans(activeOnly bool) ([]models.SubscriptionPlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var plans []models.SubscriptionPlan
+	for _, plan := range m.plans {
+		if plan.Active || !activeOnly {
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+func (m *MockSubscriptionRepository) Create(sub *models.Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub.ID = uint(len(m.subscriptions) + 1)
+	sub.CreatedAt = time.Now()
+	sub.UpdatedAt = sub.CreatedAt
+	m.subscriptions = append(m.subscriptions, *sub)
+	return nil
+}
+
+func (m *MockSubscriptionRepository) FindByID(id uint) (*models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == 0 || int(id) > len(m.subscriptions) {
+		return nil, errors.New("subscription not found")
+	}
+	sub := m.subscriptions[id-1]
+	return &sub, nil
+}
+
+func (m *MockSubscriptionRepository) FindByUserID(userID uint) ([]models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []models.Subscription
+	for _, sub := range m.subscriptions {
+		if sub.UserID == userID {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func (m *MockSubscriptionRepository) FindByStatus(status string, offset, limit int) ([]models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []models.Subscription
+	for _, sub := range m.subscriptions {
+		if status == "" || sub.Status == status {
+			subs = append(subs, sub)
+		}
+	}
+	if offset >= len(subs) {
+		return []models.Subscription{}, nil
+	}
+	subs = subs[offset:]
+	if len(subs) > limit {
+		subs = subs[:limit]
+	}
+	return subs, nil
+}
+
+func (m *MockSubscriptionRepository) FindDue(now, claimedBefore time.Time, limit int) ([]models.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []models.Subscription
+	for _, sub := range m.subscriptions {
+		renew := sub.Status == models.SubscriptionStatusActive && !sub.NextRenewalAt.After(now)
+		retry := sub.Status == models.SubscriptionStatusPastDue && sub.NextRetryAt != nil && !sub.NextRetryAt.After(now)
+		stuck := sub.Status == models.SubscriptionStatusRenewing && sub.UpdatedAt.Before(claimedBefore)
+		if (renew || retry || stuck) && len(subs) < limit {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func (m *MockSubscriptionRepository) Update(sub *models.Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sub.ID == 0 || int(sub.ID) > len(m.subscriptions) {
+		return errors.New("subscription not found")
+	}
+	if m.subscriptions[sub.ID-1].Version != sub.Version {
+		return ErrSubscriptionChanged
+	}
+	sub.Version++
+	sub.UpdatedAt = time.Now()
+	m.subscriptions[sub.ID-1] = *sub
+	return nil
+}
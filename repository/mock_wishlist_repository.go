@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"e-commerce/models"
+	"errors"
+	"sort"
+	"time"
+)
+
+type MockWishlistRepository struct {
+	wishlists map[uint]*models.Wishlist
+	items     []*models.WishlistItem
+	nextID    uint
+}
+
+func NewMockWishlistRepository() WishlistRepository {
+	return &MockWishlistRepository{
+		wishlists: make(map[uint]*models.Wishlist),
+		nextID:    1,
+	}
+}
+
+// withItems returns a copy of a wishlist with its items, newest first
+func (m *MockWishlistRepository) withItems(wishlist *models.Wishlist) *models.Wishlist {
+	result := *wishlist
+	result.Items = nil
+	for i := len(m.items) - 1; i >= 0; i-- {
+		if m.items[i].WishlistID == wishlist.ID {
+			result.Items = append(result.Items, *m.items[i])
+		}
+	}
+	return &result
+}
+
+func (m *MockWishlistRepository) Create(wishlist *models.Wishlist) error {
+	for _, existing := range m.wishlists {
+		if existing.UserID == wishlist.UserID && existing.Name == wishlist.Name {
+			return ErrWishlistNameTaken
+		}
+	}
+	wishlist.ID = m.nextID
+	m.nextID++
+	wishlist.CreatedAt = time.Now()
+	wishlist.UpdatedAt = wishlist.CreatedAt
+	stored := *wishlist
+	m.wishlists[wishlist.ID] = &stored
+	return nil
+}
+
+func (m *MockWishlistRepository) FindByID(id uint) (*models.Wishlist, error) {
+	if wishlist, exists := m.wishlists[id]; exists {
+		return m.withItems(wishlist), nil
+	}
+	return nil, errors.New("wishlist not found")
+}
+
+func (m *MockWishlistRepository) FindByUserID(userID uint) ([]models.Wishlist, error) {
+	var wishlists []models.Wishlist
+	for _, wishlist := range m.wishlists {
+		if wishlist.UserID == userID {
+			wishlists = append(wishlists, *m.withItems(wishlist))
+		}
+	}
+	sort.Slice(wishlists, func(i, j int) bool { return wishlists[i].ID < wishlists[j].ID })
+	return wishlists, nil
+}
+
+func (m *MockWishlistRepository) FindByShareToken(token string) (*models.Wishlist, error) {
+	for _, wishlist := range m.wishlists {
+		if wishlist.ShareToken != nil && *wishlist.ShareToken == token {
+			return m.withItems(wishlist), nil
+		}
+	}
+	return nil, errors.New("wishlist not found")
+}
+
+func (m *MockWishlistRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	for _, wishlist := range m.wishlists {
+		if wishlist.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockWishlistRepository) Update(wishlist *models.Wishlist) error {
+	stored, exists := m.wishlists[wishlist.ID]
+	if !exists {
+		return errors.New("wishlist not found")
+	}
+	for _, existing := range m.wishlists {
+		if existing.ID != wishlist.ID && existing.UserID == stored.UserID && existing.Name == wishlist.Name {
+			return ErrWishlistNameTaken
+		}
+	}
+	stored.Name = wishlist.Name
+	stored.ShareToken = wishlist.ShareToken
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MockWishlistRepository) Delete(id uint) error {
+	delete(m.wishlists, id)
+	var items []*models.WishlistItem
+	for _, item := range m.items {
+		if item.WishlistID != id {
+			items = append(items, item)
+		}
+	}
+	m.items = items
+	return nil
+}
+
+func (m *MockWishlistRepository) AddItem(item *models.WishlistItem) error {
+	for _, existing := range m.items {
+		if existing.WishlistID == item.WishlistID && existing.ProductID == item.ProductID {
+			return nil
+		}
+	}
+	item.ID = uint(len(m.items) + 1)
+	item.CreatedAt = time.Now()
+	stored := *item
+	m.items = append(m.items, &stored)
+	return nil
+}
+
+func (m *MockWishlistRepository) FindItem(wishlistID, productID uint) (*models.WishlistItem, error) {
+	for _, item := range m.items {
+		if item.WishlistID == wishlistID && item.ProductID == productID {
+			found := *item
+			return &found, nil
+		}
+	}
+	return nil, errors.New("wishlist item not found")
+}
+
+func (m *MockWishlistRepository) RemoveItem(wishlistID, productID uint) error {
+	for i, item := range m.items {
+		if item.WishlistID == wishlistID && item.ProductID == productID {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("wishlist item not found")
+}
+
+func (m *MockWishlistRepository) MoveItem(fromID, toID, productID uint) error {
+	if _, err := m.FindItem(toID, productID); err == nil {
+		return m.RemoveItem(fromID, productID)
+	}
+	for _, item := range m.items {
+		if item.WishlistID == fromID && item.ProductID == productID {
+			item.WishlistID = toID
+			return nil
+		}
+	}
+	return errors.New("wishlist item not found")
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderRepository interface {
+	// Place creates an order, reserves its stock under the order number,
+	// redeems its coupons and empties the cart it came from, all in one
+	// transaction. A coupon over its limits fails with ErrCouponUsedUp or
+	// ErrCouponLimitPerUser. A cartID of 0 leaves every cart alone.
+	Place(order *models.Order, items []ReservationItem, cartID uint) ([]models.StockReservation, error)
+	FindByID(id uint) (*models.Order, error)
+	FindByIdempotencyKey(userID uint, key string) (*models.Order, error)
+	// FindByUserID returns the orders of a user, newest first
+	FindByUserID(userID uint, offset, limit int) ([]models.Order, error)
+	// FindAll returns orders newest first, optionally only those in status
+	FindAll(status string, offset, limit int) ([]models.Order, error)
+	// FindOverdue returns orders awaiting payment whose payment is past due
+	FindOverdue(now time.Time, limit int) ([]models.Order, error)
+	// Transition moves order from one status to change.To, applies stock to
+	// the stock of the order and records the change, all in one transaction.
+	// It fails with ErrOrderStatusChanged when the order is no longer in
+	// from, so two concurrent transitions cannot both succeed.
+	Transition(order *models.Order, from string, change *models.OrderStatusChange, stock OrderStock) error
+	// ReleaseCoupons gives back the coupon uses of an order
+	ReleaseCoupons(orderID uint) error
+	// FindStatusHistory returns the status changes of an order, oldest first
+	FindStatusHistory(orderID uint) ([]models.OrderStatusChange, error)
+	// HasPurchased reports whether the user has an order for the product in
+	// one of statuses
+	HasPurchased(userID, productID uint, statuses []string) (bool, error)
+}
+
+var ErrOrderStatusChanged = errors.New("order status has changed")
+
+// OrderStock is what a status transition does to the stock of an order
+type OrderStock int
+
+const (
+	// StockUnchanged leaves the stock of the order alone
+	StockUnchanged OrderStock = iota
+	// StockCommit turns the reservations of the order into sales. It fails
+	// with ErrReservationNotFound when nothing is reserved any more.
+	StockCommit
+	// StockRelease gives the stock still reserved for the order back
+	StockRelease
+	// StockRestock puts the units sold that were not restocked yet, e.g. by
+	// a refund, back into the warehouses they were taken from
+	StockRestock
+)
+
+type GormOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOrderRepository(db *gorm.DB) OrderRepository {
+	return &GormOrderRepository{db: db}
+}
+
+func (r *GormOrderRepository) Place(order *models.Order, items []ReservationItem, cartID uint) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 同一個 Idempotency-Key 重送時，唯一索引會讓這裡失敗並整筆回滾
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		var err error
+		if reservations, err = reserve(tx, order.Number, items, order.PaymentDueAt); err != nil {
+			return err
+		}
+		if err := redeemCoupons(tx, order); err != nil {
+			return err
+		}
+		return clearCart(tx, cartID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// withLines loads the items, promotions and taxes of orders
+func (r *GormOrderRepository) withLines() *gorm.DB {
+	byID := func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}
+	return r.db.Preload("Items", byID).Preload("Promotions", byID).Preload("Taxes", byID)
+}
+
+func (r *GormOrderRepository) FindByID(id uint) (*models.Order, error) {
+	var order models.Order
+	err := r.withLines().First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *GormOrderRepository) FindByIdempotencyKey(userID uint, key string) (*models.Order, error) {
+	var order models.Order
+	err := r.withLines().Where("user_id = ? AND idempotency_key = ?", userID, key).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *GormOrderRepository) FindByUserID(userID uint, offset, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.withLines().Where("user_id = ?", userID).Order("id DESC").Offset(offset).Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+func (r *GormOrderRepository) FindAll(status string, offset, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	query := r.withLines()
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+func (r *GormOrderRepository) FindOverdue(now time.Time, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("status = ? AND payment_due_at < ?", models.OrderStatusPendingPayment, now).
+		Order("payment_due_at").Limit(limit).Find(&orders).Error
+	return orders, err
+}
+
+func (r *GormOrderRepository) Transition(order *models.Order, from string, change *models.OrderStatusChange, stock OrderStock) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", order.ID, from).
+			Updates(map[string]interface{}{
+				"status":          change.To,
+				"carrier":         order.Carrier,
+				"tracking_number": order.TrackingNumber,
+				"updated_at":      time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderStatusChanged
+		}
+		if err := applyOrderStock(tx, order, change, stock); err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func applyOrderStock(tx *gorm.DB, order *models.Order, change *models.OrderStatusChange, stock OrderStock) error {
+	switch stock {
+	case StockCommit:
+		committed, err := commitReservations(tx, order.Number)
+		if err != nil {
+			return err
+		}
+		if committed == 0 {
+			return ErrReservationNotFound
+		}
+	case StockRelease:
+		return releaseReservations(tx, order.Number, models.ReservationReleased)
+	case StockRestock:
+		var items []models.OrderItem
+		// 鎖定訂單明細，避免同時退款時重複補回庫存
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ?", order.ID).Order("id").
+			Find(&items).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			if n := item.Quantity - item.RestockedQuantity; n > 0 {
+				if err := returnSold(tx, StockReturn{
+					Reference: order.Number,
+					ProductID: item.ProductID,
+					Quantity:  n,
+					UserID:    change.ActorID,
+					Note:      "order " + change.To,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&models.OrderItem{}).Where("order_id = ?", order.ID).
+			Update("restocked_quantity", gorm.Expr("quantity")).Error
+	}
+	return nil
+}
+
+func (r *GormOrderRepository) ReleaseCoupons(orderID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return releaseCoupons(tx, orderID)
+	})
+}
+
+func (r *GormOrderRepository) FindStatusHistory(orderID uint) ([]models.OrderStatusChange, error) {
+	var changes []models.OrderStatusChange
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&changes).Error
+	return changes, err
+}
+
+func (r *GormOrderRepository) HasPurchased(userID, productID uint, statuses []string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND order_items.product_id = ? AND orders.status IN ?", userID, productID, statuses).
+		Count(&count).Error
+	return count > 0, err
+}
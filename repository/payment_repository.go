@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRepository interface {
+	Create(payment *models.Payment) error
+	Update(payment *models.Payment) error
+	FindByID(id uint) (*models.Payment, error)
+	FindByProviderRef(provider, providerRef string) (*models.Payment, error)
+	// FindByOrderID returns the payment attempts of an order, oldest first
+	FindByOrderID(orderID uint) ([]models.Payment, error)
+	// RecordEvent stores a webhook event, reporting false when the event was
+	// already recorded
+	RecordEvent(event *models.PaymentEvent) (bool, error)
+	// DeleteEvent forgets an event so a redelivery is processed again
+	DeleteEvent(provider, eventID string) error
+
+	CreateMethod(method *models.PaymentMethod) error
+	FindMethod(id uint) (*models.PaymentMethod, error)
+	// FindMethodsByUserID returns the saved payment methods of a user, oldest first
+	FindMethodsByUserID(userID uint) ([]models.PaymentMethod, error)
+	DeleteMethod(id uint) error
+}
+
+type GormPaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPaymentRepository(db *gorm.DB) PaymentRepository {
+	return &GormPaymentRepository{db: db}
+}
+
+func (r *GormPaymentRepository) Create(payment *models.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *GormPaymentRepository) Update(payment *models.Payment) error {
+	return r.db.Save(payment).Error
+}
+
+func (r *GormPaymentRepository) FindByID(id uint) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.First(&payment, id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *GormPaymentRepository) FindByProviderRef(provider, providerRef string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("provider = ? AND provider_ref = ?", provider, providerRef).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *GormPaymentRepository) FindByOrderID(orderID uint) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&payments).Error
+	return payments, err
+}
+
+func (r *GormPaymentRepository) RecordEvent(event *models.PaymentEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormPaymentRepository) DeleteEvent(provider, eventID string) error {
+	return r.db.Where("provider = ? AND event_id = ?", provider, eventID).Delete(&models.PaymentEvent{}).Error
+}
+
+func (r *GormPaymentRepository) CreateMethod(method *models.PaymentMethod) error {
+	return r.db.Create(method).Error
+}
+
+func (r *GormPaymentRepository) FindMethod(id uint) (*models.PaymentMethod, error) {
+	var method models.PaymentMethod
+	if err := r.db.First(&method, id).Error; err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+func (r *GormPaymentRepository) FindMethodsByUserID(userID uint) ([]models.PaymentMethod, error) {
+	var methods []models.PaymentMethod
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&methods).Error
+	return methods, err
+}
+
+func (r *GormPaymentRepository) DeleteMethod(id uint) error {
+	return r.db.Delete(&models.PaymentMethod{}, id).Error
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PriceListRepository interface {
+	Create(priceList *models.PriceList) error
+	Update(priceList *models.PriceList) error
+	FindByID(id uint) (*models.PriceList, error)
+	FindAll() ([]models.PriceList, error)
+	FindActiveByCurrency(currency string) (*models.PriceList, error)
+	FindDefault() (*models.PriceList, error)
+	SetPrice(price *models.ProductPrice) error
+	DeletePrice(priceListID, productID uint) error
+	FindPrice(priceListID, productID uint) (*models.ProductPrice, error)
+	FindPrices(priceListID uint, productIDs []uint) ([]models.ProductPrice, error)
+}
+
+type GormPriceListRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPriceListRepository(db *gorm.DB) PriceListRepository {
+	return &GormPriceListRepository{db: db}
+}
+
+func (r *GormPriceListRepository) Create(priceList *models.PriceList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefault(tx, priceList); err != nil {
+			return err
+		}
+		return tx.Create(priceList).Error
+	})
+}
+
+func (r *GormPriceListRepository) Update(priceList *models.PriceList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefault(tx, priceList); err != nil {
+			return err
+		}
+		return tx.Save(priceList).Error
+	})
+}
+
+// clearDefault keeps at most one default price list
+func clearDefault(tx *gorm.DB, priceList *models.PriceList) error {
+	if !priceList.IsDefault {
+		return nil
+	}
+	return tx.Model(&models.PriceList{}).
+		Where("is_default = ? AND id <> ?", true, priceList.ID).
+		Update("is_default", false).Error
+}
+
+func (r *GormPriceListRepository) FindByID(id uint) (*models.PriceList, error) {
+	var priceList models.PriceList
+	err := r.db.First(&priceList, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &priceList, nil
+}
+
+func (r *GormPriceListRepository) FindAll() ([]models.PriceList, error) {
+	var priceLists []models.PriceList
+	err := r.db.Order("id").Find(&priceLists).Error
+	return priceLists, err
+}
+
+func (r *GormPriceListRepository) FindActiveByCurrency(currency string) (*models.PriceList, error) {
+	var priceList models.PriceList
+	err := r.db.Where("currency = ? AND active = ?", currency, true).
+		Order("is_default DESC, id").
+		First(&priceList).Error
+	if err != nil {
+		return nil, err
+	}
+	return &priceList, nil
+}
+
+func (r *GormPriceListRepository) FindDefault() (*models.PriceList, error) {
+	var priceList models.PriceList
+	err := r.db.Where("is_default = ? AND active = ?", true, true).First(&priceList).Error
+	if err != nil {
+		return nil, err
+	}
+	return &priceList, nil
+}
+
+func (r *GormPriceListRepository) SetPrice(price *models.ProductPrice) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "price_list_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price_amount", "price_currency", "updated_at"}),
+	}).Create(price).Error
+}
+
+func (r *GormPriceListRepository) DeletePrice(priceListID, productID uint) error {
+	return r.db.Where("price_list_id = ? AND product_id = ?", priceListID, productID).
+		Delete(&models.ProductPrice{}).Error
+}
+
+func (r *GormPriceListRepository) FindPrice(priceListID, productID uint) (*models.ProductPrice, error) {
+	var price models.ProductPrice
+	err := r.db.Where("price_list_id = ? AND product_id = ?", priceListID, productID).First(&price).Error
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+func (r *GormPriceListRepository) FindPrices(priceListID uint, productIDs []uint) ([]models.ProductPrice, error) {
+	var prices []models.ProductPrice
+	if len(productIDs) == 0 {
+		return prices, nil
+	}
+	err := r.db.Where("price_list_id = ? AND product_id IN ?", priceListID, productIDs).Find(&prices).Error
+	return prices, err
+}
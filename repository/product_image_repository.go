@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+type ProductImageRepository interface {
+	Create(image *models.ProductImage) error
+	FindByID(id uint) (*models.ProductImage, error)
+	FindByProductID(productID uint) ([]models.ProductImage, error)
+	Delete(id uint) error
+	// Reorder assigns positions following the order of imageIDs, which must
+	// contain every image of the product exactly once
+	Reorder(productID uint, imageIDs []uint) error
+}
+
+type GormProductImageRepository struct {
+	db *gorm.DB
+}
+
+func NewGormProductImageRepository(db *gorm.DB) ProductImageRepository {
+	return &GormProductImageRepository{db: db}
+}
+
+func (r *GormProductImageRepository) Create(image *models.ProductImage) error {
+	return r.db.Create(image).Error
+}
+
+func (r *GormProductImageRepository) FindByID(id uint) (*models.ProductImage, error) {
+	var image models.ProductImage
+	err := r.db.Preload("Variants").First(&image, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *GormProductImageRepository) FindByProductID(productID uint) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	err := r.db.Preload("Variants").
+		Where("product_id = ?", productID).
+		Order("position").
+		Find(&images).Error
+	return images, err
+}
+
+func (r *GormProductImageRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var image models.ProductImage
+		if err := tx.First(&image, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("image_id = ?", id).Delete(&models.ProductImageVariant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		// 補齊刪除後留下的順序空缺
+		return tx.Model(&models.ProductImage{}).
+			Where("product_id = ? AND position > ?", image.ProductID, image.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+}
+
+func (r *GormProductImageRepository) Reorder(productID uint, imageIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ProductImage{}).
+			Where("product_id = ? AND id IN ?", productID, imageIDs).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		var total int64
+		if err := tx.Model(&models.ProductImage{}).
+			Where("product_id = ?", productID).
+			Count(&total).Error; err != nil {
+			return err
+		}
+		if int(count) != len(imageIDs) || count != total {
+			return errors.New("image list does not match product images")
+		}
+
+		for i, id := range imageIDs {
+			if err := tx.Model(&models.ProductImage{}).
+				Where("id = ?", id).
+				Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+type ProductRepository interface {
+	Create(product *models.Product) error
+	FindByID(id uint) (*models.Product, error)
+	FindBySKU(sku string) (*models.Product, error)
+	// FindByIDs returns the products that exist among ids, without images
+	FindByIDs(ids []uint) ([]models.Product, error)
+	FindAll(offset, limit int) ([]models.Product, error)
+	// FindVisible lists the products customers can see at now
+	FindVisible(now time.Time, offset, limit int) ([]models.Product, error)
+	FindByStatus(status string, offset, limit int) ([]models.Product, error)
+	Update(product *models.Product) error
+	Delete(id uint) error
+	// FindInBatches walks the whole catalog ordered by ID
+	FindInBatches(batchSize int, fn func(products []models.Product) error) error
+}
+
+type GormProductRepository struct {
+	db *gorm.DB
+}
+
+func NewGormProductRepository(db *gorm.DB) ProductRepository {
+	return &GormProductRepository{db: db}
+}
+
+func (r *GormProductRepository) Create(product *models.Product) error {
+	return r.db.Create(product).Error
+}
+
+func (r *GormProductRepository) FindByID(id uint) (*models.Product, error) {
+	var product models.Product
+	err := r.db.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Images.Variants").First(&product, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *GormProductRepository) FindBySKU(sku string) (*models.Product, error) {
+	var product models.Product
+	err := r.db.Where("sku = ?", sku).First(&product).Error
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *GormProductRepository) FindByIDs(ids []uint) ([]models.Product, error) {
+	var products []models.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&products).Error
+	return products, err
+}
+
+func (r *GormProductRepository) FindAll(offset, limit int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.Order("id").Offset(offset).Limit(limit).Find(&products).Error
+	return products, err
+}
+
+// visibleAt mirrors models.Product.IsVisible
+func visibleAt(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(status = ? OR (status = ? AND publish_at <= ?)) AND (unpublish_at IS NULL OR unpublish_at > ?)",
+			models.ProductStatusPublished, models.ProductStatusScheduled, now, now)
+	}
+}
+
+func (r *GormProductRepository) FindVisible(now time.Time, offset, limit int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.Scopes(visibleAt(now)).Order("id").Offset(offset).Limit(limit).Find(&products).Error
+	return products, err
+}
+
+func (r *GormProductRepository) FindByStatus(status string, offset, limit int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.Where("status = ?", status).Order("id").Offset(offset).Limit(limit).Find(&products).Error
+	return products, err
+}
+
+func (r *GormProductRepository) Update(product *models.Product) error {
+	return r.db.Omit("Images").Save(product).Error
+}
+
+func (r *GormProductRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Product{}, id).Error
+}
+
+func (r *GormProductRepository) FindInBatches(batchSize int, fn func(products []models.Product) error) error {
+	var products []models.Product
+	return r.db.Order("id").FindInBatches(&products, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(products)
+	}).Error
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProductRevisionRepository interface {
+	// Create inserts product and stores it as its first version, in one
+	// transaction
+	Create(product *models.Product, revision *models.ProductRevision) error
+	// UpsertBySKU inserts or updates products matched by SKU and stores each
+	// of them as its next version with the details of revision, in one
+	// transaction
+	UpsertBySKU(products []*models.Product, revision models.ProductRevision) error
+	// PublishScheduled publishes scheduled products whose publish time has
+	// passed and ArchiveExpired archives live products whose unpublish time
+	// has passed. Both store each product changed as its next version with
+	// the details of revision, in one transaction.
+	PublishScheduled(now time.Time, revision models.ProductRevision) (int64, error)
+	ArchiveExpired(now time.Time, revision models.ProductRevision) (int64, error)
+	// Save updates product and stores the values just written as its next
+	// version, in one transaction
+	Save(product *models.Product, revision *models.ProductRevision) error
+	FindByProductID(productID uint, offset, limit int) ([]models.ProductRevision, error)
+	FindByVersion(productID uint, version int) (*models.ProductRevision, error)
+}
+
+type GormProductRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormProductRevisionRepository(db *gorm.DB) ProductRevisionRepository {
+	return &GormProductRevisionRepository{db: db}
+}
+
+func (r *GormProductRevisionRepository) Create(product *models.Product, revision *models.ProductRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		revision.ProductID = product.ID
+		return createRevision(tx, revision, *product)
+	})
+}
+
+func (r *GormProductRevisionRepository) UpsertBySKU(products []*models.Product, revision models.ProductRevision) error {
+	if len(products) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Images").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "sku"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "description", "category", "updated_at"}),
+		}).Create(products).Error; err != nil {
+			return err
+		}
+		for _, p := range products {
+			// 更新後重新讀取，快照包含匯入未觸及的欄位
+			var product models.Product
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, p.ID).Error; err != nil {
+				return err
+			}
+			next := revision
+			next.ProductID = product.ID
+			if err := createRevision(tx, &next, product); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GormProductRevisionRepository) Save(product *models.Product, revision *models.ProductRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 更新商品列同時取得列鎖，版本號依序遞增
+		if err := tx.Omit("Images").Save(product).Error; err != nil {
+			return err
+		}
+		revision.ProductID = product.ID
+		return createRevision(tx, revision, *product)
+	})
+}
+
+func (r *GormProductRevisionRepository) PublishScheduled(now time.Time, revision models.ProductRevision) (int64, error) {
+	return r.applySchedule(revision, map[string]interface{}{
+		"status":       models.ProductStatusPublished,
+		"published_at": gorm.Expr("publish_at"),
+	}, "status = ? AND publish_at <= ?", models.ProductStatusScheduled, now)
+}
+
+func (r *GormProductRevisionRepository) ArchiveExpired(now time.Time, revision models.ProductRevision) (int64, error) {
+	return r.applySchedule(revision, map[string]interface{}{
+		"status": models.ProductStatusArchived,
+	}, "status IN ? AND unpublish_at <= ?", []string{models.ProductStatusPublished, models.ProductStatusScheduled}, now)
+}
+
+// applySchedule applies updates to the products matching query and records
+// a revision of each
+func (r *GormProductRevisionRepository) applySchedule(revision models.ProductRevision, updates map[string]interface{}, query string, args ...interface{}) (int64, error) {
+	var changed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.Product{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(query, args...).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.Product{}).Where("id IN ?", ids).Updates(updates).Error; err != nil {
+			return err
+		}
+		var products []models.Product
+		if err := tx.Where("id IN ?", ids).Find(&products).Error; err != nil {
+			return err
+		}
+		for _, product := range products {
+			next := revision
+			next.ProductID = product.ID
+			if err := createRevision(tx, &next, product); err != nil {
+				return err
+			}
+		}
+		changed = int64(len(products))
+		return nil
+	})
+	return changed, err
+}
+
+// createRevision stores a snapshot of product as its next version; the
+// product row must be locked by tx
+func createRevision(tx *gorm.DB, revision *models.ProductRevision, product models.Product) error {
+	var latest int
+	if err := tx.Model(&models.ProductRevision{}).
+		Where("product_id = ?", revision.ProductID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	revision.Version = latest + 1
+	revision.Snapshot = models.NewProductSnapshot(product)
+	return tx.Create(revision).Error
+}
+
+func (r *GormProductRevisionRepository) FindByProductID(productID uint, offset, limit int) ([]models.ProductRevision, error) {
+	var revisions []models.ProductRevision
+	err := r.db.Where("product_id = ?", productID).
+		Order("version DESC").Offset(offset).Limit(limit).
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *GormProductRevisionRepository) FindByVersion(productID uint, version int) (*models.ProductRevision, error) {
+	var revision models.ProductRevision
+	err := r.db.Where("product_id = ? AND version = ?", productID, version).First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
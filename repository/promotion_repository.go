@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCouponUsedUp       = errors.New("coupon usage limit reached")
+	ErrCouponLimitPerUser = errors.New("coupon already used the maximum number of times")
+)
+
+type PromotionRepository interface {
+	Create(promotion *models.Promotion) error
+	Update(promotion *models.Promotion) error
+	Delete(id uint) error
+	FindByID(id uint) (*models.Promotion, error)
+	// FindAll returns promotions by descending priority
+	FindAll(offset, limit int) ([]models.Promotion, error)
+	// FindRunning returns the active promotions whose date window covers now
+	FindRunning(now time.Time) ([]models.Promotion, error)
+	CreateCoupon(coupon *models.Coupon) error
+	FindCouponByCode(code string) (*models.Coupon, error)
+	FindCoupons(promotionID uint) ([]models.Coupon, error)
+	// CountRedemptions returns how many orders of a user used a coupon
+	CountRedemptions(couponID, userID uint) (int, error)
+}
+
+type GormPromotionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPromotionRepository(db *gorm.DB) PromotionRepository {
+	return &GormPromotionRepository{db: db}
+}
+
+func (r *GormPromotionRepository) Create(promotion *models.Promotion) error {
+	return r.db.Create(promotion).Error
+}
+
+func (r *GormPromotionRepository) Update(promotion *models.Promotion) error {
+	return r.db.Save(promotion).Error
+}
+
+// Delete removes a promotion and its coupons. Orders keep their copy of
+// the discount.
+func (r *GormPromotionRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("promotion_id = ?", id).Delete(&models.Coupon{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Promotion{}, id).Error
+	})
+}
+
+func (r *GormPromotionRepository) FindByID(id uint) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := r.db.First(&promotion, id).Error; err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (r *GormPromotionRepository) FindAll(offset, limit int) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.Order("priority DESC, id").Offset(offset).Limit(limit).Find(&promotions).Error
+	return promotions, err
+}
+
+func (r *GormPromotionRepository) FindRunning(now time.Time) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.Where("active = ? AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", true, now, now).
+		Order("priority DESC, id").Find(&promotions).Error
+	return promotions, err
+}
+
+func (r *GormPromotionRepository) CreateCoupon(coupon *models.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *GormPromotionRepository) FindCouponByCode(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.Where("code = ?", code).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *GormPromotionRepository) FindCoupons(promotionID uint) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := r.db.Where("promotion_id = ?", promotionID).Order("id").Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *GormPromotionRepository) CountRedemptions(couponID, userID uint) (int, error) {
+	var count int64
+	err := r.db.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&count).Error
+	return int(count), err
+}
+
+// redeemCoupons counts the coupons used by an order against their limits
+// within tx. Each coupon row is locked first so concurrent checkouts see
+// each other's redemptions.
+func redeemCoupons(tx *gorm.DB, order *models.Order) error {
+	for _, applied := range order.Promotions {
+		if applied.CouponID == nil {
+			continue
+		}
+		var coupon models.Coupon
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, *applied.CouponID).Error; err != nil {
+			return err
+		}
+		if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+			return ErrCouponUsedUp
+		}
+		if coupon.PerUserLimit > 0 {
+			var used int64
+			if err := tx.Model(&models.CouponRedemption{}).
+				Where("coupon_id = ? AND user_id = ?", coupon.ID, order.UserID).Count(&used).Error; err != nil {
+				return err
+			}
+			if int(used) >= coupon.PerUserLimit {
+				return ErrCouponLimitPerUser
+			}
+		}
+		if err := tx.Model(&coupon).Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.CouponRedemption{CouponID: coupon.ID, UserID: order.UserID, OrderID: order.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseCoupons gives back the coupon uses of an order within tx
+func releaseCoupons(tx *gorm.DB, orderID uint) error {
+	var redemptions []models.CouponRedemption
+	if err := tx.Where("order_id = ?", orderID).Find(&redemptions).Error; err != nil {
+		return err
+	}
+	for _, redemption := range redemptions {
+		if err := tx.Model(&models.Coupon{}).Where("id = ? AND used_count > 0", redemption.CouponID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("order_id = ?", orderID).Delete(&models.CouponRedemption{}).Error
+}
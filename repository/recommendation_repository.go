@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+type RecommendationRepository interface {
+	// FindSaleBaskets calls fn with the distinct products of every sale
+	// recorded since the given time, one call per checkout reference
+	FindSaleBaskets(since time.Time, fn func(productIDs []uint) error) error
+	// ReplaceAll swaps the stored recommendations for recs in one transaction
+	ReplaceAll(recs []models.ProductRecommendation) error
+	FindByProductID(productID uint, limit int) ([]models.ProductRecommendation, error)
+	// FindBestSellers returns visible products ordered by units sold since
+	// the given time. An empty category matches every product.
+	FindBestSellers(category string, since, now time.Time, exclude []uint, limit int) ([]models.Product, error)
+}
+
+type GormRecommendationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRecommendationRepository(db *gorm.DB) RecommendationRepository {
+	return &GormRecommendationRepository{db: db}
+}
+
+func (r *GormRecommendationRepository) FindSaleBaskets(since time.Time, fn func(productIDs []uint) error) error {
+	rows, err := r.db.Model(&models.StockMovement{}).
+		Select("reference, product_id").
+		Where("type = ? AND reference <> '' AND created_at >= ?", models.MovementSell, since).
+		Group("reference, product_id").
+		Order("reference").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current string
+	var basket []uint
+	for rows.Next() {
+		var reference string
+		var productID uint
+		if err := rows.Scan(&reference, &productID); err != nil {
+			return err
+		}
+		if reference != current && len(basket) > 0 {
+			if err := fn(basket); err != nil {
+				return err
+			}
+			basket = nil
+		}
+		current = reference
+		basket = append(basket, productID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(basket) > 0 {
+		return fn(basket)
+	}
+	return nil
+}
+
+func (r *GormRecommendationRepository) ReplaceAll(recs []models.ProductRecommendation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ProductRecommendation{}).Error; err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(recs, 500).Error
+	})
+}
+
+func (r *GormRecommendationRepository) FindByProductID(productID uint, limit int) ([]models.ProductRecommendation, error) {
+	var recs []models.ProductRecommendation
+	err := r.db.Where("product_id = ?", productID).Order("rank").Limit(limit).Find(&recs).Error
+	return recs, err
+}
+
+func (r *GormRecommendationRepository) FindBestSellers(category string, since, now time.Time, exclude []uint, limit int) ([]models.Product, error) {
+	query := r.db.Model(&models.Product{}).
+		Scopes(visibleAt(now)).
+		Select("products.*").
+		Joins("JOIN stock_movements ON stock_movements.product_id = products.id AND stock_movements.type = ? AND stock_movements.created_at >= ?",
+			models.MovementSell, since)
+	if category != "" {
+		query = query.Where("products.category = ?", category)
+	}
+	if len(exclude) > 0 {
+		query = query.Where("products.id NOT IN ?", exclude)
+	}
+
+	var products []models.Product
+	// 出貨數量在帳上是負數
+	err := query.Group("products.id").
+		Order("SUM(-stock_movements.quantity) DESC, products.id").
+		Limit(limit).
+		Find(&products).Error
+	return products, err
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefundExceedsCaptured = errors.New("refund exceeds the captured amount")
+	ErrRefundExceedsQuantity = errors.New("refund exceeds the quantity sold")
+	ErrAlreadyRestocked      = errors.New("items were already put back into stock")
+)
+
+type RefundRepository interface {
+	// Create records a pending refund and counts its amount and quantities
+	// as refunded on the payment, order and order lines. It fails with
+	// ErrRefundExceedsCaptured or ErrRefundExceedsQuantity instead of going
+	// over what was paid for.
+	Create(refund *models.Refund) error
+	// Complete marks a refund succeeded. A restocking refund books its units
+	// back into stock and counts them as restocked in the same transaction,
+	// failing with ErrAlreadyRestocked instead of restocking a unit twice.
+	Complete(refund *models.Refund) error
+	// Fail marks a refund failed and takes back what Create counted
+	Fail(refund *models.Refund) error
+	// FindByOrderID returns the refunds of an order, oldest first
+	FindByOrderID(orderID uint) ([]models.Refund, error)
+}
+
+type GormRefundRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRefundRepository(db *gorm.DB) RefundRepository {
+	return &GormRefundRepository{db: db}
+}
+
+func (r *GormRefundRepository) Create(refund *models.Refund) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		amount := refund.Amount.Amount
+		// 條件式更新，確保並行退款加總不會超過已請款金額
+		result := tx.Model(&models.Payment{}).
+			Where("id = ? AND refunded_amount_amount + ? <= amount_amount", refund.PaymentID, amount).
+			Update("refunded_amount_amount", gorm.Expr("refunded_amount_amount + ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefundExceedsCaptured
+		}
+
+		for _, item := range refund.Items {
+			result := tx.Model(&models.OrderItem{}).
+				Where("id = ? AND order_id = ? AND refunded_quantity + ? <= quantity", item.OrderItemID, refund.OrderID, item.Quantity).
+				Update("refunded_quantity", gorm.Expr("refunded_quantity + ?", item.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrRefundExceedsQuantity
+			}
+		}
+
+		if err := tx.Model(&models.Order{}).Where("id = ?", refund.OrderID).
+			Update("refunded_total_amount", gorm.Expr("refunded_total_amount + ?", amount)).Error; err != nil {
+			return err
+		}
+		return tx.Create(refund).Error
+	})
+}
+
+func (r *GormRefundRepository) Complete(refund *models.Refund) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		refund.Status = models.RefundStatusSucceeded
+		if err := tx.Model(refund).Select("status", "provider_ref", "restock", "updated_at").Updates(refund).Error; err != nil {
+			return err
+		}
+		if refund.Restock {
+			if err := restockRefund(tx, refund); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Payment{}).Where("id = ?", refund.PaymentID).
+			Update("status", gorm.Expr("CASE WHEN refunded_amount_amount >= amount_amount THEN ? ELSE ? END",
+				models.PaymentStatusRefunded, models.PaymentStatusPartiallyRefunded)).Error
+	})
+}
+
+// restockRefund books the units of a refund back into stock and counts
+// them as restocked on their order lines
+func restockRefund(tx *gorm.DB, refund *models.Refund) error {
+	var order models.Order
+	if err := tx.Select("id", "number").First(&order, refund.OrderID).Error; err != nil {
+		return err
+	}
+	// 鎖定訂單明細，避免與其他退款或訂單補貨重複補回庫存
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", refund.OrderID).Order("id").
+		Find(&[]models.OrderItem{}).Error; err != nil {
+		return err
+	}
+	for _, item := range refund.Items {
+		result := tx.Model(&models.OrderItem{}).
+			Where("id = ? AND restocked_quantity + ? <= quantity", item.OrderItemID, item.Quantity).
+			Update("restocked_quantity", gorm.Expr("restocked_quantity + ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyRestocked
+		}
+		if err := returnSold(tx, StockReturn{
+			Reference: order.Number,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			UserID:    refund.ActorID,
+			Note:      fmt.Sprintf("refund %d", refund.ID),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GormRefundRepository) Fail(refund *models.Refund) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		refund.Status = models.RefundStatusFailed
+		if err := tx.Model(refund).Select("status", "failure_reason", "updated_at").Updates(refund).Error; err != nil {
+			return err
+		}
+		amount := refund.Amount.Amount
+		if err := tx.Model(&models.Payment{}).Where("id = ?", refund.PaymentID).
+			Update("refunded_amount_amount", gorm.Expr("refunded_amount_amount - ?", amount)).Error; err != nil {
+			return err
+		}
+		for _, item := range refund.Items {
+			if err := tx.Model(&models.OrderItem{}).Where("id = ?", item.OrderItemID).
+				Update("refunded_quantity", gorm.Expr("refunded_quantity - ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Order{}).Where("id = ?", refund.OrderID).
+			Update("refunded_total_amount", gorm.Expr("refunded_total_amount - ?", amount)).Error
+	})
+}
+
+func (r *GormRefundRepository) FindByOrderID(orderID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.Preload("Items").Where("order_id = ?", orderID).Order("id").Find(&refunds).Error
+	return refunds, err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReturnChanged         = errors.New("return was changed by someone else")
+	ErrReturnExceedsQuantity = errors.New("return exceeds the quantity that can still be returned")
+)
+
+// openReturnStatuses are the statuses in which a return holds units of its
+// order lines
+var openReturnStatuses = []string{models.ReturnStatusRequested, models.ReturnStatusApproved, models.ReturnStatusReceived}
+
+type ReturnRepository interface {
+	// Create records a return and fails with ErrReturnExceedsQuantity when a
+	// line asks for more units than are neither refunded nor part of another
+	// open return of the order
+	Create(ret *models.Return) error
+	FindByID(id uint) (*models.Return, error)
+	// FindByOrderID returns the returns of an order, oldest first
+	FindByOrderID(orderID uint) ([]models.Return, error)
+	// FindByStatus returns returns in a status, or all of them for an empty
+	// status, oldest first
+	FindByStatus(status string, offset, limit int) ([]models.Return, error)
+	// Update saves a return and the outcomes of its lines if it is still in
+	// status from, and fails with ErrReturnChanged otherwise
+	Update(ret *models.Return, from string) error
+	// Receive saves an approved return as Update does and books the stock
+	// coming back with it, in one transaction
+	Receive(ret *models.Return, stock []StockReturn) error
+	AddPhoto(photo *models.ReturnPhoto) error
+	CountPhotos(returnItemID uint) (int, error)
+}
+
+type GormReturnRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReturnRepository(db *gorm.DB) ReturnRepository {
+	return &GormReturnRepository{db: db}
+}
+
+func (r *GormReturnRepository) Create(ret *models.Return) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 鎖定訂單明細，同一訂單的退貨申請與退款依序計算可退數量
+		var items []models.OrderItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ?", ret.OrderID).Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		var open []models.ReturnItem
+		if err := tx.Joins("JOIN returns ON returns.id = return_items.return_id").
+			Where("returns.order_id = ? AND returns.status IN ?", ret.OrderID, openReturnStatuses).
+			Find(&open).Error; err != nil {
+			return err
+		}
+		if err := checkReturnable(ret, items, open); err != nil {
+			return err
+		}
+		return tx.Create(ret).Error
+	})
+}
+
+// checkReturnable fails with ErrReturnExceedsQuantity when ret asks for more
+// units of a line than are neither refunded nor in the open return items
+func checkReturnable(ret *models.Return, items []models.OrderItem, open []models.ReturnItem) error {
+	left := make(map[uint]int, len(items))
+	for _, item := range items {
+		left[item.ID] = item.Quantity - item.RefundedQuantity
+	}
+	for _, item := range open {
+		left[item.OrderItemID] -= item.Quantity
+	}
+	for _, item := range ret.Items {
+		if left[item.OrderItemID] -= item.Quantity; left[item.OrderItemID] < 0 {
+			return ErrReturnExceedsQuantity
+		}
+	}
+	return nil
+}
+
+func (r *GormReturnRepository) FindByID(id uint) (*models.Return, error) {
+	var ret models.Return
+	if err := r.db.Preload("Items.Photos").First(&ret, id).Error; err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (r *GormReturnRepository) FindByOrderID(orderID uint) ([]models.Return, error) {
+	var returns []models.Return
+	err := r.db.Preload("Items.Photos").Where("order_id = ?", orderID).Order("id").Find(&returns).Error
+	return returns, err
+}
+
+func (r *GormReturnRepository) FindByStatus(status string, offset, limit int) ([]models.Return, error) {
+	var returns []models.Return
+	query := r.db.Preload("Items")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id").Offset(offset).Limit(limit).Find(&returns).Error
+	return returns, err
+}
+
+func (r *GormReturnRepository) Update(ret *models.Return, from string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return updateReturn(tx, ret, from)
+	})
+}
+
+func (r *GormReturnRepository) Receive(ret *models.Return, stock []StockReturn) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateReturn(tx, ret, models.ReturnStatusApproved); err != nil {
+			return err
+		}
+		for _, s := range stock {
+			if err := returnSold(tx, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func updateReturn(tx *gorm.DB, ret *models.Return, from string) error {
+	// 以狀態為條件更新，避免兩位員工同時處理同一筆退貨
+	result := tx.Model(ret).Where("status = ?", from).
+		Select("status", "resolution_note", "carrier", "tracking_number", "label_url", "refund_id",
+			"reviewed_by", "reviewed_at", "inspected_by", "inspected_at", "updated_at").
+		Updates(ret)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReturnChanged
+	}
+	for _, item := range ret.Items {
+		if err := tx.Model(&models.ReturnItem{}).Where("id = ?", item.ID).
+			Update("outcome", item.Outcome).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GormReturnRepository) AddPhoto(photo *models.ReturnPhoto) error {
+	return r.db.Create(photo).Error
+}
+
+func (r *GormReturnRepository) CountPhotos(returnItemID uint) (int, error) {
+	var count int64
+	err := r.db.Model(&models.ReturnPhoto{}).Where("return_item_id = ?", returnItemID).Count(&count).Error
+	return int(count), err
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrDuplicateVote = errors.New("already voted")
+
+type ReviewRepository interface {
+	Create(review *models.Review) error
+	FindByID(id uint) (*models.Review, error)
+	FindByProductAndUser(productID, userID uint) (*models.Review, error)
+	// FindByProductID returns approved reviews, sorted by "newest", "helpful" or "rating"
+	FindByProductID(productID uint, sort string, offset, limit int) ([]models.Review, error)
+	FindByStatus(status string, offset, limit int) ([]models.Review, error)
+	Update(review *models.Review) error
+	Delete(id uint) error
+	AddPhoto(photo *models.ReviewPhoto) error
+	CountPhotos(reviewID uint) (int, error)
+	AddVote(reviewID, userID uint) error
+}
+
+type GormReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewGormReviewRepository(db *gorm.DB) ReviewRepository {
+	return &GormReviewRepository{db: db}
+}
+
+// 每次寫入評論都在同一交易中重新計算商品評分，確保統計資料一致
+func (r *GormReviewRepository) Create(review *models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		return recalculateRating(tx, review.ProductID)
+	})
+}
+
+func (r *GormReviewRepository) FindByID(id uint) (*models.Review, error) {
+	var review models.Review
+	err := r.db.Preload("Photos").First(&review, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *GormReviewRepository) FindByProductAndUser(productID, userID uint) (*models.Review, error) {
+	var review models.Review
+	err := r.db.Where("product_id = ? AND user_id = ?", productID, userID).First(&review).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *GormReviewRepository) FindByProductID(productID uint, sort string, offset, limit int) ([]models.Review, error) {
+	order := "created_at DESC"
+	switch sort {
+	case "helpful":
+		order = "helpful_count DESC, created_at DESC"
+	case "rating":
+		order = "rating DESC, created_at DESC"
+	}
+
+	var reviews []models.Review
+	err := r.db.Preload("Photos").
+		Where("product_id = ? AND status = ?", productID, models.ReviewStatusApproved).
+		Order(order).Offset(offset).Limit(limit).
+		Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *GormReviewRepository) FindByStatus(status string, offset, limit int) ([]models.Review, error) {
+	var reviews []models.Review
+	err := r.db.Preload("Photos").
+		Where("status = ?", status).
+		Order("created_at").Offset(offset).Limit(limit).
+		Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *GormReviewRepository) Update(review *models.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Photos").Save(review).Error; err != nil {
+			return err
+		}
+		return recalculateRating(tx, review.ProductID)
+	})
+}
+
+func (r *GormReviewRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := tx.First(&review, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", id).Delete(&models.ReviewPhoto{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", id).Delete(&models.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return recalculateRating(tx, review.ProductID)
+	})
+}
+
+func (r *GormReviewRepository) AddPhoto(photo *models.ReviewPhoto) error {
+	return r.db.Create(photo).Error
+}
+
+func (r *GormReviewRepository) CountPhotos(reviewID uint) (int, error) {
+	var count int64
+	err := r.db.Model(&models.ReviewPhoto{}).Where("review_id = ?", reviewID).Count(&count).Error
+	return int(count), err
+}
+
+func (r *GormReviewRepository) AddVote(reviewID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ReviewVote{ReviewID: reviewID, UserID: userID, CreatedAt: time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDuplicateVote
+		}
+		return tx.Model(&models.Review{}).
+			Where("id = ?", reviewID).
+			UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+}
+
+// recalculateRating locks the product row and rebuilds its rating summary
+// from approved reviews
+func recalculateRating(tx *gorm.DB, productID uint) error {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").First(&product, productID).Error; err != nil {
+		return err
+	}
+
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	if err := tx.Model(&models.Review{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewStatusApproved).
+		Group("rating").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	var counts [5]int
+	for _, row := range rows {
+		if row.Rating >= 1 && row.Rating <= 5 {
+			counts[row.Rating-1] = row.Count
+		}
+	}
+	summary := models.NewRatingSummary(counts)
+	return tx.Model(&models.Product{ID: productID}).
+		Select("rating_average", "rating_count", "rating_star1", "rating_star2", "rating_star3", "rating_star4", "rating_star5").
+		UpdateColumns(&models.Product{Rating: summary}).Error
+}
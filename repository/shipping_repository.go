@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+type ShippingRepository interface {
+	CreateZone(zone *models.ShippingZone) error
+	UpdateZone(zone *models.ShippingZone) error
+	// DeleteZone removes a zone and its methods
+	DeleteZone(id uint) error
+	FindZoneByID(id uint) (*models.ShippingZone, error)
+	// FindZones returns every zone with its methods
+	FindZones() ([]models.ShippingZone, error)
+	CreateMethod(method *models.ShippingMethod) error
+	UpdateMethod(method *models.ShippingMethod) error
+	DeleteMethod(id uint) error
+	FindMethodByID(id uint) (*models.ShippingMethod, error)
+}
+
+type GormShippingRepository struct {
+	db *gorm.DB
+}
+
+func NewGormShippingRepository(db *gorm.DB) ShippingRepository {
+	return &GormShippingRepository{db: db}
+}
+
+func (r *GormShippingRepository) CreateZone(zone *models.ShippingZone) error {
+	return r.db.Omit("Methods").Create(zone).Error
+}
+
+func (r *GormShippingRepository) UpdateZone(zone *models.ShippingZone) error {
+	return r.db.Omit("Methods").Save(zone).Error
+}
+
+func (r *GormShippingRepository) DeleteZone(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("zone_id = ?", id).Delete(&models.ShippingMethod{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ShippingZone{}, id).Error
+	})
+}
+
+// withMethods loads the methods of zones
+func (r *GormShippingRepository) withMethods() *gorm.DB {
+	return r.db.Preload("Methods", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+func (r *GormShippingRepository) FindZoneByID(id uint) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	if err := r.withMethods().First(&zone, id).Error; err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func (r *GormShippingRepository) FindZones() ([]models.ShippingZone, error) {
+	var zones []models.ShippingZone
+	err := r.withMethods().Order("id").Find(&zones).Error
+	return zones, err
+}
+
+func (r *GormShippingRepository) CreateMethod(method *models.ShippingMethod) error {
+	return r.db.Create(method).Error
+}
+
+func (r *GormShippingRepository) UpdateMethod(method *models.ShippingMethod) error {
+	return r.db.Save(method).Error
+}
+
+func (r *GormShippingRepository) DeleteMethod(id uint) error {
+	return r.db.Delete(&models.ShippingMethod{}, id).Error
+}
+
+func (r *GormShippingRepository) FindMethodByID(id uint) (*models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	if err := r.db.First(&method, id).Error; err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
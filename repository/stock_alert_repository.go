@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockAlertRepository interface {
+	SetThreshold(threshold *models.ReorderThreshold) error
+	DeleteThreshold(productID uint) error
+	FindThreshold(productID uint) (*models.ReorderThreshold, error)
+	// OpenAlert records an alert unless the product already has an open one,
+	// reporting whether a new alert was created
+	OpenAlert(alert *models.StockAlert) (bool, error)
+	ResolveAlerts(productID uint, resolvedAt time.Time) error
+	FindAlerts(openOnly bool, offset, limit int) ([]models.StockAlert, error)
+	// Subscribe creates a subscription or re-arms one that was already notified
+	Subscribe(subscription *models.BackInStockSubscription) error
+	Unsubscribe(productID, userID uint) error
+	FindSubscriptionsByUser(userID uint) ([]models.BackInStockSubscription, error)
+	// ClaimSubscriptions marks the pending subscriptions of a product as
+	// notified and returns them, so each one is only emailed once
+	ClaimSubscriptions(productID uint, notifiedAt time.Time) ([]models.BackInStockSubscription, error)
+}
+
+type GormStockAlertRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStockAlertRepository(db *gorm.DB) StockAlertRepository {
+	return &GormStockAlertRepository{db: db}
+}
+
+func (r *GormStockAlertRepository) SetThreshold(threshold *models.ReorderThreshold) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"threshold", "updated_at"}),
+	}).Create(threshold).Error
+}
+
+func (r *GormStockAlertRepository) DeleteThreshold(productID uint) error {
+	return r.db.Delete(&models.ReorderThreshold{}, productID).Error
+}
+
+func (r *GormStockAlertRepository) FindThreshold(productID uint) (*models.ReorderThreshold, error) {
+	var threshold models.ReorderThreshold
+	err := r.db.First(&threshold, productID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+func (r *GormStockAlertRepository) OpenAlert(alert *models.StockAlert) (bool, error) {
+	// 部分唯一索引保證同一商品只有一筆未解除的警示
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "product_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "resolved_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(alert)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormStockAlertRepository) ResolveAlerts(productID uint, resolvedAt time.Time) error {
+	return r.db.Model(&models.StockAlert{}).
+		Where("product_id = ? AND resolved_at IS NULL", productID).
+		Update("resolved_at", resolvedAt).Error
+}
+
+func (r *GormStockAlertRepository) FindAlerts(openOnly bool, offset, limit int) ([]models.StockAlert, error) {
+	var alerts []models.StockAlert
+	query := r.db.Order("id DESC").Offset(offset).Limit(limit)
+	if openOnly {
+		query = query.Where("resolved_at IS NULL")
+	}
+	err := query.Find(&alerts).Error
+	return alerts, err
+}
+
+func (r *GormStockAlertRepository) Subscribe(subscription *models.BackInStockSubscription) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"notified_at": nil}),
+	}).Create(subscription).Error
+}
+
+func (r *GormStockAlertRepository) Unsubscribe(productID, userID uint) error {
+	return r.db.Where("product_id = ? AND user_id = ?", productID, userID).
+		Delete(&models.BackInStockSubscription{}).Error
+}
+
+func (r *GormStockAlertRepository) FindSubscriptionsByUser(userID uint) ([]models.BackInStockSubscription, error) {
+	var subscriptions []models.BackInStockSubscription
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *GormStockAlertRepository) ClaimSubscriptions(productID uint, notifiedAt time.Time) ([]models.BackInStockSubscription, error) {
+	// UPDATE ... RETURNING 讓同時補貨的兩個請求不會重複寄信
+	var subscriptions []models.BackInStockSubscription
+	err := r.db.Model(&subscriptions).
+		Clauses(clause.Returning{}).
+		Where("product_id = ? AND notified_at IS NULL", productID).
+		Update("notified_at", notifiedAt).Error
+	return subscriptions, err
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"e-commerce/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrSubscriptionChanged = errors.New("subscription was changed by someone else")
+
+type SubscriptionRepository interface {
+	CreatePlan(plan *models.SubscriptionPlan) error
+	UpdatePlan(plan *models.SubscriptionPlan) error
+	FindPlan(id uint) (*models.SubscriptionPlan, error)
+	// FindPlans returns the plans by ID, only the active ones when
+	// activeOnly is set
+	FindPlans(activeOnly bool) ([]models.SubscriptionPlan, error)
+
+	Create(sub *models.Subscription) error
+	FindByID(id uint) (*models.Subscription, error)
+	// FindByUserID returns the subscriptions of a user, oldest first
+	FindByUserID(userID uint) ([]models.Subscription, error)
+	// FindByStatus returns subscriptions in a status, or all of them for an
+	// empty status, oldest first
+	FindByStatus(status string, offset, limit int) ([]models.Subscription, error)
+	// FindDue returns active subscriptions due for renewal and past due ones
+	// due for another attempt at now, and renewals claimed before
+	// claimedBefore that were never finished
+	FindDue(now, claimedBefore time.Time, limit int) ([]models.Subscription, error)
+	// Update saves a subscription and bumps its version if nobody else
+	// updated it since it was read, and fails with ErrSubscriptionChanged
+	// otherwise
+	Update(sub *models.Subscription) error
+}
+
+type GormSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewGormSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &GormSubscriptionRepository{db: db}
+}
+
+func (r *GormSubscriptionRepository) CreatePlan(plan *models.SubscriptionPlan) error {
+	return r.db.Create(plan).Error
+}
+
+func (r *GormSubscriptionRepository) UpdatePlan(plan *models.SubscriptionPlan) error {
+	return r.db.Save(plan).Error
+}
+
+func (r *GormSubscriptionRepository) FindPlan(id uint) (*models.SubscriptionPlan, error) {
+	var plan models.SubscriptionPlan
+	if err := r.db.First(&plan, id).Error; err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *GormSubscriptionRepository) FindPlans(activeOnly bool) ([]models.SubscriptionPlan, error) {
+	var plans []models.SubscriptionPlan
+	query := r.db.Order("id")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Find(&plans).Error
+	return plans, err
+}
+
+func (r *GormSubscriptionRepository) Create(sub *models.Subscription) error {
+	return r.db.Create(sub).Error
+}
+
+func (r *GormSubscriptionRepository) FindByID(id uint) (*models.Subscription, error) {
+	var sub models.Subscription
+	if err := r.db.First(&sub, id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *GormSubscriptionRepository) FindByUserID(userID uint) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	err := r.db.Where("user_id = ?", userID).Order("id").Find(&subs).Error
+	return subs, err
+}
+
+func (r *GormSubscriptionRepository) FindByStatus(status string, offset, limit int) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	query := r.db.Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Offset(offset).Limit(limit).Find(&subs).Error
+	return subs, err
+}
+
+func (r *GormSubscriptionRepository) FindDue(now, claimedBefore time.Time, limit int) ([]models.Subscription, error) {
+	var subs []models.Subscription
+	err := r.db.Where("(status = ? AND next_renewal_at <= ?) OR (status = ? AND next_retry_at <= ?) OR (status = ? AND updated_at < ?)",
+		models.SubscriptionStatusActive, now, models.SubscriptionStatusPastDue, now,
+		models.SubscriptionStatusRenewing, claimedBefore).
+		Order("id").Limit(limit).Find(&subs).Error
+	return subs, err
+}
+
+func (r *GormSubscriptionRepository) Update(sub *models.Subscription) error {
+	// 以版本號為條件更新，避免續訂排程與顧客同時修改時互相覆蓋
+	version := sub.Version
+	sub.Version++
+	result := r.db.Model(sub).Where("version = ?", version).Select("*").Omit("id", "created_at").Updates(sub)
+	if result.Error != nil {
+		sub.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		sub.Version = version
+		return ErrSubscriptionChanged
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"errors"
+
+	"e-commerce/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrWishlistNameTaken = errors.New("you already have a wishlist with this name")
+
+type WishlistRepository interface {
+	// Create and Update fail with ErrWishlistNameTaken when the user already
+	// has a wishlist with the name
+	Create(wishlist *models.Wishlist) error
+	// FindByID returns a wishlist with its items, newest first
+	FindByID(id uint) (*models.Wishlist, error)
+	FindByUserID(userID uint) ([]models.Wishlist, error)
+	FindByShareToken(token string) (*models.Wishlist, error)
+	CountByUserID(userID uint) (int64, error)
+	Update(wishlist *models.Wishlist) error
+	// Delete removes a wishlist together with its items
+	Delete(id uint) error
+	// AddItem adds a product to a wishlist, leaving an existing entry as it was
+	AddItem(item *models.WishlistItem) error
+	FindItem(wishlistID, productID uint) (*models.WishlistItem, error)
+	RemoveItem(wishlistID, productID uint) error
+	// MoveItem moves an item to another wishlist, keeping when and at what
+	// price it was added. If the target already has the product the item is
+	// simply removed from the source.
+	MoveItem(fromID, toID, productID uint) error
+}
+
+type GormWishlistRepository struct {
+	db *gorm.DB
+}
+
+func NewGormWishlistRepository(db *gorm.DB) WishlistRepository {
+	return &GormWishlistRepository{db: db}
+}
+
+func preloadWishlistItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC, id DESC")
+	})
+}
+
+// nameTaken turns a violation of the per-user name index into
+// ErrWishlistNameTaken
+func nameTaken(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_wishlist_user_name" {
+		return ErrWishlistNameTaken
+	}
+	return err
+}
+
+func (r *GormWishlistRepository) Create(wishlist *models.Wishlist) error {
+	return nameTaken(r.db.Create(wishlist).Error)
+}
+
+func (r *GormWishlistRepository) FindByID(id uint) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := r.db.Scopes(preloadWishlistItems).First(&wishlist, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wishlist, nil
+}
+
+func (r *GormWishlistRepository) FindByUserID(userID uint) ([]models.Wishlist, error) {
+	var wishlists []models.Wishlist
+	err := r.db.Scopes(preloadWishlistItems).Where("user_id = ?", userID).Order("id").Find(&wishlists).Error
+	return wishlists, err
+}
+
+func (r *GormWishlistRepository) FindByShareToken(token string) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := r.db.Scopes(preloadWishlistItems).Where("share_token = ?", token).First(&wishlist).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wishlist, nil
+}
+
+func (r *GormWishlistRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Wishlist{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *GormWishlistRepository) Update(wishlist *models.Wishlist) error {
+	return nameTaken(r.db.Model(wishlist).Select("name", "share_token", "updated_at").Updates(wishlist).Error)
+}
+
+func (r *GormWishlistRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("wishlist_id = ?", id).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Wishlist{}, id).Error
+	})
+}
+
+func (r *GormWishlistRepository) AddItem(item *models.WishlistItem) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(item).Error
+}
+
+func (r *GormWishlistRepository) FindItem(wishlistID, productID uint) (*models.WishlistItem, error) {
+	var item models.WishlistItem
+	err := r.db.Where("wishlist_id = ? AND product_id = ?", wishlistID, productID).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *GormWishlistRepository) RemoveItem(wishlistID, productID uint) error {
+	result := r.db.Where("wishlist_id = ? AND product_id = ?", wishlistID, productID).Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *GormWishlistRepository) MoveItem(fromID, toID, productID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var item models.WishlistItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("wishlist_id = ? AND product_id = ?", fromID, productID).
+			First(&item).Error; err != nil {
+			return err
+		}
+
+		var exists int64
+		if err := tx.Model(&models.WishlistItem{}).
+			Where("wishlist_id = ? AND product_id = ?", toID, productID).
+			Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists > 0 {
+			return tx.Delete(&item).Error
+		}
+		return tx.Model(&item).Update("wishlist_id", toID).Error
+	})
+}
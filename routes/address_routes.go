@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupAddressRoutes(router *gin.Engine, addressController *controllers.AddressController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+	addresses := v1.Group("/auth/profile/addresses")
+	addresses.Use(authMiddleware.Handle())
+	{
+		addresses.GET("", addressController.List)
+		addresses.POST("", addressController.Create)
+		addresses.GET("/:id", addressController.Get)
+		addresses.PUT("/:id", addressController.Update)
+		addresses.DELETE("/:id", addressController.Delete)
+	}
+}
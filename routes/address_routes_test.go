@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	addressService := services.NewAddressService(repository.NewMockAddressRepository())
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupAddressRoutes(r, controllers.NewAddressController(addressService), middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Addresses", "GET", "/api/v1/auth/profile/addresses"},
+		{"Create Address", "POST", "/api/v1/auth/profile/addresses"},
+		{"Get Address", "GET", "/api/v1/auth/profile/addresses/1"},
+		{"Update Address", "PUT", "/api/v1/auth/profile/addresses/1"},
+		{"Delete Address", "DELETE", "/api/v1/auth/profile/addresses/1"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
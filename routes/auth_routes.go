@@ -3,6 +3,7 @@ package routes
 import (
 	"e-commerce/controllers"
 	"e-commerce/middlewares"
+	"e-commerce/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,6 +23,13 @@ func SetupAuthRoutes(router *gin.Engine, authController *controllers.AuthControl
 			protected.POST("/logout", authController.Logout)
 			protected.GET("/profile", authController.GetProfile)
 			protected.PUT("/profile", authController.UpdateProfile)
+			protected.PUT("/profile/currency", authController.UpdateCurrency)
 		}
 	}
+
+	admin := v1.Group("/admin/users")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.PUT("/:id/customer-group", authController.SetCustomerGroup)
+	}
 }
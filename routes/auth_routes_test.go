@@ -19,7 +19,7 @@ func TestAuthRoutes(t *testing.T) {
 
 	// 創建必要的依賴
 	mockUserRepo := repository.NewMockUserRepository()
-	authService := services.NewAuthService(mockUserRepo)
+	authService := services.NewAuthService(mockUserRepo, nil)
 	authController := controllers.NewAuthController(authService)
 	authMiddleware := middlewares.NewAuthMiddleware(nil, authService)
 
@@ -38,6 +38,8 @@ func TestAuthRoutes(t *testing.T) {
 		{"Logout", "POST", "/api/v1/auth/logout", "/api/v1/auth/logout"},
 		{"Get Profile", "GET", "/api/v1/auth/profile", "/api/v1/auth/profile"},
 		{"Update Profile", "PUT", "/api/v1/auth/profile", "/api/v1/auth/profile"},
+		{"Update Currency", "PUT", "/api/v1/auth/profile/currency", "/api/v1/auth/profile/currency"},
+		{"Set Customer Group", "PUT", "/api/v1/admin/users/1/customer-group", "/api/v1/admin/users/1/customer-group"},
 	}
 
 	for _, route := range routes {
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupCartRecoveryRoutes(router *gin.Engine, cartRecoveryController *controllers.CartRecoveryController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Public routes, reached from the link in each reminder
+	v1.GET("/cart-reminders/unsubscribe", cartRecoveryController.Unsubscribe)
+	v1.POST("/cart-reminders/unsubscribe", cartRecoveryController.Unsubscribe)
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.GET("/cart-reminders", cartRecoveryController.Settings)
+		protected.PUT("/cart-reminders", cartRecoveryController.UpdateSettings)
+	}
+
+	admin := v1.Group("/admin/cart-recoveries")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("", cartRecoveryController.List)
+		admin.GET("/stats", cartRecoveryController.Stats)
+	}
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartRecoveryRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	cartRepo := repository.NewMockCartRepository()
+	userRepo := repository.NewMockUserRepository()
+	cartRecoveryService := services.NewCartRecoveryService(repository.NewMockCartRecoveryRepository(cartRepo), cartRepo,
+		repository.NewMockProductRepository(), userRepo, nil, mailer.NewMemoryMailer())
+	authService := services.NewAuthService(userRepo, nil)
+
+	SetupCartRecoveryRoutes(r,
+		controllers.NewCartRecoveryController(cartRecoveryService),
+		middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Unsubscribe Link", "GET", "/api/v1/cart-reminders/unsubscribe"},
+		{"Unsubscribe", "POST", "/api/v1/cart-reminders/unsubscribe"},
+		{"Get Reminder Settings", "GET", "/api/v1/cart-reminders"},
+		{"Update Reminder Settings", "PUT", "/api/v1/cart-reminders"},
+		{"List Cart Recoveries", "GET", "/api/v1/admin/cart-recoveries"},
+		{"Cart Recovery Stats", "GET", "/api/v1/admin/cart-recoveries/stats"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupCartRoutes(router *gin.Engine, cartController *controllers.CartController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// 訪客以 X-Cart-Token 識別購物車，登入者使用自己的購物車
+	cart := v1.Group("/cart")
+	cart.Use(authMiddleware.Optional())
+	{
+		cart.DELETE("", cartController.Clear)
+
+		priced := cart.Group("")
+		priced.Use(pricingMiddleware.Handle())
+		{
+			priced.GET("", cartController.Get)
+			priced.POST("/items", cartController.AddItem)
+			priced.PUT("/items/:productId", cartController.UpdateItem)
+			priced.DELETE("/items/:productId", cartController.RemoveItem)
+			priced.PUT("/coupon", cartController.ApplyCoupon)
+			priced.DELETE("/coupon", cartController.RemoveCoupon)
+		}
+	}
+}
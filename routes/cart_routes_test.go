@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	inventoryService := services.NewInventoryService(repository.NewMockInventoryRepository(), productRepo, nil)
+	cartService := services.NewCartService(repository.NewMockCartRepository(), productRepo, inventoryService, pricingService, nil)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), cartService)
+
+	SetupCartRoutes(r,
+		controllers.NewCartController(cartService),
+		middlewares.NewAuthMiddleware(nil, authService),
+		middlewares.NewPricingMiddleware(pricingService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Get Cart", "GET", "/api/v1/cart"},
+		{"Clear Cart", "DELETE", "/api/v1/cart"},
+		{"Add Item", "POST", "/api/v1/cart/items"},
+		{"Update Item", "PUT", "/api/v1/cart/items/1"},
+		{"Remove Item", "DELETE", "/api/v1/cart/items/1"},
+		{"Apply Coupon", "PUT", "/api/v1/cart/coupon"},
+		{"Remove Coupon", "DELETE", "/api/v1/cart/coupon"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
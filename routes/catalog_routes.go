@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupCatalogRoutes(router *gin.Engine, catalogController *controllers.CatalogController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+	catalog := v1.Group("/catalog")
+	catalog.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		catalog.POST("/imports", catalogController.StartImport)
+		catalog.GET("/imports/:id", catalogController.GetImport)
+		catalog.GET("/imports/:id/errors", catalogController.DownloadErrors)
+		catalog.GET("/export", catalogController.Export)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupInventoryRoutes(router *gin.Engine, inventoryController *controllers.InventoryController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+	inventory := v1.Group("/inventory")
+	inventory.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		inventory.GET("/warehouses", inventoryController.ListWarehouses)
+		inventory.POST("/warehouses", inventoryController.CreateWarehouse)
+		inventory.GET("/products/:id", inventoryController.StockLevels)
+		inventory.GET("/products/:id/movements", inventoryController.Movements)
+		inventory.POST("/movements", inventoryController.RecordMovement)
+	}
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupInvoiceRoutes(router *gin.Engine, invoiceController *controllers.InvoiceController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.GET("/orders/:id/invoices", invoiceController.List)
+		protected.GET("/invoices/:id", invoiceController.Get)
+		protected.GET("/invoices/:id/pdf", invoiceController.Download)
+	}
+
+	admin := v1.Group("/admin/orders")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.POST("/:id/invoices", invoiceController.Issue)
+	}
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/einvoice"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/pdf"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, repository.NewMockCartRepository(), repository.NewMockPromotionRepository())
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, nil)
+	orderService := services.NewOrderService(orderRepo, inventoryService, mailer.NewMemoryMailer())
+	templates, err := pdf.NewTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoiceService := services.NewInvoiceService(repository.NewMockInvoiceRepository(), orderService, templates, einvoice.NewFakeIssuer())
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupInvoiceRoutes(r,
+		controllers.NewInvoiceController(invoiceService),
+		middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Invoices", "GET", "/api/v1/orders/1/invoices"},
+		{"Get Invoice", "GET", "/api/v1/invoices/1"},
+		{"Download Invoice", "GET", "/api/v1/invoices/1/pdf"},
+		{"Issue Invoice", "POST", "/api/v1/admin/orders/1/invoices"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupOrderRoutes(router *gin.Engine, orderController *controllers.OrderController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.POST("/checkout", pricingMiddleware.Handle(), orderController.Checkout)
+		protected.GET("/orders", orderController.List)
+		protected.GET("/orders/:id", orderController.Get)
+		protected.POST("/orders/:id/cancel", orderController.Cancel)
+		protected.GET("/orders/:id/history", orderController.History)
+	}
+
+	admin := v1.Group("/admin/orders")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("", orderController.ListAll)
+		admin.GET("/:id", orderController.GetAny)
+		admin.PUT("/:id/status", orderController.ChangeStatus)
+	}
+}
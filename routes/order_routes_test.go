@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	cartRepo := repository.NewMockCartRepository()
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, cartRepo, repository.NewMockPromotionRepository())
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, nil)
+	cartService := services.NewCartService(cartRepo, productRepo, inventoryService, pricingService, nil)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), cartService)
+	paymentRepo := repository.NewMockPaymentRepository()
+	provider := payments.NewFakeProvider("")
+	mail := mailer.NewMemoryMailer()
+	orderService := services.NewOrderService(orderRepo, inventoryService, mail)
+	cancellationService := services.NewCancellationService(orderService,
+		services.NewPaymentService(paymentRepo, orderService, provider, nil),
+		services.NewRefundService(repository.NewMockRefundRepository(paymentRepo, orderRepo), paymentRepo, orderService, inventoryService, provider, mail, nil))
+
+	SetupOrderRoutes(r,
+		controllers.NewOrderController(services.NewCheckoutService(orderRepo, cartService, nil, nil, nil, nil, nil), orderService, cancellationService),
+		middlewares.NewAuthMiddleware(nil, authService),
+		middlewares.NewPricingMiddleware(pricingService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Checkout", "POST", "/api/v1/checkout"},
+		{"List Orders", "GET", "/api/v1/orders"},
+		{"Get Order", "GET", "/api/v1/orders/1"},
+		{"Cancel Order", "POST", "/api/v1/orders/1/cancel"},
+		{"Order History", "GET", "/api/v1/orders/1/history"},
+		{"List All Orders", "GET", "/api/v1/admin/orders"},
+		{"Get Any Order", "GET", "/api/v1/admin/orders/1"},
+		{"Change Order Status", "PUT", "/api/v1/admin/orders/1/status"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
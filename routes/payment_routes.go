@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupPaymentRoutes(router *gin.Engine, paymentController *controllers.PaymentController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+	v1.POST("/payments/webhook", paymentController.Webhook)
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.POST("/orders/:id/payments", paymentController.Pay)
+		protected.GET("/orders/:id/payments", paymentController.List)
+		protected.POST("/payments/:id/simulate-3ds", paymentController.SimulateChallenge)
+		protected.GET("/payment-methods", paymentController.ListMethods)
+		protected.POST("/payment-methods", paymentController.SaveMethod)
+		protected.DELETE("/payment-methods/:id", paymentController.DeleteMethod)
+	}
+}
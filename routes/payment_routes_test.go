@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, repository.NewMockCartRepository(), repository.NewMockPromotionRepository())
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, nil)
+	orderService := services.NewOrderService(orderRepo, inventoryService, mailer.NewMemoryMailer())
+	paymentService := services.NewPaymentService(repository.NewMockPaymentRepository(), orderService, payments.NewFakeProvider(""), nil)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupPaymentRoutes(r,
+		controllers.NewPaymentController(paymentService),
+		middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Webhook", "POST", "/api/v1/payments/webhook"},
+		{"Pay", "POST", "/api/v1/orders/1/payments"},
+		{"List Payments", "GET", "/api/v1/orders/1/payments"},
+		{"Simulate 3DS", "POST", "/api/v1/payments/1/simulate-3ds"},
+		{"List Payment Methods", "GET", "/api/v1/payment-methods"},
+		{"Save Payment Method", "POST", "/api/v1/payment-methods"},
+		{"Delete Payment Method", "DELETE", "/api/v1/payment-methods/1"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
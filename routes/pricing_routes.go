@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupPricingRoutes(router *gin.Engine, priceListController *controllers.PriceListController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+	priceLists := v1.Group("/price-lists")
+	priceLists.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		priceLists.GET("", priceListController.List)
+		priceLists.POST("", priceListController.Create)
+		priceLists.PUT("/:id", priceListController.Update)
+		priceLists.PUT("/:id/prices/:productId", priceListController.SetPrice)
+		priceLists.DELETE("/:id/prices/:productId", priceListController.DeletePrice)
+	}
+}
@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupProductRoutes(router *gin.Engine, productController *controllers.ProductController, mediaController *controllers.MediaController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+	v1.GET("/media/*key", mediaController.Serve)
+
+	products := v1.Group("/products")
+	{
+		// Public routes priced per request currency
+		priced := products.Group("")
+		priced.Use(authMiddleware.Optional(), pricingMiddleware.Handle())
+		{
+			priced.GET("", productController.List)
+			priced.GET("/:id", productController.Get)
+		}
+		products.GET("/:id/images", authMiddleware.Optional(), mediaController.List)
+
+		// Staff only routes
+		staff := products.Group("")
+		staff.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+		{
+			staff.POST("", productController.Create)
+			staff.PUT("/:id", productController.Update)
+			staff.DELETE("/:id", productController.Delete)
+			staff.PUT("/:id/status", productController.ChangeStatus)
+			staff.POST("/:id/preview-token", productController.CreatePreviewToken)
+			staff.GET("/:id/revisions", productController.ListRevisions)
+			staff.GET("/:id/revisions/diff", productController.DiffRevisions)
+			staff.GET("/:id/revisions/:version", productController.GetRevision)
+			staff.POST("/:id/revisions/:version/rollback", productController.Rollback)
+			staff.POST("/:id/images", mediaController.Upload)
+			staff.PUT("/:id/images/order", mediaController.Reorder)
+			staff.DELETE("/:id/images/:imageId", mediaController.Delete)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"e-commerce/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	// 創建必要的依賴
+	productRepo := repository.NewMockProductRepository()
+	imageRepo := repository.NewMockProductImageRepository()
+	store, err := storage.NewLocalStorage(t.TempDir(), "/api/v1/media", "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	productService := services.NewProductService(productRepo, repository.NewMockProductRevisionRepository(productRepo))
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	mediaService := services.NewMediaService(imageRepo, productRepo, store)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+	authMiddleware := middlewares.NewAuthMiddleware(nil, authService)
+
+	SetupProductRoutes(r,
+		controllers.NewProductController(productService, mediaService, pricingService),
+		controllers.NewMediaController(mediaService, productService),
+		authMiddleware,
+		middlewares.NewPricingMiddleware(pricingService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Products", "GET", "/api/v1/products"},
+		{"Create Product", "POST", "/api/v1/products"},
+		{"Update Product", "PUT", "/api/v1/products/1"},
+		{"Delete Product", "DELETE", "/api/v1/products/1"},
+		{"Change Status", "PUT", "/api/v1/products/1/status"},
+		{"Create Preview Token", "POST", "/api/v1/products/1/preview-token"},
+		{"List Revisions", "GET", "/api/v1/products/1/revisions"},
+		{"Diff Revisions", "GET", "/api/v1/products/1/revisions/diff"},
+		{"Get Revision", "GET", "/api/v1/products/1/revisions/1"},
+		{"Rollback", "POST", "/api/v1/products/1/revisions/1/rollback"},
+		{"Upload Images", "POST", "/api/v1/products/1/images"},
+		{"Reorder Images", "PUT", "/api/v1/products/1/images/order"},
+		{"Delete Image", "DELETE", "/api/v1/products/1/images/1"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupPromotionRoutes(router *gin.Engine, promotionController *controllers.PromotionController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	admin := v1.Group("/admin/promotions")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("", promotionController.List)
+		admin.POST("", promotionController.Create)
+		admin.GET("/:id", promotionController.Get)
+		admin.PUT("/:id", promotionController.Update)
+		admin.DELETE("/:id", promotionController.Delete)
+		admin.GET("/:id/coupons", promotionController.ListCoupons)
+		admin.POST("/:id/coupons", promotionController.CreateCoupon)
+	}
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromotionRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	userRepo := repository.NewMockUserRepository()
+	promotionService := services.NewPromotionService(repository.NewMockPromotionRepository(), userRepo)
+	authService := services.NewAuthService(userRepo, nil)
+
+	SetupPromotionRoutes(r,
+		controllers.NewPromotionController(promotionService),
+		middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Promotions", "GET", "/api/v1/admin/promotions"},
+		{"Create Promotion", "POST", "/api/v1/admin/promotions"},
+		{"Get Promotion", "GET", "/api/v1/admin/promotions/1"},
+		{"Update Promotion", "PUT", "/api/v1/admin/promotions/1"},
+		{"Delete Promotion", "DELETE", "/api/v1/admin/promotions/1"},
+		{"List Coupons", "GET", "/api/v1/admin/promotions/1/coupons"},
+		{"Create Coupon", "POST", "/api/v1/admin/promotions/1/coupons"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
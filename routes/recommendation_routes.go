@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRecommendationRoutes(router *gin.Engine, recommendationController *controllers.RecommendationController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Public routes priced per request currency
+	priced := v1.Group("")
+	priced.Use(authMiddleware.Optional(), pricingMiddleware.Handle())
+	{
+		priced.GET("/products/:id/recommendations", recommendationController.List)
+	}
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	recommendationRepo := repository.NewMockRecommendationRepository(repository.NewMockInventoryRepository(), productRepo)
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupRecommendationRoutes(r,
+		controllers.NewRecommendationController(services.NewRecommendationService(recommendationRepo, productRepo), pricingService),
+		middlewares.NewAuthMiddleware(nil, authService),
+		middlewares.NewPricingMiddleware(pricingService))
+
+	req := httptest.NewRequest("GET", "/api/v1/products/1/recommendations", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.NotEqual(t, http.StatusNotFound, resp.Code,
+		"Route %s should exist but got 404", req.URL.Path)
+}
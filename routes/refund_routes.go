@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRefundRoutes(router *gin.Engine, refundController *controllers.RefundController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.GET("/orders/:id/refunds", refundController.List)
+	}
+
+	admin := v1.Group("/admin/orders")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.POST("/:id/refunds", refundController.Create)
+	}
+}
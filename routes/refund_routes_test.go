@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefundRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, repository.NewMockCartRepository(), repository.NewMockPromotionRepository())
+	paymentRepo := repository.NewMockPaymentRepository()
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, nil)
+	mail := mailer.NewMemoryMailer()
+	orderService := services.NewOrderService(orderRepo, inventoryService, mail)
+	refundService := services.NewRefundService(repository.NewMockRefundRepository(paymentRepo, orderRepo), paymentRepo, orderService, inventoryService, payments.NewFakeProvider(""), mail, nil)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupRefundRoutes(r,
+		controllers.NewRefundController(refundService),
+		middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Refunds", "GET", "/api/v1/orders/1/refunds"},
+		{"Create Refund", "POST", "/api/v1/admin/orders/1/refunds"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupReturnRoutes(router *gin.Engine, returnController *controllers.ReturnController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.POST("/orders/:id/returns", returnController.Request)
+		protected.GET("/orders/:id/returns", returnController.ListForOrder)
+		protected.GET("/returns/:id", returnController.Get)
+		protected.POST("/returns/:id/items/:itemId/photos", returnController.AddPhoto)
+	}
+
+	admin := v1.Group("/admin/returns")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("", returnController.List)
+		admin.POST("/:id/approve", returnController.Approve)
+		admin.POST("/:id/reject", returnController.Reject)
+		admin.POST("/:id/inspect", returnController.Inspect)
+	}
+}
@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"e-commerce/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReturnRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, repository.NewMockCartRepository(), repository.NewMockPromotionRepository())
+	paymentRepo := repository.NewMockPaymentRepository()
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, nil)
+	mail := mailer.NewMemoryMailer()
+	orderService := services.NewOrderService(orderRepo, inventoryService, mail)
+	refundService := services.NewRefundService(repository.NewMockRefundRepository(paymentRepo, orderRepo), paymentRepo, orderService, inventoryService, payments.NewFakeProvider(""), mail, nil)
+	store, err := storage.NewLocalStorage(t.TempDir(), "/api/v1/media", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	returnService := services.NewReturnService(repository.NewMockReturnRepository(orderRepo, inventoryRepo), productRepo, orderService, inventoryService, refundService, nil, store, mail)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupReturnRoutes(r,
+		controllers.NewReturnController(returnService),
+		middlewares.NewAuthMiddleware(nil, authService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Request Return", "POST", "/api/v1/orders/1/returns"},
+		{"List Order Returns", "GET", "/api/v1/orders/1/returns"},
+		{"Get Return", "GET", "/api/v1/returns/1"},
+		{"Add Return Photo", "POST", "/api/v1/returns/1/items/1/photos"},
+		{"List Returns", "GET", "/api/v1/admin/returns"},
+		{"Approve Return", "POST", "/api/v1/admin/returns/1/approve"},
+		{"Reject Return", "POST", "/api/v1/admin/returns/1/reject"},
+		{"Inspect Return", "POST", "/api/v1/admin/returns/1/inspect"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
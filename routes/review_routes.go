@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupReviewRoutes(router *gin.Engine, reviewController *controllers.ReviewController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+	v1.GET("/products/:id/reviews", reviewController.ListForProduct)
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.POST("/products/:id/reviews", reviewController.Create)
+		protected.PUT("/reviews/:id", reviewController.Update)
+		protected.DELETE("/reviews/:id", reviewController.Delete)
+		protected.POST("/reviews/:id/photos", reviewController.AddPhoto)
+		protected.POST("/reviews/:id/helpful", reviewController.Vote)
+	}
+
+	admin := v1.Group("/admin/reviews")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("", reviewController.ModerationQueue)
+		admin.PUT("/:id/approve", reviewController.Approve)
+		admin.PUT("/:id/reject", reviewController.Reject)
+	}
+}
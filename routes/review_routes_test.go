@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"e-commerce/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	// 評論路由與商品路由共用 /products/:id 前綴，需確認可同時註冊
+	productRepo := repository.NewMockProductRepository()
+	store, err := storage.NewLocalStorage(t.TempDir(), "/api/v1/media", "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	productService := services.NewProductService(productRepo, repository.NewMockProductRevisionRepository(productRepo))
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	mediaService := services.NewMediaService(repository.NewMockProductImageRepository(), productRepo, store)
+	reviewService := services.NewReviewService(repository.NewMockReviewRepository(productRepo), productRepo, services.NewNoPurchaseVerifier(), store)
+	authMiddleware := middlewares.NewAuthMiddleware(nil, services.NewAuthService(repository.NewMockUserRepository(), nil))
+
+	SetupProductRoutes(r, controllers.NewProductController(productService, mediaService, pricingService), controllers.NewMediaController(mediaService, productService), authMiddleware, middlewares.NewPricingMiddleware(pricingService))
+	SetupReviewRoutes(r, controllers.NewReviewController(reviewService), authMiddleware)
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Create Review", "POST", "/api/v1/products/1/reviews"},
+		{"Update Review", "PUT", "/api/v1/reviews/1"},
+		{"Delete Review", "DELETE", "/api/v1/reviews/1"},
+		{"Add Photo", "POST", "/api/v1/reviews/1/photos"},
+		{"Vote Helpful", "POST", "/api/v1/reviews/1/helpful"},
+		{"Moderation Queue", "GET", "/api/v1/admin/reviews"},
+		{"Approve Review", "PUT", "/api/v1/admin/reviews/1/approve"},
+		{"Reject Review", "PUT", "/api/v1/admin/reviews/1/reject"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupShippingRoutes(router *gin.Engine, shippingController *controllers.ShippingController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// 報價依購物車計算，訪客以 X-Cart-Token 識別
+	v1.GET("/shipping/quote", authMiddleware.Optional(), pricingMiddleware.Handle(), shippingController.Quote)
+
+	admin := v1.Group("/admin/shipping")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("/zones", shippingController.ListZones)
+		admin.POST("/zones", shippingController.CreateZone)
+		admin.GET("/zones/:id", shippingController.GetZone)
+		admin.PUT("/zones/:id", shippingController.UpdateZone)
+		admin.DELETE("/zones/:id", shippingController.DeleteZone)
+		admin.POST("/zones/:id/methods", shippingController.CreateMethod)
+		admin.PUT("/methods/:id", shippingController.UpdateMethod)
+		admin.DELETE("/methods/:id", shippingController.DeleteMethod)
+	}
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShippingRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	inventoryService := services.NewInventoryService(repository.NewMockInventoryRepository(), productRepo, nil)
+	cartService := services.NewCartService(repository.NewMockCartRepository(), productRepo, inventoryService, pricingService, nil)
+	shippingService := services.NewShippingService(repository.NewMockShippingRepository(), nil)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), cartService)
+
+	SetupShippingRoutes(r,
+		controllers.NewShippingController(shippingService, cartService),
+		middlewares.NewAuthMiddleware(nil, authService),
+		middlewares.NewPricingMiddleware(pricingService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Quote", "GET", "/api/v1/shipping/quote?country=TW"},
+		{"List Zones", "GET", "/api/v1/admin/shipping/zones"},
+		{"Create Zone", "POST", "/api/v1/admin/shipping/zones"},
+		{"Get Zone", "GET", "/api/v1/admin/shipping/zones/1"},
+		{"Update Zone", "PUT", "/api/v1/admin/shipping/zones/1"},
+		{"Delete Zone", "DELETE", "/api/v1/admin/shipping/zones/1"},
+		{"Create Method", "POST", "/api/v1/admin/shipping/zones/1/methods"},
+		{"Update Method", "PUT", "/api/v1/admin/shipping/methods/1"},
+		{"Delete Method", "DELETE", "/api/v1/admin/shipping/methods/1"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
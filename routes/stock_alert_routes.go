@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupStockAlertRoutes(router *gin.Engine, stockAlertController *controllers.StockAlertController, authMiddleware *middlewares.AuthMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.POST("/products/:id/stock-subscription", stockAlertController.Subscribe)
+		protected.DELETE("/products/:id/stock-subscription", stockAlertController.Unsubscribe)
+		protected.GET("/stock-subscriptions", stockAlertController.ListSubscriptions)
+	}
+
+	staff := v1.Group("/inventory")
+	staff.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		staff.GET("/alerts", stockAlertController.ListAlerts)
+		staff.PUT("/products/:id/threshold", stockAlertController.SetThreshold)
+		staff.DELETE("/products/:id/threshold", stockAlertController.DeleteThreshold)
+	}
+}
@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockAlertRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	userRepo := repository.NewMockUserRepository()
+	stockAlertService := services.NewStockAlertService(repository.NewMockStockAlertRepository(), inventoryRepo, productRepo, userRepo, mailer.NewMemoryMailer())
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, stockAlertService)
+	authMiddleware := middlewares.NewAuthMiddleware(nil, services.NewAuthService(userRepo, nil))
+
+	// 與庫存路由共用 /inventory/products/:id 前綴
+	SetupInventoryRoutes(r, controllers.NewInventoryController(inventoryService), authMiddleware)
+	SetupStockAlertRoutes(r, controllers.NewStockAlertController(stockAlertService), authMiddleware)
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Stock Levels", "GET", "/api/v1/inventory/products/1"},
+		{"Record Movement", "POST", "/api/v1/inventory/movements"},
+		{"List Alerts", "GET", "/api/v1/inventory/alerts"},
+		{"Set Threshold", "PUT", "/api/v1/inventory/products/1/threshold"},
+		{"Delete Threshold", "DELETE", "/api/v1/inventory/products/1/threshold"},
+		{"Subscribe", "POST", "/api/v1/products/1/stock-subscription"},
+		{"Unsubscribe", "DELETE", "/api/v1/products/1/stock-subscription"},
+		{"List Subscriptions", "GET", "/api/v1/stock-subscriptions"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupSubscriptionRoutes(router *gin.Engine, subscriptionController *controllers.SubscriptionController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+
+	// Public routes
+	v1.GET("/subscription-plans", subscriptionController.Plans)
+
+	// Protected routes
+	protected := v1.Group("")
+	protected.Use(authMiddleware.Handle())
+	{
+		protected.POST("/subscriptions", pricingMiddleware.Handle(), subscriptionController.Subscribe)
+		protected.GET("/subscriptions", subscriptionController.List)
+		protected.GET("/subscriptions/:id", subscriptionController.Get)
+		protected.PUT("/subscriptions/:id", subscriptionController.Change)
+		protected.POST("/subscriptions/:id/skip", subscriptionController.Skip)
+		protected.POST("/subscriptions/:id/pause", subscriptionController.Pause)
+		protected.POST("/subscriptions/:id/resume", subscriptionController.Resume)
+		protected.POST("/subscriptions/:id/cancel", subscriptionController.Cancel)
+	}
+
+	admin := v1.Group("/admin")
+	admin.Use(authMiddleware.Handle(), authMiddleware.RequireRole(models.RoleStaff, models.RoleAdmin))
+	{
+		admin.GET("/subscription-plans", subscriptionController.AllPlans)
+		admin.POST("/subscription-plans", subscriptionController.CreatePlan)
+		admin.PUT("/subscription-plans/:id", subscriptionController.UpdatePlan)
+		admin.GET("/subscriptions", subscriptionController.ListAll)
+	}
+}
@@ -0,0 +1,72 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/mailer"
+	"e-commerce/middlewares"
+	"e-commerce/payments"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	inventoryRepo := repository.NewMockInventoryRepository()
+	cartRepo := repository.NewMockCartRepository()
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, cartRepo, repository.NewMockPromotionRepository())
+	userRepo := repository.NewMockUserRepository()
+	pricingService := services.NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	inventoryService := services.NewInventoryService(inventoryRepo, productRepo, nil)
+	cartService := services.NewCartService(cartRepo, productRepo, inventoryService, pricingService, nil)
+	authService := services.NewAuthService(userRepo, cartService)
+	mail := mailer.NewMemoryMailer()
+	orderService := services.NewOrderService(orderRepo, inventoryService, mail)
+	paymentService := services.NewPaymentService(repository.NewMockPaymentRepository(), orderService, payments.NewFakeProvider(""), nil)
+	subscriptionService := services.NewSubscriptionService(repository.NewMockSubscriptionRepository(), productRepo, userRepo, pricingService,
+		services.NewCheckoutService(orderRepo, cartService, nil, nil, nil, nil, nil), orderService, paymentService, mail)
+
+	SetupSubscriptionRoutes(r,
+		controllers.NewSubscriptionController(subscriptionService),
+		middlewares.NewAuthMiddleware(nil, authService),
+		middlewares.NewPricingMiddleware(pricingService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Plans", "GET", "/api/v1/subscription-plans"},
+		{"Subscribe", "POST", "/api/v1/subscriptions"},
+		{"List Subscriptions", "GET", "/api/v1/subscriptions"},
+		{"Get Subscription", "GET", "/api/v1/subscriptions/1"},
+		{"Change Subscription", "PUT", "/api/v1/subscriptions/1"},
+		{"Skip Delivery", "POST", "/api/v1/subscriptions/1/skip"},
+		{"Pause Subscription", "POST", "/api/v1/subscriptions/1/pause"},
+		{"Resume Subscription", "POST", "/api/v1/subscriptions/1/resume"},
+		{"Cancel Subscription", "POST", "/api/v1/subscriptions/1/cancel"},
+		{"List All Plans", "GET", "/api/v1/admin/subscription-plans"},
+		{"Create Plan", "POST", "/api/v1/admin/subscription-plans"},
+		{"Update Plan", "PUT", "/api/v1/admin/subscription-plans/1"},
+		{"List All Subscriptions", "GET", "/api/v1/admin/subscriptions"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+}
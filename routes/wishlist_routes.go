@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupWishlistRoutes(router *gin.Engine, wishlistController *controllers.WishlistController, authMiddleware *middlewares.AuthMiddleware, pricingMiddleware *middlewares.PricingMiddleware) {
+	v1 := router.Group("/api/v1")
+	v1.GET("/shared-wishlists/:token", wishlistController.Shared)
+
+	// Protected routes
+	wishlists := v1.Group("/wishlists")
+	wishlists.Use(authMiddleware.Handle())
+	{
+		wishlists.GET("", wishlistController.List)
+		wishlists.POST("", wishlistController.Create)
+		wishlists.GET("/:id", wishlistController.Get)
+		wishlists.PUT("/:id", wishlistController.Rename)
+		wishlists.DELETE("/:id", wishlistController.Delete)
+		// 加入時記錄當下幣別的價格，供之後比較降價
+		wishlists.POST("/:id/items", pricingMiddleware.Handle(), wishlistController.AddItem)
+		wishlists.DELETE("/:id/items/:productId", wishlistController.RemoveItem)
+		wishlists.POST("/:id/items/:productId/move", wishlistController.MoveItem)
+		wishlists.POST("/:id/items/:productId/move-to-cart", pricingMiddleware.Handle(), wishlistController.MoveToCart)
+		wishlists.POST("/:id/share", wishlistController.Share)
+		wishlists.DELETE("/:id/share", wishlistController.Unshare)
+	}
+}
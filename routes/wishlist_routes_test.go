@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"e-commerce/controllers"
+	"e-commerce/middlewares"
+	"e-commerce/repository"
+	"e-commerce/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWishlistRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	productRepo := repository.NewMockProductRepository()
+	priceListRepo := repository.NewMockPriceListRepository()
+	pricingService := services.NewPricingService(priceListRepo, productRepo)
+	inventoryService := services.NewInventoryService(repository.NewMockInventoryRepository(), productRepo, nil)
+	cartService := services.NewCartService(repository.NewMockCartRepository(), productRepo, inventoryService, pricingService, nil)
+	wishlistService := services.NewWishlistService(repository.NewMockWishlistRepository(), productRepo, priceListRepo, cartService)
+	authService := services.NewAuthService(repository.NewMockUserRepository(), nil)
+
+	SetupWishlistRoutes(r,
+		controllers.NewWishlistController(wishlistService),
+		middlewares.NewAuthMiddleware(nil, authService),
+		middlewares.NewPricingMiddleware(pricingService))
+
+	routes := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"List Wishlists", "GET", "/api/v1/wishlists"},
+		{"Create Wishlist", "POST", "/api/v1/wishlists"},
+		{"Get Wishlist", "GET", "/api/v1/wishlists/1"},
+		{"Rename Wishlist", "PUT", "/api/v1/wishlists/1"},
+		{"Delete Wishlist", "DELETE", "/api/v1/wishlists/1"},
+		{"Add Item", "POST", "/api/v1/wishlists/1/items"},
+		{"Remove Item", "DELETE", "/api/v1/wishlists/1/items/1"},
+		{"Move Item", "POST", "/api/v1/wishlists/1/items/1/move"},
+		{"Move To Cart", "POST", "/api/v1/wishlists/1/items/1/move-to-cart"},
+		{"Share", "POST", "/api/v1/wishlists/1/share"},
+		{"Unshare", "DELETE", "/api/v1/wishlists/1/share"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusNotFound, resp.Code,
+				"Route %s should exist but got 404", route.path)
+		})
+	}
+
+	// 無效的分享連結應回傳 404 錯誤訊息
+	req := httptest.NewRequest("GET", "/api/v1/shared-wishlists/unknown", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Contains(t, resp.Body.String(), "wishlist not found")
+}
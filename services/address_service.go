@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+// maxAddresses caps the address book of a user
+const maxAddresses = 20
+
+// Kinds of default address
+const (
+	AddressShipping = "shipping"
+	AddressBilling  = "billing"
+)
+
+var (
+	ErrAddressNotFound  = errors.New("address not found")
+	ErrInvalidAddress   = errors.New("invalid address")
+	ErrAddressRequired  = errors.New("shipping address is required")
+	ErrTooManyAddresses = errors.New("address book is full")
+)
+
+// postalFormat checks the postal codes of a country, with spaces and
+// dashes removed, and writes them the way the post office does
+type postalFormat struct {
+	pattern *regexp.Regexp
+	format  func(code string) string
+	example string
+}
+
+var postalFormats = map[string]postalFormat{
+	// 3 碼、3+2 碼或 3+3 碼郵遞區號
+	"TW": {regexp.MustCompile(`^\d{3}(\d{2}|\d{3})?$`), nil, "100, 10058 or 100058"},
+	"JP": {regexp.MustCompile(`^\d{7}$`), func(c string) string { return c[:3] + "-" + c[3:] }, "100-0001"},
+	"US": {regexp.MustCompile(`^\d{5}(\d{4})?$`), func(c string) string {
+		if len(c) == 9 {
+			return c[:5] + "-" + c[5:]
+		}
+		return c
+	}, "94105 or 94105-1804"},
+	"CA": {regexp.MustCompile(`^[A-Z]\d[A-Z]\d[A-Z]\d$`), func(c string) string { return c[:3] + " " + c[3:] }, "K1A 0B1"},
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+func invalidAddress(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidAddress, reason)
+}
+
+// NormalizeAddress trims an address and checks it against the format of
+// its country, writing postal codes in their standard form
+func NormalizeAddress(address models.Address) (models.Address, error) {
+	a := models.Address{
+		Name:       strings.TrimSpace(address.Name),
+		Phone:      strings.TrimSpace(address.Phone),
+		Line1:      strings.TrimSpace(address.Line1),
+		Line2:      strings.TrimSpace(address.Line2),
+		City:       strings.TrimSpace(address.City),
+		PostalCode: strings.ToUpper(strings.TrimSpace(address.PostalCode)),
+		Country:    strings.ToUpper(strings.TrimSpace(address.Country)),
+	}
+	if len(a.Country) != 2 {
+		return a, invalidAddress("country must be a two-letter country code")
+	}
+	if a.Name == "" || a.Line1 == "" || a.City == "" {
+		return a, invalidAddress("name, line1 and city are required")
+	}
+	if a.Phone != "" {
+		digits := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(a.Phone)
+		if !phonePattern.MatchString(digits) {
+			return a, invalidAddress("phone must be 6 to 15 digits")
+		}
+	}
+
+	format, ok := postalFormats[a.Country]
+	if !ok {
+		return a, nil
+	}
+	code := strings.NewReplacer(" ", "", "-", "").Replace(a.PostalCode)
+	if !format.pattern.MatchString(code) {
+		return a, invalidAddress(fmt.Sprintf("postal code of %s should look like %s", a.Country, format.example))
+	}
+	if format.format != nil {
+		code = format.format(code)
+	}
+	a.PostalCode = code
+	return a, nil
+}
+
+// AddressDetails is an address book entry as the customer enters it
+type AddressDetails struct {
+	Label             string
+	Address           models.Address
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+}
+
+// AddressService manages the address books of users
+type AddressService struct {
+	addressRepo repository.AddressRepository
+}
+
+func NewAddressService(addressRepo repository.AddressRepository) *AddressService {
+	return &AddressService{
+		addressRepo: addressRepo,
+	}
+}
+
+func (s *AddressService) List(userID uint) ([]models.CustomerAddress, error) {
+	addresses, err := s.addressRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if addresses == nil {
+		addresses = []models.CustomerAddress{}
+	}
+	return addresses, nil
+}
+
+// Get returns an address of the user; other users' addresses are not found
+func (s *AddressService) Get(userID, id uint) (*models.CustomerAddress, error) {
+	address, err := s.addressRepo.FindByID(id)
+	if err != nil || address.UserID != userID {
+		return nil, ErrAddressNotFound
+	}
+	return address, nil
+}
+
+// Create adds an address to the user's book. The first address becomes
+// the default for both shipping and billing.
+func (s *AddressService) Create(userID uint, details AddressDetails) (*models.CustomerAddress, error) {
+	normalized, err := NormalizeAddress(details.Address)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.addressRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAddresses {
+		return nil, ErrTooManyAddresses
+	}
+
+	address := &models.CustomerAddress{
+		UserID:            userID,
+		Label:             strings.TrimSpace(details.Label),
+		Address:           normalized,
+		IsDefaultShipping: details.IsDefaultShipping || len(existing) == 0,
+		IsDefaultBilling:  details.IsDefaultBilling || len(existing) == 0,
+	}
+	if err := s.addressRepo.Create(address); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// Update replaces an address. Clearing a default flag leaves the user
+// without that default until another address takes it.
+func (s *AddressService) Update(userID, id uint, details AddressDetails) (*models.CustomerAddress, error) {
+	address, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := NormalizeAddress(details.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	address.Label = strings.TrimSpace(details.Label)
+	address.Address = normalized
+	address.IsDefaultShipping = details.IsDefaultShipping
+	address.IsDefaultBilling = details.IsDefaultBilling
+	if err := s.addressRepo.Update(address); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// Delete removes an address. Orders keep their copy of it.
+func (s *AddressService) Delete(userID, id uint) error {
+	if _, err := s.Get(userID, id); err != nil {
+		return err
+	}
+	return s.addressRepo.Delete(id)
+}
+
+// Default returns the user's default address of a kind
+func (s *AddressService) Default(userID uint, kind string) (*models.CustomerAddress, error) {
+	addresses, err := s.addressRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i, address := range addresses {
+		if (kind == AddressShipping && address.IsDefaultShipping) || (kind == AddressBilling && address.IsDefaultBilling) {
+			return &addresses[i], nil
+		}
+	}
+	return nil, ErrAddressNotFound
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	base := models.Address{Name: "王小明", Line1: "信義路五段7號", City: "台北市"}
+	tests := []struct {
+		country    string
+		postalCode string
+		want       string
+		ok         bool
+	}{
+		{"TW", "110", "110", true},
+		{"tw", "11049", "11049", true},
+		{"TW", "110-049", "110049", true},
+		{"TW", "1104", "", false},
+		{"TW", "1100499", "", false},
+		{"JP", "1000001", "100-0001", true},
+		{"JP", "100-001", "", false},
+		{"US", "94105-1804", "94105-1804", true},
+		{"US", "9410", "", false},
+		{"CA", "k1a0b1", "K1A 0B1", true},
+		{"CA", "12345", "", false},
+		{"DE", "10115", "10115", true},
+		{"TWN", "110", "", false},
+	}
+	for _, tt := range tests {
+		address := base
+		address.Country = tt.country
+		address.PostalCode = tt.postalCode
+		got, err := NormalizeAddress(address)
+		if tt.ok != (err == nil) {
+			t.Errorf("NormalizeAddress(%s %s) error = %v", tt.country, tt.postalCode, err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("NormalizeAddress(%s %s) error = %v, want %v", tt.country, tt.postalCode, err, ErrInvalidAddress)
+		}
+		if tt.ok && got.PostalCode != tt.want {
+			t.Errorf("NormalizeAddress(%s %s) postal code = %q, want %q", tt.country, tt.postalCode, got.PostalCode, tt.want)
+		}
+	}
+
+	if _, err := NormalizeAddress(models.Address{Name: "王小明", City: "台北市", PostalCode: "110", Country: "TW"}); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("NormalizeAddress() without line1 error = %v, want %v", err, ErrInvalidAddress)
+	}
+	phone := base
+	phone.Country, phone.PostalCode, phone.Phone = "TW", "110", "call me"
+	if _, err := NormalizeAddress(phone); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("NormalizeAddress() with a bad phone error = %v, want %v", err, ErrInvalidAddress)
+	}
+}
+
+func TestAddressDefaults(t *testing.T) {
+	s := NewAddressService(repository.NewMockAddressRepository())
+	home, err := s.Create(1, AddressDetails{Label: "Home", Address: testAddress()})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !home.IsDefaultShipping || !home.IsDefaultBilling {
+		t.Errorf("first address defaults = %v/%v, want both", home.IsDefaultShipping, home.IsDefaultBilling)
+	}
+
+	office := testAddress()
+	office.Line1 = "市府路1號"
+	work, _ := s.Create(1, AddressDetails{Label: "Office", Address: office, IsDefaultBilling: true})
+	if got, _ := s.Default(1, AddressBilling); got == nil || got.ID != work.ID {
+		t.Errorf("Default(billing) = %v, want the office", got)
+	}
+	if got, _ := s.Default(1, AddressShipping); got == nil || got.ID != home.ID {
+		t.Errorf("Default(shipping) = %v, want home", got)
+	}
+
+	if _, err := s.Get(2, home.ID); !errors.Is(err, ErrAddressNotFound) {
+		t.Errorf("Get() by another user error = %v, want %v", err, ErrAddressNotFound)
+	}
+	if err := s.Delete(2, home.ID); !errors.Is(err, ErrAddressNotFound) {
+		t.Errorf("Delete() by another user error = %v, want %v", err, ErrAddressNotFound)
+	}
+}
+
+func TestCheckoutCopiesAddress(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.fillCart(t)
+	if _, _, err := tc.checkout.Checkout(tc.user, "key-1", CheckoutDetails{}, tc.priceList); !errors.Is(err, ErrAddressRequired) {
+		t.Errorf("Checkout() without an address error = %v, want %v", err, ErrAddressRequired)
+	}
+
+	home, err := tc.addresses.Create(tc.user.ID, AddressDetails{Label: "Home", Address: testAddress()})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	order, _, err := tc.checkout.Checkout(tc.user, "key-2", CheckoutDetails{}, tc.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if order.ShippingAddress != home.Address || order.BillingAddress != home.Address {
+		t.Errorf("order addresses = %+v / %+v, want the default address", order.ShippingAddress, order.BillingAddress)
+	}
+
+	// 修改通訊錄不影響已成立的訂單
+	moved := testAddress()
+	moved.Line1 = "忠孝東路一段1號"
+	if _, err := tc.addresses.Update(tc.user.ID, home.ID, AddressDetails{Address: moved, IsDefaultShipping: true}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	placed, err := tc.orders.Get(tc.user.ID, order.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if placed.ShippingAddress.Line1 != "信義路五段7號" {
+		t.Errorf("order address changed to %q after editing the address book", placed.ShippingAddress.Line1)
+	}
+}
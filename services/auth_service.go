@@ -2,22 +2,33 @@ package services
 
 import (
 	"errors"
+	"log"
 	"os"
+	"strings"
 	"time"
 
 	"e-commerce/models"
+	"e-commerce/money"
 	"e-commerce/repository"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// GuestCartMerger moves the cart a guest built up into their own cart
+// when they sign in
+type GuestCartMerger interface {
+	MergeGuestCart(userID uint, token string) error
+}
+
 type AuthService struct {
 	userRepo repository.UserRepository
+	carts    GuestCartMerger
 }
 
-func NewAuthService(userRepo repository.UserRepository) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, carts GuestCartMerger) *AuthService {
 	return &AuthService{
 		userRepo: userRepo,
+		carts:    carts,
 	}
 }
 
@@ -26,6 +37,7 @@ func (s *AuthService) Register(name, email, password string) error {
 		Name:     name,
 		Email:    email,
 		Password: password,
+		Role:     models.RoleCustomer,
 	}
 
 	// 在創建用戶前先進行密碼雜湊
@@ -36,7 +48,9 @@ func (s *AuthService) Register(name, email, password string) error {
 	return s.userRepo.Create(user)
 }
 
-func (s *AuthService) Login(email, password string) (*models.User, string, error) {
+// Login checks the credentials and issues a token. A guest cart token, if
+// given, is merged into the user's cart.
+func (s *AuthService) Login(email, password, cartToken string) (*models.User, string, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		return nil, "", errors.New("invalid credentials")
@@ -56,6 +70,13 @@ func (s *AuthService) Login(email, password string) (*models.User, string, error
 		return nil, "", errors.New("could not generate token")
 	}
 
+	// 合併失敗不影響登入，訪客購物車仍可用原 token 取回
+	if cartToken != "" && s.carts != nil {
+		if err := s.carts.MergeGuestCart(user.ID, cartToken); err != nil {
+			log.Printf("auth: failed to merge guest cart for user %d: %v", user.ID, err)
+		}
+	}
+
 	return user, tokenString, nil
 }
 
@@ -93,3 +114,40 @@ func (s *AuthService) UpdateProfile(userID uint, name, email string) (*models.Us
 
 	return user, nil
 }
+
+// SetPreferredCurrency stores the currency used for pricing when a request
+// does not ask for one. An empty currency clears the preference.
+func (s *AuthService) SetPreferredCurrency(userID uint, currency string) (*models.User, error) {
+	currency = strings.ToUpper(currency)
+	if currency != "" && !money.IsSupported(currency) {
+		return nil, errors.New("unsupported currency")
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	user.PreferredCurrency = currency
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("failed to update profile")
+	}
+
+	return user, nil
+}
+
+// SetCustomerGroup puts a user in a group that promotions can target. An
+// empty group removes the user from any group.
+func (s *AuthService) SetCustomerGroup(userID uint, group string) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	user.CustomerGroup = strings.ToLower(strings.TrimSpace(group))
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, errors.New("failed to update user")
+	}
+
+	return user, nil
+}
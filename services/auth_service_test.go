@@ -84,7 +84,7 @@ func (m *MockUserRepository) Update(user *models.User) error {
 
 func TestRegister(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	authService := NewAuthService(mockRepo)
+	authService := NewAuthService(mockRepo, nil)
 
 	tests := []struct {
 		name     string
@@ -121,7 +121,7 @@ func TestRegister(t *testing.T) {
 
 func TestLogin(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	authService := NewAuthService(mockRepo)
+	authService := NewAuthService(mockRepo, nil)
 
 	// Create a test user
 	testUser := &models.User{
@@ -162,7 +162,7 @@ func TestLogin(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			user, token, err := authService.Login(tt.email, tt.password)
+			user, token, err := authService.Login(tt.email, tt.password, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Login() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -181,7 +181,7 @@ func TestLogin(t *testing.T) {
 
 func TestUpdateProfile(t *testing.T) {
 	mockRepo := NewMockUserRepository()
-	authService := NewAuthService(mockRepo)
+	authService := NewAuthService(mockRepo, nil)
 
 	// Create test users
 	testUser := &models.User{
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"e-commerce/models"
+)
+
+// CancellationService cancels orders and settles their payment: open
+// authorizations are voided and captured money is refunded in full. Stock,
+// coupons and the order history are handled by the cancel transition.
+type CancellationService struct {
+	orderService   *OrderService
+	paymentService *PaymentService
+	refundService  *RefundService
+}
+
+func NewCancellationService(orderService *OrderService, paymentService *PaymentService, refundService *RefundService) *CancellationService {
+	return &CancellationService{
+		orderService:   orderService,
+		paymentService: paymentService,
+		refundService:  refundService,
+	}
+}
+
+// Cancel cancels an order for a customer or staff member. Customers may
+// cancel until fulfillment starts or within the cancellation window; staff
+// may cancel later with a reason. When a void or the refund fails the order
+// stays cancelled and ErrPaymentFailed is returned so the payment can be
+// settled by hand.
+func (s *CancellationService) Cancel(ctx context.Context, actor *models.User, orderID uint, reason string) (*models.Order, error) {
+	order, err := s.orderService.ChangeStatus(actor, orderID, StatusChange{
+		To:     models.OrderStatusCancelled,
+		Reason: reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	voidErr := s.paymentService.VoidOpen(ctx, order.ID)
+	if order.RefundedTotal.Cmp(order.Total) < 0 {
+		refundReason := "Order cancelled"
+		if reason = strings.TrimSpace(reason); reason != "" {
+			refundReason += ": " + reason
+		}
+		_, err := s.refundService.Refund(ctx, actor, order.ID, RefundRequest{Reason: refundReason})
+		switch {
+		case errors.Is(err, ErrNothingToRefund):
+			// 尚未付款的訂單沒有要退的錢
+		case err != nil:
+			return order, fmt.Errorf("%w: order cancelled but not refunded: %v", ErrPaymentFailed, err)
+		}
+	}
+	if voidErr != nil {
+		return order, fmt.Errorf("%w: order cancelled but not voided: %v", ErrPaymentFailed, voidErr)
+	}
+	return s.orderService.GetAny(order.ID)
+}
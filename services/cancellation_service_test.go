@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/payments"
+	"e-commerce/repository"
+)
+
+func (tr *testRefunds) cancellations() *CancellationService {
+	return NewCancellationService(tr.orders, tr.payments, tr.refunds)
+}
+
+func TestCustomerCancelsPaidOrder(t *testing.T) {
+	tr := newTestRefunds(t)
+	ctx := context.Background()
+
+	if _, err := tr.cancellations().Cancel(ctx, &models.User{ID: 99}, tr.order.ID, ""); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Cancel() by another customer error = %v, want %v", err, ErrOrderNotFound)
+	}
+	order, err := tr.cancellations().Cancel(ctx, &tr.user, tr.order.ID, "Ordered the wrong size")
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if order.Status != models.OrderStatusCancelled || order.RefundedTotal != twd(1200) {
+		t.Errorf("order = %s with %v refunded, want cancelled and refunded in full", order.Status, order.RefundedTotal)
+	}
+	if levels, _ := tr.inventory.StockLevels(1); levels[0].OnHand != 10 {
+		t.Errorf("OnHand = %d, want the sold stock returned", levels[0].OnHand)
+	}
+
+	refunds, _ := tr.refunds.ForOrder(&tr.user, tr.order.ID)
+	if len(refunds) != 1 || refunds[0].Restock || refunds[0].Reason != "Order cancelled: Ordered the wrong size" {
+		t.Errorf("refunds = %+v, want one refund without restocking again", refunds)
+	}
+	history, _ := tr.orders.History(&tr.user, tr.order.ID)
+	last := history[len(history)-1]
+	if last.To != models.OrderStatusCancelled || last.ActorID == nil || *last.ActorID != tr.user.ID || last.Reason != "Ordered the wrong size" {
+		t.Errorf("last history entry = %+v, want the customer's cancellation", last)
+	}
+}
+
+func TestCancellationWindow(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		after   time.Duration
+		wantErr error
+	}{
+		{"within the window", 30 * time.Minute, nil},
+		{"after the window", 2 * time.Hour, ErrCancellationClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestRefunds(t)
+			if _, err := tr.orders.ChangeStatus(tr.staff, tr.order.ID, StatusChange{To: models.OrderStatusFulfilling}); err != nil {
+				t.Fatalf("ChangeStatus(fulfilling) error = %v", err)
+			}
+			tr.orders.now = func() time.Time { return tr.order.CreatedAt.Add(tt.after) }
+
+			if _, err := tr.cancellations().Cancel(ctx, &tr.user, tr.order.ID, ""); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Cancel() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStaffOverridesCancellationWindow(t *testing.T) {
+	tr := newTestRefunds(t)
+	ctx := context.Background()
+	if _, err := tr.orders.ChangeStatus(tr.staff, tr.order.ID, StatusChange{To: models.OrderStatusFulfilling}); err != nil {
+		t.Fatalf("ChangeStatus(fulfilling) error = %v", err)
+	}
+	tr.orders.now = func() time.Time { return tr.order.CreatedAt.Add(24 * time.Hour) }
+
+	if _, err := tr.cancellations().Cancel(ctx, tr.staff, tr.order.ID, ""); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("Cancel() by staff without a reason error = %v, want %v", err, ErrReasonRequired)
+	}
+	order, err := tr.cancellations().Cancel(ctx, tr.staff, tr.order.ID, "Customer called support")
+	if err != nil || order.Status != models.OrderStatusCancelled || order.RefundedTotal != twd(1200) {
+		t.Fatalf("Cancel() by staff = %+v, %v, want cancelled and refunded", order, err)
+	}
+}
+
+func TestCancelPendingOrderVoidsPayment(t *testing.T) {
+	tc := newTestCheckout(t)
+	ctx := context.Background()
+	provider := payments.NewFakeProvider("secret")
+	paymentRepo := repository.NewMockPaymentRepository()
+	paymentService := NewPaymentService(paymentRepo, tc.orders, provider, nil)
+	refunds := NewRefundService(repository.NewMockRefundRepository(paymentRepo, tc.orders.orderRepo), paymentRepo, tc.orders, tc.inventory, provider, tc.mail, nil)
+	order := tc.placeOrder(t)
+
+	payment, err := paymentService.Pay(ctx, tc.user.ID, order.ID, payments.TestCard3DS)
+	if err != nil || payment.Status != models.PaymentStatusRequiresAction {
+		t.Fatalf("Pay() = %+v, %v, want a 3-D Secure challenge", payment, err)
+	}
+	cancelled, err := NewCancellationService(tc.orders, paymentService, refunds).Cancel(ctx, &tc.user, order.ID, "")
+	if err != nil || cancelled.Status != models.OrderStatusCancelled {
+		t.Fatalf("Cancel() = %+v, %v, want cancelled", cancelled, err)
+	}
+	if attempts, _ := paymentService.ForOrder(&tc.user, order.ID); attempts[0].Status != models.PaymentStatusVoided {
+		t.Errorf("payment status = %q, want %q", attempts[0].Status, models.PaymentStatusVoided)
+	}
+	if available, _ := tc.inventory.Available(1); available != 10 {
+		t.Errorf("Available() = %d, want the stock released", available)
+	}
+}
+
+func TestCancelReportsFailedVoid(t *testing.T) {
+	tc := newTestCheckout(t)
+	ctx := context.Background()
+	provider := payments.NewFakeProvider("secret")
+	paymentRepo := repository.NewMockPaymentRepository()
+	paymentService := NewPaymentService(paymentRepo, tc.orders, provider, nil)
+	refunds := NewRefundService(repository.NewMockRefundRepository(paymentRepo, tc.orders.orderRepo), paymentRepo, tc.orders, tc.inventory, provider, tc.mail, nil)
+	order := tc.placeOrder(t)
+
+	payment, err := paymentService.Pay(ctx, tc.user.ID, order.ID, payments.TestCard3DS)
+	if err != nil {
+		t.Fatalf("Pay() error = %v", err)
+	}
+	// 金流商不認得這筆授權，作廢會失敗
+	payment.ProviderRef = "unknown"
+	paymentRepo.Update(payment)
+
+	if _, err := NewCancellationService(tc.orders, paymentService, refunds).Cancel(ctx, &tc.user, order.ID, ""); !errors.Is(err, ErrPaymentFailed) {
+		t.Fatalf("Cancel() error = %v, want %v", err, ErrPaymentFailed)
+	}
+	if cancelled, _ := tc.orders.GetAny(order.ID); cancelled.Status != models.OrderStatusCancelled {
+		t.Errorf("order status = %q, want %q", cancelled.Status, models.OrderStatusCancelled)
+	}
+	if attempts, _ := paymentService.ForOrder(&tc.user, order.ID); attempts[0].Status != models.PaymentStatusRequiresAction {
+		t.Errorf("payment status = %q, want it left open", attempts[0].Status)
+	}
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+const (
+	// cartRecoverySweepInterval is how often abandoned carts are looked for
+	// and due reminders sent
+	cartRecoverySweepInterval = time.Minute
+	cartRecoverySweepBatch    = 50
+	defaultCartAbandonedAfter = time.Hour
+	// cartRecoveryLookback keeps carts idle for longer than this past the
+	// threshold, e.g. when the feature is first turned on, from being
+	// reminded of
+	cartRecoveryLookback = 24 * time.Hour
+	// cartRecoveryAttribution is how long after the last reminder an order
+	// from the cart still counts as won back
+	cartRecoveryAttribution = 7 * 24 * time.Hour
+)
+
+// cartReminders is the reminder sequence; each waits its delay after the
+// one before it, the first is sent as soon as the cart is found abandoned.
+// The last one carries the coupon, if any.
+var cartReminders = []struct {
+	delay   time.Duration
+	subject string
+}{
+	{0, "You left something in your cart"},
+	{24 * time.Hour, "Your cart is still waiting"},
+	{48 * time.Hour, "Still thinking it over?"},
+}
+
+var (
+	ErrInvalidCartRecovery     = errors.New("invalid cart recovery query")
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+	ErrCartRecoveryChanged     = repository.ErrCartRecoveryChanged
+)
+
+// CartRecoveryService reminds signed-in customers of carts they left with
+// items in them and tracks the orders the reminders win back
+type CartRecoveryService struct {
+	recoveryRepo     repository.CartRecoveryRepository
+	cartRepo         repository.CartRepository
+	productRepo      repository.ProductRepository
+	userRepo         repository.UserRepository
+	promotionService *PromotionService
+	mailer           mailer.Mailer
+	abandonedAfter   time.Duration
+	// couponPromotionID is the promotion the last reminder's one-time coupon
+	// unlocks; zero sends no coupon
+	couponPromotionID uint
+	unsubscribeKey    []byte
+	storeURL          string
+	now               func() time.Time
+}
+
+func NewCartRecoveryService(recoveryRepo repository.CartRecoveryRepository, cartRepo repository.CartRepository, productRepo repository.ProductRepository, userRepo repository.UserRepository, promotionService *PromotionService, m mailer.Mailer) *CartRecoveryService {
+	abandonedAfter := defaultCartAbandonedAfter
+	if v, err := time.ParseDuration(os.Getenv("CART_ABANDONED_AFTER")); err == nil && v > 0 {
+		abandonedAfter = v
+	}
+	promotionID, _ := strconv.ParseUint(os.Getenv("CART_RECOVERY_PROMOTION_ID"), 10, 64)
+
+	key := os.Getenv("UNSUBSCRIBE_SIGNING_KEY")
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+	unsubscribeKey := []byte(key)
+	if len(unsubscribeKey) == 0 {
+		// 未設定金鑰時使用隨機金鑰，退訂連結在重啟後失效
+		unsubscribeKey = make([]byte, 32)
+		rand.Read(unsubscribeKey)
+	}
+
+	return &CartRecoveryService{
+		recoveryRepo:      recoveryRepo,
+		cartRepo:          cartRepo,
+		productRepo:       productRepo,
+		userRepo:          userRepo,
+		promotionService:  promotionService,
+		mailer:            m,
+		abandonedAfter:    abandonedAfter,
+		couponPromotionID: uint(promotionID),
+		unsubscribeKey:    unsubscribeKey,
+		storeURL:          strings.TrimRight(os.Getenv("STORE_URL"), "/"),
+		now:               time.Now,
+	}
+}
+
+func (s *CartRecoveryService) unsubscribeSignature(userID uint) string {
+	mac := hmac.New(sha256.New, s.unsubscribeKey)
+	fmt.Fprintf(mac, "cart-reminders:%d", userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// UnsubscribeToken returns the token of a user's unsubscribe link. It does
+// not expire, so old reminders can always be unsubscribed from.
+func (s *CartRecoveryService) UnsubscribeToken(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10) + "." + s.unsubscribeSignature(userID)
+}
+
+// Unsubscribe stops the reminders of the user a token was made for
+func (s *CartRecoveryService) Unsubscribe(token string) error {
+	userPart, signature, ok := strings.Cut(token, ".")
+	userID, err := strconv.ParseUint(userPart, 10, 64)
+	if !ok || err != nil || userID == 0 {
+		return ErrInvalidUnsubscribeToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.unsubscribeSignature(uint(userID)))) {
+		return ErrInvalidUnsubscribeToken
+	}
+	return s.recoveryRepo.OptOut(uint(userID))
+}
+
+// RemindersEnabled reports whether a user gets abandoned cart reminders
+func (s *CartRecoveryService) RemindersEnabled(userID uint) (bool, error) {
+	optedOut, err := s.recoveryRepo.IsOptedOut(userID)
+	return !optedOut, err
+}
+
+// SetReminders turns a user's abandoned cart reminders on or off
+func (s *CartRecoveryService) SetReminders(userID uint, enabled bool) error {
+	if enabled {
+		return s.recoveryRepo.OptIn(userID)
+	}
+	return s.recoveryRepo.OptOut(userID)
+}
+
+// List returns recoveries for staff, optionally filtered by status
+func (s *CartRecoveryService) List(status string, page, pageSize int) ([]models.CartRecovery, error) {
+	switch status {
+	case "", models.CartRecoveryStatusActive, models.CartRecoveryStatusReturned, models.CartRecoveryStatusFinished,
+		models.CartRecoveryStatusConverted, models.CartRecoveryStatusUnsubscribed:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidCartRecovery, status)
+	}
+	offset, limit := paginate(page, pageSize)
+	return s.recoveryRepo.FindByStatus(status, offset, limit)
+}
+
+// Stats summarizes the recoveries started in the last days
+func (s *CartRecoveryService) Stats(days int) (*models.CartRecoveryStats, error) {
+	if days < 1 || days > 366 {
+		return nil, fmt.Errorf("%w: days must be between 1 and 366", ErrInvalidCartRecovery)
+	}
+	since := s.now().AddDate(0, 0, -days).Truncate(time.Second)
+	counts, err := s.recoveryRepo.CountByStatus(since)
+	if err != nil {
+		return nil, err
+	}
+	revenue, err := s.recoveryRepo.Revenue(since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.CartRecoveryStats{
+		Since:        since,
+		Converted:    counts[models.CartRecoveryStatusConverted],
+		Unsubscribed: counts[models.CartRecoveryStatusUnsubscribed],
+		Revenue:      revenue,
+	}
+	for _, n := range counts {
+		stats.Abandoned += n
+	}
+	if stats.Abandoned > 0 {
+		stats.ConversionRate = float64(stats.Converted) / float64(stats.Abandoned)
+	}
+	return stats, nil
+}
+
+// CartConverted marks the open recovery of a cart as converted when the
+// order comes soon enough after a reminder. It implements
+// ConversionTracker.
+func (s *CartRecoveryService) CartConverted(cartID uint, order *models.Order) {
+	recovery, err := s.recoveryRepo.FindOpen(cartID)
+	if err != nil || recovery.LastRemindedAt == nil {
+		return
+	}
+	now := s.now()
+	if now.Sub(*recovery.LastRemindedAt) > cartRecoveryAttribution {
+		return
+	}
+
+	from := recovery.Status
+	recovery.Status = models.CartRecoveryStatusConverted
+	recovery.NextReminderAt = nil
+	recovery.OrderID = &order.ID
+	recovery.OrderTotal = order.Total
+	recovery.ConvertedAt = &now
+	if err := s.recoveryRepo.Update(recovery, from); err != nil {
+		log.Printf("cart recovery: failed to record conversion of cart %d to order %d: %v", cartID, order.ID, err)
+	}
+}
+
+// detect starts a recovery for each newly abandoned cart
+func (s *CartRecoveryService) detect() error {
+	now := s.now()
+	idleBefore := now.Add(-s.abandonedAfter)
+	carts, err := s.recoveryRepo.FindAbandoned(idleBefore.Add(-cartRecoveryLookback), idleBefore, cartRecoverySweepBatch)
+	if err != nil {
+		return err
+	}
+	for _, cart := range carts {
+		next := now.Add(cartReminders[0].delay)
+		recovery := &models.CartRecovery{
+			CartID:         cart.ID,
+			UserID:         *cart.UserID,
+			CartUpdatedAt:  cart.UpdatedAt,
+			Status:         models.CartRecoveryStatusActive,
+			NextReminderAt: &next,
+		}
+		if err := s.recoveryRepo.Create(recovery); err != nil {
+			// 另一個排程可能已建立
+			log.Printf("cart recovery: failed to start recovery of cart %d: %v", cart.ID, err)
+		}
+	}
+	return nil
+}
+
+// remind sends the next reminder of a recovery, or stops it when the cart
+// has changed since it was abandoned, and reports whether it sent one. The
+// recovery is saved before the email is sent, so a reminder is never sent
+// twice.
+func (s *CartRecoveryService) remind(ctx context.Context, recovery *models.CartRecovery) (bool, error) {
+	cart, err := s.cartRepo.FindByUserID(recovery.UserID)
+	if err != nil || cart.ID != recovery.CartID || !cart.UpdatedAt.Equal(recovery.CartUpdatedAt) || len(cart.Items) == 0 {
+		recovery.Status = models.CartRecoveryStatusReturned
+		recovery.NextReminderAt = nil
+		return false, s.recoveryRepo.Update(recovery, models.CartRecoveryStatusActive)
+	}
+	user, err := s.userRepo.FindByID(recovery.UserID)
+	if err != nil {
+		// 帳號已不存在，不再提醒
+		log.Printf("cart recovery: stopping reminders of cart %d: %v", recovery.CartID, err)
+		recovery.Status = models.CartRecoveryStatusFinished
+		recovery.NextReminderAt = nil
+		return false, s.recoveryRepo.Update(recovery, models.CartRecoveryStatusActive)
+	}
+
+	step := recovery.RemindersSent
+	last := step == len(cartReminders)-1
+	if last && s.couponPromotionID != 0 && recovery.CouponCode == "" {
+		if coupon, err := s.createCoupon(); err != nil {
+			log.Printf("cart recovery: sending reminder of cart %d without a coupon: %v", recovery.CartID, err)
+		} else {
+			recovery.CouponCode = coupon.Code
+		}
+	}
+
+	now := s.now()
+	recovery.RemindersSent++
+	recovery.LastRemindedAt = &now
+	if last {
+		recovery.Status = models.CartRecoveryStatusFinished
+		recovery.NextReminderAt = nil
+	} else {
+		next := now.Add(cartReminders[step+1].delay)
+		recovery.NextReminderAt = &next
+	}
+	if err := s.recoveryRepo.Update(recovery, models.CartRecoveryStatusActive); err != nil {
+		return false, err
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: cartReminders[step].subject,
+		Body:    s.reminderBody(user, cart, recovery),
+	}); err != nil {
+		log.Printf("cart recovery: failed to remind %s of cart %d: %v", user.Email, cart.ID, err)
+	}
+	return true, nil
+}
+
+// createCoupon creates a one-time coupon for the configured promotion
+func (s *CartRecoveryService) createCoupon() (*models.Coupon, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return s.promotionService.CreateCoupon(s.couponPromotionID, CouponDetails{
+		Code:         "COMEBACK-" + strings.ToUpper(hex.EncodeToString(b)),
+		UsageLimit:   1,
+		PerUserLimit: 1,
+	})
+}
+
+func (s *CartRecoveryService) reminderBody(user *models.User, cart *models.Cart, recovery *models.CartRecovery) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nYou left these in your cart:\n\n", user.Name)
+	for _, item := range cart.Items {
+		name := fmt.Sprintf("Product #%d", item.ProductID)
+		if product, err := s.productRepo.FindByID(item.ProductID); err == nil {
+			name = product.Name
+		}
+		fmt.Fprintf(&body, "- %s x %d\n", name, item.Quantity)
+	}
+	if recovery.CouponCode != "" {
+		fmt.Fprintf(&body, "\nEnter coupon %s at checkout for a little something off your order. It can be used once.\n", recovery.CouponCode)
+	}
+	fmt.Fprintf(&body, "\nYour cart is saved, pick up where you left off whenever you are ready.\n\nTo stop these reminders, visit %s/api/v1/cart-reminders/unsubscribe?token=%s\n",
+		s.storeURL, s.UnsubscribeToken(user.ID))
+	return body.String()
+}
+
+// RemindDue starts recoveries for newly abandoned carts and sends the
+// reminders that are due
+func (s *CartRecoveryService) RemindDue(ctx context.Context) (int, error) {
+	if err := s.detect(); err != nil {
+		return 0, err
+	}
+	recoveries, err := s.recoveryRepo.FindDue(s.now(), cartRecoverySweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	reminded := 0
+	for i := range recoveries {
+		sent, err := s.remind(ctx, &recoveries[i])
+		if errors.Is(err, ErrCartRecoveryChanged) {
+			// 顧客剛好在這時結帳或退訂
+			continue
+		}
+		if err != nil {
+			return reminded, err
+		}
+		if sent {
+			reminded++
+		}
+	}
+	return reminded, nil
+}
+
+// Run sends abandoned cart reminders until ctx is cancelled
+func (s *CartRecoveryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(cartRecoverySweepInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RemindDue(ctx); err != nil {
+			log.Printf("cart recovery: failed to send reminders: %v", err)
+		} else if n > 0 {
+			log.Printf("cart recovery: sent %d reminders", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
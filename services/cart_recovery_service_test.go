@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+type testRecoveries struct {
+	*testCheckout
+	recoveries *CartRecoveryService
+	abandoned  time.Time
+}
+
+func newTestRecoveries(t *testing.T) *testRecoveries {
+	t.Helper()
+	tc := newTestCheckout(t)
+	recoveries := NewCartRecoveryService(repository.NewMockCartRecoveryRepository(tc.cartRepo), tc.cartRepo, tc.products, tc.users, tc.promotions, tc.mail)
+	tc.checkout.tracker = recoveries
+	tc.fillCart(t)
+	tr := &testRecoveries{testCheckout: tc, recoveries: recoveries, abandoned: time.Now()}
+	tr.after(2 * time.Hour)
+	return tr
+}
+
+// after moves the clock of the reminder scheduler to d after the cart was
+// last changed
+func (tr *testRecoveries) after(d time.Duration) {
+	now := tr.abandoned.Add(d)
+	tr.recoveries.now = func() time.Time { return now }
+}
+
+func (tr *testRecoveries) remind(t *testing.T, want int) {
+	t.Helper()
+	if n, err := tr.recoveries.RemindDue(context.Background()); err != nil || n != want {
+		t.Fatalf("RemindDue() = %d, %v, want %d reminders", n, err, want)
+	}
+}
+
+func (tr *testRecoveries) recovery(t *testing.T) models.CartRecovery {
+	t.Helper()
+	recoveries, err := tr.recoveries.List("", 1, 20)
+	if err != nil || len(recoveries) == 0 {
+		t.Fatalf("List() = %v, %v, want a recovery", recoveries, err)
+	}
+	return recoveries[0]
+}
+
+func TestCartRecoveryReminderSequence(t *testing.T) {
+	tr := newTestRecoveries(t)
+	promotion, err := tr.promotions.Create(PromotionDetails{
+		Name: "Come back for 10% off", Type: models.PromotionPercentOff, Percent: 10, Active: true, RequiresCoupon: true,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tr.recoveries.couponPromotionID = promotion.ID
+
+	tr.after(30 * time.Minute)
+	tr.remind(t, 0)
+
+	tr.after(2 * time.Hour)
+	tr.remind(t, 1)
+	tr.remind(t, 0)
+	sent := tr.mail.Sent()
+	if len(sent) != 1 || sent[0].To[0] != tr.user.Email || sent[0].Subject != cartReminders[0].subject {
+		t.Fatalf("sent = %+v, want the first reminder to the buyer", sent)
+	}
+	if !strings.Contains(sent[0].Body, "Coffee x 2") || !strings.Contains(sent[0].Body, tr.recoveries.UnsubscribeToken(tr.user.ID)) {
+		t.Errorf("body = %q, want the cart and an unsubscribe link", sent[0].Body)
+	}
+
+	tr.after(26 * time.Hour)
+	tr.remind(t, 1)
+	if recovery := tr.recovery(t); recovery.RemindersSent != 2 || recovery.CouponCode != "" {
+		t.Errorf("recovery = %+v, want a second reminder without a coupon", recovery)
+	}
+
+	tr.after(74 * time.Hour)
+	tr.remind(t, 1)
+	recovery := tr.recovery(t)
+	if recovery.Status != models.CartRecoveryStatusFinished || recovery.RemindersSent != 3 || recovery.NextReminderAt != nil {
+		t.Fatalf("recovery = %+v, want finished after 3 reminders", recovery)
+	}
+	if sent := tr.mail.Sent(); !strings.Contains(sent[2].Body, recovery.CouponCode) {
+		t.Errorf("last reminder = %q, want coupon %s", sent[2].Body, recovery.CouponCode)
+	}
+	coupon, err := tr.promotions.CheckCoupon(recovery.CouponCode, tr.user.ID)
+	if err != nil || coupon.PromotionID != promotion.ID || coupon.UsageLimit != 1 {
+		t.Errorf("CheckCoupon() = %+v, %v, want a one-time coupon for the promotion", coupon, err)
+	}
+
+	tr.after(200 * time.Hour)
+	tr.remind(t, 0)
+}
+
+func TestCartRecoveryConversion(t *testing.T) {
+	tr := newTestRecoveries(t)
+	tr.remind(t, 1)
+
+	order, _, err := tr.checkout.Checkout(tr.user, "key-1", CheckoutDetails{ShippingAddress: testAddress()}, tr.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	recovery := tr.recovery(t)
+	if recovery.Status != models.CartRecoveryStatusConverted || recovery.OrderID == nil || *recovery.OrderID != order.ID {
+		t.Fatalf("recovery = %+v, want converted to order %d", recovery, order.ID)
+	}
+	if recovery.OrderTotal != order.Total || recovery.NextReminderAt != nil {
+		t.Errorf("recovery = %+v, want the order total and no more reminders", recovery)
+	}
+
+	stats, err := tr.recoveries.Stats(30)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Abandoned != 1 || stats.Converted != 1 || stats.ConversionRate != 1 {
+		t.Errorf("Stats() = %+v, want 1 of 1 converted", stats)
+	}
+	if len(stats.Revenue) != 1 || stats.Revenue[0] != order.Total {
+		t.Errorf("Revenue = %+v, want %v", stats.Revenue, order.Total)
+	}
+	if _, err := tr.recoveries.Stats(0); !errors.Is(err, ErrInvalidCartRecovery) {
+		t.Errorf("Stats(0) error = %v, want %v", err, ErrInvalidCartRecovery)
+	}
+}
+
+func TestCartRecoveryStopsWhenCartChanges(t *testing.T) {
+	tr := newTestRecoveries(t)
+	tr.remind(t, 1)
+
+	if _, err := tr.carts.AddItem(tr.user.ID, "", 2, 1, tr.priceList); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	tr.after(26 * time.Hour)
+	tr.remind(t, 0)
+	if recovery := tr.recovery(t); recovery.Status != models.CartRecoveryStatusReturned || recovery.RemindersSent != 1 {
+		t.Fatalf("recovery = %+v, want returned after 1 reminder", recovery)
+	}
+
+	// 顧客回來後結帳，仍算是提醒帶回的訂單
+	if _, _, err := tr.checkout.Checkout(tr.user, "key-1", CheckoutDetails{ShippingAddress: testAddress()}, tr.priceList); err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if recovery := tr.recovery(t); recovery.Status != models.CartRecoveryStatusConverted {
+		t.Errorf("Status = %q, want %q", recovery.Status, models.CartRecoveryStatusConverted)
+	}
+}
+
+func TestCartRecoveryUnsubscribe(t *testing.T) {
+	tr := newTestRecoveries(t)
+	tr.remind(t, 1)
+
+	if err := tr.recoveries.Unsubscribe("7.forged"); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+		t.Errorf("Unsubscribe() with a forged token error = %v, want %v", err, ErrInvalidUnsubscribeToken)
+	}
+	if err := tr.recoveries.Unsubscribe(tr.recoveries.UnsubscribeToken(tr.user.ID)); err != nil {
+		t.Fatalf("Unsubscribe() error = %v", err)
+	}
+	if recovery := tr.recovery(t); recovery.Status != models.CartRecoveryStatusUnsubscribed {
+		t.Errorf("Status = %q, want %q", recovery.Status, models.CartRecoveryStatusUnsubscribed)
+	}
+	if enabled, _ := tr.recoveries.RemindersEnabled(tr.user.ID); enabled {
+		t.Error("RemindersEnabled() = true after unsubscribing")
+	}
+
+	// 退訂後即使購物車再次閒置也不提醒
+	if _, err := tr.carts.AddItem(tr.user.ID, "", 2, 1, tr.priceList); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	tr.abandoned = time.Now()
+	tr.after(2 * time.Hour)
+	tr.remind(t, 0)
+
+	if err := tr.recoveries.SetReminders(tr.user.ID, true); err != nil {
+		t.Fatalf("SetReminders() error = %v", err)
+	}
+	tr.remind(t, 1)
+}
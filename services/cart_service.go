@@ -0,0 +1,408 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+)
+
+// maxCartLineQuantity caps a single cart line
+const maxCartLineQuantity = 99
+
+// Ways of resolving a product that is in both the guest and the user cart
+// when a guest signs in
+const (
+	CartMergeSum   = "sum"
+	CartMergeMax   = "max"
+	CartMergeUser  = "user"
+	CartMergeGuest = "guest"
+)
+
+var (
+	ErrCartNotFound     = errors.New("cart not found")
+	ErrCartItemNotFound = errors.New("product is not in the cart")
+	ErrQuantityTooLarge = errors.New("quantity exceeds the per-line limit")
+)
+
+type CartService struct {
+	cartRepo         repository.CartRepository
+	productRepo      repository.ProductRepository
+	inventoryService *InventoryService
+	pricingService   *PricingService
+	discounter       CartDiscounter
+	mergeStrategy    string
+}
+
+// NewCartService creates a cart service. A nil discounter prices carts
+// without promotions.
+func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository, inventoryService *InventoryService, pricingService *PricingService, discounter CartDiscounter) *CartService {
+	strategy := strings.ToLower(os.Getenv("CART_MERGE_STRATEGY"))
+	switch strategy {
+	case CartMergeSum, CartMergeMax, CartMergeUser, CartMergeGuest:
+	default:
+		strategy = CartMergeSum
+	}
+	return &CartService{
+		cartRepo:         cartRepo,
+		productRepo:      productRepo,
+		inventoryService: inventoryService,
+		pricingService:   pricingService,
+		discounter:       discounter,
+		mergeStrategy:    strategy,
+	}
+}
+
+// find returns the cart of a user, or of a guest token when userID is zero
+func (s *CartService) find(userID uint, token string) (*models.Cart, error) {
+	if userID != 0 {
+		return s.cartRepo.FindByUserID(userID)
+	}
+	if token == "" {
+		return nil, ErrCartNotFound
+	}
+	return s.cartRepo.FindByToken(token)
+}
+
+// findOrCreate returns the cart of a user or guest, creating it on first
+// use. Guests without a valid token get a new cart and token.
+func (s *CartService) findOrCreate(userID uint, token string) (*models.Cart, error) {
+	if cart, err := s.find(userID, token); err == nil {
+		return cart, nil
+	}
+
+	cart := &models.Cart{}
+	if userID != 0 {
+		cart.UserID = &userID
+	} else {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		newToken := hex.EncodeToString(b)
+		cart.Token = &newToken
+	}
+	if err := s.cartRepo.Create(cart); err != nil {
+		// 同一使用者同時建立購物車時，改用已存在的那一台
+		if userID != 0 {
+			if existing, findErr := s.cartRepo.FindByUserID(userID); findErr == nil {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	return cart, nil
+}
+
+// Get returns a priced cart. A user or guest without a cart gets an empty one.
+func (s *CartService) Get(userID uint, token string, priceList *models.PriceList) (*models.Cart, error) {
+	cart, err := s.find(userID, token)
+	if err != nil {
+		cart = &models.Cart{Items: []models.CartItem{}}
+	}
+	if err := s.price(cart, priceList); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// price attaches products and prices to the lines of a cart, flags the
+// lines that cannot be checked out and applies promotions
+func (s *CartService) price(cart *models.Cart, priceList *models.PriceList) error {
+	if len(cart.Items) == 0 {
+		cart.Items = []models.CartItem{}
+		return nil
+	}
+
+	ids := make([]uint, len(cart.Items))
+	for i, item := range cart.Items {
+		ids[i] = item.ProductID
+	}
+	products, err := s.productRepo.FindByIDs(ids)
+	if err != nil {
+		return err
+	}
+	if err := s.pricingService.AttachPrices(priceList, products); err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	now := time.Now()
+	var subtotal *money.Money
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		product, exists := byID[item.ProductID]
+		if !exists || !product.IsVisible(now) {
+			item.Issue = models.CartIssueUnavailable
+			continue
+		}
+		item.Product = &product
+		if available, err := s.inventoryService.Available(product.ID); err != nil {
+			return err
+		} else if available < item.Quantity {
+			item.Issue = models.CartIssueInsufficientStock
+		}
+		if product.Price == nil {
+			item.Issue = models.CartIssueNoPrice
+			continue
+		}
+
+		unit := *product.Price
+		total := unit.Mul(int64(item.Quantity))
+		item.UnitPrice = &unit
+		item.LineTotal = &total
+		if subtotal == nil {
+			subtotal = &total
+		} else {
+			sum := subtotal.Add(total)
+			subtotal = &sum
+		}
+	}
+	cart.Subtotal = subtotal
+	if s.discounter == nil {
+		return nil
+	}
+	return s.discounter.ApplyPromotions(cart)
+}
+
+// validate checks that quantity of a product can go in a cart priced with
+// priceList. A nil priceList skips the price check.
+func (s *CartService) validate(productID uint, quantity int, priceList *models.PriceList) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if quantity > maxCartLineQuantity {
+		return ErrQuantityTooLarge
+	}
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil || !product.IsVisible(time.Now()) {
+		return ErrProductNotFound
+	}
+	if priceList != nil {
+		if _, err := s.pricingService.PriceFor(priceList, productID); err != nil {
+			return err
+		}
+	}
+	available, err := s.inventoryService.Available(productID)
+	if err != nil {
+		return err
+	}
+	if available < quantity {
+		return &repository.InsufficientStockError{ProductID: productID, Requested: quantity, Available: available}
+	}
+	return nil
+}
+
+// AddItem adds quantity of a product to the cart of a user or guest,
+// on top of what is already there
+func (s *CartService) AddItem(userID uint, token string, productID uint, quantity int, priceList *models.PriceList) (*models.Cart, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	cart, err := s.findOrCreate(userID, token)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range cart.Items {
+		if item.ProductID == productID {
+			quantity += item.Quantity
+		}
+	}
+	if err := s.validate(productID, quantity, priceList); err != nil {
+		return nil, err
+	}
+	if err := s.cartRepo.SetItem(cart.ID, productID, quantity); err != nil {
+		return nil, err
+	}
+	return s.reload(cart, priceList)
+}
+
+// AddForUser implements CartAdder
+func (s *CartService) AddForUser(userID, productID uint, quantity int, priceList *models.PriceList) error {
+	_, err := s.AddItem(userID, "", productID, quantity, priceList)
+	return err
+}
+
+// UpdateItem sets the quantity of a cart line; zero removes it
+func (s *CartService) UpdateItem(userID uint, token string, productID uint, quantity int, priceList *models.PriceList) (*models.Cart, error) {
+	if quantity == 0 {
+		return s.RemoveItem(userID, token, productID, priceList)
+	}
+	cart, err := s.find(userID, token)
+	if err != nil {
+		return nil, ErrCartNotFound
+	}
+	if !hasCartItem(cart, productID) {
+		return nil, ErrCartItemNotFound
+	}
+	if err := s.validate(productID, quantity, priceList); err != nil {
+		return nil, err
+	}
+	if err := s.cartRepo.SetItem(cart.ID, productID, quantity); err != nil {
+		return nil, err
+	}
+	return s.reload(cart, priceList)
+}
+
+func (s *CartService) RemoveItem(userID uint, token string, productID uint, priceList *models.PriceList) (*models.Cart, error) {
+	cart, err := s.find(userID, token)
+	if err != nil {
+		return nil, ErrCartNotFound
+	}
+	if !hasCartItem(cart, productID) {
+		return nil, ErrCartItemNotFound
+	}
+	if err := s.cartRepo.RemoveItem(cart.ID, productID); err != nil {
+		return nil, err
+	}
+	return s.reload(cart, priceList)
+}
+
+// ApplyCoupon enters a coupon code on the cart of a user or guest,
+// replacing any earlier one
+func (s *CartService) ApplyCoupon(userID uint, token, code string, priceList *models.PriceList) (*models.Cart, error) {
+	if s.discounter == nil {
+		return nil, ErrCouponNotFound
+	}
+	coupon, err := s.discounter.CheckCoupon(code, userID)
+	if err != nil {
+		return nil, err
+	}
+	cart, err := s.findOrCreate(userID, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cartRepo.SetCoupon(cart.ID, coupon.Code); err != nil {
+		return nil, err
+	}
+	return s.reload(cart, priceList)
+}
+
+func (s *CartService) RemoveCoupon(userID uint, token string, priceList *models.PriceList) (*models.Cart, error) {
+	cart, err := s.find(userID, token)
+	if err != nil {
+		return nil, ErrCartNotFound
+	}
+	if err := s.cartRepo.SetCoupon(cart.ID, ""); err != nil {
+		return nil, err
+	}
+	return s.reload(cart, priceList)
+}
+
+// Clear empties the cart. Having no cart at all counts as empty.
+func (s *CartService) Clear(userID uint, token string) error {
+	cart, err := s.find(userID, token)
+	if err != nil {
+		return nil
+	}
+	return s.cartRepo.Clear(cart.ID)
+}
+
+func (s *CartService) reload(cart *models.Cart, priceList *models.PriceList) (*models.Cart, error) {
+	var err error
+	if cart.UserID != nil {
+		cart, err = s.cartRepo.FindByUserID(*cart.UserID)
+	} else {
+		cart, err = s.cartRepo.FindByToken(*cart.Token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.price(cart, priceList); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func hasCartItem(cart *models.Cart, productID uint) bool {
+	for _, item := range cart.Items {
+		if item.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeQuantity resolves a product found in both carts. userQty is zero
+// when only the guest cart has it.
+func (s *CartService) mergeQuantity(userQty, guestQty int) int {
+	if userQty == 0 {
+		return guestQty
+	}
+	switch s.mergeStrategy {
+	case CartMergeMax:
+		if guestQty > userQty {
+			return guestQty
+		}
+		return userQty
+	case CartMergeUser:
+		return userQty
+	case CartMergeGuest:
+		return guestQty
+	}
+	return userQty + guestQty
+}
+
+// MergeGuestCart moves the guest cart of token into the user's cart and
+// deletes it, resolving duplicate lines with the configured strategy.
+// Merged lines are capped by the line limit and by available stock. Only
+// the guest strategy can lower what the user's cart already held, since it
+// takes the guest's quantity. It implements GuestCartMerger.
+func (s *CartService) MergeGuestCart(userID uint, token string) error {
+	guest, err := s.cartRepo.FindByToken(token)
+	if err != nil {
+		// 訪客購物車已合併或過期時不需處理
+		return nil
+	}
+	cart, err := s.findOrCreate(userID, "")
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[uint]int, len(cart.Items))
+	for _, item := range cart.Items {
+		existing[item.ProductID] = item.Quantity
+	}
+	now := time.Now()
+	quantities := make(map[uint]int, len(guest.Items))
+	for _, item := range guest.Items {
+		userQty := existing[item.ProductID]
+		quantity := s.mergeQuantity(userQty, item.Quantity)
+		if quantity > maxCartLineQuantity {
+			quantity = maxCartLineQuantity
+		}
+		if product, err := s.productRepo.FindByID(item.ProductID); err != nil || !product.IsVisible(now) {
+			continue
+		}
+		available, err := s.inventoryService.Available(item.ProductID)
+		if err != nil {
+			return err
+		}
+		if quantity > available {
+			quantity = available
+		}
+		if quantity < userQty && s.mergeStrategy != CartMergeGuest {
+			quantity = userQty
+		}
+		if quantity > 0 && quantity != userQty {
+			quantities[item.ProductID] = quantity
+		}
+	}
+	if err := s.cartRepo.Merge(guest.ID, cart.ID, quantities); err != nil {
+		return err
+	}
+	// 使用者購物車沒有優惠券時沿用訪客輸入的
+	if guest.CouponCode != "" && cart.CouponCode == "" {
+		return s.cartRepo.SetCoupon(cart.ID, guest.CouponCode)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+func newTestCartService(t *testing.T) (*CartService, *InventoryService, *models.PriceList) {
+	t.Helper()
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee", Status: models.ProductStatusPublished})
+	productRepo.Create(&models.Product{ID: 2, SKU: "SKU-2", Name: "Tea", Status: models.ProductStatusPublished})
+	productRepo.Create(&models.Product{ID: 3, SKU: "SKU-3", Name: "Unpriced", Status: models.ProductStatusPublished})
+
+	pricingService := NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	priceList, err := pricingService.CreatePriceList("TW-TWD", "Taiwan", "TWD", "TW", true, true)
+	if err != nil {
+		t.Fatalf("CreatePriceList() error = %v", err)
+	}
+	pricingService.SetPrice(priceList.ID, 1, "450")
+	pricingService.SetPrice(priceList.ID, 2, "300")
+
+	inventoryService := NewInventoryService(repository.NewMockInventoryRepository(), productRepo, nil)
+	inventoryService.CreateWarehouse("TPE", "Taipei")
+	receive(t, inventoryService, 1, 1, 10)
+	receive(t, inventoryService, 2, 1, 5)
+	receive(t, inventoryService, 3, 1, 5)
+
+	return NewCartService(repository.NewMockCartRepository(), productRepo, inventoryService, pricingService, nil), inventoryService, priceList
+}
+
+func TestGuestCart(t *testing.T) {
+	s, _, priceList := newTestCartService(t)
+
+	cart, err := s.AddItem(0, "", 1, 2, priceList)
+	if err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	if cart.Token == nil || len(*cart.Token) != 64 {
+		t.Fatalf("guest cart token = %v, want a 64 character token", cart.Token)
+	}
+	token := *cart.Token
+
+	cart, err = s.AddItem(0, token, 1, 1, priceList)
+	if err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	cart, _ = s.AddItem(0, token, 2, 1, priceList)
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 3 {
+		t.Fatalf("items = %+v, want 3 coffee and 1 tea", cart.Items)
+	}
+	if cart.Subtotal == nil || cart.Subtotal.Amount != 165000 {
+		t.Errorf("Subtotal = %v, want 1650", cart.Subtotal)
+	}
+
+	// 其他訪客看不到這台購物車
+	if other, _ := s.Get(0, "unknown", priceList); len(other.Items) != 0 {
+		t.Errorf("Get() with an unknown token = %+v, want an empty cart", other)
+	}
+}
+
+func TestCartValidation(t *testing.T) {
+	s, _, priceList := newTestCartService(t)
+
+	tests := []struct {
+		name      string
+		productID uint
+		quantity  int
+		wantErr   error
+	}{
+		{"unknown product", 9, 1, ErrProductNotFound},
+		{"no price in this currency", 3, 1, ErrPriceNotFound},
+		{"more than in stock", 2, 6, ErrInsufficientStock},
+		{"over the line limit", 1, maxCartLineQuantity + 1, ErrQuantityTooLarge},
+		{"zero quantity", 1, 0, ErrInvalidQuantity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.AddItem(1, "", tt.productID, tt.quantity, priceList)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddItem() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := s.UpdateItem(1, "", 2, 1, priceList); !errors.Is(err, ErrCartItemNotFound) {
+		t.Errorf("UpdateItem() error = %v, want %v", err, ErrCartItemNotFound)
+	}
+}
+
+func TestCartFlagsIssues(t *testing.T) {
+	s, inventoryService, priceList := newTestCartService(t)
+	s.AddItem(1, "", 2, 4, priceList)
+
+	// 加入購物車後庫存減少
+	if _, err := inventoryService.RecordMovement(1, 2, 1, models.MovementAdjust, -3, "", ""); err != nil {
+		t.Fatalf("RecordMovement() error = %v", err)
+	}
+	cart, _ := s.Get(1, "", priceList)
+	if cart.Items[0].Issue != models.CartIssueInsufficientStock {
+		t.Errorf("Issue = %q, want %q", cart.Items[0].Issue, models.CartIssueInsufficientStock)
+	}
+
+	cart, err := s.UpdateItem(1, "", 2, 0, priceList)
+	if err != nil || len(cart.Items) != 0 || cart.Subtotal != nil {
+		t.Errorf("UpdateItem() to zero = %+v, %v, want an empty cart", cart, err)
+	}
+}
+
+func TestMergeGuestCart(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     int
+	}{
+		{CartMergeSum, 5},
+		{CartMergeMax, 3},
+		{CartMergeUser, 2},
+		{CartMergeGuest, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			s, _, priceList := newTestCartService(t)
+			s.mergeStrategy = tt.strategy
+
+			s.AddItem(1, "", 1, 2, priceList)
+			guest, _ := s.AddItem(0, "", 1, 3, priceList)
+			s.AddItem(0, *guest.Token, 2, 5, priceList)
+
+			if err := s.MergeGuestCart(1, *guest.Token); err != nil {
+				t.Fatalf("MergeGuestCart() error = %v", err)
+			}
+			cart, _ := s.Get(1, "", priceList)
+			if len(cart.Items) != 2 || cart.Items[0].Quantity != tt.want || cart.Items[1].Quantity != 5 {
+				t.Errorf("items = %+v, want %d coffee and 5 tea", cart.Items, tt.want)
+			}
+			if _, err := s.cartRepo.FindByToken(*guest.Token); err == nil {
+				t.Errorf("guest cart still exists after merging")
+			}
+		})
+	}
+}
+
+func TestMergeGuestStrategyLowersQuantity(t *testing.T) {
+	s, _, priceList := newTestCartService(t)
+	s.mergeStrategy = CartMergeGuest
+	s.AddItem(1, "", 1, 4, priceList)
+	guest, _ := s.AddItem(0, "", 1, 1, priceList)
+
+	if err := s.MergeGuestCart(1, *guest.Token); err != nil {
+		t.Fatalf("MergeGuestCart() error = %v", err)
+	}
+	cart, _ := s.Get(1, "", priceList)
+	if len(cart.Items) != 1 || cart.Items[0].Quantity != 1 {
+		t.Errorf("items = %+v, want the guest's 1 coffee", cart.Items)
+	}
+}
+
+func TestMergeCapsAtStock(t *testing.T) {
+	s, _, priceList := newTestCartService(t)
+	s.AddItem(1, "", 2, 4, priceList)
+	guest, _ := s.AddItem(0, "", 2, 4, priceList)
+
+	s.MergeGuestCart(1, *guest.Token)
+	cart, _ := s.Get(1, "", priceList)
+	if cart.Items[0].Quantity != 5 {
+		t.Errorf("Quantity = %d, want 5 in stock", cart.Items[0].Quantity)
+	}
+}
+
+func TestLoginMergesGuestCart(t *testing.T) {
+	s, _, priceList := newTestCartService(t)
+	userRepo := NewMockUserRepository()
+	user := &models.User{ID: 7, Name: "Test User", Email: "test@example.com", Password: "password123"}
+	userRepo.Create(user)
+	authService := NewAuthService(userRepo, s)
+
+	guest, _ := s.AddItem(0, "", 1, 2, priceList)
+	if _, _, err := authService.Login("test@example.com", "password123", *guest.Token); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	cart, _ := s.Get(7, "", priceList)
+	if len(cart.Items) != 1 || cart.Items[0].ProductID != 1 || cart.Items[0].Quantity != 2 {
+		t.Errorf("user cart = %+v, want the guest's coffee", cart.Items)
+	}
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/storage"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+
+	importBatchSize = 200
+	exportBatchSize = 500
+)
+
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrUnsupportedFormat = errors.New("unsupported format, use csv or json")
+
+	errMissingColumns = errors.New("csv header must contain sku and name columns")
+)
+
+var catalogColumns = []string{"sku", "name", "description", "category"}
+
+// importPollInterval bounds how long a queued job waits if a wake-up is missed
+const importPollInterval = 30 * time.Second
+
+// CatalogRow is the flat product representation used for import and export
+type CatalogRow struct {
+	SKU         string `json:"sku"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+type CatalogService struct {
+	productRepo  repository.ProductRepository
+	revisionRepo repository.ProductRevisionRepository
+	jobRepo      repository.ImportJobRepository
+	storage      storage.Storage
+	wake         chan struct{}
+}
+
+func NewCatalogService(productRepo repository.ProductRepository, revisionRepo repository.ProductRevisionRepository, jobRepo repository.ImportJobRepository, store storage.Storage) *CatalogService {
+	return &CatalogService{
+		productRepo:  productRepo,
+		revisionRepo: revisionRepo,
+		jobRepo:      jobRepo,
+		storage:      store,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// DetectFormat picks the import format from an explicit value or the file extension
+func DetectFormat(format, filename string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// StartImport stores the uploaded file and queues a job to process it
+func (s *CatalogService) StartImport(ctx context.Context, userID uint, filename, format string, r io.Reader, size int64) (*models.ImportJob, error) {
+	format, err := DetectFormat(format, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.ImportJob{
+		UserID:   userID,
+		Format:   format,
+		Filename: filepath.Base(filename),
+		Status:   models.ImportStatusPending,
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, errors.New("failed to create import job")
+	}
+
+	job.StorageKey = fmt.Sprintf("imports/%d.%s", job.ID, format)
+	if err := s.storage.Put(ctx, job.StorageKey, r, size, "application/octet-stream"); err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Error = "failed to store upload"
+		s.jobRepo.Update(job)
+		return nil, err
+	}
+	if err := s.jobRepo.Update(job); err != nil {
+		return nil, errors.New("failed to create import job")
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (s *CatalogService) GetJob(id uint) (*models.ImportJob, error) {
+	job, err := s.jobRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+// WriteErrorReport writes the rejected rows of a job as CSV
+func (s *CatalogService) WriteErrorReport(jobID uint, w io.Writer) error {
+	if _, err := s.GetJob(jobID); err != nil {
+		return err
+	}
+	rowErrors, err := s.jobRepo.FindRowErrors(jobID)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"row", "sku", "field", "message"})
+	for _, e := range rowErrors {
+		cw.Write([]string{fmt.Sprint(e.Row), e.SKU, e.Field, e.Message})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Run processes queued import jobs until ctx is cancelled
+func (s *CatalogService) Run(ctx context.Context) {
+	// 服務重啟時，將中斷的工作重新排入佇列
+	if running, err := s.jobRepo.FindByStatus(models.ImportStatusRunning); err == nil {
+		for i := range running {
+			running[i].Status = models.ImportStatusPending
+			s.jobRepo.Update(&running[i])
+		}
+	}
+
+	ticker := time.NewTicker(importPollInterval)
+	defer ticker.Stop()
+	for {
+		jobs, err := s.jobRepo.FindByStatus(models.ImportStatusPending)
+		if err != nil {
+			log.Printf("catalog import: failed to load pending jobs: %v", err)
+		}
+		for _, job := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := s.processJob(ctx, job.ID); err != nil {
+				log.Printf("catalog import: job %d failed: %v", job.ID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *CatalogService) processJob(ctx context.Context, jobID uint) error {
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.Status = models.ImportStatusRunning
+	job.StartedAt = &now
+	job.TotalRows, job.ImportedRows, job.FailedRows = 0, 0, 0
+	if err := s.jobRepo.DeleteRowErrors(job.ID); err != nil {
+		return err
+	}
+	if err := s.jobRepo.Update(job); err != nil {
+		return err
+	}
+
+	err = s.importFile(ctx, job)
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Status = models.ImportStatusCompleted
+	if err != nil {
+		job.Status = models.ImportStatusFailed
+		job.Error = err.Error()
+	}
+	if updateErr := s.jobRepo.Update(job); updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+type pendingRow struct {
+	row     int
+	product *models.Product
+}
+
+func (s *CatalogService) importFile(ctx context.Context, job *models.ImportJob) error {
+	rc, err := s.storage.Get(ctx, job.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer rc.Close()
+
+	seen := make(map[string]int)
+	var batch []pendingRow
+	var rowErrors []models.ImportRowError
+
+	flush := func() error {
+		if len(batch) > 0 {
+			products := make([]*models.Product, len(batch))
+			for i, p := range batch {
+				products[i] = p.product
+			}
+			// 整批連同版本紀錄在同一交易中寫入，失敗時整批標記為錯誤
+			revision := models.ProductRevision{
+				Action:   models.RevisionActionImport,
+				AuthorID: &job.UserID,
+				Note:     fmt.Sprintf("Import job %d", job.ID),
+			}
+			if err := s.revisionRepo.UpsertBySKU(products, revision); err != nil {
+				for _, p := range batch {
+					rowErrors = append(rowErrors, models.ImportRowError{
+						JobID: job.ID, Row: p.row, SKU: p.product.SKU, Message: "failed to save: " + err.Error(),
+					})
+				}
+				job.FailedRows += len(batch)
+			} else {
+				job.ImportedRows += len(batch)
+			}
+			batch = batch[:0]
+		}
+
+		if err := s.jobRepo.AddRowErrors(rowErrors); err != nil {
+			return err
+		}
+		rowErrors = rowErrors[:0]
+		return s.jobRepo.Update(job)
+	}
+
+	handle := func(row int, r CatalogRow, parseErr error) {
+		job.TotalRows++
+		errs := validateCatalogRow(r, parseErr)
+		if prev, dup := seen[r.SKU]; dup && len(errs) == 0 {
+			errs = append(errs, models.ImportRowError{Field: "sku", Message: fmt.Sprintf("duplicate of row %d", prev)})
+		}
+		if len(errs) > 0 {
+			for _, e := range errs {
+				e.JobID, e.Row, e.SKU = job.ID, row, r.SKU
+				rowErrors = append(rowErrors, e)
+			}
+			job.FailedRows++
+			return
+		}
+
+		seen[r.SKU] = row
+		batch = append(batch, pendingRow{row: row, product: &models.Product{
+			SKU: r.SKU, Name: r.Name, Description: r.Description, Category: normalizeCategory(r.Category),
+		}})
+	}
+
+	each := readCSVRows
+	if job.Format == FormatJSON {
+		each = readJSONRows
+	}
+	err = each(rc, func(row int, r CatalogRow, parseErr error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		handle(row, r, parseErr)
+		if len(batch) >= importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+func validateCatalogRow(r CatalogRow, parseErr error) []models.ImportRowError {
+	if parseErr != nil {
+		return []models.ImportRowError{{Message: parseErr.Error()}}
+	}
+
+	var errs []models.ImportRowError
+	switch {
+	case r.SKU == "":
+		errs = append(errs, models.ImportRowError{Field: "sku", Message: "sku is required"})
+	case len(r.SKU) > 64:
+		errs = append(errs, models.ImportRowError{Field: "sku", Message: "sku must be at most 64 characters"})
+	case strings.IndexFunc(r.SKU, unicode.IsSpace) >= 0:
+		errs = append(errs, models.ImportRowError{Field: "sku", Message: "sku must not contain whitespace"})
+	}
+	if r.Name == "" {
+		errs = append(errs, models.ImportRowError{Field: "name", Message: "name is required"})
+	} else if len(r.Name) > 255 {
+		errs = append(errs, models.ImportRowError{Field: "name", Message: "name must be at most 255 characters"})
+	}
+	if len(r.Category) > 64 {
+		errs = append(errs, models.ImportRowError{Field: "category", Message: "category must be at most 64 characters"})
+	}
+	return errs
+}
+
+type rowFunc func(row int, r CatalogRow, parseErr error) error
+
+// readCSVRows calls fn for every data row; row numbers count the header as row 1
+func readCSVRows(r io.Reader, fn rowFunc) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := index["sku"]; !ok {
+		return errMissingColumns
+	}
+	if _, ok := index["name"]; !ok {
+		return errMissingColumns
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(row, CatalogRow{}, parseErr.Err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(row, CatalogRow{
+			SKU:         field(record, "sku"),
+			Name:        field(record, "name"),
+			Description: field(record, "description"),
+			Category:    field(record, "category"),
+		}, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// readJSONRows streams a top-level JSON array; row numbers start at 1
+func readJSONRows(r io.Reader, fn rowFunc) error {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return errors.New("json import must be an array of products")
+	}
+
+	for row := 1; dec.More(); row++ {
+		var item CatalogRow
+		err := dec.Decode(&item)
+		var typeErr *json.UnmarshalTypeError
+		if err != nil && !errors.As(err, &typeErr) {
+			return fmt.Errorf("invalid json at item %d: %w", row, err)
+		}
+		if typeErr != nil {
+			err = fmt.Errorf("field %s has the wrong type", typeErr.Field)
+		}
+
+		item.SKU = strings.TrimSpace(item.SKU)
+		item.Name = strings.TrimSpace(item.Name)
+		if err := fn(row, item, err); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// Export streams the whole catalog to w without loading it into memory
+func (s *CatalogService) Export(format string, w io.Writer) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(catalogColumns); err != nil {
+			return err
+		}
+		err := s.productRepo.FindInBatches(exportBatchSize, func(products []models.Product) error {
+			for _, p := range products {
+				if err := cw.Write([]string{p.SKU, p.Name, p.Description, p.Category}); err != nil {
+					return err
+				}
+			}
+			cw.Flush()
+			return cw.Error()
+		})
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case FormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return err
+		}
+		first := true
+		enc := json.NewEncoder(w)
+		err := s.productRepo.FindInBatches(exportBatchSize, func(products []models.Product) error {
+			for _, p := range products {
+				if !first {
+					if _, err := io.WriteString(w, ","); err != nil {
+						return err
+					}
+				}
+				first = false
+				if err := enc.Encode(CatalogRow{SKU: p.SKU, Name: p.Name, Description: p.Description, Category: p.Category}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "]\n")
+		return err
+	}
+	return ErrUnsupportedFormat
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/storage"
+)
+
+func newTestCatalogService(t *testing.T) (*CatalogService, repository.ProductRepository) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir(), "/api/v1/media", "", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	productRepo := repository.NewMockProductRepository()
+	return NewCatalogService(productRepo, repository.NewMockProductRevisionRepository(productRepo), repository.NewMockImportJobRepository(), store), productRepo
+}
+
+func runImport(t *testing.T, s *CatalogService, filename, content string) *models.ImportJob {
+	t.Helper()
+	ctx := context.Background()
+	job, err := s.StartImport(ctx, 1, filename, "", strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("StartImport() error = %v", err)
+	}
+	if job.Status != models.ImportStatusPending {
+		t.Errorf("StartImport() status = %s, want pending", job.Status)
+	}
+	s.processJob(ctx, job.ID)
+
+	job, err = s.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	return job
+}
+
+func TestCatalogImportCSV(t *testing.T) {
+	catalogService, productRepo := newTestCatalogService(t)
+	productRepo.Create(&models.Product{SKU: "A-1", Name: "Old name"})
+
+	csvData := "sku,name,description\n" +
+		"A-1,New name,updated\n" +
+		"B-2,Second,\n" +
+		",Missing sku,\n" +
+		"C 3,Bad sku,\n" +
+		"B-2,Duplicate,\n" +
+		"D-4,\n"
+
+	job := runImport(t, catalogService, "catalog.csv", csvData)
+	if job.Status != models.ImportStatusCompleted {
+		t.Fatalf("job status = %s (%s), want completed", job.Status, job.Error)
+	}
+	if job.TotalRows != 6 || job.ImportedRows != 2 || job.FailedRows != 4 {
+		t.Errorf("job counters = %d/%d/%d, want 6/2/4", job.TotalRows, job.ImportedRows, job.FailedRows)
+	}
+
+	updated, _ := productRepo.FindBySKU("A-1")
+	if updated == nil || updated.Name != "New name" || updated.Description != "updated" {
+		t.Errorf("existing product not updated: %+v", updated)
+	}
+	if _, err := productRepo.FindBySKU("B-2"); err != nil {
+		t.Errorf("new product not created: %v", err)
+	}
+	revisions, _ := catalogService.revisionRepo.FindByProductID(updated.ID, 0, 10)
+	if len(revisions) != 1 || revisions[0].Action != models.RevisionActionImport || revisions[0].Snapshot.Name != "New name" {
+		t.Errorf("revisions = %+v, want one import revision of the new name", revisions)
+	}
+
+	var report bytes.Buffer
+	if err := catalogService.WriteErrorReport(job.ID, &report); err != nil {
+		t.Fatalf("WriteErrorReport() error = %v", err)
+	}
+	for _, want := range []string{"4,,sku,sku is required", "5,C 3,sku,", "6,B-2,sku,duplicate of row 3", "7,D-4,name,name is required"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("error report missing %q:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestCatalogImportJSON(t *testing.T) {
+	catalogService, productRepo := newTestCatalogService(t)
+
+	job := runImport(t, catalogService, "catalog.json",
+		`[{"sku":"J-1","name":"Json product"},{"sku":2,"name":"Wrong type"},{"sku":"J-3","name":"Third"}]`)
+	if job.Status != models.ImportStatusCompleted || job.ImportedRows != 2 || job.FailedRows != 1 {
+		t.Errorf("job = %s %d/%d, want completed 2/1", job.Status, job.ImportedRows, job.FailedRows)
+	}
+	if _, err := productRepo.FindBySKU("J-3"); err != nil {
+		t.Errorf("product after bad row not imported: %v", err)
+	}
+
+	job = runImport(t, catalogService, "broken.json", `{"sku":"X"}`)
+	if job.Status != models.ImportStatusFailed {
+		t.Errorf("job status = %s, want failed", job.Status)
+	}
+
+	if _, err := catalogService.StartImport(context.Background(), 1, "catalog.xlsx", "", strings.NewReader(""), 0); err != ErrUnsupportedFormat {
+		t.Errorf("StartImport() error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestCatalogExport(t *testing.T) {
+	catalogService, productRepo := newTestCatalogService(t)
+	productRepo.Create(&models.Product{SKU: "A-1", Name: "First", Description: "with, comma", Category: "coffee"})
+	productRepo.Create(&models.Product{SKU: "B-2", Name: "Second"})
+
+	var csvOut bytes.Buffer
+	if err := catalogService.Export(FormatCSV, &csvOut); err != nil {
+		t.Fatalf("Export(csv) error = %v", err)
+	}
+	want := "sku,name,description,category\nA-1,First,\"with, comma\",coffee\nB-2,Second,,\n"
+	if csvOut.String() != want {
+		t.Errorf("Export(csv) = %q, want %q", csvOut.String(), want)
+	}
+
+	var jsonOut bytes.Buffer
+	if err := catalogService.Export(FormatJSON, &jsonOut); err != nil {
+		t.Fatalf("Export(json) error = %v", err)
+	}
+	var rows []CatalogRow
+	if err := json.Unmarshal(jsonOut.Bytes(), &rows); err != nil {
+		t.Fatalf("Export(json) produced invalid JSON: %v", err)
+	}
+	if len(rows) != 2 || rows[1].SKU != "B-2" {
+		t.Errorf("Export(json) = %+v", rows)
+	}
+}
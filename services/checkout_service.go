@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+)
+
+const (
+	defaultPaymentWindow = 30 * time.Minute
+	maxIdempotencyKeyLen = 255
+)
+
+var (
+	ErrIdempotencyKeyRequired = errors.New("Idempotency-Key header is required")
+	ErrIdempotencyKeyReused   = errors.New("Idempotency-Key was already used for a different checkout")
+	ErrCartEmpty              = errors.New("cart is empty")
+	ErrCartInvalid            = errors.New("cart has items that cannot be checked out")
+)
+
+// CheckoutDetails is what the customer enters at checkout. Addresses are
+// either entered inline or picked from the address book by ID; without a
+// shipping address the default one is used, and without a billing address
+// the default billing address or else the shipping address. ShippingMethodID
+// is one of the methods quoted for the cart and shipping address. EInvoice
+// is how orders shipped in Taiwan take their e-invoice.
+type CheckoutDetails struct {
+	ShippingAddress   models.Address
+	ShippingAddressID uint
+	BillingAddress    *models.Address
+	BillingAddressID  uint
+	ShippingMethodID  uint
+	EInvoice          models.EInvoiceBuyer
+	Note              string
+}
+
+// ConversionTracker is told when a cart has been turned into an order
+type ConversionTracker interface {
+	CartConverted(cartID uint, order *models.Order)
+}
+
+// CheckoutService turns carts into orders
+type CheckoutService struct {
+	orderRepo       repository.OrderRepository
+	cartService     *CartService
+	addressService  *AddressService
+	shippingService *ShippingService
+	taxService      *TaxService
+	observer        StockObserver
+	tracker         ConversionTracker
+	paymentWindow   time.Duration
+	now             func() time.Time
+}
+
+// NewCheckoutService creates a checkout. Without a shipping or tax service
+// orders are placed without shipping charges or tax, and without an address
+// service addresses must be entered inline.
+func NewCheckoutService(orderRepo repository.OrderRepository, cartService *CartService, addressService *AddressService, shippingService *ShippingService, taxService *TaxService, observer StockObserver, tracker ConversionTracker) *CheckoutService {
+	window := defaultPaymentWindow
+	if v, err := time.ParseDuration(os.Getenv("CHECKOUT_PAYMENT_WINDOW")); err == nil && v > 0 {
+		window = v
+	}
+	return &CheckoutService{
+		orderRepo:       orderRepo,
+		cartService:     cartService,
+		addressService:  addressService,
+		shippingService: shippingService,
+		taxService:      taxService,
+		observer:        observer,
+		tracker:         tracker,
+		paymentWindow:   window,
+		now:             time.Now,
+	}
+}
+
+// hashCheckout fingerprints a checkout request so a reused idempotency key
+// with different details can be told apart from a retry
+func hashCheckout(details interface{}, currency string) string {
+	b, _ := json.Marshal(struct {
+		Details  interface{}
+		Currency string
+	}{details, currency})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func newOrderNumber(now time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ORD-%s-%s", now.Format("20060102"), strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+// replay returns the order already placed with key, if any
+func (s *CheckoutService) replay(userID uint, key, hash string) (*models.Order, error) {
+	order, err := s.orderRepo.FindByIdempotencyKey(userID, key)
+	if err != nil {
+		return nil, nil
+	}
+	if order.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return order, nil
+}
+
+// Checkout places an order for everything in the user's cart, priced with
+// priceList, and holds its stock until payment is due. Retrying with the
+// same idempotency key returns the original order and reports created as
+// false.
+func (s *CheckoutService) Checkout(user models.User, key string, details CheckoutDetails, priceList *models.PriceList) (order *models.Order, created bool, err error) {
+	key = strings.TrimSpace(key)
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return nil, false, ErrIdempotencyKeyRequired
+	}
+	hash := hashCheckout(details, priceList.Currency)
+	if existing, err := s.replay(user.ID, key, hash); err != nil || existing != nil {
+		return existing, false, err
+	}
+	if err := s.resolveAddresses(user.ID, &details); err != nil {
+		return nil, false, err
+	}
+	if details.EInvoice, err = normalizeEInvoiceBuyer(user, details.ShippingAddress.Country, details.EInvoice); err != nil {
+		return nil, false, err
+	}
+
+	order, err = s.place(user, key, hash, details, priceList)
+	if err != nil {
+		// 並行的重送可能已先建立訂單並清空購物車
+		if existing, replayErr := s.replay(user.ID, key, hash); replayErr != nil || existing != nil {
+			return existing, false, replayErr
+		}
+		return nil, false, err
+	}
+	return order, true, nil
+}
+
+// resolveAddresses fills in the addresses of a checkout from the user's
+// address book and checks them, so the order gets a copy of each
+func (s *CheckoutService) resolveAddresses(userID uint, details *CheckoutDetails) error {
+	shipping, err := s.savedAddress(userID, details.ShippingAddressID, AddressShipping)
+	if err != nil {
+		return err
+	}
+	switch {
+	case shipping != nil && details.ShippingAddressID != 0:
+		details.ShippingAddress = shipping.Address
+	case details.ShippingAddress == (models.Address{}):
+		if shipping == nil {
+			return ErrAddressRequired
+		}
+		details.ShippingAddress = shipping.Address
+	}
+	if details.ShippingAddress, err = NormalizeAddress(details.ShippingAddress); err != nil {
+		return err
+	}
+
+	if details.BillingAddress == nil || details.BillingAddressID != 0 {
+		billing, err := s.savedAddress(userID, details.BillingAddressID, AddressBilling)
+		if err != nil {
+			return err
+		}
+		if billing != nil {
+			details.BillingAddress = &billing.Address
+		} else {
+			details.BillingAddress = &details.ShippingAddress
+		}
+	}
+	billing, err := NormalizeAddress(*details.BillingAddress)
+	if err != nil {
+		return err
+	}
+	details.BillingAddress = &billing
+	return nil
+}
+
+// savedAddress returns the address book entry with id, or the user's
+// default of kind when id is 0. A missing default is not an error.
+func (s *CheckoutService) savedAddress(userID, id uint, kind string) (*models.CustomerAddress, error) {
+	if s.addressService == nil {
+		if id != 0 {
+			return nil, ErrAddressNotFound
+		}
+		return nil, nil
+	}
+	if id != 0 {
+		return s.addressService.Get(userID, id)
+	}
+	address, err := s.addressService.Default(userID, kind)
+	if errors.Is(err, ErrAddressNotFound) {
+		return nil, nil
+	}
+	return address, err
+}
+
+// place validates the user's cart and records the order, its stock
+// reservations and the emptied cart in one transaction
+func (s *CheckoutService) place(user models.User, key, hash string, details CheckoutDetails, priceList *models.PriceList) (*models.Order, error) {
+	cart, err := s.cartService.Get(user.ID, "", priceList)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Items) == 0 {
+		return nil, ErrCartEmpty
+	}
+	return s.placeCart(user, key, hash, details, priceList, cart)
+}
+
+// placeCart turns a priced cart into an order. A cart that was never
+// stored has no ID and nothing to empty.
+func (s *CheckoutService) placeCart(user models.User, key, hash string, details CheckoutDetails, priceList *models.PriceList, cart *models.Cart) (*models.Order, error) {
+	var issues []string
+	for _, item := range cart.Items {
+		if item.Issue != "" {
+			issues = append(issues, fmt.Sprintf("product %d: %s", item.ProductID, item.Issue))
+		}
+	}
+	if cart.CouponIssue != "" {
+		issues = append(issues, "coupon "+cart.CouponCode+": "+cart.CouponIssue)
+	}
+	if len(issues) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCartInvalid, strings.Join(issues, ", "))
+	}
+
+	now := s.now()
+	number, err := newOrderNumber(now)
+	if err != nil {
+		return nil, err
+	}
+	discount := money.Zero(priceList.Currency)
+	if cart.DiscountTotal != nil {
+		discount = *cart.DiscountTotal
+	}
+	order := &models.Order{
+		Number:          number,
+		UserID:          user.ID,
+		IdempotencyKey:  key,
+		RequestHash:     hash,
+		Status:          models.OrderStatusPendingPayment,
+		Email:           user.Email,
+		PriceListID:     priceList.ID,
+		Currency:        priceList.Currency,
+		Subtotal:        *cart.Subtotal,
+		DiscountTotal:   discount,
+		ShippingTotal:   money.Zero(priceList.Currency),
+		TaxTotal:        money.Zero(priceList.Currency),
+		RefundedTotal:   money.Zero(priceList.Currency),
+		ShippingAddress: details.ShippingAddress,
+		BillingAddress:  *details.BillingAddress,
+		EInvoice:        details.EInvoice,
+		Note:            strings.TrimSpace(details.Note),
+		PaymentDueAt:    now.Add(s.paymentWindow),
+		Promotions:      cart.Promotions,
+	}
+	items := make([]repository.ReservationItem, len(cart.Items))
+	for i, line := range cart.Items {
+		lineDiscount := money.Zero(priceList.Currency)
+		for _, d := range line.Discounts {
+			lineDiscount = lineDiscount.Add(d.Amount)
+		}
+		order.Items = append(order.Items, models.OrderItem{
+			ProductID:     line.ProductID,
+			SKU:           line.Product.SKU,
+			Name:          line.Product.Name,
+			Quantity:      line.Quantity,
+			UnitPrice:     *line.UnitPrice,
+			LineTotal:     *line.LineTotal,
+			DiscountTotal: lineDiscount,
+			TaxClass:      line.Product.TaxClass,
+			TaxTotal:      money.Zero(priceList.Currency),
+		})
+		items[i] = repository.ReservationItem{ProductID: line.ProductID, Quantity: line.Quantity}
+	}
+	ctx := context.Background()
+	if s.shippingService != nil {
+		quote, err := s.shippingService.QuoteMethod(ctx, cart, details.ShippingAddress, details.ShippingMethodID)
+		if err != nil {
+			return nil, err
+		}
+		order.ShippingTotal = quote.Amount
+		order.ShippingMethod = quote.Name
+	}
+	if s.taxService != nil {
+		if err := s.taxService.ApplyToOrder(ctx, order, priceList.Market); err != nil {
+			return nil, err
+		}
+	}
+	order.Total = order.Subtotal.Sub(order.DiscountTotal).Add(order.ShippingTotal)
+	if !order.TaxInclusive {
+		order.Total = order.Total.Add(order.TaxTotal)
+	}
+
+	reservations, err := s.orderRepo.Place(order, items, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.observer != nil {
+		seen := make(map[uint]bool)
+		for _, res := range reservations {
+			if !seen[res.ProductID] {
+				seen[res.ProductID] = true
+				s.observer.StockChanged(res.ProductID)
+			}
+		}
+	}
+	if s.tracker != nil && cart.ID != 0 {
+		s.tracker.CartConverted(cart.ID, order)
+	}
+	return order, nil
+}
+
+// RenewalDetails describes an order placed for a subscription. The
+// subscription discount is taken off what promotions leave of the line.
+type RenewalDetails struct {
+	CheckoutDetails
+	ProductID       uint
+	Quantity        int
+	DiscountPercent int
+	DiscountName    string
+}
+
+// PlaceRenewal places an order for a subscription without touching the
+// customer's cart. Retrying with the same key returns the original order.
+func (s *CheckoutService) PlaceRenewal(user models.User, key string, details RenewalDetails, priceList *models.PriceList) (*models.Order, error) {
+	hash := hashCheckout(details, priceList.Currency)
+	if existing, err := s.replay(user.ID, key, hash); err != nil || existing != nil {
+		return existing, err
+	}
+	if err := s.resolveAddresses(user.ID, &details.CheckoutDetails); err != nil {
+		return nil, err
+	}
+	var err error
+	if details.EInvoice, err = normalizeEInvoiceBuyer(user, details.ShippingAddress.Country, details.EInvoice); err != nil {
+		return nil, err
+	}
+
+	cart := &models.Cart{
+		UserID: &user.ID,
+		Items:  []models.CartItem{{ProductID: details.ProductID, Quantity: details.Quantity}},
+	}
+	if err := s.cartService.price(cart, priceList); err != nil {
+		return nil, err
+	}
+	applySubscriptionDiscount(cart, details.DiscountName, details.DiscountPercent)
+
+	order, err := s.placeCart(user, key, hash, details.CheckoutDetails, priceList, cart)
+	if err != nil {
+		if existing, replayErr := s.replay(user.ID, key, hash); replayErr != nil || existing != nil {
+			return existing, replayErr
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// applySubscriptionDiscount takes percent off what is left of each priced
+// line of a cart and lists it with the cart's promotions
+func applySubscriptionDiscount(cart *models.Cart, name string, percent int) {
+	if percent <= 0 || cart.Subtotal == nil {
+		return
+	}
+	total := money.Zero(cart.Subtotal.Currency)
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		if item.LineTotal == nil {
+			continue
+		}
+		remaining := *item.LineTotal
+		for _, d := range item.Discounts {
+			remaining = remaining.Sub(d.Amount)
+		}
+		discount := remaining.MulFrac(int64(percent), 100).Round()
+		if discount.IsZero() {
+			continue
+		}
+		item.Discounts = append(item.Discounts, models.LineDiscount{Name: name, Amount: discount})
+		total = total.Add(discount)
+	}
+	if total.IsZero() {
+		return
+	}
+	cart.Promotions = append(cart.Promotions, models.AppliedPromotion{Name: name, Discount: total})
+	if cart.DiscountTotal != nil {
+		total = cart.DiscountTotal.Add(total)
+	}
+	cart.DiscountTotal = &total
+	left := cart.Subtotal.Sub(total)
+	cart.Total = &left
+}
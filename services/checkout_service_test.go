@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/tax"
+)
+
+type testCheckout struct {
+	checkout   *CheckoutService
+	orders     *OrderService
+	mail       *mailer.MemoryMailer
+	carts      *CartService
+	addresses  *AddressService
+	promotions *PromotionService
+	inventory  *InventoryService
+	pricing    *PricingService
+	products   repository.ProductRepository
+	cartRepo   repository.CartRepository
+	users      repository.UserRepository
+	priceList  *models.PriceList
+	user       models.User
+}
+
+func newTestCheckout(t *testing.T) *testCheckout {
+	t.Helper()
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee", Category: "coffee", Status: models.ProductStatusPublished})
+	productRepo.Create(&models.Product{ID: 2, SKU: "SKU-2", Name: "Tea", Category: "tea", Status: models.ProductStatusPublished})
+
+	pricingService := NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	priceList, err := pricingService.CreatePriceList("TW-TWD", "Taiwan", "TWD", "TW", true, true)
+	if err != nil {
+		t.Fatalf("CreatePriceList() error = %v", err)
+	}
+	pricingService.SetPrice(priceList.ID, 1, "450")
+	pricingService.SetPrice(priceList.ID, 2, "300")
+
+	user := models.User{ID: 7, Email: "buyer@example.com"}
+	userRepo := repository.NewMockUserRepository()
+	userRepo.Create(&user)
+	promotionRepo := repository.NewMockPromotionRepository()
+	promotionService := NewPromotionService(promotionRepo, userRepo)
+
+	inventoryRepo := repository.NewMockInventoryRepository()
+	inventoryService := NewInventoryService(inventoryRepo, productRepo, nil)
+	inventoryService.CreateWarehouse("TPE", "Taipei")
+	receive(t, inventoryService, 1, 1, 10)
+	receive(t, inventoryService, 2, 1, 5)
+
+	cartRepo := repository.NewMockCartRepository()
+	cartService := NewCartService(cartRepo, productRepo, inventoryService, pricingService, promotionService)
+	orderRepo := repository.NewMockOrderRepository(inventoryRepo, cartRepo, promotionRepo)
+	mail := mailer.NewMemoryMailer()
+	addressService := NewAddressService(repository.NewMockAddressRepository())
+
+	return &testCheckout{
+		checkout:   NewCheckoutService(orderRepo, cartService, addressService, nil, NewTaxService(tax.NewLocalProvider()), nil, nil),
+		orders:     NewOrderService(orderRepo, inventoryService, mail),
+		mail:       mail,
+		carts:      cartService,
+		addresses:  addressService,
+		promotions: promotionService,
+		inventory:  inventoryService,
+		pricing:    pricingService,
+		products:   productRepo,
+		cartRepo:   cartRepo,
+		users:      userRepo,
+		priceList:  priceList,
+		user:       user,
+	}
+}
+
+func testAddress() models.Address {
+	return models.Address{Name: "王小明", Line1: "信義路五段7號", City: "台北市", PostalCode: "110", Country: "TW"}
+}
+
+func (tc *testCheckout) fillCart(t *testing.T) {
+	t.Helper()
+	if _, err := tc.carts.AddItem(tc.user.ID, "", 1, 2, tc.priceList); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	if _, err := tc.carts.AddItem(tc.user.ID, "", 2, 1, tc.priceList); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+}
+
+func TestCheckoutPlacesOrder(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.fillCart(t)
+
+	order, created, err := tc.checkout.Checkout(tc.user, "key-1", CheckoutDetails{ShippingAddress: testAddress()}, tc.priceList)
+	if err != nil || !created {
+		t.Fatalf("Checkout() = %v, %v, want a new order", created, err)
+	}
+	if order.Status != models.OrderStatusPendingPayment || order.Email != tc.user.Email {
+		t.Errorf("order = %+v, want pending payment for the buyer", order)
+	}
+	if order.Total.Amount != 120000 || order.Total.Currency != "TWD" || len(order.Items) != 2 {
+		t.Errorf("Total = %v with %d items, want TWD 1200 over 2 items", order.Total, len(order.Items))
+	}
+	if order.Items[0].SKU != "SKU-1" || order.Items[0].LineTotal.Amount != 90000 {
+		t.Errorf("first item = %+v, want a snapshot of SKU-1", order.Items[0])
+	}
+	if order.BillingAddress != order.ShippingAddress {
+		t.Errorf("billing address = %+v, want the shipping address", order.BillingAddress)
+	}
+
+	if available, _ := tc.inventory.Available(1); available != 8 {
+		t.Errorf("Available() = %d, want 8 after reserving", available)
+	}
+	reservations, _ := tc.inventory.Reservations(order.Number)
+	if len(reservations) != 2 || !reservations[0].ExpiresAt.Equal(order.PaymentDueAt) {
+		t.Errorf("reservations = %+v, want 2 held until payment is due", reservations)
+	}
+	if cart, _ := tc.carts.Get(tc.user.ID, "", tc.priceList); len(cart.Items) != 0 {
+		t.Errorf("cart still has %d items after checkout", len(cart.Items))
+	}
+}
+
+func TestCheckoutIsIdempotent(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.fillCart(t)
+	details := CheckoutDetails{ShippingAddress: testAddress()}
+
+	first, _, err := tc.checkout.Checkout(tc.user, "key-1", details, tc.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	tc.fillCart(t)
+	again, created, err := tc.checkout.Checkout(tc.user, "key-1", details, tc.priceList)
+	if err != nil || created || again.ID != first.ID {
+		t.Errorf("retry = order %d, created %v, %v, want order %d replayed", again.ID, created, err, first.ID)
+	}
+	if available, _ := tc.inventory.Available(1); available != 8 {
+		t.Errorf("Available() = %d, want 8 after one order", available)
+	}
+
+	other := details
+	other.Note = "leave at the door"
+	if _, _, err := tc.checkout.Checkout(tc.user, "key-1", other, tc.priceList); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Checkout() error = %v, want %v", err, ErrIdempotencyKeyReused)
+	}
+	if _, _, err := tc.checkout.Checkout(tc.user, "", details, tc.priceList); !errors.Is(err, ErrIdempotencyKeyRequired) {
+		t.Errorf("Checkout() error = %v, want %v", err, ErrIdempotencyKeyRequired)
+	}
+}
+
+func TestConcurrentCheckoutCreatesOneOrder(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.fillCart(t)
+	details := CheckoutDetails{ShippingAddress: testAddress()}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[uint]bool)
+	created := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, isNew, err := tc.checkout.Checkout(tc.user, "double-click", details, tc.priceList)
+			if err != nil {
+				t.Errorf("Checkout() error = %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			ids[order.ID] = true
+			if isNew {
+				created++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(ids) != 1 || created != 1 {
+		t.Errorf("got orders %v with %d created, want exactly one", ids, created)
+	}
+}
+
+func TestCheckoutRejectsInvalidCart(t *testing.T) {
+	tc := newTestCheckout(t)
+	details := CheckoutDetails{ShippingAddress: testAddress()}
+	if _, _, err := tc.checkout.Checkout(tc.user, "key-1", details, tc.priceList); !errors.Is(err, ErrCartEmpty) {
+		t.Errorf("Checkout() error = %v, want %v", err, ErrCartEmpty)
+	}
+
+	tc.fillCart(t)
+	// 另一筆訂單先買走了庫存
+	tc.inventory.Reserve("other", []repository.ReservationItem{{ProductID: 2, Quantity: 5}})
+	_, _, err := tc.checkout.Checkout(tc.user, "key-2", details, tc.priceList)
+	if !errors.Is(err, ErrCartInvalid) {
+		t.Fatalf("Checkout() error = %v, want %v", err, ErrCartInvalid)
+	}
+	if want := fmt.Sprintf("product 2: %s", models.CartIssueInsufficientStock); !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to mention %q", err, want)
+	}
+	if available, _ := tc.inventory.Available(1); available != 10 {
+		t.Errorf("Available() = %d, want nothing reserved for a rejected checkout", available)
+	}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+const (
+	defaultReservationTTL = 15 * time.Minute
+	// reservationSweepInterval is how often expired reservations are released
+	reservationSweepInterval = time.Minute
+)
+
+var (
+	ErrWarehouseNotFound    = errors.New("warehouse not found")
+	ErrInvalidMovement      = errors.New("invalid stock movement")
+	ErrInvalidQuantity      = errors.New("quantity must be positive")
+	ErrReservationNotFound  = repository.ErrReservationNotFound
+	ErrReturnExceedsSold    = repository.ErrReturnExceedsSold
+	ErrInsufficientStock    = repository.ErrInsufficientStock
+	ErrReservationReference = errors.New("reservation reference is required")
+)
+
+// StockObserver is told when the available stock of a product may have changed
+type StockObserver interface {
+	StockChanged(productID uint)
+}
+
+type InventoryService struct {
+	inventoryRepo  repository.InventoryRepository
+	productRepo    repository.ProductRepository
+	observer       StockObserver
+	reservationTTL time.Duration
+	now            func() time.Time
+}
+
+func NewInventoryService(inventoryRepo repository.InventoryRepository, productRepo repository.ProductRepository, observer StockObserver) *InventoryService {
+	ttl := defaultReservationTTL
+	if v, err := time.ParseDuration(os.Getenv("RESERVATION_TTL")); err == nil && v > 0 {
+		ttl = v
+	}
+	return &InventoryService{
+		inventoryRepo:  inventoryRepo,
+		productRepo:    productRepo,
+		observer:       observer,
+		reservationTTL: ttl,
+		now:            time.Now,
+	}
+}
+
+// notify tells the observer about every product in reservations
+func (s *InventoryService) notify(reservations []models.StockReservation) {
+	productIDs := make([]uint, len(reservations))
+	for i, res := range reservations {
+		productIDs[i] = res.ProductID
+	}
+	s.notifyProducts(productIDs)
+}
+
+// notifyItems tells the observer about order items whose stock was moved
+// together with an order status change
+func (s *InventoryService) notifyItems(items []models.OrderItem) {
+	productIDs := make([]uint, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+	s.notifyProducts(productIDs)
+}
+
+func (s *InventoryService) notifyProducts(productIDs []uint) {
+	if s.observer == nil {
+		return
+	}
+	seen := make(map[uint]bool)
+	for _, productID := range productIDs {
+		if !seen[productID] {
+			seen[productID] = true
+			s.observer.StockChanged(productID)
+		}
+	}
+}
+
+func (s *InventoryService) ListWarehouses() ([]models.Warehouse, error) {
+	return s.inventoryRepo.FindWarehouses()
+}
+
+func (s *InventoryService) CreateWarehouse(code, name string) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{
+		Code:   strings.ToUpper(code),
+		Name:   name,
+		Active: true,
+	}
+	if err := s.inventoryRepo.CreateWarehouse(warehouse); err != nil {
+		return nil, errors.New("warehouse code already exists")
+	}
+	return warehouse, nil
+}
+
+func (s *InventoryService) StockLevels(productID uint) ([]models.StockLevel, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	return s.inventoryRepo.FindStockLevels(productID)
+}
+
+// Available is the total quantity of a product that can still be reserved
+func (s *InventoryService) Available(productID uint) (int, error) {
+	levels, err := s.inventoryRepo.FindStockLevels(productID)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, level := range levels {
+		total += level.Available()
+	}
+	return total, nil
+}
+
+func (s *InventoryService) Movements(productID uint, page, pageSize int) ([]models.StockMovement, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	offset, limit := paginate(page, pageSize)
+	return s.inventoryRepo.FindMovements(productID, offset, limit)
+}
+
+// RecordMovement adds a manual ledger entry. Receipts and returns add
+// stock, write-offs take it away and adjustments may go either way. Sales
+// are only recorded by committing reservations.
+func (s *InventoryService) RecordMovement(userID, productID, warehouseID uint, movementType string, quantity int, reference, note string) (*models.StockMovement, error) {
+	switch movementType {
+	case models.MovementReceive, models.MovementReturn:
+		if quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+	case models.MovementWriteOff:
+		if quantity >= 0 {
+			return nil, ErrInvalidQuantity
+		}
+	case models.MovementAdjust:
+		if quantity == 0 {
+			return nil, ErrInvalidMovement
+		}
+	default:
+		return nil, ErrInvalidMovement
+	}
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	if _, err := s.inventoryRepo.FindWarehouseByID(warehouseID); err != nil {
+		return nil, ErrWarehouseNotFound
+	}
+
+	movement := &models.StockMovement{
+		ProductID:   productID,
+		WarehouseID: warehouseID,
+		Type:        movementType,
+		Quantity:    quantity,
+		Reference:   reference,
+		Note:        note,
+		UserID:      &userID,
+	}
+	if err := s.inventoryRepo.ApplyMovement(movement); err != nil {
+		return nil, err
+	}
+	if s.observer != nil {
+		s.observer.StockChanged(productID)
+	}
+	return movement, nil
+}
+
+// Reserve holds stock for all items under reference until the reservation
+// TTL passes. Either every item is reserved or none is.
+func (s *InventoryService) Reserve(reference string, items []repository.ReservationItem) ([]models.StockReservation, error) {
+	if reference == "" {
+		return nil, ErrReservationReference
+	}
+	if len(items) == 0 {
+		return nil, ErrInvalidQuantity
+	}
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+	}
+	reservations, err := s.inventoryRepo.Reserve(reference, items, s.now().Add(s.reservationTTL))
+	if err != nil {
+		return nil, err
+	}
+	s.notify(reservations)
+	return reservations, nil
+}
+
+func (s *InventoryService) Reservations(reference string) ([]models.StockReservation, error) {
+	return s.inventoryRepo.FindReservations(reference)
+}
+
+// Commit turns the reservations of reference into sales on the ledger.
+// Committing again is a no-op, but reservations that were released or have
+// expired cannot be committed.
+func (s *InventoryService) Commit(reference string) error {
+	committed, err := s.inventoryRepo.CommitReservations(reference)
+	if err != nil {
+		return err
+	}
+	reservations, err := s.inventoryRepo.FindReservations(reference)
+	if err != nil {
+		return err
+	}
+	if committed == 0 {
+		for _, res := range reservations {
+			if res.Status == models.ReservationCommitted {
+				return nil
+			}
+		}
+		return ErrReservationNotFound
+	}
+	s.notify(reservations)
+	return nil
+}
+
+// ReturnStock puts quantity units of a product sold under reference back
+// into the warehouses they were taken from. No more can come back than was
+// sold and not returned yet.
+func (s *InventoryService) ReturnStock(userID uint, reference string, productID uint, quantity int, note string) error {
+	return s.returnSold(userID, reference, productID, quantity, note, false)
+}
+
+// WriteOffReturn records quantity returned units of a product sold under
+// reference that cannot be sold again. They are booked back into the
+// warehouses they came from and written off at once, so the ledger shows
+// them without changing what is on hand.
+func (s *InventoryService) WriteOffReturn(userID uint, reference string, productID uint, quantity int, note string) error {
+	return s.returnSold(userID, reference, productID, quantity, note, true)
+}
+
+func (s *InventoryService) returnSold(userID uint, reference string, productID uint, quantity int, note string, writeOff bool) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if err := s.inventoryRepo.ReturnSold(repository.StockReturn{
+		Reference: reference,
+		ProductID: productID,
+		Quantity:  quantity,
+		WriteOff:  writeOff,
+		UserID:    &userID,
+		Note:      note,
+	}); err != nil {
+		return err
+	}
+	if s.observer != nil {
+		s.observer.StockChanged(productID)
+	}
+	return nil
+}
+
+// Release gives reserved stock back, e.g. when a checkout is abandoned
+func (s *InventoryService) Release(reference string) error {
+	return s.release(reference, models.ReservationReleased)
+}
+
+func (s *InventoryService) release(reference, status string) error {
+	reservations, err := s.inventoryRepo.FindReservations(reference)
+	if err != nil {
+		return err
+	}
+	if err := s.inventoryRepo.ReleaseReservations(reference, status); err != nil {
+		return err
+	}
+	s.notify(reservations)
+	return nil
+}
+
+// ExpireReservations releases every active reservation past its expiry
+func (s *InventoryService) ExpireReservations() (int, error) {
+	references, err := s.inventoryRepo.FindExpiredReservationReferences(s.now())
+	if err != nil {
+		return 0, err
+	}
+	for _, reference := range references {
+		if err := s.release(reference, models.ReservationExpired); err != nil {
+			return 0, err
+		}
+	}
+	return len(references), nil
+}
+
+// Run releases expired reservations until ctx is cancelled
+func (s *InventoryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(reservationSweepInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.ExpireReservations(); err != nil {
+			log.Printf("inventory: failed to expire reservations: %v", err)
+		} else if n > 0 {
+			log.Printf("inventory: released %d expired reservations", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+func newTestInventoryService(t *testing.T) *InventoryService {
+	t.Helper()
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee"})
+	productRepo.Create(&models.Product{ID: 2, SKU: "SKU-2", Name: "Tea"})
+
+	inventoryService := NewInventoryService(repository.NewMockInventoryRepository(), productRepo, nil)
+	for _, code := range []string{"TPE", "KHH"} {
+		if _, err := inventoryService.CreateWarehouse(code, code); err != nil {
+			t.Fatalf("CreateWarehouse() error = %v", err)
+		}
+	}
+	return inventoryService
+}
+
+func receive(t *testing.T, s *InventoryService, productID, warehouseID uint, quantity int) {
+	t.Helper()
+	if _, err := s.RecordMovement(1, productID, warehouseID, models.MovementReceive, quantity, "", ""); err != nil {
+		t.Fatalf("RecordMovement() error = %v", err)
+	}
+}
+
+func TestRecordMovementValidation(t *testing.T) {
+	s := newTestInventoryService(t)
+
+	tests := []struct {
+		name         string
+		movementType string
+		quantity     int
+		warehouseID  uint
+		wantErr      error
+	}{
+		{"sales come from reservations", models.MovementSell, -1, 1, ErrInvalidMovement},
+		{"receive must be positive", models.MovementReceive, -5, 1, ErrInvalidQuantity},
+		{"write-off must be negative", models.MovementWriteOff, 2, 1, ErrInvalidQuantity},
+		{"unknown warehouse", models.MovementReceive, 5, 9, ErrWarehouseNotFound},
+		{"adjust below zero", models.MovementAdjust, -1, 1, ErrInsufficientStock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.RecordMovement(1, 1, tt.warehouseID, tt.movementType, tt.quantity, "", "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RecordMovement() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLedgerBalances(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 10)
+	if _, err := s.RecordMovement(1, 1, 1, models.MovementAdjust, -3, "", "damaged"); err != nil {
+		t.Fatalf("RecordMovement() error = %v", err)
+	}
+
+	movements, _ := s.Movements(1, 1, 20)
+	if len(movements) != 2 || movements[0].BalanceAfter != 7 || movements[1].BalanceAfter != 10 {
+		t.Errorf("Movements() = %+v, want balances 7 then 10", movements)
+	}
+}
+
+func TestReserveIsAllOrNothing(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 5)
+	receive(t, s, 2, 1, 1)
+
+	_, err := s.Reserve("checkout-1", []repository.ReservationItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 3}})
+	var stockErr *repository.InsufficientStockError
+	if !errors.As(err, &stockErr) || stockErr.ProductID != 2 {
+		t.Fatalf("Reserve() error = %v, want insufficient stock for product 2", err)
+	}
+	if available, _ := s.Available(1); available != 5 {
+		t.Errorf("Available() = %d after failed reservation, want 5", available)
+	}
+}
+
+func TestReserveSpansWarehouses(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 3)
+	receive(t, s, 1, 2, 4)
+
+	reservations, err := s.Reserve("checkout-1", []repository.ReservationItem{{ProductID: 1, Quantity: 6}})
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if len(reservations) != 2 || reservations[0].WarehouseID != 2 || reservations[0].Quantity != 4 {
+		t.Errorf("Reserve() = %+v, want 4 from warehouse 2 then 2 from warehouse 1", reservations)
+	}
+	if available, _ := s.Available(1); available != 1 {
+		t.Errorf("Available() = %d, want 1", available)
+	}
+
+	// 預留中的庫存不能被盤點調整扣掉
+	if _, err := s.RecordMovement(1, 1, 2, models.MovementAdjust, -1, "", ""); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("RecordMovement() error = %v, want %v", err, ErrInsufficientStock)
+	}
+}
+
+func TestCommitAndRelease(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 10)
+
+	s.Reserve("checkout-1", []repository.ReservationItem{{ProductID: 1, Quantity: 4}})
+	s.Reserve("checkout-2", []repository.ReservationItem{{ProductID: 1, Quantity: 3}})
+	if err := s.Commit("checkout-1"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := s.Release("checkout-2"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	// 重複提交不應再次扣庫存
+	if err := s.Commit("checkout-1"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	levels, _ := s.StockLevels(1)
+	if levels[0].OnHand != 6 || levels[0].Reserved != 0 {
+		t.Errorf("StockLevels() = %+v, want on hand 6 and nothing reserved", levels[0])
+	}
+	movements, _ := s.Movements(1, 1, 20)
+	if movements[0].Type != models.MovementSell || movements[0].Quantity != -4 || movements[0].Reference != "checkout-1" {
+		t.Errorf("latest movement = %+v, want a sale of 4 for checkout-1", movements[0])
+	}
+	if err := s.Commit("unknown"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("Commit() error = %v, want %v", err, ErrReservationNotFound)
+	}
+	if err := s.Commit("checkout-2"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("Commit() of a released reservation error = %v, want %v", err, ErrReservationNotFound)
+	}
+}
+
+func TestReturnStockIsCappedAtSold(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 5)
+	s.Reserve("order-1", []repository.ReservationItem{{ProductID: 1, Quantity: 4}})
+	if err := s.Commit("order-1"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if err := s.ReturnStock(1, "order-1", 1, 3, "refund"); err != nil {
+		t.Fatalf("ReturnStock() error = %v", err)
+	}
+	if err := s.WriteOffReturn(1, "order-1", 1, 2, "return"); !errors.Is(err, ErrReturnExceedsSold) {
+		t.Errorf("WriteOffReturn() error = %v, want %v", err, ErrReturnExceedsSold)
+	}
+	if err := s.WriteOffReturn(1, "order-1", 1, 1, "return"); err != nil {
+		t.Fatalf("WriteOffReturn() error = %v", err)
+	}
+	if err := s.ReturnStock(1, "order-1", 1, 1, "refund"); !errors.Is(err, ErrReturnExceedsSold) {
+		t.Errorf("ReturnStock() error = %v, want %v", err, ErrReturnExceedsSold)
+	}
+	if err := s.ReturnStock(1, "order-2", 1, 1, "refund"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("ReturnStock() of an unknown order error = %v, want %v", err, ErrReservationNotFound)
+	}
+
+	levels, _ := s.StockLevels(1)
+	if levels[0].OnHand != 4 {
+		t.Errorf("on hand = %d, want 4 after 3 returned and 1 written off", levels[0].OnHand)
+	}
+	movements, _ := s.Movements(1, 1, 20)
+	if len(movements) != 5 || movements[0].Type != models.MovementWriteOff || movements[1].Type != models.MovementReturn {
+		t.Errorf("Movements() = %+v, want the write-off booked with its return", movements)
+	}
+}
+
+func TestExpireReservations(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 5)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	s.Reserve("checkout-1", []repository.ReservationItem{{ProductID: 1, Quantity: 5}})
+
+	s.now = func() time.Time { return now.Add(s.reservationTTL + time.Second) }
+	n, err := s.ExpireReservations()
+	if err != nil || n != 1 {
+		t.Fatalf("ExpireReservations() = %d, %v, want 1", n, err)
+	}
+
+	reservations, _ := s.Reservations("checkout-1")
+	if reservations[0].Status != models.ReservationExpired {
+		t.Errorf("status = %q, want %q", reservations[0].Status, models.ReservationExpired)
+	}
+	if available, _ := s.Available(1); available != 5 {
+		t.Errorf("Available() = %d, want 5", available)
+	}
+	if err := s.Commit("checkout-1"); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("Commit() error = %v, want %v", err, ErrReservationNotFound)
+	}
+	if levels, _ := s.StockLevels(1); levels[0].OnHand != 5 {
+		t.Errorf("expired reservation was committed: on hand = %d", levels[0].OnHand)
+	}
+}
+
+func TestConcurrentReservationsNeverOversell(t *testing.T) {
+	s := newTestInventoryService(t)
+	receive(t, s, 1, 1, 10)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := s.Reserve(fmt.Sprintf("checkout-%d", i), []repository.ReservationItem{{ProductID: 1, Quantity: 1}}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if succeeded != 10 {
+		t.Errorf("%d reservations succeeded, want 10", succeeded)
+	}
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"e-commerce/einvoice"
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/pdf"
+	"e-commerce/repository"
+)
+
+const defaultInvoiceStore = "WEB"
+
+var (
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	ErrInvalidEInvoice = errors.New("invalid e-invoice details")
+	ErrOrderNotPaid    = errors.New("order is not paid")
+	ErrEInvoiceFailed  = errors.New("e-invoice provider is unavailable")
+)
+
+// normalizeEInvoiceBuyer checks the e-invoice choice of an order shipped
+// to country. Only orders in Taiwan get e-invoices; there the member
+// carrier is used unless the buyer chose otherwise.
+func normalizeEInvoiceBuyer(user models.User, country string, buyer models.EInvoiceBuyer) (models.EInvoiceBuyer, error) {
+	if country != "TW" {
+		return models.EInvoiceBuyer{}, nil
+	}
+	switch strings.TrimSpace(buyer.Type) {
+	case "", einvoice.BuyerMember:
+		return models.EInvoiceBuyer{Type: einvoice.BuyerMember, Carrier: user.Email}, nil
+	case einvoice.BuyerMobile:
+		code := strings.ToUpper(strings.TrimSpace(buyer.Carrier))
+		if !einvoice.ValidMobileBarcode(code) {
+			return buyer, fmt.Errorf("%w: mobile barcode must be / followed by 7 characters", ErrInvalidEInvoice)
+		}
+		return models.EInvoiceBuyer{Type: einvoice.BuyerMobile, Carrier: code}, nil
+	case einvoice.BuyerDonation:
+		code := strings.TrimSpace(buyer.LoveCode)
+		if !einvoice.ValidLoveCode(code) {
+			return buyer, fmt.Errorf("%w: love code must be 3 to 7 digits", ErrInvalidEInvoice)
+		}
+		return models.EInvoiceBuyer{Type: einvoice.BuyerDonation, LoveCode: code}, nil
+	case einvoice.BuyerCompany:
+		id := strings.TrimSpace(buyer.TaxID)
+		if !einvoice.ValidTaxID(id) {
+			return buyer, fmt.Errorf("%w: unified business number %q is not valid", ErrInvalidEInvoice, id)
+		}
+		return models.EInvoiceBuyer{Type: einvoice.BuyerCompany, TaxID: id, Title: strings.TrimSpace(buyer.Title)}, nil
+	}
+	return buyer, fmt.Errorf("%w: unknown type %q", ErrInvalidEInvoice, buyer.Type)
+}
+
+// Invoicer issues the documents of orders as they are paid and refunded
+type Invoicer interface {
+	OrderPaid(ctx context.Context, orderID uint) error
+	OrderRefunded(ctx context.Context, refund *models.Refund) error
+}
+
+// InvoiceService issues invoices for paid orders and credit notes for
+// refunds, numbered without gaps per store, kind and year, and renders them
+// as PDF. Orders with an e-invoice buyer also get a government uniform
+// invoice, which refunds void in full or reduce with an allowance.
+type InvoiceService struct {
+	invoiceRepo  repository.InvoiceRepository
+	orderService *OrderService
+	templates    *pdf.Templates
+	issuer       einvoice.Issuer
+	store        string
+	now          func() time.Time
+}
+
+// NewInvoiceService creates the invoicing of the store named by
+// INVOICE_STORE, WEB by default. Without an issuer no e-invoices are issued.
+func NewInvoiceService(invoiceRepo repository.InvoiceRepository, orderService *OrderService, templates *pdf.Templates, issuer einvoice.Issuer) *InvoiceService {
+	store := strings.ToUpper(strings.TrimSpace(os.Getenv("INVOICE_STORE")))
+	if store == "" {
+		store = defaultInvoiceStore
+	}
+	return &InvoiceService{
+		invoiceRepo:  invoiceRepo,
+		orderService: orderService,
+		templates:    templates,
+		issuer:       issuer,
+		store:        store,
+		now:          time.Now,
+	}
+}
+
+func orderSource(orderID uint) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+func refundSource(refundID uint) string {
+	return fmt.Sprintf("refund:%d", refundID)
+}
+
+// newDocument starts a document of kind for an order
+func (s *InvoiceService) newDocument(kind, source string, order *models.Order) *models.Invoice {
+	now := s.now()
+	return &models.Invoice{
+		Kind:           kind,
+		Store:          s.store,
+		Year:           now.Year(),
+		Source:         source,
+		OrderID:        order.ID,
+		OrderNumber:    order.Number,
+		UserID:         order.UserID,
+		IssuedAt:       now,
+		Email:          order.Email,
+		BillingAddress: order.BillingAddress,
+		Currency:       order.Currency,
+		TaxInclusive:   order.TaxInclusive,
+		Subtotal:       money.Zero(order.Currency),
+		DiscountTotal:  money.Zero(order.Currency),
+		ShippingTotal:  money.Zero(order.Currency),
+		TaxTotal:       money.Zero(order.Currency),
+		Total:          money.Zero(order.Currency),
+	}
+}
+
+// OrderPaid issues the invoice of an order. An order is invoiced once. The
+// invoice is stored before its e-invoice is asked for, so when the provider
+// fails, calling OrderPaid again only asks for the e-invoice again.
+func (s *InvoiceService) OrderPaid(ctx context.Context, orderID uint) error {
+	invoice, err := s.invoiceRepo.FindBySource(orderSource(orderID))
+	if err != nil {
+		if invoice, err = s.issueInvoice(orderID); err != nil {
+			return err
+		}
+	}
+	return s.finishEInvoice(ctx, invoice)
+}
+
+func (s *InvoiceService) issueInvoice(orderID uint) (*models.Invoice, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == models.OrderStatusPendingPayment {
+		return nil, ErrOrderNotPaid
+	}
+
+	invoice := s.newDocument(models.InvoiceKindInvoice, orderSource(order.ID), order)
+	invoice.Subtotal = order.Subtotal
+	invoice.DiscountTotal = order.DiscountTotal
+	invoice.ShippingTotal = order.ShippingTotal
+	invoice.TaxTotal = order.TaxTotal
+	invoice.Total = order.Total
+	for _, item := range order.Items {
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			SKU:         item.SKU,
+			Description: item.Name,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.LineTotal,
+		})
+	}
+	for _, tax := range order.Taxes {
+		invoice.Taxes = append(invoice.Taxes, models.InvoiceTax{
+			TaxClass: tax.TaxClass,
+			Rate:     tax.Rate,
+			Taxable:  tax.Taxable,
+			Amount:   tax.Amount,
+		})
+	}
+	invoice.EInvoicePending = s.issuer != nil && order.EInvoice.Type != ""
+	if err := s.invoiceRepo.Issue(invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// finishEInvoice asks the provider for the e-invoice of a pending document.
+// Requests carry our own references, so a request whose answer was lost is
+// answered with what was issued the first time.
+func (s *InvoiceService) finishEInvoice(ctx context.Context, doc *models.Invoice) error {
+	if !doc.EInvoicePending || s.issuer == nil {
+		return nil
+	}
+	var err error
+	if doc.IsCreditNote() {
+		err = s.creditEInvoice(ctx, doc)
+	} else {
+		err = s.issueEInvoice(ctx, doc)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrEInvoiceFailed, s.issuer.Name(), err)
+	}
+	return s.invoiceRepo.CompleteEInvoice(doc)
+}
+
+func (s *InvoiceService) issueEInvoice(ctx context.Context, invoice *models.Invoice) error {
+	order, err := s.orderService.GetAny(invoice.OrderID)
+	if err != nil {
+		return err
+	}
+	issued, err := s.issuer.Issue(ctx, einvoice.IssueRequest{
+		Reference: order.Number,
+		Buyer:     eInvoiceBuyer(order),
+		Items:     eInvoiceItems(invoice),
+		Tax:       invoice.TaxTotal,
+		Total:     invoice.Total,
+	})
+	if err != nil {
+		return err
+	}
+	invoice.EInvoiceNumber = issued.Number
+	invoice.EInvoiceRandomCode = issued.RandomCode
+	return nil
+}
+
+func eInvoiceBuyer(order *models.Order) einvoice.Buyer {
+	buyer := einvoice.Buyer{
+		Type:     order.EInvoice.Type,
+		Carrier:  order.EInvoice.Carrier,
+		LoveCode: order.EInvoice.LoveCode,
+		TaxID:    order.EInvoice.TaxID,
+		Name:     order.EInvoice.Title,
+		Email:    order.Email,
+	}
+	if buyer.Name == "" {
+		buyer.Name = order.BillingAddress.Name
+	}
+	return buyer
+}
+
+// eInvoiceItems lists what a document bills for the e-invoice, with
+// discounts and shipping as lines of their own so the items add up
+func eInvoiceItems(doc *models.Invoice) []einvoice.Item {
+	items := make([]einvoice.Item, 0, len(doc.Lines)+2)
+	for _, line := range doc.Lines {
+		items = append(items, einvoice.Item{Description: line.Description, Quantity: line.Quantity, UnitPrice: line.UnitPrice, Amount: line.Total})
+	}
+	if !doc.DiscountTotal.IsZero() {
+		discount := money.Zero(doc.Currency).Sub(doc.DiscountTotal)
+		items = append(items, einvoice.Item{Description: "Discount", Quantity: 1, UnitPrice: discount, Amount: discount})
+	}
+	if !doc.ShippingTotal.IsZero() {
+		items = append(items, einvoice.Item{Description: "Shipping", Quantity: 1, UnitPrice: doc.ShippingTotal, Amount: doc.ShippingTotal})
+	}
+	return items
+}
+
+// IssueForOrder issues the invoice of a paid order if it has none yet, e.g.
+// after the e-invoice provider was unavailable when the order was paid
+func (s *InvoiceService) IssueForOrder(ctx context.Context, orderID uint) (*models.Invoice, error) {
+	if err := s.OrderPaid(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.invoiceRepo.FindBySource(orderSource(orderID))
+}
+
+// OrderRefunded issues the credit note of a refund. It lists the refunded
+// lines at what was paid for them; the tax credited is the refund's share of
+// the order's tax, and any difference to the refunded amount, such as a
+// restocking fee, is shown as an adjustment. Like invoices, credit notes are
+// stored before the e-invoice is voided or reduced.
+func (s *InvoiceService) OrderRefunded(ctx context.Context, refund *models.Refund) error {
+	note, err := s.invoiceRepo.FindBySource(refundSource(refund.ID))
+	if err != nil {
+		if note, err = s.issueCreditNote(refund); err != nil {
+			return err
+		}
+	}
+	return s.finishEInvoice(ctx, note)
+}
+
+func (s *InvoiceService) issueCreditNote(refund *models.Refund) (*models.Invoice, error) {
+	order, err := s.orderService.GetAny(refund.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	note := s.newDocument(models.InvoiceKindCreditNote, refundSource(refund.ID), order)
+	note.RefundID = &refund.ID
+	note.Reason = refund.Reason
+	invoice, err := s.invoiceRepo.FindBySource(orderSource(order.ID))
+	if err == nil {
+		note.InvoiceNumber = invoice.Number
+	}
+	note.Total = refund.Amount
+	if !order.Total.IsZero() {
+		note.TaxTotal = order.TaxTotal.MulFrac(refund.Amount.Amount, order.Total.Amount).Round()
+	}
+	net := note.Total
+	if !order.TaxInclusive {
+		net = net.Sub(note.TaxTotal)
+	}
+
+	lines := money.Zero(order.Currency)
+	for _, refunded := range refund.Items {
+		item := findOrderItem(order, refunded.OrderItemID)
+		if item == nil {
+			continue
+		}
+		paid := item.LineTotal.Sub(item.DiscountTotal).MulFrac(int64(refunded.Quantity), int64(item.Quantity)).Round()
+		note.Lines = append(note.Lines, models.InvoiceLine{
+			SKU:         item.SKU,
+			Description: item.Name,
+			Quantity:    refunded.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       paid,
+		})
+		lines = lines.Add(paid)
+	}
+	if adjustment := net.Sub(lines); !adjustment.IsZero() {
+		description := "Adjustment"
+		if len(note.Lines) == 0 {
+			description = "Refund of order " + order.Number
+		}
+		note.Lines = append(note.Lines, models.InvoiceLine{
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   adjustment,
+			Total:       adjustment,
+		})
+	}
+	note.Subtotal = net
+
+	note.EInvoicePending = s.issuer != nil && invoice != nil && (invoice.EInvoiceNumber != "" || invoice.EInvoicePending)
+	if err := s.invoiceRepo.Issue(note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// creditEInvoice voids the e-invoice of an order refunded in full at once,
+// and otherwise issues an allowance for the credit note
+func (s *InvoiceService) creditEInvoice(ctx context.Context, note *models.Invoice) error {
+	invoice, err := s.invoiceRepo.FindBySource(orderSource(note.OrderID))
+	if err != nil {
+		return err
+	}
+	if invoice.EInvoiceNumber == "" {
+		return errors.New("the e-invoice of the order has not been issued yet")
+	}
+	if note.Total == invoice.Total {
+		reason := note.Reason
+		if reason == "" {
+			reason = "Refunded in full"
+		}
+		// 重送時作廢已經完成，視為成功
+		if err := s.issuer.Void(ctx, invoice.EInvoiceNumber, reason); err != nil && !errors.Is(err, einvoice.ErrAlreadyVoided) {
+			return err
+		}
+		note.EInvoiceNumber = invoice.EInvoiceNumber
+		note.EInvoiceVoided = true
+		return nil
+	}
+
+	allowance, err := s.issuer.Allowance(ctx, einvoice.AllowanceRequest{
+		Reference:     note.Number,
+		InvoiceNumber: invoice.EInvoiceNumber,
+		Items:         eInvoiceItems(note),
+		Tax:           note.TaxTotal,
+		Total:         note.Total,
+	})
+	if err != nil {
+		return err
+	}
+	note.EInvoiceNumber = allowance.Number
+	return nil
+}
+
+// ForOrder returns the invoices and credit notes of an order the user can see
+func (s *InvoiceService) ForOrder(user *models.User, orderID uint) ([]models.Invoice, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil || (!user.IsStaff() && order.UserID != user.ID) {
+		return nil, ErrOrderNotFound
+	}
+	invoices, err := s.invoiceRepo.FindByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if invoices == nil {
+		invoices = []models.Invoice{}
+	}
+	return invoices, nil
+}
+
+// Get returns an invoice or credit note the user can see
+func (s *InvoiceService) Get(user *models.User, id uint) (*models.Invoice, error) {
+	invoice, err := s.invoiceRepo.FindByID(id)
+	if err != nil || (!user.IsStaff() && invoice.UserID != user.ID) {
+		return nil, ErrInvoiceNotFound
+	}
+	return invoice, nil
+}
+
+// PDF renders an invoice or credit note the user can see with the template
+// of its kind
+func (s *InvoiceService) PDF(user *models.User, id uint) (*models.Invoice, []byte, error) {
+	invoice, err := s.Get(user, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := s.templates.Render(invoice.Kind+".tmpl", invoice)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rendering %s: %w", invoice.Number, err)
+	}
+	return invoice, doc, nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/payments"
+)
+
+func TestInvoiceAndCreditNotes(t *testing.T) {
+	tr := newTestRefunds(t)
+	ctx := context.Background()
+
+	// 付款時已開立發票；重複通知不會再開一張
+	if err := tr.invoices.OrderPaid(ctx, tr.order.ID); err != nil {
+		t.Fatalf("OrderPaid() error = %v", err)
+	}
+	invoices, err := tr.invoices.ForOrder(&tr.user, tr.order.ID)
+	if err != nil || len(invoices) != 1 {
+		t.Fatalf("ForOrder() = %d invoices, %v, want 1", len(invoices), err)
+	}
+	invoice := invoices[0]
+	if invoice.Kind != models.InvoiceKindInvoice || invoice.Total != twd(1200) || invoice.TaxTotal != twd(57) {
+		t.Errorf("invoice = %s for %v with %v tax, want an invoice for NT$1,200 with NT$57 tax", invoice.Kind, invoice.Total, invoice.TaxTotal)
+	}
+
+	line := tr.order.Items[0]
+	// 扣除 NT$50 手續費
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{
+		Lines:  []RefundLine{{OrderItemID: line.ID, Quantity: 1}},
+		Amount: "400",
+	}); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{Amount: "100"}); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+
+	invoices, _ = tr.invoices.ForOrder(tr.staff, tr.order.ID)
+	if len(invoices) != 3 {
+		t.Fatalf("ForOrder() = %d documents, want an invoice and 2 credit notes", len(invoices))
+	}
+	for i, format := range []string{"INV-WEB-%d-000001", "CN-WEB-%d-000001", "CN-WEB-%d-000002"} {
+		want := fmt.Sprintf(format, invoice.Year)
+		if invoices[i].Number != want {
+			t.Errorf("document %d number = %q, want %q", i, invoices[i].Number, want)
+		}
+	}
+
+	note, err := tr.invoices.Get(&tr.user, invoices[1].ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if note.InvoiceNumber != invoice.Number || note.Total != twd(400) || note.TaxTotal != twd(19) {
+		t.Errorf("credit note = %v with %v tax correcting %q, want NT$400 with NT$19 tax correcting %q", note.Total, note.TaxTotal, note.InvoiceNumber, invoice.Number)
+	}
+	if len(note.Lines) != 2 || note.Lines[0].Total != twd(450) || note.Lines[1].Total != twd(-50) {
+		t.Errorf("credit note lines = %+v, want the refunded unit and a NT$-50 adjustment", note.Lines)
+	}
+
+	other := &models.User{ID: 99}
+	if _, err := tr.invoices.Get(other, invoice.ID); !errors.Is(err, ErrInvoiceNotFound) {
+		t.Errorf("Get() by another customer error = %v, want %v", err, ErrInvoiceNotFound)
+	}
+	if _, err := tr.invoices.ForOrder(other, tr.order.ID); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("ForOrder() by another customer error = %v, want %v", err, ErrOrderNotFound)
+	}
+}
+
+func TestInvoicePDF(t *testing.T) {
+	tr := newTestRefunds(t)
+	invoices, _ := tr.invoices.ForOrder(&tr.user, tr.order.ID)
+	if len(invoices) != 1 {
+		t.Fatalf("ForOrder() = %d invoices, want 1", len(invoices))
+	}
+
+	invoice, doc, err := tr.invoices.PDF(&tr.user, invoices[0].ID)
+	if err != nil {
+		t.Fatalf("PDF() error = %v", err)
+	}
+	if !bytes.HasPrefix(doc, []byte("%PDF-")) {
+		t.Fatalf("PDF() does not start with a PDF header: %q", doc[:16])
+	}
+	if !bytes.Contains(doc, []byte(invoice.Number)) {
+		t.Errorf("PDF() does not show the number %s", invoice.Number)
+	}
+}
+
+func TestNormalizeEInvoiceBuyer(t *testing.T) {
+	user := models.User{ID: 7, Email: "buyer@example.com"}
+	tests := []struct {
+		name    string
+		country string
+		buyer   models.EInvoiceBuyer
+		want    models.EInvoiceBuyer
+		wantErr bool
+	}{
+		{"member by default", "TW", models.EInvoiceBuyer{}, models.EInvoiceBuyer{Type: "member", Carrier: "buyer@example.com"}, false},
+		{"mobile barcode", "TW", models.EInvoiceBuyer{Type: "mobile", Carrier: " /abc+123 "}, models.EInvoiceBuyer{Type: "mobile", Carrier: "/ABC+123"}, false},
+		{"mobile barcode too short", "TW", models.EInvoiceBuyer{Type: "mobile", Carrier: "/ABC12"}, models.EInvoiceBuyer{}, true},
+		{"mobile barcode without slash", "TW", models.EInvoiceBuyer{Type: "mobile", Carrier: "ABC+1234"}, models.EInvoiceBuyer{}, true},
+		{"donation", "TW", models.EInvoiceBuyer{Type: "donation", LoveCode: "168001"}, models.EInvoiceBuyer{Type: "donation", LoveCode: "168001"}, false},
+		{"donation with letters", "TW", models.EInvoiceBuyer{Type: "donation", LoveCode: "GOOD"}, models.EInvoiceBuyer{}, true},
+		{"company", "TW", models.EInvoiceBuyer{Type: "company", TaxID: "22099131", Title: " 台積電 "}, models.EInvoiceBuyer{Type: "company", TaxID: "22099131", Title: "台積電"}, false},
+		{"company with bad check digit", "TW", models.EInvoiceBuyer{Type: "company", TaxID: "22099132"}, models.EInvoiceBuyer{}, true},
+		{"unknown type", "TW", models.EInvoiceBuyer{Type: "paper"}, models.EInvoiceBuyer{}, true},
+		// 海外訂單不開立電子發票
+		{"outside Taiwan", "JP", models.EInvoiceBuyer{Type: "mobile", Carrier: "bad"}, models.EInvoiceBuyer{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeEInvoiceBuyer(user, tt.country, tt.buyer)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidEInvoice) {
+					t.Errorf("normalizeEInvoiceBuyer() error = %v, want %v", err, ErrInvalidEInvoice)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeEInvoiceBuyer() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEInvoiceAllowanceAndVoid(t *testing.T) {
+	tr := newTestRefunds(t)
+	ctx := context.Background()
+
+	if tr.order.EInvoice.Type != "member" || tr.order.EInvoice.Carrier != tr.user.Email {
+		t.Errorf("order e-invoice = %+v, want the member carrier", tr.order.EInvoice)
+	}
+	invoices, _ := tr.invoices.ForOrder(&tr.user, tr.order.ID)
+	if len(invoices) != 1 || invoices[0].EInvoiceNumber == "" || len(invoices[0].EInvoiceRandomCode) != 4 {
+		t.Fatalf("invoices = %+v, want an invoice with an e-invoice number and random code", invoices)
+	}
+	number := invoices[0].EInvoiceNumber
+
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{Amount: "300"}); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	invoices, _ = tr.invoices.ForOrder(&tr.user, tr.order.ID)
+	allowance := invoices[1]
+	if allowance.EInvoiceNumber == "" || allowance.EInvoiceNumber == number || allowance.EInvoiceVoided {
+		t.Errorf("credit note e-invoice = %q voided %v, want an allowance", allowance.EInvoiceNumber, allowance.EInvoiceVoided)
+	}
+	if tr.issuer.Voided(number) {
+		t.Errorf("e-invoice %s voided by a partial refund", number)
+	}
+
+	// 分次退完全額時以折讓處理，不作廢
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{}); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if tr.issuer.Voided(number) {
+		t.Errorf("e-invoice %s voided after an allowance", number)
+	}
+}
+
+func TestEInvoiceVoidAndRetry(t *testing.T) {
+	tr := newTestRefunds(t)
+	ctx := context.Background()
+
+	invoices, _ := tr.invoices.ForOrder(&tr.user, tr.order.ID)
+	number := invoices[0].EInvoiceNumber
+	// 作廢的回應遺失過，重送時已作廢視為成功
+	if err := tr.issuer.Void(ctx, number, "Refunded in full"); err != nil {
+		t.Fatalf("Void() error = %v", err)
+	}
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{}); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	invoices, _ = tr.invoices.ForOrder(&tr.user, tr.order.ID)
+	if len(invoices) != 2 || !invoices[1].EInvoiceVoided || invoices[1].EInvoiceNumber != number || !tr.issuer.Voided(number) {
+		t.Errorf("credit note = %+v, want e-invoice %s voided", invoices[len(invoices)-1], number)
+	}
+
+	// 加值中心暫停服務時付款仍成功，發票稍後補開
+	tr.issuer.SetDown(true)
+	tr.fillCart(t)
+	order, _, err := tr.checkout.Checkout(tr.user, "key-2", CheckoutDetails{
+		ShippingAddress: testAddress(),
+		EInvoice:        models.EInvoiceBuyer{Type: "company", TaxID: "22099131", Title: "台積電"},
+	}, tr.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if _, err := tr.payments.Pay(ctx, tr.user.ID, order.ID, payments.TestCardSuccess); err != nil {
+		t.Fatalf("Pay() error = %v", err)
+	}
+	pending, _ := tr.invoices.ForOrder(&tr.user, order.ID)
+	if len(pending) != 1 || !pending[0].EInvoicePending || pending[0].EInvoiceNumber != "" {
+		t.Fatalf("ForOrder() = %+v while the provider is down, want an invoice waiting for its e-invoice", pending)
+	}
+	if _, err := tr.invoices.IssueForOrder(ctx, order.ID); !errors.Is(err, ErrEInvoiceFailed) {
+		t.Errorf("IssueForOrder() error = %v, want %v", err, ErrEInvoiceFailed)
+	}
+
+	tr.issuer.SetDown(false)
+	invoice, err := tr.invoices.IssueForOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("IssueForOrder() error = %v", err)
+	}
+	if invoice.ID != pending[0].ID || invoice.EInvoicePending || invoice.EInvoiceNumber == "" || invoice.EInvoiceNumber == number {
+		t.Errorf("invoice = %+v, want the pending invoice with a new e-invoice number", invoice)
+	}
+	want := fmt.Sprintf("INV-WEB-%d-000002", invoice.Year)
+	if invoice.Number != want {
+		t.Errorf("invoice number = %q, want %q without a gap", invoice.Number, want)
+	}
+	if again, err := tr.invoices.IssueForOrder(ctx, order.ID); err != nil || again.ID != invoice.ID {
+		t.Errorf("IssueForOrder() again = %v, %v, want the same invoice", again, err)
+	}
+}
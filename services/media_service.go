@@ -0,0 +1,296 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"e-commerce/imaging"
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/storage"
+)
+
+const defaultMaxUploadSize = 10 << 20
+
+var (
+	ErrImageNotFound        = errors.New("image not found")
+	ErrFileTooLarge         = errors.New("file exceeds the maximum upload size")
+	ErrUnsupportedMediaType = errors.New("unsupported image type")
+	ErrImageTooLarge        = errors.New("image dimensions exceed the maximum")
+	ErrDuplicateImage       = errors.New("image is already attached to this product")
+	ErrSignatureRequired    = errors.New("a signed URL is required")
+)
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type MediaService struct {
+	imageRepo     repository.ProductImageRepository
+	productRepo   repository.ProductRepository
+	storage       storage.Storage
+	maxUploadSize int64
+	variants      []imaging.Variant
+}
+
+// maxUploadSizeFromEnv returns the per-file upload limit in bytes
+func maxUploadSizeFromEnv() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_UPLOAD_BYTES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultMaxUploadSize
+}
+
+// readImage reads at most maxSize bytes from r and checks that the content
+// is an allowed image type of at most imaging.MaxPixels
+func readImage(r io.Reader, maxSize int64) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", ErrFileTooLarge
+	}
+
+	// 以檔案內容判斷類型，不信任客戶端送來的 Content-Type
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return nil, "", ErrUnsupportedMediaType
+	}
+	// 只讀取檔頭的尺寸，避免解壓縮炸彈；標準函式庫讀不了的 WebP 不會被解碼
+	if err := imaging.CheckPixels(data); errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, "", ErrImageTooLarge
+	}
+	return data, contentType, nil
+}
+
+func NewMediaService(imageRepo repository.ProductImageRepository, productRepo repository.ProductRepository, store storage.Storage) *MediaService {
+	return &MediaService{
+		imageRepo:     imageRepo,
+		productRepo:   productRepo,
+		storage:       store,
+		maxUploadSize: maxUploadSizeFromEnv(),
+		variants:      imaging.DefaultVariants,
+	}
+}
+
+func (s *MediaService) MaxUploadSize() int64 {
+	return s.maxUploadSize
+}
+
+// Upload stores an image for a product along with its resized variants.
+// Objects are keyed by content hash so their URLs can be cached forever.
+func (s *MediaService) Upload(ctx context.Context, productID uint, r io.Reader) (*models.ProductImage, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	data, contentType, err := readImage(r, s.maxUploadSize)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.imageRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	base := fmt.Sprintf("products/%d/%x", productID, sum[:12])
+	key := base + imaging.Extension(contentType)
+	for _, img := range existing {
+		if img.StorageKey == key {
+			return nil, ErrDuplicateImage
+		}
+	}
+
+	image := &models.ProductImage{
+		ProductID:   productID,
+		Position:    len(existing),
+		StorageKey:  key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	stored := []string{key}
+	cleanup := func() {
+		for _, k := range stored {
+			s.storage.Delete(ctx, k)
+		}
+	}
+
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), image.Size, contentType); err != nil {
+		return nil, err
+	}
+
+	// WebP 原檔無法以標準函式庫解碼，此時僅保存原檔
+	if decoded, _, err := imaging.Decode(data); err == nil {
+		image.Width = decoded.Bounds().Dx()
+		image.Height = decoded.Bounds().Dy()
+
+		for _, v := range s.variants {
+			resized := imaging.Fit(decoded, v.MaxWidth, v.MaxHeight)
+			encoded, err := imaging.Encode(resized, v.Format)
+			if errors.Is(err, imaging.ErrEncoderUnavailable) {
+				continue
+			}
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+
+			variantType := imaging.ContentType(v.Format)
+			variantKey := base + "_" + v.Name + imaging.Extension(variantType)
+			if err := s.storage.Put(ctx, variantKey, bytes.NewReader(encoded), int64(len(encoded)), variantType); err != nil {
+				cleanup()
+				return nil, err
+			}
+			stored = append(stored, variantKey)
+
+			image.Variants = append(image.Variants, models.ProductImageVariant{
+				Name:        v.Name,
+				StorageKey:  variantKey,
+				ContentType: variantType,
+				Width:       resized.Bounds().Dx(),
+				Height:      resized.Bounds().Dy(),
+			})
+		}
+	}
+
+	if err := s.imageRepo.Create(image); err != nil {
+		cleanup()
+		return nil, errors.New("failed to save image")
+	}
+
+	if err := s.attachURLs(ctx, image); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+func (s *MediaService) List(ctx context.Context, productID uint) ([]models.ProductImage, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	images, err := s.imageRepo.FindByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.AttachURLs(ctx, images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (s *MediaService) Delete(ctx context.Context, productID, imageID uint) error {
+	image, err := s.imageRepo.FindByID(imageID)
+	if err != nil || image.ProductID != productID {
+		return ErrImageNotFound
+	}
+	if err := s.imageRepo.Delete(imageID); err != nil {
+		return err
+	}
+
+	for _, v := range image.Variants {
+		if err := s.storage.Delete(ctx, v.StorageKey); err != nil {
+			log.Printf("failed to delete %s: %v", v.StorageKey, err)
+		}
+	}
+	if err := s.storage.Delete(ctx, image.StorageKey); err != nil {
+		log.Printf("failed to delete %s: %v", image.StorageKey, err)
+	}
+	return nil
+}
+
+func (s *MediaService) Reorder(ctx context.Context, productID uint, imageIDs []uint) ([]models.ProductImage, error) {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+	if err := s.imageRepo.Reorder(productID, imageIDs); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, productID)
+}
+
+// AttachURLs fills in the client-facing URLs of images and their variants
+func (s *MediaService) AttachURLs(ctx context.Context, images []models.ProductImage) error {
+	for i := range images {
+		if err := s.attachURLs(ctx, &images[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MediaService) attachURLs(ctx context.Context, image *models.ProductImage) error {
+	u, err := s.storage.URL(ctx, image.StorageKey)
+	if err != nil {
+		return err
+	}
+	image.URL = u
+
+	for i := range image.Variants {
+		u, err := s.storage.URL(ctx, image.Variants[i].StorageKey)
+		if err != nil {
+			return err
+		}
+		image.Variants[i].URL = u
+	}
+	return nil
+}
+
+// Open returns the stored object for key, used when the storage backend
+// does not serve files itself
+func (s *MediaService) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.storage.Get(ctx, key)
+}
+
+// publicMediaPrefixes are the objects anyone may fetch. Return photos are
+// only served with a valid signature, and everything else, such as catalog
+// imports, never.
+var (
+	publicMediaPrefixes = []string{"products/", "reviews/"}
+	signedMediaPrefixes = []string{"returns/"}
+)
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyURL checks that a media key may be served and verifies its signed
+// URL when the backend supports it. Keys that are never served report
+// storage.ErrNotFound.
+func (s *MediaService) VerifyURL(key string, query map[string][]string) error {
+	verifier, ok := s.storage.(storage.URLVerifier)
+	switch {
+	case hasAnyPrefix(key, publicMediaPrefixes):
+		if ok {
+			return verifier.VerifyURL(key, query)
+		}
+		return nil
+	case hasAnyPrefix(key, signedMediaPrefixes):
+		// 未設定簽章金鑰時 URL 沒有簽章，不提供退貨照片
+		if !ok || len(query["signature"]) == 0 || query["signature"][0] == "" {
+			return ErrSignatureRequired
+		}
+		return verifier.VerifyURL(key, query)
+	}
+	return storage.ErrNotFound
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/storage"
+)
+
+func newTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestPNGHeader returns a 1x1 PNG whose header claims w x h pixels
+func newTestPNGHeader(t *testing.T, w, h uint32) []byte {
+	t.Helper()
+	data := newTestPNG(t, 1, 1)
+	// IHDR 緊接在 8 位元組簽章之後：長度、類型、寬、高……、CRC
+	binary.BigEndian.PutUint32(data[16:], w)
+	binary.BigEndian.PutUint32(data[20:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func newTestMediaService(t *testing.T) (*MediaService, repository.ProductImageRepository) {
+	t.Helper()
+	t.Setenv("MEDIA_MAX_UPLOAD_BYTES", "200000")
+
+	productRepo := repository.NewMockProductRepository()
+	if err := productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Test Product"}); err != nil {
+		t.Fatalf("Failed to create product: %v", err)
+	}
+	imageRepo := repository.NewMockProductImageRepository()
+
+	store, err := storage.NewLocalStorage(t.TempDir(), "/api/v1/media", "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	return NewMediaService(imageRepo, productRepo, store), imageRepo
+}
+
+func TestMediaUpload(t *testing.T) {
+	mediaService, _ := newTestMediaService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		productID uint
+		data      []byte
+		wantErr   error
+	}{
+		{
+			name:      "successful upload",
+			productID: 1,
+			data:      newTestPNG(t, 400, 300),
+		},
+		{
+			name:      "duplicate image",
+			productID: 1,
+			data:      newTestPNG(t, 400, 300),
+			wantErr:   ErrDuplicateImage,
+		},
+		{
+			name:      "unknown product",
+			productID: 99,
+			data:      newTestPNG(t, 10, 10),
+			wantErr:   ErrProductNotFound,
+		},
+		{
+			name:      "not an image",
+			productID: 1,
+			data:      []byte("<html><body>hello</body></html>"),
+			wantErr:   ErrUnsupportedMediaType,
+		},
+		{
+			name:      "too large",
+			productID: 1,
+			data:      bytes.Repeat([]byte{0}, 200001),
+			wantErr:   ErrFileTooLarge,
+		},
+		{
+			name:      "too many pixels",
+			productID: 1,
+			data:      newTestPNGHeader(t, 100000, 100000),
+			wantErr:   ErrImageTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := mediaService.Upload(ctx, tt.productID, bytes.NewReader(tt.data))
+			if err != tt.wantErr {
+				t.Fatalf("Upload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if img.ContentType != "image/png" || img.Width != 400 || img.Height != 300 {
+				t.Errorf("Upload() = %s %dx%d, want image/png 400x300", img.ContentType, img.Width, img.Height)
+			}
+			if !strings.Contains(img.URL, "signature=") {
+				t.Errorf("Upload() URL %q is not signed", img.URL)
+			}
+
+			var thumb *models.ProductImageVariant
+			for i := range img.Variants {
+				if img.Variants[i].Name == "thumb" {
+					thumb = &img.Variants[i]
+				}
+			}
+			if thumb == nil {
+				t.Fatal("Upload() did not create a thumbnail")
+			}
+			if thumb.Width != 200 || thumb.Height != 150 {
+				t.Errorf("thumbnail size = %dx%d, want 200x150", thumb.Width, thumb.Height)
+			}
+		})
+	}
+}
+
+func TestMediaReorderAndDelete(t *testing.T) {
+	mediaService, imageRepo := newTestMediaService(t)
+	ctx := context.Background()
+
+	var ids []uint
+	for i := 1; i <= 3; i++ {
+		img, err := mediaService.Upload(ctx, 1, bytes.NewReader(newTestPNG(t, 10*i, 10)))
+		if err != nil {
+			t.Fatalf("Upload() error = %v", err)
+		}
+		if img.Position != i-1 {
+			t.Errorf("Upload() position = %d, want %d", img.Position, i-1)
+		}
+		ids = append(ids, img.ID)
+	}
+
+	if _, err := mediaService.Reorder(ctx, 1, []uint{ids[0], ids[1]}); err == nil {
+		t.Error("Reorder() with missing image should fail")
+	}
+
+	images, err := mediaService.Reorder(ctx, 1, []uint{ids[2], ids[0], ids[1]})
+	if err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+	if images[0].ID != ids[2] || images[1].ID != ids[0] || images[2].ID != ids[1] {
+		t.Errorf("Reorder() order = %d,%d,%d", images[0].ID, images[1].ID, images[2].ID)
+	}
+
+	if err := mediaService.Delete(ctx, 1, ids[2]); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	remaining, _ := imageRepo.FindByProductID(1)
+	if len(remaining) != 2 || remaining[0].Position != 0 || remaining[1].Position != 1 {
+		t.Errorf("Delete() left positions %+v", remaining)
+	}
+	if err := mediaService.Delete(ctx, 2, ids[0]); err != ErrImageNotFound {
+		t.Errorf("Delete() on other product error = %v, want %v", err, ErrImageNotFound)
+	}
+}
+
+func TestMediaVerifyURLPrefixes(t *testing.T) {
+	mediaService, _ := newTestMediaService(t)
+	ctx := context.Background()
+
+	query := func(t *testing.T, key string) map[string][]string {
+		t.Helper()
+		u, err := mediaService.storage.URL(ctx, key)
+		if err != nil {
+			t.Fatalf("URL() error = %v", err)
+		}
+		parsed, _ := url.Parse(u)
+		return parsed.Query()
+	}
+
+	if err := mediaService.VerifyURL("products/1/abc.png", query(t, "products/1/abc.png")); err != nil {
+		t.Errorf("VerifyURL(product image) error = %v", err)
+	}
+	if err := mediaService.VerifyURL("returns/1/abc.png", query(t, "returns/1/abc.png")); err != nil {
+		t.Errorf("VerifyURL(signed return photo) error = %v", err)
+	}
+	if err := mediaService.VerifyURL("returns/1/abc.png", nil); !errors.Is(err, ErrSignatureRequired) {
+		t.Errorf("VerifyURL(unsigned return photo) error = %v, want %v", err, ErrSignatureRequired)
+	}
+	// 商品匯入檔即使有合法簽章也不提供下載
+	if err := mediaService.VerifyURL("imports/1.csv", query(t, "imports/1.csv")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("VerifyURL(import) error = %v, want %v", err, storage.ErrNotFound)
+	}
+}
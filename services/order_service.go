@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+const (
+	// orderSweepInterval is how often unpaid orders past their due time are cancelled
+	orderSweepInterval = time.Minute
+	orderSweepBatch    = 100
+	systemActorName    = "system"
+	// defaultCancellationWindow is how long after checkout customers may
+	// still cancel an order that is already being fulfilled
+	defaultCancellationWindow = time.Hour
+)
+
+var (
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrInvalidOrderStatus     = errors.New("invalid order status")
+	ErrIllegalTransition      = errors.New("illegal order status transition")
+	ErrTransitionForbidden    = errors.New("you are not allowed to make this status change")
+	ErrOrderStatusChanged     = repository.ErrOrderStatusChanged
+	ErrPaymentOverdue         = errors.New("payment is past due and the stock hold has lapsed")
+	ErrTrackingNumberRequired = errors.New("tracking number is required to ship an order")
+	ErrReasonRequired         = errors.New("a reason is required for this status change")
+	ErrNotFullyRefunded       = errors.New("order has not been fully refunded")
+	ErrCancellationClosed     = errors.New("order is being fulfilled and can no longer be cancelled")
+)
+
+// orderActor is a bit set of who may make a transition
+type orderActor int
+
+const (
+	actorSystem orderActor = 1 << iota
+	actorCustomer
+	actorStaff
+)
+
+// StatusChange describes a requested order status transition
+type StatusChange struct {
+	To             string
+	Reason         string
+	Carrier        string
+	TrackingNumber string
+}
+
+// orderEffect is a side effect run after a transition has been recorded
+type orderEffect func(s *OrderService, order *models.Order, change *models.OrderStatusChange) error
+
+type orderTransition struct {
+	from, to string
+	actors   orderActor
+	// guard rejects the transition before anything is changed
+	guard func(s *OrderService, order *models.Order, role orderActor, change StatusChange) error
+	// stock is applied together with the transition; when it fails the
+	// order keeps its status
+	stock   repository.OrderStock
+	effects []orderEffect
+}
+
+// orderTransitions is the order lifecycle. Any transition not listed is illegal.
+var orderTransitions = []orderTransition{
+	{from: models.OrderStatusPendingPayment, to: models.OrderStatusPaid, actors: actorSystem | actorStaff,
+		guard: guardPaymentDue, stock: repository.StockCommit, effects: []orderEffect{notifyCustomer}},
+	{from: models.OrderStatusPendingPayment, to: models.OrderStatusCancelled, actors: actorSystem | actorCustomer | actorStaff,
+		stock: repository.StockRelease, effects: []orderEffect{releaseCoupons, notifyCustomer}},
+	{from: models.OrderStatusPaid, to: models.OrderStatusFulfilling, actors: actorStaff},
+	{from: models.OrderStatusPaid, to: models.OrderStatusCancelled, actors: actorCustomer | actorStaff,
+		guard: guardCancellation, stock: repository.StockRestock, effects: []orderEffect{releaseCoupons, notifyCustomer}},
+	{from: models.OrderStatusPaid, to: models.OrderStatusRefunded, actors: actorSystem | actorStaff,
+		guard: guardFullyRefunded, effects: []orderEffect{notifyCustomer}},
+	{from: models.OrderStatusFulfilling, to: models.OrderStatusShipped, actors: actorStaff,
+		guard: guardTracking, effects: []orderEffect{notifyCustomer}},
+	{from: models.OrderStatusFulfilling, to: models.OrderStatusCancelled, actors: actorCustomer | actorStaff,
+		guard: guardCancellation, stock: repository.StockRestock, effects: []orderEffect{releaseCoupons, notifyCustomer}},
+	{from: models.OrderStatusFulfilling, to: models.OrderStatusRefunded, actors: actorSystem | actorStaff,
+		guard: guardFullyRefunded, effects: []orderEffect{notifyCustomer}},
+	{from: models.OrderStatusShipped, to: models.OrderStatusDelivered, actors: actorSystem | actorStaff,
+		effects: []orderEffect{notifyCustomer}},
+	{from: models.OrderStatusShipped, to: models.OrderStatusRefunded, actors: actorSystem | actorStaff,
+		guard: guardFullyRefunded, effects: []orderEffect{notifyCustomer}},
+	{from: models.OrderStatusDelivered, to: models.OrderStatusRefunded, actors: actorSystem | actorStaff,
+		guard: guardFullyRefunded, effects: []orderEffect{notifyCustomer}},
+}
+
+var orderStatuses = []string{
+	models.OrderStatusPendingPayment,
+	models.OrderStatusPaid,
+	models.OrderStatusFulfilling,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+	models.OrderStatusCancelled,
+	models.OrderStatusRefunded,
+}
+
+// purchasedStatuses are the statuses in which the customer has paid for an order
+var purchasedStatuses = []string{
+	models.OrderStatusPaid,
+	models.OrderStatusFulfilling,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+}
+
+func isOrderStatus(status string) bool {
+	for _, s := range orderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func findTransition(from, to string) (orderTransition, bool) {
+	for _, t := range orderTransitions {
+		if t.from == from && t.to == to {
+			return t, true
+		}
+	}
+	return orderTransition{}, false
+}
+
+// NextStatuses lists the statuses an order in status can move to
+func NextStatuses(status string) []string {
+	var next []string
+	for _, t := range orderTransitions {
+		if t.from == status {
+			next = append(next, t.to)
+		}
+	}
+	return next
+}
+
+func guardPaymentDue(s *OrderService, order *models.Order, role orderActor, change StatusChange) error {
+	if s.now().After(order.PaymentDueAt) {
+		return ErrPaymentOverdue
+	}
+	return nil
+}
+
+func guardTracking(s *OrderService, order *models.Order, role orderActor, change StatusChange) error {
+	if strings.TrimSpace(change.TrackingNumber) == "" {
+		return ErrTrackingNumberRequired
+	}
+	return nil
+}
+
+func guardReason(s *OrderService, order *models.Order, role orderActor, change StatusChange) error {
+	if strings.TrimSpace(change.Reason) == "" {
+		return ErrReasonRequired
+	}
+	return nil
+}
+
+// guardCancellation lets customers cancel a paid order until fulfillment
+// starts, or within the cancellation window after checkout. Staff may cancel
+// later but must give a reason.
+func guardCancellation(s *OrderService, order *models.Order, role orderActor, change StatusChange) error {
+	if role == actorStaff {
+		return guardReason(s, order, role, change)
+	}
+	if order.Status == models.OrderStatusFulfilling && s.now().After(order.CreatedAt.Add(s.cancellationWindow)) {
+		return ErrCancellationClosed
+	}
+	return nil
+}
+
+// guardFullyRefunded keeps orders from being marked refunded before the
+// money has actually been returned
+func guardFullyRefunded(s *OrderService, order *models.Order, role orderActor, change StatusChange) error {
+	if order.RefundedTotal.Currency != order.Total.Currency || order.RefundedTotal.Cmp(order.Total) < 0 {
+		return ErrNotFullyRefunded
+	}
+	return nil
+}
+
+// releaseCoupons lets the customer use the coupons of a cancelled order again
+func releaseCoupons(s *OrderService, order *models.Order, change *models.OrderStatusChange) error {
+	if len(order.Promotions) == 0 {
+		return nil
+	}
+	return s.orderRepo.ReleaseCoupons(order.ID)
+}
+
+var orderStatusSubjects = map[string]string{
+	models.OrderStatusPaid:      "Payment received for order %s",
+	models.OrderStatusShipped:   "Order %s has shipped",
+	models.OrderStatusDelivered: "Order %s has been delivered",
+	models.OrderStatusCancelled: "Order %s has been cancelled",
+	models.OrderStatusRefunded:  "Order %s has been refunded",
+}
+
+func notifyCustomer(s *OrderService, order *models.Order, change *models.OrderStatusChange) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nYour order %s is now %s.\n", order.ShippingAddress.Name, order.Number, strings.ReplaceAll(change.To, "_", " "))
+	if change.To == models.OrderStatusShipped {
+		fmt.Fprintf(&body, "\nCarrier: %s\nTracking number: %s\n", order.Carrier, order.TrackingNumber)
+	}
+	if change.Reason != "" {
+		fmt.Fprintf(&body, "\nReason: %s\n", change.Reason)
+	}
+	return s.mailer.Send(context.Background(), mailer.Message{
+		To:      []string{order.Email},
+		Subject: fmt.Sprintf(orderStatusSubjects[change.To], order.Number),
+		Body:    body.String(),
+	})
+}
+
+type OrderService struct {
+	orderRepo        repository.OrderRepository
+	inventoryService *InventoryService
+	mailer           mailer.Mailer
+	// cancellationWindow is how long after checkout customers may cancel
+	// an order that is being fulfilled
+	cancellationWindow time.Duration
+	now                func() time.Time
+}
+
+func NewOrderService(orderRepo repository.OrderRepository, inventoryService *InventoryService, m mailer.Mailer) *OrderService {
+	window := defaultCancellationWindow
+	if v, err := time.ParseDuration(os.Getenv("ORDER_CANCELLATION_WINDOW")); err == nil && v > 0 {
+		window = v
+	}
+	return &OrderService{
+		orderRepo:          orderRepo,
+		inventoryService:   inventoryService,
+		mailer:             m,
+		cancellationWindow: window,
+		now:                time.Now,
+	}
+}
+
+// List returns the orders of a user, newest first
+func (s *OrderService) List(userID uint, page, pageSize int) ([]models.Order, error) {
+	offset, limit := paginate(page, pageSize)
+	return s.orderRepo.FindByUserID(userID, offset, limit)
+}
+
+// Get returns an order of a user, hiding other users' orders as not found
+func (s *OrderService) Get(userID, id uint) (*models.Order, error) {
+	order, err := s.orderRepo.FindByID(id)
+	if err != nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// ListAll returns every order for staff, optionally filtered by status
+func (s *OrderService) ListAll(status string, page, pageSize int) ([]models.Order, error) {
+	if status != "" && !isOrderStatus(status) {
+		return nil, ErrInvalidOrderStatus
+	}
+	offset, limit := paginate(page, pageSize)
+	return s.orderRepo.FindAll(status, offset, limit)
+}
+
+// GetAny returns any order for staff
+func (s *OrderService) GetAny(id uint) (*models.Order, error) {
+	order, err := s.orderRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// History returns the status changes of an order, oldest first. Customers
+// only see their own orders; staff see all.
+func (s *OrderService) History(actor *models.User, id uint) ([]models.OrderStatusChange, error) {
+	order, err := s.orderRepo.FindByID(id)
+	if err != nil || (!actor.IsStaff() && order.UserID != actor.ID) {
+		return nil, ErrOrderNotFound
+	}
+	return s.orderRepo.FindStatusHistory(order.ID)
+}
+
+// ChangeStatus moves an order along its lifecycle. A nil actor is the
+// system, e.g. the payment-expiry sweeper. Stock is committed, released or
+// restocked in the same transaction as the status change, so the transition
+// fails when the stock cannot be moved. The other side effects run after the
+// transition is recorded; a failed one is logged and does not undo it.
+func (s *OrderService) ChangeStatus(actor *models.User, id uint, change StatusChange) (*models.Order, error) {
+	if !isOrderStatus(change.To) {
+		return nil, ErrInvalidOrderStatus
+	}
+	order, err := s.orderRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	role := actorSystem
+	if actor != nil {
+		switch {
+		case actor.IsStaff():
+			role = actorStaff
+		case actor.ID == order.UserID:
+			role = actorCustomer
+		default:
+			return nil, ErrOrderNotFound
+		}
+	}
+
+	transition, ok := findTransition(order.Status, change.To)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot go from %s to %s", ErrIllegalTransition, order.Status, change.To)
+	}
+	if transition.actors&role == 0 {
+		return nil, ErrTransitionForbidden
+	}
+	if transition.guard != nil {
+		if err := transition.guard(s, order, role, change); err != nil {
+			return nil, err
+		}
+	}
+
+	record := &models.OrderStatusChange{
+		OrderID:   order.ID,
+		From:      order.Status,
+		To:        change.To,
+		ActorName: systemActorName,
+		Reason:    strings.TrimSpace(change.Reason),
+	}
+	if actor != nil {
+		record.ActorID = &actor.ID
+		record.ActorName = actor.Name
+	}
+	if change.To == models.OrderStatusShipped {
+		order.Carrier = strings.TrimSpace(change.Carrier)
+		order.TrackingNumber = strings.TrimSpace(change.TrackingNumber)
+	}
+	if err := s.orderRepo.Transition(order, order.Status, record, transition.stock); err != nil {
+		return nil, err
+	}
+	order.Status = change.To
+	if transition.stock != repository.StockUnchanged {
+		s.inventoryService.notifyItems(order.Items)
+	}
+
+	for _, effect := range transition.effects {
+		if err := effect(s, order, record); err != nil {
+			log.Printf("orders: %s %s -> %s: %v", order.Number, record.From, record.To, err)
+		}
+	}
+	return order, nil
+}
+
+// HasPurchased implements PurchaseVerifier using paid orders
+func (s *OrderService) HasPurchased(userID, productID uint) (bool, error) {
+	return s.orderRepo.HasPurchased(userID, productID, purchasedStatuses)
+}
+
+// CancelOverdue cancels orders whose payment did not arrive in time,
+// releasing their stock
+func (s *OrderService) CancelOverdue() (int, error) {
+	orders, err := s.orderRepo.FindOverdue(s.now(), orderSweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	cancelled := 0
+	for _, order := range orders {
+		_, err := s.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusCancelled, Reason: "Payment was not received in time"})
+		if errors.Is(err, ErrOrderStatusChanged) {
+			// 付款剛好在這時完成
+			continue
+		}
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled++
+	}
+	return cancelled, nil
+}
+
+// Run cancels overdue unpaid orders until ctx is cancelled
+func (s *OrderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(orderSweepInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.CancelOverdue(); err != nil {
+			log.Printf("orders: failed to cancel overdue orders: %v", err)
+		} else if n > 0 {
+			log.Printf("orders: cancelled %d unpaid orders", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
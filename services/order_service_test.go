@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+)
+
+func (tc *testCheckout) placeOrder(t *testing.T) *models.Order {
+	t.Helper()
+	tc.fillCart(t)
+	order, _, err := tc.checkout.Checkout(tc.user, "key-1", CheckoutDetails{ShippingAddress: testAddress()}, tc.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	return order
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	tc := newTestCheckout(t)
+	order := tc.placeOrder(t)
+	staff := &models.User{ID: 1, Name: "Staff", Role: models.RoleStaff}
+
+	if _, err := tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusPaid}); err != nil {
+		t.Fatalf("ChangeStatus(paid) error = %v", err)
+	}
+	if levels, _ := tc.inventory.StockLevels(1); levels[0].OnHand != 8 || levels[0].Reserved != 0 {
+		t.Errorf("StockLevels() = %+v, want the reservation committed", levels[0])
+	}
+
+	if _, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusFulfilling}); err != nil {
+		t.Fatalf("ChangeStatus(fulfilling) error = %v", err)
+	}
+	if _, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusShipped}); !errors.Is(err, ErrTrackingNumberRequired) {
+		t.Errorf("ChangeStatus(shipped) error = %v, want %v", err, ErrTrackingNumberRequired)
+	}
+	shipped, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusShipped, Carrier: "black-cat", TrackingNumber: "9056"})
+	if err != nil || shipped.TrackingNumber != "9056" {
+		t.Fatalf("ChangeStatus(shipped) = %+v, %v, want tracking number 9056", shipped, err)
+	}
+	if _, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusDelivered}); err != nil {
+		t.Fatalf("ChangeStatus(delivered) error = %v", err)
+	}
+
+	history, _ := tc.orders.History(staff, order.ID)
+	if len(history) != 4 || history[0].ActorName != systemActorName || history[3].To != models.OrderStatusDelivered {
+		t.Errorf("History() = %+v, want 4 changes from paid to delivered", history)
+	}
+	if *history[1].ActorID != staff.ID {
+		t.Errorf("ActorID = %d, want %d", *history[1].ActorID, staff.ID)
+	}
+	// paid, shipped, delivered 各寄一封
+	if sent := tc.mail.Sent(); len(sent) != 3 || sent[0].To[0] != tc.user.Email {
+		t.Errorf("sent %d emails, want 3 to the buyer", len(sent))
+	}
+}
+
+func TestIllegalTransitionsAreRejected(t *testing.T) {
+	tc := newTestCheckout(t)
+	order := tc.placeOrder(t)
+	customer := &tc.user
+
+	if _, err := tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusShipped}); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("pending to shipped error = %v, want %v", err, ErrIllegalTransition)
+	}
+	if _, err := tc.orders.ChangeStatus(customer, order.ID, StatusChange{To: models.OrderStatusPaid}); !errors.Is(err, ErrTransitionForbidden) {
+		t.Errorf("customer marking paid error = %v, want %v", err, ErrTransitionForbidden)
+	}
+	if _, err := tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: "lost"}); !errors.Is(err, ErrInvalidOrderStatus) {
+		t.Errorf("unknown status error = %v, want %v", err, ErrInvalidOrderStatus)
+	}
+	stranger := &models.User{ID: 99}
+	if _, err := tc.orders.ChangeStatus(stranger, order.ID, StatusChange{To: models.OrderStatusCancelled}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("stranger cancelling error = %v, want %v", err, ErrOrderNotFound)
+	}
+
+	if _, err := tc.orders.ChangeStatus(customer, order.ID, StatusChange{To: models.OrderStatusCancelled}); err != nil {
+		t.Fatalf("customer cancelling error = %v", err)
+	}
+	if available, _ := tc.inventory.Available(1); available != 10 {
+		t.Errorf("Available() = %d, want the stock released", available)
+	}
+	if _, err := tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusPaid}); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("cancelled to paid error = %v, want %v", err, ErrIllegalTransition)
+	}
+}
+
+func TestCancelPaidOrderRestocks(t *testing.T) {
+	tc := newTestCheckout(t)
+	order := tc.placeOrder(t)
+	staff := &models.User{ID: 1, Role: models.RoleAdmin}
+	tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusPaid})
+
+	if _, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusRefunded}); !errors.Is(err, ErrNotFullyRefunded) {
+		t.Errorf("refunded without a refund error = %v, want %v", err, ErrNotFullyRefunded)
+	}
+	if _, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusCancelled}); !errors.Is(err, ErrReasonRequired) {
+		t.Errorf("cancel without reason error = %v, want %v", err, ErrReasonRequired)
+	}
+	if _, err := tc.orders.ChangeStatus(staff, order.ID, StatusChange{To: models.OrderStatusCancelled, Reason: "Out of stock at supplier"}); err != nil {
+		t.Fatalf("ChangeStatus(cancelled) error = %v", err)
+	}
+	if levels, _ := tc.inventory.StockLevels(1); levels[0].OnHand != 10 {
+		t.Errorf("OnHand = %d, want the sold stock returned", levels[0].OnHand)
+	}
+}
+
+func TestStockFailureRejectsTransition(t *testing.T) {
+	tc := newTestCheckout(t)
+	order := tc.placeOrder(t)
+	// 預留已被釋放時不能標記為已付款
+	if err := tc.inventory.Release(order.Number); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	if _, err := tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusPaid}); !errors.Is(err, ErrReservationNotFound) {
+		t.Fatalf("ChangeStatus(paid) error = %v, want %v", err, ErrReservationNotFound)
+	}
+	unchanged, _ := tc.orders.Get(tc.user.ID, order.ID)
+	if unchanged.Status != models.OrderStatusPendingPayment {
+		t.Errorf("Status = %q, want %q", unchanged.Status, models.OrderStatusPendingPayment)
+	}
+	if history, _ := tc.orders.History(&tc.user, order.ID); len(history) != 0 {
+		t.Errorf("History() = %+v, want no status change recorded", history)
+	}
+	if sent := tc.mail.Sent(); len(sent) != 0 {
+		t.Errorf("sent %d emails, want none", len(sent))
+	}
+}
+
+func TestCancelOverdueOrders(t *testing.T) {
+	tc := newTestCheckout(t)
+	order := tc.placeOrder(t)
+
+	tc.orders.now = func() time.Time { return order.PaymentDueAt.Add(time.Second) }
+	if _, err := tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusPaid}); !errors.Is(err, ErrPaymentOverdue) {
+		t.Errorf("late payment error = %v, want %v", err, ErrPaymentOverdue)
+	}
+	if n, err := tc.orders.CancelOverdue(); err != nil || n != 1 {
+		t.Fatalf("CancelOverdue() = %d, %v, want 1", n, err)
+	}
+	cancelled, _ := tc.orders.Get(tc.user.ID, order.ID)
+	if cancelled.Status != models.OrderStatusCancelled {
+		t.Errorf("Status = %q, want %q", cancelled.Status, models.OrderStatusCancelled)
+	}
+	if available, _ := tc.inventory.Available(1); available != 10 {
+		t.Errorf("Available() = %d, want the stock released", available)
+	}
+}
+
+func TestHasPurchasedNeedsPaidOrder(t *testing.T) {
+	tc := newTestCheckout(t)
+	order := tc.placeOrder(t)
+
+	if bought, _ := tc.orders.HasPurchased(tc.user.ID, 1); bought {
+		t.Error("HasPurchased() = true for an unpaid order")
+	}
+	tc.orders.ChangeStatus(nil, order.ID, StatusChange{To: models.OrderStatusPaid})
+	if bought, _ := tc.orders.HasPurchased(tc.user.ID, 1); !bought {
+		t.Error("HasPurchased() = false after payment")
+	}
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/payments"
+	"e-commerce/repository"
+)
+
+var (
+	ErrPaymentNotFound       = errors.New("payment not found")
+	ErrOrderNotPayable       = errors.New("order is not awaiting payment")
+	ErrPaymentDeclined       = errors.New("payment was declined")
+	ErrPaymentFailed         = errors.New("payment provider is unavailable")
+	ErrInvalidWebhook        = errors.New("invalid webhook")
+	ErrInvalidCardNumber     = errors.New("invalid card number")
+	ErrChallengeNotFaked     = errors.New("3-D Secure can only be simulated with the fake provider")
+	ErrNoPendingChallenge    = errors.New("payment is not waiting for 3-D Secure")
+	ErrPaymentMethodNotFound = errors.New("payment method not found")
+)
+
+// PaymentService takes payments for orders through a PaymentProvider and
+// applies the provider's webhooks
+type PaymentService struct {
+	paymentRepo  repository.PaymentRepository
+	orderService *OrderService
+	provider     payments.PaymentProvider
+	invoicer     Invoicer
+	now          func() time.Time
+}
+
+// NewPaymentService creates the payment flow. Paid orders are invoiced when
+// an invoicer is given.
+func NewPaymentService(paymentRepo repository.PaymentRepository, orderService *OrderService, provider payments.PaymentProvider, invoicer Invoicer) *PaymentService {
+	return &PaymentService{
+		paymentRepo:  paymentRepo,
+		orderService: orderService,
+		provider:     provider,
+		invoicer:     invoicer,
+		now:          time.Now,
+	}
+}
+
+// ForOrder returns the payment attempts of an order the user can see
+func (s *PaymentService) ForOrder(user *models.User, orderID uint) ([]models.Payment, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil || (!user.IsStaff() && order.UserID != user.ID) {
+		return nil, ErrOrderNotFound
+	}
+	return s.paymentRepo.FindByOrderID(order.ID)
+}
+
+// Pay charges the order total to a card. The payment is captured as soon
+// as it is authorized; when the card needs 3-D Secure it stays in
+// requires_action until the provider's webhook arrives.
+func (s *PaymentService) Pay(ctx context.Context, userID, orderID uint, card string) (*models.Payment, error) {
+	card = strings.ReplaceAll(card, " ", "")
+	if !isCardNumber(card) {
+		return nil, ErrInvalidCardNumber
+	}
+	order, err := s.orderService.Get(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.charge(ctx, order, payments.AuthorizeRequest{Method: card}, card[len(card)-4:])
+}
+
+// ChargeSaved charges the order total to a saved payment method of the
+// order's customer while they are not present. Cards that need 3-D Secure
+// are declined.
+func (s *PaymentService) ChargeSaved(ctx context.Context, orderID, methodID uint) (*models.Payment, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil {
+		return nil, err
+	}
+	method, err := s.Method(order.UserID, methodID)
+	if err != nil {
+		return nil, err
+	}
+	return s.charge(ctx, order, payments.AuthorizeRequest{Method: method.Token, OffSession: true}, method.CardLast4)
+}
+
+func isCardNumber(card string) bool {
+	return len(card) >= 12 && strings.Trim(card, "0123456789") == ""
+}
+
+// charge authorizes the order total with req's payment method and captures
+// it
+func (s *PaymentService) charge(ctx context.Context, order *models.Order, req payments.AuthorizeRequest, last4 string) (*models.Payment, error) {
+	if order.Status != models.OrderStatusPendingPayment {
+		return nil, ErrOrderNotPayable
+	}
+	if s.now().After(order.PaymentDueAt) {
+		return nil, ErrPaymentOverdue
+	}
+
+	// 放棄的 3DS 驗證不能在之後又被授權
+	attempts, err := s.paymentRepo.FindByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attempts {
+		if attempts[i].Status == models.PaymentStatusRequiresAction {
+			s.void(ctx, &attempts[i])
+		}
+	}
+
+	payment := &models.Payment{
+		OrderID:        order.ID,
+		Provider:       s.provider.Name(),
+		Status:         models.PaymentStatusPending,
+		Amount:         order.Total,
+		CardLast4:      last4,
+		RefundedAmount: money.Zero(order.Total.Currency),
+	}
+	if err := s.paymentRepo.Create(payment); err != nil {
+		return nil, err
+	}
+
+	req.Reference = order.Number
+	req.Amount = order.Total
+	result, err := s.provider.Authorize(ctx, req)
+	if err != nil {
+		payment.Status = models.PaymentStatusFailed
+		payment.FailureReason = err.Error()
+		s.paymentRepo.Update(payment)
+		return payment, fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+
+	payment.ProviderRef = result.ProviderRef
+	payment.ActionURL = result.ActionURL
+	payment.FailureReason = result.FailureReason
+	switch result.Status {
+	case payments.StatusAuthorized:
+		payment.Status = models.PaymentStatusAuthorized
+	case payments.StatusRequiresAction:
+		payment.Status = models.PaymentStatusRequiresAction
+	default:
+		payment.Status = models.PaymentStatusDeclined
+	}
+	if err := s.paymentRepo.Update(payment); err != nil {
+		return nil, err
+	}
+
+	switch payment.Status {
+	case models.PaymentStatusDeclined:
+		return payment, fmt.Errorf("%w: %s", ErrPaymentDeclined, payment.FailureReason)
+	case models.PaymentStatusAuthorized:
+		return payment, s.capture(ctx, payment)
+	}
+	return payment, nil
+}
+
+// capture takes the authorized money and marks the order paid. An order
+// that stopped awaiting payment in the meantime is not charged.
+func (s *PaymentService) capture(ctx context.Context, payment *models.Payment) error {
+	order, err := s.orderService.GetAny(payment.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != models.OrderStatusPendingPayment || s.now().After(order.PaymentDueAt) {
+		s.void(ctx, payment)
+		return ErrOrderNotPayable
+	}
+
+	if _, err := s.provider.Capture(ctx, payment.ProviderRef, payment.Amount); err != nil {
+		return fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+	payment.Status = models.PaymentStatusCaptured
+	if err := s.paymentRepo.Update(payment); err != nil {
+		return err
+	}
+	return s.markPaid(ctx, payment)
+}
+
+func (s *PaymentService) markPaid(ctx context.Context, payment *models.Payment) error {
+	_, err := s.orderService.ChangeStatus(nil, payment.OrderID, StatusChange{
+		To:     models.OrderStatusPaid,
+		Reason: "Payment " + payment.ProviderRef + " captured",
+	})
+	if err == nil {
+		if s.invoicer != nil {
+			// 發票開立失敗不影響付款，留待人工補開
+			if err := s.invoicer.OrderPaid(ctx, payment.OrderID); err != nil {
+				log.Printf("payments: invoicing order %d: %v", payment.OrderID, err)
+			}
+		}
+		return nil
+	}
+
+	// 付款已扣但訂單無法轉為已付款（例如剛好逾期取消），只能全額退回
+	log.Printf("payments: order %d not marked paid after capture of %s: %v", payment.OrderID, payment.ProviderRef, err)
+	if _, refundErr := s.provider.Refund(ctx, payment.ProviderRef, payment.Amount); refundErr != nil {
+		log.Printf("payments: refund of %s failed: %v", payment.ProviderRef, refundErr)
+		return err
+	}
+	payment.Status = models.PaymentStatusRefunded
+	payment.RefundedAmount = payment.Amount
+	s.paymentRepo.Update(payment)
+	return err
+}
+
+func (s *PaymentService) void(ctx context.Context, payment *models.Payment) error {
+	if _, err := s.provider.Void(ctx, payment.ProviderRef); err != nil {
+		log.Printf("payments: void of %s failed: %v", payment.ProviderRef, err)
+		return err
+	}
+	payment.Status = models.PaymentStatusVoided
+	return s.paymentRepo.Update(payment)
+}
+
+// SaveMethod stores a card at the provider so the user can be charged
+// later without entering it
+func (s *PaymentService) SaveMethod(ctx context.Context, userID uint, card string) (*models.PaymentMethod, error) {
+	card = strings.ReplaceAll(card, " ", "")
+	if !isCardNumber(card) {
+		return nil, ErrInvalidCardNumber
+	}
+	saved, err := s.provider.SaveMethod(ctx, card)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+	method := &models.PaymentMethod{
+		UserID:    userID,
+		Provider:  s.provider.Name(),
+		Token:     saved.Token,
+		CardLast4: saved.CardLast4,
+	}
+	if err := s.paymentRepo.CreateMethod(method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// Methods returns the saved payment methods of a user
+func (s *PaymentService) Methods(userID uint) ([]models.PaymentMethod, error) {
+	return s.paymentRepo.FindMethodsByUserID(userID)
+}
+
+// Method returns a saved payment method of a user, hiding other users'
+// methods as not found
+func (s *PaymentService) Method(userID, id uint) (*models.PaymentMethod, error) {
+	method, err := s.paymentRepo.FindMethod(id)
+	if err != nil || method.UserID != userID || method.Provider != s.provider.Name() {
+		return nil, ErrPaymentMethodNotFound
+	}
+	return method, nil
+}
+
+// DeleteMethod forgets a saved payment method. Subscriptions charged to it
+// fail to renew until they are given another one.
+func (s *PaymentService) DeleteMethod(userID, id uint) error {
+	if _, err := s.Method(userID, id); err != nil {
+		return err
+	}
+	return s.paymentRepo.DeleteMethod(id)
+}
+
+// VoidOpen voids the attempts of an order that were authorized or are
+// waiting for 3-D Secure, so they can no longer be captured. Every attempt
+// is tried; the first failure is returned.
+func (s *PaymentService) VoidOpen(ctx context.Context, orderID uint) error {
+	attempts, err := s.paymentRepo.FindByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+	var failed error
+	for i := range attempts {
+		switch attempts[i].Status {
+		case models.PaymentStatusAuthorized, models.PaymentStatusRequiresAction:
+			if err := s.void(ctx, &attempts[i]); err != nil && failed == nil {
+				failed = fmt.Errorf("payment %d: %w", attempts[i].ID, err)
+			}
+		}
+	}
+	return failed
+}
+
+// HandleWebhook verifies and applies a provider webhook. Each event is
+// applied at most once; redeliveries are acknowledged without effect.
+func (s *PaymentService) HandleWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	event, err := s.provider.VerifyWebhook(payload, header)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	record := &models.PaymentEvent{
+		Provider:    s.provider.Name(),
+		EventID:     event.ID,
+		Type:        event.Type,
+		ProviderRef: event.ProviderRef,
+	}
+	created, err := s.paymentRepo.RecordEvent(record)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return nil
+	}
+
+	if err := s.apply(ctx, event); err != nil {
+		// 讓金流商重送時能再處理一次
+		if delErr := s.paymentRepo.DeleteEvent(record.Provider, record.EventID); delErr != nil {
+			log.Printf("payments: failed to forget event %s: %v", record.EventID, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *PaymentService) apply(ctx context.Context, event *payments.Event) error {
+	payment, err := s.paymentRepo.FindByProviderRef(s.provider.Name(), event.ProviderRef)
+	if err != nil {
+		log.Printf("payments: webhook %s for unknown charge %s ignored", event.ID, event.ProviderRef)
+		return nil
+	}
+
+	switch event.Type {
+	case payments.EventAuthorized:
+		if payment.Status != models.PaymentStatusRequiresAction {
+			return nil
+		}
+		payment.Status = models.PaymentStatusAuthorized
+		payment.ActionURL = ""
+		if err := s.paymentRepo.Update(payment); err != nil {
+			return err
+		}
+		// 只有金流商端失敗才需要重送，訂單狀態的問題重送也不會改變
+		if err := s.capture(ctx, payment); errors.Is(err, ErrPaymentFailed) {
+			return err
+		} else if err != nil {
+			log.Printf("payments: %v", err)
+		}
+	case payments.EventFailed:
+		if payment.Status != models.PaymentStatusRequiresAction && payment.Status != models.PaymentStatusPending {
+			return nil
+		}
+		payment.Status = models.PaymentStatusDeclined
+		payment.ActionURL = ""
+		payment.FailureReason = event.Reason
+		return s.paymentRepo.Update(payment)
+	case payments.EventCaptured:
+		if payment.Status != models.PaymentStatusAuthorized {
+			return nil
+		}
+		payment.Status = models.PaymentStatusCaptured
+		if err := s.paymentRepo.Update(payment); err != nil {
+			return err
+		}
+		if err := s.markPaid(ctx, payment); err != nil && !errors.Is(err, ErrOrderStatusChanged) {
+			log.Printf("payments: %v", err)
+		}
+	default:
+		log.Printf("payments: webhook %s of type %s ignored", event.ID, event.Type)
+	}
+	return nil
+}
+
+// SimulateChallenge completes a pending 3-D Secure challenge with the fake
+// provider, so the whole payment flow can be exercised offline
+func (s *PaymentService) SimulateChallenge(ctx context.Context, userID, paymentID uint, approve bool) (*models.Payment, error) {
+	fake, ok := s.provider.(*payments.FakeProvider)
+	if !ok {
+		return nil, ErrChallengeNotFaked
+	}
+	payment, err := s.paymentRepo.FindByID(paymentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if _, err := s.orderService.Get(userID, payment.OrderID); err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.Status != models.PaymentStatusRequiresAction {
+		return nil, ErrNoPendingChallenge
+	}
+
+	payload, header, err := fake.Complete3DS(payment.ProviderRef, approve)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.HandleWebhook(ctx, payload, header); err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.FindByID(paymentID)
+}
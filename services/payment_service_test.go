@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/payments"
+	"e-commerce/repository"
+)
+
+func newTestPayments(t *testing.T) (*testCheckout, *PaymentService, *payments.FakeProvider) {
+	t.Helper()
+	tc := newTestCheckout(t)
+	provider := payments.NewFakeProvider("secret")
+	return tc, NewPaymentService(repository.NewMockPaymentRepository(), tc.orders, provider, nil), provider
+}
+
+func TestPayCapturesAndMarksOrderPaid(t *testing.T) {
+	tc, s, _ := newTestPayments(t)
+	order := tc.placeOrder(t)
+
+	payment, err := s.Pay(context.Background(), tc.user.ID, order.ID, payments.TestCardSuccess)
+	if err != nil {
+		t.Fatalf("Pay() error = %v", err)
+	}
+	if payment.Status != models.PaymentStatusCaptured || payment.CardLast4 != "4242" || payment.Amount != order.Total {
+		t.Errorf("payment = %+v, want the order total captured", payment)
+	}
+	if paid, _ := tc.orders.Get(tc.user.ID, order.ID); paid.Status != models.OrderStatusPaid {
+		t.Errorf("order status = %q, want %q", paid.Status, models.OrderStatusPaid)
+	}
+	if _, err := s.Pay(context.Background(), tc.user.ID, order.ID, payments.TestCardSuccess); !errors.Is(err, ErrOrderNotPayable) {
+		t.Errorf("second Pay() error = %v, want %v", err, ErrOrderNotPayable)
+	}
+}
+
+func TestPayDeclined(t *testing.T) {
+	tc, s, _ := newTestPayments(t)
+	order := tc.placeOrder(t)
+
+	payment, err := s.Pay(context.Background(), tc.user.ID, order.ID, payments.TestCardInsufficientFunds)
+	if !errors.Is(err, ErrPaymentDeclined) || payment.FailureReason != "insufficient_funds" {
+		t.Fatalf("Pay() = %+v, %v, want declined for insufficient funds", payment, err)
+	}
+	if _, err := s.Pay(context.Background(), tc.user.ID, order.ID, payments.TestCardProcessingError); !errors.Is(err, ErrPaymentFailed) {
+		t.Errorf("Pay() error = %v, want %v", err, ErrPaymentFailed)
+	}
+	if unpaid, _ := tc.orders.Get(tc.user.ID, order.ID); unpaid.Status != models.OrderStatusPendingPayment {
+		t.Errorf("order status = %q, want it still awaiting payment", unpaid.Status)
+	}
+	if attempts, _ := s.ForOrder(&tc.user, order.ID); len(attempts) != 2 {
+		t.Errorf("ForOrder() returned %d attempts, want 2", len(attempts))
+	}
+}
+
+func TestThreeDSecureCompletesByWebhook(t *testing.T) {
+	tc, s, provider := newTestPayments(t)
+	order := tc.placeOrder(t)
+	ctx := context.Background()
+
+	payment, err := s.Pay(ctx, tc.user.ID, order.ID, payments.TestCard3DS)
+	if err != nil || payment.Status != models.PaymentStatusRequiresAction || payment.ActionURL == "" {
+		t.Fatalf("Pay() = %+v, %v, want a 3-D Secure challenge", payment, err)
+	}
+
+	payload, header, _ := provider.Complete3DS(payment.ProviderRef, true)
+	if err := s.HandleWebhook(ctx, payload, header); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+	// 重送同一事件不應再處理
+	if err := s.HandleWebhook(ctx, payload, header); err != nil {
+		t.Fatalf("redelivered HandleWebhook() error = %v", err)
+	}
+
+	attempts, _ := s.ForOrder(&tc.user, order.ID)
+	if attempts[0].Status != models.PaymentStatusCaptured {
+		t.Errorf("payment status = %q, want %q", attempts[0].Status, models.PaymentStatusCaptured)
+	}
+	history, _ := tc.orders.History(&tc.user, order.ID)
+	if len(history) != 1 || history[0].To != models.OrderStatusPaid {
+		t.Errorf("History() = %+v, want a single change to paid", history)
+	}
+}
+
+func TestSimulateFailedChallenge(t *testing.T) {
+	tc, s, _ := newTestPayments(t)
+	order := tc.placeOrder(t)
+	ctx := context.Background()
+
+	payment, _ := s.Pay(ctx, tc.user.ID, order.ID, payments.TestCard3DS)
+	if _, err := s.SimulateChallenge(ctx, 99, payment.ID, false); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("SimulateChallenge() by another user error = %v, want %v", err, ErrPaymentNotFound)
+	}
+	declined, err := s.SimulateChallenge(ctx, tc.user.ID, payment.ID, false)
+	if err != nil || declined.Status != models.PaymentStatusDeclined {
+		t.Fatalf("SimulateChallenge() = %+v, %v, want declined", declined, err)
+	}
+	if _, err := s.SimulateChallenge(ctx, tc.user.ID, payment.ID, true); !errors.Is(err, ErrNoPendingChallenge) {
+		t.Errorf("SimulateChallenge() again error = %v, want %v", err, ErrNoPendingChallenge)
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	tc, s, provider := newTestPayments(t)
+	order := tc.placeOrder(t)
+	payment, _ := s.Pay(context.Background(), tc.user.ID, order.ID, payments.TestCard3DS)
+
+	payload, header, _ := provider.Complete3DS(payment.ProviderRef, true)
+	header.Set(payments.FakeSignatureHeader, "t=1,v1=deadbeef")
+	if err := s.HandleWebhook(context.Background(), payload, header); !errors.Is(err, ErrInvalidWebhook) {
+		t.Errorf("HandleWebhook() error = %v, want %v", err, ErrInvalidWebhook)
+	}
+}
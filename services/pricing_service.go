@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+)
+
+var (
+	ErrPriceListNotFound = errors.New("price list not found")
+	ErrPriceNotFound     = errors.New("product has no price in this price list")
+	ErrPriceNotRounded   = errors.New("price must be a whole amount in this currency")
+)
+
+type PricingService struct {
+	priceListRepo repository.PriceListRepository
+	productRepo   repository.ProductRepository
+}
+
+func NewPricingService(priceListRepo repository.PriceListRepository, productRepo repository.ProductRepository) *PricingService {
+	return &PricingService{
+		priceListRepo: priceListRepo,
+		productRepo:   productRepo,
+	}
+}
+
+// ParseAcceptCurrency returns the currencies of an Accept-Currency style
+// header ordered by preference, e.g. "USD, JPY;q=0.5"
+func ParseAcceptCurrency(header string) []string {
+	type entry struct {
+		code string
+		q    float64
+	}
+	var entries []entry
+	for _, part := range strings.Split(header, ",") {
+		code, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || code == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			entries = append(entries, entry{code, q})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+	codes := make([]string, len(entries))
+	for i, e := range entries {
+		codes[i] = e.code
+	}
+	return codes
+}
+
+// SelectPriceList picks the price list for a request: the first requested
+// currency with an active list, then the user's preferred currency, then
+// the default list
+func (s *PricingService) SelectPriceList(acceptCurrency string, user *models.User) (*models.PriceList, error) {
+	candidates := ParseAcceptCurrency(acceptCurrency)
+	if user != nil && user.PreferredCurrency != "" {
+		candidates = append(candidates, user.PreferredCurrency)
+	}
+	for _, currency := range candidates {
+		if priceList, err := s.priceListRepo.FindActiveByCurrency(currency); err == nil {
+			return priceList, nil
+		}
+	}
+
+	priceList, err := s.priceListRepo.FindDefault()
+	if err != nil {
+		return nil, ErrPriceListNotFound
+	}
+	return priceList, nil
+}
+
+func (s *PricingService) ListPriceLists() ([]models.PriceList, error) {
+	return s.priceListRepo.FindAll()
+}
+
+func (s *PricingService) GetPriceList(id uint) (*models.PriceList, error) {
+	priceList, err := s.priceListRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrPriceListNotFound
+	}
+	return priceList, nil
+}
+
+func (s *PricingService) CreatePriceList(code, name, currency, market string, isDefault, active bool) (*models.PriceList, error) {
+	if !money.IsSupported(currency) {
+		return nil, money.ErrUnknownCurrency
+	}
+
+	priceList := &models.PriceList{
+		Code:      strings.ToUpper(code),
+		Name:      name,
+		Currency:  strings.ToUpper(currency),
+		Market:    strings.ToUpper(market),
+		IsDefault: isDefault,
+		Active:    active || isDefault,
+	}
+	if err := s.priceListRepo.Create(priceList); err != nil {
+		return nil, errors.New("price list code already exists")
+	}
+	return priceList, nil
+}
+
+// UpdatePriceList changes the descriptive fields and flags; the currency
+// of a list is fixed because its prices are stored in that currency
+func (s *PricingService) UpdatePriceList(id uint, name, market string, isDefault, active bool) (*models.PriceList, error) {
+	priceList, err := s.GetPriceList(id)
+	if err != nil {
+		return nil, err
+	}
+
+	priceList.Name = name
+	priceList.Market = strings.ToUpper(market)
+	priceList.IsDefault = isDefault
+	priceList.Active = active || isDefault
+	if err := s.priceListRepo.Update(priceList); err != nil {
+		return nil, errors.New("failed to update price list")
+	}
+	return priceList, nil
+}
+
+// SetPrice parses amount in the list's currency and stores it for a product
+func (s *PricingService) SetPrice(priceListID, productID uint, amount string) (*models.ProductPrice, error) {
+	priceList, err := s.GetPriceList(priceListID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	price, err := money.Parse(amount, priceList.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if price.IsNegative() {
+		return nil, money.ErrInvalidAmount
+	}
+	if !price.IsRounded() {
+		return nil, ErrPriceNotRounded
+	}
+
+	productPrice := &models.ProductPrice{
+		PriceListID: priceListID,
+		ProductID:   productID,
+		Price:       price,
+	}
+	if err := s.priceListRepo.SetPrice(productPrice); err != nil {
+		return nil, errors.New("failed to save price")
+	}
+	return productPrice, nil
+}
+
+func (s *PricingService) DeletePrice(priceListID, productID uint) error {
+	if _, err := s.GetPriceList(priceListID); err != nil {
+		return err
+	}
+	return s.priceListRepo.DeletePrice(priceListID, productID)
+}
+
+// PriceFor returns the price of a product in a price list
+func (s *PricingService) PriceFor(priceList *models.PriceList, productID uint) (money.Money, error) {
+	price, err := s.priceListRepo.FindPrice(priceList.ID, productID)
+	if err != nil {
+		return money.Money{}, ErrPriceNotFound
+	}
+	return price.Price, nil
+}
+
+// AttachPrices sets Product.Price from the given price list. Products
+// without a price in that list are left without one.
+func (s *PricingService) AttachPrices(priceList *models.PriceList, products []models.Product) error {
+	if priceList == nil || len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	prices, err := s.priceListRepo.FindPrices(priceList.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	byProduct := make(map[uint]money.Money, len(prices))
+	for _, p := range prices {
+		byProduct[p.ProductID] = p.Price
+	}
+	for i := range products {
+		if price, ok := byProduct[products[i].ID]; ok {
+			products[i].Price = &price
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+func newTestPricingService(t *testing.T) *PricingService {
+	t.Helper()
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee"})
+	productRepo.Create(&models.Product{ID: 2, SKU: "SKU-2", Name: "Tea"})
+
+	pricingService := NewPricingService(repository.NewMockPriceListRepository(), productRepo)
+	for _, pl := range []struct {
+		code, currency string
+		isDefault      bool
+		active         bool
+	}{
+		{"TW-TWD", "TWD", true, true},
+		{"US-USD", "USD", false, true},
+		{"JP-JPY", "JPY", false, false},
+	} {
+		if _, err := pricingService.CreatePriceList(pl.code, pl.code, pl.currency, "", pl.isDefault, pl.active); err != nil {
+			t.Fatalf("CreatePriceList() error = %v", err)
+		}
+	}
+	return pricingService
+}
+
+func TestParseAcceptCurrency(t *testing.T) {
+	got := ParseAcceptCurrency("jpy;q=0.5, USD, *;q=0.1, EUR;q=0")
+	want := []string{"USD", "JPY"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAcceptCurrency() = %v, want %v", got, want)
+	}
+}
+
+func TestSelectPriceList(t *testing.T) {
+	pricingService := newTestPricingService(t)
+
+	tests := []struct {
+		name   string
+		header string
+		user   *models.User
+		want   string
+	}{
+		{"no preference uses default", "", nil, "TWD"},
+		{"header", "USD", nil, "USD"},
+		{"inactive list is skipped", "JPY, USD;q=0.5", nil, "USD"},
+		{"user preference", "", &models.User{PreferredCurrency: "USD"}, "USD"},
+		{"header wins over user", "TWD", &models.User{PreferredCurrency: "USD"}, "TWD"},
+		{"unknown currency falls back", "EUR", nil, "TWD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priceList, err := pricingService.SelectPriceList(tt.header, tt.user)
+			if err != nil {
+				t.Fatalf("SelectPriceList() error = %v", err)
+			}
+			if priceList.Currency != tt.want {
+				t.Errorf("SelectPriceList() currency = %s, want %s", priceList.Currency, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPrice(t *testing.T) {
+	pricingService := newTestPricingService(t)
+
+	tests := []struct {
+		name        string
+		priceListID uint
+		productID   uint
+		amount      string
+		want        int64
+		wantErr     bool
+	}{
+		{"TWD whole dollars", 1, 1, "1200", 120000, false},
+		{"TWD cents are rejected", 1, 1, "1200.50", 0, true},
+		{"USD cents", 2, 1, "39.99", 3999, false},
+		{"negative", 2, 1, "-1", 0, true},
+		{"unknown product", 2, 99, "1", 0, true},
+		{"unknown price list", 99, 1, "1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := pricingService.SetPrice(tt.priceListID, tt.productID, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetPrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && price.Price.Amount != tt.want {
+				t.Errorf("SetPrice() amount = %d, want %d", price.Price.Amount, tt.want)
+			}
+		})
+	}
+
+	twd, _ := pricingService.GetPriceList(1)
+	products := []models.Product{{ID: 1}, {ID: 2}}
+	if err := pricingService.AttachPrices(twd, products); err != nil {
+		t.Fatalf("AttachPrices() error = %v", err)
+	}
+	if products[0].Price == nil || products[0].Price.String() != "NT$1,200" {
+		t.Errorf("AttachPrices() price = %v, want NT$1,200", products[0].Price)
+	}
+	if products[1].Price != nil {
+		t.Errorf("AttachPrices() set a price for an unpriced product: %v", products[1].Price)
+	}
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/tax"
+)
+
+const (
+	defaultPreviewTTL = 24 * time.Hour
+	// publishInterval is how often scheduled publishing is applied
+	publishInterval = time.Minute
+)
+
+var (
+	ErrProductNotFound     = errors.New("product not found")
+	ErrInvalidTransition   = errors.New("product cannot move to this status")
+	ErrInvalidSchedule     = errors.New("invalid publish schedule")
+	ErrInvalidPreviewToken = errors.New("invalid or expired preview token")
+	ErrRevisionNotFound    = errors.New("revision not found")
+	ErrInvalidTaxClass     = errors.New("invalid tax class")
+	ErrInvalidWeight       = errors.New("weight cannot be negative")
+)
+
+// productTransitions lists the statuses a product may move to from each status
+var productTransitions = map[string][]string{
+	models.ProductStatusDraft:     {models.ProductStatusInReview, models.ProductStatusScheduled, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusInReview:  {models.ProductStatusDraft, models.ProductStatusScheduled, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusScheduled: {models.ProductStatusDraft, models.ProductStatusScheduled, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusPublished: {models.ProductStatusDraft, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusArchived:  {models.ProductStatusDraft},
+}
+
+// FieldChange is one field that differs between two product revisions
+type FieldChange struct {
+	Field string `json:"field" example:"name"`
+	From  string `json:"from" example:"Ethiopia 250g"`
+	To    string `json:"to" example:"Ethiopia Yirgacheffe 250g"`
+}
+
+// ProductDetails are the editable fields of a product
+type ProductDetails struct {
+	Name        string
+	Description string
+	Category    string
+	TaxClass    string
+	WeightGrams int
+}
+
+type ProductService struct {
+	productRepo  repository.ProductRepository
+	revisionRepo repository.ProductRevisionRepository
+	previewKey   []byte
+	previewTTL   time.Duration
+	now          func() time.Time
+}
+
+func NewProductService(productRepo repository.ProductRepository, revisionRepo repository.ProductRevisionRepository) *ProductService {
+	key := os.Getenv("PREVIEW_SIGNING_KEY")
+	if key == "" {
+		key = os.Getenv("JWT_SECRET")
+	}
+	previewKey := []byte(key)
+	if len(previewKey) == 0 {
+		// 未設定金鑰時使用隨機金鑰，預覽連結在重啟後失效
+		previewKey = make([]byte, 32)
+		rand.Read(previewKey)
+	}
+
+	ttl := defaultPreviewTTL
+	if v, err := time.ParseDuration(os.Getenv("PREVIEW_TOKEN_TTL")); err == nil && v > 0 {
+		ttl = v
+	}
+	return &ProductService{
+		productRepo:  productRepo,
+		revisionRepo: revisionRepo,
+		previewKey:   previewKey,
+		previewTTL:   ttl,
+		now:          time.Now,
+	}
+}
+
+// save updates a product and records what was written as a revision by
+// author, both or neither
+func (s *ProductService) save(author models.User, product *models.Product, action, note string) error {
+	if err := s.revisionRepo.Save(product, newRevision(author, action, note)); err != nil {
+		return errors.New("failed to update product")
+	}
+	return nil
+}
+
+func newRevision(author models.User, action, note string) *models.ProductRevision {
+	return &models.ProductRevision{
+		Action:     action,
+		AuthorID:   &author.ID,
+		AuthorName: author.Name,
+		Note:       note,
+	}
+}
+
+func (s *ProductService) Create(author models.User, sku string, details ProductDetails) (*models.Product, error) {
+	sku = strings.TrimSpace(sku)
+	taxClass, err := normalizeTaxClass(details.TaxClass)
+	if err != nil {
+		return nil, err
+	}
+	if details.WeightGrams < 0 {
+		return nil, ErrInvalidWeight
+	}
+	if existing, _ := s.productRepo.FindBySKU(sku); existing != nil {
+		return nil, errors.New("sku already exists")
+	}
+
+	product := &models.Product{
+		SKU:         sku,
+		Name:        details.Name,
+		Description: details.Description,
+		Category:    normalizeCategory(details.Category),
+		TaxClass:    taxClass,
+		WeightGrams: details.WeightGrams,
+		Status:      models.ProductStatusDraft,
+	}
+	if err := s.revisionRepo.Create(product, newRevision(author, models.RevisionActionCreate, "")); err != nil {
+		return nil, errors.New("failed to create product")
+	}
+	return product, nil
+}
+
+// normalizeCategory stores categories as lower-case slugs
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// normalizeTaxClass defaults products to the standard rate
+func normalizeTaxClass(taxClass string) (string, error) {
+	taxClass = strings.ToLower(strings.TrimSpace(taxClass))
+	if taxClass == "" {
+		return tax.ClassStandard, nil
+	}
+	if !tax.IsClass(taxClass) {
+		return "", ErrInvalidTaxClass
+	}
+	return taxClass, nil
+}
+
+func (s *ProductService) GetByID(id uint) (*models.Product, error) {
+	product, err := s.productRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	return product, nil
+}
+
+// GetVisible returns a product only if customers can see it
+func (s *ProductService) GetVisible(id uint) (*models.Product, error) {
+	product, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsVisible(s.now()) {
+		return nil, ErrProductNotFound
+	}
+	return product, nil
+}
+
+// List returns products in any status for staff, optionally filtered by status
+func (s *ProductService) List(status string, page, pageSize int) ([]models.Product, error) {
+	offset, limit := paginate(page, pageSize)
+	if status != "" {
+		return s.productRepo.FindByStatus(status, offset, limit)
+	}
+	return s.productRepo.FindAll(offset, limit)
+}
+
+// ListVisible returns the products customers can see
+func (s *ProductService) ListVisible(page, pageSize int) ([]models.Product, error) {
+	offset, limit := paginate(page, pageSize)
+	return s.productRepo.FindVisible(s.now(), offset, limit)
+}
+
+func (s *ProductService) Update(author models.User, id uint, details ProductDetails) (*models.Product, error) {
+	product, err := s.productRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	taxClass, err := normalizeTaxClass(details.TaxClass)
+	if err != nil {
+		return nil, err
+	}
+	if details.WeightGrams < 0 {
+		return nil, ErrInvalidWeight
+	}
+
+	product.Name = details.Name
+	product.Description = details.Description
+	product.Category = normalizeCategory(details.Category)
+	product.TaxClass = taxClass
+	product.WeightGrams = details.WeightGrams
+	if err := s.save(author, product, models.RevisionActionUpdate, ""); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *ProductService) Delete(id uint) error {
+	if _, err := s.productRepo.FindByID(id); err != nil {
+		return ErrProductNotFound
+	}
+	return s.productRepo.Delete(id)
+}
+
+// ChangeStatus moves a product through the publishing workflow. Scheduling
+// needs a future publishAt; unpublishAt may be set on scheduled and
+// published products to take them down again.
+func (s *ProductService) ChangeStatus(author models.User, id uint, status string, publishAt, unpublishAt *time.Time) (*models.Product, error) {
+	product, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := false
+	for _, next := range productTransitions[product.Status] {
+		allowed = allowed || next == status
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, product.Status, status)
+	}
+
+	now := s.now()
+	switch status {
+	case models.ProductStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return nil, fmt.Errorf("%w: publish_at must be in the future", ErrInvalidSchedule)
+		}
+		if unpublishAt != nil && !unpublishAt.After(*publishAt) {
+			return nil, fmt.Errorf("%w: unpublish_at must be after publish_at", ErrInvalidSchedule)
+		}
+		product.PublishAt = publishAt
+		product.UnpublishAt = unpublishAt
+	case models.ProductStatusPublished:
+		if unpublishAt != nil && !unpublishAt.After(now) {
+			return nil, fmt.Errorf("%w: unpublish_at must be in the future", ErrInvalidSchedule)
+		}
+		if product.Status != models.ProductStatusPublished {
+			product.PublishedAt = &now
+		}
+		product.PublishAt = nil
+		product.UnpublishAt = unpublishAt
+	default:
+		product.PublishAt = nil
+		product.UnpublishAt = nil
+	}
+
+	product.Status = status
+	if err := s.save(author, product, models.RevisionActionStatus, ""); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *ProductService) Revisions(productID uint, page, pageSize int) ([]models.ProductRevision, error) {
+	if _, err := s.GetByID(productID); err != nil {
+		return nil, err
+	}
+	offset, limit := paginate(page, pageSize)
+	return s.revisionRepo.FindByProductID(productID, offset, limit)
+}
+
+func (s *ProductService) Revision(productID uint, version int) (*models.ProductRevision, error) {
+	revision, err := s.revisionRepo.FindByVersion(productID, version)
+	if err != nil {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, nil
+}
+
+// DiffRevisions lists the fields that changed between two versions
+func (s *ProductService) DiffRevisions(productID uint, from, to int) ([]FieldChange, error) {
+	a, err := s.Revision(productID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.Revision(productID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(a.Snapshot, b.Snapshot), nil
+}
+
+func diffSnapshots(a, b models.ProductSnapshot) []FieldChange {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"sku", a.SKU, b.SKU},
+		{"name", a.Name, b.Name},
+		{"description", a.Description, b.Description},
+		{"category", a.Category, b.Category},
+		{"tax_class", a.TaxClass, b.TaxClass},
+		{"weight_grams", strconv.Itoa(a.WeightGrams), strconv.Itoa(b.WeightGrams)},
+		{"status", a.Status, b.Status},
+		{"publish_at", formatTime(a.PublishAt), formatTime(b.PublishAt)},
+		{"unpublish_at", formatTime(a.UnpublishAt), formatTime(b.UnpublishAt)},
+	}
+
+	changes := []FieldChange{}
+	for _, f := range fields {
+		if f.from != f.to {
+			changes = append(changes, FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+	return changes
+}
+
+// Rollback restores the content of an earlier revision and records it as a
+// new revision. The publishing status is left alone so a rollback never
+// publishes or hides a product by surprise.
+func (s *ProductService) Rollback(author models.User, productID uint, version int) (*models.Product, error) {
+	product, err := s.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.Revision(productID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Name = revision.Snapshot.Name
+	product.Description = revision.Snapshot.Description
+	product.Category = revision.Snapshot.Category
+	if revision.Snapshot.TaxClass != "" {
+		product.TaxClass = revision.Snapshot.TaxClass
+	}
+	product.WeightGrams = revision.Snapshot.WeightGrams
+	if err := s.save(author, product, models.RevisionActionRollback, fmt.Sprintf("Rolled back to version %d", version)); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (s *ProductService) previewSignature(id uint, expires int64) string {
+	mac := hmac.New(sha256.New, s.previewKey)
+	fmt.Fprintf(mac, "preview:%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PreviewToken returns a signed token that lets anyone holding it view the
+// product before it is published
+func (s *ProductService) PreviewToken(id uint) (string, time.Time, error) {
+	if _, err := s.GetByID(id); err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := s.now().Add(s.previewTTL).Truncate(time.Second)
+	token := strconv.FormatInt(expiresAt.Unix(), 10) + "." + s.previewSignature(id, expiresAt.Unix())
+	return token, expiresAt, nil
+}
+
+// GetPreview returns a product in any status given a valid preview token
+func (s *ProductService) GetPreview(id uint, token string) (*models.Product, error) {
+	expiresPart, signature, ok := strings.Cut(token, ".")
+	expires, err := strconv.ParseInt(expiresPart, 10, 64)
+	if !ok || err != nil || s.now().Unix() > expires {
+		return nil, ErrInvalidPreviewToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.previewSignature(id, expires))) {
+		return nil, ErrInvalidPreviewToken
+	}
+	return s.GetByID(id)
+}
+
+// ApplySchedules publishes and archives products whose scheduled times
+// have passed, recording a revision without an author for each
+func (s *ProductService) ApplySchedules() (published, archived int64, err error) {
+	now := s.now()
+	archive := models.ProductRevision{Action: models.RevisionActionStatus, Note: "Archived on schedule"}
+	if archived, err = s.revisionRepo.ArchiveExpired(now, archive); err != nil {
+		return 0, 0, err
+	}
+	publish := models.ProductRevision{Action: models.RevisionActionStatus, Note: "Published on schedule"}
+	if published, err = s.revisionRepo.PublishScheduled(now, publish); err != nil {
+		return 0, archived, err
+	}
+	return published, archived, nil
+}
+
+// Run applies publishing schedules until ctx is cancelled
+func (s *ProductService) Run(ctx context.Context) {
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+	for {
+		published, archived, err := s.ApplySchedules()
+		if err != nil {
+			log.Printf("publishing: failed to apply schedules: %v", err)
+		} else if published+archived > 0 {
+			log.Printf("publishing: published %d and archived %d products", published, archived)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
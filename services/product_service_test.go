@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+var testEditor = models.User{ID: 9, Name: "Eve Editor", Role: models.RoleStaff}
+
+func newTestProductService(t *testing.T) (*ProductService, *models.Product) {
+	t.Helper()
+	t.Setenv("PREVIEW_SIGNING_KEY", "test-key")
+	productRepo := repository.NewMockProductRepository()
+	productService := NewProductService(productRepo, repository.NewMockProductRevisionRepository(productRepo))
+	product, err := productService.Create(testEditor, "SKU-1", ProductDetails{Name: "Coffee", Category: "coffee"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return productService, product
+}
+
+func TestNewProductsAreHidden(t *testing.T) {
+	s, product := newTestProductService(t)
+
+	if product.Status != models.ProductStatusDraft {
+		t.Errorf("Status = %q, want %q", product.Status, models.ProductStatusDraft)
+	}
+	if _, err := s.GetVisible(product.ID); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("GetVisible() error = %v, want %v", err, ErrProductNotFound)
+	}
+	if products, _ := s.ListVisible(1, 20); len(products) != 0 {
+		t.Errorf("ListVisible() = %d products, want 0", len(products))
+	}
+
+	if _, err := s.ChangeStatus(testEditor, product.ID, models.ProductStatusPublished, nil, nil); err != nil {
+		t.Fatalf("ChangeStatus() error = %v", err)
+	}
+	if products, _ := s.ListVisible(1, 20); len(products) != 1 || products[0].PublishedAt == nil {
+		t.Errorf("ListVisible() = %+v, want the published product", products)
+	}
+}
+
+func TestChangeStatusRules(t *testing.T) {
+	s, product := newTestProductService(t)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	if _, err := s.ChangeStatus(testEditor, product.ID, models.ProductStatusScheduled, &past, nil); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("schedule in the past: error = %v, want %v", err, ErrInvalidSchedule)
+	}
+	if _, err := s.ChangeStatus(testEditor, product.ID, models.ProductStatusScheduled, &future, &future); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("unpublish before publish: error = %v, want %v", err, ErrInvalidSchedule)
+	}
+
+	s.ChangeStatus(testEditor, product.ID, models.ProductStatusArchived, nil, nil)
+	if _, err := s.ChangeStatus(testEditor, product.ID, models.ProductStatusPublished, nil, nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("archived to published: error = %v, want %v", err, ErrInvalidTransition)
+	}
+	if _, err := s.ChangeStatus(testEditor, product.ID, models.ProductStatusDraft, nil, nil); err != nil {
+		t.Errorf("archived to draft: error = %v", err)
+	}
+}
+
+func TestScheduledPublishing(t *testing.T) {
+	s, product := newTestProductService(t)
+	now := time.Now()
+	publishAt := now.Add(time.Hour)
+	unpublishAt := now.Add(2 * time.Hour)
+
+	if _, err := s.ChangeStatus(testEditor, product.ID, models.ProductStatusScheduled, &publishAt, &unpublishAt); err != nil {
+		t.Fatalf("ChangeStatus() error = %v", err)
+	}
+	if _, err := s.GetVisible(product.ID); err == nil {
+		t.Error("scheduled product is visible before publish_at")
+	}
+
+	// 排程時間一到即可見，不必等背景工作執行
+	s.now = func() time.Time { return publishAt }
+	if _, err := s.GetVisible(product.ID); err != nil {
+		t.Errorf("GetVisible() at publish_at error = %v", err)
+	}
+	if published, _, _ := s.ApplySchedules(); published != 1 {
+		t.Errorf("ApplySchedules() published %d, want 1", published)
+	}
+
+	s.now = func() time.Time { return unpublishAt }
+	if _, archived, _ := s.ApplySchedules(); archived != 1 {
+		t.Errorf("ApplySchedules() archived %d, want 1", archived)
+	}
+	got, _ := s.GetByID(product.ID)
+	if got.Status != models.ProductStatusArchived {
+		t.Errorf("Status = %q, want %q", got.Status, models.ProductStatusArchived)
+	}
+	// 排程變更記為沒有作者的版本
+	revisions, _ := s.Revisions(product.ID, 1, 20)
+	if len(revisions) < 2 || revisions[0].AuthorID != nil || revisions[0].Snapshot.Status != models.ProductStatusArchived ||
+		revisions[1].AuthorID != nil || revisions[1].Snapshot.Status != models.ProductStatusPublished {
+		t.Errorf("revisions = %+v, want scheduled publish and archive without an author", revisions)
+	}
+}
+
+func TestPreviewToken(t *testing.T) {
+	s, product := newTestProductService(t)
+	other, _ := s.Create(testEditor, "SKU-2", ProductDetails{Name: "Tea", Category: "tea"})
+
+	token, expiresAt, err := s.PreviewToken(product.ID)
+	if err != nil {
+		t.Fatalf("PreviewToken() error = %v", err)
+	}
+	if _, err := s.GetPreview(product.ID, token); err != nil {
+		t.Errorf("GetPreview() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		id    uint
+		token string
+	}{
+		{"other product", other.ID, token},
+		{"tampered expiry", product.ID, "9999999999" + token[len(token)-65:]},
+		{"garbage", product.ID, "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.GetPreview(tt.id, tt.token); !errors.Is(err, ErrInvalidPreviewToken) {
+				t.Errorf("GetPreview() error = %v, want %v", err, ErrInvalidPreviewToken)
+			}
+		})
+	}
+
+	s.now = func() time.Time { return expiresAt.Add(time.Second) }
+	if _, err := s.GetPreview(product.ID, token); !errors.Is(err, ErrInvalidPreviewToken) {
+		t.Errorf("expired token: error = %v, want %v", err, ErrInvalidPreviewToken)
+	}
+}
+
+func TestRevisionHistoryAndRollback(t *testing.T) {
+	s, product := newTestProductService(t)
+	other := models.User{ID: 10, Name: "Oscar"}
+
+	s.Update(testEditor, product.ID, ProductDetails{Name: "Coffee beans", Description: "Medium roast", Category: "coffee"})
+	s.Update(other, product.ID, ProductDetails{Name: "BROKEN", Category: "coffee"})
+
+	revisions, err := s.Revisions(product.ID, 1, 20)
+	if err != nil || len(revisions) != 3 {
+		t.Fatalf("Revisions() = %d, %v, want 3", len(revisions), err)
+	}
+	if latest := revisions[0]; latest.Version != 3 || latest.AuthorName != "Oscar" || latest.Snapshot.Name != "BROKEN" {
+		t.Errorf("latest revision = %+v, want version 3 by Oscar", latest)
+	}
+
+	changes, err := s.DiffRevisions(product.ID, 2, 3)
+	if err != nil {
+		t.Fatalf("DiffRevisions() error = %v", err)
+	}
+	want := []FieldChange{
+		{Field: "name", From: "Coffee beans", To: "BROKEN"},
+		{Field: "description", From: "Medium roast", To: ""},
+	}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("DiffRevisions() = %+v, want %+v", changes, want)
+	}
+
+	restored, err := s.Rollback(testEditor, product.ID, 2)
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if restored.Name != "Coffee beans" || restored.Description != "Medium roast" {
+		t.Errorf("Rollback() = %+v, want version 2 content", restored)
+	}
+	revisions, _ = s.Revisions(product.ID, 1, 20)
+	if revisions[0].Action != models.RevisionActionRollback || revisions[0].Version != 4 {
+		t.Errorf("latest revision = %+v, want a rollback recorded as version 4", revisions[0])
+	}
+	if _, err := s.Rollback(testEditor, product.ID, 99); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Rollback() error = %v, want %v", err, ErrRevisionNotFound)
+	}
+}
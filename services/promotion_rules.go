@@ -0,0 +1,234 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/money"
+)
+
+// promotionLine is a priced cart line as the promotion rules see it
+type promotionLine struct {
+	SKU       string
+	Category  string
+	Quantity  int
+	UnitPrice money.Money
+	Total     money.Money
+}
+
+// promotionCart is what the rules are evaluated against
+type promotionCart struct {
+	Currency string
+	Lines    []promotionLine
+	Group    string
+	Coupon   *models.Coupon
+}
+
+// promotionResult is the outcome of the rules for a cart. Discounts holds
+// the explanation of each line, in the order of the cart lines.
+type promotionResult struct {
+	Discounts    [][]models.LineDiscount
+	Applied      []models.AppliedPromotion
+	Total        money.Money
+	FreeShipping bool
+}
+
+// applyPromotions runs promotions, sorted by descending priority, against
+// a cart. Each rule discounts what the rules before it left of a line, so
+// stacked rules never take a line below zero.
+func applyPromotions(promotions []models.Promotion, cart promotionCart) promotionResult {
+	result := promotionResult{
+		Discounts: make([][]models.LineDiscount, len(cart.Lines)),
+		Total:     money.Zero(cart.Currency),
+	}
+	remaining := make([]money.Money, len(cart.Lines))
+	for i, line := range cart.Lines {
+		remaining[i] = line.Total
+	}
+
+	for _, promotion := range promotions {
+		if promotion.Exclusive && len(result.Applied) > 0 {
+			continue
+		}
+		if !promotionEligible(promotion, cart) {
+			continue
+		}
+		lines := qualifyingLines(promotion, cart)
+		if len(lines) == 0 {
+			continue
+		}
+
+		applied := models.AppliedPromotion{
+			PromotionID:  promotion.ID,
+			Name:         promotion.Name,
+			Discount:     money.Zero(cart.Currency),
+			FreeShipping: promotion.Type == models.PromotionFreeShipping,
+		}
+		if promotion.RequiresCoupon {
+			applied.CouponID = &cart.Coupon.ID
+			applied.CouponCode = cart.Coupon.Code
+		}
+		for k, share := range promotionDiscounts(promotion, cart, lines, remaining) {
+			i := lines[k]
+			if share.Cmp(remaining[i]) > 0 {
+				share = remaining[i]
+			}
+			if share.Amount <= 0 {
+				continue
+			}
+			remaining[i] = remaining[i].Sub(share)
+			applied.Discount = applied.Discount.Add(share)
+			result.Discounts[i] = append(result.Discounts[i], models.LineDiscount{
+				PromotionID: promotion.ID,
+				Name:        promotion.Name,
+				Amount:      share,
+			})
+		}
+		if applied.Discount.IsZero() && !applied.FreeShipping {
+			continue
+		}
+
+		result.Applied = append(result.Applied, applied)
+		result.Total = result.Total.Add(applied.Discount)
+		result.FreeShipping = result.FreeShipping || applied.FreeShipping
+		if promotion.Exclusive {
+			break
+		}
+	}
+	return result
+}
+
+// promotionEligible checks the cart-wide conditions of a promotion
+func promotionEligible(promotion models.Promotion, cart promotionCart) bool {
+	if promotion.Currency != "" && promotion.Currency != cart.Currency {
+		return false
+	}
+	if promotion.RequiresCoupon && (cart.Coupon == nil || cart.Coupon.PromotionID != promotion.ID) {
+		return false
+	}
+	if groups := promotion.Conditions.CustomerGroups; len(groups) > 0 && !containsFold(groups, cart.Group) {
+		return false
+	}
+	return true
+}
+
+// qualifyingLines returns the indexes of the lines a promotion applies to,
+// or none when they do not reach its minimum subtotal
+func qualifyingLines(promotion models.Promotion, cart promotionCart) []int {
+	conditions := promotion.Conditions
+	subtotal := money.Zero(cart.Currency)
+	var lines []int
+	for i, line := range cart.Lines {
+		if len(conditions.Categories) > 0 || len(conditions.SKUs) > 0 {
+			if !containsFold(conditions.Categories, line.Category) && !containsFold(conditions.SKUs, line.SKU) {
+				continue
+			}
+		}
+		lines = append(lines, i)
+		subtotal = subtotal.Add(line.Total)
+	}
+	if minimum := conditions.MinSubtotal; minimum != nil && (minimum.Currency != cart.Currency || subtotal.Cmp(*minimum) < 0) {
+		return nil
+	}
+	return lines
+}
+
+// promotionDiscounts returns what a promotion takes off each qualifying
+// line, before capping at what is left of the line
+func promotionDiscounts(promotion models.Promotion, cart promotionCart, lines []int, remaining []money.Money) []money.Money {
+	shares := make([]money.Money, len(lines))
+	for k := range shares {
+		shares[k] = money.Zero(cart.Currency)
+	}
+
+	switch promotion.Type {
+	case models.PromotionPercentOff:
+		for k, i := range lines {
+			shares[k] = remaining[i].MulFrac(int64(promotion.Percent), 100).Round()
+		}
+	case models.PromotionFixedAmount:
+		return spreadDiscount(promotion.Amount, lines, remaining)
+	case models.PromotionTiered:
+		subtotal := money.Zero(cart.Currency)
+		for _, i := range lines {
+			subtotal = subtotal.Add(cart.Lines[i].Total)
+		}
+		// 取符合門檻的最高一階
+		var discount *money.Money
+		for _, tier := range promotion.Tiers {
+			if tier.Threshold.Currency == cart.Currency && subtotal.Cmp(tier.Threshold) >= 0 {
+				d := tier.Discount
+				discount = &d
+			}
+		}
+		if discount != nil {
+			return spreadDiscount(*discount, lines, remaining)
+		}
+	case models.PromotionBuyXGetY:
+		for k, free := range freeUnits(promotion, cart, lines) {
+			shares[k] = cart.Lines[lines[k]].UnitPrice.Mul(int64(free))
+		}
+	}
+	return shares
+}
+
+// spreadDiscount splits an amount off the qualifying lines in proportion
+// to what is left of them, in whole currency increments where possible
+func spreadDiscount(amount money.Money, lines []int, remaining []money.Money) []money.Money {
+	weights := make([]int64, len(lines))
+	var left int64
+	for k, i := range lines {
+		weights[k] = remaining[i].Amount
+		left += remaining[i].Amount
+	}
+	if amount.Amount > left {
+		amount.Amount = left
+	}
+
+	increment := int64(1)
+	if c, err := money.Lookup(amount.Currency); err == nil && amount.Amount%c.Increment == 0 {
+		increment = c.Increment
+	}
+	shares := money.New(amount.Amount/increment, amount.Currency).Allocate(weights...)
+	for k := range shares {
+		shares[k] = shares[k].Mul(increment)
+	}
+	return shares
+}
+
+// freeUnits returns how many units of each qualifying line a buy X get Y
+// promotion gives away. Units are grouped from the most expensive down and
+// the cheapest Y of every full group of X+Y are free.
+func freeUnits(promotion models.Promotion, cart promotionCart, lines []int) []int {
+	free := make([]int, len(lines))
+	group := promotion.BuyQuantity + promotion.GetQuantity
+	if promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+		return free
+	}
+
+	var units []int
+	for k, i := range lines {
+		for n := 0; n < cart.Lines[i].Quantity; n++ {
+			units = append(units, k)
+		}
+	}
+	sort.SliceStable(units, func(a, b int) bool {
+		return cart.Lines[lines[units[a]]].UnitPrice.Cmp(cart.Lines[lines[units[b]]].UnitPrice) > 0
+	})
+	for start := 0; start+group <= len(units); start += group {
+		for _, k := range units[start+promotion.BuyQuantity : start+group] {
+			free[k]++
+		}
+	}
+	return free
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if value != "" && strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
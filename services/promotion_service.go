@@ -0,0 +1,384 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+)
+
+var (
+	ErrPromotionNotFound   = errors.New("promotion not found")
+	ErrInvalidPromotion    = errors.New("invalid promotion")
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponCodeTaken     = errors.New("coupon code already exists")
+	ErrCouponExpired       = errors.New("coupon is not valid at this time")
+	ErrCouponUsedUp        = repository.ErrCouponUsedUp
+	ErrCouponLimitPerUser  = repository.ErrCouponLimitPerUser
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this cart")
+)
+
+// CartDiscounter applies promotions to a priced cart and checks the
+// coupons entered for it
+type CartDiscounter interface {
+	ApplyPromotions(cart *models.Cart) error
+	CheckCoupon(code string, userID uint) (*models.Coupon, error)
+}
+
+// PromotionTierDetails is a step of a tiered promotion in decimal amounts
+type PromotionTierDetails struct {
+	Threshold string
+	Discount  string
+}
+
+// PromotionDetails is what staff enter for a promotion. Amounts are decimal
+// strings in Currency.
+type PromotionDetails struct {
+	Name           string
+	Description    string
+	Type           string
+	Active         bool
+	Priority       int
+	Exclusive      bool
+	RequiresCoupon bool
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	Currency       string
+	Percent        int
+	Amount         string
+	BuyQuantity    int
+	GetQuantity    int
+	Tiers          []PromotionTierDetails
+	Categories     []string
+	SKUs           []string
+	CustomerGroups []string
+	MinSubtotal    string
+}
+
+// CouponDetails is a coupon code and its limits; zero limits are unlimited
+type CouponDetails struct {
+	Code         string
+	UsageLimit   int
+	PerUserLimit int
+}
+
+// PromotionService manages promotions and coupons and prices carts with
+// them
+type PromotionService struct {
+	promotionRepo repository.PromotionRepository
+	userRepo      repository.UserRepository
+	now           func() time.Time
+}
+
+func NewPromotionService(promotionRepo repository.PromotionRepository, userRepo repository.UserRepository) *PromotionService {
+	return &PromotionService{
+		promotionRepo: promotionRepo,
+		userRepo:      userRepo,
+		now:           time.Now,
+	}
+}
+
+func (s *PromotionService) List(page, pageSize int) ([]models.Promotion, error) {
+	offset, limit := paginate(page, pageSize)
+	return s.promotionRepo.FindAll(offset, limit)
+}
+
+func (s *PromotionService) Get(id uint) (*models.Promotion, error) {
+	promotion, err := s.promotionRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrPromotionNotFound
+	}
+	return promotion, nil
+}
+
+func (s *PromotionService) Create(details PromotionDetails) (*models.Promotion, error) {
+	promotion := &models.Promotion{}
+	if err := buildPromotion(promotion, details); err != nil {
+		return nil, err
+	}
+	if err := s.promotionRepo.Create(promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func (s *PromotionService) Update(id uint, details PromotionDetails) (*models.Promotion, error) {
+	promotion, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := buildPromotion(promotion, details); err != nil {
+		return nil, err
+	}
+	if err := s.promotionRepo.Update(promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// Delete removes a promotion and its coupons; placed orders keep their
+// discounts
+func (s *PromotionService) Delete(id uint) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.promotionRepo.Delete(id)
+}
+
+func invalidPromotion(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPromotion, reason)
+}
+
+// buildPromotion validates details and copies them onto promotion
+func buildPromotion(promotion *models.Promotion, details PromotionDetails) error {
+	name := strings.TrimSpace(details.Name)
+	if name == "" {
+		return invalidPromotion("name is required")
+	}
+	currency := strings.ToUpper(details.Currency)
+	if currency != "" && !money.IsSupported(currency) {
+		return money.ErrUnknownCurrency
+	}
+	if details.StartsAt != nil && details.EndsAt != nil && !details.EndsAt.After(*details.StartsAt) {
+		return invalidPromotion("ends_at must be after starts_at")
+	}
+	parse := func(field, value string) (money.Money, error) {
+		if currency == "" {
+			return money.Money{}, invalidPromotion(field + " needs a currency")
+		}
+		amount, err := money.Parse(value, currency)
+		if err != nil || amount.Amount <= 0 {
+			return money.Money{}, invalidPromotion(field + " must be a positive amount")
+		}
+		return amount, nil
+	}
+
+	result := models.Promotion{
+		ID:             promotion.ID,
+		CreatedAt:      promotion.CreatedAt,
+		Name:           name,
+		Description:    strings.TrimSpace(details.Description),
+		Type:           details.Type,
+		Active:         details.Active,
+		Priority:       details.Priority,
+		Exclusive:      details.Exclusive,
+		RequiresCoupon: details.RequiresCoupon,
+		StartsAt:       details.StartsAt,
+		EndsAt:         details.EndsAt,
+		Currency:       currency,
+		Amount:         money.Zero(currency),
+		Conditions: models.PromotionConditions{
+			Categories:     trimAll(details.Categories),
+			SKUs:           trimAll(details.SKUs),
+			CustomerGroups: trimAll(details.CustomerGroups),
+		},
+	}
+	if details.MinSubtotal != "" {
+		minimum, err := parse("min_subtotal", details.MinSubtotal)
+		if err != nil {
+			return err
+		}
+		result.Conditions.MinSubtotal = &minimum
+	}
+
+	switch details.Type {
+	case models.PromotionPercentOff:
+		if details.Percent < 1 || details.Percent > 100 {
+			return invalidPromotion("percent must be between 1 and 100")
+		}
+		result.Percent = details.Percent
+	case models.PromotionFixedAmount:
+		amount, err := parse("amount", details.Amount)
+		if err != nil {
+			return err
+		}
+		result.Amount = amount
+	case models.PromotionBuyXGetY:
+		if details.BuyQuantity < 1 || details.GetQuantity < 1 {
+			return invalidPromotion("buy_quantity and get_quantity must be at least 1")
+		}
+		result.BuyQuantity = details.BuyQuantity
+		result.GetQuantity = details.GetQuantity
+	case models.PromotionTiered:
+		if len(details.Tiers) == 0 {
+			return invalidPromotion("tiered promotions need at least one tier")
+		}
+		for _, t := range details.Tiers {
+			threshold, err := parse("tier threshold", t.Threshold)
+			if err != nil {
+				return err
+			}
+			discount, err := parse("tier discount", t.Discount)
+			if err != nil {
+				return err
+			}
+			if discount.Cmp(threshold) > 0 {
+				return invalidPromotion("tier discount cannot exceed its threshold")
+			}
+			result.Tiers = append(result.Tiers, models.PromotionTier{Threshold: threshold, Discount: discount})
+		}
+		sort.Slice(result.Tiers, func(i, j int) bool {
+			return result.Tiers[i].Threshold.Cmp(result.Tiers[j].Threshold) < 0
+		})
+	case models.PromotionFreeShipping:
+	default:
+		return invalidPromotion("unknown type " + details.Type)
+	}
+
+	*promotion = result
+	return nil
+}
+
+func trimAll(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// normalizeCouponCode makes codes case-insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CreateCoupon adds a code to a promotion that requires one
+func (s *PromotionService) CreateCoupon(promotionID uint, details CouponDetails) (*models.Coupon, error) {
+	promotion, err := s.Get(promotionID)
+	if err != nil {
+		return nil, err
+	}
+	if !promotion.RequiresCoupon {
+		return nil, invalidPromotion("promotion is applied without a coupon")
+	}
+	code := normalizeCouponCode(details.Code)
+	if code == "" || details.UsageLimit < 0 || details.PerUserLimit < 0 {
+		return nil, invalidPromotion("coupon needs a code and non-negative limits")
+	}
+
+	coupon := &models.Coupon{
+		PromotionID:  promotion.ID,
+		Code:         code,
+		UsageLimit:   details.UsageLimit,
+		PerUserLimit: details.PerUserLimit,
+	}
+	if err := s.promotionRepo.CreateCoupon(coupon); err != nil {
+		return nil, ErrCouponCodeTaken
+	}
+	return coupon, nil
+}
+
+func (s *PromotionService) Coupons(promotionID uint) ([]models.Coupon, error) {
+	if _, err := s.Get(promotionID); err != nil {
+		return nil, err
+	}
+	return s.promotionRepo.FindCoupons(promotionID)
+}
+
+// CheckCoupon returns the coupon of code if its promotion is running and
+// the user can still use it. Guests (userID zero) are checked against the
+// per-user limit at checkout, once they have signed in.
+func (s *PromotionService) CheckCoupon(code string, userID uint) (*models.Coupon, error) {
+	coupon, err := s.promotionRepo.FindCouponByCode(normalizeCouponCode(code))
+	if err != nil {
+		return nil, ErrCouponNotFound
+	}
+	promotion, err := s.promotionRepo.FindByID(coupon.PromotionID)
+	if err != nil {
+		return nil, ErrCouponNotFound
+	}
+	if !promotion.IsRunning(s.now()) {
+		return nil, ErrCouponExpired
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return nil, ErrCouponUsedUp
+	}
+	if coupon.PerUserLimit > 0 && userID != 0 {
+		used, err := s.promotionRepo.CountRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= coupon.PerUserLimit {
+			return nil, ErrCouponLimitPerUser
+		}
+	}
+	return coupon, nil
+}
+
+// ApplyPromotions discounts a priced cart with the running promotions and
+// its coupon, explaining on each line which rules applied. It implements
+// CartDiscounter.
+func (s *PromotionService) ApplyPromotions(cart *models.Cart) error {
+	if cart.Subtotal == nil {
+		return nil
+	}
+	currency := cart.Subtotal.Currency
+
+	input := promotionCart{Currency: currency}
+	if cart.UserID != nil {
+		if user, err := s.userRepo.FindByID(*cart.UserID); err == nil {
+			input.Group = user.CustomerGroup
+		}
+	}
+	cart.CouponIssue = ""
+	if cart.CouponCode != "" {
+		var userID uint
+		if cart.UserID != nil {
+			userID = *cart.UserID
+		}
+		coupon, err := s.CheckCoupon(cart.CouponCode, userID)
+		if err != nil {
+			cart.CouponIssue = err.Error()
+		}
+		input.Coupon = coupon
+	}
+
+	// 只有已定價的品項參與折扣
+	var priced []int
+	for i, item := range cart.Items {
+		if item.LineTotal == nil || item.Product == nil {
+			continue
+		}
+		priced = append(priced, i)
+		input.Lines = append(input.Lines, promotionLine{
+			SKU:       item.Product.SKU,
+			Category:  item.Product.Category,
+			Quantity:  item.Quantity,
+			UnitPrice: *item.UnitPrice,
+			Total:     *item.LineTotal,
+		})
+	}
+
+	promotions, err := s.promotionRepo.FindRunning(s.now())
+	if err != nil {
+		return err
+	}
+	result := applyPromotions(promotions, input)
+
+	for k, i := range priced {
+		cart.Items[i].Discounts = result.Discounts[k]
+	}
+	cart.Promotions = result.Applied
+	cart.FreeShipping = result.FreeShipping
+	cart.DiscountTotal = &result.Total
+	total := cart.Subtotal.Sub(result.Total)
+	cart.Total = &total
+
+	if input.Coupon != nil {
+		used := false
+		for _, applied := range result.Applied {
+			used = used || applied.PromotionID == input.Coupon.PromotionID
+		}
+		if !used {
+			cart.CouponIssue = ErrCouponNotApplicable.Error()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/money"
+)
+
+func twd(amount int64) money.Money {
+	return money.New(amount*100, "TWD")
+}
+
+func testPromotionCart() promotionCart {
+	return promotionCart{
+		Currency: "TWD",
+		Lines: []promotionLine{
+			{SKU: "SKU-1", Category: "coffee", Quantity: 2, UnitPrice: twd(450), Total: twd(900)},
+			{SKU: "SKU-2", Category: "tea", Quantity: 1, UnitPrice: twd(300), Total: twd(300)},
+		},
+	}
+}
+
+func TestPromotionTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion models.Promotion
+		want      int64
+	}{
+		{"percent off", models.Promotion{ID: 1, Type: models.PromotionPercentOff, Percent: 10}, 120},
+		{"fixed amount", models.Promotion{ID: 1, Type: models.PromotionFixedAmount, Currency: "TWD", Amount: twd(100)}, 100},
+		{"fixed amount capped", models.Promotion{ID: 1, Type: models.PromotionFixedAmount, Currency: "TWD", Amount: twd(5000)}, 1200},
+		{"tiered", models.Promotion{ID: 1, Type: models.PromotionTiered, Currency: "TWD", Tiers: models.PromotionTiers{
+			{Threshold: twd(1000), Discount: twd(100)}, {Threshold: twd(2000), Discount: twd(200)},
+		}}, 100},
+		{"buy 2 get 1", models.Promotion{ID: 1, Type: models.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, 300},
+		{"category", models.Promotion{ID: 1, Type: models.PromotionPercentOff, Percent: 50,
+			Conditions: models.PromotionConditions{Categories: []string{"tea"}}}, 150},
+		{"below minimum", models.Promotion{ID: 1, Type: models.PromotionPercentOff, Percent: 10,
+			Conditions: models.PromotionConditions{SKUs: []string{"SKU-2"}, MinSubtotal: &[]money.Money{twd(500)}[0]}}, 0},
+		{"other currency", models.Promotion{ID: 1, Type: models.PromotionFixedAmount, Currency: "USD", Amount: money.New(500, "USD")}, 0},
+		{"customer group", models.Promotion{ID: 1, Type: models.PromotionPercentOff, Percent: 10,
+			Conditions: models.PromotionConditions{CustomerGroups: []string{"vip"}}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := applyPromotions([]models.Promotion{tt.promotion}, testPromotionCart())
+			if result.Total != twd(tt.want) {
+				t.Errorf("Total = %v, want %v", result.Total, twd(tt.want))
+			}
+		})
+	}
+}
+
+func TestPromotionsExplainEachLine(t *testing.T) {
+	cart := testPromotionCart()
+	cart.Group = "vip"
+	promotions := []models.Promotion{
+		{ID: 1, Name: "VIP 10% off", Type: models.PromotionPercentOff, Percent: 10, Priority: 20,
+			Conditions: models.PromotionConditions{CustomerGroups: []string{"VIP"}}},
+		{ID: 2, Name: "Tea NT$50 off", Type: models.PromotionFixedAmount, Currency: "TWD", Amount: twd(50), Priority: 10,
+			Conditions: models.PromotionConditions{Categories: []string{"tea"}}},
+		{ID: 3, Name: "Free shipping", Type: models.PromotionFreeShipping},
+	}
+
+	result := applyPromotions(promotions, cart)
+	if result.Total != twd(170) || !result.FreeShipping || len(result.Applied) != 3 {
+		t.Fatalf("result = %+v, want NT$170 off with free shipping", result)
+	}
+	if got := result.Discounts[0]; len(got) != 1 || got[0].PromotionID != 1 || got[0].Amount != twd(90) {
+		t.Errorf("coffee discounts = %+v, want 10%% only", got)
+	}
+	// 第二條規則從打折後的 270 再折 50
+	if got := result.Discounts[1]; len(got) != 2 || got[1].PromotionID != 2 || got[1].Amount != twd(50) {
+		t.Errorf("tea discounts = %+v, want 10%% then NT$50", got)
+	}
+}
+
+func TestExclusivePromotions(t *testing.T) {
+	percent := models.Promotion{ID: 1, Type: models.PromotionPercentOff, Percent: 10, Priority: 10}
+	fixed := models.Promotion{ID: 2, Type: models.PromotionFixedAmount, Currency: "TWD", Amount: twd(300), Priority: 5}
+
+	exclusiveFirst := percent
+	exclusiveFirst.Exclusive = true
+	if result := applyPromotions([]models.Promotion{exclusiveFirst, fixed}, testPromotionCart()); result.Total != twd(120) {
+		t.Errorf("exclusive first Total = %v, want only 10%% off", result.Total)
+	}
+
+	exclusiveLater := fixed
+	exclusiveLater.Exclusive = true
+	if result := applyPromotions([]models.Promotion{percent, exclusiveLater}, testPromotionCart()); result.Total != twd(120) || len(result.Applied) != 1 {
+		t.Errorf("exclusive later Total = %v, want it skipped", result.Total)
+	}
+}
+
+func TestValidatePromotion(t *testing.T) {
+	tests := []PromotionDetails{
+		{Name: "", Type: models.PromotionPercentOff, Percent: 10},
+		{Name: "Too much", Type: models.PromotionPercentOff, Percent: 150},
+		{Name: "No currency", Type: models.PromotionFixedAmount, Amount: "100"},
+		{Name: "Free gift", Type: "gift"},
+		{Name: "Bad tier", Type: models.PromotionTiered, Currency: "TWD", Tiers: []PromotionTierDetails{{Threshold: "100", Discount: "200"}}},
+	}
+	for _, details := range tests {
+		if err := buildPromotion(&models.Promotion{}, details); !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("buildPromotion(%q) error = %v, want %v", details.Name, err, ErrInvalidPromotion)
+		}
+	}
+}
+
+// newTestCoupon creates a NT$100 off coupon promotion
+func (tc *testCheckout) newTestCoupon(t *testing.T, details CouponDetails) *models.Coupon {
+	t.Helper()
+	promotion, err := tc.promotions.Create(PromotionDetails{
+		Name: "Welcome", Type: models.PromotionFixedAmount, Currency: "TWD", Amount: "100",
+		Active: true, RequiresCoupon: true,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	coupon, err := tc.promotions.CreateCoupon(promotion.ID, details)
+	if err != nil {
+		t.Fatalf("CreateCoupon() error = %v", err)
+	}
+	return coupon
+}
+
+func TestCheckoutWithCoupon(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.newTestCoupon(t, CouponDetails{Code: "welcome100", PerUserLimit: 1})
+	tc.promotions.Create(PromotionDetails{Name: "Spend 1000 save 50", Type: models.PromotionTiered, Currency: "TWD", Active: true,
+		Tiers: []PromotionTierDetails{{Threshold: "1000", Discount: "50"}}})
+	tc.fillCart(t)
+
+	cart, err := tc.carts.ApplyCoupon(tc.user.ID, "", "WELCOME100", tc.priceList)
+	if err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+	if cart.DiscountTotal == nil || *cart.DiscountTotal != twd(150) || cart.Total == nil || *cart.Total != twd(1050) {
+		t.Fatalf("cart discount = %v, total = %v, want NT$150 off", cart.DiscountTotal, cart.Total)
+	}
+	if len(cart.Items[0].Discounts) != 2 {
+		t.Errorf("first line discounts = %+v, want both promotions explained", cart.Items[0].Discounts)
+	}
+
+	order, _, err := tc.checkout.Checkout(tc.user, "key-1", CheckoutDetails{ShippingAddress: testAddress()}, tc.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if order.DiscountTotal != twd(150) || order.Total != twd(1050) || len(order.Promotions) != 2 {
+		t.Errorf("order discount = %v, total = %v, promotions = %d, want NT$150 off by 2", order.DiscountTotal, order.Total, len(order.Promotions))
+	}
+	if order.Items[0].DiscountTotal.Add(order.Items[1].DiscountTotal) != twd(150) {
+		t.Errorf("line discounts do not add up to the order discount")
+	}
+
+	// 每人限用一次
+	tc.fillCart(t)
+	if _, err := tc.carts.ApplyCoupon(tc.user.ID, "", "WELCOME100", tc.priceList); !errors.Is(err, ErrCouponLimitPerUser) {
+		t.Errorf("second ApplyCoupon() error = %v, want %v", err, ErrCouponLimitPerUser)
+	}
+
+	// 取消訂單後可以再使用
+	if _, err := tc.orders.ChangeStatus(&tc.user, order.ID, StatusChange{To: models.OrderStatusCancelled}); err != nil {
+		t.Fatalf("ChangeStatus(cancelled) error = %v", err)
+	}
+	if _, err := tc.carts.ApplyCoupon(tc.user.ID, "", "WELCOME100", tc.priceList); err != nil {
+		t.Errorf("ApplyCoupon() after cancelling error = %v", err)
+	}
+}
+
+func TestCouponGlobalLimitUnderConcurrentCheckouts(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.newTestCoupon(t, CouponDetails{Code: "ONCE", UsageLimit: 1})
+	other := models.User{ID: 8, Email: "other@example.com"}
+
+	// 兩位顧客都先輸入優惠券，同時結帳時只有一位能使用
+	tc.fillCart(t)
+	if _, err := tc.carts.ApplyCoupon(tc.user.ID, "", "ONCE", tc.priceList); err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+	if _, err := tc.carts.AddItem(other.ID, "", 2, 1, tc.priceList); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	if _, err := tc.carts.ApplyCoupon(other.ID, "", "ONCE", tc.priceList); err != nil {
+		t.Fatalf("ApplyCoupon() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, buyer := range []models.User{tc.user, other} {
+		wg.Add(1)
+		go func(i int, buyer models.User) {
+			defer wg.Done()
+			_, _, errs[i] = tc.checkout.Checkout(buyer, fmt.Sprintf("key-%d", i), CheckoutDetails{ShippingAddress: testAddress()}, tc.priceList)
+		}(i, buyer)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		if !errors.Is(err, ErrCartInvalid) && !errors.Is(err, ErrCouponUsedUp) {
+			t.Errorf("Checkout() error = %v, want the coupon rejected", err)
+		}
+	}
+	if failed != 1 {
+		t.Errorf("%d checkouts failed, want exactly one to get the coupon", failed)
+	}
+}
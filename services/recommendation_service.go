@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+const (
+	defaultRecommendationTopN       = 10
+	defaultRecommendationWindow     = 90 * 24 * time.Hour
+	defaultRecommendationInterval   = 6 * time.Hour
+	defaultRecommendationMinSupport = 2
+)
+
+// RecommendationService computes "frequently bought together" products from
+// completed sales and serves them, falling back to best sellers of the same
+// category when a product has too few
+type RecommendationService struct {
+	recommendationRepo repository.RecommendationRepository
+	productRepo        repository.ProductRepository
+	topN               int
+	window             time.Duration
+	interval           time.Duration
+	minSupport         int
+	now                func() time.Time
+}
+
+func NewRecommendationService(recommendationRepo repository.RecommendationRepository, productRepo repository.ProductRepository) *RecommendationService {
+	s := &RecommendationService{
+		recommendationRepo: recommendationRepo,
+		productRepo:        productRepo,
+		topN:               defaultRecommendationTopN,
+		window:             defaultRecommendationWindow,
+		interval:           defaultRecommendationInterval,
+		minSupport:         defaultRecommendationMinSupport,
+		now:                time.Now,
+	}
+	if v, err := strconv.Atoi(os.Getenv("RECOMMENDATION_TOP_N")); err == nil && v > 0 {
+		s.topN = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RECOMMENDATION_MIN_SUPPORT")); err == nil && v > 0 {
+		s.minSupport = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RECOMMENDATION_WINDOW")); err == nil && v > 0 {
+		s.window = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RECOMMENDATION_INTERVAL")); err == nil && v > 0 {
+		s.interval = v
+	}
+	return s
+}
+
+// Compute rebuilds the recommendations from the sales of the last window.
+// Two products are related when they were sold together in at least
+// minSupport orders; each product keeps its topN strongest relations.
+func (s *RecommendationService) Compute() (int, error) {
+	now := s.now()
+	orders := make(map[uint]int)
+	pairs := make(map[uint]map[uint]int)
+	err := s.recommendationRepo.FindSaleBaskets(now.Add(-s.window), func(productIDs []uint) error {
+		for _, a := range productIDs {
+			orders[a]++
+			for _, b := range productIDs {
+				if a == b {
+					continue
+				}
+				if pairs[a] == nil {
+					pairs[a] = make(map[uint]int)
+				}
+				pairs[a][b]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var recs []models.ProductRecommendation
+	for productID, related := range pairs {
+		var candidates []models.ProductRecommendation
+		for relatedID, support := range related {
+			if support < s.minSupport {
+				continue
+			}
+			candidates = append(candidates, models.ProductRecommendation{
+				ProductID:        productID,
+				RelatedProductID: relatedID,
+				Support:          support,
+				Confidence:       float64(support) / float64(orders[productID]),
+				ComputedAt:       now,
+			})
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].Support != candidates[j].Support {
+				return candidates[i].Support > candidates[j].Support
+			}
+			return candidates[i].RelatedProductID < candidates[j].RelatedProductID
+		})
+		if len(candidates) > s.topN {
+			candidates = candidates[:s.topN]
+		}
+		for i := range candidates {
+			candidates[i].Rank = i + 1
+		}
+		recs = append(recs, candidates...)
+	}
+
+	if err := s.recommendationRepo.ReplaceAll(recs); err != nil {
+		return 0, err
+	}
+	return len(recs), nil
+}
+
+// For returns up to limit visible products to show next to a product.
+// Computed recommendations come first, then same-category best sellers.
+func (s *RecommendationService) For(productID uint, limit int) ([]models.Product, error) {
+	now := s.now()
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil || !product.IsVisible(now) {
+		return nil, ErrProductNotFound
+	}
+	if limit <= 0 || limit > s.topN {
+		limit = s.topN
+	}
+
+	recs, err := s.recommendationRepo.FindByProductID(productID, s.topN)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(recs))
+	for i, rec := range recs {
+		ids[i] = rec.RelatedProductID
+	}
+	related, err := s.productRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(related))
+	for _, p := range related {
+		byID[p.ID] = p
+	}
+
+	products := []models.Product{}
+	exclude := []uint{productID}
+	for _, rec := range recs {
+		if len(products) == limit {
+			break
+		}
+		// 推薦結果是批次計算的，商品可能已下架
+		if p, exists := byID[rec.RelatedProductID]; exists && p.IsVisible(now) {
+			products = append(products, p)
+			exclude = append(exclude, p.ID)
+		}
+	}
+	if len(products) < limit {
+		bestSellers, err := s.recommendationRepo.FindBestSellers(product.Category, now.Add(-s.window), now, exclude, limit-len(products))
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, bestSellers...)
+	}
+	return products, nil
+}
+
+// Run recomputes recommendations until ctx is cancelled
+func (s *RecommendationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Compute(); err != nil {
+			log.Printf("recommendations: failed to compute: %v", err)
+		} else {
+			log.Printf("recommendations: stored %d related products", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+func newTestRecommendationService(t *testing.T) (*RecommendationService, *InventoryService, repository.ProductRepository) {
+	t.Helper()
+	productRepo := repository.NewMockProductRepository()
+	products := []models.Product{
+		{ID: 1, SKU: "BEAN-1", Name: "Coffee beans", Category: "coffee", Status: models.ProductStatusPublished},
+		{ID: 2, SKU: "FILTER-1", Name: "Paper filters", Category: "brewing", Status: models.ProductStatusPublished},
+		{ID: 3, SKU: "MUG-1", Name: "Mug", Category: "brewing", Status: models.ProductStatusPublished},
+		{ID: 4, SKU: "BEAN-2", Name: "Decaf beans", Category: "coffee", Status: models.ProductStatusPublished},
+		{ID: 5, SKU: "BEAN-3", Name: "Draft beans", Category: "coffee", Status: models.ProductStatusDraft},
+	}
+	for i := range products {
+		productRepo.Create(&products[i])
+	}
+
+	inventoryRepo := repository.NewMockInventoryRepository()
+	inventoryService := NewInventoryService(inventoryRepo, productRepo, nil)
+	if _, err := inventoryService.CreateWarehouse("TPE", "Taipei"); err != nil {
+		t.Fatalf("CreateWarehouse() error = %v", err)
+	}
+	for _, p := range products {
+		receive(t, inventoryService, p.ID, 1, 100)
+	}
+
+	s := NewRecommendationService(repository.NewMockRecommendationRepository(inventoryRepo, productRepo), productRepo)
+	return s, inventoryService, productRepo
+}
+
+func sell(t *testing.T, s *InventoryService, reference string, items ...repository.ReservationItem) {
+	t.Helper()
+	if _, err := s.Reserve(reference, items); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := s.Commit(reference); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+}
+
+func TestComputeRecommendations(t *testing.T) {
+	s, inventoryService, _ := newTestRecommendationService(t)
+	sell(t, inventoryService, "order-1", repository.ReservationItem{ProductID: 1, Quantity: 1}, repository.ReservationItem{ProductID: 2, Quantity: 1})
+	sell(t, inventoryService, "order-2", repository.ReservationItem{ProductID: 1, Quantity: 2}, repository.ReservationItem{ProductID: 2, Quantity: 1}, repository.ReservationItem{ProductID: 3, Quantity: 1})
+	sell(t, inventoryService, "order-3", repository.ReservationItem{ProductID: 1, Quantity: 1}, repository.ReservationItem{ProductID: 3, Quantity: 1})
+	sell(t, inventoryService, "order-4", repository.ReservationItem{ProductID: 1, Quantity: 1}, repository.ReservationItem{ProductID: 2, Quantity: 1})
+	// 未結帳的預留不算銷售
+	inventoryService.Reserve("cart-1", []repository.ReservationItem{{ProductID: 1, Quantity: 1}, {ProductID: 3, Quantity: 1}})
+
+	n, err := s.Compute()
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	// 1-2 (3 orders), 1-3 (2 orders) in both directions
+	if n != 4 {
+		t.Errorf("Compute() = %d, want 4", n)
+	}
+
+	recs, _ := s.recommendationRepo.FindByProductID(1, 10)
+	if len(recs) != 2 || recs[0].RelatedProductID != 2 || recs[0].Support != 3 || recs[1].RelatedProductID != 3 {
+		t.Fatalf("recommendations for 1 = %+v, want 2 then 3", recs)
+	}
+	if recs[0].Confidence != 0.75 {
+		t.Errorf("Confidence = %v, want 0.75", recs[0].Confidence)
+	}
+}
+
+func TestRecommendationsFallBackToCategoryBestSellers(t *testing.T) {
+	s, inventoryService, productRepo := newTestRecommendationService(t)
+	sell(t, inventoryService, "order-1", repository.ReservationItem{ProductID: 1, Quantity: 1}, repository.ReservationItem{ProductID: 2, Quantity: 1})
+	sell(t, inventoryService, "order-2", repository.ReservationItem{ProductID: 1, Quantity: 1}, repository.ReservationItem{ProductID: 2, Quantity: 1})
+	sell(t, inventoryService, "order-3", repository.ReservationItem{ProductID: 4, Quantity: 3})
+	sell(t, inventoryService, "order-4", repository.ReservationItem{ProductID: 5, Quantity: 9})
+	if _, err := s.Compute(); err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+
+	products, err := s.For(1, 5)
+	if err != nil {
+		t.Fatalf("For() error = %v", err)
+	}
+	// 2 is bought together; 4 is the only other visible coffee best seller
+	if len(products) != 2 || products[0].ID != 2 || products[1].ID != 4 {
+		t.Errorf("For() = %+v, want products 2 then 4", products)
+	}
+
+	// 下架的推薦商品不再出現
+	filters, _ := productRepo.FindByID(2)
+	filters.Status = models.ProductStatusArchived
+	products, _ = s.For(1, 5)
+	if len(products) != 1 || products[0].ID != 4 {
+		t.Errorf("For() = %+v, want only product 4", products)
+	}
+
+	if _, err := s.For(5, 5); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("For() error = %v, want %v", err, ErrProductNotFound)
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/payments"
+	"e-commerce/repository"
+)
+
+var (
+	ErrNothingToRefund       = errors.New("order has no captured payment to refund")
+	ErrInvalidRefundAmount   = errors.New("refund amount must be positive")
+	ErrRefundExceedsCaptured = repository.ErrRefundExceedsCaptured
+	ErrRefundExceedsQuantity = repository.ErrRefundExceedsQuantity
+	ErrOrderItemNotFound     = errors.New("order item not found")
+	ErrRestockWithoutLines   = errors.New("restocking needs the refunded lines")
+	ErrAlreadyRestocked      = repository.ErrAlreadyRestocked
+)
+
+// RefundLine is a quantity of an order line to refund
+type RefundLine struct {
+	OrderItemID uint
+	Quantity    int
+}
+
+// RefundRequest describes a refund. Without lines or an amount the rest of
+// the order is refunded. Lines alone refund their value; an amount, e.g.
+// after a restocking fee, overrides it.
+type RefundRequest struct {
+	Lines   []RefundLine
+	Amount  string
+	Restock bool
+	Reason  string
+}
+
+// RefundService returns money of captured payments through the payment
+// provider
+type RefundService struct {
+	refundRepo       repository.RefundRepository
+	paymentRepo      repository.PaymentRepository
+	orderService     *OrderService
+	inventoryService *InventoryService
+	provider         payments.PaymentProvider
+	mailer           mailer.Mailer
+	invoicer         Invoicer
+}
+
+// NewRefundService creates the refund flow. Refunds get credit notes when an
+// invoicer is given.
+func NewRefundService(refundRepo repository.RefundRepository, paymentRepo repository.PaymentRepository, orderService *OrderService, inventoryService *InventoryService, provider payments.PaymentProvider, m mailer.Mailer, invoicer Invoicer) *RefundService {
+	return &RefundService{
+		refundRepo:       refundRepo,
+		paymentRepo:      paymentRepo,
+		orderService:     orderService,
+		inventoryService: inventoryService,
+		provider:         provider,
+		mailer:           m,
+		invoicer:         invoicer,
+	}
+}
+
+// ForOrder returns the refunds of an order the user can see
+func (s *RefundService) ForOrder(user *models.User, orderID uint) ([]models.Refund, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil || (!user.IsStaff() && order.UserID != user.ID) {
+		return nil, ErrOrderNotFound
+	}
+	return s.refundRepo.FindByOrderID(order.ID)
+}
+
+// capturedPayment returns the payment of an order that money can be refunded from
+func (s *RefundService) capturedPayment(orderID uint) (*models.Payment, error) {
+	attempts, err := s.paymentRepo.FindByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attempts {
+		switch attempts[i].Status {
+		case models.PaymentStatusCaptured, models.PaymentStatusPartiallyRefunded, models.PaymentStatusRefunded:
+			return &attempts[i], nil
+		}
+	}
+	return nil, ErrNothingToRefund
+}
+
+// Refund returns money for an order. Once everything paid has been
+// refunded the order moves to refunded.
+func (s *RefundService) Refund(ctx context.Context, actor *models.User, orderID uint, req RefundRequest) (*models.Refund, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := s.capturedPayment(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := payment.Amount.Sub(payment.RefundedAmount)
+
+	items, value, err := refundItems(order, req.Lines)
+	if err != nil {
+		return nil, err
+	}
+	amount := value
+	switch {
+	case req.Amount != "":
+		if amount, err = money.Parse(req.Amount, payment.Amount.Currency); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRefundAmount, err)
+		}
+	case len(req.Lines) == 0:
+		// 全額退款：退回剩下的金額與所有尚未退的商品
+		amount = remaining
+		for _, item := range order.Items {
+			if n := item.Quantity - item.RefundedQuantity; n > 0 {
+				items = append(items, models.RefundItem{OrderItemID: item.ID, ProductID: item.ProductID, Quantity: n})
+			}
+		}
+	}
+	if amount.Amount <= 0 {
+		return nil, ErrInvalidRefundAmount
+	}
+	if amount.Cmp(remaining) > 0 {
+		return nil, ErrRefundExceedsCaptured
+	}
+	if req.Restock {
+		if len(items) == 0 {
+			return nil, ErrRestockWithoutLines
+		}
+		// 提早回報；儲存庫在完成退款時鎖定明細後會再檢查一次
+		for _, item := range items {
+			line := findOrderItem(order, item.OrderItemID)
+			if line.RestockedQuantity+item.Quantity > line.Quantity {
+				return nil, ErrAlreadyRestocked
+			}
+		}
+	}
+
+	refund := &models.Refund{
+		OrderID:   order.ID,
+		PaymentID: payment.ID,
+		Status:    models.RefundStatusPending,
+		Amount:    amount,
+		Reason:    strings.TrimSpace(req.Reason),
+		Restock:   req.Restock,
+		ActorID:   &actor.ID,
+		Items:     items,
+	}
+	if err := s.refundRepo.Create(refund); err != nil {
+		return nil, err
+	}
+
+	result, err := s.provider.Refund(ctx, payment.ProviderRef, amount)
+	if err != nil {
+		refund.FailureReason = err.Error()
+		if failErr := s.refundRepo.Fail(refund); failErr != nil {
+			log.Printf("refunds: failed to record failure of refund %d: %v", refund.ID, failErr)
+		}
+		return refund, fmt.Errorf("%w: %v", ErrPaymentFailed, err)
+	}
+	refund.ProviderRef = result.ProviderRef
+	err = s.refundRepo.Complete(refund)
+	if err != nil && refund.Restock {
+		// 金流商已退款：庫存無法補回時仍記錄退款，標記為未補貨以便人工調整
+		log.Printf("refunds: refund %d of %s could not be restocked: %v", refund.ID, order.Number, err)
+		refund.Restock = false
+		err = s.refundRepo.Complete(refund)
+	}
+	if err != nil {
+		// 金流商已退款，只能記錄下來人工處理
+		log.Printf("refunds: refund %d succeeded as %s but could not be recorded: %v", refund.ID, result.ProviderRef, err)
+		return nil, err
+	}
+
+	if refund.Restock {
+		productIDs := make([]uint, len(refund.Items))
+		for i, item := range refund.Items {
+			productIDs[i] = item.ProductID
+		}
+		s.inventoryService.notifyProducts(productIDs)
+	}
+	if s.invoicer != nil {
+		if err := s.invoicer.OrderRefunded(ctx, refund); err != nil {
+			log.Printf("refunds: credit note for refund %d of %s: %v", refund.ID, order.Number, err)
+		}
+	}
+	s.finish(ctx, actor, order.ID, refund)
+	return refund, nil
+}
+
+// finish marks a fully refunded order refunded, or tells the customer about
+// a partial refund
+func (s *RefundService) finish(ctx context.Context, actor *models.User, orderID uint, refund *models.Refund) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil {
+		return
+	}
+	if order.RefundedTotal.Cmp(order.Total) >= 0 {
+		for _, next := range NextStatuses(order.Status) {
+			if next != models.OrderStatusRefunded {
+				continue
+			}
+			reason := refund.Reason
+			if reason == "" {
+				reason = "Refunded in full"
+			}
+			if _, err := s.orderService.ChangeStatus(actor, order.ID, StatusChange{To: models.OrderStatusRefunded, Reason: reason}); err != nil {
+				log.Printf("refunds: order %s not marked refunded: %v", order.Number, err)
+			}
+			return
+		}
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nWe have refunded %s for order %s.\n", order.ShippingAddress.Name, refund.Amount, order.Number)
+	if refund.Reason != "" {
+		body += "\nReason: " + refund.Reason + "\n"
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{order.Email},
+		Subject: fmt.Sprintf("Refund for order %s", order.Number),
+		Body:    body,
+	}); err != nil {
+		log.Printf("refunds: failed to notify %s: %v", order.Email, err)
+	}
+}
+
+func findOrderItem(order *models.Order, id uint) *models.OrderItem {
+	for i := range order.Items {
+		if order.Items[i].ID == id {
+			return &order.Items[i]
+		}
+	}
+	return nil
+}
+
+// paidShare is what the first n of quantity units of a line paid, rounded
+// to the currency increment and never more than paid
+func paidShare(paid money.Money, n, quantity int) money.Money {
+	if n >= quantity {
+		return paid
+	}
+	share := paid.MulFrac(int64(n), int64(quantity)).Round()
+	if share.Cmp(paid) > 0 {
+		return paid
+	}
+	return share
+}
+
+// refundItems validates lines against the order and returns them with the
+// value of the units refunded. Units are valued as the difference between
+// the shares paid before and after them, so the refunds of a line add up
+// to exactly what was paid for it.
+func refundItems(order *models.Order, lines []RefundLine) ([]models.RefundItem, money.Money, error) {
+	value := money.Zero(order.Currency)
+	quantities := make(map[uint]int)
+	var items []models.RefundItem
+	for _, line := range lines {
+		item := findOrderItem(order, line.OrderItemID)
+		if item == nil {
+			return nil, value, ErrOrderItemNotFound
+		}
+		if line.Quantity <= 0 {
+			return nil, value, ErrInvalidQuantity
+		}
+		before := item.RefundedQuantity + quantities[item.ID]
+		quantities[item.ID] += line.Quantity
+		if before+line.Quantity > item.Quantity {
+			return nil, value, ErrRefundExceedsQuantity
+		}
+		items = append(items, models.RefundItem{OrderItemID: item.ID, ProductID: item.ProductID, Quantity: line.Quantity})
+		// 退的是折扣後實付的金額，外加的稅一併退還
+		paid := item.LineTotal
+		if !item.DiscountTotal.IsZero() {
+			paid = paid.Sub(item.DiscountTotal)
+		}
+		if !order.TaxInclusive && !item.TaxTotal.IsZero() {
+			paid = paid.Add(item.TaxTotal)
+		}
+		value = value.Add(paidShare(paid, before+line.Quantity, item.Quantity).Sub(paidShare(paid, before, item.Quantity)))
+	}
+	return items, value, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"e-commerce/einvoice"
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/payments"
+	"e-commerce/pdf"
+	"e-commerce/repository"
+)
+
+type testRefunds struct {
+	*testCheckout
+	payments *PaymentService
+	refunds  *RefundService
+	invoices *InvoiceService
+	issuer   *einvoice.FakeIssuer
+	staff    *models.User
+	order    *models.Order
+}
+
+// newTestRefunds places and pays an order of 2 × TWD 450 and 1 × TWD 300
+func newTestRefunds(t *testing.T) *testRefunds {
+	t.Helper()
+	tc := newTestCheckout(t)
+	provider := payments.NewFakeProvider("secret")
+	paymentRepo := repository.NewMockPaymentRepository()
+	refundRepo := repository.NewMockRefundRepository(paymentRepo, tc.orders.orderRepo)
+	templates, err := pdf.NewTemplates("")
+	if err != nil {
+		t.Fatalf("NewTemplates() error = %v", err)
+	}
+	issuer := einvoice.NewFakeIssuer()
+	invoices := NewInvoiceService(repository.NewMockInvoiceRepository(), tc.orders, templates, issuer)
+
+	tr := &testRefunds{
+		testCheckout: tc,
+		payments:     NewPaymentService(paymentRepo, tc.orders, provider, invoices),
+		refunds:      NewRefundService(refundRepo, paymentRepo, tc.orders, tc.inventory, provider, tc.mail, invoices),
+		invoices:     invoices,
+		issuer:       issuer,
+		staff:        &models.User{ID: 1, Role: models.RoleStaff},
+		order:        tc.placeOrder(t),
+	}
+	if _, err := tr.payments.Pay(context.Background(), tc.user.ID, tr.order.ID, payments.TestCardSuccess); err != nil {
+		t.Fatalf("Pay() error = %v", err)
+	}
+	return tr
+}
+
+func (tr *testRefunds) reload(t *testing.T) *models.Order {
+	t.Helper()
+	order, err := tr.orders.GetAny(tr.order.ID)
+	if err != nil {
+		t.Fatalf("GetAny() error = %v", err)
+	}
+	return order
+}
+
+func TestRefundLineWithRestock(t *testing.T) {
+	tr := newTestRefunds(t)
+	line := tr.order.Items[0]
+
+	refund, err := tr.refunds.Refund(context.Background(), tr.staff, tr.order.ID, RefundRequest{
+		Lines:   []RefundLine{{OrderItemID: line.ID, Quantity: 1}},
+		Restock: true,
+		Reason:  "Damaged in transit",
+	})
+	if err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if refund.Status != models.RefundStatusSucceeded || refund.Amount.Amount != 45000 || refund.ProviderRef == "" {
+		t.Errorf("refund = %+v, want TWD 450 refunded", refund)
+	}
+
+	order := tr.reload(t)
+	if order.Status != models.OrderStatusPaid || order.RefundedTotal.Amount != 45000 {
+		t.Errorf("order = %s with %v refunded, want still paid with TWD 450 refunded", order.Status, order.RefundedTotal)
+	}
+	if order.Items[0].RefundedQuantity != 1 || order.Items[0].RestockedQuantity != 1 {
+		t.Errorf("item = %+v, want 1 refunded and restocked", order.Items[0])
+	}
+	if levels, _ := tr.inventory.StockLevels(1); levels[0].OnHand != 9 {
+		t.Errorf("OnHand = %d, want 9", levels[0].OnHand)
+	}
+	attempts, _ := tr.payments.ForOrder(tr.staff, tr.order.ID)
+	if attempts[0].Status != models.PaymentStatusPartiallyRefunded {
+		t.Errorf("payment status = %q, want %q", attempts[0].Status, models.PaymentStatusPartiallyRefunded)
+	}
+
+	// 取消時只補回還沒退回庫存的數量
+	if _, err := tr.orders.ChangeStatus(tr.staff, tr.order.ID, StatusChange{To: models.OrderStatusCancelled, Reason: "Customer changed their mind"}); err != nil {
+		t.Fatalf("ChangeStatus(cancelled) error = %v", err)
+	}
+	if levels, _ := tr.inventory.StockLevels(1); levels[0].OnHand != 10 {
+		t.Errorf("OnHand after cancel = %d, want 10", levels[0].OnHand)
+	}
+}
+
+func TestRefundRecordedWhenRestockFails(t *testing.T) {
+	tr := newTestRefunds(t)
+	tea := tr.order.Items[1]
+	// 茶已被手動退回庫存，退款時無法再補回
+	if err := tr.inventory.ReturnStock(tr.staff.ID, tr.order.Number, tea.ProductID, tea.Quantity, "manual"); err != nil {
+		t.Fatalf("ReturnStock() error = %v", err)
+	}
+	movements, _ := tr.inventory.inventoryRepo.FindMovements(tea.ProductID, 0, 100)
+
+	refund, err := tr.refunds.Refund(context.Background(), tr.staff, tr.order.ID, RefundRequest{
+		Lines:   []RefundLine{{OrderItemID: tea.ID, Quantity: 1}},
+		Restock: true,
+	})
+	if err != nil || refund.Status != models.RefundStatusSucceeded || refund.Restock {
+		t.Fatalf("Refund() = %+v, %v, want succeeded without restocking", refund, err)
+	}
+	if item := tr.reload(t).Items[1]; item.RefundedQuantity != 1 || item.RestockedQuantity != 0 {
+		t.Errorf("item = %+v, want refunded but not restocked", item)
+	}
+	if after, _ := tr.inventory.inventoryRepo.FindMovements(tea.ProductID, 0, 100); len(after) != len(movements) {
+		t.Errorf("movements = %d, want %d with nothing booked", len(after), len(movements))
+	}
+}
+
+func TestRefundAmountThenRest(t *testing.T) {
+	tr := newTestRefunds(t)
+	ctx := context.Background()
+
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{Amount: "100"}); err != nil {
+		t.Fatalf("Refund(100) error = %v", err)
+	}
+	rest, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{Reason: "Order lost"})
+	if err != nil || rest.Amount.Amount != 110000 || len(rest.Items) != 2 {
+		t.Fatalf("Refund() = %+v, %v, want the remaining TWD 1100 over both lines", rest, err)
+	}
+
+	order := tr.reload(t)
+	if order.Status != models.OrderStatusRefunded || order.RefundedTotal != order.Total {
+		t.Errorf("order = %s with %v refunded, want refunded in full", order.Status, order.RefundedTotal)
+	}
+	if _, err := tr.refunds.Refund(ctx, tr.staff, tr.order.ID, RefundRequest{Amount: "1"}); !errors.Is(err, ErrRefundExceedsCaptured) {
+		t.Errorf("Refund() after full refund error = %v, want %v", err, ErrRefundExceedsCaptured)
+	}
+	if refunds, _ := tr.refunds.ForOrder(&tr.user, tr.order.ID); len(refunds) != 2 {
+		t.Errorf("ForOrder() returned %d refunds, want 2", len(refunds))
+	}
+}
+
+func TestRefundValidation(t *testing.T) {
+	tr := newTestRefunds(t)
+	line := tr.order.Items[0]
+
+	tests := []struct {
+		name    string
+		req     RefundRequest
+		wantErr error
+	}{
+		{"too many units", RefundRequest{Lines: []RefundLine{{OrderItemID: line.ID, Quantity: 3}}}, ErrRefundExceedsQuantity},
+		{"same line twice", RefundRequest{Lines: []RefundLine{{OrderItemID: line.ID, Quantity: 2}, {OrderItemID: line.ID, Quantity: 1}}}, ErrRefundExceedsQuantity},
+		{"unknown line", RefundRequest{Lines: []RefundLine{{OrderItemID: 99, Quantity: 1}}}, ErrOrderItemNotFound},
+		{"more than captured", RefundRequest{Amount: "1200.01"}, ErrRefundExceedsCaptured},
+		{"negative amount", RefundRequest{Amount: "-5"}, ErrInvalidRefundAmount},
+		{"restock without lines", RefundRequest{Amount: "10", Restock: true, Lines: nil}, ErrRestockWithoutLines},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tr.refunds.Refund(context.Background(), tr.staff, tr.order.ID, tt.req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Refund() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if order := tr.reload(t); !order.RefundedTotal.IsZero() {
+		t.Errorf("RefundedTotal = %v after rejected refunds, want zero", order.RefundedTotal)
+	}
+}
+
+func TestRefundLinesAddUpToWhatWasPaid(t *testing.T) {
+	// 三件共 TWD 1000，逐件退款時每筆取整，合計仍為實付金額
+	order := &models.Order{Currency: "TWD", TaxInclusive: true, Items: []models.OrderItem{
+		{ID: 1, ProductID: 1, Quantity: 3, LineTotal: twd(1000)},
+	}}
+	total := money.Zero("TWD")
+	for i, want := range []int64{333, 334, 333} {
+		_, value, err := refundItems(order, []RefundLine{{OrderItemID: 1, Quantity: 1}})
+		if err != nil {
+			t.Fatalf("refundItems() unit %d error = %v", i+1, err)
+		}
+		if value != twd(want) {
+			t.Errorf("unit %d refunds %v, want %v", i+1, value, twd(want))
+		}
+		total = total.Add(value)
+		order.Items[0].RefundedQuantity++
+	}
+	if total != twd(1000) {
+		t.Errorf("units refunded %v in total, want %v", total, twd(1000))
+	}
+}
+
+func TestRefundNeedsCapturedPayment(t *testing.T) {
+	tc := newTestCheckout(t)
+	paymentRepo := repository.NewMockPaymentRepository()
+	refunds := NewRefundService(repository.NewMockRefundRepository(paymentRepo, tc.orders.orderRepo), paymentRepo, tc.orders, tc.inventory, payments.NewFakeProvider(""), tc.mail, nil)
+	order := tc.placeOrder(t)
+
+	if _, err := refunds.Refund(context.Background(), &models.User{ID: 1, Role: models.RoleStaff}, order.ID, RefundRequest{}); !errors.Is(err, ErrNothingToRefund) {
+		t.Errorf("Refund() error = %v, want %v", err, ErrNothingToRefund)
+	}
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"e-commerce/imaging"
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/storage"
+)
+
+const maxReturnPhotos = 5
+
+var (
+	ErrReturnNotFound        = errors.New("return not found")
+	ErrReturnItemNotFound    = errors.New("return item not found")
+	ErrInvalidReturn         = errors.New("invalid return")
+	ErrNotReturnable         = errors.New("only delivered orders can be returned")
+	ErrReturnExceedsQuantity = repository.ErrReturnExceedsQuantity
+	ErrReturnStatus          = errors.New("return cannot be changed in its current status")
+	ErrReturnChanged         = repository.ErrReturnChanged
+	ErrLabelFailed           = errors.New("carrier could not create a return label")
+	ErrInspectionIncomplete  = errors.New("every returned line needs an outcome")
+	ErrTooManyReturnPhotos   = errors.New("a returned line can have at most 5 photos")
+)
+
+var returnReasons = map[string]bool{
+	models.ReturnReasonDamaged:        true,
+	models.ReturnReasonDefective:      true,
+	models.ReturnReasonWrongItem:      true,
+	models.ReturnReasonNotAsDescribed: true,
+	models.ReturnReasonChangedMind:    true,
+	models.ReturnReasonOther:          true,
+}
+
+var returnStatuses = map[string]bool{
+	models.ReturnStatusRequested: true,
+	models.ReturnStatusApproved:  true,
+	models.ReturnStatusRejected:  true,
+	models.ReturnStatusReceived:  true,
+	models.ReturnStatusCompleted: true,
+}
+
+// ReturnLine is a quantity of an order line a customer sends back and why
+type ReturnLine struct {
+	OrderItemID uint
+	Quantity    int
+	Reason      string
+	Comment     string
+}
+
+// ReturnDetails is what a customer enters to request a return
+type ReturnDetails struct {
+	Lines []ReturnLine
+	Note  string
+}
+
+// InspectionLine is the outcome of inspecting a returned line
+type InspectionLine struct {
+	ReturnItemID uint
+	Outcome      string
+}
+
+// Inspection records what arrived of a return. Amount overrides the value
+// of the lines refunded, e.g. to deduct a restocking fee.
+type Inspection struct {
+	Lines  []InspectionLine
+	Amount string
+	Note   string
+}
+
+// ReturnService runs returns (RMA) from the customer's request through
+// approval, the return label and inspection to the refund
+type ReturnService struct {
+	returnRepo       repository.ReturnRepository
+	productRepo      repository.ProductRepository
+	orderService     *OrderService
+	inventoryService *InventoryService
+	refundService    *RefundService
+	shippingService  *ShippingService
+	storage          storage.Storage
+	mailer           mailer.Mailer
+	maxUploadSize    int64
+	now              func() time.Time
+}
+
+func NewReturnService(returnRepo repository.ReturnRepository, productRepo repository.ProductRepository, orderService *OrderService, inventoryService *InventoryService, refundService *RefundService, shippingService *ShippingService, store storage.Storage, m mailer.Mailer) *ReturnService {
+	return &ReturnService{
+		returnRepo:       returnRepo,
+		productRepo:      productRepo,
+		orderService:     orderService,
+		inventoryService: inventoryService,
+		refundService:    refundService,
+		shippingService:  shippingService,
+		storage:          store,
+		mailer:           m,
+		maxUploadSize:    maxUploadSizeFromEnv(),
+		now:              time.Now,
+	}
+}
+
+func newReturnNumber(now time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("RMA-%s-%s", now.Format("20060102"), strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+// Request opens a return for lines of a delivered order of the user. Only
+// units neither refunded already nor part of an open return can be
+// returned; the repository checks this while holding the order's lines.
+func (s *ReturnService) Request(ctx context.Context, user *models.User, orderID uint, details ReturnDetails) (*models.Return, error) {
+	order, err := s.orderService.Get(user.ID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusDelivered {
+		return nil, ErrNotReturnable
+	}
+	if len(details.Lines) == 0 {
+		return nil, fmt.Errorf("%w: no lines to return", ErrInvalidReturn)
+	}
+
+	now := s.now()
+	number, err := newReturnNumber(now)
+	if err != nil {
+		return nil, err
+	}
+	ret := &models.Return{
+		Number:      number,
+		OrderID:     order.ID,
+		OrderNumber: order.Number,
+		UserID:      order.UserID,
+		Status:      models.ReturnStatusRequested,
+		Note:        strings.TrimSpace(details.Note),
+	}
+	for _, line := range details.Lines {
+		item := findOrderItem(order, line.OrderItemID)
+		if item == nil {
+			return nil, ErrOrderItemNotFound
+		}
+		if line.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+		if !returnReasons[line.Reason] {
+			return nil, fmt.Errorf("%w: unknown reason %q", ErrInvalidReturn, line.Reason)
+		}
+		ret.Items = append(ret.Items, models.ReturnItem{
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			SKU:         item.SKU,
+			Name:        item.Name,
+			Quantity:    line.Quantity,
+			Reason:      line.Reason,
+			Comment:     strings.TrimSpace(line.Comment),
+		})
+	}
+	if err := s.returnRepo.Create(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// AddPhoto attaches a photo to a line of the user's own return while it
+// waits for approval
+func (s *ReturnService) AddPhoto(ctx context.Context, userID, returnID, itemID uint, r io.Reader) (*models.ReturnPhoto, error) {
+	ret, err := s.returnRepo.FindByID(returnID)
+	if err != nil || ret.UserID != userID {
+		return nil, ErrReturnNotFound
+	}
+	if ret.Status != models.ReturnStatusRequested {
+		return nil, ErrReturnStatus
+	}
+	var item *models.ReturnItem
+	for i := range ret.Items {
+		if ret.Items[i].ID == itemID {
+			item = &ret.Items[i]
+		}
+	}
+	if item == nil {
+		return nil, ErrReturnItemNotFound
+	}
+	count, err := s.returnRepo.CountPhotos(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxReturnPhotos {
+		return nil, ErrTooManyReturnPhotos
+	}
+
+	data, contentType, err := readImage(r, s.maxUploadSize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("returns/%d/%x%s", ret.ID, sum[:12], imaging.Extension(contentType))
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+
+	photo := &models.ReturnPhoto{ReturnItemID: item.ID, StorageKey: key, ContentType: contentType}
+	if err := s.returnRepo.AddPhoto(photo); err != nil {
+		s.storage.Delete(ctx, key)
+		return nil, errors.New("failed to save photo")
+	}
+	photo.URL, err = s.storage.URL(ctx, key)
+	return photo, err
+}
+
+func (s *ReturnService) attachPhotoURLs(ctx context.Context, ret *models.Return) error {
+	for i := range ret.Items {
+		for j := range ret.Items[i].Photos {
+			u, err := s.storage.URL(ctx, ret.Items[i].Photos[j].StorageKey)
+			if err != nil {
+				return err
+			}
+			ret.Items[i].Photos[j].URL = u
+		}
+	}
+	return nil
+}
+
+// ForOrder returns the returns of an order the user can see
+func (s *ReturnService) ForOrder(ctx context.Context, user *models.User, orderID uint) ([]models.Return, error) {
+	order, err := s.orderService.GetAny(orderID)
+	if err != nil || (!user.IsStaff() && order.UserID != user.ID) {
+		return nil, ErrOrderNotFound
+	}
+	returns, err := s.returnRepo.FindByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range returns {
+		if err := s.attachPhotoURLs(ctx, &returns[i]); err != nil {
+			return nil, err
+		}
+	}
+	return returns, nil
+}
+
+// Get returns a return the user can see
+func (s *ReturnService) Get(ctx context.Context, user *models.User, id uint) (*models.Return, error) {
+	ret, err := s.returnRepo.FindByID(id)
+	if err != nil || (!user.IsStaff() && ret.UserID != user.ID) {
+		return nil, ErrReturnNotFound
+	}
+	return ret, s.attachPhotoURLs(ctx, ret)
+}
+
+// List returns returns in a status for staff, oldest first
+func (s *ReturnService) List(status string, page, pageSize int) ([]models.Return, error) {
+	if status != "" && !returnStatuses[status] {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidReturn, status)
+	}
+	offset, limit := paginate(page, pageSize)
+	return s.returnRepo.FindByStatus(status, offset, limit)
+}
+
+// Approve accepts a requested return and buys a return label from the
+// carrier of the order, or from another carrier when it has none. Without
+// carriers the return is approved without a label.
+func (s *ReturnService) Approve(ctx context.Context, actor *models.User, id uint, note string) (*models.Return, error) {
+	ret, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrReturnNotFound
+	}
+	if ret.Status != models.ReturnStatusRequested {
+		return nil, ErrReturnStatus
+	}
+	order, err := s.orderService.GetAny(ret.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.shippingService != nil {
+		weight := 0
+		for _, item := range ret.Items {
+			if product, err := s.productRepo.FindByID(item.ProductID); err == nil {
+				weight += product.WeightGrams * item.Quantity
+			}
+		}
+		label, carrier, err := s.shippingService.ReturnLabel(ctx, order.Carrier, ret.Number, order.ShippingAddress, weight)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrLabelFailed, carrier, err)
+		}
+		if label != nil {
+			ret.Carrier = carrier
+			ret.TrackingNumber = label.TrackingNumber
+			ret.LabelURL = label.URL
+		}
+	}
+
+	now := s.now()
+	ret.Status = models.ReturnStatusApproved
+	ret.ResolutionNote = strings.TrimSpace(note)
+	ret.ReviewedBy = &actor.ID
+	ret.ReviewedAt = &now
+	if err := s.returnRepo.Update(ret, models.ReturnStatusRequested); err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nYour return %s for order %s has been approved.\n", order.ShippingAddress.Name, ret.Number, order.Number)
+	if ret.LabelURL != "" {
+		body += fmt.Sprintf("\nPrint your %s return label here: %s\nTracking number: %s\n", ret.Carrier, ret.LabelURL, ret.TrackingNumber)
+	}
+	s.notify(ctx, order, fmt.Sprintf("Return %s approved", ret.Number), body)
+	return ret, nil
+}
+
+// Reject turns down a requested return. The reason is shown to the customer.
+func (s *ReturnService) Reject(ctx context.Context, actor *models.User, id uint, reason string) (*models.Return, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required", ErrInvalidReturn)
+	}
+	ret, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrReturnNotFound
+	}
+	if ret.Status != models.ReturnStatusRequested {
+		return nil, ErrReturnStatus
+	}
+
+	now := s.now()
+	ret.Status = models.ReturnStatusRejected
+	ret.ResolutionNote = reason
+	ret.ReviewedBy = &actor.ID
+	ret.ReviewedAt = &now
+	if err := s.returnRepo.Update(ret, models.ReturnStatusRequested); err != nil {
+		return nil, err
+	}
+
+	if order, err := s.orderService.GetAny(ret.OrderID); err == nil {
+		body := fmt.Sprintf("Hi %s,\n\nWe could not accept your return %s for order %s.\n\nReason: %s\n", order.ShippingAddress.Name, ret.Number, order.Number, reason)
+		s.notify(ctx, order, fmt.Sprintf("Return %s rejected", ret.Number), body)
+	}
+	return ret, nil
+}
+
+// Inspect records what arrived of an approved return: restocked lines go
+// back into stock, written off lines are booked out. The lines are then
+// refunded through the payment provider and the return is completed. When
+// the refund fails the return stays received, and inspecting it again only
+// retries the refund.
+func (s *ReturnService) Inspect(ctx context.Context, actor *models.User, id uint, inspection Inspection) (*models.Return, error) {
+	ret, err := s.returnRepo.FindByID(id)
+	if err != nil {
+		return nil, ErrReturnNotFound
+	}
+	switch ret.Status {
+	case models.ReturnStatusApproved:
+		if err := s.receive(actor, ret, inspection); err != nil {
+			return nil, err
+		}
+	case models.ReturnStatusReceived:
+	default:
+		return nil, ErrReturnStatus
+	}
+
+	lines := make([]RefundLine, len(ret.Items))
+	for i, item := range ret.Items {
+		lines[i] = RefundLine{OrderItemID: item.OrderItemID, Quantity: item.Quantity}
+	}
+	// 庫存已在驗收時處理，退款時不再退回庫存
+	refund, err := s.refundService.Refund(ctx, actor, ret.OrderID, RefundRequest{
+		Lines:  lines,
+		Amount: inspection.Amount,
+		Reason: "Return " + ret.Number,
+	})
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Status = models.ReturnStatusCompleted
+	ret.RefundID = &refund.ID
+	if err := s.returnRepo.Update(ret, models.ReturnStatusReceived); err != nil {
+		// 已退款，只能記錄下來人工處理
+		log.Printf("returns: %s refunded as refund %d but could not be completed: %v", ret.Number, refund.ID, err)
+		return nil, err
+	}
+	return ret, nil
+}
+
+// receive records the outcome of every line of an approved return and
+// moves the stock accordingly. When the stock cannot be moved the return
+// stays approved.
+func (s *ReturnService) receive(actor *models.User, ret *models.Return, inspection Inspection) error {
+	outcomes := make(map[uint]string, len(inspection.Lines))
+	for _, line := range inspection.Lines {
+		switch line.Outcome {
+		case models.ReturnOutcomeRestock, models.ReturnOutcomeWriteOff:
+		default:
+			return fmt.Errorf("%w: unknown outcome %q", ErrInvalidReturn, line.Outcome)
+		}
+		outcomes[line.ReturnItemID] = line.Outcome
+	}
+	for i := range ret.Items {
+		outcome, ok := outcomes[ret.Items[i].ID]
+		if !ok {
+			return ErrInspectionIncomplete
+		}
+		ret.Items[i].Outcome = outcome
+	}
+
+	now := s.now()
+	ret.Status = models.ReturnStatusReceived
+	if note := strings.TrimSpace(inspection.Note); note != "" {
+		ret.ResolutionNote = note
+	}
+	ret.InspectedBy = &actor.ID
+	ret.InspectedAt = &now
+
+	stock := make([]repository.StockReturn, len(ret.Items))
+	productIDs := make([]uint, len(ret.Items))
+	for i, item := range ret.Items {
+		stock[i] = repository.StockReturn{
+			Reference: ret.OrderNumber,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			WriteOff:  item.Outcome == models.ReturnOutcomeWriteOff,
+			UserID:    &actor.ID,
+			Note:      "return " + ret.Number,
+		}
+		productIDs[i] = item.ProductID
+	}
+	if err := s.returnRepo.Receive(ret, stock); err != nil {
+		return err
+	}
+	s.inventoryService.notifyProducts(productIDs)
+	return nil
+}
+
+func (s *ReturnService) notify(ctx context.Context, order *models.Order, subject, body string) {
+	if err := s.mailer.Send(ctx, mailer.Message{To: []string{order.Email}, Subject: subject, Body: body}); err != nil {
+		log.Printf("returns: failed to notify %s: %v", order.Email, err)
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/shipping"
+	"e-commerce/storage"
+)
+
+type testReturns struct {
+	*testRefunds
+	returns *ReturnService
+	carrier *shipping.FakeCarrier
+}
+
+// newTestReturns delivers the paid order of newTestRefunds
+func newTestReturns(t *testing.T) *testReturns {
+	t.Helper()
+	tr := newTestRefunds(t)
+	for _, change := range []StatusChange{
+		{To: models.OrderStatusFulfilling},
+		{To: models.OrderStatusShipped, Carrier: "fake", TrackingNumber: "FK1"},
+		{To: models.OrderStatusDelivered},
+	} {
+		if _, err := tr.orders.ChangeStatus(tr.staff, tr.order.ID, change); err != nil {
+			t.Fatalf("ChangeStatus(%s) error = %v", change.To, err)
+		}
+	}
+
+	store, err := storage.NewLocalStorage(t.TempDir(), "/api/v1/media", "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	carrier := shipping.NewFakeCarrier()
+	shippingService := NewShippingService(repository.NewMockShippingRepository(), []shipping.Carrier{carrier})
+	return &testReturns{
+		testRefunds: tr,
+		returns:     NewReturnService(repository.NewMockReturnRepository(tr.orders.orderRepo, tr.inventory.inventoryRepo), repository.NewMockProductRepository(), tr.orders, tr.inventory, tr.refunds, shippingService, store, tr.mail),
+		carrier:     carrier,
+	}
+}
+
+func (tr *testReturns) onHand(t *testing.T, productID uint) int {
+	t.Helper()
+	levels, err := tr.inventory.StockLevels(productID)
+	if err != nil || len(levels) == 0 {
+		t.Fatalf("StockLevels() = %v, %v", levels, err)
+	}
+	return levels[0].OnHand
+}
+
+func TestReturnRequestValidation(t *testing.T) {
+	tr := newTestReturns(t)
+	ctx := context.Background()
+	coffee, tea := tr.order.Items[0], tr.order.Items[1]
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		lines   []ReturnLine
+		wantErr error
+	}{
+		{"another customer's order", &models.User{ID: 99}, []ReturnLine{{OrderItemID: coffee.ID, Quantity: 1, Reason: "damaged"}}, ErrOrderNotFound},
+		{"no lines", &tr.user, nil, ErrInvalidReturn},
+		{"unknown line", &tr.user, []ReturnLine{{OrderItemID: 999, Quantity: 1, Reason: "damaged"}}, ErrOrderItemNotFound},
+		{"unknown reason", &tr.user, []ReturnLine{{OrderItemID: coffee.ID, Quantity: 1, Reason: "bored"}}, ErrInvalidReturn},
+		{"more than bought", &tr.user, []ReturnLine{{OrderItemID: tea.ID, Quantity: 2, Reason: "damaged"}}, ErrReturnExceedsQuantity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tr.returns.Request(ctx, tt.user, tr.order.ID, ReturnDetails{Lines: tt.lines}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Request() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 申請中的數量不能再退一次
+	if _, err := tr.returns.Request(ctx, &tr.user, tr.order.ID, ReturnDetails{Lines: []ReturnLine{{OrderItemID: coffee.ID, Quantity: 2, Reason: "changed_mind"}}}); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if _, err := tr.returns.Request(ctx, &tr.user, tr.order.ID, ReturnDetails{Lines: []ReturnLine{{OrderItemID: coffee.ID, Quantity: 1, Reason: "damaged"}}}); !errors.Is(err, ErrReturnExceedsQuantity) {
+		t.Errorf("Request() for an open return's units error = %v, want %v", err, ErrReturnExceedsQuantity)
+	}
+}
+
+func TestConcurrentReturnRequests(t *testing.T) {
+	tr := newTestReturns(t)
+	tea := tr.order.Items[1]
+	details := ReturnDetails{Lines: []ReturnLine{{OrderItemID: tea.ID, Quantity: 1, Reason: "changed_mind"}}}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	opened := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tr.returns.Request(context.Background(), &tr.user, tr.order.ID, details)
+			if err != nil && !errors.Is(err, ErrReturnExceedsQuantity) {
+				t.Errorf("Request() error = %v", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				opened++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if opened != 1 {
+		t.Errorf("opened %d returns for the single tea, want 1", opened)
+	}
+}
+
+func TestReturnOfUndeliveredOrder(t *testing.T) {
+	tr := newTestRefunds(t)
+	returns := NewReturnService(repository.NewMockReturnRepository(tr.orders.orderRepo, tr.inventory.inventoryRepo), repository.NewMockProductRepository(), tr.orders, tr.inventory, tr.refunds, nil, nil, tr.mail)
+	_, err := returns.Request(context.Background(), &tr.user, tr.order.ID, ReturnDetails{
+		Lines: []ReturnLine{{OrderItemID: tr.order.Items[0].ID, Quantity: 1, Reason: "damaged"}},
+	})
+	if !errors.Is(err, ErrNotReturnable) {
+		t.Errorf("Request() error = %v, want %v", err, ErrNotReturnable)
+	}
+}
+
+func TestReturnLifecycle(t *testing.T) {
+	tr := newTestReturns(t)
+	ctx := context.Background()
+	coffee, tea := tr.order.Items[0], tr.order.Items[1]
+
+	ret, err := tr.returns.Request(ctx, &tr.user, tr.order.ID, ReturnDetails{
+		Lines: []ReturnLine{
+			{OrderItemID: coffee.ID, Quantity: 2, Reason: "damaged", Comment: "One bag torn"},
+			{OrderItemID: tea.ID, Quantity: 1, Reason: "changed_mind"},
+		},
+		Note: "Please pick up in the morning",
+	})
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if ret.Status != models.ReturnStatusRequested || len(ret.Items) != 2 || ret.Items[0].SKU != "SKU-1" {
+		t.Errorf("return = %+v, want 2 requested lines", ret)
+	}
+
+	photo, err := tr.returns.AddPhoto(ctx, tr.user.ID, ret.ID, ret.Items[0].ID, bytes.NewReader(newTestPNG(t, 8, 8)))
+	if err != nil || photo.URL == "" {
+		t.Fatalf("AddPhoto() = %+v, %v, want a photo with a URL", photo, err)
+	}
+	if _, err := tr.returns.AddPhoto(ctx, 99, ret.ID, ret.Items[0].ID, bytes.NewReader(newTestPNG(t, 8, 8))); !errors.Is(err, ErrReturnNotFound) {
+		t.Errorf("AddPhoto() by another customer error = %v, want %v", err, ErrReturnNotFound)
+	}
+
+	// 物流商故障時不核准，維持申請中
+	tr.carrier.SetDown(true)
+	if _, err := tr.returns.Approve(ctx, tr.staff, ret.ID, ""); !errors.Is(err, ErrLabelFailed) {
+		t.Fatalf("Approve() while the carrier is down error = %v, want %v", err, ErrLabelFailed)
+	}
+	tr.carrier.SetDown(false)
+	approved, err := tr.returns.Approve(ctx, tr.staff, ret.ID, "")
+	if err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if approved.Status != models.ReturnStatusApproved || approved.Carrier != "fake" || approved.TrackingNumber == "" || approved.LabelURL == "" {
+		t.Errorf("approved return = %+v, want a fake return label", approved)
+	}
+	if _, err := tr.returns.Reject(ctx, tr.staff, ret.ID, "Too late"); !errors.Is(err, ErrReturnStatus) {
+		t.Errorf("Reject() after approval error = %v, want %v", err, ErrReturnStatus)
+	}
+	if _, err := tr.returns.AddPhoto(ctx, tr.user.ID, ret.ID, ret.Items[0].ID, bytes.NewReader(newTestPNG(t, 8, 8))); !errors.Is(err, ErrReturnStatus) {
+		t.Errorf("AddPhoto() after approval error = %v, want %v", err, ErrReturnStatus)
+	}
+	sent := tr.mail.Sent()
+	if last := sent[len(sent)-1]; !bytes.Contains([]byte(last.Body), []byte(approved.LabelURL)) {
+		t.Errorf("approval email = %q, want the label URL", last.Body)
+	}
+
+	if _, err := tr.returns.Inspect(ctx, tr.staff, ret.ID, Inspection{
+		Lines: []InspectionLine{{ReturnItemID: ret.Items[0].ID, Outcome: models.ReturnOutcomeWriteOff}},
+	}); !errors.Is(err, ErrInspectionIncomplete) {
+		t.Errorf("Inspect() without every line error = %v, want %v", err, ErrInspectionIncomplete)
+	}
+
+	coffeeBefore, teaBefore := tr.onHand(t, 1), tr.onHand(t, 2)
+	completed, err := tr.returns.Inspect(ctx, tr.staff, ret.ID, Inspection{
+		Lines: []InspectionLine{
+			{ReturnItemID: ret.Items[0].ID, Outcome: models.ReturnOutcomeWriteOff},
+			{ReturnItemID: ret.Items[1].ID, Outcome: models.ReturnOutcomeRestock},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if completed.Status != models.ReturnStatusCompleted || completed.RefundID == nil || completed.Items[0].Outcome != models.ReturnOutcomeWriteOff {
+		t.Errorf("inspected return = %+v, want completed with a refund", completed)
+	}
+	if got := tr.onHand(t, 1); got != coffeeBefore {
+		t.Errorf("coffee on hand = %d, want %d after writing off", got, coffeeBefore)
+	}
+	if got := tr.onHand(t, 2); got != teaBefore+1 {
+		t.Errorf("tea on hand = %d, want %d after restocking", got, teaBefore+1)
+	}
+	movements, _ := tr.inventory.Movements(1, 1, 20)
+	if len(movements) < 2 || movements[0].Type != models.MovementWriteOff || movements[1].Type != models.MovementReturn {
+		t.Errorf("coffee movements = %+v, want a return written off", movements)
+	}
+
+	order := tr.reload(t)
+	if order.Status != models.OrderStatusRefunded || order.RefundedTotal != twd(1200) {
+		t.Errorf("order = %s with %v refunded, want refunded in full", order.Status, order.RefundedTotal)
+	}
+
+	returns, err := tr.returns.ForOrder(ctx, &tr.user, tr.order.ID)
+	if err != nil || len(returns) != 1 || len(returns[0].Items[0].Photos) != 1 || returns[0].Items[0].Photos[0].URL == "" {
+		t.Errorf("ForOrder() = %+v, %v, want the return with its photo", returns, err)
+	}
+	if _, err := tr.returns.Get(ctx, &models.User{ID: 99}, ret.ID); !errors.Is(err, ErrReturnNotFound) {
+		t.Errorf("Get() by another customer error = %v, want %v", err, ErrReturnNotFound)
+	}
+}
+
+func TestRejectedReturnFreesUnits(t *testing.T) {
+	tr := newTestReturns(t)
+	ctx := context.Background()
+	line := []ReturnLine{{OrderItemID: tr.order.Items[1].ID, Quantity: 1, Reason: "changed_mind"}}
+
+	ret, err := tr.returns.Request(ctx, &tr.user, tr.order.ID, ReturnDetails{Lines: line})
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if _, err := tr.returns.Reject(ctx, tr.staff, ret.ID, " "); !errors.Is(err, ErrInvalidReturn) {
+		t.Errorf("Reject() without a reason error = %v, want %v", err, ErrInvalidReturn)
+	}
+	rejected, err := tr.returns.Reject(ctx, tr.staff, ret.ID, "Opened food cannot be returned")
+	if err != nil || rejected.Status != models.ReturnStatusRejected {
+		t.Fatalf("Reject() = %+v, %v, want rejected", rejected, err)
+	}
+	if _, err := tr.returns.Request(ctx, &tr.user, tr.order.ID, ReturnDetails{Lines: line}); err != nil {
+		t.Errorf("Request() after rejection error = %v", err)
+	}
+
+	if list, _ := tr.returns.List(models.ReturnStatusRequested, 1, 20); len(list) != 1 {
+		t.Errorf("List(requested) = %d returns, want 1", len(list))
+	}
+	if _, err := tr.returns.List("lost", 1, 20); !errors.Is(err, ErrInvalidReturn) {
+		t.Errorf("List(lost) error = %v, want %v", err, ErrInvalidReturn)
+	}
+}
+
+func TestReceiveKeepsReturnWhenStockFails(t *testing.T) {
+	tr := newTestReturns(t)
+	ctx := context.Background()
+	coffee, tea := tr.order.Items[0], tr.order.Items[1]
+
+	ret, err := tr.returns.Request(ctx, &tr.user, tr.order.ID, ReturnDetails{Lines: []ReturnLine{
+		{OrderItemID: coffee.ID, Quantity: 1, Reason: "damaged"},
+		{OrderItemID: tea.ID, Quantity: 1, Reason: "changed_mind"},
+	}})
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if _, err := tr.returns.Approve(ctx, tr.staff, ret.ID, ""); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	// 茶已全數以其他方式退回庫存，此退貨無法再入帳
+	if err := tr.inventory.ReturnStock(tr.staff.ID, tr.order.Number, tea.ProductID, tea.Quantity, "manual"); err != nil {
+		t.Fatalf("ReturnStock() error = %v", err)
+	}
+
+	coffeeBefore := tr.onHand(t, coffee.ProductID)
+	_, err = tr.returns.Inspect(ctx, tr.staff, ret.ID, Inspection{Lines: []InspectionLine{
+		{ReturnItemID: ret.Items[0].ID, Outcome: models.ReturnOutcomeRestock},
+		{ReturnItemID: ret.Items[1].ID, Outcome: models.ReturnOutcomeRestock},
+	}})
+	if !errors.Is(err, ErrReturnExceedsSold) {
+		t.Fatalf("Inspect() error = %v, want %v", err, ErrReturnExceedsSold)
+	}
+	if got := tr.onHand(t, coffee.ProductID); got != coffeeBefore {
+		t.Errorf("coffee on hand = %d, want %d after the failed receipt", got, coffeeBefore)
+	}
+	stored, err := tr.returns.Get(ctx, tr.staff, ret.ID)
+	if err != nil || stored.Status != models.ReturnStatusApproved || stored.RefundID != nil {
+		t.Errorf("Get() = %+v, %v, want the return still approved", stored, err)
+	}
+}
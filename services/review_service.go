@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"e-commerce/imaging"
+	"e-commerce/models"
+	"e-commerce/repository"
+	"e-commerce/storage"
+)
+
+const maxReviewPhotos = 5
+
+var (
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReviewExists     = errors.New("you have already reviewed this product")
+	ErrNotVerifiedBuyer = errors.New("only customers who purchased this product can review it")
+	ErrInvalidRating    = errors.New("rating must be between 1 and 5")
+	ErrReviewForbidden  = errors.New("you can only change your own review")
+	ErrTooManyPhotos    = errors.New("a review can have at most 5 photos")
+	ErrOwnReviewVote    = errors.New("you cannot vote on your own review")
+	ErrAlreadyVoted     = errors.New("you have already voted on this review")
+)
+
+// PurchaseVerifier reports whether a user has bought a product
+type PurchaseVerifier interface {
+	HasPurchased(userID, productID uint) (bool, error)
+}
+
+// NoPurchaseVerifier treats nobody as a verified buyer, which keeps reviews
+// closed rather than open to all when no order history is available.
+type NoPurchaseVerifier struct{}
+
+func NewNoPurchaseVerifier() PurchaseVerifier {
+	return NoPurchaseVerifier{}
+}
+
+func (NoPurchaseVerifier) HasPurchased(userID, productID uint) (bool, error) {
+	return false, nil
+}
+
+type ReviewService struct {
+	reviewRepo    repository.ReviewRepository
+	productRepo   repository.ProductRepository
+	purchases     PurchaseVerifier
+	storage       storage.Storage
+	maxUploadSize int64
+}
+
+func NewReviewService(reviewRepo repository.ReviewRepository, productRepo repository.ProductRepository, purchases PurchaseVerifier, store storage.Storage) *ReviewService {
+	return &ReviewService{
+		reviewRepo:    reviewRepo,
+		productRepo:   productRepo,
+		purchases:     purchases,
+		storage:       store,
+		maxUploadSize: maxUploadSizeFromEnv(),
+	}
+}
+
+// displayName shortens a full name to first name and last initial
+func displayName(name string) string {
+	parts := strings.Fields(name)
+	if len(parts) == 0 {
+		return "Anonymous"
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	last := []rune(parts[len(parts)-1])
+	return parts[0] + " " + string(last[0]) + "."
+}
+
+func (s *ReviewService) Create(user models.User, productID uint, rating int, title, body string) (*models.Review, error) {
+	if rating < 1 || rating > 5 {
+		return nil, ErrInvalidRating
+	}
+	if product, err := s.productRepo.FindByID(productID); err != nil || !product.IsVisible(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+	if existing, _ := s.reviewRepo.FindByProductAndUser(productID, user.ID); existing != nil {
+		return nil, ErrReviewExists
+	}
+
+	purchased, err := s.purchases.HasPurchased(user.ID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !purchased {
+		return nil, ErrNotVerifiedBuyer
+	}
+
+	review := &models.Review{
+		ProductID:  productID,
+		UserID:     user.ID,
+		AuthorName: displayName(user.Name),
+		Rating:     rating,
+		Title:      title,
+		Body:       body,
+		Status:     models.ReviewStatusPending,
+	}
+	if err := s.reviewRepo.Create(review); err != nil {
+		// 唯一索引擋下同時送出的重複評論
+		return nil, ErrReviewExists
+	}
+	return review, nil
+}
+
+// Update edits the user's own review, which sends it back to moderation
+func (s *ReviewService) Update(userID, reviewID uint, rating int, title, body string) (*models.Review, error) {
+	if rating < 1 || rating > 5 {
+		return nil, ErrInvalidRating
+	}
+	review, err := s.ownReview(userID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Rating = rating
+	review.Title = title
+	review.Body = body
+	review.Status = models.ReviewStatusPending
+	review.ModerationNote = ""
+	if err := s.reviewRepo.Update(review); err != nil {
+		return nil, errors.New("failed to update review")
+	}
+	return review, s.attachPhotoURLs(context.Background(), review)
+}
+
+func (s *ReviewService) Delete(user models.User, reviewID uint) error {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return ErrReviewNotFound
+	}
+	if review.UserID != user.ID && !user.IsStaff() {
+		return ErrReviewForbidden
+	}
+	if err := s.reviewRepo.Delete(reviewID); err != nil {
+		return err
+	}
+
+	for _, photo := range review.Photos {
+		s.storage.Delete(context.Background(), photo.StorageKey)
+	}
+	return nil
+}
+
+func (s *ReviewService) AddPhoto(ctx context.Context, userID, reviewID uint, r io.Reader) (*models.ReviewPhoto, error) {
+	review, err := s.ownReview(userID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.reviewRepo.CountPhotos(review.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxReviewPhotos {
+		return nil, ErrTooManyPhotos
+	}
+
+	data, contentType, err := readImage(r, s.maxUploadSize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("reviews/%d/%x%s", review.ID, sum[:12], imaging.Extension(contentType))
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+
+	photo := &models.ReviewPhoto{ReviewID: review.ID, StorageKey: key, ContentType: contentType}
+	if err := s.reviewRepo.AddPhoto(photo); err != nil {
+		s.storage.Delete(ctx, key)
+		return nil, errors.New("failed to save photo")
+	}
+
+	// 新增照片後需重新審核
+	if review.Status != models.ReviewStatusPending {
+		review.Status = models.ReviewStatusPending
+		if err := s.reviewRepo.Update(review); err != nil {
+			return nil, err
+		}
+	}
+
+	photo.URL, err = s.storage.URL(ctx, key)
+	return photo, err
+}
+
+func (s *ReviewService) ListForProduct(ctx context.Context, productID uint, sort string, page, pageSize int) ([]models.Review, error) {
+	if product, err := s.productRepo.FindByID(productID); err != nil || !product.IsVisible(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+	offset, limit := paginate(page, pageSize)
+	reviews, err := s.reviewRepo.FindByProductID(productID, sort, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		if err := s.attachPhotoURLs(ctx, &reviews[i]); err != nil {
+			return nil, err
+		}
+	}
+	return reviews, nil
+}
+
+func (s *ReviewService) Vote(userID, reviewID uint) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil || review.Status != models.ReviewStatusApproved {
+		return nil, ErrReviewNotFound
+	}
+	if review.UserID == userID {
+		return nil, ErrOwnReviewVote
+	}
+	if err := s.reviewRepo.AddVote(reviewID, userID); err != nil {
+		if errors.Is(err, repository.ErrDuplicateVote) {
+			return nil, ErrAlreadyVoted
+		}
+		return nil, err
+	}
+	return s.reviewRepo.FindByID(reviewID)
+}
+
+// ModerationQueue lists reviews waiting for a moderator, oldest first
+func (s *ReviewService) ModerationQueue(ctx context.Context, status string, page, pageSize int) ([]models.Review, error) {
+	if status == "" {
+		status = models.ReviewStatusPending
+	}
+	offset, limit := paginate(page, pageSize)
+	reviews, err := s.reviewRepo.FindByStatus(status, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		if err := s.attachPhotoURLs(ctx, &reviews[i]); err != nil {
+			return nil, err
+		}
+	}
+	return reviews, nil
+}
+
+func (s *ReviewService) Moderate(moderatorID, reviewID uint, approve bool, note string) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+
+	now := time.Now()
+	review.Status = models.ReviewStatusRejected
+	if approve {
+		review.Status = models.ReviewStatusApproved
+	}
+	review.ModerationNote = note
+	review.ModeratedBy = &moderatorID
+	review.ModeratedAt = &now
+	if err := s.reviewRepo.Update(review); err != nil {
+		return nil, errors.New("failed to update review")
+	}
+	return review, nil
+}
+
+func (s *ReviewService) ownReview(userID, reviewID uint) (*models.Review, error) {
+	review, err := s.reviewRepo.FindByID(reviewID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+	if review.UserID != userID {
+		return nil, ErrReviewForbidden
+	}
+	return review, nil
+}
+
+func (s *ReviewService) attachPhotoURLs(ctx context.Context, review *models.Review) error {
+	for i := range review.Photos {
+		u, err := s.storage.URL(ctx, review.Photos[i].StorageKey)
+		if err != nil {
+			return err
+		}
+		review.Photos[i].URL = u
+	}
+	return nil
+}
+
+// paginate converts a 1-based page into an offset and limit
+func paginate(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return (page - 1) * pageSize, pageSize
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+// stubPurchaseVerifier treats the listed user IDs as buyers of every product
+type stubPurchaseVerifier map[uint]bool
+
+func (s stubPurchaseVerifier) HasPurchased(userID, productID uint) (bool, error) {
+	return s[userID], nil
+}
+
+func TestReviewLifecycle(t *testing.T) {
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee", Status: models.ProductStatusPublished})
+	reviewRepo := repository.NewMockReviewRepository(productRepo)
+	reviewService := NewReviewService(reviewRepo, productRepo, stubPurchaseVerifier{1: true, 2: true}, nil)
+
+	buyer := models.User{ID: 1, Name: "Wang Xiao Ming"}
+	other := models.User{ID: 2, Name: "Lin"}
+	stranger := models.User{ID: 3, Name: "Stranger"}
+
+	tests := []struct {
+		name    string
+		user    models.User
+		rating  int
+		wantErr error
+	}{
+		{"verified buyer", buyer, 4, nil},
+		{"second review", buyer, 5, ErrReviewExists},
+		{"not a buyer", stranger, 5, ErrNotVerifiedBuyer},
+		{"invalid rating", other, 6, ErrInvalidRating},
+		{"another buyer", other, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := reviewService.Create(tt.user, 1, tt.rating, "title", "body")
+			if err != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	product, _ := productRepo.FindByID(1)
+	if product.Rating.Count != 0 {
+		t.Errorf("pending reviews counted in rating: %+v", product.Rating)
+	}
+
+	first, _ := reviewRepo.FindByProductAndUser(1, buyer.ID)
+	if first.AuthorName != "Wang M." {
+		t.Errorf("AuthorName = %q, want %q", first.AuthorName, "Wang M.")
+	}
+	second, _ := reviewRepo.FindByProductAndUser(1, other.ID)
+	for _, id := range []uint{first.ID, second.ID} {
+		if _, err := reviewService.Moderate(99, id, true, ""); err != nil {
+			t.Fatalf("Moderate() error = %v", err)
+		}
+	}
+
+	product, _ = productRepo.FindByID(1)
+	want := models.RatingSummary{Average: 3, Count: 2, Star2: 1, Star4: 1}
+	if product.Rating != want {
+		t.Errorf("rating after approval = %+v, want %+v", product.Rating, want)
+	}
+
+	if _, err := reviewService.Vote(buyer.ID, first.ID); err != ErrOwnReviewVote {
+		t.Errorf("Vote() on own review error = %v, want %v", err, ErrOwnReviewVote)
+	}
+	if review, err := reviewService.Vote(other.ID, first.ID); err != nil || review.HelpfulCount != 1 {
+		t.Errorf("Vote() = %+v, %v", review, err)
+	}
+	if _, err := reviewService.Vote(other.ID, first.ID); err != ErrAlreadyVoted {
+		t.Errorf("second Vote() error = %v, want %v", err, ErrAlreadyVoted)
+	}
+
+	// 修改評論後重新進入審核，評分統計隨之更新
+	if _, err := reviewService.Update(buyer.ID, first.ID, 5, "edited", "body"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := reviewService.Update(other.ID, first.ID, 1, "hijack", "body"); err != ErrReviewForbidden {
+		t.Errorf("Update() of another user's review error = %v, want %v", err, ErrReviewForbidden)
+	}
+	product, _ = productRepo.FindByID(1)
+	if product.Rating.Count != 1 || product.Rating.Average != 2 {
+		t.Errorf("rating after edit = %+v", product.Rating)
+	}
+
+	queue, _ := reviewService.ModerationQueue(context.Background(), "", 1, 20)
+	if len(queue) != 1 || queue[0].ID != first.ID {
+		t.Errorf("ModerationQueue() = %+v", queue)
+	}
+
+	if err := reviewService.Delete(stranger, second.ID); err != ErrReviewForbidden {
+		t.Errorf("Delete() by stranger error = %v, want %v", err, ErrReviewForbidden)
+	}
+	if err := reviewService.Delete(models.User{ID: 50, Role: models.RoleStaff}, second.ID); err != nil {
+		t.Errorf("Delete() by staff error = %v", err)
+	}
+	product, _ = productRepo.FindByID(1)
+	if product.Rating.Count != 0 {
+		t.Errorf("rating after delete = %+v", product.Rating)
+	}
+}
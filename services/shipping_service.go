@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+	"e-commerce/shipping"
+)
+
+var (
+	ErrShippingZoneNotFound   = errors.New("shipping zone not found")
+	ErrShippingMethodNotFound = errors.New("shipping method not found")
+	ErrInvalidShippingZone    = errors.New("invalid shipping zone")
+	ErrInvalidShippingMethod  = errors.New("invalid shipping method")
+	ErrShippingMethodRequired = errors.New("shipping method is required")
+	ErrShippingUnavailable    = errors.New("shipping method is not available for this cart and address")
+)
+
+// ShippingZoneDetails is what staff enter for a zone
+type ShippingZoneDetails struct {
+	Name        string
+	Countries   []string
+	Cities      []string
+	PostalCodes []string
+}
+
+// ShippingTierDetails is a step of a rate table. Min is whole grams for
+// weight-based rates and a decimal amount for price-based rates.
+type ShippingTierDetails struct {
+	Min    string
+	Amount string
+}
+
+// ShippingMethodDetails is what staff enter for a method. Amounts are
+// decimal strings in Currency.
+type ShippingMethodDetails struct {
+	Name           string
+	Active         bool
+	Currency       string
+	RateType       string
+	Amount         string
+	Tiers          []ShippingTierDetails
+	FreeOver       string
+	Carrier        string
+	CarrierService string
+	EstimatedDays  int
+}
+
+// ShippingService manages shipping zones and methods and quotes carts
+// against them
+type ShippingService struct {
+	shippingRepo repository.ShippingRepository
+	carriers     map[string]shipping.Carrier
+}
+
+func NewShippingService(shippingRepo repository.ShippingRepository, carriers []shipping.Carrier) *ShippingService {
+	byName := make(map[string]shipping.Carrier, len(carriers))
+	for _, c := range carriers {
+		byName[c.Name()] = c
+	}
+	return &ShippingService{
+		shippingRepo: shippingRepo,
+		carriers:     byName,
+	}
+}
+
+func (s *ShippingService) Zones() ([]models.ShippingZone, error) {
+	return s.shippingRepo.FindZones()
+}
+
+func (s *ShippingService) Zone(id uint) (*models.ShippingZone, error) {
+	zone, err := s.shippingRepo.FindZoneByID(id)
+	if err != nil {
+		return nil, ErrShippingZoneNotFound
+	}
+	return zone, nil
+}
+
+func (s *ShippingService) CreateZone(details ShippingZoneDetails) (*models.ShippingZone, error) {
+	zone := &models.ShippingZone{}
+	if err := buildShippingZone(zone, details); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.CreateZone(zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+func (s *ShippingService) UpdateZone(id uint, details ShippingZoneDetails) (*models.ShippingZone, error) {
+	zone, err := s.Zone(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := buildShippingZone(zone, details); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.UpdateZone(zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// DeleteZone removes a zone and its methods
+func (s *ShippingService) DeleteZone(id uint) error {
+	if _, err := s.Zone(id); err != nil {
+		return err
+	}
+	return s.shippingRepo.DeleteZone(id)
+}
+
+func buildShippingZone(zone *models.ShippingZone, details ShippingZoneDetails) error {
+	name := strings.TrimSpace(details.Name)
+	countries := trimAll(details.Countries)
+	if name == "" || len(countries) == 0 {
+		return fmt.Errorf("%w: a zone needs a name and at least one country", ErrInvalidShippingZone)
+	}
+	for i, c := range countries {
+		if len(c) != 2 {
+			return fmt.Errorf("%w: %q is not a country code", ErrInvalidShippingZone, c)
+		}
+		countries[i] = strings.ToUpper(c)
+	}
+	zone.Name = name
+	zone.Area = models.ShippingArea{
+		Countries:   countries,
+		Cities:      trimAll(details.Cities),
+		PostalCodes: trimAll(details.PostalCodes),
+	}
+	return nil
+}
+
+func (s *ShippingService) Method(id uint) (*models.ShippingMethod, error) {
+	method, err := s.shippingRepo.FindMethodByID(id)
+	if err != nil {
+		return nil, ErrShippingMethodNotFound
+	}
+	return method, nil
+}
+
+func (s *ShippingService) CreateMethod(zoneID uint, details ShippingMethodDetails) (*models.ShippingMethod, error) {
+	if _, err := s.Zone(zoneID); err != nil {
+		return nil, err
+	}
+	method := &models.ShippingMethod{ZoneID: zoneID}
+	if err := s.buildShippingMethod(method, details); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.CreateMethod(method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+func (s *ShippingService) UpdateMethod(id uint, details ShippingMethodDetails) (*models.ShippingMethod, error) {
+	method, err := s.Method(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.buildShippingMethod(method, details); err != nil {
+		return nil, err
+	}
+	if err := s.shippingRepo.UpdateMethod(method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+func (s *ShippingService) DeleteMethod(id uint) error {
+	if _, err := s.Method(id); err != nil {
+		return err
+	}
+	return s.shippingRepo.DeleteMethod(id)
+}
+
+func invalidShippingMethod(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidShippingMethod, reason)
+}
+
+// buildShippingMethod validates details and copies them onto method
+func (s *ShippingService) buildShippingMethod(method *models.ShippingMethod, details ShippingMethodDetails) error {
+	name := strings.TrimSpace(details.Name)
+	if name == "" {
+		return invalidShippingMethod("name is required")
+	}
+	if details.EstimatedDays < 0 {
+		return invalidShippingMethod("estimated_days cannot be negative")
+	}
+	currency := strings.ToUpper(details.Currency)
+	if currency != "" && !money.IsSupported(currency) {
+		return money.ErrUnknownCurrency
+	}
+	if currency == "" && details.RateType != models.ShippingRateCarrier {
+		return invalidShippingMethod("currency is required")
+	}
+	parse := func(field, value string) (money.Money, error) {
+		amount, err := money.Parse(value, currency)
+		if err != nil || amount.IsNegative() {
+			return money.Money{}, invalidShippingMethod(field + " must be an amount of zero or more")
+		}
+		return amount, nil
+	}
+
+	result := models.ShippingMethod{
+		ID:            method.ID,
+		CreatedAt:     method.CreatedAt,
+		ZoneID:        method.ZoneID,
+		Name:          name,
+		Active:        details.Active,
+		Currency:      currency,
+		RateType:      details.RateType,
+		EstimatedDays: details.EstimatedDays,
+	}
+	if currency != "" {
+		result.Amount = money.Zero(currency)
+	}
+	if details.FreeOver != "" {
+		if currency == "" {
+			return invalidShippingMethod("free_over needs a currency")
+		}
+		freeOver, err := parse("free_over", details.FreeOver)
+		if err != nil {
+			return err
+		}
+		result.FreeOver = &freeOver
+	}
+
+	switch details.RateType {
+	case models.ShippingRateFlat:
+		amount, err := parse("amount", details.Amount)
+		if err != nil {
+			return err
+		}
+		result.Amount = amount
+	case models.ShippingRateWeight, models.ShippingRatePrice:
+		if len(details.Tiers) == 0 {
+			return invalidShippingMethod("rate tables need at least one tier")
+		}
+		for _, t := range details.Tiers {
+			var from int64
+			if details.RateType == models.ShippingRateWeight {
+				grams, err := strconv.ParseInt(strings.TrimSpace(t.Min), 10, 64)
+				if err != nil || grams < 0 {
+					return invalidShippingMethod("tier min must be whole grams")
+				}
+				from = grams
+			} else {
+				threshold, err := parse("tier min", t.Min)
+				if err != nil {
+					return err
+				}
+				from = threshold.Amount
+			}
+			amount, err := parse("tier amount", t.Amount)
+			if err != nil {
+				return err
+			}
+			result.Tiers = append(result.Tiers, models.ShippingRateTier{Min: from, Amount: amount})
+		}
+		sort.Slice(result.Tiers, func(i, j int) bool {
+			return result.Tiers[i].Min < result.Tiers[j].Min
+		})
+	case models.ShippingRateCarrier:
+		carrier := strings.TrimSpace(details.Carrier)
+		if _, ok := s.carriers[carrier]; !ok {
+			return invalidShippingMethod("unknown carrier " + carrier)
+		}
+		service := strings.TrimSpace(details.CarrierService)
+		if service == "" {
+			return invalidShippingMethod("carrier_service is required")
+		}
+		result.Carrier = carrier
+		result.CarrierService = service
+	case models.ShippingRateFree:
+	default:
+		return invalidShippingMethod("unknown rate type " + details.RateType)
+	}
+
+	*method = result
+	return nil
+}
+
+// normalizePlace compares place names ignoring case and the 臺/台 variants
+func normalizePlace(place string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(place), "臺", "台"))
+}
+
+// matchZone returns the most specific zone covering an address: postal
+// code zones before city zones before whole countries
+func matchZone(zones []models.ShippingZone, address models.Address) *models.ShippingZone {
+	var best *models.ShippingZone
+	bestScore := -1
+	for i, zone := range zones {
+		area := zone.Area
+		if !containsFold(area.Countries, address.Country) {
+			continue
+		}
+		score := 0
+		if len(area.Cities) > 0 {
+			found := false
+			for _, city := range area.Cities {
+				found = found || (address.City != "" && normalizePlace(city) == normalizePlace(address.City))
+			}
+			if !found {
+				continue
+			}
+			score++
+		}
+		if len(area.PostalCodes) > 0 {
+			found := false
+			postalCode := strings.ReplaceAll(address.PostalCode, " ", "")
+			for _, prefix := range area.PostalCodes {
+				found = found || (postalCode != "" && strings.HasPrefix(postalCode, prefix))
+			}
+			if !found {
+				continue
+			}
+			score += 2
+		}
+		if score > bestScore {
+			best, bestScore = &zones[i], score
+		}
+	}
+	return best
+}
+
+// shippingParcel is what a cart sends
+type shippingParcel struct {
+	weightGrams  int
+	value        money.Money
+	freeShipping bool
+}
+
+// parcelOf measures a priced cart. Its value is what the customer pays for
+// the goods, after discounts.
+func parcelOf(cart *models.Cart) (shippingParcel, error) {
+	if cart.Subtotal == nil {
+		return shippingParcel{}, ErrCartEmpty
+	}
+	parcel := shippingParcel{value: *cart.Subtotal, freeShipping: cart.FreeShipping}
+	if cart.Total != nil {
+		parcel.value = *cart.Total
+	}
+	for _, item := range cart.Items {
+		if item.Product != nil {
+			parcel.weightGrams += item.Product.WeightGrams * item.Quantity
+		}
+	}
+	return parcel, nil
+}
+
+// rate prices a method for a parcel, reporting false when the method does
+// not ship it
+func (s *ShippingService) rate(ctx context.Context, method models.ShippingMethod, parcel shippingParcel, address models.Address) (*models.ShippingQuote, bool) {
+	currency := parcel.value.Currency
+	if !method.Active || (method.Currency != "" && method.Currency != currency) {
+		return nil, false
+	}
+	quote := &models.ShippingQuote{
+		MethodID:      method.ID,
+		Name:          method.Name,
+		Amount:        money.Zero(currency),
+		EstimatedDays: method.EstimatedDays,
+	}
+
+	switch method.RateType {
+	case models.ShippingRateFlat:
+		quote.Amount = method.Amount
+	case models.ShippingRateWeight, models.ShippingRatePrice:
+		measure := int64(parcel.weightGrams)
+		if method.RateType == models.ShippingRatePrice {
+			measure = parcel.value.Amount
+		}
+		// 取不超過包裹的最高一階
+		found := false
+		for _, tier := range method.Tiers {
+			if tier.Min <= measure {
+				quote.Amount, found = tier.Amount, true
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	case models.ShippingRateCarrier:
+		carrier, ok := s.carriers[method.Carrier]
+		if !ok {
+			return nil, false
+		}
+		rates, err := carrier.Rates(ctx, shipping.RateRequest{
+			Destination: shipping.Destination{Country: address.Country, City: address.City, PostalCode: address.PostalCode},
+			WeightGrams: parcel.weightGrams,
+			Value:       parcel.value,
+		})
+		if err != nil {
+			log.Printf("shipping: %s rates for method %d: %v", carrier.Name(), method.ID, err)
+			return nil, false
+		}
+		found := false
+		for _, r := range rates {
+			if r.Service == method.CarrierService && r.Amount.Currency == currency {
+				quote.Amount, found = r.Amount, true
+				if quote.EstimatedDays == 0 {
+					quote.EstimatedDays = r.EstimatedDays
+				}
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		quote.Carrier = carrier.Name()
+	case models.ShippingRateFree:
+	default:
+		return nil, false
+	}
+
+	if parcel.freeShipping || (method.FreeOver != nil && method.FreeOver.Currency == currency && parcel.value.Cmp(*method.FreeOver) >= 0) {
+		quote.Amount = money.Zero(currency)
+	}
+	return quote, true
+}
+
+// Quote returns the shipping methods available for a priced cart to an
+// address, cheapest first
+func (s *ShippingService) Quote(ctx context.Context, cart *models.Cart, address models.Address) ([]models.ShippingQuote, error) {
+	parcel, err := parcelOf(cart)
+	if err != nil {
+		return nil, err
+	}
+	zones, err := s.shippingRepo.FindZones()
+	if err != nil {
+		return nil, err
+	}
+	quotes := []models.ShippingQuote{}
+	zone := matchZone(zones, address)
+	if zone == nil {
+		return quotes, nil
+	}
+	for _, method := range zone.Methods {
+		if quote, ok := s.rate(ctx, method, parcel, address); ok {
+			quotes = append(quotes, *quote)
+		}
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		return quotes[i].Amount.Cmp(quotes[j].Amount) < 0
+	})
+	return quotes, nil
+}
+
+// QuoteMethod prices the chosen method for a priced cart to an address
+func (s *ShippingService) QuoteMethod(ctx context.Context, cart *models.Cart, address models.Address, methodID uint) (*models.ShippingQuote, error) {
+	if methodID == 0 {
+		return nil, ErrShippingMethodRequired
+	}
+	quotes, err := s.Quote(ctx, cart, address)
+	if err != nil {
+		return nil, err
+	}
+	for _, quote := range quotes {
+		if quote.MethodID == methodID {
+			return &quote, nil
+		}
+	}
+	return nil, ErrShippingUnavailable
+}
+
+// ReturnLabel buys a return label for a parcel picked up at address from
+// the preferred carrier, or from the first carrier by name when it is not
+// configured. It returns no label and no error when there are no carriers,
+// and the name of the carrier otherwise.
+func (s *ShippingService) ReturnLabel(ctx context.Context, preferred, reference string, address models.Address, weightGrams int) (*shipping.Label, string, error) {
+	carrier, ok := s.carriers[preferred]
+	if !ok {
+		names := make([]string, 0, len(s.carriers))
+		for name := range s.carriers {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil, "", nil
+		}
+		sort.Strings(names)
+		carrier = s.carriers[names[0]]
+	}
+	label, err := carrier.ReturnLabel(ctx, shipping.LabelRequest{
+		Reference: reference,
+		From: shipping.Address{
+			Name:       address.Name,
+			Phone:      address.Phone,
+			Line1:      address.Line1,
+			Line2:      address.Line2,
+			City:       address.City,
+			PostalCode: address.PostalCode,
+			Country:    address.Country,
+		},
+		WeightGrams: weightGrams,
+	})
+	return label, carrier.Name(), err
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+	"e-commerce/shipping"
+)
+
+func TestMatchZone(t *testing.T) {
+	zones := []models.ShippingZone{
+		{ID: 1, Area: models.ShippingArea{Countries: []string{"TW"}}},
+		{ID: 2, Area: models.ShippingArea{Countries: []string{"TW"}, Cities: []string{"臺北市", "新北市"}}},
+		{ID: 3, Area: models.ShippingArea{Countries: []string{"TW"}, PostalCodes: []string{"880", "881", "882", "883", "884", "885"}}},
+	}
+	tests := []struct {
+		address models.Address
+		want    uint
+	}{
+		{models.Address{Country: "TW", City: "台中市", PostalCode: "400"}, 1},
+		{models.Address{Country: "tw", City: "台北市", PostalCode: "110"}, 2},
+		{models.Address{Country: "TW", City: "澎湖縣", PostalCode: "88043"}, 3},
+		{models.Address{Country: "JP", City: "東京都", PostalCode: "100-0001"}, 0},
+	}
+	for _, tt := range tests {
+		var got uint
+		if zone := matchZone(zones, tt.address); zone != nil {
+			got = zone.ID
+		}
+		if got != tt.want {
+			t.Errorf("matchZone(%s %s) = %d, want %d", tt.address.City, tt.address.PostalCode, got, tt.want)
+		}
+	}
+}
+
+func TestShippingRates(t *testing.T) {
+	carrier := shipping.NewFakeCarrier()
+	s := NewShippingService(repository.NewMockShippingRepository(), []shipping.Carrier{carrier})
+	address := models.Address{Country: "TW"}
+	parcel := shippingParcel{weightGrams: 2500, value: twd(1200)}
+	freeOver := twd(1000)
+
+	tests := []struct {
+		name   string
+		method models.ShippingMethod
+		parcel shippingParcel
+		want   int64
+		ok     bool
+	}{
+		{"flat", models.ShippingMethod{RateType: models.ShippingRateFlat, Currency: "TWD", Amount: twd(100)}, parcel, 100, true},
+		{"weight", models.ShippingMethod{RateType: models.ShippingRateWeight, Currency: "TWD", Tiers: models.ShippingRateTiers{
+			{Min: 0, Amount: twd(80)}, {Min: 2000, Amount: twd(150)}, {Min: 5000, Amount: twd(250)},
+		}}, parcel, 150, true},
+		{"price", models.ShippingMethod{RateType: models.ShippingRatePrice, Currency: "TWD", Tiers: models.ShippingRateTiers{
+			{Min: 0, Amount: twd(120)}, {Min: twd(1000).Amount, Amount: twd(60)},
+		}}, parcel, 60, true},
+		{"below first tier", models.ShippingMethod{RateType: models.ShippingRateWeight, Currency: "TWD", Tiers: models.ShippingRateTiers{
+			{Min: 3000, Amount: twd(200)},
+		}}, parcel, 0, false},
+		{"free over", models.ShippingMethod{RateType: models.ShippingRateFlat, Currency: "TWD", Amount: twd(100), FreeOver: &freeOver}, parcel, 0, true},
+		{"free shipping promotion", models.ShippingMethod{RateType: models.ShippingRateFlat, Currency: "TWD", Amount: twd(100)},
+			shippingParcel{value: twd(500), freeShipping: true}, 0, true},
+		{"carrier", models.ShippingMethod{RateType: models.ShippingRateCarrier, Carrier: "fake", CarrierService: shipping.FakeServiceExpress}, parcel, 210, true},
+		{"other currency", models.ShippingMethod{RateType: models.ShippingRateFlat, Currency: "USD", Amount: money.New(500, "USD")}, parcel, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.method.Active = true
+			quote, ok := s.rate(context.Background(), tt.method, tt.parcel, address)
+			if ok != tt.ok {
+				t.Fatalf("rate() available = %v, want %v", ok, tt.ok)
+			}
+			if ok && quote.Amount != twd(tt.want) {
+				t.Errorf("rate() = %v, want %v", quote.Amount, twd(tt.want))
+			}
+		})
+	}
+
+	// 物流商無法報價時不提供該方式
+	carrier.SetDown(true)
+	method := models.ShippingMethod{Active: true, RateType: models.ShippingRateCarrier, Carrier: "fake", CarrierService: shipping.FakeServiceStandard}
+	if _, ok := s.rate(context.Background(), method, parcel, address); ok {
+		t.Error("rate() with the carrier down is available")
+	}
+}
+
+func TestCheckoutChargesShipping(t *testing.T) {
+	tc := newTestCheckout(t)
+	shippingService := NewShippingService(repository.NewMockShippingRepository(), nil)
+	tc.checkout.shippingService = shippingService
+
+	taiwan, _ := shippingService.CreateZone(ShippingZoneDetails{Name: "Taiwan", Countries: []string{"TW"}})
+	home, err := shippingService.CreateMethod(taiwan.ID, ShippingMethodDetails{Name: "Home delivery", Active: true, Currency: "TWD", RateType: models.ShippingRateFlat, Amount: "100", FreeOver: "2000"})
+	if err != nil {
+		t.Fatalf("CreateMethod() error = %v", err)
+	}
+	japan, _ := shippingService.CreateZone(ShippingZoneDetails{Name: "Japan", Countries: []string{"JP"}})
+	abroad, _ := shippingService.CreateMethod(japan.ID, ShippingMethodDetails{Name: "EMS", Active: true, Currency: "TWD", RateType: models.ShippingRateFlat, Amount: "500"})
+	tc.fillCart(t)
+
+	details := CheckoutDetails{ShippingAddress: testAddress()}
+	if _, _, err := tc.checkout.Checkout(tc.user, "key-1", details, tc.priceList); !errors.Is(err, ErrShippingMethodRequired) {
+		t.Errorf("Checkout() without a method error = %v, want %v", err, ErrShippingMethodRequired)
+	}
+	details.ShippingMethodID = abroad.ID
+	if _, _, err := tc.checkout.Checkout(tc.user, "key-2", details, tc.priceList); !errors.Is(err, ErrShippingUnavailable) {
+		t.Errorf("Checkout() with a method of another zone error = %v, want %v", err, ErrShippingUnavailable)
+	}
+
+	details.ShippingMethodID = home.ID
+	order, _, err := tc.checkout.Checkout(tc.user, "key-3", details, tc.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if order.ShippingTotal != twd(100) || order.ShippingMethod != "Home delivery" || order.Total != twd(1300) {
+		t.Errorf("shipping = %v by %q, total = %v, want NT$100 home delivery", order.ShippingTotal, order.ShippingMethod, order.Total)
+	}
+	// 運費也含 5% 營業稅：1300 × 5/105 = 61.9
+	if order.TaxTotal != twd(62) {
+		t.Errorf("TaxTotal = %v, want tax on goods and shipping", order.TaxTotal)
+	}
+}
+
+func TestValidateShippingMethod(t *testing.T) {
+	s := NewShippingService(repository.NewMockShippingRepository(), nil)
+	zone, _ := s.CreateZone(ShippingZoneDetails{Name: "Taiwan", Countries: []string{"TW"}})
+	tests := []ShippingMethodDetails{
+		{Name: "", Currency: "TWD", RateType: models.ShippingRateFlat, Amount: "100"},
+		{Name: "No currency", RateType: models.ShippingRateFlat, Amount: "100"},
+		{Name: "Negative", Currency: "TWD", RateType: models.ShippingRateFlat, Amount: "-1"},
+		{Name: "No tiers", Currency: "TWD", RateType: models.ShippingRateWeight},
+		{Name: "Half a gram", Currency: "TWD", RateType: models.ShippingRateWeight, Tiers: []ShippingTierDetails{{Min: "0.5", Amount: "80"}}},
+		{Name: "Unknown carrier", RateType: models.ShippingRateCarrier, Carrier: "pigeon", CarrierService: "fast"},
+	}
+	for _, details := range tests {
+		if _, err := s.CreateMethod(zone.ID, details); !errors.Is(err, ErrInvalidShippingMethod) {
+			t.Errorf("CreateMethod(%q) error = %v, want %v", details.Name, err, ErrInvalidShippingMethod)
+		}
+	}
+	if _, err := s.CreateZone(ShippingZoneDetails{Name: "Nowhere"}); !errors.Is(err, ErrInvalidShippingZone) {
+		t.Errorf("CreateZone() without countries error = %v, want %v", err, ErrInvalidShippingZone)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+// stockAlertTimeout bounds the checks and emails for one product
+const stockAlertTimeout = 30 * time.Second
+
+var (
+	ErrInvalidThreshold = errors.New("threshold cannot be negative")
+	ErrProductInStock   = errors.New("product is in stock")
+)
+
+// StockAlertService raises low-stock alerts for staff and tells subscribed
+// customers when a product is back in stock. Stock changes are checked by
+// Run in the background so requests never wait for email.
+type StockAlertService struct {
+	alertRepo       repository.StockAlertRepository
+	inventoryRepo   repository.InventoryRepository
+	productRepo     repository.ProductRepository
+	userRepo        repository.UserRepository
+	mailer          mailer.Mailer
+	staffRecipients []string
+
+	mu      sync.Mutex
+	pending map[uint]bool
+	wake    chan struct{}
+}
+
+func NewStockAlertService(alertRepo repository.StockAlertRepository, inventoryRepo repository.InventoryRepository, productRepo repository.ProductRepository, userRepo repository.UserRepository, m mailer.Mailer) *StockAlertService {
+	return &StockAlertService{
+		alertRepo:       alertRepo,
+		inventoryRepo:   inventoryRepo,
+		productRepo:     productRepo,
+		userRepo:        userRepo,
+		mailer:          m,
+		staffRecipients: mailer.Recipients(os.Getenv("STOCK_ALERT_RECIPIENTS")),
+		pending:         make(map[uint]bool),
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// StockChanged implements StockObserver by queueing a check of the product
+func (s *StockAlertService) StockChanged(productID uint) {
+	s.mu.Lock()
+	s.pending[productID] = true
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run checks the products whose stock changed until ctx is cancelled
+func (s *StockAlertService) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+			s.checkPending(ctx)
+		}
+	}
+}
+
+// checkPending checks every queued product once, however often its stock
+// changed since the last run
+func (s *StockAlertService) checkPending(ctx context.Context) {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[uint]bool)
+	s.mu.Unlock()
+
+	for productID := range pending {
+		checkCtx, cancel := context.WithTimeout(ctx, stockAlertTimeout)
+		if err := s.check(checkCtx, productID); err != nil {
+			log.Printf("stock alerts: product %d: %v", productID, err)
+		}
+		cancel()
+	}
+}
+
+func (s *StockAlertService) available(productID uint) (int, error) {
+	levels, err := s.inventoryRepo.FindStockLevels(productID)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, level := range levels {
+		total += level.Available()
+	}
+	return total, nil
+}
+
+func (s *StockAlertService) check(ctx context.Context, productID uint) error {
+	available, err := s.available(productID)
+	if err != nil {
+		return err
+	}
+
+	if threshold, err := s.alertRepo.FindThreshold(productID); err == nil {
+		if available < threshold.Threshold {
+			alert := &models.StockAlert{ProductID: productID, Threshold: threshold.Threshold, Available: available}
+			created, err := s.alertRepo.OpenAlert(alert)
+			if err != nil {
+				return err
+			}
+			if created {
+				s.notifyStaff(ctx, alert)
+			}
+		} else if err := s.alertRepo.ResolveAlerts(productID, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if available > 0 {
+		return s.notifySubscribers(ctx, productID)
+	}
+	return nil
+}
+
+func (s *StockAlertService) notifyStaff(ctx context.Context, alert *models.StockAlert) {
+	if len(s.staffRecipients) == 0 {
+		return
+	}
+	product, err := s.productRepo.FindByID(alert.ProductID)
+	if err != nil {
+		return
+	}
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      s.staffRecipients,
+		Subject: fmt.Sprintf("Low stock: %s (%s)", product.Name, product.SKU),
+		Body: fmt.Sprintf("%s (%s) has %d units available, below the reorder threshold of %d.",
+			product.Name, product.SKU, alert.Available, alert.Threshold),
+	})
+	if err != nil {
+		log.Printf("stock alerts: failed to email staff about product %d: %v", alert.ProductID, err)
+	}
+}
+
+func (s *StockAlertService) notifySubscribers(ctx context.Context, productID uint) error {
+	subscriptions, err := s.alertRepo.ClaimSubscriptions(productID, time.Now())
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		user, err := s.userRepo.FindByID(subscription.UserID)
+		if err != nil {
+			continue
+		}
+		err = s.mailer.Send(ctx, mailer.Message{
+			To:      []string{user.Email},
+			Subject: fmt.Sprintf("%s is back in stock", product.Name),
+			Body:    fmt.Sprintf("Hi %s,\n\n%s is available again. Order soon, stock is limited.", user.Name, product.Name),
+		})
+		if err != nil {
+			// 寄送失敗時重新登記，下次補貨再通知
+			log.Printf("stock alerts: failed to email user %d about product %d: %v", user.ID, productID, err)
+			s.alertRepo.Subscribe(&models.BackInStockSubscription{ProductID: productID, UserID: user.ID})
+		}
+	}
+	return nil
+}
+
+// SetThreshold sets the reorder threshold of a product and queues a check
+// against the current stock
+func (s *StockAlertService) SetThreshold(productID uint, threshold int) (*models.ReorderThreshold, error) {
+	if threshold < 0 {
+		return nil, ErrInvalidThreshold
+	}
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	reorder := &models.ReorderThreshold{ProductID: productID, Threshold: threshold}
+	if err := s.alertRepo.SetThreshold(reorder); err != nil {
+		return nil, errors.New("failed to save threshold")
+	}
+	s.StockChanged(productID)
+	return reorder, nil
+}
+
+func (s *StockAlertService) DeleteThreshold(productID uint) error {
+	if err := s.alertRepo.DeleteThreshold(productID); err != nil {
+		return err
+	}
+	return s.alertRepo.ResolveAlerts(productID, time.Now())
+}
+
+func (s *StockAlertService) Alerts(openOnly bool, page, pageSize int) ([]models.StockAlert, error) {
+	offset, limit := paginate(page, pageSize)
+	return s.alertRepo.FindAlerts(openOnly, offset, limit)
+}
+
+// Subscribe asks to be emailed when an out-of-stock product is available
+func (s *StockAlertService) Subscribe(userID, productID uint) (*models.BackInStockSubscription, error) {
+	if product, err := s.productRepo.FindByID(productID); err != nil || !product.IsVisible(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+	available, err := s.available(productID)
+	if err != nil {
+		return nil, err
+	}
+	if available > 0 {
+		return nil, ErrProductInStock
+	}
+
+	subscription := &models.BackInStockSubscription{ProductID: productID, UserID: userID}
+	if err := s.alertRepo.Subscribe(subscription); err != nil {
+		return nil, errors.New("failed to subscribe")
+	}
+	return subscription, nil
+}
+
+func (s *StockAlertService) Unsubscribe(userID, productID uint) error {
+	return s.alertRepo.Unsubscribe(productID, userID)
+}
+
+func (s *StockAlertService) Subscriptions(userID uint) ([]models.BackInStockSubscription, error) {
+	return s.alertRepo.FindSubscriptionsByUser(userID)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+func newTestStockAlertService(t *testing.T) (*StockAlertService, *InventoryService, *mailer.MemoryMailer) {
+	t.Helper()
+	t.Setenv("STOCK_ALERT_RECIPIENTS", "ops@example.com")
+
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee", Status: models.ProductStatusPublished})
+	userRepo := repository.NewMockUserRepository()
+	userRepo.Create(&models.User{ID: 7, Name: "Amy", Email: "amy@example.com"})
+	inventoryRepo := repository.NewMockInventoryRepository()
+	mail := mailer.NewMemoryMailer()
+
+	alerts := NewStockAlertService(repository.NewMockStockAlertRepository(), inventoryRepo, productRepo, userRepo, mail)
+	inventory := NewInventoryService(inventoryRepo, productRepo, alerts)
+	if _, err := inventory.CreateWarehouse("TPE", "Taipei"); err != nil {
+		t.Fatalf("CreateWarehouse() error = %v", err)
+	}
+	return alerts, inventory, mail
+}
+
+// settle runs the stock checks queued so far, as Run would
+func settle(alerts *StockAlertService) {
+	alerts.checkPending(context.Background())
+}
+
+func sentTo(mail *mailer.MemoryMailer, addr string) int {
+	n := 0
+	for _, msg := range mail.Sent() {
+		for _, to := range msg.To {
+			if to == addr {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestLowStockAlertIsDeduplicated(t *testing.T) {
+	alerts, inventory, mail := newTestStockAlertService(t)
+	receive(t, inventory, 1, 1, 10)
+	if _, err := alerts.SetThreshold(1, 5); err != nil {
+		t.Fatalf("SetThreshold() error = %v", err)
+	}
+
+	settle(alerts)
+
+	// 庫存異動只排入檢查，不在請求中寄信
+	inventory.Reserve("checkout-1", []repository.ReservationItem{{ProductID: 1, Quantity: 6}})
+	if n := sentTo(mail, "ops@example.com"); n != 0 {
+		t.Fatalf("staff received %d alerts before the check ran, want 0", n)
+	}
+	settle(alerts)
+	inventory.Reserve("checkout-2", []repository.ReservationItem{{ProductID: 1, Quantity: 1}})
+	settle(alerts)
+	if n := sentTo(mail, "ops@example.com"); n != 1 {
+		t.Fatalf("staff received %d alerts, want 1", n)
+	}
+	open, _ := alerts.Alerts(true, 1, 20)
+	if len(open) != 1 || open[0].Available != 4 {
+		t.Fatalf("Alerts() = %+v, want one open alert with 4 available", open)
+	}
+
+	// 補貨後警示解除，再次低於門檻時重新通知
+	receive(t, inventory, 1, 1, 10)
+	settle(alerts)
+	if open, _ := alerts.Alerts(true, 1, 20); len(open) != 0 {
+		t.Errorf("Alerts() = %+v after restock, want none open", open)
+	}
+	inventory.Reserve("checkout-3", []repository.ReservationItem{{ProductID: 1, Quantity: 12}})
+	settle(alerts)
+	if n := sentTo(mail, "ops@example.com"); n != 2 {
+		t.Errorf("staff received %d alerts, want 2", n)
+	}
+}
+
+func TestBackInStockNotification(t *testing.T) {
+	alerts, inventory, mail := newTestStockAlertService(t)
+
+	if _, err := alerts.Subscribe(7, 1); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	receive(t, inventory, 1, 1, 3)
+	settle(alerts)
+	receive(t, inventory, 1, 1, 3)
+	settle(alerts)
+
+	if n := sentTo(mail, "amy@example.com"); n != 1 {
+		t.Fatalf("customer received %d emails, want 1", n)
+	}
+	if msg := mail.Sent()[0]; !strings.Contains(msg.Subject, "Coffee") {
+		t.Errorf("subject = %q, want product name", msg.Subject)
+	}
+
+	if _, err := alerts.Subscribe(7, 1); !errors.Is(err, ErrProductInStock) {
+		t.Errorf("Subscribe() error = %v, want %v", err, ErrProductInStock)
+	}
+	if _, err := alerts.Subscribe(7, 99); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Subscribe() error = %v, want %v", err, ErrProductNotFound)
+	}
+}
+
+func TestResubscribeAfterNotification(t *testing.T) {
+	alerts, inventory, mail := newTestStockAlertService(t)
+
+	alerts.Subscribe(7, 1)
+	receive(t, inventory, 1, 1, 1)
+	settle(alerts)
+	inventory.Reserve("checkout-1", []repository.ReservationItem{{ProductID: 1, Quantity: 1}})
+	inventory.Commit("checkout-1")
+	settle(alerts)
+
+	if _, err := alerts.Subscribe(7, 1); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	receive(t, inventory, 1, 1, 1)
+	settle(alerts)
+	if n := sentTo(mail, "amy@example.com"); n != 2 {
+		t.Errorf("customer received %d emails, want 2", n)
+	}
+	subscriptions, _ := alerts.Subscriptions(7)
+	if len(subscriptions) != 1 {
+		t.Errorf("Subscriptions() = %d, want 1", len(subscriptions))
+	}
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"e-commerce/mailer"
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+const (
+	// subscriptionSweepInterval is how often due renewals and retries run
+	subscriptionSweepInterval = time.Minute
+	subscriptionSweepBatch    = 50
+	maxSubscriptionInterval   = 12
+	// renewalClaimTimeout is how long a renewal may stay claimed before the
+	// sweeper assumes the worker died and renews it again. The retry reuses
+	// the idempotency key, so it gets back the order already placed.
+	renewalClaimTimeout = 10 * time.Minute
+)
+
+// dunningSchedule is how long to wait before each retry of a failed
+// renewal. The subscription is cancelled when the last retry fails too.
+var dunningSchedule = []time.Duration{24 * time.Hour, 3 * 24 * time.Hour, 5 * 24 * time.Hour}
+
+var (
+	ErrPlanNotFound         = errors.New("subscription plan not found")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrInvalidSubscription  = errors.New("invalid subscription")
+	ErrSubscriptionStatus   = errors.New("subscription cannot be changed in its current status")
+	ErrSubscriptionChanged  = repository.ErrSubscriptionChanged
+)
+
+// PlanDetails is what staff enter for a subscription plan
+type PlanDetails struct {
+	Name            string
+	ProductID       uint
+	Quantity        int
+	Interval        string
+	IntervalCount   int
+	DiscountPercent int
+	Active          bool
+}
+
+// SubscriptionDetails is what a customer enters to subscribe. Quantity and
+// frequency default to the plan's. The shipping address is entered inline
+// or picked from the address book, as at checkout.
+type SubscriptionDetails struct {
+	PlanID            uint
+	Quantity          int
+	Interval          string
+	IntervalCount     int
+	PaymentMethodID   uint
+	ShippingAddress   models.Address
+	ShippingAddressID uint
+	ShippingMethodID  uint
+}
+
+// SubscriptionChange is what a customer can change on a subscription
+type SubscriptionChange struct {
+	Quantity        int
+	Interval        string
+	IntervalCount   int
+	PaymentMethodID uint
+}
+
+// SubscriptionService manages subscription plans and customers'
+// subscriptions, and renews them on schedule
+type SubscriptionService struct {
+	subscriptionRepo repository.SubscriptionRepository
+	productRepo      repository.ProductRepository
+	userRepo         repository.UserRepository
+	pricingService   *PricingService
+	checkoutService  *CheckoutService
+	orderService     *OrderService
+	paymentService   *PaymentService
+	mailer           mailer.Mailer
+	now              func() time.Time
+}
+
+func NewSubscriptionService(subscriptionRepo repository.SubscriptionRepository, productRepo repository.ProductRepository, userRepo repository.UserRepository, pricingService *PricingService, checkoutService *CheckoutService, orderService *OrderService, paymentService *PaymentService, m mailer.Mailer) *SubscriptionService {
+	return &SubscriptionService{
+		subscriptionRepo: subscriptionRepo,
+		productRepo:      productRepo,
+		userRepo:         userRepo,
+		pricingService:   pricingService,
+		checkoutService:  checkoutService,
+		orderService:     orderService,
+		paymentService:   paymentService,
+		mailer:           m,
+		now:              time.Now,
+	}
+}
+
+func validateFrequency(interval string, count int) error {
+	if interval != models.SubscriptionIntervalWeek && interval != models.SubscriptionIntervalMonth {
+		return fmt.Errorf("%w: interval must be week or month", ErrInvalidSubscription)
+	}
+	if count < 1 || count > maxSubscriptionInterval {
+		return fmt.Errorf("%w: interval count must be between 1 and %d", ErrInvalidSubscription, maxSubscriptionInterval)
+	}
+	return nil
+}
+
+func validateSubscriptionQuantity(quantity int) error {
+	if quantity < 1 || quantity > maxCartLineQuantity {
+		return fmt.Errorf("%w: quantity must be between 1 and %d", ErrInvalidSubscription, maxCartLineQuantity)
+	}
+	return nil
+}
+
+// advance returns the renewal after t for a frequency
+func advance(t time.Time, interval string, count int) time.Time {
+	if interval == models.SubscriptionIntervalWeek {
+		return t.AddDate(0, 0, 7*count)
+	}
+	return t.AddDate(0, count, 0)
+}
+
+// nextAfter returns the first renewal on the schedule from t that is after now
+func nextAfter(t, now time.Time, interval string, count int) time.Time {
+	for !t.After(now) {
+		t = advance(t, interval, count)
+	}
+	return t
+}
+
+func (s *SubscriptionService) planFromDetails(plan *models.SubscriptionPlan, details PlanDetails) error {
+	details.Name = strings.TrimSpace(details.Name)
+	if details.Name == "" || len(details.Name) > 100 {
+		return fmt.Errorf("%w: name is required", ErrInvalidSubscription)
+	}
+	if err := validateFrequency(details.Interval, details.IntervalCount); err != nil {
+		return err
+	}
+	if err := validateSubscriptionQuantity(details.Quantity); err != nil {
+		return err
+	}
+	if details.DiscountPercent < 0 || details.DiscountPercent > 100 {
+		return fmt.Errorf("%w: discount must be between 0 and 100 percent", ErrInvalidSubscription)
+	}
+	if _, err := s.productRepo.FindByID(details.ProductID); err != nil {
+		return ErrProductNotFound
+	}
+	plan.Name = details.Name
+	plan.ProductID = details.ProductID
+	plan.Quantity = details.Quantity
+	plan.Interval = details.Interval
+	plan.IntervalCount = details.IntervalCount
+	plan.DiscountPercent = details.DiscountPercent
+	plan.Active = details.Active
+	return nil
+}
+
+// CreatePlan adds a subscription plan
+func (s *SubscriptionService) CreatePlan(details PlanDetails) (*models.SubscriptionPlan, error) {
+	plan := &models.SubscriptionPlan{}
+	if err := s.planFromDetails(plan, details); err != nil {
+		return nil, err
+	}
+	if err := s.subscriptionRepo.CreatePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// UpdatePlan changes a plan for new subscribers. Existing subscriptions
+// keep the terms they subscribed with.
+func (s *SubscriptionService) UpdatePlan(id uint, details PlanDetails) (*models.SubscriptionPlan, error) {
+	plan, err := s.subscriptionRepo.FindPlan(id)
+	if err != nil {
+		return nil, ErrPlanNotFound
+	}
+	if err := s.planFromDetails(plan, details); err != nil {
+		return nil, err
+	}
+	if err := s.subscriptionRepo.UpdatePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Plans returns the plans customers can subscribe to, or all plans for staff
+func (s *SubscriptionService) Plans(all bool) ([]models.SubscriptionPlan, error) {
+	return s.subscriptionRepo.FindPlans(!all)
+}
+
+// Subscribe starts a subscription and places its first order right away,
+// charged to the saved payment method. When the first order cannot be
+// placed or paid the subscription is cancelled and the error returned.
+func (s *SubscriptionService) Subscribe(ctx context.Context, user models.User, details SubscriptionDetails, priceList *models.PriceList) (*models.Subscription, error) {
+	plan, err := s.subscriptionRepo.FindPlan(details.PlanID)
+	if err != nil || !plan.Active {
+		return nil, ErrPlanNotFound
+	}
+	if details.Quantity == 0 {
+		details.Quantity = plan.Quantity
+	}
+	if details.Interval == "" {
+		details.Interval, details.IntervalCount = plan.Interval, plan.IntervalCount
+	}
+	if err := validateSubscriptionQuantity(details.Quantity); err != nil {
+		return nil, err
+	}
+	if err := validateFrequency(details.Interval, details.IntervalCount); err != nil {
+		return nil, err
+	}
+	if _, err := s.paymentService.Method(user.ID, details.PaymentMethodID); err != nil {
+		return nil, err
+	}
+	checkout := CheckoutDetails{ShippingAddress: details.ShippingAddress, ShippingAddressID: details.ShippingAddressID}
+	if err := s.checkoutService.resolveAddresses(user.ID, &checkout); err != nil {
+		return nil, err
+	}
+
+	// 第一期付款完成前不讓續訂排程接手
+	sub := &models.Subscription{
+		UserID:           user.ID,
+		PlanID:           plan.ID,
+		PlanName:         plan.Name,
+		ProductID:        plan.ProductID,
+		Quantity:         details.Quantity,
+		Interval:         details.Interval,
+		IntervalCount:    details.IntervalCount,
+		DiscountPercent:  plan.DiscountPercent,
+		Status:           models.SubscriptionStatusRenewing,
+		PriceListID:      priceList.ID,
+		PaymentMethodID:  details.PaymentMethodID,
+		ShippingAddress:  checkout.ShippingAddress,
+		ShippingMethodID: details.ShippingMethodID,
+		NextRenewalAt:    s.now(),
+	}
+	if err := s.subscriptionRepo.Create(sub); err != nil {
+		return nil, err
+	}
+
+	order, err := s.placeRenewal(ctx, sub)
+	if err != nil {
+		// 顧客就在現場，第一期失敗不進入催繳
+		now := s.now()
+		sub.Status = models.SubscriptionStatusCancelled
+		sub.CancelledAt = &now
+		sub.CancelReason = "First order failed"
+		sub.LastFailure = err.Error()
+		if updateErr := s.subscriptionRepo.Update(sub); updateErr != nil {
+			log.Printf("subscriptions: cancelling subscription %d: %v", sub.ID, updateErr)
+		}
+		return nil, err
+	}
+	s.renewed(sub, order)
+	if err := s.subscriptionRepo.Update(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// List returns the subscriptions of a user
+func (s *SubscriptionService) List(userID uint) ([]models.Subscription, error) {
+	return s.subscriptionRepo.FindByUserID(userID)
+}
+
+// Get returns a subscription of a user, hiding other users' subscriptions
+// as not found
+func (s *SubscriptionService) Get(userID, id uint) (*models.Subscription, error) {
+	sub, err := s.subscriptionRepo.FindByID(id)
+	if err != nil || sub.UserID != userID {
+		return nil, ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// ListAll returns every subscription for staff, optionally filtered by status
+func (s *SubscriptionService) ListAll(status string, page, pageSize int) ([]models.Subscription, error) {
+	switch status {
+	case "", models.SubscriptionStatusActive, models.SubscriptionStatusPaused,
+		models.SubscriptionStatusPastDue, models.SubscriptionStatusRenewing, models.SubscriptionStatusCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidSubscription, status)
+	}
+	offset, limit := paginate(page, pageSize)
+	return s.subscriptionRepo.FindByStatus(status, offset, limit)
+}
+
+// update applies change to a subscription of a user in one of statuses. A
+// renewal claimed after the subscription was read fails the update with
+// ErrSubscriptionChanged.
+func (s *SubscriptionService) update(userID, id uint, statuses []string, change func(sub *models.Subscription) error) (*models.Subscription, error) {
+	sub, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	allowed := false
+	for _, status := range statuses {
+		allowed = allowed || sub.Status == status
+	}
+	if !allowed {
+		return nil, ErrSubscriptionStatus
+	}
+	if err := change(sub); err != nil {
+		return nil, err
+	}
+	if err := s.subscriptionRepo.Update(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Skip skips the next delivery of an active subscription
+func (s *SubscriptionService) Skip(userID, id uint) (*models.Subscription, error) {
+	return s.update(userID, id, []string{models.SubscriptionStatusActive}, func(sub *models.Subscription) error {
+		sub.NextRenewalAt = advance(sub.NextRenewalAt, sub.Interval, sub.IntervalCount)
+		return nil
+	})
+}
+
+// Pause stops renewals until the subscription is resumed
+func (s *SubscriptionService) Pause(userID, id uint) (*models.Subscription, error) {
+	return s.update(userID, id, []string{models.SubscriptionStatusActive}, func(sub *models.Subscription) error {
+		sub.Status = models.SubscriptionStatusPaused
+		return nil
+	})
+}
+
+// Resume restarts a paused subscription. Renewals missed while paused are
+// not made up for; the next one is the first on the schedule from now.
+func (s *SubscriptionService) Resume(userID, id uint) (*models.Subscription, error) {
+	return s.update(userID, id, []string{models.SubscriptionStatusPaused}, func(sub *models.Subscription) error {
+		sub.Status = models.SubscriptionStatusActive
+		sub.NextRenewalAt = nextAfter(sub.NextRenewalAt, s.now(), sub.Interval, sub.IntervalCount)
+		return nil
+	})
+}
+
+// Change updates the quantity, frequency and payment method of a
+// subscription. A new frequency counts from the last renewal. A past due
+// subscription given a new payment method is retried right away.
+func (s *SubscriptionService) Change(userID, id uint, change SubscriptionChange) (*models.Subscription, error) {
+	if err := validateSubscriptionQuantity(change.Quantity); err != nil {
+		return nil, err
+	}
+	if err := validateFrequency(change.Interval, change.IntervalCount); err != nil {
+		return nil, err
+	}
+	if _, err := s.paymentService.Method(userID, change.PaymentMethodID); err != nil {
+		return nil, err
+	}
+	statuses := []string{models.SubscriptionStatusActive, models.SubscriptionStatusPaused, models.SubscriptionStatusPastDue}
+	return s.update(userID, id, statuses, func(sub *models.Subscription) error {
+		now := s.now()
+		if change.Interval != sub.Interval || change.IntervalCount != sub.IntervalCount {
+			sub.Interval, sub.IntervalCount = change.Interval, change.IntervalCount
+			if sub.LastRenewedAt != nil {
+				sub.NextRenewalAt = advance(*sub.LastRenewedAt, sub.Interval, sub.IntervalCount)
+				if sub.NextRenewalAt.Before(now) {
+					sub.NextRenewalAt = now
+				}
+			}
+		}
+		if sub.Status == models.SubscriptionStatusPastDue && change.PaymentMethodID != sub.PaymentMethodID {
+			sub.NextRetryAt = &now
+		}
+		sub.Quantity = change.Quantity
+		sub.PaymentMethodID = change.PaymentMethodID
+		return nil
+	})
+}
+
+// Cancel ends a subscription. Orders already placed are not affected.
+func (s *SubscriptionService) Cancel(userID, id uint, reason string) (*models.Subscription, error) {
+	statuses := []string{models.SubscriptionStatusActive, models.SubscriptionStatusPaused, models.SubscriptionStatusPastDue}
+	return s.update(userID, id, statuses, func(sub *models.Subscription) error {
+		now := s.now()
+		sub.Status = models.SubscriptionStatusCancelled
+		sub.CancelledAt = &now
+		sub.CancelReason = strings.TrimSpace(reason)
+		sub.NextRetryAt = nil
+		return nil
+	})
+}
+
+// placeRenewal places the order for the next renewal of a subscription and
+// charges it to the saved payment method. An order that cannot be paid is
+// cancelled so its stock is released; the next attempt places a new one.
+func (s *SubscriptionService) placeRenewal(ctx context.Context, sub *models.Subscription) (*models.Order, error) {
+	user, err := s.userRepo.FindByID(sub.UserID)
+	if err != nil {
+		return nil, err
+	}
+	priceList, err := s.pricingService.GetPriceList(sub.PriceListID)
+	if err != nil {
+		return nil, err
+	}
+	// 每次嘗試用不同的 key，重送同一次嘗試則拿回同一張訂單
+	key := fmt.Sprintf("subscription-%d-%d-%d", sub.ID, sub.Renewals+1, sub.FailedAttempts+1)
+	order, err := s.checkoutService.PlaceRenewal(*user, key, RenewalDetails{
+		CheckoutDetails: CheckoutDetails{
+			ShippingAddress:  sub.ShippingAddress,
+			ShippingMethodID: sub.ShippingMethodID,
+			Note:             "Subscription: " + sub.PlanName,
+		},
+		ProductID:       sub.ProductID,
+		Quantity:        sub.Quantity,
+		DiscountPercent: sub.DiscountPercent,
+		DiscountName:    fmt.Sprintf("Subscription %d%% off", sub.DiscountPercent),
+	}, priceList)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPendingPayment {
+		if order.Status == models.OrderStatusCancelled {
+			return nil, ErrOrderNotPayable
+		}
+		return order, nil
+	}
+
+	if _, err := s.paymentService.ChargeSaved(ctx, order.ID, sub.PaymentMethodID); err != nil {
+		if _, cancelErr := s.orderService.ChangeStatus(nil, order.ID, StatusChange{
+			To:     models.OrderStatusCancelled,
+			Reason: "Subscription renewal could not be paid",
+		}); cancelErr != nil {
+			log.Printf("subscriptions: cancelling unpaid renewal %s: %v", order.Number, cancelErr)
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// renewed records a paid renewal and schedules the next one
+func (s *SubscriptionService) renewed(sub *models.Subscription, order *models.Order) {
+	now := s.now()
+	sub.Status = models.SubscriptionStatusActive
+	sub.Renewals++
+	sub.LastOrderID = &order.ID
+	sub.LastRenewedAt = &now
+	sub.FailedAttempts = 0
+	sub.NextRetryAt = nil
+	sub.LastFailure = ""
+	sub.NextRenewalAt = nextAfter(sub.NextRenewalAt, now, sub.Interval, sub.IntervalCount)
+}
+
+// renew places a due renewal, or retries a failed one. The subscription is
+// claimed before anything is charged, so a customer cannot change or cancel
+// it halfway through. Failures are retried on the dunning schedule and the
+// customer is asked to check their payment method; after the last retry the
+// subscription is cancelled.
+func (s *SubscriptionService) renew(ctx context.Context, sub *models.Subscription) error {
+	sub.Status = models.SubscriptionStatusRenewing
+	if err := s.subscriptionRepo.Update(sub); err != nil {
+		return err
+	}
+	order, err := s.placeRenewal(ctx, sub)
+	if err == nil {
+		s.renewed(sub, order)
+		return s.subscriptionRepo.Update(sub)
+	}
+
+	now := s.now()
+	sub.FailedAttempts++
+	sub.LastFailure = err.Error()
+	if sub.FailedAttempts > len(dunningSchedule) {
+		sub.Status = models.SubscriptionStatusCancelled
+		sub.CancelledAt = &now
+		sub.CancelReason = fmt.Sprintf("Renewal failed %d times", sub.FailedAttempts)
+		sub.NextRetryAt = nil
+	} else {
+		retry := now.Add(dunningSchedule[sub.FailedAttempts-1])
+		sub.Status = models.SubscriptionStatusPastDue
+		sub.NextRetryAt = &retry
+	}
+	if err := s.subscriptionRepo.Update(sub); err != nil {
+		return err
+	}
+	s.notifyFailure(ctx, sub)
+	return nil
+}
+
+func (s *SubscriptionService) notifyFailure(ctx context.Context, sub *models.Subscription) {
+	user, err := s.userRepo.FindByID(sub.UserID)
+	if err != nil {
+		return
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nWe could not renew your subscription to %s: %s\n", sub.ShippingAddress.Name, sub.PlanName, sub.LastFailure)
+	subject := fmt.Sprintf("Your %s subscription could not be renewed", sub.PlanName)
+	if sub.Status == models.SubscriptionStatusCancelled {
+		subject = fmt.Sprintf("Your %s subscription has been cancelled", sub.PlanName)
+		body.WriteString("\nWe have cancelled the subscription. You are welcome to subscribe again at any time.\n")
+	} else {
+		fmt.Fprintf(&body, "\nWe will try again on %s. To avoid missing a delivery, please check your payment method.\n", sub.NextRetryAt.Format("2006-01-02"))
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: subject,
+		Body:    body.String(),
+	}); err != nil {
+		log.Printf("subscriptions: failed to notify %s: %v", user.Email, err)
+	}
+}
+
+// RenewDue renews the subscriptions that are due and retries failed
+// renewals whose retry is due
+func (s *SubscriptionService) RenewDue(ctx context.Context) (int, error) {
+	now := s.now()
+	subs, err := s.subscriptionRepo.FindDue(now, now.Add(-renewalClaimTimeout), subscriptionSweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	renewed := 0
+	for i := range subs {
+		err := s.renew(ctx, &subs[i])
+		if errors.Is(err, ErrSubscriptionChanged) {
+			// 顧客剛好在這時修改、暫停或取消，或另一個排程已接手
+			continue
+		}
+		if err != nil {
+			return renewed, err
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+// Run renews due subscriptions until ctx is cancelled
+func (s *SubscriptionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(subscriptionSweepInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RenewDue(ctx); err != nil {
+			log.Printf("subscriptions: failed to renew subscriptions: %v", err)
+		} else if n > 0 {
+			log.Printf("subscriptions: processed %d renewals", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/payments"
+	"e-commerce/repository"
+)
+
+type testSubscriptions struct {
+	*testCheckout
+	payments      *PaymentService
+	subscriptions *SubscriptionService
+	plan          *models.SubscriptionPlan
+}
+
+// newTestSubscriptions offers 2 bags of coffee every 2 weeks at 10% off
+func newTestSubscriptions(t *testing.T) *testSubscriptions {
+	t.Helper()
+	tc := newTestCheckout(t)
+	paymentService := NewPaymentService(repository.NewMockPaymentRepository(), tc.orders, payments.NewFakeProvider("secret"), nil)
+	subscriptions := NewSubscriptionService(repository.NewMockSubscriptionRepository(), tc.products, tc.users, tc.pricing, tc.checkout, tc.orders, paymentService, tc.mail)
+	plan, err := subscriptions.CreatePlan(PlanDetails{
+		Name: "Coffee club", ProductID: 1, Quantity: 2,
+		Interval: models.SubscriptionIntervalWeek, IntervalCount: 2, DiscountPercent: 10, Active: true,
+	})
+	if err != nil {
+		t.Fatalf("CreatePlan() error = %v", err)
+	}
+	ts := &testSubscriptions{testCheckout: tc, payments: paymentService, subscriptions: subscriptions, plan: plan}
+	ts.at(time.Now())
+	return ts
+}
+
+func (ts *testSubscriptions) saveCard(t *testing.T, card string) *models.PaymentMethod {
+	t.Helper()
+	method, err := ts.payments.SaveMethod(context.Background(), ts.user.ID, card)
+	if err != nil {
+		t.Fatalf("SaveMethod() error = %v", err)
+	}
+	return method
+}
+
+func (ts *testSubscriptions) subscribe(t *testing.T, card string) *models.Subscription {
+	t.Helper()
+	sub, err := ts.subscriptions.Subscribe(context.Background(), ts.user, SubscriptionDetails{
+		PlanID:          ts.plan.ID,
+		PaymentMethodID: ts.saveCard(t, card).ID,
+		ShippingAddress: testAddress(),
+	}, ts.priceList)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	return sub
+}
+
+// at moves the clock of the subscription scheduler
+func (ts *testSubscriptions) at(when time.Time) {
+	ts.subscriptions.now = func() time.Time { return when }
+}
+
+func TestSubscriptionPlanValidation(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	valid := PlanDetails{Name: "Tea club", ProductID: 2, Quantity: 1, Interval: models.SubscriptionIntervalMonth, IntervalCount: 1}
+
+	tests := []struct {
+		name    string
+		change  func(d *PlanDetails)
+		wantErr error
+	}{
+		{"valid", func(d *PlanDetails) {}, nil},
+		{"no name", func(d *PlanDetails) { d.Name = " " }, ErrInvalidSubscription},
+		{"daily", func(d *PlanDetails) { d.Interval = "day" }, ErrInvalidSubscription},
+		{"no interval count", func(d *PlanDetails) { d.IntervalCount = 0 }, ErrInvalidSubscription},
+		{"no quantity", func(d *PlanDetails) { d.Quantity = 0 }, ErrInvalidSubscription},
+		{"discount over 100%", func(d *PlanDetails) { d.DiscountPercent = 120 }, ErrInvalidSubscription},
+		{"unknown product", func(d *PlanDetails) { d.ProductID = 99 }, ErrProductNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := valid
+			tt.change(&details)
+			if _, err := ts.subscriptions.CreatePlan(details); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreatePlan() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if plans, _ := ts.subscriptions.Plans(false); len(plans) != 1 {
+		t.Errorf("Plans() = %d plans, want only the active one", len(plans))
+	}
+}
+
+func TestSubscribeAndRenew(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	ctx := context.Background()
+	ts.carts.AddItem(ts.user.ID, "", 2, 1, ts.priceList)
+
+	sub := ts.subscribe(t, payments.TestCardSuccess)
+	if sub.Status != models.SubscriptionStatusActive || sub.Renewals != 1 || sub.LastOrderID == nil {
+		t.Fatalf("subscription = %+v, want active with its first order", sub)
+	}
+	first, _ := ts.orders.Get(ts.user.ID, *sub.LastOrderID)
+	if first.Status != models.OrderStatusPaid || first.Items[0].Quantity != 2 || first.DiscountTotal != twd(90) {
+		t.Errorf("first order = %s, %d bags, %v off, want paid 2 bags with 10%% off", first.Status, first.Items[0].Quantity, first.DiscountTotal)
+	}
+	if due := sub.NextRenewalAt.Sub(*sub.LastRenewedAt); due != 14*24*time.Hour {
+		t.Errorf("next renewal in %v, want 2 weeks", due)
+	}
+
+	// 還沒到期不續訂
+	if n, err := ts.subscriptions.RenewDue(ctx); err != nil || n != 0 {
+		t.Errorf("RenewDue() before the renewal = %d, %v, want 0", n, err)
+	}
+	ts.at(sub.NextRenewalAt.Add(time.Minute))
+	if n, err := ts.subscriptions.RenewDue(ctx); err != nil || n != 1 {
+		t.Fatalf("RenewDue() = %d, %v, want 1", n, err)
+	}
+	renewed, _ := ts.subscriptions.Get(ts.user.ID, sub.ID)
+	if renewed.Renewals != 2 || *renewed.LastOrderID == *sub.LastOrderID || !renewed.NextRenewalAt.Equal(sub.NextRenewalAt.AddDate(0, 0, 14)) {
+		t.Errorf("renewed subscription = %+v, want a second order and the renewal after", renewed)
+	}
+	if cart, _ := ts.carts.Get(ts.user.ID, "", ts.priceList); len(cart.Items) != 1 {
+		t.Errorf("cart has %d lines, want the customer's cart untouched", len(cart.Items))
+	}
+}
+
+func TestSubscribeWithCardNeedingAuthentication(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	_, err := ts.subscriptions.Subscribe(context.Background(), ts.user, SubscriptionDetails{
+		PlanID:          ts.plan.ID,
+		PaymentMethodID: ts.saveCard(t, payments.TestCard3DS).ID,
+		ShippingAddress: testAddress(),
+	}, ts.priceList)
+	if !errors.Is(err, ErrPaymentDeclined) {
+		t.Fatalf("Subscribe() error = %v, want %v", err, ErrPaymentDeclined)
+	}
+	subs, _ := ts.subscriptions.List(ts.user.ID)
+	if len(subs) != 1 || subs[0].Status != models.SubscriptionStatusCancelled {
+		t.Errorf("subscriptions = %+v, want the failed one cancelled", subs)
+	}
+	if available, _ := ts.inventory.Available(1); available != 10 {
+		t.Errorf("Available() = %d, want the unpaid order's stock released", available)
+	}
+}
+
+func TestRenewalDunning(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	ctx := context.Background()
+	sub := ts.subscribe(t, payments.TestCardSuccess)
+	declined := ts.saveCard(t, payments.TestCardDeclined)
+	if _, err := ts.subscriptions.Change(ts.user.ID, sub.ID, SubscriptionChange{
+		Quantity: 2, Interval: sub.Interval, IntervalCount: sub.IntervalCount, PaymentMethodID: declined.ID,
+	}); err != nil {
+		t.Fatalf("Change() error = %v", err)
+	}
+
+	now := sub.NextRenewalAt.Add(time.Minute)
+	ts.at(now)
+	ts.subscriptions.RenewDue(ctx)
+	pastDue, _ := ts.subscriptions.Get(ts.user.ID, sub.ID)
+	if pastDue.Status != models.SubscriptionStatusPastDue || pastDue.FailedAttempts != 1 || !pastDue.NextRetryAt.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("subscription = %+v, want past due with a retry in a day", pastDue)
+	}
+	sent := ts.mail.Sent()
+	if last := sent[len(sent)-1]; !strings.Contains(last.Subject, "could not be renewed") {
+		t.Errorf("last email = %q, want the dunning notice", last.Subject)
+	}
+	if available, _ := ts.inventory.Available(1); available != 8 {
+		t.Errorf("Available() = %d, want the unpaid renewal's stock released", available)
+	}
+
+	// 每次重試失敗都延後，最後一次失敗就取消
+	for i := range dunningSchedule {
+		sub, _ := ts.subscriptions.Get(ts.user.ID, sub.ID)
+		now = sub.NextRetryAt.Add(time.Minute)
+		ts.at(now)
+		if n, err := ts.subscriptions.RenewDue(ctx); err != nil || n != 1 {
+			t.Fatalf("RenewDue() retry %d = %d, %v, want 1", i+1, n, err)
+		}
+	}
+	cancelled, _ := ts.subscriptions.Get(ts.user.ID, sub.ID)
+	if cancelled.Status != models.SubscriptionStatusCancelled || cancelled.FailedAttempts != len(dunningSchedule)+1 {
+		t.Errorf("subscription = %+v, want cancelled after the last retry", cancelled)
+	}
+}
+
+func TestNewPaymentMethodRetriesPastDueRenewal(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	ctx := context.Background()
+	sub := ts.subscribe(t, payments.TestCardSuccess)
+	change := SubscriptionChange{Quantity: 2, Interval: sub.Interval, IntervalCount: sub.IntervalCount}
+	change.PaymentMethodID = ts.saveCard(t, payments.TestCardInsufficientFunds).ID
+	ts.subscriptions.Change(ts.user.ID, sub.ID, change)
+	ts.at(sub.NextRenewalAt.Add(time.Minute))
+	ts.subscriptions.RenewDue(ctx)
+
+	change.PaymentMethodID = ts.saveCard(t, payments.TestCardSuccess).ID
+	updated, err := ts.subscriptions.Change(ts.user.ID, sub.ID, change)
+	if err != nil || updated.Status != models.SubscriptionStatusPastDue {
+		t.Fatalf("Change() = %+v, %v, want still past due", updated, err)
+	}
+	if n, err := ts.subscriptions.RenewDue(ctx); err != nil || n != 1 {
+		t.Fatalf("RenewDue() = %d, %v, want the retry right away", n, err)
+	}
+	recovered, _ := ts.subscriptions.Get(ts.user.ID, sub.ID)
+	if recovered.Status != models.SubscriptionStatusActive || recovered.Renewals != 2 || recovered.FailedAttempts != 0 || recovered.NextRetryAt != nil {
+		t.Errorf("subscription = %+v, want active again after the retry", recovered)
+	}
+}
+
+func TestSubscriptionSelfService(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	ctx := context.Background()
+	sub := ts.subscribe(t, payments.TestCardSuccess)
+	next := sub.NextRenewalAt
+
+	if _, err := ts.subscriptions.Skip(99, sub.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Skip() by another customer error = %v, want %v", err, ErrSubscriptionNotFound)
+	}
+	skipped, err := ts.subscriptions.Skip(ts.user.ID, sub.ID)
+	if err != nil || !skipped.NextRenewalAt.Equal(next.AddDate(0, 0, 14)) {
+		t.Fatalf("Skip() = %v, %v, want the next renewal 2 weeks later", skipped.NextRenewalAt, err)
+	}
+
+	monthly := SubscriptionChange{Quantity: 3, Interval: models.SubscriptionIntervalMonth, IntervalCount: 1, PaymentMethodID: sub.PaymentMethodID}
+	changed, err := ts.subscriptions.Change(ts.user.ID, sub.ID, monthly)
+	if err != nil || changed.Quantity != 3 || !changed.NextRenewalAt.Equal(sub.LastRenewedAt.AddDate(0, 1, 0)) {
+		t.Fatalf("Change() = %+v, %v, want 3 bags a month after the last renewal", changed, err)
+	}
+	monthly.IntervalCount = 13
+	if _, err := ts.subscriptions.Change(ts.user.ID, sub.ID, monthly); !errors.Is(err, ErrInvalidSubscription) {
+		t.Errorf("Change() every 13 months error = %v, want %v", err, ErrInvalidSubscription)
+	}
+
+	if _, err := ts.subscriptions.Pause(ts.user.ID, sub.ID); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if _, err := ts.subscriptions.Skip(ts.user.ID, sub.ID); !errors.Is(err, ErrSubscriptionStatus) {
+		t.Errorf("Skip() while paused error = %v, want %v", err, ErrSubscriptionStatus)
+	}
+	later := changed.NextRenewalAt.AddDate(0, 2, 1)
+	ts.at(later)
+	if n, _ := ts.subscriptions.RenewDue(ctx); n != 0 {
+		t.Errorf("RenewDue() while paused = %d, want 0", n)
+	}
+	resumed, err := ts.subscriptions.Resume(ts.user.ID, sub.ID)
+	if err != nil || resumed.Status != models.SubscriptionStatusActive || !resumed.NextRenewalAt.After(later) {
+		t.Fatalf("Resume() = %+v, %v, want active with the next renewal in the future", resumed, err)
+	}
+
+	cancelled, err := ts.subscriptions.Cancel(ts.user.ID, sub.ID, "Too much coffee")
+	if err != nil || cancelled.Status != models.SubscriptionStatusCancelled || cancelled.CancelReason != "Too much coffee" {
+		t.Fatalf("Cancel() = %+v, %v, want cancelled", cancelled, err)
+	}
+	if _, err := ts.subscriptions.Resume(ts.user.ID, sub.ID); !errors.Is(err, ErrSubscriptionStatus) {
+		t.Errorf("Resume() after cancelling error = %v, want %v", err, ErrSubscriptionStatus)
+	}
+	if list, _ := ts.subscriptions.ListAll(models.SubscriptionStatusCancelled, 1, 20); len(list) != 1 {
+		t.Errorf("ListAll(cancelled) = %d subscriptions, want 1", len(list))
+	}
+}
+
+func TestRenewalClaimsSubscription(t *testing.T) {
+	ts := newTestSubscriptions(t)
+	ctx := context.Background()
+	sub := ts.subscribe(t, payments.TestCardSuccess)
+
+	// 續訂排程先佔用訂閱，扣款期間顧客不能取消
+	claimed := *sub
+	claimed.Status = models.SubscriptionStatusRenewing
+	if err := ts.subscriptions.subscriptionRepo.Update(&claimed); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := ts.subscriptions.Cancel(ts.user.ID, sub.ID, ""); !errors.Is(err, ErrSubscriptionStatus) {
+		t.Errorf("Cancel() during a renewal error = %v, want %v", err, ErrSubscriptionStatus)
+	}
+	// 讀取後被改過的舊資料不能覆蓋
+	if err := ts.subscriptions.subscriptionRepo.Update(sub); !errors.Is(err, ErrSubscriptionChanged) {
+		t.Errorf("Update() of a stale copy error = %v, want %v", err, ErrSubscriptionChanged)
+	}
+
+	// 排程中斷後，逾時的續訂會被重新處理
+	ts.at(time.Now().Add(renewalClaimTimeout / 2))
+	if n, err := ts.subscriptions.RenewDue(ctx); err != nil || n != 0 {
+		t.Errorf("RenewDue() while the renewal is claimed = %d, %v, want 0", n, err)
+	}
+	ts.at(time.Now().Add(2 * renewalClaimTimeout))
+	if n, err := ts.subscriptions.RenewDue(ctx); err != nil || n != 1 {
+		t.Fatalf("RenewDue() after the claim timed out = %d, %v, want 1", n, err)
+	}
+	renewed, _ := ts.subscriptions.Get(ts.user.ID, sub.ID)
+	if renewed.Status != models.SubscriptionStatusActive || renewed.Renewals != 2 {
+		t.Errorf("subscription = %+v, want active after the second renewal", renewed)
+	}
+}
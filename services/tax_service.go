@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"e-commerce/models"
+	"e-commerce/tax"
+)
+
+// TaxService works out the taxes of orders with a tax provider
+type TaxService struct {
+	provider tax.Provider
+}
+
+func NewTaxService(provider tax.Provider) *TaxService {
+	return &TaxService{
+		provider: provider,
+	}
+}
+
+// ApplyToOrder charges the taxes of the jurisdiction an order ships to on
+// its discounted lines and shipping, which is taxed at the standard rate.
+// When the market of the price list displays prices with tax included, the
+// tax is taken out of the prices instead of added.
+func (s *TaxService) ApplyToOrder(ctx context.Context, order *models.Order, market string) error {
+	display, _ := tax.Lookup(market)
+	req := tax.Request{
+		Jurisdiction:     order.ShippingAddress.Country,
+		Currency:         order.Currency,
+		PricesIncludeTax: display.PricesIncludeTax,
+	}
+	for _, item := range order.Items {
+		req.Lines = append(req.Lines, tax.Line{
+			Reference: item.SKU,
+			TaxClass:  item.TaxClass,
+			Amount:    item.LineTotal.Sub(item.DiscountTotal),
+		})
+	}
+	if !order.ShippingTotal.IsZero() {
+		req.Lines = append(req.Lines, tax.Line{Reference: "shipping", TaxClass: tax.ClassStandard, Amount: order.ShippingTotal})
+	}
+
+	result, err := s.provider.Calculate(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%s tax provider: %w", s.provider.Name(), err)
+	}
+	if len(result.Lines) != len(req.Lines) {
+		return fmt.Errorf("%s tax provider returned %d lines for %d", s.provider.Name(), len(result.Lines), len(req.Lines))
+	}
+
+	for i := range order.Items {
+		order.Items[i].TaxTotal = result.Lines[i]
+	}
+	order.TaxInclusive = req.PricesIncludeTax
+	order.TaxTotal = result.Total
+	order.Taxes = nil
+	for _, b := range result.Breakdown {
+		order.Taxes = append(order.Taxes, models.OrderTax{
+			Jurisdiction: b.Jurisdiction,
+			TaxClass:     b.TaxClass,
+			Rate:         b.Rate,
+			Taxable:      b.Taxable,
+			Amount:       b.Amount,
+		})
+	}
+	return nil
+}
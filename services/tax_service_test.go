@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/tax"
+)
+
+func TestCheckoutIncludesTaxInPrices(t *testing.T) {
+	tc := newTestCheckout(t)
+	tc.fillCart(t)
+
+	order, _, err := tc.checkout.Checkout(tc.user, "key-1", CheckoutDetails{ShippingAddress: testAddress()}, tc.priceList)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	// 台灣售價已含 5% 營業稅，總額不變
+	if !order.TaxInclusive || order.TaxTotal != twd(57) || order.Total != twd(1200) {
+		t.Errorf("tax = %v (inclusive %v), total = %v, want NT$57 included in NT$1,200", order.TaxTotal, order.TaxInclusive, order.Total)
+	}
+	if order.Items[0].TaxTotal.Add(order.Items[1].TaxTotal) != order.TaxTotal {
+		t.Errorf("line taxes %v and %v do not add up to %v", order.Items[0].TaxTotal, order.Items[1].TaxTotal, order.TaxTotal)
+	}
+	if len(order.Taxes) != 1 || order.Taxes[0].Jurisdiction != "TW" || order.Taxes[0].Taxable != twd(1143) {
+		t.Errorf("Taxes = %+v, want one TW breakdown on NT$1,143", order.Taxes)
+	}
+}
+
+func TestTaxAddedOnDiscountedLines(t *testing.T) {
+	usd := func(cents int64) money.Money { return money.New(cents, "USD") }
+	order := &models.Order{
+		Currency:        "USD",
+		ShippingAddress: models.Address{Country: "CA"},
+		Items: []models.OrderItem{
+			{SKU: "SKU-1", TaxClass: tax.ClassStandard, LineTotal: usd(2000), DiscountTotal: usd(200)},
+			{SKU: "SKU-2", TaxClass: tax.ClassExempt, LineTotal: usd(1000), DiscountTotal: usd(0)},
+		},
+	}
+
+	if err := NewTaxService(tax.NewLocalProvider()).ApplyToOrder(context.Background(), order, "US"); err != nil {
+		t.Fatalf("ApplyToOrder() error = %v", err)
+	}
+	if order.TaxInclusive || order.TaxTotal != usd(90) {
+		t.Errorf("tax = %v (inclusive %v), want 5%% of $18.00 added", order.TaxTotal, order.TaxInclusive)
+	}
+	if order.Items[0].TaxTotal != usd(90) || !order.Items[1].TaxTotal.IsZero() {
+		t.Errorf("line taxes = %v, %v, want only the standard line taxed", order.Items[0].TaxTotal, order.Items[1].TaxTotal)
+	}
+	if len(order.Taxes) != 2 || order.Taxes[1].TaxClass != tax.ClassExempt {
+		t.Errorf("Taxes = %+v, want the exempt line reported too", order.Taxes)
+	}
+}
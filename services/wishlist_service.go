@@ -0,0 +1,303 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"e-commerce/models"
+	"e-commerce/money"
+	"e-commerce/repository"
+)
+
+// maxWishlistsPerUser keeps a runaway client from creating endless lists
+const maxWishlistsPerUser = 20
+
+var (
+	ErrWishlistNotFound     = errors.New("wishlist not found")
+	ErrWishlistNameTaken    = repository.ErrWishlistNameTaken
+	ErrWishlistLimit        = errors.New("wishlist limit reached")
+	ErrWishlistItemNotFound = errors.New("product is not on this wishlist")
+)
+
+// CartAdder puts products into a user's cart
+type CartAdder interface {
+	AddForUser(userID, productID uint, quantity int, priceList *models.PriceList) error
+}
+
+type WishlistService struct {
+	wishlistRepo  repository.WishlistRepository
+	productRepo   repository.ProductRepository
+	priceListRepo repository.PriceListRepository
+	cart          CartAdder
+}
+
+func NewWishlistService(wishlistRepo repository.WishlistRepository, productRepo repository.ProductRepository, priceListRepo repository.PriceListRepository, cart CartAdder) *WishlistService {
+	return &WishlistService{
+		wishlistRepo:  wishlistRepo,
+		productRepo:   productRepo,
+		priceListRepo: priceListRepo,
+		cart:          cart,
+	}
+}
+
+// owned loads a wishlist, hiding other users' lists as not found
+func (s *WishlistService) owned(userID, id uint) (*models.Wishlist, error) {
+	wishlist, err := s.wishlistRepo.FindByID(id)
+	if err != nil || wishlist.UserID != userID {
+		return nil, ErrWishlistNotFound
+	}
+	return wishlist, nil
+}
+
+// decorate attaches products, current prices and price drops to items.
+// Items of deleted products are dropped, as are hidden products when
+// visibleOnly is set.
+func (s *WishlistService) decorate(items []models.WishlistItem, visibleOnly bool) ([]models.WishlistItem, error) {
+	if len(items) == 0 {
+		return []models.WishlistItem{}, nil
+	}
+
+	ids := make([]uint, len(items))
+	byList := make(map[uint][]uint)
+	for i, item := range items {
+		ids[i] = item.ProductID
+		if item.PriceListID != 0 {
+			byList[item.PriceListID] = append(byList[item.PriceListID], item.ProductID)
+		}
+	}
+	products, err := s.productRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	type listProduct struct{ priceListID, productID uint }
+	current := make(map[listProduct]money.Money)
+	for priceListID, productIDs := range byList {
+		prices, err := s.priceListRepo.FindPrices(priceListID, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range prices {
+			current[listProduct{priceListID, p.ProductID}] = p.Price
+		}
+	}
+
+	decorated := []models.WishlistItem{}
+	for _, item := range items {
+		product, exists := byID[item.ProductID]
+		if !exists || (visibleOnly && !product.IsVisible(time.Now())) {
+			continue
+		}
+		item.Product = &product
+		if price, ok := current[listProduct{item.PriceListID, item.ProductID}]; ok {
+			item.CurrentPrice = &price
+			if price.Currency == item.AddedPrice.Currency && price.Cmp(item.AddedPrice) < 0 {
+				drop := item.AddedPrice.Sub(price)
+				item.PriceDrop = &drop
+			}
+		}
+		decorated = append(decorated, item)
+	}
+	return decorated, nil
+}
+
+func (s *WishlistService) List(userID uint) ([]models.Wishlist, error) {
+	wishlists, err := s.wishlistRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range wishlists {
+		if wishlists[i].Items, err = s.decorate(wishlists[i].Items, false); err != nil {
+			return nil, err
+		}
+	}
+	return wishlists, nil
+}
+
+func (s *WishlistService) Get(userID, id uint) (*models.Wishlist, error) {
+	wishlist, err := s.owned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist.Items, err = s.decorate(wishlist.Items, false); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (s *WishlistService) Create(userID uint, name string) (*models.Wishlist, error) {
+	count, err := s.wishlistRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWishlistsPerUser {
+		return nil, ErrWishlistLimit
+	}
+
+	wishlist := &models.Wishlist{
+		UserID: userID,
+		Name:   strings.TrimSpace(name),
+		Items:  []models.WishlistItem{},
+	}
+	if err := s.wishlistRepo.Create(wishlist); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (s *WishlistService) Rename(userID, id uint, name string) (*models.Wishlist, error) {
+	wishlist, err := s.owned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	wishlist.Name = strings.TrimSpace(name)
+	if err := s.wishlistRepo.Update(wishlist); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, id)
+}
+
+func (s *WishlistService) Delete(userID, id uint) error {
+	if _, err := s.owned(userID, id); err != nil {
+		return err
+	}
+	return s.wishlistRepo.Delete(id)
+}
+
+// AddItem saves a visible product on a wishlist, remembering its price in
+// priceList. Adding a product twice keeps the original entry.
+func (s *WishlistService) AddItem(userID, id, productID uint, priceList *models.PriceList) (*models.WishlistItem, error) {
+	if _, err := s.owned(userID, id); err != nil {
+		return nil, err
+	}
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil || !product.IsVisible(time.Now()) {
+		return nil, ErrProductNotFound
+	}
+
+	item := &models.WishlistItem{
+		WishlistID: id,
+		ProductID:  productID,
+	}
+	if priceList != nil {
+		if price, err := s.priceListRepo.FindPrice(priceList.ID, productID); err == nil {
+			item.PriceListID = priceList.ID
+			item.AddedPrice = price.Price
+		}
+	}
+	if err := s.wishlistRepo.AddItem(item); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.wishlistRepo.FindItem(id, productID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.decorate([]models.WishlistItem{*saved}, false)
+	if err != nil {
+		return nil, err
+	}
+	// 商品可能在加入後被刪除
+	if len(items) == 0 {
+		return nil, ErrProductNotFound
+	}
+	return &items[0], nil
+}
+
+func (s *WishlistService) RemoveItem(userID, id, productID uint) error {
+	if _, err := s.owned(userID, id); err != nil {
+		return err
+	}
+	if err := s.wishlistRepo.RemoveItem(id, productID); err != nil {
+		return ErrWishlistItemNotFound
+	}
+	return nil
+}
+
+// MoveItem moves a product from one of the user's wishlists to another
+func (s *WishlistService) MoveItem(userID, fromID, productID, toID uint) error {
+	if _, err := s.owned(userID, fromID); err != nil {
+		return err
+	}
+	if _, err := s.owned(userID, toID); err != nil {
+		return err
+	}
+	if _, err := s.wishlistRepo.FindItem(fromID, productID); err != nil {
+		return ErrWishlistItemNotFound
+	}
+	if fromID == toID {
+		return nil
+	}
+	return s.wishlistRepo.MoveItem(fromID, toID, productID)
+}
+
+// MoveToCart adds a wishlist item to the user's cart, priced with
+// priceList, and then removes it from the wishlist
+func (s *WishlistService) MoveToCart(userID, id, productID uint, quantity int, priceList *models.PriceList) error {
+	if quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if _, err := s.owned(userID, id); err != nil {
+		return err
+	}
+	if _, err := s.wishlistRepo.FindItem(id, productID); err != nil {
+		return ErrWishlistItemNotFound
+	}
+	if err := s.cart.AddForUser(userID, productID, quantity, priceList); err != nil {
+		return err
+	}
+	return s.wishlistRepo.RemoveItem(id, productID)
+}
+
+// Share returns the wishlist with a read-only share token, creating one on
+// first use
+func (s *WishlistService) Share(userID, id uint) (*models.Wishlist, error) {
+	wishlist, err := s.owned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist.ShareToken == nil {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		token := hex.EncodeToString(b)
+		wishlist.ShareToken = &token
+		if err := s.wishlistRepo.Update(wishlist); err != nil {
+			return nil, err
+		}
+	}
+	return s.Get(userID, id)
+}
+
+// Unshare revokes the share token so existing links stop working
+func (s *WishlistService) Unshare(userID, id uint) error {
+	wishlist, err := s.owned(userID, id)
+	if err != nil {
+		return err
+	}
+	wishlist.ShareToken = nil
+	return s.wishlistRepo.Update(wishlist)
+}
+
+// Shared returns a wishlist by share token, showing only visible products
+func (s *WishlistService) Shared(token string) (*models.Wishlist, error) {
+	if token == "" {
+		return nil, ErrWishlistNotFound
+	}
+	wishlist, err := s.wishlistRepo.FindByShareToken(token)
+	if err != nil {
+		return nil, ErrWishlistNotFound
+	}
+	if wishlist.Items, err = s.decorate(wishlist.Items, true); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
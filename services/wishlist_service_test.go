@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"e-commerce/models"
+	"e-commerce/repository"
+)
+
+type fakeCart struct {
+	added map[uint]int
+	err   error
+}
+
+func (c *fakeCart) AddForUser(userID, productID uint, quantity int, priceList *models.PriceList) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.added[productID] += quantity
+	return nil
+}
+
+func newTestWishlistService(t *testing.T, cart CartAdder) (*WishlistService, *PricingService, *models.PriceList, repository.ProductRepository) {
+	t.Helper()
+	productRepo := repository.NewMockProductRepository()
+	productRepo.Create(&models.Product{ID: 1, SKU: "SKU-1", Name: "Coffee", Status: models.ProductStatusPublished})
+	productRepo.Create(&models.Product{ID: 2, SKU: "SKU-2", Name: "Tea", Status: models.ProductStatusPublished})
+	productRepo.Create(&models.Product{ID: 3, SKU: "SKU-3", Name: "Draft", Status: models.ProductStatusDraft})
+
+	priceListRepo := repository.NewMockPriceListRepository()
+	pricingService := NewPricingService(priceListRepo, productRepo)
+	priceList, err := pricingService.CreatePriceList("TW-TWD", "Taiwan", "TWD", "TW", true, true)
+	if err != nil {
+		t.Fatalf("CreatePriceList() error = %v", err)
+	}
+	for id, amount := range map[uint]string{1: "450", 2: "300"} {
+		if _, err := pricingService.SetPrice(priceList.ID, id, amount); err != nil {
+			t.Fatalf("SetPrice() error = %v", err)
+		}
+	}
+
+	return NewWishlistService(repository.NewMockWishlistRepository(), productRepo, priceListRepo, cart), pricingService, priceList, productRepo
+}
+
+func TestWishlistOwnershipAndNames(t *testing.T) {
+	s, _, _, _ := newTestWishlistService(t, &fakeCart{added: make(map[uint]int)})
+	wishlist, err := s.Create(1, " Birthday ")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if wishlist.Name != "Birthday" {
+		t.Errorf("Name = %q, want trimmed", wishlist.Name)
+	}
+	if _, err := s.Create(1, "Birthday"); !errors.Is(err, ErrWishlistNameTaken) {
+		t.Errorf("Create() error = %v, want %v", err, ErrWishlistNameTaken)
+	}
+	if _, err := s.Create(2, "Birthday"); err != nil {
+		t.Errorf("Create() for another user error = %v", err)
+	}
+	if _, err := s.Get(2, wishlist.ID); !errors.Is(err, ErrWishlistNotFound) {
+		t.Errorf("Get() by another user error = %v, want %v", err, ErrWishlistNotFound)
+	}
+	if _, err := s.AddItem(1, wishlist.ID, 3, nil); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("AddItem() of a draft error = %v, want %v", err, ErrProductNotFound)
+	}
+}
+
+func TestWishlistPriceDrop(t *testing.T) {
+	s, pricingService, priceList, _ := newTestWishlistService(t, &fakeCart{added: make(map[uint]int)})
+	wishlist, _ := s.Create(1, "Later")
+
+	item, err := s.AddItem(1, wishlist.ID, 1, priceList)
+	if err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	if item.AddedPrice.Amount != 45000 || item.PriceDrop != nil {
+		t.Errorf("AddItem() = %+v, want added at 450 without a drop", item)
+	}
+
+	pricingService.SetPrice(priceList.ID, 1, "400")
+	// 重複加入不會覆蓋原本的價格
+	item, _ = s.AddItem(1, wishlist.ID, 1, priceList)
+	if item.AddedPrice.Amount != 45000 {
+		t.Errorf("AddedPrice = %v after adding again, want 450", item.AddedPrice)
+	}
+	if item.PriceDrop == nil || item.PriceDrop.Amount != 5000 || item.CurrentPrice.Amount != 40000 {
+		t.Errorf("AddItem() = %+v, want a drop of 50", item)
+	}
+}
+
+func TestMoveWishlistItems(t *testing.T) {
+	s, _, priceList, _ := newTestWishlistService(t, &fakeCart{added: make(map[uint]int)})
+	later, _ := s.Create(1, "Later")
+	gifts, _ := s.Create(1, "Gifts")
+	other, _ := s.Create(2, "Other")
+	s.AddItem(1, later.ID, 1, priceList)
+	s.AddItem(1, later.ID, 2, priceList)
+	s.AddItem(1, gifts.ID, 2, priceList)
+
+	if err := s.MoveItem(1, later.ID, 1, other.ID); !errors.Is(err, ErrWishlistNotFound) {
+		t.Errorf("MoveItem() to another user's list error = %v, want %v", err, ErrWishlistNotFound)
+	}
+	if err := s.MoveItem(1, later.ID, 1, gifts.ID); err != nil {
+		t.Fatalf("MoveItem() error = %v", err)
+	}
+	if err := s.MoveItem(1, later.ID, 2, gifts.ID); err != nil {
+		t.Fatalf("MoveItem() of a duplicate error = %v", err)
+	}
+
+	later, _ = s.Get(1, later.ID)
+	gifts, _ = s.Get(1, gifts.ID)
+	if len(later.Items) != 0 || len(gifts.Items) != 2 {
+		t.Errorf("items = %d and %d, want 0 and 2", len(later.Items), len(gifts.Items))
+	}
+	if err := s.MoveItem(1, later.ID, 1, gifts.ID); !errors.Is(err, ErrWishlistItemNotFound) {
+		t.Errorf("MoveItem() error = %v, want %v", err, ErrWishlistItemNotFound)
+	}
+}
+
+func TestShareWishlist(t *testing.T) {
+	s, _, priceList, productRepo := newTestWishlistService(t, &fakeCart{added: make(map[uint]int)})
+	wishlist, _ := s.Create(1, "Birthday")
+	s.AddItem(1, wishlist.ID, 1, priceList)
+	s.AddItem(1, wishlist.ID, 2, priceList)
+
+	shared, err := s.Share(1, wishlist.ID)
+	if err != nil || shared.ShareToken == nil || len(*shared.ShareToken) != 64 {
+		t.Fatalf("Share() = %+v, %v, want a 64 character token", shared, err)
+	}
+	token := *shared.ShareToken
+	if again, _ := s.Share(1, wishlist.ID); *again.ShareToken != token {
+		t.Errorf("Share() created a new token instead of reusing %q", token)
+	}
+
+	tea, _ := productRepo.FindByID(2)
+	tea.Status = models.ProductStatusArchived
+	view, err := s.Shared(token)
+	if err != nil {
+		t.Fatalf("Shared() error = %v", err)
+	}
+	if len(view.Items) != 1 || view.Items[0].ProductID != 1 {
+		t.Errorf("Shared() items = %+v, want only product 1", view.Items)
+	}
+
+	if err := s.Unshare(1, wishlist.ID); err != nil {
+		t.Fatalf("Unshare() error = %v", err)
+	}
+	if _, err := s.Shared(token); !errors.Is(err, ErrWishlistNotFound) {
+		t.Errorf("Shared() after unsharing error = %v, want %v", err, ErrWishlistNotFound)
+	}
+}
+
+func TestMoveToCart(t *testing.T) {
+	cart := &fakeCart{added: make(map[uint]int), err: ErrInsufficientStock}
+	s, _, priceList, _ := newTestWishlistService(t, cart)
+	wishlist, _ := s.Create(1, "Later")
+	s.AddItem(1, wishlist.ID, 1, priceList)
+	if err := s.MoveToCart(1, wishlist.ID, 1, 1, priceList); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("MoveToCart() error = %v, want %v", err, ErrInsufficientStock)
+	}
+	if got, _ := s.Get(1, wishlist.ID); len(got.Items) != 1 {
+		t.Errorf("item was removed although the cart refused it")
+	}
+
+	cart.err = nil
+	if err := s.MoveToCart(1, wishlist.ID, 1, 2, priceList); err != nil {
+		t.Fatalf("MoveToCart() error = %v", err)
+	}
+	if got, _ := s.Get(1, wishlist.ID); len(got.Items) != 0 || cart.added[1] != 2 {
+		t.Errorf("wishlist has %d items and cart %v, want the item moved", len(got.Items), cart.added)
+	}
+}
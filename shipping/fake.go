@@ -0,0 +1,89 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"e-commerce/money"
+)
+
+// Services offered by FakeCarrier
+const (
+	FakeServiceStandard = "standard"
+	FakeServiceExpress  = "express"
+)
+
+var errFakeUnavailable = errors.New("fake carrier: service unavailable")
+
+// FakeCarrier quotes predictable rates without talking to the network:
+// a base price per service plus a price per started kilogram, both in
+// whole units of the requested currency. Express is only offered within
+// Taiwan. Return labels are numbered FK0000000001 and up.
+type FakeCarrier struct {
+	mu     sync.Mutex
+	down   bool
+	labels int
+}
+
+func NewFakeCarrier() *FakeCarrier {
+	return &FakeCarrier{}
+}
+
+func (c *FakeCarrier) Name() string {
+	return "fake"
+}
+
+// SetDown makes the carrier fail every request, to test fallbacks
+func (c *FakeCarrier) SetDown(down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = down
+}
+
+func (c *FakeCarrier) Rates(ctx context.Context, req RateRequest) ([]Rate, error) {
+	c.mu.Lock()
+	down := c.down
+	c.mu.Unlock()
+	if down {
+		return nil, errFakeUnavailable
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	currency, err := money.Lookup(req.Value.Currency)
+	if err != nil {
+		return nil, err
+	}
+	unit := int64(1)
+	for i := 0; i < currency.Exponent; i++ {
+		unit *= 10
+	}
+	kilograms := int64((req.WeightGrams + 999) / 1000)
+	price := func(base, perKilogram int64) money.Money {
+		return money.New((base+perKilogram*kilograms)*unit, currency.Code)
+	}
+
+	rates := []Rate{{Service: FakeServiceStandard, Amount: price(60, 20), EstimatedDays: 3}}
+	if strings.EqualFold(req.Destination.Country, "TW") {
+		rates = append(rates, Rate{Service: FakeServiceExpress, Amount: price(120, 30), EstimatedDays: 1})
+	}
+	return rates, nil
+}
+
+func (c *FakeCarrier) ReturnLabel(ctx context.Context, req LabelRequest) (*Label, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return nil, errFakeUnavailable
+	}
+	if req.From.Line1 == "" || req.From.Country == "" {
+		return nil, errors.New("fake carrier: pickup address is incomplete")
+	}
+	c.labels++
+	tracking := fmt.Sprintf("FK%010d", c.labels)
+	return &Label{TrackingNumber: tracking, URL: "https://labels.fake-carrier.invalid/" + tracking + ".pdf"}, nil
+}
@@ -0,0 +1,54 @@
+package shipping
+
+import (
+	"context"
+	"testing"
+
+	"e-commerce/money"
+)
+
+func TestFakeRates(t *testing.T) {
+	c := NewFakeCarrier()
+	rates, err := c.Rates(context.Background(), RateRequest{
+		Destination: Destination{Country: "TW"},
+		WeightGrams: 1200,
+		Value:       money.New(100000, "TWD"),
+	})
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	// 1.2 公斤以 2 公斤計
+	if len(rates) != 2 || rates[0].Amount != money.New(10000, "TWD") || rates[1].Amount != money.New(18000, "TWD") {
+		t.Errorf("Rates() = %+v, want NT$100 standard and NT$180 express", rates)
+	}
+
+	rates, _ = c.Rates(context.Background(), RateRequest{Destination: Destination{Country: "JP"}, Value: money.New(1000, "JPY")})
+	if len(rates) != 1 || rates[0].Service != FakeServiceStandard || rates[0].Amount != money.New(60, "JPY") {
+		t.Errorf("Rates() abroad = %+v, want standard only", rates)
+	}
+
+	c.SetDown(true)
+	if _, err := c.Rates(context.Background(), RateRequest{Value: money.New(100, "USD")}); err == nil {
+		t.Error("Rates() while down succeeded")
+	}
+}
+
+func TestFakeReturnLabel(t *testing.T) {
+	c := NewFakeCarrier()
+	from := Address{Name: "王小明", Line1: "信義路五段7號", City: "台北市", PostalCode: "110", Country: "TW"}
+	label, err := c.ReturnLabel(context.Background(), LabelRequest{Reference: "RMA-1", From: from, WeightGrams: 500})
+	if err != nil {
+		t.Fatalf("ReturnLabel() error = %v", err)
+	}
+	if label.TrackingNumber != "FK0000000001" || label.URL == "" {
+		t.Errorf("ReturnLabel() = %+v, want the first fake label", label)
+	}
+
+	if _, err := c.ReturnLabel(context.Background(), LabelRequest{Reference: "RMA-2"}); err == nil {
+		t.Error("ReturnLabel() without an address succeeded")
+	}
+	c.SetDown(true)
+	if _, err := c.ReturnLabel(context.Background(), LabelRequest{Reference: "RMA-3", From: from}); err == nil {
+		t.Error("ReturnLabel() while down succeeded")
+	}
+}
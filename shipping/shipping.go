@@ -0,0 +1,86 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"e-commerce/money"
+)
+
+var ErrServiceUnavailable = errors.New("carrier does not offer this service to the destination")
+
+// Destination is where a parcel goes
+type Destination struct {
+	Country    string
+	City       string
+	PostalCode string
+}
+
+// RateRequest asks a carrier for the price of a parcel
+type RateRequest struct {
+	Destination Destination
+	WeightGrams int
+	// Value is the declared value, also the currency to quote in
+	Value money.Money
+}
+
+// Rate is the price of one service of a carrier
+type Rate struct {
+	Service       string
+	Amount        money.Money
+	EstimatedDays int
+}
+
+// Address is where a parcel is picked up
+type Address struct {
+	Name       string
+	Phone      string
+	Line1      string
+	Line2      string
+	City       string
+	PostalCode string
+	Country    string
+}
+
+// LabelRequest asks a carrier for a prepaid return label. Returns go to the
+// address the shop's account has on file with the carrier.
+type LabelRequest struct {
+	// Reference is our own identifier, e.g. the return number
+	Reference   string
+	From        Address
+	WeightGrams int
+}
+
+// Label is a prepaid shipping label
+type Label struct {
+	TrackingNumber string
+	// URL is where the printable label can be downloaded
+	URL string
+}
+
+// Carrier quotes live shipping rates and issues return labels.
+// Implementations must be safe for concurrent use.
+type Carrier interface {
+	Name() string
+	Rates(ctx context.Context, req RateRequest) ([]Rate, error)
+	ReturnLabel(ctx context.Context, req LabelRequest) (*Label, error)
+}
+
+// NewFromEnv builds the carriers listed in SHIPPING_CARRIERS, separated by
+// commas
+func NewFromEnv() ([]Carrier, error) {
+	var carriers []Carrier
+	for _, name := range strings.Split(os.Getenv("SHIPPING_CARRIERS"), ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case "fake":
+			carriers = append(carriers, NewFakeCarrier())
+		default:
+			return nil, fmt.Errorf("unknown shipping carrier %q", name)
+		}
+	}
+	return carriers, nil
+}
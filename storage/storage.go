@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage abstracts where uploaded media is kept
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns an address clients can fetch the object from. Depending on
+	// the backend it is either a signed, expiring URL or a public one.
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// URLVerifier is implemented by backends that serve their own signed URLs
+type URLVerifier interface {
+	VerifyURL(key string, query url.Values) error
+}
+
+// NewFromEnv builds the storage backend selected by STORAGE_DRIVER
+func NewFromEnv() (Storage, error) {
+	ttl := envDuration("STORAGE_URL_TTL", time.Hour)
+
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		root := os.Getenv("STORAGE_LOCAL_ROOT")
+		if root == "" {
+			root = "uploads"
+		}
+		baseURL := os.Getenv("STORAGE_PUBLIC_URL")
+		if baseURL == "" {
+			baseURL = "/api/v1/media"
+		}
+		return NewLocalStorage(root, baseURL, os.Getenv("STORAGE_SIGNING_KEY"), ttl)
+	case "s3":
+		return NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+			URLTTL:    ttl,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// cleanKey rejects keys that could escape the storage root
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "..") || strings.Contains(key, "\\") {
+		return "", errors.New("invalid object key")
+	}
+	return key, nil
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") &&
+		r.URL.Query().Get("X-Amz-Signature") == "" {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[r.URL.Path])
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s, err := NewS3Storage(S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "media",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "products/1/a b.png", strings.NewReader("data"), 4, "image/png"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if fake.types["/media/products/1/a b.png"] != "image/png" {
+		t.Errorf("Put() stored %v", fake.types)
+	}
+
+	rc, err := s.Get(ctx, "products/1/a b.png")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "data" {
+		t.Errorf("Get() = %q, want %q", body, "data")
+	}
+
+	u, err := s.URL(ctx, "products/1/a b.png")
+	if err != nil {
+		t.Fatalf("URL() error = %v", err)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatalf("GET presigned URL error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET presigned URL status = %d", resp.StatusCode)
+	}
+
+	if err := s.Delete(ctx, "products/1/a b.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, "products/1/a b.png"); err != ErrNotFound {
+		t.Errorf("Get() after delete error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestLocalStorageSignedURL(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/media", "secret", time.Minute)
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "../escape.txt", strings.NewReader("x"), 1, "text/plain"); err == nil {
+		t.Error("Put() should reject keys escaping the root")
+	}
+
+	raw, err := s.URL(ctx, "a/b.jpg")
+	if err != nil {
+		t.Fatalf("URL() error = %v", err)
+	}
+	u, _ := url.Parse(raw)
+	if u.Path != "/media/a/b.jpg" {
+		t.Errorf("URL() path = %q", u.Path)
+	}
+	if err := s.VerifyURL("a/b.jpg", u.Query()); err != nil {
+		t.Errorf("VerifyURL() error = %v", err)
+	}
+	if err := s.VerifyURL("a/c.jpg", u.Query()); err == nil {
+		t.Error("VerifyURL() should reject a signature for another key")
+	}
+
+	s.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if err := s.VerifyURL("a/b.jpg", u.Query()); err == nil {
+		t.Error("VerifyURL() should reject an expired URL")
+	}
+}
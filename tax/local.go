@@ -0,0 +1,110 @@
+package tax
+
+import (
+	"context"
+
+	"e-commerce/money"
+)
+
+// LocalProvider calculates taxes from the built-in jurisdiction table
+type LocalProvider struct{}
+
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{}
+}
+
+func (p *LocalProvider) Name() string {
+	return "local"
+}
+
+// rateGroup is the lines of a request taxed under one class
+type rateGroup struct {
+	class string
+	rate  int64
+	lines []int
+	base  money.Money
+}
+
+func (p *LocalProvider) Calculate(ctx context.Context, req Request) (*Result, error) {
+	result := &Result{Lines: make([]money.Money, len(req.Lines)), Total: money.Zero(req.Currency)}
+	for i := range result.Lines {
+		result.Lines[i] = money.Zero(req.Currency)
+	}
+	j, ok := Lookup(req.Jurisdiction)
+	if !ok {
+		return result, nil
+	}
+
+	var groups []*rateGroup
+	index := make(map[string]*rateGroup)
+	for i, line := range req.Lines {
+		class := line.TaxClass
+		if class == "" {
+			class = ClassStandard
+		}
+		g, ok := index[class]
+		if !ok {
+			g = &rateGroup{class: class, rate: j.Rate(class), base: money.Zero(req.Currency)}
+			index[class] = g
+			groups = append(groups, g)
+		}
+		g.lines = append(g.lines, i)
+		g.base = g.base.Add(line.Amount)
+	}
+
+	for _, g := range groups {
+		amount := money.Zero(req.Currency)
+		if j.Rounding == RoundPerLine {
+			for _, i := range g.lines {
+				result.Lines[i] = taxOn(req.Lines[i].Amount, g.rate, req.PricesIncludeTax).Round()
+				amount = amount.Add(result.Lines[i])
+			}
+		} else {
+			amount = taxOn(g.base, g.rate, req.PricesIncludeTax).Round()
+			weights := make([]int64, len(g.lines))
+			for k, i := range g.lines {
+				weights[k] = req.Lines[i].Amount.Amount
+			}
+			for k, share := range spread(amount, weights) {
+				result.Lines[g.lines[k]] = share
+			}
+		}
+
+		taxable := g.base
+		if req.PricesIncludeTax {
+			taxable = taxable.Sub(amount)
+		}
+		result.Breakdown = append(result.Breakdown, Breakdown{
+			Jurisdiction: j.Code,
+			TaxClass:     g.class,
+			Rate:         g.rate,
+			Taxable:      taxable,
+			Amount:       amount,
+		})
+		result.Total = result.Total.Add(amount)
+	}
+	return result, nil
+}
+
+// taxOn returns the unrounded tax of amount at rate basis points. Tax
+// included in a price is rate/(1+rate) of it.
+func taxOn(amount money.Money, rate int64, included bool) money.Money {
+	if included {
+		return amount.MulFrac(rate, 10000+rate)
+	}
+	return amount.MulFrac(rate, 10000)
+}
+
+// spread splits tax over lines in proportion to weights, in whole
+// currency increments where possible
+func spread(tax money.Money, weights []int64) []money.Money {
+	increment := int64(1)
+	if c, err := money.Lookup(tax.Currency); err == nil && tax.Amount%c.Increment == 0 {
+		increment = c.Increment
+	}
+	shares := money.New(tax.Amount/increment, tax.Currency).Allocate(weights...)
+	for k := range shares {
+		shares[k] = shares[k].Mul(increment)
+	}
+	return shares
+}
@@ -0,0 +1,85 @@
+package tax
+
+import (
+	"context"
+	"testing"
+
+	"e-commerce/money"
+)
+
+func twd(amount int64) money.Money {
+	return money.New(amount*100, "TWD")
+}
+
+func TestLocalTaxIncludedInPrice(t *testing.T) {
+	p := NewLocalProvider()
+	result, err := p.Calculate(context.Background(), Request{
+		Jurisdiction: "tw", Currency: "TWD", PricesIncludeTax: true,
+		Lines: []Line{
+			{Reference: "1", Amount: twd(900)},
+			{Reference: "2", TaxClass: ClassStandard, Amount: twd(300)},
+			{Reference: "3", TaxClass: ClassExempt, Amount: twd(100)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	// 1200 × 5/105 = 57.14，整張發票四捨五入到元
+	if result.Total != twd(57) {
+		t.Errorf("Total = %v, want NT$57", result.Total)
+	}
+	if sum := result.Lines[0].Add(result.Lines[1]); sum != twd(57) || !result.Lines[2].IsZero() {
+		t.Errorf("Lines = %v, want NT$57 spread over the standard lines", result.Lines)
+	}
+	if len(result.Breakdown) != 2 {
+		t.Fatalf("Breakdown = %+v, want standard and exempt", result.Breakdown)
+	}
+	if b := result.Breakdown[0]; b.Rate != 500 || b.Taxable != twd(1143) || b.Amount != twd(57) {
+		t.Errorf("standard breakdown = %+v, want NT$57 on NT$1,143 at 5%%", b)
+	}
+}
+
+func TestLocalTaxAddedPerLine(t *testing.T) {
+	p := NewLocalProvider()
+	result, err := p.Calculate(context.Background(), Request{
+		Jurisdiction: "CA", Currency: "USD",
+		Lines: []Line{
+			{Amount: money.New(1010, "USD")},
+			{Amount: money.New(1010, "USD")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	// 每行 50.5 分各自進位，合計比整單計算多一分
+	if result.Lines[0] != money.New(51, "USD") || result.Total != money.New(102, "USD") {
+		t.Errorf("Lines = %v, Total = %v, want 51 cents a line", result.Lines, result.Total)
+	}
+	if b := result.Breakdown[0]; b.Taxable != money.New(2020, "USD") {
+		t.Errorf("Taxable = %v, want the net amount", b.Taxable)
+	}
+}
+
+func TestLocalTaxUnknownJurisdiction(t *testing.T) {
+	result, err := NewLocalProvider().Calculate(context.Background(), Request{
+		Jurisdiction: "US", Currency: "USD", Lines: []Line{{Amount: money.New(1000, "USD")}},
+	})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if !result.Total.IsZero() || len(result.Breakdown) != 0 || !result.Lines[0].IsZero() {
+		t.Errorf("result = %+v, want no tax", result)
+	}
+}
+
+func TestReducedRate(t *testing.T) {
+	j, _ := Lookup("JP")
+	if j.Rate(ClassReduced) != 800 || j.Rate("unknown") != 1000 || j.Rate(ClassZero) != 0 {
+		t.Errorf("JP rates = %d/%d/%d, want 800/1000/0", j.Rate(ClassReduced), j.Rate("unknown"), j.Rate(ClassZero))
+	}
+	tw, _ := Lookup("TW")
+	if tw.Rate(ClassReduced) != 500 {
+		t.Errorf("TW reduced rate = %d, want the standard rate", tw.Rate(ClassReduced))
+	}
+}
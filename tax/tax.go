@@ -0,0 +1,131 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"e-commerce/money"
+)
+
+// Product tax classes
+const (
+	ClassStandard = "standard"
+	ClassReduced  = "reduced"
+	ClassZero     = "zero"
+	ClassExempt   = "exempt"
+)
+
+// Rounding strategies of a jurisdiction
+const (
+	// RoundPerLine rounds the tax of every line on its own
+	RoundPerLine = "line"
+	// RoundPerOrder rounds the tax of each rate once over the whole order
+	// and spreads it back over the lines
+	RoundPerOrder = "order"
+)
+
+// Jurisdiction is a tax authority and the rates it charges per tax class,
+// in basis points. Classes without a rate are taxed at the standard rate.
+type Jurisdiction struct {
+	Code string
+	Name string
+	// PricesIncludeTax is whether prices are displayed with tax included
+	// in this market
+	PricesIncludeTax bool
+	Rounding         string
+	Rates            map[string]int64
+}
+
+// Rate returns the rate of a tax class in basis points
+func (j Jurisdiction) Rate(class string) int64 {
+	switch class {
+	case ClassZero, ClassExempt:
+		return 0
+	}
+	if rate, ok := j.Rates[class]; ok {
+		return rate
+	}
+	return j.Rates[ClassStandard]
+}
+
+var jurisdictions = map[string]Jurisdiction{
+	// 營業稅 5%，發票以整張計算稅額
+	"TW": {Code: "TW", Name: "Taiwan VAT", PricesIncludeTax: true, Rounding: RoundPerOrder,
+		Rates: map[string]int64{ClassStandard: 500}},
+	"JP": {Code: "JP", Name: "Japan consumption tax", PricesIncludeTax: true, Rounding: RoundPerOrder,
+		Rates: map[string]int64{ClassStandard: 1000, ClassReduced: 800}},
+	// 只計聯邦 GST，省稅尚未支援
+	"CA": {Code: "CA", Name: "Canada GST", PricesIncludeTax: false, Rounding: RoundPerLine,
+		Rates: map[string]int64{ClassStandard: 500}},
+}
+
+// Lookup returns the jurisdiction of an ISO 3166 country code. Places we
+// have no nexus in are not taxed.
+func Lookup(code string) (Jurisdiction, bool) {
+	j, ok := jurisdictions[strings.ToUpper(code)]
+	return j, ok
+}
+
+// IsClass reports whether class is a known tax class
+func IsClass(class string) bool {
+	switch class {
+	case ClassStandard, ClassReduced, ClassZero, ClassExempt:
+		return true
+	}
+	return false
+}
+
+// Line is an amount to be taxed, after discounts
+type Line struct {
+	Reference string
+	TaxClass  string
+	Amount    money.Money
+}
+
+// Request asks for the taxes of a sale delivered to a jurisdiction.
+// With PricesIncludeTax the tax is taken out of the line amounts instead
+// of added on top of them.
+type Request struct {
+	Jurisdiction     string
+	Currency         string
+	PricesIncludeTax bool
+	Lines            []Line
+}
+
+// Breakdown is the tax charged at one rate
+type Breakdown struct {
+	Jurisdiction string
+	TaxClass     string
+	// Rate is in basis points
+	Rate int64
+	// Taxable is the amount the tax is charged on, excluding the tax
+	Taxable money.Money
+	Amount  money.Money
+}
+
+// Result is the tax of a request. Lines holds the tax of each request
+// line in order and adds up to Total.
+type Result struct {
+	Lines     []money.Money
+	Breakdown []Breakdown
+	Total     money.Money
+}
+
+// Provider calculates taxes. Implementations must be safe for concurrent
+// use.
+type Provider interface {
+	Name() string
+	Calculate(ctx context.Context, req Request) (*Result, error)
+}
+
+// NewFromEnv builds the provider selected by TAX_PROVIDER
+func NewFromEnv() (Provider, error) {
+	switch provider := os.Getenv("TAX_PROVIDER"); provider {
+	case "", "local":
+		return NewLocalProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown tax provider %q", provider)
+	}
+}